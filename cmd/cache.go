@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the runtime download cache",
+	Long: `Verified runtime archives are kept in ~/.templatr/cache/ so that setting up
+another template with the same runtime version doesn't download it again.`,
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete all cached runtime downloads",
+	Run: func(cmd *cobra.Command, args []string) {
+		files, size, err := install.CleanCache()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		if files == 0 {
+			fmt.Println("Download cache is already empty.")
+			return
+		}
+		fmt.Printf("Removed %d cached download(s), freeing %s.\n", files, term.FormatBytes(size))
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheCleanCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+var (
+	cleanKeep   int
+	cleanDryRun bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove superseded runtime versions from ~/.templatr/runtimes",
+	Long: `Lists every runtime version in ~/.templatr/runtimes/ with its size and
+removes all but the newest of each runtime (see --keep). Versions found on
+disk but missing from state.json are included.
+
+If a removed version was the one on PATH, its PATH entry and environment
+variables are reverted and the newest remaining version takes its place.
+Use --dry-run to see what would be removed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runClean()
+	},
+}
+
+func init() {
+	cleanCmd.Flags().IntVar(&cleanKeep, "keep", 1, "Number of versions to keep per runtime")
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Show what would be removed without deleting anything")
+	rootCmd.AddCommand(cleanCmd)
+}
+
+func runClean() {
+	if cleanKeep < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --keep must be at least 1 (use 'templatr-setup uninstall' to remove everything)")
+		os.Exit(1)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %s\n", err)
+		os.Exit(1)
+	}
+
+	versions, err := install.ListRuntimeVersions(st)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if len(versions) == 0 {
+		fmt.Println("No runtimes installed in ~/.templatr/runtimes. Nothing to clean.")
+		return
+	}
+
+	prune := install.PrunableVersions(versions, cleanKeep)
+	removing := map[string]bool{}
+	var total int64
+	for _, v := range prune {
+		removing[v.Path] = true
+		total += v.Size
+	}
+
+	fmt.Println("Installed runtime versions:")
+	fmt.Println()
+	last := ""
+	for _, v := range versions {
+		if v.Runtime != last {
+			fmt.Printf("  %s\n", v.Runtime)
+			last = v.Runtime
+		}
+		status := "keep"
+		if removing[v.Path] {
+			status = "remove"
+		}
+		var notes string
+		if v.Active {
+			notes += ", active"
+		}
+		if !v.Tracked {
+			notes += ", not in state.json"
+		}
+		fmt.Printf("    %-12s %10s  %s%s\n", v.Version, term.FormatBytes(v.Size), status, notes)
+	}
+	fmt.Println()
+
+	if len(prune) == 0 {
+		fmt.Printf("Nothing to remove: no runtime has more than %d version(s).\n", cleanKeep)
+		return
+	}
+	if cleanDryRun {
+		fmt.Printf("Would remove %d version(s), freeing %s. Run without --dry-run to remove them.\n", len(prune), term.FormatBytes(total))
+		return
+	}
+
+	log := logger.New()
+	if err := log.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not initialize logger: %s\n", err)
+	} else {
+		defer log.Close()
+	}
+
+	// Against a fresh copy of the state under the lock, so a setup running
+	// meanwhile can't be overwritten
+	var freed int64
+	var removeErr error
+	err = state.WithLock(func(st *state.State) error {
+		freed, removeErr = install.RemoveRuntimeVersions(st, prune, log)
+		return nil
+	})
+	if errors.Is(err, state.ErrLocked) {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save state: %s\n", err)
+	}
+	if removeErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", removeErr)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Removed %d version(s), freeing %s.\n", len(prune), term.FormatBytes(freed))
+}
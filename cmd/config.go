@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/selfupdate"
+	"github.com/templatr/templatr-setup/internal/userconfig"
+)
+
+var (
+	runtimesDirFlag      string
+	verifySignaturesFlag bool
+
+	// userCfg is ~/.templatr/config.toml with the TEMPLATR_* overrides,
+	// as loaded by Execute. It stays the defaults if the file is broken, and
+	// userCfgErr says why.
+	userCfg    = userconfig.Default()
+	userCfgErr error
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show or change the settings in ~/.templatr/config.toml",
+	Long: `Settings for every run of templatr-setup live in ~/.templatr/config.toml:
+
+  runtimes_dir           Where runtimes are installed (default ~/.templatr/runtimes)
+  update_check.enabled   Check for a newer templatr-setup release (default true)
+  http.proxy             Proxy URL for all downloads (default: HTTPS_PROXY etc.)
+  http.ca_bundle         PEM file of extra CAs to trust, e.g. a corporate proxy's
+  setup.yes              Skip the confirmation prompts as --yes does (default false)
+  mirrors.<runtime>      Download mirror for node, go, python or flutter
+
+Each setting but the mirrors can be overridden for one run with a
+TEMPLATR_* environment variable, e.g. TEMPLATR_UPDATE_CHECK_ENABLED=false
+or TEMPLATR_RUNTIMES_DIR, and runtimes_dir with --runtimes-dir.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print the value of a setting, or of every setting",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runConfigGet(args)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting, keeping the rest of the file and its comments",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runConfigSet(args[0], args[1])
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&verifySignaturesFlag, "verify-signatures", false, "Check the PGP signatures of Node.js and Go downloads and the signature of self-updates (default: verify_signatures)")
+	rootCmd.PersistentFlags().StringVar(&runtimesDirFlag, "runtimes-dir", "", "Install and look for runtimes in this directory instead of runtimes_dir or ~/.templatr/runtimes")
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// loadUserConfig reads the user configuration and applies it to this run.
+func loadUserConfig() {
+	cfg, err := userconfig.Load()
+	if err != nil {
+		userCfgErr = err
+		return
+	}
+	userCfg = cfg
+	userCfgErr = applyUserConfig()
+}
+
+// applyUserConfig points the installers and every HTTP request of the run
+// at userCfg, and turns the update check off and signature checks on if
+// it says so.
+func applyUserConfig() error {
+	install.SetRuntimesDir(userCfg.RuntimesDir)
+	applyVerifySignatures()
+	selfupdate.DisableCheck(!userCfg.UpdateCheck.Enabled)
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		return userCfg.ApplyHTTP(t)
+	}
+	return nil
+}
+
+// applyConfigFlags reports a broken configuration file, unless cmd is one
+// of the config commands there to fix it, and lets the flags override the
+// configuration: --runtimes-dir over runtimes_dir, --verify-signatures over
+// verify_signatures, and --yes or --yes=false over setup.yes.
+func applyConfigFlags(cmd *cobra.Command) error {
+	if userCfgErr != nil && !isConfigCommand(cmd) {
+		return userCfgErr
+	}
+	if runtimesDirFlag != "" {
+		dir, err := filepath.Abs(runtimesDirFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --runtimes-dir %s: %w", runtimesDirFlag, err)
+		}
+		if err := userCfg.Set("runtimes_dir", dir); err != nil {
+			return err
+		}
+		install.SetRuntimesDir(userCfg.RuntimesDir)
+	}
+	if cmd.Root().PersistentFlags().Changed("verify-signatures") {
+		userCfg.VerifySignatures = verifySignaturesFlag
+		applyVerifySignatures()
+	}
+	if userCfg.Setup.Yes && !cmd.Flags().Changed("yes") {
+		yesFlag = true
+	}
+	return nil
+}
+
+func applyVerifySignatures() {
+	install.SetVerifySignatures(userCfg.VerifySignatures)
+	selfupdate.SetVerifySignatures(userCfg.VerifySignatures)
+}
+
+func isConfigCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == configCmd {
+			return true
+		}
+	}
+	return false
+}
+
+func runConfigGet(args []string) {
+	cfg, err := userconfig.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+
+	if len(args) == 0 {
+		for _, kv := range cfg.Values() {
+			fmt.Printf("%s = %s\n", kv.Key, kv.Value)
+		}
+		return
+	}
+	value, err := cfg.Get(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+	fmt.Println(value)
+}
+
+func runConfigSet(key, value string) {
+	path, err := userconfig.Path()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+	if err := userconfig.SetFile(path, key, value); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+
+	fmt.Printf("Set %s in %s\n", key, path)
+	if env := userconfig.EnvVar(key); slices.Contains(userconfig.Keys(), key) && os.Getenv(env) != "" {
+		fmt.Printf("Note: %s is set and overrides the file until it is unset.\n", env)
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/userconfig"
+)
+
+var errTest = errors.New("failed to parse config.toml")
+
+func TestApplyConfigFlags(t *testing.T) {
+	t.Cleanup(func() {
+		userCfg, userCfgErr, runtimesDirFlag, yesFlag, verifySignaturesFlag = userconfig.Default(), nil, "", false, false
+		setupCmd.Flags().Lookup("yes").Changed = false
+		rootCmd.PersistentFlags().Lookup("verify-signatures").Changed = false
+		install.SetRuntimesDir("")
+		applyVerifySignatures()
+	})
+	dir := t.TempDir()
+
+	// setup.yes stands in for --yes, and --runtimes-dir wins over runtimes_dir
+	userCfg = userconfig.Default()
+	userCfg.Setup.Yes, userCfg.RuntimesDir = true, filepath.Join(dir, "file")
+	runtimesDirFlag = filepath.Join(dir, "flag")
+	if err := applyConfigFlags(setupCmd); err != nil {
+		t.Fatal(err)
+	}
+	if !yesFlag {
+		t.Error("setup.yes should turn on --yes")
+	}
+	if got, _ := install.RuntimesDir(); got != runtimesDirFlag {
+		t.Errorf("RuntimesDir() = %s, want %s from --runtimes-dir", got, runtimesDirFlag)
+	}
+
+	// --yes=false wins over setup.yes
+	yesFlag = false
+	if err := setupCmd.Flags().Set("yes", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFlags(setupCmd); err != nil {
+		t.Fatal(err)
+	}
+	if yesFlag {
+		t.Error("--yes=false should win over setup.yes")
+	}
+
+	// --verify-signatures=false wins over verify_signatures
+	userCfg.VerifySignatures = true
+	if err := rootCmd.PersistentFlags().Set("verify-signatures", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFlags(setupCmd); err != nil {
+		t.Fatal(err)
+	}
+	if userCfg.VerifySignatures {
+		t.Error("--verify-signatures=false should win over verify_signatures")
+	}
+
+	// A broken file stops every command but config
+	userCfgErr = errTest
+	if err := applyConfigFlags(setupCmd); err != errTest {
+		t.Errorf("applyConfigFlags(setup) = %v, want the config file error", err)
+	}
+	if err := applyConfigFlags(configSetCmd); err != nil {
+		t.Errorf("applyConfigFlags(config set) = %v, want config set to be able to fix the file", err)
+	}
+}
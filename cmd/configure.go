@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"strings"
 
@@ -10,6 +13,17 @@ import (
 	"github.com/templatr/templatr-setup/internal/config"
 	"github.com/templatr/templatr-setup/internal/logger"
 	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/secrets"
+	"github.com/templatr/templatr-setup/internal/state"
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+var (
+	onlyNewFlag bool
+	envNameFlag string
+	exampleFlag bool
+	valuesFile  string
+	setValues   []string
 )
 
 var configureCmd = &cobra.Command{
@@ -17,13 +31,21 @@ var configureCmd = &cobra.Command{
 	Short: "Configure .env and site.ts files for your template",
 	Long: `Reads the configuration definitions from .templatr.toml and presents
 an interactive form to fill out .env variables and site.ts fields.
-Values are written directly to the template files.`,
+Values are written directly to the template files.
+
+With --values or --set, the answers are taken from a TOML file mapping
+env keys and config field paths to values, and from KEY=VALUE pairs,
+and the files are written without prompting.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runConfigure()
 	},
 }
 
 func init() {
+	configureCmd.Flags().BoolVar(&onlyNewFlag, "only-new", false, "Only ask for fields added since your last setup")
+	configureCmd.Flags().StringVar(&envNameFlag, "env-name", "", "Only configure this environment from [env_environments] (e.g. production)")
+	configureCmd.Flags().BoolVar(&exampleFlag, "example", false, "Also write a .env.example next to each env file, as [env_options] write_example does")
+	addValuesFlags(configureCmd)
 	rootCmd.AddCommand(configureCmd)
 }
 
@@ -34,49 +56,107 @@ func runConfigure() {
 	} else {
 		defer log.Close()
 	}
+	config.SetBackupLogger(log)
 
 	m, err := manifest.Load(manifestFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		exit(exitCode(err, exitManifest))
 	}
+	checkToolVersion(m, log)
 
 	if len(m.Env) == 0 && len(m.Config) == 0 {
 		fmt.Println("No configuration fields defined in the manifest.")
 		return
 	}
 
-	// Read existing env values from all target files to pre-fill
-	existingEnv := make(map[string]string)
-	grouped, fileOrder := config.GroupEnvByFile(m.Env)
-	for _, file := range fileOrder {
-		existing, _ := config.ReadEnvFile(file)
-		for k, v := range existing {
-			existingEnv[k] = v
+	given, err := loadValues()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitConfigure)
+	}
+	if given != nil && onlyNewFlag {
+		fmt.Fprintln(os.Stderr, "Error: --only-new can't be used with --values or --set")
+		exit(exitError)
+	}
+
+	// In --only-new mode, fields that existed at the last setup keep their
+	// current values and are not prompted for.
+	var changes *manifest.Diff
+	if onlyNewFlag {
+		snap, err := state.LoadSnapshot(m)
+		if err != nil {
+			log.Warn("Could not load previous setup: %s", err)
+		}
+		if snap == nil {
+			fmt.Println("No previous setup recorded - asking for all fields.")
+			fmt.Println()
+		} else {
+			changes = manifest.Compare(snap.Manifest, m)
+			if len(changes.AddedEnv) == 0 && len(changes.AddedConfig) == 0 {
+				fmt.Println("No new configuration fields since your last setup.")
+				return
+			}
 		}
 	}
 
+	if envNameFlag != "" && !config.IsEnvironment(m, envNameFlag) {
+		fmt.Fprintf(os.Stderr, "Error: unknown environment %q - declared in [env_environments]: %s\n", envNameFlag, strings.Join(m.EnvEnvironments.Names, ", "))
+		exit(exitError)
+	}
+
+	// Read existing env values from all target files to pre-fill,
+	// per environment (a single unnamed one when none are declared)
+	existingEnv := config.ReadExistingEnv(m)
+
+	if given != nil {
+		resolved := resolveValues(m, given, existingEnv, envNameFlag, log)
+		writeResolved(m, resolved, envNameFlag, log)
+		fmt.Println("\nConfiguration complete!")
+		return
+	}
+
 	// Plain text interactive mode
 	reader := bufio.NewReader(os.Stdin)
 	envValues := make(map[string]string)
+	perEnvValues := make(map[string]map[string]string)
 
 	if len(m.Env) > 0 {
-		currentFile := ""
-		for _, env := range m.Env {
-			// Show file header when switching to a new target file
-			target := config.EnvFileTarget(env)
-			if target != currentFile {
-				if currentFile != "" {
+		currentSection := ""
+		for _, p := range config.EnvPrompts(m, envNameFlag) {
+			env := p.Var
+			values := envValues
+			if p.Environment != "" {
+				if perEnvValues[p.Environment] == nil {
+					perEnvValues[p.Environment] = make(map[string]string)
+				}
+				values = perEnvValues[p.Environment]
+			}
+			if !p.Shown(envValues, perEnvValues) {
+				continue
+			}
+
+			if changes != nil && !changes.IsNewEnv(env.Key) {
+				values[env.Key] = existingEnv.Value(p)
+				if values[env.Key] == "" {
+					values[env.Key] = env.Default
+				}
+				continue
+			}
+
+			// Show a header when switching to a new file or environment
+			if p.Section != currentSection {
+				if currentSection != "" {
 					fmt.Println()
 				}
-				currentFile = target
-				fmt.Printf("Environment Variables (%s)\n", target)
-				fmt.Println(strings.Repeat("─", 40))
+				currentSection = p.Section
+				fmt.Printf("Environment Variables (%s)\n", p.Section)
+				fmt.Println(term.Rule(40))
 				fmt.Println()
 			}
 
 			defaultVal := env.Default
-			if existing, ok := existingEnv[env.Key]; ok && existing != "" {
+			if existing := existingEnv.Value(p); existing != "" {
 				defaultVal = existing
 			}
 
@@ -89,66 +169,281 @@ func runConfigure() {
 			if env.Description != "" {
 				fmt.Printf("  %s\n", env.Description)
 			}
+			if env.DocsURL != "" {
+				if env.Type == "secret" {
+					fmt.Printf("  Get this from: %s\n", env.DocsURL)
+				} else {
+					fmt.Printf("  Docs: %s\n", env.DocsURL)
+				}
+			}
+			printFieldRules(config.EnvRules(env))
 			if defaultVal != "" {
 				fmt.Printf("  [default: %s]\n", defaultVal)
 			}
-			fmt.Print("  > ")
-
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(input)
-			if input == "" {
-				input = defaultVal
-			}
-			envValues[env.Key] = input
+			values[env.Key] = readFieldValue(reader, config.EnvRules(env), defaultVal)
 			fmt.Println()
 		}
 
-		log.Info("Writing env files...")
-		for _, file := range fileOrder {
-			defs := grouped[file]
-			if err := config.WriteEnvFile(file, defs, envValues); err != nil {
-				fmt.Fprintf(os.Stderr, "Error writing %s: %s\n", file, err)
-				os.Exit(1)
-			}
-			fmt.Printf("  ✓ %s written\n", file)
-		}
+		writeEnvValues(m, envValues, perEnvValues, envNameFlag, reader, log)
 	}
 
-	// Config files
+	// Config files. Their conditions can refer to the env values above and
+	// the fields asked before them.
+	asked := maps.Clone(envValues)
 	for _, cfg := range m.Config {
+		var fields []manifest.ConfigField
+		for _, f := range cfg.Fields {
+			if changes == nil || changes.IsNewConfigField(cfg.File, f.Path) {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
 		fmt.Printf("\n%s (%s)\n", cfg.Label, cfg.File)
-		fmt.Println(strings.Repeat("─", 40))
+		fmt.Println(term.Rule(40))
 		fmt.Println()
 
 		fieldValues := make(map[string]string)
-		for _, f := range cfg.Fields {
+		for _, f := range fields {
+			if !manifest.Shown(f.When, asked) {
+				continue
+			}
 			label := f.Label
 			fmt.Printf("  %s\n", label)
 			if f.Description != "" {
 				fmt.Printf("  %s\n", f.Description)
 			}
+			printFieldRules(config.ConfigRules(f))
 			if f.Default != "" {
 				fmt.Printf("  [default: %s]\n", f.Default)
 			}
-			fmt.Print("  > ")
-
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(input)
-			if input == "" {
-				input = f.Default
-			}
-			fieldValues[f.Path] = input
+			fieldValues[f.Path] = readFieldValue(reader, config.ConfigRules(f), f.Default)
+			asked[f.Path] = fieldValues[f.Path]
 			fmt.Println()
 		}
 
-		log.Info("Updating %s...", cfg.File)
-		if err := config.UpdateConfigFile(cfg.File, fieldValues); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not update %s: %s\n", cfg.File, err)
-			log.Warn("Failed to update %s: %s", cfg.File, err)
+		writeConfigValues(cfg, fieldValues, log)
+	}
+
+	fmt.Println("\nConfiguration complete!")
+}
+
+// addValuesFlags adds --values and --set, the answers configure takes
+// without prompting, to cmd.
+func addValuesFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&valuesFile, "values", "", "Take configuration values from this TOML file (- for stdin) instead of prompting")
+	cmd.Flags().StringArrayVar(&setValues, "set", nil, "Set a configuration value, KEY=VALUE, instead of prompting (repeatable)")
+}
+
+// loadValues reads the values given with --values and --set, or returns
+// nil when neither was given.
+func loadValues() (*config.Values, error) {
+	if valuesFile == "" && len(setValues) == 0 {
+		return nil, nil
+	}
+	values := config.NewValues()
+	if valuesFile != "" {
+		var data []byte
+		var err error
+		if valuesFile == "-" {
+			data, err = io.ReadAll(os.Stdin)
 		} else {
-			fmt.Printf("  ✓ %s updated\n", cfg.File)
+			data, err = os.ReadFile(valuesFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading values: %w", err)
+		}
+		if values, err = config.ParseValues(data); err != nil {
+			return nil, err
 		}
 	}
+	for _, assignment := range setValues {
+		if err := values.Set(assignment); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
 
-	fmt.Println("\nConfiguration complete!")
+// resolveValues works out the values to write from values, and exits
+// listing what is missing or wrong if they don't cover the manifest.
+// Secret values are masked in the log from then on.
+func resolveValues(m *manifest.Manifest, values *config.Values, existing config.ExistingEnv, only string, log *logger.Logger) *config.Resolved {
+	resolved, err := values.Resolve(m, existing, only)
+	var verr *config.ValuesError
+	if errors.As(err, &verr) {
+		fmt.Fprintln(os.Stderr, "Error: the configuration values don't fit the manifest:")
+		for _, key := range verr.Missing {
+			fmt.Fprintf(os.Stderr, "  - %s is required\n", key)
+		}
+		for _, problem := range verr.Invalid {
+			fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+		}
+		for _, key := range verr.Unknown {
+			fmt.Fprintf(os.Stderr, "  - %s is not an env var or config field of the manifest\n", key)
+		}
+		log.Error("Invalid configuration values: %s", err)
+		exit(exitConfigure)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitConfigure)
+	}
+
+	for _, env := range m.Env {
+		if env.Type != "secret" {
+			continue
+		}
+		if v := resolved.Env[env.Key]; v != "" {
+			log.AddSecret(v)
+		}
+		for _, values := range resolved.EnvByEnvironment {
+			if v := values[env.Key]; v != "" {
+				log.AddSecret(v)
+			}
+		}
+	}
+	return resolved
+}
+
+// writeResolved writes resolved values to the env and config files, as the
+// prompts would have.
+func writeResolved(m *manifest.Manifest, resolved *config.Resolved, only string, log *logger.Logger) {
+	if len(m.Env) > 0 {
+		writeEnvValues(m, resolved.Env, resolved.EnvByEnvironment, only, nil, log)
+	}
+	for _, cfg := range m.Config {
+		fieldValues := make(map[string]string)
+		for _, f := range cfg.Fields {
+			if v, ok := resolved.Config[f.Path]; ok {
+				fieldValues[f.Path] = v
+			}
+		}
+		if len(fieldValues) > 0 {
+			writeConfigValues(cfg, fieldValues, log)
+		}
+	}
+}
+
+// writeEnvValues stores keychain secrets and writes the env files (and
+// their examples), then checks the ones with secrets are git-ignored. It
+// only asks to fix .gitignore when reader is set. It exits if the files
+// can't be written.
+func writeEnvValues(m *manifest.Manifest, envValues map[string]string, perEnvValues map[string]map[string]string, only string, reader *bufio.Reader, log *logger.Logger) {
+	fileValues, err := config.StoreSecrets(m, envValues)
+	if errors.Is(err, secrets.ErrUnsupported) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		log.Warn("%s", err)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitConfigure)
+	}
+
+	log.Info("Writing env files...")
+	written, err := config.WriteEnvironmentFiles(m, fileValues, perEnvValues, only)
+	for _, file := range written {
+		fmt.Printf("  ✓ %s written\n", file)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitConfigure)
+	}
+	if exampleFlag || m.EnvOptions.WriteExample {
+		written, err := config.WriteExampleFiles(m)
+		for _, file := range written {
+			fmt.Printf("  ✓ %s written\n", file)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			exit(exitConfigure)
+		}
+	}
+
+	if !noGitignore {
+		ensureGitignored(m, reader, log)
+	}
+}
+
+// writeConfigValues updates a config file with fieldValues, warning if it
+// can't.
+func writeConfigValues(cfg manifest.ConfigFile, fieldValues map[string]string, log *logger.Logger) {
+	log.Info("Updating %s...", cfg.File)
+	if err := config.UpdateConfigFile(cfg.File, cfg.Fields, fieldValues); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not update %s: %s\n", cfg.File, err)
+		log.Warn("Failed to update %s: %s", cfg.File, err)
+	} else {
+		fmt.Printf("  ✓ %s updated\n", cfg.File)
+	}
+}
+
+// printFieldRules shows the choices, format and range a value must fit.
+func printFieldRules(rules config.FieldRules) {
+	if rules.Type == "select" {
+		fmt.Printf("  Options: %s\n", strings.Join(rules.Options, ", "))
+	}
+	if rules.Pattern != "" {
+		fmt.Printf("  Format: %s\n", rules.Pattern)
+	}
+	if rules.Type == "number" && (rules.Min != nil || rules.Max != nil) {
+		fmt.Printf("  Range: %s\n", manifest.FormatRange(rules.Min, rules.Max))
+	}
+}
+
+// readFieldValue reads one answer, falling back to def when it is empty.
+// It asks again until the answer passes the field's rules, or until input
+// runs out.
+func readFieldValue(reader *bufio.Reader, rules config.FieldRules, def string) string {
+	for {
+		fmt.Print("  > ")
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if input == "" {
+			input = def
+		}
+		input = config.NormalizeFieldValue(input, rules)
+		verr := config.ValidateFieldValue(input, rules)
+		if verr == nil || err != nil {
+			return input
+		}
+		fmt.Printf("  ✗ %s\n", verr)
+	}
+}
+
+// ensureGitignored warns about env files with secrets that git would pick up
+// and, when confirmed, adds them to .gitignore. Without a reader it only
+// warns.
+func ensureGitignored(m *manifest.Manifest, reader *bufio.Reader, log *logger.Logger) {
+	gaps := config.CheckGitignore(config.SecretEnvFiles(config.AllEnvDefs(m)))
+	if len(gaps) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("⚠ These files contain secrets but are not ignored by git:")
+	for _, g := range gaps {
+		fmt.Printf("    %s\n", g.File)
+		log.Warn("%s is not git-ignored", g.File)
+	}
+
+	if reader == nil || !isTerminal() {
+		fmt.Println("  Add them to .gitignore before committing.")
+		return
+	}
+
+	fmt.Print("  Add them to .gitignore? [Y/n] ")
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if answer != "" && answer != "y" && answer != "yes" {
+		fmt.Println("  Skipped - add them to .gitignore before committing.")
+		return
+	}
+
+	if err := config.AppendGitignore(gaps); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: could not update .gitignore: %s\n", err)
+		log.Warn("Could not update .gitignore: %s", err)
+		return
+	}
+	fmt.Println("  ✓ .gitignore updated")
 }
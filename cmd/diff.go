@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/config"
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what changed in the manifest since your last setup",
+	Long: `Compares the current .templatr.toml against the manifest recorded after
+your last successful setup of this template (~/.templatr/templates/<slug>.json)
+and prints only the differences: runtime constraints, new or removed env vars
+and config fields, and changed post-setup commands.
+
+Required env vars that are missing from your env files are listed so you can
+fill them in with 'templatr-setup configure --only-new'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runDiff()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff() {
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	snap, err := state.LoadSnapshot(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if snap == nil {
+		fmt.Printf("No previous setup recorded for %s.\n", m.Template.Name)
+		fmt.Println("Run 'templatr-setup setup' to set up this template.")
+		return
+	}
+
+	fmt.Printf("Template: %s\n", m.Template.Name)
+	fmt.Printf("Last setup: %s\n\n", snap.AppliedAt)
+
+	d := manifest.Compare(snap.Manifest, m)
+	if d.Empty() && snap.Hash == manifest.Hash(m) {
+		fmt.Println("No changes since your last setup.")
+		return
+	}
+	if d.Empty() {
+		fmt.Println("No changes that affect setup.")
+	} else {
+		fmt.Println("Changes since your last setup:")
+		engine.PrintChanges(d)
+	}
+
+	missing := missingRequiredEnv(m)
+	if len(missing) > 0 {
+		fmt.Println()
+		fmt.Println("Required values missing from your env files:")
+		for _, f := range missing {
+			fmt.Printf("  ✗ %s (%s)\n", f.Key, f.File)
+		}
+	}
+
+	if len(d.AddedEnv) > 0 || len(d.AddedConfig) > 0 {
+		fmt.Println()
+		fmt.Println("Run 'templatr-setup configure --only-new' to fill in just the new fields.")
+	}
+}
+
+// missingRequiredEnv returns required env vars that have no value in their target file.
+func missingRequiredEnv(m *manifest.Manifest) []manifest.FieldChange {
+	var missing []manifest.FieldChange
+	existing := make(map[string]map[string]string)
+
+	for _, env := range config.AllEnvDefs(m) {
+		if !env.Required {
+			continue
+		}
+		target := config.EnvFileTarget(env)
+		values, ok := existing[target]
+		if !ok {
+			values, _ = config.ReadEnvFile(target)
+			existing[target] = values
+		}
+		if values[env.Key] == "" {
+			missing = append(missing, manifest.FieldChange{Key: env.Key, Label: env.Label, File: target, Required: true})
+		}
+	}
+
+	return missing
+}
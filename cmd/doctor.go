@@ -1,49 +1,57 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/templatr/templatr-setup/internal/detect"
+	"github.com/templatr/templatr-setup/internal/doctor"
 )
 
+var doctorJSON bool
+
 var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check system status: installed runtimes, versions, and PATH",
-	Long:  `Scans your system for installed runtimes and reports their versions, locations, and PATH status.`,
+	Long: `Scans your system for installed runtimes and reports their versions,
+locations and where they were found, then checks that setup can work here:
+
+  - ~/.templatr and your shell config files are writable
+  - the bin directories in state.json are on PATH in a new shell
+  - nodejs.org, go.dev and api.adoptium.net are reachable
+  - there is enough free disk space
+  - state.json matches what is on disk
+  - the .templatr.toml in the current directory (or --file) is valid
+
+Each check passes, warns or fails with a suggested fix. Use --json for a
+report to attach to an issue. Exits with status 1 if any check fails.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("templatr-setup doctor - System Health Check\n")
-		fmt.Printf("Version: %s (commit: %s, built: %s)\n\n", versionStr, commitStr, dateStr)
-
-		sysInfo := detect.GetSystemInfo()
-		fmt.Printf("OS:           %s\n", sysInfo.OS)
-		fmt.Printf("Architecture: %s\n", sysInfo.Arch)
-		fmt.Printf("Home:         %s\n\n", sysInfo.HomeDir)
-
-		fmt.Println("Runtime Detection:")
-		fmt.Println("─────────────────────────────────────────────────")
-
-		runtimes := detect.ScanRuntimes()
-		for _, r := range runtimes {
-			status := "not found"
-			if r.Installed {
-				status = r.Version
-			}
-			icon := "✗"
-			if r.Installed {
-				icon = "✓"
-			}
-			fmt.Printf("  %s %-12s %s", icon, r.Name, status)
-			if r.Installed && r.Path != "" {
-				fmt.Printf("  (%s)", r.Path)
+		report := doctor.Run(context.Background(), doctor.Options{
+			Version:      versionStr,
+			Commit:       commitStr,
+			Built:        dateStr,
+			ManifestPath: manifestFile,
+		})
+
+		if doctorJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
 			}
-			fmt.Println()
+			fmt.Println(string(data))
+		} else {
+			report.Print(os.Stdout)
+		}
+		if report.Failed() {
+			os.Exit(1)
 		}
-
-		fmt.Println()
 	},
 }
 
 func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
 	rootCmd.AddCommand(doctorCmd)
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// Exit statuses of setup, configure, uninstall and the web UI, so scripts
+// can tell why a command failed.
+const (
+	exitOK          = 0
+	exitError       = 1 // any other failure, such as a bad combination of flags
+	exitManifest    = 2 // the manifest couldn't be loaded or is invalid
+	exitNeedsAction = 3 // setup --dry-run --json: something needs installing
+	exitInstall     = 4 // a runtime or download failed to install
+	exitConfigure   = 5 // the configuration values are invalid or couldn't be written
+	exitCancelled   = 6 // the user declined a prompt or pressed ctrl+c
+	exitNetwork     = 7 // a fetch or download failed, or no version matched a requirement
+)
+
+// exitCodes describes each exit status for --help, in order.
+var exitCodes = []struct {
+	code int
+	desc string
+}{
+	{exitOK, "success"},
+	{exitError, "any other error, such as a bad combination of flags"},
+	{exitManifest, "the manifest couldn't be loaded or is invalid"},
+	{exitNeedsAction, "setup --dry-run --json: something needs installing"},
+	{exitInstall, "a runtime or download failed to install"},
+	{exitConfigure, "the configuration values are invalid or couldn't be written"},
+	{exitCancelled, "cancelled: a prompt was declined or ctrl+c pressed"},
+	{exitNetwork, "network failure, or no version matched a requirement"},
+}
+
+// exit ends the process with a status; tests replace it to see the status.
+var exit = os.Exit
+
+// exitCode returns the exit status for err: exitCancelled and exitNetwork
+// for errors that match install.ErrCancelled, or install.ErrNetwork,
+// install.ErrResolve and manifest.ErrFetch, and otherwise fallback, the
+// status for the step that failed.
+func exitCode(err error, fallback int) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, install.ErrCancelled):
+		return exitCancelled
+	case errors.Is(err, install.ErrNetwork), errors.Is(err, install.ErrResolve), errors.Is(err, manifest.ErrFetch):
+		return exitNetwork
+	}
+	return fallback
+}
+
+// exitCodesHelp is the exit status section at the end of --help.
+func exitCodesHelp() string {
+	var b strings.Builder
+	b.WriteString("\nExit Codes:\n")
+	for _, c := range exitCodes {
+		fmt.Fprintf(&b, "  %d  %s\n", c.code, c.desc)
+	}
+	return b.String()
+}
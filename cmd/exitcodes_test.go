@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// exited is what the test exit panics with, to unwind the command.
+type exited struct{ code int }
+
+// runCommand runs a command func in a fresh project directory whose
+// .templatr.toml is toml (none if empty), with stdin reading input, and
+// returns the status it exited with: exitOK if it returned.
+func runCommand(t *testing.T, toml, input string, run func()) (code int) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Chdir(t.TempDir())
+	if toml != "" {
+		if err := os.WriteFile(".templatr.toml", []byte(toml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A pipe is never a terminal, so setup takes the plain text flow
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(input)
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	exit = func(code int) { panic(exited{code}) }
+	t.Cleanup(func() {
+		os.Stdin, exit = stdin, os.Exit
+		r.Close()
+	})
+
+	defer func() {
+		if p := recover(); p != nil {
+			e, ok := p.(exited)
+			if !ok {
+				panic(p)
+			}
+			code = e.code
+		}
+	}()
+	run()
+	return exitOK
+}
+
+// setFlags sets the command flags for one test, and puts them back to
+// their defaults after it.
+func setFlags(t *testing.T, set func()) {
+	t.Helper()
+	t.Cleanup(func() {
+		manifestFile, dryRun, planJSON, yesFlag, skipPreflight, noGitignore = "", false, false, false, false, false
+		valuesFile, setValues, onlyNewFlag, envNameFlag = "", nil, false, ""
+		skipPackages, skipPostSetup, onlyRuntimes, forceFlag = false, false, nil, false
+	})
+	noGitignore, skipPreflight = true, true
+	set()
+}
+
+// offlineManifest needs a Node.js version no machine has, installed from
+// an empty archives directory, so the plan always needs action and never
+// touches the network.
+func offlineManifest(t *testing.T, node string) string {
+	t.Helper()
+	engine.SetOffline(t.TempDir())
+	t.Cleanup(func() { engine.SetOffline("") })
+	return fmt.Sprintf(`
+[template]
+name = "Exit Codes"
+version = "1.0.0"
+
+[runtimes]
+node = %q
+`, node)
+}
+
+func TestExitCodes_Setup(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest func(t *testing.T) string
+		input    string
+		flags    func()
+		want     int
+	}{
+		{
+			name:     "manifest missing",
+			manifest: func(*testing.T) string { return "" },
+			flags:    func() { yesFlag = true },
+			want:     exitManifest,
+		},
+		{
+			name:     "manifest invalid",
+			manifest: func(*testing.T) string { return "[template]\nversion = \"1.0.0\"\n" },
+			flags:    func() { yesFlag = true },
+			want:     exitManifest,
+		},
+		{
+			name:     "manifest fetch fails",
+			manifest: func(*testing.T) string { return "" },
+			flags:    func() { yesFlag, manifestFile = true, "https://127.0.0.1:1/.templatr.toml" },
+			want:     exitNetwork,
+		},
+		{
+			name:     "dry run needs action",
+			manifest: func(t *testing.T) string { return offlineManifest(t, "21.99.0") },
+			flags:    func() { dryRun, planJSON = true, true },
+			want:     exitNeedsAction,
+		},
+		{
+			name:     "json without dry run",
+			manifest: func(t *testing.T) string { return offlineManifest(t, "21.99.0") },
+			flags:    func() { planJSON = true },
+			want:     exitError,
+		},
+		{
+			name:     "install fails",
+			manifest: func(t *testing.T) string { return offlineManifest(t, "21.99.0") },
+			flags:    func() { yesFlag = true },
+			want:     exitInstall,
+		},
+		{
+			name:     "version can't be resolved",
+			manifest: func(t *testing.T) string { return offlineManifest(t, ">=99") },
+			flags:    func() { yesFlag = true },
+			want:     exitNetwork,
+		},
+		{
+			name:     "user declines",
+			manifest: func(t *testing.T) string { return offlineManifest(t, "21.99.0") },
+			input:    "n\n",
+			flags:    func() {},
+			want:     exitCancelled,
+		},
+		{
+			name:     "only an unknown runtime",
+			manifest: func(t *testing.T) string { return offlineManifest(t, "21.99.0") },
+			flags:    func() { dryRun, onlyRuntimes = true, []string{"ruby"} },
+			want:     exitError,
+		},
+		{
+			name:     "invalid values",
+			manifest: func(t *testing.T) string { return offlineManifest(t, "21.99.0") },
+			flags:    func() { yesFlag, setValues = true, []string{"NOT_DECLARED=1"} },
+			want:     exitConfigure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlags(t, tt.flags)
+			m := tt.manifest(t)
+			if code := runCommand(t, m, tt.input, runSetupCommand); code != tt.want {
+				t.Errorf("setup exited with %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodes_Configure(t *testing.T) {
+	const m = `
+[template]
+name = "Exit Codes"
+version = "1.0.0"
+
+[[env]]
+key = "SITE_URL"
+label = "Site URL"
+type = "url"
+required = true
+`
+	tests := []struct {
+		name  string
+		files string
+		flags func()
+		want  int
+	}{
+		{"manifest missing", "", func() { setValues = []string{"SITE_URL=https://example.com"} }, exitManifest},
+		{"invalid value", m, func() { setValues = []string{"SITE_URL=example"} }, exitConfigure},
+		{"bad flags", m, func() { setValues, onlyNewFlag = []string{"SITE_URL=https://example.com"}, true }, exitError},
+		{"written", m, func() { setValues = []string{"SITE_URL=https://example.com"} }, exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlags(t, tt.flags)
+			if code := runCommand(t, tt.files, "", runConfigure); code != tt.want {
+				t.Errorf("configure exited with %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("disk full"), exitInstall},
+		{fmt.Errorf("failed to install Node.js: %w", install.ErrCancelled), exitCancelled},
+		{fmt.Errorf("failed to install Node.js: %w", install.ErrNetwork), exitNetwork},
+		{fmt.Errorf("%w for Node.js: no LTS versions found", install.ErrResolve), exitNetwork},
+		{fmt.Errorf("%w: 404 Not Found", manifest.ErrFetch), exitNetwork},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err, exitInstall); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+var (
+	initYes   bool
+	initForce bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a starter .templatr.toml for the template in this directory",
+	Long: `Inspects the current directory and writes a starter .templatr.toml:
+
+  - the template name and version from package.json, pyproject.toml,
+    pubspec.yaml or go.mod, or the folder name
+  - runtimes with constraints from engines, requires-python, the pubspec
+    environment, go.mod's go directive or version files like .nvmrc
+  - the package manager and install command from the lockfile
+  - env entries from .env.example, with descriptions from the comments
+    above each key
+
+Each section is shown for confirmation unless --yes is given. An existing
+manifest is only overwritten with --force.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runInit()
+	},
+}
+
+func init() {
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Write every detected section without prompting")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite an existing manifest")
+	rootCmd.AddCommand(initCmd)
+}
+
+func runInit() {
+	path := manifestFile
+	if path == "" {
+		path = manifest.DefaultManifestName
+	}
+	if manifest.IsURL(path) {
+		fmt.Fprintln(os.Stderr, "Error: init writes a local file; pass a path with --file, not a URL.")
+		os.Exit(1)
+	}
+	if _, err := os.Stat(path); err == nil && !initForce {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists. Use --force to overwrite it.\n", path)
+		os.Exit(1)
+	}
+
+	s, err := manifest.NewScaffold(filepath.Dir(path))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if !initYes {
+		reader := bufio.NewReader(os.Stdin)
+		s.SetName(initPrompt(reader, "Template name", s.Template.Name))
+		s.Template.Version = initPrompt(reader, "Version", s.Template.Version)
+		for _, section := range s.Sections() {
+			if section == manifest.SectionTemplate {
+				continue
+			}
+			fmt.Printf("\n%s\n", strings.TrimRight(s.Section(section), "\n"))
+			fmt.Printf("Include [%s]? [Y/n] ", section)
+			answer, _ := reader.ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "" && answer != "y" && answer != "yes" {
+				dropSection(s, section)
+			}
+		}
+		fmt.Println()
+	}
+
+	data := s.Render()
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %s\n", path, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created %s with %s.\n", path, strings.Join(s.Sections(), ", "))
+
+	// Only answers typed at the prompts can make it invalid
+	if m, err := manifest.Parse(data); err == nil {
+		for _, e := range manifest.Validate(m) {
+			fmt.Printf("  ! %s\n", e)
+		}
+	}
+	fmt.Println("Review it, then run 'templatr-setup validate --deep' to check it against the template's files.")
+}
+
+// initPrompt asks for a value, keeping def on an empty answer.
+func initPrompt(reader *bufio.Reader, label, def string) string {
+	fmt.Printf("%s [%s]: ", label, def)
+	answer, _ := reader.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// dropSection leaves a declined section out of the scaffold.
+func dropSection(s *manifest.Scaffold, section string) {
+	switch section {
+	case manifest.SectionRuntimes:
+		s.Runtimes = nil
+	case manifest.SectionPackages:
+		s.Packages = manifest.PackageConfig{}
+	case manifest.SectionEnv:
+		s.Env = nil
+	}
+}
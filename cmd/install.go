@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install runtime[@version] ...",
+	Short: "Install runtimes without a manifest, e.g. node@22",
+	Long: `Installs the given runtimes the same way setup does, without needing a
+.templatr.toml. Each argument is a runtime name, optionally followed by @ and
+an exact version or constraint:
+
+  templatr-setup install node@22
+  templatr-setup install "node@>=20" python@3.12 go
+
+A runtime without a version installs the latest release. Runtimes that are
+already installed and satisfy the requirement are skipped.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runInstallCommand(args)
+	},
+}
+
+func init() {
+	installCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be installed without installing")
+	installCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Skip confirmation prompts")
+	installCmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip the permissions preflight check")
+	installCmd.Flags().BoolVar(&noPath, "no-path", false, "Don't change PATH or set variables such as JAVA_HOME; the lines to add to your shell config are listed at the end")
+	rootCmd.AddCommand(installCmd)
+}
+
+func runInstallCommand(args []string) {
+	specs, err := install.ParseRuntimeSpecs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	log := logger.New()
+	if err := log.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not initialize logger: %s\n", err)
+	} else {
+		defer log.Close()
+		log.Info("templatr-setup %s install %s", versionStr, strings.Join(args, " "))
+	}
+
+	plan, err := engine.BuildPlan(install.RuntimesManifest(specs))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building setup plan: %s\n", err)
+		log.Error("Failed to build plan: %s", err)
+		os.Exit(1)
+	}
+	install.EstimateDownloads(plan)
+
+	printSummary(plan, log)
+	if dryRun {
+		log.Printf("Dry run mode - no changes were made.\n")
+		return
+	}
+	if !plan.NeedsAction() {
+		fmt.Println("Nothing to install - all requirements are satisfied.")
+		return
+	}
+
+	if !skipPreflight {
+		if issues := install.Preflight(plan); len(issues) > 0 {
+			for _, issue := range issues {
+				log.Error("Preflight: %s", issue)
+			}
+			printPreflightIssues(issues)
+			fmt.Fprintln(os.Stderr, "\nFix the issues above, or re-run with --skip-preflight to continue anyway.")
+			os.Exit(1)
+		}
+	}
+
+	offerPathConsolidation(log)
+
+	if !yesFlag {
+		fmt.Print("Proceed with installation? [y/N] ")
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Installation cancelled.")
+			return
+		}
+	}
+
+	fmt.Println()
+	log.Info("Starting installation...")
+
+	results, err := executePlanPlain(plan, log, install.ExecuteOptions{NoPath: noPath})
+	if errors.Is(err, install.ErrCancelled) {
+		fmt.Fprintln(os.Stderr, "\nInstallation cancelled. Partial downloads were removed.")
+		log.Warn("Installation cancelled by user")
+		os.Exit(130)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nError: %s\n", err)
+		log.Error("Installation failed: %s", err)
+		if log.FilePath() != "" {
+			fmt.Fprintf(os.Stderr, "See log file: %s\n", log.FilePath())
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Println("Installation complete!")
+	printInstalled(log, results)
+
+	if log.FilePath() != "" {
+		fmt.Printf("\nLog file: %s\n", log.FilePath())
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+var (
+	listRuntime string
+	listJSON    bool
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the runtimes and files installed by this tool",
+	Long: `Lists every installation recorded in ~/.templatr/state.json: runtime,
+version, size on disk, install date, the template it was installed for and
+its path, followed by the PATH entries and environment variables the tool
+added.
+
+Entries whose path no longer exists, e.g. because it was deleted by hand,
+are flagged "missing on disk". Uninstalling them only updates state.json.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runList()
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVar(&listRuntime, "runtime", "", "Only list installations of one runtime, e.g. node")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print installations as JSON")
+	rootCmd.AddCommand(listCmd)
+}
+
+func runList() {
+	st, err := state.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %s\n", err)
+		os.Exit(1)
+	}
+
+	listing := install.ListManaged(st, listRuntime)
+
+	if listJSON {
+		data, err := json.MarshalIndent(listing, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(listing.Installations) == 0 {
+		if listRuntime != "" {
+			fmt.Printf("No %s installations recorded by templatr-setup.\n", listRuntime)
+		} else {
+			fmt.Println("No runtimes were installed by templatr-setup.")
+		}
+		return
+	}
+
+	listing.Print(os.Stdout)
+	if missing := listing.Missing(); len(missing) > 0 {
+		fmt.Println()
+		fmt.Printf("%d path(s) no longer exist on disk. 'templatr-setup uninstall' removes them from state.json.\n", len(missing))
+	}
+}
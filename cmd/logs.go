@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/term"
 )
 
 var logsCmd = &cobra.Command{
@@ -33,24 +39,111 @@ var logsCmd = &cobra.Command{
 			if err != nil {
 				continue
 			}
-			fmt.Printf("  %d. %s  (%s)\n", i+1, filepath.Base(f), formatSize(info.Size()))
+			fmt.Printf("  %d. %s  (%s)\n", i+1, filepath.Base(f), term.FormatBytes(info.Size()))
 		}
 
 		fmt.Printf("\nLog directory: %s\n", filepath.Dir(files[0]))
-		fmt.Printf("\nTo view the latest log:\n  cat %s\n", files[0])
+		fmt.Printf("\nTo view the latest log:\n  templatr-setup logs show\n")
+	},
+}
+
+var logsShowCmd = &cobra.Command{
+	Use:   "show [n]",
+	Short: "Print a log file",
+	Long:  `Prints the nth most recent log file, as numbered by 'templatr-setup logs' (default: 1, the latest).`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		n := 1
+		if len(args) == 1 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "Error: %q is not a log file number\n", args[0])
+				os.Exit(1)
+			}
+		}
+
+		files, err := logger.RecentLogFiles(n)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading log files: %s\n", err)
+			os.Exit(1)
+		}
+		if len(files) < n {
+			fmt.Fprintf(os.Stderr, "Error: there are only %d log files\n", len(files))
+			os.Exit(1)
+		}
+
+		f, err := os.Open(files[n-1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		io.Copy(os.Stdout, f)
+	},
+}
+
+var logsTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Follow the newest log file",
+	Long: `Prints the newest log file and keeps printing what is appended to it,
+switching to the next file when a run rolls over or a new run starts.
+Press Ctrl+C to stop.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := followLogs(ctx, os.Stdout, 500*time.Millisecond); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
+	logsCmd.AddCommand(logsShowCmd, logsTailCmd)
 	rootCmd.AddCommand(logsCmd)
 }
 
-func formatSize(bytes int64) string {
-	if bytes < 1024 {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	if bytes < 1024*1024 {
-		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+// followLogs copies the newest log file to w as it grows, checking every
+// interval, until ctx is done. When a newer file appears, the rest of the
+// current one is copied before moving on to it.
+func followLogs(ctx context.Context, w io.Writer, interval time.Duration) error {
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	waiting := false
+	for {
+		files, err := logger.RecentLogFiles(1)
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 && (f == nil || files[0] != f.Name()) {
+			if f != nil {
+				io.Copy(w, f)
+				f.Close()
+				fmt.Fprintf(w, "==> %s <==\n", filepath.Base(files[0]))
+			}
+			if f, err = os.Open(files[0]); err != nil {
+				return err
+			}
+		}
+
+		if f != nil {
+			if _, err := io.Copy(w, f); err != nil {
+				return err
+			}
+		} else if !waiting {
+			fmt.Fprintln(w, "No log files yet. Waiting for a run to start...")
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
 	}
-	return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
 }
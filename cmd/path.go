@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+var (
+	tidyDryRun bool
+	tidyYes    bool
+)
+
+var pathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Inspect and tidy the PATH lines in your shell config",
+	Long: `Every PATH entry and environment variable the tool adds to a shell
+config file is a "# templatr-setup:" comment followed by the line it
+marks. state.json records which of them are current.`,
+}
+
+var pathTidyCmd = &cobra.Command{
+	Use:   "tidy",
+	Short: "Remove templatr-setup blocks that state.json doesn't account for",
+	Long: `Older releases added a block to your shell config files for each runtime
+version they installed, so a .bashrc can hold several PATH blocks for Node.js
+of which the shell uses whichever comes last. tidy removes from each file
+the blocks for PATH entries and variables that state.json doesn't record,
+and all but one of the blocks for each one it does. Everything else in the
+files, your own lines and comments between the blocks included, is kept
+byte for byte.
+
+Use --dry-run to only list the blocks.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPathTidy()
+	},
+}
+
+func init() {
+	pathTidyCmd.Flags().BoolVar(&tidyDryRun, "dry-run", false, "List the blocks without removing them")
+	pathTidyCmd.Flags().BoolVarP(&tidyYes, "yes", "y", false, "Remove the blocks without prompting")
+	pathCmd.AddCommand(pathTidyCmd)
+	rootCmd.AddCommand(pathCmd)
+}
+
+func runPathTidy() {
+	st, err := state.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %s\n", err)
+		exit(exitError)
+	}
+	untidy, err := install.UntidyBlocks(st)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+	if len(untidy) == 0 {
+		fmt.Println("Your shell config files match state.json. Nothing to tidy.")
+		return
+	}
+
+	printUntidy(untidy)
+	if tidyDryRun {
+		fmt.Println("Run without --dry-run to remove them.")
+		return
+	}
+	if !tidyYes && !repairConfirm(bufio.NewReader(os.Stdin), "Remove these blocks?") {
+		fmt.Println("Nothing was changed.")
+		return
+	}
+
+	// Under the lock, so a setup writing these files meanwhile isn't undone
+	var removed []install.UntidyBlock
+	err = state.WithLock(func(st *state.State) error {
+		removed, err = install.TidyShellConfigs(st)
+		return err
+	})
+	if errors.Is(err, state.ErrLocked) {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+	fmt.Printf("Removed %d block(s). Open a new terminal to pick up the change.\n", len(removed))
+}
+
+// printUntidy lists the blocks tidy would remove, by file.
+func printUntidy(untidy []install.UntidyBlock) {
+	file := ""
+	for _, u := range untidy {
+		if u.File != file {
+			if file != "" {
+				fmt.Println()
+			}
+			file = u.File
+			fmt.Printf("%s:\n", file)
+		}
+		fmt.Printf("  %s\n", u.Describe())
+		if u.Block.Line != "" {
+			fmt.Printf("    %s\n", u.Block.Line)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/config"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [number]",
+	Short: "Restore an env or config file from a backup",
+	Long: `Lists the backups kept in .templatr-backup/ (see --backup-dir) and
+restores the one you choose over its original file.
+
+configure and setup back up each .env and config file before changing it,
+keeping the 5 newest backups per file. Pass the number shown in the list to
+restore without being asked. The file's current contents are backed up
+before it is restored, so a restore can be undone the same way.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRestore(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runRestore(args []string) {
+	backups, err := config.ListBackups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if len(backups) == 0 {
+		fmt.Printf("No backups found in %s.\n", backupDir)
+		fmt.Println("Backups are made when 'templatr-setup configure' changes an existing file.")
+		return
+	}
+
+	fmt.Println("Backups:")
+	fmt.Println()
+	last := ""
+	for i, b := range backups {
+		if b.File != last {
+			fmt.Printf("  %s\n", b.File)
+			last = b.File
+		}
+		fmt.Printf("    %2d. %s\n", i+1, b.Time.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println()
+
+	var choice string
+	if len(args) > 0 {
+		choice = args[0]
+	} else {
+		fmt.Printf("Restore which backup? [1-%d, Enter to cancel] ", len(backups))
+		input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		choice = strings.TrimSpace(input)
+		if choice == "" {
+			fmt.Println("Nothing restored.")
+			return
+		}
+	}
+
+	n, err := strconv.Atoi(choice)
+	if err != nil || n < 1 || n > len(backups) {
+		fmt.Fprintf(os.Stderr, "Error: %q is not a backup number between 1 and %d\n", choice, len(backups))
+		os.Exit(1)
+	}
+	b := backups[n-1]
+
+	log := logger.New()
+	if err := log.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not initialize logger: %s\n", err)
+	} else {
+		defer log.Close()
+	}
+	config.SetBackupLogger(log)
+
+	if err := config.RestoreBackup(b); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		log.Error("Failed to restore %s from %s: %s", b.File, b.Path, err)
+		os.Exit(1)
+	}
+	log.Info("Restored %s from %s", b.File, b.Path)
+	fmt.Printf("✓ Restored %s from the backup made %s.\n", b.File, b.Time.Format("2006-01-02 15:04:05"))
+}
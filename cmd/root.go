@@ -7,19 +7,35 @@ import (
 	"path/filepath"
 
 	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/config"
+	"github.com/templatr/templatr-setup/internal/engine"
 	"github.com/templatr/templatr-setup/internal/logger"
 	"github.com/templatr/templatr-setup/internal/manifest"
 	"github.com/templatr/templatr-setup/internal/selfupdate"
 	"github.com/templatr/templatr-setup/internal/server"
-	"golang.org/x/term"
+	"github.com/templatr/templatr-setup/internal/term"
 )
 
 var (
-	versionStr string
-	commitStr  string
-	dateStr    string
-	uiFlag     bool
-	webAssets  embed.FS
+	versionStr  string
+	commitStr   string
+	dateStr     string
+	uiFlag      bool
+	devAssets   string
+	listenAddr  string
+	listenPort  int
+	noOpen      bool
+	noGitignore bool
+	offline     bool
+	archivesDir string
+	backupDir   string
+	verbose     bool
+	quiet       bool
+	logFormat   string
+	logMaxSize  int64
+	noColor     bool
+	insecureURL bool
+	webAssets   embed.FS
 )
 
 // SetVersionInfo sets the version info from ldflags.
@@ -45,6 +61,23 @@ through an interactive interface.
 For developers: run in your terminal for an interactive TUI experience.
 For everyone else: double-click the downloaded file to open the visual
 web dashboard in your browser.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := applyLogFlags(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			exit(exitError)
+		}
+		if err := applyOfflineFlags(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			exit(exitError)
+		}
+		if err := applyConfigFlags(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			exit(exitError)
+		}
+		config.SetBackupDir(backupDir)
+		manifest.SetAllowInsecure(insecureURL)
+		term.SetNoColor(noColor)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if uiFlag {
 			launchWebUI()
@@ -64,6 +97,7 @@ func Execute() {
 	// detects whether we were launched from a terminal (cmd/PowerShell) or
 	// double-clicked from Explorer. Must run before shouldLaunchWebUI().
 	attachConsole()
+	loadUserConfig()
 
 	// If double-clicked from Explorer (no terminal), launch the web UI
 	// directly, bypassing cobra.
@@ -74,12 +108,15 @@ func Execute() {
 
 	// Non-blocking update check (runs in background, prints notice after command)
 	updateCh := make(chan *selfupdate.CheckResult, 1)
-	go func() {
-		updateCh <- selfupdate.CheckForUpdate(versionStr)
-	}()
+	if !selfupdate.CheckDisabled() {
+		channel := userCfg.Update.Channel
+		go func() {
+			updateCh <- selfupdate.CheckForUpdate(versionStr, channel)
+		}()
+	}
 
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		exit(exitError)
 	}
 
 	// Show update notice if available
@@ -110,7 +147,85 @@ func shouldLaunchWebUI() bool {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&uiFlag, "ui", false, "Launch the visual web dashboard in your browser")
-	rootCmd.PersistentFlags().StringVarP(&manifestFile, "file", "f", "", "Path to .templatr.toml manifest file")
+	rootCmd.PersistentFlags().StringVar(&listenAddr, "listen", "127.0.0.1", "Address the web dashboard listens on (e.g. 0.0.0.0 to open it from another machine on the LAN)")
+	rootCmd.PersistentFlags().IntVar(&listenPort, "port", 0, "Port for the web dashboard (default: the first free one from 19532)")
+	rootCmd.PersistentFlags().BoolVar(&noOpen, "no-open", false, "Print the web dashboard's URL instead of opening a browser")
+	rootCmd.PersistentFlags().StringVar(&devAssets, "dev-assets", "", "Serve the web UI from this directory (e.g. ./web/dist) instead of the embedded build")
+	rootCmd.PersistentFlags().BoolVar(&noGitignore, "no-gitignore", false, "Don't check that written env files with secrets are git-ignored")
+	rootCmd.PersistentFlags().StringVarP(&manifestFile, "file", "f", "", "Path or https:// URL of the .templatr.toml manifest file")
+	rootCmd.PersistentFlags().BoolVar(&insecureURL, "insecure-manifest", false, "Allow fetching the manifest from a plain http:// URL")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Install runtimes from pre-fetched archives instead of downloading them (requires --archives)")
+	rootCmd.PersistentFlags().StringVar(&archivesDir, "archives", "", "Directory holding the runtime archives to use with --offline")
+	rootCmd.PersistentFlags().StringVar(&backupDir, "backup-dir", config.DefaultBackupDir, "Where copies of env and config files are kept before they are changed")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Show debug messages as well")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only show errors (the log file still records everything)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Plain output: no colours, spinners or redrawn progress (also set by NO_COLOR)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format for the log file and stdout: text or json (one object per line)")
+	rootCmd.PersistentFlags().Int64Var(&logMaxSize, "log-max-size", logger.DefaultMaxFileSize>>20, "MB a log file may reach before the run continues in setup-<time>.1.log (0 for no limit)")
+}
+
+// applyLogFlags sets the format and file size limit of the loggers this
+// run creates from --log-format and --log-max-size, and their level from
+// --verbose or --quiet, or else TEMPLATR_LOG_LEVEL.
+func applyLogFlags() error {
+	format, err := logger.ParseFormat(logFormat)
+	if err != nil {
+		return fmt.Errorf("--log-format: %w", err)
+	}
+	logger.SetDefaultFormat(format)
+	if logMaxSize < 0 {
+		return fmt.Errorf("--log-max-size can't be negative")
+	}
+	logger.SetDefaultMaxFileSize(logMaxSize << 20)
+
+	switch {
+	case verbose && quiet:
+		return fmt.Errorf("--verbose and --quiet can't be used together")
+	case verbose:
+		logger.SetDefaultLevel(logger.DEBUG)
+	case quiet:
+		logger.SetDefaultLevel(logger.ERROR)
+	default:
+		env := os.Getenv("TEMPLATR_LOG_LEVEL")
+		if env == "" {
+			return nil
+		}
+		level, err := logger.ParseLevel(env)
+		if err != nil {
+			return fmt.Errorf("TEMPLATR_LOG_LEVEL: %w", err)
+		}
+		logger.SetDefaultLevel(level)
+	}
+	return nil
+}
+
+// applyOfflineFlags checks --offline and --archives and turns on offline
+// mode for the plans built by this run.
+func applyOfflineFlags() error {
+	if !offline {
+		if archivesDir != "" {
+			return fmt.Errorf("--archives can only be used with --offline")
+		}
+		return nil
+	}
+	if archivesDir == "" {
+		return fmt.Errorf("--offline needs --archives <dir> pointing at the pre-fetched runtime archives")
+	}
+
+	dir, err := filepath.Abs(archivesDir)
+	if err != nil {
+		return fmt.Errorf("invalid archives directory %s: %w", archivesDir, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("archives directory %s: %w", archivesDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("archives directory %s is not a directory", archivesDir)
+	}
+
+	engine.SetOffline(dir)
+	return nil
 }
 
 // hasManifestAvailable checks if a manifest file is available for CLI mode.
@@ -130,21 +245,44 @@ func hasManifestAvailable() bool {
 
 // isTerminal checks if stdin is connected to a terminal.
 func isTerminal() bool {
-	return term.IsTerminal(int(os.Stdin.Fd()))
+	return term.IsTerminal(os.Stdin)
 }
 
 func launchWebUI() {
+	// From a terminal, offer the TUI instead of a browser page that can
+	// only say the frontend was never built.
+	if devAssets == "" && isTerminal() {
+		if _, err := server.CheckAssets(webAssets); err != nil {
+			if server.PromptTerminalFallback(os.Stdin, os.Stdout) {
+				if !hasManifestAvailable() {
+					printNoManifestHelp()
+					return
+				}
+				runSetupCommand()
+				return
+			}
+		}
+	}
+
 	log := logger.New()
 	if err := log.Init(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not initialize logger: %s\n", err)
 	} else {
 		defer log.Close()
 	}
+	config.SetBackupLogger(log)
 
 	srv := server.New(webAssets, log, manifestFile)
+	if devAssets != "" {
+		srv.SetDevAssets(devAssets)
+	}
+	srv.SetListen(listenAddr, listenPort)
+	srv.SetOpenBrowser(!noOpen)
+	srv.SetGitignoreCheck(!noGitignore)
+	srv.SetToolVersion(versionStr)
 	if err := srv.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		exit(exitCode(err, exitError))
 	}
 }
 
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/config"
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/secrets"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage env values kept in the OS credential store",
+	Long: `Env vars with store = "keychain" in .templatr.toml are saved to the OS
+credential store by configure (Keychain on macOS, Credential Manager on
+Windows, the Secret Service through secret-tool on Linux) instead of the
+env file. They are named templatr-setup/<template>/<key>.`,
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the template's stored env vars",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		m, store := openSecrets()
+		vars := config.StoredEnvVars(m)
+		if len(vars) == 0 {
+			fmt.Println("No env vars in the manifest use store = \"keychain\".")
+			return
+		}
+		for _, env := range vars {
+			status := "stored"
+			if _, err := store.Get(config.SecretName(m, env)); errors.Is(err, secrets.ErrNotFound) {
+				status = "not stored"
+			} else if err != nil {
+				status = err.Error()
+			}
+			fmt.Printf("  %-30s %s (%s)\n", env.Key, status, config.SecretName(m, env))
+		}
+	},
+}
+
+var secretsGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print a stored env var's value",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		m, store := openSecrets()
+		env := storedEnvVar(m, args[0])
+		value, err := store.Get(config.SecretName(m, env))
+		if errors.Is(err, secrets.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Error: %s is not stored. Run 'templatr-setup configure' to set it.\n", env.Key)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+var secretsDeleteCmd = &cobra.Command{
+	Use:   "delete KEY",
+	Short: "Remove a stored env var's value",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		m, store := openSecrets()
+		env := storedEnvVar(m, args[0])
+		if err := store.Delete(config.SecretName(m, env)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted %s.\n", config.SecretName(m, env))
+	},
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsListCmd, secretsGetCmd, secretsDeleteCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
+
+// openSecrets loads the manifest and the credential store, exiting if
+// either is unavailable.
+func openSecrets() (*manifest.Manifest, secrets.Store) {
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	store, err := config.OpenSecretStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	return m, store
+}
+
+// storedEnvVar returns the manifest's var with store = "keychain" named
+// key, exiting if there is none.
+func storedEnvVar(m *manifest.Manifest, key string) manifest.EnvVar {
+	vars := config.StoredEnvVars(m)
+	i := slices.IndexFunc(vars, func(env manifest.EnvVar) bool { return env.Key == key })
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s is not an env var with store = \"keychain\" in the manifest.\n", key)
+		os.Exit(1)
+	}
+	return vars[i]
+}
@@ -2,24 +2,39 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/config"
 	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/history"
 	"github.com/templatr/templatr-setup/internal/install"
 	"github.com/templatr/templatr-setup/internal/logger"
 	"github.com/templatr/templatr-setup/internal/manifest"
 	"github.com/templatr/templatr-setup/internal/packages"
+	"github.com/templatr/templatr-setup/internal/state"
+	"github.com/templatr/templatr-setup/internal/term"
 	"github.com/templatr/templatr-setup/internal/tui"
 )
 
 var (
-	manifestFile string
-	dryRun       bool
-	yesFlag      bool
+	manifestFile  string
+	dryRun        bool
+	yesFlag       bool
+	skipPreflight bool
+	planJSON      bool
+	skipPackages  bool
+	skipPostSetup bool
+	noPath        bool
+	onlyRuntimes  []string
+	forceFlag     bool
 )
 
 var setupCmd = &cobra.Command{
@@ -29,46 +44,93 @@ var setupCmd = &cobra.Command{
 (or a specified path) and installs all required runtimes and packages.
 
 After installation, optionally runs the configure step to set up
-.env and site.ts files through an interactive form.`,
+.env and site.ts files through an interactive form. With --yes and
+--values or --set, as configure takes them, the configuration is
+written without prompting and the whole setup runs unattended.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runSetupCommand()
 	},
 }
 
 func init() {
+	// setup's --help ends with the exit statuses scripts check for
+	setupCmd.SetHelpTemplate(setupCmd.HelpTemplate() + exitCodesHelp())
 	setupCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be installed without installing")
 	setupCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Skip confirmation prompts")
+	setupCmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip the permissions preflight check")
+	setupCmd.Flags().BoolVar(&planJSON, "json", false, "With --dry-run, print the plan as JSON and exit with status 3 if anything needs installing")
+	setupCmd.Flags().BoolVar(&skipPackages, "skip-packages", false, "Install the runtimes but not the packages; the commands to run are listed at the end")
+	setupCmd.Flags().BoolVar(&skipPostSetup, "skip-post-setup", false, "Don't run the post-setup commands; they are listed at the end")
+	setupCmd.Flags().BoolVar(&noPath, "no-path", false, "Don't change PATH or set variables such as JAVA_HOME; the lines to add to your shell config are listed at the end")
+	setupCmd.Flags().StringSliceVar(&onlyRuntimes, "only", nil, "Set up only this runtime from the manifest (repeatable)")
+	setupCmd.Flags().BoolVar(&forceFlag, "force", false, "Reinstall the runtimes even if what is installed satisfies the manifest")
+	addValuesFlags(setupCmd)
 	rootCmd.AddCommand(setupCmd)
 }
 
 func runSetupCommand() {
-	// Initialize logger
+	if planJSON && !dryRun {
+		fmt.Fprintln(os.Stderr, "Error: --json can only be used with --dry-run")
+		exit(exitError)
+	}
+
+	// Initialize logger. With --json stdout is left to the plan; the log
+	// file still records everything.
 	log := logger.New()
+	if planJSON {
+		log.SetLevel(logger.ERROR)
+	}
 	if err := log.Init(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not initialize logger: %s\n", err)
 	} else {
 		defer log.Close()
 		log.Info("templatr-setup %s started", versionStr)
 	}
+	config.SetBackupLogger(log)
 
 	// Load manifest
 	m, err := manifest.Load(manifestFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		log.Error("Failed to load manifest: %s", err)
-		os.Exit(1)
+		exit(exitCode(err, exitManifest))
+	}
+	if m.Source != "" {
+		log.Info("Fetched manifest from %s", manifest.Redact(m.Source))
 	}
 	log.Info("Loaded manifest: %s (%s)", m.Template.Name, m.Template.Tier)
 
+	checkToolVersion(m, log)
+
 	// Validate manifest
 	errs := manifest.Validate(m)
-	if len(errs) > 0 {
+	if manifest.HasErrors(errs) {
 		fmt.Fprintln(os.Stderr, "Manifest validation errors:")
 		for _, e := range errs {
 			fmt.Fprintf(os.Stderr, "  - %s\n", e)
 			log.Error("Validation: %s", e)
 		}
-		os.Exit(1)
+		exit(exitManifest)
+	}
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", e)
+		log.Warn("Validation: %s", e)
+	}
+
+	// Configuration values given up front are checked before anything is
+	// installed, and written after the packages without prompting
+	given, err := loadValues()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitConfigure)
+	}
+	if given != nil && !yesFlag && !dryRun {
+		fmt.Fprintln(os.Stderr, "Error: --values and --set need --yes")
+		exit(exitError)
+	}
+	var resolved *config.Resolved
+	if given != nil {
+		resolved = resolveValues(m, given, config.ReadExistingEnv(m), "", log)
 	}
 
 	// Build plan
@@ -76,41 +138,131 @@ func runSetupCommand() {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building setup plan: %s\n", err)
 		log.Error("Failed to build plan: %s", err)
-		os.Exit(1)
+		exit(exitCode(err, exitManifest))
+	}
+	if err := plan.Only(onlyRuntimes); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --only: %s\n", err)
+		exit(exitError)
 	}
+	if forceFlag {
+		plan.Force()
+	}
+	install.EstimateDownloads(plan)
 
 	// Dry run: print summary and exit
+	if dryRun && planJSON {
+		if err := engine.WritePlanJSON(os.Stdout, plan); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			exit(exitError)
+		}
+		if plan.NeedsAction() {
+			exit(exitNeedsAction)
+		}
+		return
+	}
 	if dryRun {
-		engine.PrintSummary(plan)
-		fmt.Println("Dry run mode - no changes were made.")
+		printSummary(plan, log)
+		log.Printf("Dry run mode - no changes were made.\n")
 		return
 	}
 
-	// Interactive TUI mode when running in a terminal
-	if isTerminal() {
-		tuiModel := tui.New(plan, log, yesFlag)
+	// Permissions preflight: fail before anything is downloaded
+	if !skipPreflight && plan.NeedsAction() {
+		if issues := install.Preflight(plan); len(issues) > 0 {
+			for _, issue := range issues {
+				log.Error("Preflight: %s", issue)
+			}
+			printPreflightIssues(issues)
+			fmt.Fprintln(os.Stderr, "\nFix the issues above, or re-run with --skip-preflight to continue anyway.")
+			exit(exitInstall)
+		}
+	}
+
+	offerPathConsolidation(log)
+
+	opts := packages.SetupOptions{
+		SkipPackages:   skipPackages,
+		SkipPostSetup:  skipPostSetup,
+		ExecuteOptions: install.ExecuteOptions{NoPath: noPath},
+	}
+
+	// Interactive TUI mode when running in a terminal, unless the setup is
+	// meant to run unattended
+	if isTerminal() && resolved == nil {
+		report := history.NewReport(plan, "tui")
+		tuiModel := tui.New(plan, log, opts, yesFlag, !noGitignore)
 		p := tea.NewProgram(tuiModel, tea.WithAltScreen())
-		if _, err := p.Run(); err != nil {
+		final, err := p.Run()
+		tuiModel.Close()
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "TUI error: %s\n", err)
 			log.Error("TUI error: %s", err)
-			os.Exit(1)
+			exit(exitError)
+		}
+		if fm, ok := final.(tui.Model); ok {
+			results, started, installErr := fm.Outcome()
+			if started {
+				report.AddResults(plan, results)
+				report.Finish(installErr)
+				recordHistory(report, log)
+			}
+			if fm.Succeeded() {
+				recordSnapshot(m, log)
+			}
+			// Quitting before the install started declines it
+			if !started {
+				exit(exitCancelled)
+			}
+			if installErr != nil {
+				exit(exitCode(installErr, exitInstall))
+			}
 		}
 		return
 	}
 
 	// Fallback: non-interactive plain text mode (CI, pipes, etc.)
-	runSetupPlainText(plan, m, log)
+	runSetupPlainText(plan, m, opts, resolved, log)
+}
+
+// checkToolVersion exits if the manifest's min_tool_version is newer than
+// this build. Development builds only get a warning.
+func checkToolVersion(m *manifest.Manifest, log *logger.Logger) {
+	err := m.Meta.CheckToolVersion(versionStr)
+	if errors.Is(err, manifest.ErrDevBuild) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		log.Warn("%s", err)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		log.Error("%s", err)
+		exit(exitManifest)
+	}
 }
 
 // runSetupPlainText is the non-TUI fallback for non-interactive environments.
-func runSetupPlainText(plan *engine.SetupPlan, m *manifest.Manifest, log *logger.Logger) {
-	fmt.Println("templatr-setup - Template dependency installer")
-	fmt.Printf("Version: %s\n\n", versionStr)
+// Resolved configuration values, when given, are written once the packages
+// are installed. The steps opts skips are listed at the end with the
+// commands to run instead.
+func runSetupPlainText(plan *engine.SetupPlan, m *manifest.Manifest, opts packages.SetupOptions, resolved *config.Resolved, log *logger.Logger) {
+	log.Printf("templatr-setup - Template dependency installer\n")
+	log.Printf("Version: %s\n\n", versionStr)
 
-	engine.PrintSummary(plan)
+	// The summary is the confirmation prompt's context, so it is only
+	// left out when nothing will be asked
+	if log.Enabled(logger.INFO) || !yesFlag {
+		printSummary(plan, log)
+	}
+	report := history.NewReport(plan, "plain")
 
 	if !plan.NeedsAction() {
-		fmt.Println("Nothing to install - all requirements are satisfied.")
+		log.Printf("Nothing to install - all requirements are satisfied.\n")
+		report.Finish(nil)
+		recordHistory(report, log)
+		if resolved != nil {
+			writeSetupConfig(m, resolved, log)
+			recordSnapshot(m, log)
+		}
 		return
 	}
 
@@ -122,65 +274,307 @@ func runSetupPlainText(plan *engine.SetupPlan, m *manifest.Manifest, log *logger
 		answer = strings.TrimSpace(strings.ToLower(answer))
 		if answer != "y" && answer != "yes" {
 			fmt.Println("Installation cancelled.")
-			return
+			exit(exitCancelled)
 		}
 	}
 
-	fmt.Println()
+	log.Printf("\n")
 	log.Info("Starting installation...")
 
-	progress := func(downloaded, total int64) {
-		if total > 0 {
-			pct := float64(downloaded) / float64(total) * 100
-			fmt.Printf("\r  Downloading... %.0f%% (%d / %d MB)", pct, downloaded/(1024*1024), total/(1024*1024))
-		} else {
-			fmt.Printf("\r  Downloading... %d MB", downloaded/(1024*1024))
-		}
+	results, err := executePlanPlain(plan, log, opts.ExecuteOptions)
+	report.AddResults(plan, results)
+	report.Finish(err)
+	recordHistory(report, log)
+	if errors.Is(err, install.ErrCancelled) {
+		fmt.Fprintln(os.Stderr, "\nInstallation cancelled. Partial downloads were removed.")
+		log.Warn("Installation cancelled by user")
+		exit(exitCancelled)
 	}
-
-	results, err := install.ExecutePlan(plan, log, progress)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\nError: %s\n", err)
 		log.Error("Installation failed: %s", err)
 		if log.FilePath() != "" {
 			fmt.Fprintf(os.Stderr, "See log file: %s\n", log.FilePath())
 		}
-		os.Exit(1)
+		exit(exitCode(err, exitInstall))
 	}
 
-	fmt.Println()
+	log.Printf("\n")
 
-	if err := packages.RunGlobalInstalls(m, log); err != nil {
-		log.Warn("Global install issues: %s", err)
+	// ctrl+c stops a wedged package command along with everything it started
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	out := commandOutput(log)
+
+	if err := packages.EnsureManager(ctx, plan, log, out); err != nil {
+		exitIfCancelled(err, log)
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
 	}
 
-	if m.Packages.InstallCommand != "" {
-		log.Info("Running: %s", m.Packages.InstallCommand)
-		if err := packages.RunInstall(m, log); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	if opts.SkipPackages {
+		log.Info("Skipping package installation")
+	} else {
+		if err := packages.RunGlobalInstalls(ctx, plan, log, out); err != nil {
+			exitIfCancelled(err, log)
+			log.Warn("Global install issues: %s", err)
+		}
+
+		if plan.Packages != nil && len(plan.Packages.Steps) > 0 {
+			if err := packages.RunInstall(ctx, plan, log, out); err != nil {
+				exitIfCancelled(err, log)
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+			}
 		}
 	}
 
-	fmt.Println()
-	fmt.Println("Installation complete!")
-	for _, r := range results {
-		fmt.Printf("  ✓ %s %s → %s\n", r.Runtime, r.Version, r.InstallPath)
+	log.Printf("\nInstallation complete!\n")
+	printInstalled(log, results)
+
+	if resolved != nil {
+		writeSetupConfig(m, resolved, log)
 	}
 
-	if len(m.PostSetup.Commands) > 0 {
-		fmt.Println()
+	if len(m.PostSetup.Commands) > 0 && opts.SkipPostSetup {
+		log.Info("Skipping post-setup commands")
+	} else if len(m.PostSetup.Commands) > 0 {
+		log.Printf("\n")
 		log.Info("Running post-setup commands...")
-		if err := packages.RunPostSetup(m, log); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		results, err := packages.RunPostSetup(ctx, m, config.ConfiguredEnv(m), log, out)
+		if err != nil {
+			exitIfCancelled(err, log)
+			failed := results.Failed()
+			fmt.Fprintf(os.Stderr, "\nWarning: %d of %d post-setup commands did not complete:\n", len(failed), len(results))
+			for _, r := range failed {
+				fmt.Fprintf(os.Stderr, "  ✗ %s\n", r.Err)
+			}
 		}
 	}
 
+	printManualSteps(log, opts.ManualSteps(plan))
+
 	if m.PostSetup.Message != "" {
-		fmt.Println()
-		fmt.Println(strings.TrimSpace(m.PostSetup.Message))
+		log.Printf("\n%s\n", strings.TrimSpace(m.PostSetup.Message))
 	}
 
+	recordSnapshot(m, log)
+
 	if log.FilePath() != "" {
-		fmt.Printf("\nLog file: %s\n", log.FilePath())
+		log.Printf("\nLog file: %s\n", log.FilePath())
+	}
+}
+
+// writeSetupConfig writes the configuration values given to setup.
+func writeSetupConfig(m *manifest.Manifest, resolved *config.Resolved, log *logger.Logger) {
+	log.Printf("\n")
+	log.Info("Writing the configuration...")
+	writeResolved(m, resolved, "", log)
+}
+
+// commandOutput is where package and post-setup command output is shown:
+// stdout, or nowhere with --quiet, though the log file still records it.
+// With --log-format json the logger writes it to stdout as records.
+func commandOutput(log *logger.Logger) io.Writer {
+	if !log.Enabled(logger.INFO) || log.Format() == logger.FormatJSON {
+		return nil
+	}
+	return os.Stdout
+}
+
+// exitIfCancelled exits if err is from ctrl+c stopping a package or
+// post-setup command.
+func exitIfCancelled(err error, log *logger.Logger) {
+	if !errors.Is(err, install.ErrCancelled) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nSetup cancelled: %s\n", err)
+	log.Warn("Setup cancelled by user: %s", err)
+	exit(exitCancelled)
+}
+
+// executePlanPlain runs the plan with plain text download progress.
+// ctrl+c stops the download in progress instead of killing the process
+// mid-extract, so partial files get cleaned up.
+func executePlanPlain(plan *engine.SetupPlan, log *logger.Logger, opts install.ExecuteOptions) ([]install.InstallResult, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return install.ExecutePlan(ctx, plan, log, plainProgress(log, term.Rich(os.Stdout)), opts)
+}
+
+// plainProgress returns the download progress for plain text mode. On a
+// terminal it is redrawn in place with \r; otherwise, as in CI logs, a line
+// is printed for every 10% (or 10 MB when the size is unknown).
+func plainProgress(log *logger.Logger, redraw bool) install.EventFunc {
+	const mb = 1024 * 1024
+	last := int64(-1) // the 10% or 10 MB step last printed
+	return func(ev install.ProgressEvent) {
+		if ev.Phase != install.PhaseDownloading {
+			return
+		}
+		if !redraw {
+			step := ev.Done / (10 * mb)
+			if ev.Total > 0 {
+				step = ev.Done * 10 / ev.Total
+			}
+			if step == last {
+				return
+			}
+			last = step
+		}
+		prefix, suffix := "\r", ""
+		if !redraw {
+			prefix, suffix = "", "\n"
+		}
+		if ev.Total > 0 {
+			pct := float64(ev.Done) / float64(ev.Total) * 100
+			log.Printf("%s  Downloading... %.0f%% (%d / %d MB)%s", prefix, pct, ev.Done/mb, ev.Total/mb, suffix)
+		} else {
+			log.Printf("%s  Downloading... %d MB%s", prefix, ev.Done/mb, suffix)
+		}
+	}
+}
+
+// printInstalled lists the installed runtimes with their paths, followed by
+// the environment changes made for them.
+func printInstalled(log *logger.Logger, results []install.InstallResult) {
+	for _, r := range results {
+		log.Printf("  ✓ %s %s → %s\n", r.Runtime, r.Version, r.InstallPath)
+	}
+	printEnvChanges(log, install.SummarizeEnvChanges(results))
+}
+
+// recordHistory appends the run to the local history used by 'stats'.
+func recordHistory(report *history.SetupReport, log *logger.Logger) {
+	if err := history.Append(report); err != nil {
+		log.Warn("Could not record setup history: %s", err)
+	}
+}
+
+// recordSnapshot saves the applied manifest so the next run can show what changed.
+func recordSnapshot(m *manifest.Manifest, log *logger.Logger) {
+	if err := state.SaveSnapshot(m); err != nil {
+		log.Warn("Could not record setup snapshot: %s", err)
+	}
+}
+
+// printManualSteps lists the skipped setup steps with the commands to run
+// in their place.
+func printManualSteps(log *logger.Logger, steps []packages.ManualStep) {
+	if len(steps) == 0 {
+		return
+	}
+
+	log.Printf("\nSkipped steps - run these yourself from the project directory:\n")
+	for _, step := range steps {
+		log.Printf("  %s:\n", step.Name)
+		for _, cmd := range step.Commands {
+			log.Printf("    %s\n", cmd)
+		}
+	}
+}
+
+// printEnvChanges prints the shell config and env var modifications made
+// during install, with commands to apply them to the current shell, and
+// the lines --no-path left for the user to add.
+func printEnvChanges(log *logger.Logger, summary install.EnvSummary) {
+	printManualEnv(log, summary)
+	if len(summary.Changes) == 0 {
+		return
+	}
+
+	log.Printf("\nEnvironment changes:\n")
+	for _, line := range summary.Lines() {
+		log.Printf("  %s\n", line)
+	}
+	if !summary.Modified() {
+		log.Printf("  No shell configuration changes were needed.\n")
+	}
+	if len(summary.Activation) > 0 {
+		log.Printf("\nTo use them in this terminal (%s), run:\n", summary.Shell)
+		for _, cmd := range summary.Activation {
+			log.Printf("  %s\n", cmd)
+		}
+		log.Printf("New terminals pick them up automatically.\n")
+	}
+}
+
+// printManualEnv prints the PATH and env var lines --no-path left out, for
+// the user to add to their shell config.
+func printManualEnv(log *logger.Logger, summary install.EnvSummary) {
+	if len(summary.Manual) == 0 {
+		return
+	}
+	log.Printf("\nPATH was left unchanged (--no-path). Add these lines to %s:\n", summary.ManualFile)
+	for _, line := range summary.Manual {
+		log.Printf("  %s\n", line)
+	}
+}
+
+// offerPathConsolidation finds PATH entries left by earlier releases, which
+// added every installed version's bin dir, and offers to replace them with
+// one stable current/bin entry per runtime. Without --yes it only asks at
+// a terminal, as it rewrites shell config files, and the default is no.
+func offerPathConsolidation(log *logger.Logger) {
+	if !yesFlag && !isTerminal() {
+		return
+	}
+	st, err := state.Load()
+	if err != nil {
+		return
+	}
+	stale := install.StalePathEntries(st)
+	if len(stale) == 0 {
+		return
+	}
+
+	fmt.Println("Your PATH has entries for specific runtime versions from earlier setups:")
+	for _, mod := range stale {
+		fmt.Printf("  %s\n", mod.Value)
+	}
+	if !yesFlag {
+		fmt.Print("Replace them with one stable entry per runtime? [y/N] ")
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Println()
+			return
+		}
+	}
+
+	// Under the lock, so a run recording its install meanwhile isn't lost
+	var kept []string
+	err = state.WithLock(func(st *state.State) error {
+		kept, err = install.ConsolidatePath(st, log)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not consolidate PATH entries: %s\n", err)
+		log.Warn("PATH consolidation failed: %s", err)
+		return
+	}
+	for _, dir := range kept {
+		log.Printf("  ✓ %s\n", dir)
+	}
+	log.Printf("\n")
+}
+
+// printSummary prints the plan's summary table. With --log-format json its
+// lines go through log as records, so stdout stays one JSON object per line.
+func printSummary(plan *engine.SetupPlan, log *logger.Logger) {
+	if log.Format() != logger.FormatJSON {
+		engine.PrintSummary(plan)
+		return
+	}
+	var b strings.Builder
+	engine.FprintSummary(&b, plan)
+	log.Printf("%s", b.String())
+}
+
+// printPreflightIssues prints each preflight problem with its remediation.
+func printPreflightIssues(issues []install.PreflightIssue) {
+	fmt.Fprintln(os.Stderr, "Preflight check failed:")
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", issue)
+		fmt.Fprintf(os.Stderr, "    → %s\n", issue.Fix)
 	}
 }
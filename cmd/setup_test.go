@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestPrintSummary_JSON(t *testing.T) {
+	m := &manifest.Manifest{}
+	m.Template.Name = "JSON Template"
+	plan := &engine.SetupPlan{Manifest: m, Runtimes: []engine.RuntimePlan{
+		{Name: "node", DisplayName: "Node.js", RequiredVersion: ">=20", Action: engine.ActionInstall},
+	}}
+	log := logger.New()
+	log.SetFormat(logger.FormatJSON)
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	printSummary(plan, log)
+	w.Close()
+	os.Stdout = stdout
+	out, _ := io.ReadAll(r)
+
+	if !strings.Contains(string(out), "JSON Template") {
+		t.Errorf("summary missing from output:\n%s", out)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("stdout line %q is not a JSON record", line)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+	"github.com/templatr/templatr-setup/internal/userconfig"
+)
+
+var (
+	repairDryRun bool
+	repairYes    bool
+	rehomeFrom   string
+)
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Inspect and repair the installation state file",
+	Long: `The state file (~/.templatr/state.json) records every runtime the tool
+installed and every PATH entry and environment variable it added, so that
+uninstall can reverse them.`,
+}
+
+var stateRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Reconcile state.json with what is actually on disk",
+	Long: `Cross-checks state.json against the filesystem:
+
+  - Installations whose directory was deleted by hand are removed from
+    state.json, and optionally their PATH and env var lines from your
+    shell config (or the user environment on Windows).
+  - A current link whose version is gone is repointed at the newest
+    remaining version, or removed if none is left.
+  - Version directories in ~/.templatr/runtimes/ that state.json doesn't
+    know about can be adopted (so uninstall and clean manage them) or
+    deleted.
+
+Use --dry-run to only report the differences.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStateRepair()
+	},
+}
+
+var stateRehomeCmd = &cobra.Command{
+	Use:   "rehome <newdir>",
+	Short: "Move the installed runtimes to another directory",
+	Long: `Moves the runtimes directory (~/.templatr/runtimes/ or runtimes_dir) to
+newdir, e.g. a data volume, and rewrites everything that points into it:
+the paths in state.json, the current links, and the PATH and environment
+variable lines in your shell config (or the user environment on Windows).
+runtimes_dir in ~/.templatr/config.toml is then set to newdir so later
+runs install there.
+
+If you already moved the directory yourself, rehome only rewrites the
+paths. The old directory is taken from state.json; use --from if it can't
+tell.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runStateRehome(args[0])
+	},
+}
+
+func init() {
+	stateRehomeCmd.Flags().StringVar(&rehomeFrom, "from", "", "The runtimes directory to move from (default: the one state.json records)")
+	stateCmd.AddCommand(stateRehomeCmd)
+	stateRepairCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "Report differences without changing anything")
+	stateRepairCmd.Flags().BoolVarP(&repairYes, "yes", "y", false, "Repair without prompting: prune missing entries and their PATH lines, adopt orphans")
+	stateCmd.AddCommand(stateRepairCmd)
+	rootCmd.AddCommand(stateCmd)
+}
+
+func runStateRepair() {
+	st, err := state.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading state: %s\n", err)
+		os.Exit(1)
+	}
+	base, err := install.RuntimesDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	drift, err := state.Reconcile(st, base)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if drift.Empty() {
+		fmt.Println("state.json matches what is on disk. Nothing to repair.")
+		return
+	}
+
+	printDrift(drift)
+	if repairDryRun {
+		fmt.Println("Run without --dry-run to repair.")
+		return
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	prune := len(drift.Missing) > 0 || len(drift.Repoint) > 0 || len(drift.DanglingLinks) > 0
+	if prune && !repairYes {
+		prune = repairConfirm(reader, "Remove the missing entries from state.json?")
+	}
+	mods := prune && (len(drift.PathMods) > 0 || len(drift.EnvMods) > 0)
+	if mods && !repairYes {
+		mods = repairConfirm(reader, "Also remove their PATH and environment variable lines?")
+	}
+
+	var adopt, remove []state.Orphan
+	for _, o := range drift.Orphans {
+		if repairYes {
+			adopt = append(adopt, o)
+			continue
+		}
+		fmt.Printf("%s %s (%s): [a]dopt into state.json, [d]elete from disk, or [s]kip? [a/d/S] ", o.Runtime, o.Version, o.Path)
+		answer, _ := reader.ReadString('\n')
+		switch strings.TrimSpace(strings.ToLower(answer)) {
+		case "a", "adopt":
+			adopt = append(adopt, o)
+		case "d", "delete":
+			remove = append(remove, o)
+		}
+	}
+	fmt.Println()
+
+	err = state.WithLock(func(st *state.State) error {
+		if prune {
+			st.Prune(drift, mods)
+		}
+		for _, o := range adopt {
+			st.Adopt(o)
+		}
+		return nil
+	})
+	if errors.Is(err, state.ErrLocked) {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not save state: %s\n", err)
+		os.Exit(1)
+	}
+
+	if prune {
+		for _, link := range drift.Repoint {
+			if _, err := install.LinkCurrent(link.Target); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: could not repoint %s: %s\n", link.Path, err)
+			} else {
+				fmt.Printf("  %s now points at %s\n", link.Path, link.Target)
+			}
+		}
+		for _, link := range drift.DanglingLinks {
+			if err := install.RemoveCurrent(link.Path); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: %s\n", err)
+			}
+		}
+		fmt.Printf("  Removed %d missing installation(s) from state.json\n", len(drift.Missing))
+	}
+	if mods {
+		for _, mod := range drift.PathMods {
+			if err := install.RemoveFromPath(mod); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: could not remove PATH entry %s: %s\n", mod.Value, err)
+			}
+		}
+		for _, mod := range drift.EnvMods {
+			if err := install.RemoveEnvVar(mod); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: could not remove %s: %s\n", mod.Name, err)
+			}
+		}
+		fmt.Printf("  Removed %d PATH entr(ies) and %d environment variable(s)\n", len(drift.PathMods), len(drift.EnvMods))
+	}
+	for _, o := range adopt {
+		fmt.Printf("  Adopted %s %s\n", o.Runtime, o.Version)
+	}
+	for _, o := range remove {
+		if err := os.RemoveAll(o.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: could not delete %s: %s\n", o.Path, err)
+		} else {
+			fmt.Printf("  Deleted %s\n", o.Path)
+		}
+	}
+}
+
+func runStateRehome(newDir string) {
+	to, err := filepath.Abs(newDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid directory %s: %s\n", newDir, err)
+		exit(exitError)
+	}
+
+	log := logger.New()
+	if err := log.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not initialize logger: %s\n", err)
+	} else {
+		defer log.Close()
+	}
+
+	var result *install.RehomeResult
+	err = state.WithLock(func(st *state.State) error {
+		from := rehomeFrom
+		if from == "" {
+			base, err := st.RuntimesBase()
+			if err != nil {
+				return fmt.Errorf("%w; say which to move with --from", err)
+			}
+			if base == "" {
+				if base, err = install.RuntimesDir(); err != nil {
+					return err
+				}
+			}
+			from = base
+		} else if from, err = filepath.Abs(from); err != nil {
+			return fmt.Errorf("invalid --from directory %s: %w", rehomeFrom, err)
+		}
+		result, err = install.Rehome(st, from, to, log)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+
+	if !result.Moved {
+		fmt.Printf("Runtimes already moved to %s, rewrote the paths from %s\n", result.To, result.From)
+	}
+	fmt.Printf("  Updated %d installation(s), %d PATH entr(ies) and %d environment variable(s)\n", result.Installations, result.PathEntries, result.EnvVars)
+
+	path, err := userconfig.Path()
+	if err == nil {
+		err = userconfig.SetFile(path, "runtimes_dir", result.To)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not set runtimes_dir: %s\n", err)
+		fmt.Fprintf(os.Stderr, "Run 'templatr-setup config set runtimes_dir %s' so later runs use it.\n", result.To)
+		exit(exitError)
+	}
+	fmt.Printf("  Set runtimes_dir = %s in %s\n", result.To, path)
+	if env := os.Getenv(userconfig.EnvVar("runtimes_dir")); env != "" && env != result.To {
+		fmt.Printf("  Note: TEMPLATR_RUNTIMES_DIR is set to %s and overrides it; update or unset it.\n", env)
+	}
+	fmt.Println("Open a new terminal for the PATH changes to take effect.")
+}
+
+// printDrift lists the differences Reconcile found.
+func printDrift(d state.Drift) {
+	if len(d.Missing) > 0 {
+		fmt.Println("Recorded in state.json but missing on disk:")
+		for _, inst := range d.Missing {
+			fmt.Printf("  ✗ %s %s  (%s)\n", inst.Runtime, inst.Version, inst.Path)
+		}
+		fmt.Println()
+	}
+	if len(d.Repoint) > 0 || len(d.DanglingLinks) > 0 {
+		fmt.Println("Current links to a deleted version:")
+		for _, link := range d.Repoint {
+			fmt.Printf("  ! %s → will point at %s\n", link.Path, link.Target)
+		}
+		for _, link := range d.DanglingLinks {
+			fmt.Printf("  ✗ %s (no version left)\n", link.Path)
+		}
+		fmt.Println()
+	}
+	if len(d.PathMods) > 0 || len(d.EnvMods) > 0 {
+		fmt.Println("PATH entries and environment variables pointing at them:")
+		for _, mod := range d.PathMods {
+			fmt.Printf("  ✗ %s\n", mod.Value)
+		}
+		for _, mod := range d.EnvMods {
+			fmt.Printf("  ✗ %s=%s\n", mod.Name, mod.Value)
+		}
+		fmt.Println()
+	}
+	if len(d.Orphans) > 0 {
+		fmt.Println("On disk but not in state.json:")
+		for _, o := range d.Orphans {
+			fmt.Printf("  ? %s %s  (%s)\n", o.Runtime, o.Version, o.Path)
+		}
+		fmt.Println()
+	}
+}
+
+// repairConfirm asks a yes/no question, defaulting to no.
+func repairConfirm(reader *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/history"
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+var statsJSON bool
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show aggregate stats from your local setup history",
+	Long: `Aggregates the local run history in ~/.templatr/history.jsonl: number of
+setups, per-template counts, bytes downloaded, cache hit rate, average install
+time per runtime, and failures by kind.
+
+History is recorded locally only and never sent anywhere. A "cache hit" is a
+runtime or download that was already satisfied and needed no download.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStats()
+	},
+}
+
+func init() {
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print stats as JSON")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats() {
+	reports, skipped, err := history.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	stats := history.Aggregate(reports)
+	stats.SkippedRecords = skipped
+
+	if statsJSON {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if stats.Runs == 0 {
+		fmt.Println("No setup history yet.")
+		fmt.Println("History is recorded each time 'templatr-setup setup' finishes.")
+		return
+	}
+
+	fmt.Println("Setup History")
+	fmt.Println("─────────────────────────────────────────────────")
+	fmt.Printf("  Runs:             %d (%d succeeded, %d failed)\n", stats.Runs, stats.Successes, stats.Failures)
+	fmt.Printf("  Downloaded:       %s\n", term.FormatBytes(stats.BytesDownloaded))
+	fmt.Printf("  Cache hit rate:   %.0f%% (%d of %d)\n", stats.CacheHitRate*100, stats.CacheHits, stats.CacheLookups)
+	if skipped > 0 {
+		fmt.Printf("  Skipped records:  %d (unreadable lines)\n", skipped)
+	}
+
+	fmt.Println()
+	fmt.Println("Per Template:")
+	for _, name := range history.SortedKeys(stats.PerTemplate) {
+		fmt.Printf("  %-30s %d\n", name, stats.PerTemplate[name])
+	}
+
+	if len(stats.AvgInstallMs) > 0 {
+		fmt.Println()
+		fmt.Println("Average Install Time:")
+		for _, name := range history.SortedKeys(stats.AvgInstallMs) {
+			fmt.Printf("  %-30s %s\n", name, (time.Duration(stats.AvgInstallMs[name]) * time.Millisecond).Round(100*time.Millisecond))
+		}
+	}
+
+	if len(stats.FailuresByKind) > 0 {
+		fmt.Println()
+		fmt.Println("Failures by Kind:")
+		for _, kind := range history.SortedKeys(stats.FailuresByKind) {
+			fmt.Printf("  %-30s %d\n", kind, stats.FailuresByKind[kind])
+		}
+	}
+}
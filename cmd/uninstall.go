@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -11,32 +13,51 @@ import (
 	"github.com/templatr/templatr-setup/internal/state"
 )
 
-var uninstallAll bool
+var (
+	uninstallAll         bool
+	uninstallAllVersions bool
+	uninstallTemplate    string
+)
 
 var uninstallCmd = &cobra.Command{
-	Use:   "uninstall",
+	Use:   "uninstall [runtime[@version] ...]",
 	Short: "Remove runtimes previously installed by this tool",
 	Long: `Reads the state file (~/.templatr/state.json) and removes all runtimes
 that were installed by templatr-setup. Does not touch runtimes that
 were already installed before the tool ran.
 
+Pass runtime names to remove only those, e.g. "uninstall python" or
+"uninstall node@22.14.0"; everything else stays installed. If a runtime
+has several versions installed and none is given, you're asked which to
+remove (or pass --all-versions).
+
+Pass --template with a template's slug to remove only what was installed
+for it. Runtimes that another set-up template also uses are kept.
+
 If a runtime was upgraded (e.g., Node.js 20 → 22), uninstalling removes
 the newer version and your original installation becomes active again.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runUninstall()
+		runUninstall(args)
 	},
 }
 
 func init() {
-	uninstallCmd.Flags().BoolVar(&uninstallAll, "all", false, "Remove all installed runtimes without prompting")
+	uninstallCmd.Flags().BoolVar(&uninstallAll, "all", false, "Remove without prompting for confirmation")
+	uninstallCmd.Flags().BoolVar(&uninstallAllVersions, "all-versions", false, "Remove every installed version of the given runtimes")
+	uninstallCmd.Flags().StringVar(&uninstallTemplate, "template", "", "Remove only what was installed for this template (by slug)")
 	rootCmd.AddCommand(uninstallCmd)
 }
 
-func runUninstall() {
+func runUninstall(args []string) {
+	if uninstallTemplate != "" && len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: pass either runtimes or --template, not both")
+		exit(exitError)
+	}
+
 	st, err := state.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading state: %s\n", err)
-		os.Exit(1)
+		exit(exitError)
 	}
 
 	if len(st.Installations) == 0 {
@@ -44,9 +65,41 @@ func runUninstall() {
 		return
 	}
 
-	fmt.Println("The following runtimes were installed by templatr-setup:")
+	reader := bufio.NewReader(os.Stdin)
+	targets := st.Installations
+	selective := len(args) > 0 || uninstallTemplate != ""
+	var kept []state.SharedInstallation
+	if uninstallTemplate != "" {
+		sel, err := st.SelectTemplate(uninstallTemplate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			exit(exitError)
+		}
+		targets, kept = sel.Installations, sel.Kept
+		if len(targets) == 0 {
+			printKept(kept)
+			fmt.Printf("Nothing to remove: everything %s uses is needed by other templates.\n", uninstallTemplate)
+			forgetTemplate(kept)
+			return
+		}
+		fmt.Printf("The following were installed only for %s and will be removed:\n", uninstallTemplate)
+	} else if len(args) > 0 {
+		targets = selectUninstall(st, args, reader)
+		if len(targets) == 0 {
+			fmt.Println("Nothing selected. Uninstall cancelled.")
+			exit(exitCancelled)
+		}
+		fmt.Println("The following will be removed:")
+	} else {
+		fmt.Println("The following runtimes were installed by templatr-setup:")
+	}
 	fmt.Println()
-	for _, inst := range st.Installations {
+	for _, inst := range targets {
+		if inst.Action == install.ActionDownload {
+			fmt.Printf("  %s (downloaded)\n", inst.Runtime)
+			fmt.Printf("    Path: %s\n", inst.Path)
+			continue
+		}
 		action := "installed"
 		if inst.Action == "upgrade" {
 			action = fmt.Sprintf("upgraded from %s", inst.PreviousVersion)
@@ -58,19 +111,51 @@ func runUninstall() {
 		}
 	}
 	fmt.Println()
+	printKept(kept)
 
 	if !uninstallAll {
-		fmt.Print("Remove all of these? [y/N] ")
-		reader := bufio.NewReader(os.Stdin)
+		if selective {
+			fmt.Printf("Remove the %d selected installation(s)? [y/N] ", len(targets))
+		} else {
+			fmt.Print("Remove all of these? [y/N] ")
+		}
 		answer, _ := reader.ReadString('\n')
 		answer = strings.TrimSpace(strings.ToLower(answer))
 		if answer != "y" && answer != "yes" {
 			fmt.Println("Uninstall cancelled.")
-			return
+			exit(exitCancelled)
 		}
 	}
 
-	results, errs := st.UndoAll()
+	// Undo against a fresh copy of the state under the lock, so a setup
+	// running meanwhile can't be overwritten
+	var results []state.UndoResult
+	var errs []error
+	err = state.WithLock(func(st *state.State) error {
+		if selective {
+			for _, inst := range targets {
+				result, err := st.UndoInstallation(inst.Runtime, inst.Version)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s %s: %w", inst.Runtime, inst.Version, err))
+					continue
+				}
+				results = append(results, *result)
+			}
+		} else {
+			results, errs = st.UndoAll()
+		}
+		for _, k := range kept {
+			st.DropTemplate(k.Runtime, k.Version, uninstallTemplate)
+		}
+		return nil
+	})
+	if errors.Is(err, state.ErrLocked) {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save state: %s\n", err)
+	}
 	if len(errs) > 0 {
 		for _, e := range errs {
 			fmt.Fprintf(os.Stderr, "  Error: %s\n", e)
@@ -79,6 +164,24 @@ func runUninstall() {
 
 	// Remove PATH and env var modifications
 	for _, result := range results {
+		if result.AlreadyGone != "" {
+			fmt.Printf("  %s was already deleted; removed it from state.json\n", result.AlreadyGone)
+		}
+		if result.NoPath != "" {
+			fmt.Printf("  %s was installed with --no-path; remove the PATH lines you added for it yourself\n", result.NoPath)
+		}
+		if result.Repoint != nil {
+			if _, err := install.LinkCurrent(result.Repoint.Target); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: could not repoint %s: %s\n", result.Repoint.Path, err)
+			} else {
+				fmt.Printf("  %s now points at %s\n", result.Repoint.Path, result.Repoint.Target)
+			}
+		}
+		if result.RemovedLink != nil {
+			if err := install.RemoveCurrent(result.RemovedLink.Path); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: %s\n", err)
+			}
+		}
 		if result.PathMod != nil {
 			if err := install.RemoveFromPath(*result.PathMod); err != nil {
 				fmt.Fprintf(os.Stderr, "  Warning: could not remove PATH entry %s: %s\n", result.PathMod.Value, err)
@@ -96,10 +199,84 @@ func runUninstall() {
 		}
 	}
 
-	if err := st.Save(); err != nil {
+	fmt.Println()
+	fmt.Println("Uninstall complete. Restart your terminal for PATH changes to take effect.")
+}
+
+// printKept lists the installations uninstall --template keeps because
+// other templates need them.
+func printKept(kept []state.SharedInstallation) {
+	if len(kept) == 0 {
+		return
+	}
+	fmt.Println("Kept, because other templates still need them:")
+	fmt.Println()
+	for _, k := range kept {
+		fmt.Printf("  %s %s (also used by %s)\n", k.Runtime, k.Version, strings.Join(k.NeededBy, ", "))
+	}
+	fmt.Println()
+}
+
+// forgetTemplate drops uninstall --template's template from the kept
+// installations, which stay with the other templates.
+func forgetTemplate(kept []state.SharedInstallation) {
+	err := state.WithLock(func(st *state.State) error {
+		for _, k := range kept {
+			st.DropTemplate(k.Runtime, k.Version, uninstallTemplate)
+		}
+		return nil
+	})
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not save state: %s\n", err)
 	}
+}
 
-	fmt.Println()
-	fmt.Println("Uninstall complete. Restart your terminal for PATH changes to take effect.")
+// selectUninstall resolves runtime[@version] arguments to installations,
+// asking which version to remove where an argument matches several.
+func selectUninstall(st *state.State, args []string, reader *bufio.Reader) []state.Installation {
+	sel, err := st.Select(args, uninstallAllVersions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitError)
+	}
+
+	targets := sel.Installations
+	for _, arg := range args {
+		runtime, _, _ := strings.Cut(strings.ToLower(arg), "@")
+		matches, ok := sel.Ambiguous[runtime]
+		if !ok {
+			continue
+		}
+		delete(sel.Ambiguous, runtime)
+
+		fmt.Printf("Several %s versions were installed by templatr-setup:\n", runtime)
+		for i, inst := range matches {
+			fmt.Printf("  %d) %s (%s)\n", i+1, inst.Version, inst.Path)
+		}
+		fmt.Println("  a) all of them")
+		fmt.Printf("Which one should be removed? [1-%d/a, empty to skip] ", len(matches))
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		fmt.Println()
+
+		switch n, err := strconv.Atoi(answer); {
+		case answer == "a" || answer == "all":
+			targets = append(targets, matches...)
+		case err == nil && n >= 1 && n <= len(matches):
+			targets = append(targets, matches[n-1])
+		case answer != "":
+			fmt.Fprintf(os.Stderr, "Unknown choice %q, keeping %s.\n", answer, runtime)
+		}
+	}
+
+	// An explicit version may also have been picked from a prompt
+	seen := map[string]bool{}
+	unique := targets[:0]
+	for _, inst := range targets {
+		if key := inst.Runtime + "@" + inst.Version; !seen[key] {
+			seen[key] = true
+			unique = append(unique, inst)
+		}
+	}
+	return unique
 }
@@ -8,27 +8,101 @@ import (
 	"github.com/templatr/templatr-setup/internal/selfupdate"
 )
 
+var (
+	updateCheck    bool
+	updateVersion  string
+	updateRollback bool
+	updateChannel  string
+)
+
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Self-update to the latest version from GitHub Releases",
 	Long: `Downloads the latest version of templatr-setup for your OS and architecture,
 verifies the SHA256 checksum, and replaces the current binary.
+--version installs a specific release instead, older ones included.
+The binary replaced is kept in ~/.templatr/backup (the last two), and
+--rollback puts the newest one back.
+
+Pre-releases such as v1.5.0-beta.1 are only taken on the beta channel:
+--channel beta for one run, or update.channel = "beta" in
+~/.templatr/config.toml.
+
+--check only reports whether a newer release exists: it exits 0 when
+templatr-setup is up to date, 1 when an update is available and 7 when
+it couldn't check.
 
 If you installed via a package manager (Homebrew, Scoop, winget),
 the tool will detect this and suggest using the package manager instead.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		modes := 0
+		for _, on := range []bool{updateCheck, updateVersion != "", updateRollback} {
+			if on {
+				modes++
+			}
+		}
+		if modes > 1 {
+			fmt.Fprintln(os.Stderr, "Error: --check, --version and --rollback can't be used together")
+			exit(exitError)
+		}
+		if updateChannel != "" {
+			if err := userCfg.Set("update.channel", updateChannel); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				exit(exitError)
+			}
+		}
+		if updateCheck {
+			runUpdateCheck()
+			return
+		}
+		if updateRollback {
+			fmt.Printf("Current version: %s\n", versionStr)
+			restored, err := selfupdate.Rollback()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Rollback failed: %s\n", err)
+				exit(exitError)
+			}
+			fmt.Printf("Rolled back to %s! Restart templatr-setup to use it.\n", restored)
+			return
+		}
+
 		fmt.Printf("Current version: %s\n", versionStr)
-		fmt.Println("Checking for updates...")
+		if updateVersion != "" {
+			fmt.Printf("Looking for %s...\n", updateVersion)
+		} else {
+			fmt.Println("Checking for updates...")
+		}
 
-		if err := selfupdate.DoUpdate(versionStr); err != nil {
+		installed, err := selfupdate.DoUpdate(versionStr, updateVersion, userCfg.Update.Channel)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Update failed: %s\n", err)
-			os.Exit(1)
+			exit(exitError)
 		}
 
-		fmt.Println("Update successful! Restart templatr-setup to use the new version.")
+		fmt.Printf("Updated to %s! Restart templatr-setup to use the new version.\n", installed)
 	},
 }
 
+// runUpdateCheck reports whether a newer release exists, through its exit
+// status as well, for scripts.
+func runUpdateCheck() {
+	result, err := selfupdate.Check(versionStr, userCfg.Update.Channel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		exit(exitNetwork)
+	}
+	if !result.UpdateAvail {
+		fmt.Printf("templatr-setup %s is up to date\n", result.CurrentVersion)
+		return
+	}
+	fmt.Printf("A new version is available: %s (current: %s)\n", result.LatestVersion, result.CurrentVersion)
+	exit(exitError)
+}
+
 func init() {
+	updateCmd.Flags().BoolVar(&updateCheck, "check", false, "Only report whether an update is available (exit 0 if up to date, 1 if not)")
+	updateCmd.Flags().StringVar(&updateVersion, "version", "", "Install this release (e.g. v1.4.2) instead of the latest")
+	updateCmd.Flags().StringVar(&updateChannel, "channel", "", "Release channel to update from: stable, or beta for pre-releases too (default: update.channel)")
+	updateCmd.Flags().BoolVar(&updateRollback, "rollback", false, "Put back the binary the last update replaced")
 	rootCmd.AddCommand(updateCmd)
 }
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+var (
+	validateDeep bool
+	validateJSON bool
+)
+
+// validateReport is the --json output of validate.
+type validateReport struct {
+	File   string                     `json:"file"`
+	Valid  bool                       `json:"valid"`
+	Errors []manifest.ValidationError `json:"errors"`
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check a .templatr.toml manifest for mistakes",
+	Long: `Parses and validates the manifest (.templatr.toml in the current directory,
+or --file) and lists every problem found, not just the first.
+
+With --deep it also checks the manifest against the template's files:
+
+  - config files in [[config]] exist relative to the manifest
+  - env files can be written inside the template directory
+  - runtime requirements, including "auto" and "file:", are version constraints
+  - post-setup commands run programs setup installs, or that are on PATH
+  - no env key is set twice in the same file
+
+Exits with status 1 if there are errors; warnings alone don't fail.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runValidate()
+	},
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateDeep, "deep", false, "Also check config files, env files, version constraints and post-setup commands against the template directory")
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Print the results as JSON")
+	rootCmd.AddCommand(validateCmd)
+}
+
+func runValidate() {
+	path := manifestFile
+	if path == "" {
+		path = manifest.DefaultManifestName
+	}
+	report := validateReport{File: path, Errors: []manifest.ValidationError{}}
+
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		// Reported like the web UI does: a single error without a path
+		report.Errors = append(report.Errors, manifest.ParseError(err))
+	} else {
+		report.Errors = append(report.Errors, manifest.Validate(m)...)
+		if validateDeep {
+			report.Errors = append(report.Errors, m.DeepValidate(m.Dir)...)
+		}
+	}
+	report.Valid = !manifest.HasErrors(report.Errors)
+
+	if validateJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		printValidation(report)
+	}
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
+
+// printValidation lists the errors, then the warnings, and a summary line.
+func printValidation(r validateReport) {
+	var errs, warnings int
+	for _, severity := range []string{manifest.SeverityError, manifest.SeverityWarning} {
+		for _, e := range r.Errors {
+			if e.Severity != severity {
+				continue
+			}
+			icon := "✗"
+			if severity == manifest.SeverityWarning {
+				icon = "!"
+				warnings++
+			} else {
+				errs++
+			}
+			switch {
+			case e.Line > 0:
+				fmt.Printf("  %s line %d, column %d: %s\n", icon, e.Line, e.Column, e.Message)
+			case e.Path == "":
+				fmt.Printf("  %s %s\n", icon, e.Message)
+			default:
+				fmt.Printf("  %s %s\n", icon, e)
+			}
+		}
+	}
+
+	name := filepath.Base(r.File)
+	switch {
+	case errs > 0:
+		fmt.Printf("\n%s: %d error(s), %d warning(s)\n", name, errs, warnings)
+	case warnings > 0:
+		fmt.Printf("%s is valid, with %d warning(s)\n", name, warnings)
+	default:
+		fmt.Printf("%s is valid\n", name)
+	}
+	if !validateDeep && errs == 0 {
+		fmt.Println("Run with --deep to also check it against the template's files.")
+	}
+}
@@ -15,7 +15,7 @@ var versionCmd = &cobra.Command{
 		fmt.Printf("  commit: %s\n", commitStr)
 		fmt.Printf("  built:  %s\n", dateStr)
 
-		if result := selfupdate.CheckForUpdate(versionStr); result != nil && result.UpdateAvail {
+		if result := selfupdate.CheckForUpdate(versionStr, userCfg.Update.Channel); result != nil && result.UpdateAvail {
 			fmt.Println()
 			fmt.Printf("A new version is available: %s (current: %s)\n", result.LatestVersion, result.CurrentVersion)
 			fmt.Println("Run 'templatr-setup update' to upgrade, or visit https://templatr.io/tools/setup")
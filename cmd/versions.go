@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/templatr/templatr-setup/internal/install"
+)
+
+var (
+	versionsConstraint string
+	versionsJSON       bool
+)
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions <runtime>",
+	Short: "List the versions of a runtime the tool can install",
+	Long: `Lists the versions of a runtime found in its release index, newest
+first, so you can check what a [runtimes] constraint will resolve to.
+Node.js and Java LTS releases are marked, as are Go and Flutter channels.
+
+Runtimes without a browsable release index show only the latest version.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runVersions(args[0])
+	},
+}
+
+func init() {
+	versionsCmd.Flags().StringVar(&versionsConstraint, "constraint", "", `Only list versions matching a constraint, e.g. ">=20"`)
+	versionsCmd.Flags().BoolVar(&versionsJSON, "json", false, "Print versions as JSON")
+	rootCmd.AddCommand(versionsCmd)
+}
+
+func runVersions(name string) {
+	installer, err := install.FindInstaller(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	versions, err := install.ListVersions(installer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+	versions, err = install.FilterVersions(versions, versionsConstraint)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if versionsJSON {
+		if versions == nil {
+			versions = []install.AvailableVersion{}
+		}
+		data, err := json.MarshalIndent(versions, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(versions) == 0 {
+		fmt.Printf("No %s versions match %s.\n", installer.Name(), versionsConstraint)
+		return
+	}
+
+	for _, v := range versions {
+		var notes []string
+		if v.LTS {
+			lts := "LTS"
+			if v.Codename != "" {
+				lts += " (" + v.Codename + ")"
+			}
+			notes = append(notes, lts)
+		}
+		if v.Channel != "" {
+			notes = append(notes, v.Channel)
+		}
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %-20s %s", v.Version, strings.Join(notes, ", ")), " "))
+	}
+}
@@ -0,0 +1,34 @@
+package browser
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Open opens the given URL in the user's default browser. It returns once
+// the opener has started, without waiting for the browser.
+func Open(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default: // linux and others
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// CanOpen reports whether a browser on this machine is likely to be seen
+// by the user. It is false in SSH sessions, where the URL should be printed
+// instead.
+func CanOpen() bool {
+	return !isSSH(os.Getenv)
+}
+
+// isSSH reports whether the environment looks like a remote SSH session.
+func isSSH(getenv func(string) string) bool {
+	return getenv("SSH_TTY") != "" || getenv("SSH_CONNECTION") != ""
+}
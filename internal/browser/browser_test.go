@@ -0,0 +1,30 @@
+package browser
+
+import "testing"
+
+func TestIsSSH(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{"local", map[string]string{}, false},
+		{"ssh tty", map[string]string{"SSH_TTY": "/dev/pts/0"}, true},
+		{"ssh connection", map[string]string{"SSH_CONNECTION": "10.0.0.1 5000 10.0.0.2 22"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(k string) string { return tt.env[k] }
+			if got := isSSH(getenv); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanOpen_SSH(t *testing.T) {
+	t.Setenv("SSH_TTY", "/dev/pts/0")
+	if CanOpen() {
+		t.Error("expected CanOpen to be false in an SSH session")
+	}
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/fsutil"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+// DefaultBackupDir is where copies of files are kept before configure
+// changes them, relative to the project directory.
+const DefaultBackupDir = ".templatr-backup"
+
+// maxBackupsPerFile is how many backups of one file are kept; older ones
+// are removed when a new backup is made.
+const maxBackupsPerFile = 5
+
+// backupTimeFormat is appended to the file name of each backup, e.g.
+// ".env.20260314-091502.123456789".
+const backupTimeFormat = "20060102-150405.000000000"
+
+var (
+	backupDir = DefaultBackupDir
+	backupLog *logger.Logger
+)
+
+// SetBackupDir sets where backups are kept. A relative dir is resolved
+// against the project (working) directory; empty restores the default.
+func SetBackupDir(dir string) {
+	if dir == "" {
+		dir = DefaultBackupDir
+	}
+	backupDir = dir
+}
+
+// SetBackupLogger records each backup made afterwards in log.
+func SetBackupLogger(log *logger.Logger) {
+	backupLog = log
+}
+
+// Backup is a saved copy of a project file.
+type Backup struct {
+	File string    // the original file, relative to the project directory
+	Path string    // the copy inside the backup directory
+	Time time.Time // when the copy was made
+}
+
+// BackupFile copies the file at path into the backup directory, mirroring
+// its location in the project, and prunes all but the newest backups of
+// it. It returns the backup's path, or "" when there is nothing to back up:
+// the file doesn't exist or lives outside the project directory.
+func BackupFile(path string) (string, error) {
+	rel, ok := projectPath(path)
+	if !ok {
+		return "", nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot back up %s: %w", path, err)
+	}
+
+	dest := filepath.Join(backupDir, rel) + "." + time.Now().Format(backupTimeFormat)
+	if err := ensureBackupDir(filepath.Dir(dest)); err != nil {
+		return "", err
+	}
+	if err := copyFile(path, dest, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("cannot back up %s: %w", path, err)
+	}
+	if backupLog != nil {
+		backupLog.Info("Backed up %s to %s", path, dest)
+	}
+
+	if err := pruneBackups(filepath.ToSlash(rel)); err != nil {
+		return dest, err
+	}
+	return dest, nil
+}
+
+// ListBackups returns every backup in the backup directory, grouped by
+// file in name order and newest first within each file.
+func ListBackups() ([]Backup, error) {
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var backups []Backup
+	err := filepath.WalkDir(backupDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(backupDir, path)
+		if err != nil {
+			return nil
+		}
+		if b, ok := parseBackupName(filepath.ToSlash(rel)); ok {
+			b.Path = path
+			backups = append(backups, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list backups: %w", err)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].File != backups[j].File {
+			return backups[i].File < backups[j].File
+		}
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// RestoreBackup copies a backup over its original file. The file's current
+// contents are backed up first, so a restore can itself be undone. The
+// backup is read before that, as making it may prune b when b is the oldest.
+func RestoreBackup(b Backup) error {
+	target := filepath.FromSlash(b.File)
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return fmt.Errorf("cannot read backup %s: %w", b.Path, err)
+	}
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return fmt.Errorf("cannot read backup %s: %w", b.Path, err)
+	}
+	if _, err := BackupFile(target); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(target); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("cannot create %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(target, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("cannot restore %s: %w", b.File, err)
+	}
+	return nil
+}
+
+// writeFileWithBackup replaces an existing file's content atomically,
+// backing it up first. Nothing is written when the content is unchanged, so re-running
+// configure doesn't push real backups out.
+func writeFileWithBackup(path string, old, content []byte) error {
+	if bytes.Equal(old, content) {
+		return nil
+	}
+	if _, err := BackupFile(path); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, content, 0o644)
+}
+
+// projectPath returns path relative to the working directory, or false if
+// it lies outside it.
+func projectPath(path string) (string, bool) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// ensureBackupDir creates dir inside the backup directory. A new backup
+// directory gets a .gitignore ignoring everything, as backups of env files
+// hold secrets.
+func ensureBackupDir(dir string) error {
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(backupDir, 0o755); err != nil {
+			return fmt.Errorf("cannot create backup directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(backupDir, ".gitignore"), []byte("*\n"), 0o644); err != nil {
+			return fmt.Errorf("cannot create backup directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("cannot create backup directory: %w", err)
+	}
+	return nil
+}
+
+// parseBackupName splits a backup's path inside the backup directory into
+// the original file and the time it was made.
+func parseBackupName(rel string) (Backup, bool) {
+	n := len(rel) - len(backupTimeFormat)
+	if n < 2 || rel[n-1] != '.' {
+		return Backup{}, false
+	}
+	t, err := time.ParseInLocation(backupTimeFormat, rel[n:], time.Local)
+	if err != nil {
+		return Backup{}, false
+	}
+	return Backup{File: rel[:n-1], Time: t}, true
+}
+
+// pruneBackups removes all but the newest maxBackupsPerFile backups of file.
+func pruneBackups(file string) error {
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, b := range backups {
+		if b.File != file {
+			continue
+		}
+		kept++
+		if kept > maxBackupsPerFile {
+			if err := os.Remove(b.Path); err != nil {
+				return fmt.Errorf("cannot remove old backup %s: %w", b.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, perm)
+}
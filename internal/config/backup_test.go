@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestWriteEnvFile_BacksUpExisting(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile(".env", []byte("API_KEY=old\n"), 0o600)
+
+	envDefs := []manifest.EnvVar{{Key: "API_KEY", Label: "API Key"}}
+	if err := WriteEnvFile(".env", envDefs, map[string]string{"API_KEY": "new"}); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].File != ".env" {
+		t.Fatalf("backups = %+v, want one of .env", backups)
+	}
+	if !strings.HasPrefix(backups[0].Path, filepath.Join(DefaultBackupDir, ".env.")) {
+		t.Errorf("backup path = %s", backups[0].Path)
+	}
+	data, _ := os.ReadFile(backups[0].Path)
+	if string(data) != "API_KEY=old\n" {
+		t.Errorf("backup holds %q, want the old contents", data)
+	}
+	if info, _ := os.Stat(backups[0].Path); info.Mode().Perm() != 0o600 {
+		t.Errorf("backup mode = %v, want the original's 0600", info.Mode().Perm())
+	}
+	if ignore, _ := os.ReadFile(filepath.Join(DefaultBackupDir, ".gitignore")); string(ignore) != "*\n" {
+		t.Errorf("backup directory .gitignore = %q", ignore)
+	}
+
+	// An unchanged file is neither rewritten nor backed up again
+	if err := WriteEnvFile(".env", envDefs, map[string]string{"API_KEY": "new"}); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+	if backups, _ := ListBackups(); len(backups) != 1 {
+		t.Errorf("got %d backups after a no-op write, want 1", len(backups))
+	}
+}
+
+func TestWriteEnvFile_NewFileNotBackedUp(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	envDefs := []manifest.EnvVar{{Key: "API_KEY", Label: "API Key"}}
+	if err := WriteEnvFile(".env", envDefs, map[string]string{"API_KEY": "new"}); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+	if _, err := os.Stat(DefaultBackupDir); !os.IsNotExist(err) {
+		t.Error("backup directory created for a new file")
+	}
+}
+
+func TestRestoreBackup_RoundTrip(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll("src/config", 0o755)
+	path := filepath.Join("src", "config", "site.ts")
+	original := "export const siteConfig = {\n  name: \"Original\",\n};\n"
+	os.WriteFile(path, []byte(original), 0o644)
+
+	fields := []manifest.ConfigField{{Path: "siteConfig.name", Type: "text"}}
+	if err := UpdateConfigFile(path, fields, map[string]string{"siteConfig.name": "Broken"}); err != nil {
+		t.Fatalf("UpdateConfigFile failed: %s", err)
+	}
+
+	backups, err := ListBackups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("ListBackups() = %+v, %v", backups, err)
+	}
+	if backups[0].File != "src/config/site.ts" {
+		t.Errorf("File = %q, want src/config/site.ts", backups[0].File)
+	}
+
+	if err := RestoreBackup(backups[0]); err != nil {
+		t.Fatalf("RestoreBackup failed: %s", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != original {
+		t.Errorf("restored file = %q, want the original", data)
+	}
+
+	// The overwritten contents were backed up too, newest first
+	backups, _ = ListBackups()
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups after restore, want 2", len(backups))
+	}
+	if data, _ := os.ReadFile(backups[0].Path); !strings.Contains(string(data), "Broken") {
+		t.Errorf("newest backup = %q, want the contents replaced by the restore", data)
+	}
+}
+
+func TestRestoreBackup_OldestAtLimit(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for i := range maxBackupsPerFile + 1 {
+		os.WriteFile(".env", []byte(fmt.Sprintf("A=%d\n", i)), 0o644)
+		if i < maxBackupsPerFile {
+			if _, err := BackupFile(".env"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	backups, _ := ListBackups()
+	if len(backups) != maxBackupsPerFile {
+		t.Fatalf("got %d backups, want %d", len(backups), maxBackupsPerFile)
+	}
+	// Backing up the current file before restoring prunes the oldest backup
+	if err := RestoreBackup(backups[len(backups)-1]); err != nil {
+		t.Fatalf("RestoreBackup(oldest) failed: %s", err)
+	}
+	if data, _ := os.ReadFile(".env"); string(data) != "A=0\n" {
+		t.Errorf("restored .env = %q, want the oldest backup's A=0", data)
+	}
+	backups, _ = ListBackups()
+	if data, _ := os.ReadFile(backups[0].Path); string(data) != fmt.Sprintf("A=%d\n", maxBackupsPerFile) {
+		t.Errorf("newest backup = %q, want the contents replaced by the restore", data)
+	}
+}
+
+func TestBackupFile_Prunes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile(".env", []byte("A=1\n"), 0o644)
+	os.WriteFile(".env.production", []byte("A=2\n"), 0o644)
+
+	if _, err := BackupFile(".env.production"); err != nil {
+		t.Fatal(err)
+	}
+	var made []string
+	for range maxBackupsPerFile + 2 {
+		p, err := BackupFile(".env")
+		if err != nil {
+			t.Fatal(err)
+		}
+		made = append(made, p)
+	}
+
+	backups, _ := ListBackups()
+	var kept []string
+	for _, b := range backups {
+		if b.File == ".env" {
+			kept = append(kept, b.Path)
+		}
+	}
+	if len(kept) != maxBackupsPerFile {
+		t.Fatalf("kept %d backups of .env, want %d", len(kept), maxBackupsPerFile)
+	}
+	if kept[0] != made[len(made)-1] {
+		t.Errorf("newest kept = %s, want %s", kept[0], made[len(made)-1])
+	}
+	for _, old := range made[:2] {
+		if _, err := os.Stat(old); !os.IsNotExist(err) {
+			t.Errorf("oldest backup %s not pruned", old)
+		}
+	}
+	if len(backups)-len(kept) != 1 {
+		t.Error("pruning .env removed backups of other files")
+	}
+}
+
+func TestBackupFile_CustomDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	SetBackupDir("backups")
+	t.Cleanup(func() { SetBackupDir("") })
+	os.WriteFile(".env", []byte("A=1\n"), 0o644)
+
+	p, err := BackupFile(".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(p, filepath.Join("backups", ".env.")) {
+		t.Errorf("backup path = %s, want it under backups/", p)
+	}
+}
+
+func TestBackupFile_OutsideProject(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outside := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(outside, []byte("A=1\n"), 0o644)
+
+	p, err := BackupFile(outside)
+	if err != nil || p != "" {
+		t.Errorf("BackupFile(outside) = %q, %v, want no backup", p, err)
+	}
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/templatr/templatr-setup/internal/fsutil"
 	"github.com/templatr/templatr-setup/internal/manifest"
 )
 
@@ -51,41 +54,141 @@ func WriteEnvFiles(envDefs []manifest.EnvVar, values map[string]string) error {
 	return nil
 }
 
+// envAddedMarker heads the keys appended to an existing env file.
+const envAddedMarker = "# Added by templatr-setup"
+
 // WriteEnvFile writes a .env file with the given values.
-// It preserves field order from the manifest and adds comments.
+// A new file follows the manifest's field order with comments. An existing
+// file is merged instead: managed keys are updated in place, keys and
+// comments the manifest doesn't know about are kept, and keys missing from
+// the file are appended under a marker comment. The existing file is
+// backed up before it changes (see BackupFile). Vars whose when condition
+// doesn't hold for values are left out, and left alone in an existing file.
+// Missing parent directories of a new file, as for apps/web/.env.local,
+// are created.
 func WriteEnvFile(path string, envDefs []manifest.EnvVar, values map[string]string) error {
+	envDefs = slices.DeleteFunc(slices.Clone(envDefs), func(env manifest.EnvVar) bool {
+		return !manifest.Shown(env.When, values)
+	})
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if len(envDefs) == 0 {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return fsutil.WriteFileAtomic(path, []byte(generateEnvFile(envDefs, values)), 0o644)
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileWithBackup(path, data, []byte(mergeEnvFile(string(data), envDefs, values)))
+}
+
+// generateEnvFile renders a fresh env file for the definitions.
+func generateEnvFile(envDefs []manifest.EnvVar, values map[string]string) string {
 	var b strings.Builder
 
 	b.WriteString("# Generated by templatr-setup\n")
 	b.WriteString("# See .templatr.toml for field descriptions\n\n")
 
 	for i, env := range envDefs {
-		// Comment with description
-		if env.Description != "" {
-			b.WriteString(fmt.Sprintf("# %s\n", env.Description))
-		}
-		if env.DocsURL != "" {
-			b.WriteString(fmt.Sprintf("# Docs: %s\n", env.DocsURL))
+		writeEnvEntry(&b, env, values[env.Key])
+		if i < len(envDefs)-1 {
+			b.WriteString("\n")
 		}
+	}
 
-		value := ""
-		if v, ok := values[env.Key]; ok {
-			value = v
-		}
+	return b.String()
+}
 
-		// Quote values that contain spaces or special characters
-		if needsQuoting(value) {
-			b.WriteString(fmt.Sprintf("%s=\"%s\"\n", env.Key, escapeEnvValue(value)))
-		} else {
-			b.WriteString(fmt.Sprintf("%s=%s\n", env.Key, value))
+// mergeEnvFile updates the managed keys in an existing env file's content,
+// leaving every other line as it is. A managed key without a value in
+// values keeps its current value.
+func mergeEnvFile(content string, envDefs []manifest.EnvVar, values map[string]string) string {
+	managed := make(map[string]bool, len(envDefs))
+	for _, env := range envDefs {
+		managed[env.Key] = true
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		key, export := envLineKey(line)
+		if !managed[key] {
+			continue
 		}
+		seen[key] = true
+		if value, ok := values[key]; ok {
+			lines[i] = export + formatEnvAssignment(key, value)
+		}
+	}
 
-		if i < len(envDefs)-1 {
-			b.WriteString("\n")
+	var b strings.Builder
+	if content != "" {
+		b.WriteString(strings.Join(lines, "\n"))
+		b.WriteString("\n")
+	}
+
+	added := false
+	for _, env := range envDefs {
+		if seen[env.Key] {
+			continue
+		}
+		if !added {
+			if content != "" {
+				b.WriteString("\n")
+			}
+			b.WriteString(envAddedMarker + "\n")
+			added = true
 		}
+		writeEnvEntry(&b, env, values[env.Key])
+		seen[env.Key] = true // a key defined twice is only added once
+	}
+
+	return b.String()
+}
+
+// envLineKey returns the key assigned on an env file line, and the
+// "export " prefix if it has one. Comments and blank lines have no key.
+func envLineKey(line string) (key, export string) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", ""
+	}
+	if rest, ok := strings.CutPrefix(trimmed, "export "); ok {
+		trimmed = strings.TrimSpace(rest)
+		export = "export "
 	}
+	idx := strings.IndexByte(trimmed, '=')
+	if idx < 0 {
+		return "", ""
+	}
+	return strings.TrimSpace(trimmed[:idx]), export
+}
 
-	return os.WriteFile(path, []byte(b.String()), 0o644)
+// writeEnvEntry writes one key with its description and docs comments.
+func writeEnvEntry(b *strings.Builder, env manifest.EnvVar, value string) {
+	if env.Description != "" {
+		b.WriteString(fmt.Sprintf("# %s\n", env.Description))
+	}
+	if env.DocsURL != "" {
+		b.WriteString(fmt.Sprintf("# Docs: %s\n", env.DocsURL))
+	}
+	if env.Store == manifest.StoreKeychain && value == "" {
+		b.WriteString(fmt.Sprintf("# Kept in the OS credential store: templatr-setup secrets get %s\n", env.Key))
+	}
+	b.WriteString(formatEnvAssignment(env.Key, value) + "\n")
+}
+
+// formatEnvAssignment returns KEY=value, quoting values that contain
+// spaces or special characters.
+func formatEnvAssignment(key, value string) string {
+	if needsQuoting(value) {
+		return fmt.Sprintf("%s=\"%s\"", key, escapeEnvValue(value))
+	}
+	return fmt.Sprintf("%s=%s", key, value)
 }
 
 // ReadEnvFile reads existing .env values from a file.
@@ -104,6 +207,7 @@ func ReadEnvFile(path string) (map[string]string, error) {
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		line = strings.TrimPrefix(line, "export ")
 
 		idx := strings.IndexByte(line, '=')
 		if idx < 0 {
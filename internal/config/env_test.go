@@ -114,3 +114,128 @@ func TestReadEnvFile_NotFound(t *testing.T) {
 		t.Errorf("expected empty map, got %d entries", len(vals))
 	}
 }
+
+func TestWriteEnvFile_MergesExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+
+	existing := `# My local overrides
+DEBUG=true
+
+# Your site URL
+SITE_URL=http://localhost:3000
+export OLD_KEY=from-v1
+`
+	if err := os.WriteFile(path, []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	envDefs := []manifest.EnvVar{
+		{Key: "SITE_URL", Label: "Site URL", Description: "Your site URL", Type: "url"},
+		{Key: "API_KEY", Label: "API Key", Description: "Get from dashboard", Type: "secret"},
+	}
+	values := map[string]string{
+		"SITE_URL": "https://example.com",
+		"API_KEY":  "sk_test_12345",
+	}
+
+	if err := WriteEnvFile(path, envDefs, values); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := `# My local overrides
+DEBUG=true
+
+# Your site URL
+SITE_URL=https://example.com
+export OLD_KEY=from-v1
+
+# Added by templatr-setup
+# Get from dashboard
+API_KEY=sk_test_12345
+`
+	if string(data) != want {
+		t.Errorf("merged file:\n%s\nwant:\n%s", data, want)
+	}
+
+	// Re-running with the same values changes nothing
+	if err := WriteEnvFile(path, envDefs, values); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+	again, _ := os.ReadFile(path)
+	if string(again) != want {
+		t.Errorf("second run changed the file:\n%s", again)
+	}
+}
+
+func TestWriteEnvFile_KeepsValueWithoutNewOne(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	os.WriteFile(path, []byte("API_KEY=\"keep me\"\n"), 0o644)
+
+	envDefs := []manifest.EnvVar{{Key: "API_KEY", Label: "API Key"}}
+	if err := WriteEnvFile(path, envDefs, map[string]string{}); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+
+	vals, _ := ReadEnvFile(path)
+	if vals["API_KEY"] != "keep me" {
+		t.Errorf("API_KEY = %q, want the existing value", vals["API_KEY"])
+	}
+}
+
+func TestWriteEnvFile_OmitsHiddenKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+
+	envDefs := []manifest.EnvVar{
+		{Key: "PAYMENT_PROVIDER", Type: "select", Options: []string{"stripe", "lemonsqueezy"}},
+		{Key: "STRIPE_SECRET_KEY", When: "PAYMENT_PROVIDER == 'stripe'"},
+		{Key: "LEMON_API_KEY", When: "PAYMENT_PROVIDER == 'lemonsqueezy'"},
+	}
+	values := map[string]string{"PAYMENT_PROVIDER": "lemonsqueezy", "LEMON_API_KEY": "lk_1"}
+	if err := WriteEnvFile(path, envDefs, values); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+	vals, _ := ReadEnvFile(path)
+	if _, ok := vals["STRIPE_SECRET_KEY"]; ok || vals["LEMON_API_KEY"] != "lk_1" {
+		t.Errorf("written keys = %v, want LEMON_API_KEY without the hidden STRIPE_SECRET_KEY", vals)
+	}
+
+	// Switching provider adds the Stripe key and leaves the old one alone
+	values = map[string]string{"PAYMENT_PROVIDER": "stripe", "STRIPE_SECRET_KEY": "sk_1"}
+	if err := WriteEnvFile(path, envDefs, values); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+	vals, _ = ReadEnvFile(path)
+	if vals["STRIPE_SECRET_KEY"] != "sk_1" || vals["LEMON_API_KEY"] != "lk_1" || vals["PAYMENT_PROVIDER"] != "stripe" {
+		t.Errorf("written keys = %v", vals)
+	}
+}
+
+func TestWriteEnvFile_AllHiddenWritesNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env.stripe")
+	envDefs := []manifest.EnvVar{{Key: "STRIPE_SECRET_KEY", File: ".env.stripe", When: "PAYMENT_PROVIDER == 'stripe'"}}
+	if err := WriteEnvFile(path, envDefs, map[string]string{"PAYMENT_PROVIDER": "lemonsqueezy"}); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("a file with only hidden keys should not be created, stat err = %v", err)
+	}
+}
+
+func TestWriteEnvFile_CreatesParentDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apps", "web", ".env.local")
+	envDefs := []manifest.EnvVar{{Key: "NEXT_PUBLIC_API_URL", File: "apps/web/.env.local"}}
+	if err := WriteEnvFile(path, envDefs, map[string]string{"NEXT_PUBLIC_API_URL": "http://localhost:4000"}); err != nil {
+		t.Fatalf("WriteEnvFile failed: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected the file and its directories to be created: %s", err)
+	}
+	if !strings.Contains(string(data), "NEXT_PUBLIC_API_URL=http://localhost:4000") {
+		t.Errorf("missing value in:\n%s", data)
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// defaultEnvFilePattern names per-environment files: ".env" becomes
+// ".env.production".
+const defaultEnvFilePattern = "{file}.{env}"
+
+// EnvironmentVars is the set of env vars written for one environment.
+type EnvironmentVars struct {
+	Name string            // environment name; empty when none are declared
+	Vars []manifest.EnvVar // File is already rewritten to the environment's file
+}
+
+// EnvPrompt is one value to ask the user for. Environment is empty for vars
+// that are asked once and copied to every environment.
+type EnvPrompt struct {
+	Var         manifest.EnvVar
+	Environment string
+	Section     string // heading to group under: the target file, or the environment
+}
+
+// HasEnvironments reports whether the manifest declares [env_environments].
+func HasEnvironments(m *manifest.Manifest) bool {
+	return len(m.EnvEnvironments.Names) > 0
+}
+
+// IsEnvironment reports whether name is declared in [env_environments].
+func IsEnvironment(m *manifest.Manifest, name string) bool {
+	return contains(m.EnvEnvironments.Names, name)
+}
+
+// EnvironmentFile returns the file that target is written to for environment
+// name, using the manifest's file pattern.
+func EnvironmentFile(m *manifest.Manifest, target, name string) string {
+	pattern := m.EnvEnvironments.FilePattern
+	if pattern == "" {
+		pattern = defaultEnvFilePattern
+	}
+	return strings.NewReplacer("{file}", target, "{env}", name).Replace(pattern)
+}
+
+// EnvironmentDefs returns the env vars written for each declared environment,
+// in declaration order. Vars without an environments list are included in
+// every environment. Without [env_environments] a single unnamed entry holds
+// m.Env unchanged.
+func EnvironmentDefs(m *manifest.Manifest) []EnvironmentVars {
+	if !HasEnvironments(m) {
+		return []EnvironmentVars{{Vars: m.Env}}
+	}
+
+	var out []EnvironmentVars
+	for _, name := range m.EnvEnvironments.Names {
+		var vars []manifest.EnvVar
+		for _, env := range m.Env {
+			if len(env.Environments) > 0 && !contains(env.Environments, name) {
+				continue
+			}
+			env.File = EnvironmentFile(m, EnvFileTarget(env), name)
+			vars = append(vars, env)
+		}
+		out = append(out, EnvironmentVars{Name: name, Vars: vars})
+	}
+	return out
+}
+
+// AllEnvDefs flattens EnvironmentDefs, so each var appears once per file it
+// is written to.
+func AllEnvDefs(m *manifest.Manifest) []manifest.EnvVar {
+	var defs []manifest.EnvVar
+	for _, e := range EnvironmentDefs(m) {
+		defs = append(defs, e.Vars...)
+	}
+	return defs
+}
+
+// EnvPrompts returns the values to ask for, in order: vars shared by all
+// environments first (asked once), then each environment's own vars. When
+// only is set, environments other than it are left out.
+func EnvPrompts(m *manifest.Manifest, only string) []EnvPrompt {
+	var prompts []EnvPrompt
+	if !HasEnvironments(m) {
+		for _, env := range m.Env {
+			prompts = append(prompts, EnvPrompt{Var: env, Section: EnvFileTarget(env)})
+		}
+		return prompts
+	}
+
+	for _, env := range m.Env {
+		if len(env.Environments) == 0 {
+			prompts = append(prompts, EnvPrompt{Var: env, Section: "all environments"})
+		}
+	}
+
+	for _, name := range m.EnvEnvironments.Names {
+		if only != "" && name != only {
+			continue
+		}
+		for _, env := range m.Env {
+			if contains(env.Environments, name) {
+				prompts = append(prompts, EnvPrompt{Var: env, Environment: name, Section: name})
+			}
+		}
+	}
+	return prompts
+}
+
+// Shown reports whether the prompt's var applies, given the shared values
+// and per-environment values entered so far.
+func (p EnvPrompt) Shown(shared map[string]string, perEnv map[string]map[string]string) bool {
+	return manifest.Shown(p.Var.When, EnvironmentVars{Name: p.Environment}.Values(shared, perEnv))
+}
+
+// Values merges shared values (vars asked once) with this environment's
+// entry in perEnv (keyed by environment, then key).
+func (e EnvironmentVars) Values(shared map[string]string, perEnv map[string]map[string]string) map[string]string {
+	values := make(map[string]string, len(shared))
+	for k, v := range shared {
+		values[k] = v
+	}
+	for k, v := range perEnv[e.Name] {
+		values[k] = v
+	}
+	return values
+}
+
+// ExistingEnv holds the values already in each environment's env files,
+// used to pre-fill prompts.
+type ExistingEnv struct {
+	order  []string                     // environment names, in declaration order
+	values map[string]map[string]string // environment, then key
+}
+
+// ReadExistingEnv reads the current values from every environment's env
+// files. Files that don't exist or can't be read contribute nothing.
+func ReadExistingEnv(m *manifest.Manifest) ExistingEnv {
+	existing := ExistingEnv{values: make(map[string]map[string]string)}
+	for _, e := range EnvironmentDefs(m) {
+		values := make(map[string]string)
+		_, fileOrder := GroupEnvByFile(e.Vars)
+		for _, file := range fileOrder {
+			fileValues, _ := ReadEnvFile(file)
+			for k, v := range fileValues {
+				values[k] = v
+			}
+		}
+		existing.order = append(existing.order, e.Name)
+		existing.values[e.Name] = values
+	}
+	return existing
+}
+
+// Value returns the current value for a prompt; values asked once come from
+// the first environment that has one.
+func (e ExistingEnv) Value(p EnvPrompt) string {
+	if p.Environment != "" {
+		return e.values[p.Environment][p.Var.Key]
+	}
+	for _, name := range e.order {
+		if v := e.values[name][p.Var.Key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ConfiguredEnv returns the values in the manifest's env files, for
+// post-setup commands to run with. With [env_environments], a key set in
+// more than one environment gets the first environment's value. Vars with
+// store = "keychain" get their value from the credential store unless the
+// env file has one.
+func ConfiguredEnv(m *manifest.Manifest) map[string]string {
+	env := make(map[string]string)
+	existing := ReadExistingEnv(m)
+	for _, name := range existing.order {
+		for k, v := range existing.values[name] {
+			if _, ok := env[k]; !ok {
+				env[k] = v
+			}
+		}
+	}
+	for k, v := range storedValues(m) {
+		if env[k] == "" {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// WriteEnvironmentFiles writes the env files of every environment (or only
+// the one named by only) and returns the files written, in order.
+func WriteEnvironmentFiles(m *manifest.Manifest, shared map[string]string, perEnv map[string]map[string]string, only string) ([]string, error) {
+	var written []string
+	for _, e := range EnvironmentDefs(m) {
+		if only != "" && e.Name != only {
+			continue
+		}
+		grouped, order := GroupEnvByFile(e.Vars)
+		values := e.Values(shared, perEnv)
+		for _, file := range order {
+			if err := WriteEnvFile(file, grouped[file], values); err != nil {
+				return written, fmt.Errorf("writing %s: %w", file, err)
+			}
+			written = append(written, file)
+		}
+	}
+	return written, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
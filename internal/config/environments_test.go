@@ -0,0 +1,204 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func environmentsManifest(t *testing.T) *manifest.Manifest {
+	t.Helper()
+	m, err := manifest.Parse([]byte(`
+[template]
+name = "Test"
+version = "1.0.0"
+
+[env_environments]
+names = ["development", "production"]
+
+[[env]]
+key = "SITE_NAME"
+label = "Site name"
+
+[[env]]
+key = "NEXT_PUBLIC_API_URL"
+label = "API URL"
+environments = ["development", "production"]
+
+[[env]]
+key = "SENTRY_DSN"
+label = "Sentry DSN"
+environments = ["production"]
+
+[[env]]
+key = "DB_URL"
+label = "Database URL"
+file = "apps/api/.env"
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %s", err)
+	}
+	return m
+}
+
+func TestEnvironmentDefs_Grouping(t *testing.T) {
+	m := environmentsManifest(t)
+	envs := EnvironmentDefs(m)
+	if len(envs) != 2 {
+		t.Fatalf("expected 2 environments, got %d", len(envs))
+	}
+
+	want := map[string][]string{
+		"development": {"SITE_NAME=.env.development", "NEXT_PUBLIC_API_URL=.env.development", "DB_URL=apps/api/.env.development"},
+		"production":  {"SITE_NAME=.env.production", "NEXT_PUBLIC_API_URL=.env.production", "SENTRY_DSN=.env.production", "DB_URL=apps/api/.env.production"},
+	}
+	for _, e := range envs {
+		var got []string
+		for _, v := range e.Vars {
+			got = append(got, v.Key+"="+v.File)
+		}
+		if strings.Join(got, ",") != strings.Join(want[e.Name], ",") {
+			t.Errorf("%s: got %v, want %v", e.Name, got, want[e.Name])
+		}
+	}
+}
+
+func TestEnvironmentDefs_NoneDeclared(t *testing.T) {
+	m := &manifest.Manifest{Env: []manifest.EnvVar{{Key: "A"}, {Key: "B", File: ".env.local"}}}
+	envs := EnvironmentDefs(m)
+	if len(envs) != 1 || envs[0].Name != "" || len(envs[0].Vars) != 2 || envs[0].Vars[1].File != ".env.local" {
+		t.Errorf("expected manifest env unchanged, got %+v", envs)
+	}
+}
+
+func TestEnvironmentFile_Pattern(t *testing.T) {
+	m := &manifest.Manifest{EnvEnvironments: manifest.EnvEnvironments{FilePattern: "env/{env}{file}"}}
+	if got := EnvironmentFile(m, ".env", "staging"); got != "env/staging.env" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestEnvPrompts_Order(t *testing.T) {
+	m := environmentsManifest(t)
+
+	var got []string
+	for _, p := range EnvPrompts(m, "") {
+		got = append(got, p.Section+":"+p.Var.Key)
+	}
+	want := []string{
+		"all environments:SITE_NAME",
+		"all environments:DB_URL",
+		"development:NEXT_PUBLIC_API_URL",
+		"production:NEXT_PUBLIC_API_URL",
+		"production:SENTRY_DSN",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = nil
+	for _, p := range EnvPrompts(m, "production") {
+		got = append(got, p.Environment+":"+p.Var.Key)
+	}
+	want = []string{":SITE_NAME", ":DB_URL", "production:NEXT_PUBLIC_API_URL", "production:SENTRY_DSN"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("only production: got %v, want %v", got, want)
+	}
+}
+
+func TestWriteEnvironmentFiles_Matrix(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll(filepath.Join("apps", "api"), 0755)
+
+	m := environmentsManifest(t)
+	shared := map[string]string{"SITE_NAME": "Acme", "DB_URL": "postgres://db"}
+	perEnv := map[string]map[string]string{
+		"development": {"NEXT_PUBLIC_API_URL": "http://localhost:4000"},
+		"production":  {"NEXT_PUBLIC_API_URL": "https://api.acme.com", "SENTRY_DSN": "https://sentry"},
+	}
+
+	written, err := WriteEnvironmentFiles(m, shared, perEnv, "")
+	if err != nil {
+		t.Fatalf("WriteEnvironmentFiles failed: %s", err)
+	}
+	if len(written) != 4 {
+		t.Errorf("expected 4 files written, got %v", written)
+	}
+
+	matrix := map[string]map[string]string{
+		".env.development": {"SITE_NAME": "Acme", "NEXT_PUBLIC_API_URL": "http://localhost:4000"},
+		".env.production":  {"SITE_NAME": "Acme", "NEXT_PUBLIC_API_URL": "https://api.acme.com", "SENTRY_DSN": "https://sentry"},
+		filepath.Join("apps", "api", ".env.development"): {"DB_URL": "postgres://db"},
+		filepath.Join("apps", "api", ".env.production"):  {"DB_URL": "postgres://db"},
+	}
+	for file, want := range matrix {
+		got, err := ReadEnvFile(file)
+		if err != nil {
+			t.Fatalf("reading %s: %s", file, err)
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: got keys %v, want %v", file, got, want)
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %q, want %q", file, k, got[k], v)
+			}
+		}
+	}
+	if _, err := os.Stat(".env"); !os.IsNotExist(err) {
+		t.Error("base .env should not be written when environments are declared")
+	}
+}
+
+func TestWriteEnvironmentFiles_Only(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll(filepath.Join("apps", "api"), 0755)
+
+	m := environmentsManifest(t)
+	written, err := WriteEnvironmentFiles(m, map[string]string{}, nil, "production")
+	if err != nil {
+		t.Fatalf("WriteEnvironmentFiles failed: %s", err)
+	}
+	for _, f := range written {
+		if !strings.HasSuffix(f, ".production") {
+			t.Errorf("unexpected file %s", f)
+		}
+	}
+	if _, err := os.Stat(".env.development"); !os.IsNotExist(err) {
+		t.Error("development file should not be written")
+	}
+}
+
+func TestConfiguredEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll(filepath.Join("apps", "api"), 0755)
+
+	m := environmentsManifest(t)
+	shared := map[string]string{"SITE_NAME": "Acme", "DB_URL": "postgres://db"}
+	perEnv := map[string]map[string]string{
+		"development": {"NEXT_PUBLIC_API_URL": "http://localhost:4000"},
+		"production":  {"NEXT_PUBLIC_API_URL": "https://api.acme.com", "SENTRY_DSN": "https://sentry"},
+	}
+	if _, err := WriteEnvironmentFiles(m, shared, perEnv, ""); err != nil {
+		t.Fatalf("WriteEnvironmentFiles failed: %s", err)
+	}
+
+	got := ConfiguredEnv(m)
+	want := map[string]string{
+		"SITE_NAME":           "Acme",
+		"DB_URL":              "postgres://db",
+		"NEXT_PUBLIC_API_URL": "http://localhost:4000", // development is declared first
+		"SENTRY_DSN":          "https://sentry",        // only production has it
+	}
+	if len(got) != len(want) {
+		t.Errorf("ConfiguredEnv() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/templatr/templatr-setup/internal/fsutil"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// ExampleFile returns the example file committed alongside an env file:
+// .env.example for .env, apps/web/.env.local.example for
+// apps/web/.env.local.
+func ExampleFile(envFile string) string {
+	return envFile + ".example"
+}
+
+// WriteExampleFiles writes an example file next to each env file the
+// manifest's vars target and returns the files written, in order.
+// Environments share their keys, so the base files' examples cover them.
+func WriteExampleFiles(m *manifest.Manifest) ([]string, error) {
+	grouped, order := GroupEnvByFile(m.Env)
+	var written []string
+	for _, file := range order {
+		example := ExampleFile(file)
+		if err := WriteExampleFile(example, grouped[file]); err != nil {
+			return written, fmt.Errorf("writing %s: %w", example, err)
+		}
+		written = append(written, example)
+	}
+	return written, nil
+}
+
+// WriteExampleFile writes an example env file listing every var, whatever
+// its when condition, with its comments and manifest default. It never
+// sees the values entered for the real env file, so secrets stay out of
+// it. An existing file is merged like WriteEnvFile merges: keys it doesn't
+// manage are kept, and a placeholder is only replaced by a default the
+// manifest sets.
+func WriteExampleFile(path string, envDefs []manifest.EnvVar) error {
+	defaults := make(map[string]string, len(envDefs))
+	for _, env := range envDefs {
+		if env.Default != "" {
+			defaults[env.Key] = env.Default
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return fsutil.WriteFileAtomic(path, []byte(generateEnvFile(envDefs, defaults)), 0o644)
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileWithBackup(path, data, []byte(mergeEnvFile(string(data), envDefs, defaults)))
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func exampleManifest() *manifest.Manifest {
+	return &manifest.Manifest{Env: []manifest.EnvVar{
+		{Key: "SITE_URL", Description: "Public site URL", Default: "http://localhost:3000"},
+		{Key: "STRIPE_SECRET_KEY", Description: "Stripe secret key", Type: "secret", DocsURL: "https://dashboard.stripe.com/apikeys", When: "PAYMENT_PROVIDER == 'stripe'"},
+		{Key: "API_TOKEN", Type: "secret", File: "apps/web/.env.local"},
+	}}
+}
+
+func TestWriteExampleFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m := exampleManifest()
+	values := map[string]string{
+		"SITE_URL":          "https://example.com",
+		"PAYMENT_PROVIDER":  "stripe",
+		"STRIPE_SECRET_KEY": "sk_live_abc123",
+		"API_TOKEN":         "tok_xyz789",
+	}
+	if err := WriteEnvFiles(m.Env, values); err != nil {
+		t.Fatalf("WriteEnvFiles failed: %s", err)
+	}
+
+	written, err := WriteExampleFiles(m)
+	if err != nil {
+		t.Fatalf("WriteExampleFiles failed: %s", err)
+	}
+	if want := []string{".env.example", "apps/web/.env.local.example"}; strings.Join(written, ",") != strings.Join(want, ",") {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+
+	root, err := os.ReadFile(".env.example")
+	if err != nil {
+		t.Fatalf("reading .env.example: %s", err)
+	}
+	web, err := os.ReadFile(filepath.Join("apps", "web", ".env.local.example"))
+	if err != nil {
+		t.Fatalf("reading apps/web/.env.local.example: %s", err)
+	}
+	for _, secret := range []string{"sk_live_abc123", "tok_xyz789", "https://example.com"} {
+		if strings.Contains(string(root), secret) || strings.Contains(string(web), secret) {
+			t.Errorf("example files must not contain the entered value %q:\n%s\n%s", secret, root, web)
+		}
+	}
+	for _, want := range []string{
+		"# Public site URL\nSITE_URL=http://localhost:3000\n",
+		"# Stripe secret key\n# Docs: https://dashboard.stripe.com/apikeys\nSTRIPE_SECRET_KEY=\n",
+	} {
+		if !strings.Contains(string(root), want) {
+			t.Errorf(".env.example missing %q:\n%s", want, root)
+		}
+	}
+	if !strings.Contains(string(web), "API_TOKEN=\n") || strings.Contains(string(web), "SITE_URL") {
+		t.Errorf("apps/web/.env.local.example should list only its own keys:\n%s", web)
+	}
+}
+
+func TestWriteExampleFile_MergesExisting(t *testing.T) {
+	t.Chdir(t.TempDir())
+	existing := "# Shared with the mobile app\nEXPO_PUBLIC_URL=http://localhost:8081\nSTRIPE_SECRET_KEY=sk_test_placeholder\nSITE_URL=http://old.local\n"
+	if err := os.WriteFile(".env.example", []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := exampleManifest()
+	if err := WriteExampleFile(".env.example", m.Env[:2]); err != nil {
+		t.Fatalf("WriteExampleFile failed: %s", err)
+	}
+	data, err := os.ReadFile(".env.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Shared with the mobile app\nEXPO_PUBLIC_URL=http://localhost:8081\nSTRIPE_SECRET_KEY=sk_test_placeholder\nSITE_URL=http://localhost:3000\n"
+	if string(data) != want {
+		t.Errorf("merged example =\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// gitignoreMarker precedes entries appended by AppendGitignore.
+const gitignoreMarker = "# Added by templatr-setup: env files containing secrets"
+
+// IgnoreGap is a written file inside a git repository that no ignore rule
+// covers, so it would be picked up by `git add`.
+type IgnoreGap struct {
+	File  string // path as written (relative to the working directory)
+	Root  string // git repository root
+	Entry string // .gitignore entry that would cover it, e.g. "/apps/api/.env.production"
+}
+
+// SecretEnvFiles returns the env target files that hold at least one
+// secret-typed variable, in manifest order.
+func SecretEnvFiles(envDefs []manifest.EnvVar) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, env := range envDefs {
+		target := EnvFileTarget(env)
+		if env.Type == "secret" && !seen[target] {
+			files = append(files, target)
+			seen[target] = true
+		}
+	}
+	return files
+}
+
+// CheckGitignore returns the files that live in a git repository but are not
+// matched by its .gitignore rules. Files outside any repository are skipped.
+func CheckGitignore(files []string) []IgnoreGap {
+	var gaps []IgnoreGap
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		root, ok := FindGitRoot(filepath.Dir(abs))
+		if !ok {
+			continue
+		}
+		ignored, err := IsGitIgnored(root, abs)
+		if err != nil || ignored {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil {
+			continue
+		}
+		gaps = append(gaps, IgnoreGap{File: file, Root: root, Entry: "/" + filepath.ToSlash(rel)})
+	}
+	return gaps
+}
+
+// AppendGitignore adds an entry for each gap to its repository's .gitignore,
+// under a marker comment.
+func AppendGitignore(gaps []IgnoreGap) error {
+	byRoot := make(map[string][]string)
+	var roots []string
+	for _, g := range gaps {
+		if _, ok := byRoot[g.Root]; !ok {
+			roots = append(roots, g.Root)
+		}
+		byRoot[g.Root] = append(byRoot[g.Root], g.Entry)
+	}
+
+	for _, root := range roots {
+		ignorePath := filepath.Join(root, ".gitignore")
+		existing, err := os.ReadFile(ignorePath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("reading %s: %w", ignorePath, err)
+		}
+
+		var b strings.Builder
+		if len(existing) > 0 {
+			if !strings.HasSuffix(string(existing), "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(gitignoreMarker + "\n")
+		for _, entry := range byRoot[root] {
+			b.WriteString(entry + "\n")
+		}
+
+		f, err := os.OpenFile(ignorePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", ignorePath, err)
+		}
+		_, err = f.WriteString(b.String())
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", ignorePath, err)
+		}
+	}
+
+	return nil
+}
+
+// FindGitRoot returns the nearest directory at or above dir that contains
+// a .git entry (a directory, or a file for worktrees and submodules).
+func FindGitRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// IsGitIgnored reports whether file (absolute, inside root) is ignored by
+// the repository's .gitignore files or .git/info/exclude. A file is also
+// ignored when any of its parent directories is.
+func IsGitIgnored(root, file string) (bool, error) {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return false, err
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasPrefix(rel, "../") {
+		return false, fmt.Errorf("%s is outside %s", file, root)
+	}
+
+	rules := readIgnoreFile(filepath.Join(root, ".git", "info", "exclude"), "")
+	rules = append(rules, readIgnoreFile(filepath.Join(root, ".gitignore"), "")...)
+
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		p := strings.Join(parts[:i+1], "/")
+		isDir := i < len(parts)-1
+		if matchIgnoreRules(rules, p, isDir) {
+			return true, nil
+		}
+		if isDir {
+			rules = append(rules, readIgnoreFile(filepath.Join(root, filepath.FromSlash(p), ".gitignore"), p)...)
+		}
+	}
+	return false, nil
+}
+
+// ignoreRule is one parsed .gitignore line.
+type ignoreRule struct {
+	pattern  string
+	base     string // directory of the .gitignore, relative to the repo root
+	negate   bool
+	dirOnly  bool
+	anchored bool // pattern contains a slash, so it matches from base only
+}
+
+// readIgnoreFile parses a gitignore file; a missing file yields no rules.
+func readIgnoreFile(file, base string) []ignoreRule {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	return parseIgnoreRules(string(data), base)
+}
+
+func parseIgnoreRules(content, base string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		r.pattern = line
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// matchIgnoreRules applies rules in order; the last matching rule decides.
+func matchIgnoreRules(rules []ignoreRule, p string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.matches(p, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func (r ignoreRule) matches(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(p, r.base+"/") {
+			return false
+		}
+		p = strings.TrimPrefix(p, r.base+"/")
+	}
+	if r.anchored {
+		return matchGlobPath(strings.Split(r.pattern, "/"), strings.Split(p, "/"))
+	}
+	ok, _ := path.Match(r.pattern, path.Base(p))
+	return ok
+}
+
+// matchGlobPath matches slash-separated segments, where a "**" segment
+// matches zero or more path segments.
+func matchGlobPath(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchGlobPath(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
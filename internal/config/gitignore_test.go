@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestMatchIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules(`
+# comment
+.env
+*.local
+!keep.local
+/secrets.txt
+build/
+docs/**/private.md
+apps/*/.env.production
+`, "")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{".env", false, true},
+		{"apps/api/.env", false, true},
+		{".env.production", false, false},
+		{"apps/api/.env.production", false, true},
+		{"apps/api/nested/.env.production", false, false},
+		{"config.local", false, true},
+		{"keep.local", false, false},
+		{"secrets.txt", false, true},
+		{"sub/secrets.txt", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"docs/private.md", false, true},
+		{"docs/a/b/private.md", false, true},
+		{"src/main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchIgnoreRules(rules, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%s (dir=%v): got %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestMatchIgnoreRules_NestedBase(t *testing.T) {
+	rules := parseIgnoreRules("/.env.production\n", "apps/api")
+
+	if !matchIgnoreRules(rules, "apps/api/.env.production", false) {
+		t.Error("expected nested anchored pattern to match in its own directory")
+	}
+	if matchIgnoreRules(rules, ".env.production", false) {
+		t.Error("nested pattern should not match outside its directory")
+	}
+}
+
+// newGitRepo creates a directory that looks like a git repo (a .git dir is
+// all FindGitRoot needs) with the given .gitignore content.
+func newGitRepo(t *testing.T, gitignore string) string {
+	t.Helper()
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	if gitignore != "" {
+		os.WriteFile(filepath.Join(root, ".gitignore"), []byte(gitignore), 0644)
+	}
+	return root
+}
+
+func TestIsGitIgnored_ParentDirAndNested(t *testing.T) {
+	root := newGitRepo(t, "secrets/\n")
+	os.MkdirAll(filepath.Join(root, "apps", "api"), 0755)
+	os.WriteFile(filepath.Join(root, "apps", ".gitignore"), []byte("api/.env*\n"), 0644)
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"secrets/prod/.env", true},
+		{"apps/api/.env.production", true},
+		{"apps/web/.env.production", false},
+	}
+	for _, tt := range tests {
+		got, err := IsGitIgnored(root, filepath.Join(root, filepath.FromSlash(tt.file)))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.file, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAndAppendGitignore(t *testing.T) {
+	root := newGitRepo(t, "node_modules\n.env") // no trailing newline
+	os.MkdirAll(filepath.Join(root, "apps", "api"), 0755)
+	t.Chdir(root)
+
+	files := []string{".env", filepath.Join("apps", "api", ".env.production")}
+	gaps := CheckGitignore(files)
+	if len(gaps) != 1 {
+		t.Fatalf("expected 1 gap, got %+v", gaps)
+	}
+	if gaps[0].Entry != "/apps/api/.env.production" {
+		t.Errorf("unexpected entry %q", gaps[0].Entry)
+	}
+
+	if err := AppendGitignore(gaps); err != nil {
+		t.Fatalf("AppendGitignore failed: %s", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(root, ".gitignore"))
+	content := string(data)
+	if !strings.HasPrefix(content, "node_modules\n.env\n\n") {
+		t.Errorf("existing entries should be preserved and separated, got:\n%s", content)
+	}
+	if !strings.Contains(content, gitignoreMarker+"\n/apps/api/.env.production\n") {
+		t.Errorf("expected marker and entry, got:\n%s", content)
+	}
+
+	if gaps := CheckGitignore(files); len(gaps) != 0 {
+		t.Errorf("expected no gaps after append, got %+v", gaps)
+	}
+}
+
+func TestCheckGitignore_OutsideRepo(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if gaps := CheckGitignore([]string{".env"}); len(gaps) != 0 {
+		t.Errorf("expected files outside a repo to be skipped, got %+v", gaps)
+	}
+}
+
+func TestSecretEnvFiles(t *testing.T) {
+	defs := []manifest.EnvVar{
+		{Key: "SITE_URL", Type: "url"},
+		{Key: "API_KEY", Type: "secret"},
+		{Key: "DB_URL", Type: "text", File: "apps/api/.env"},
+		{Key: "DB_PASSWORD", Type: "secret", File: "apps/api/.env"},
+		{Key: "TOKEN", Type: "secret"},
+	}
+	got := SecretEnvFiles(defs)
+	if len(got) != 2 || got[0] != ".env" || got[1] != "apps/api/.env" {
+		t.Errorf("unexpected files %v", got)
+	}
+}
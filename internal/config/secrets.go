@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/secrets"
+)
+
+var (
+	// secretStore replaces the OS credential store when set.
+	secretStore secrets.Store
+	// openSystemStore opens the OS credential store; tests replace it.
+	openSystemStore = secrets.Open
+)
+
+// SetSecretStore sets the store used for vars with store = "keychain", such
+// as a secrets.Memory in tests; nil restores the OS credential store.
+func SetSecretStore(s secrets.Store) {
+	secretStore = s
+}
+
+// OpenSecretStore returns the store for vars with store = "keychain".
+func OpenSecretStore() (secrets.Store, error) {
+	if secretStore != nil {
+		return secretStore, nil
+	}
+	return openSystemStore()
+}
+
+// SecretName returns the name env's value is kept under in the store.
+func SecretName(m *manifest.Manifest, env manifest.EnvVar) string {
+	return secrets.Name(m.Template.Name, env.Key)
+}
+
+// StoredEnvVars returns the manifest's vars with store = "keychain".
+func StoredEnvVars(m *manifest.Manifest) []manifest.EnvVar {
+	var vars []manifest.EnvVar
+	for _, env := range m.Env {
+		if env.Store == manifest.StoreKeychain {
+			vars = append(vars, env)
+		}
+	}
+	return vars
+}
+
+// StoreSecrets puts the values entered for vars with store = "keychain" in
+// the credential store, and returns values with those keys emptied so
+// their env file only gets a placeholder. An empty value leaves what is
+// stored alone. Without a credential store, values is returned unchanged,
+// to be written to the env file as before, with an error wrapping
+// secrets.ErrUnsupported for the caller to warn about.
+func StoreSecrets(m *manifest.Manifest, values map[string]string) (map[string]string, error) {
+	var vars []manifest.EnvVar
+	var keys []string
+	for _, env := range StoredEnvVars(m) {
+		if _, ok := values[env.Key]; ok {
+			vars = append(vars, env)
+			keys = append(keys, env.Key)
+		}
+	}
+	if len(vars) == 0 {
+		return values, nil
+	}
+
+	store, err := OpenSecretStore()
+	if err != nil {
+		return values, fmt.Errorf("%w, so %s will be written to the env file", err, strings.Join(keys, ", "))
+	}
+	written := maps.Clone(values)
+	for _, env := range vars {
+		if v := values[env.Key]; v != "" {
+			if err := store.Set(SecretName(m, env), v); err != nil {
+				return values, err
+			}
+		}
+		written[env.Key] = ""
+	}
+	return written, nil
+}
+
+// storedValues returns the values in the credential store for vars with
+// store = "keychain". Names with nothing stored, or a store that can't be
+// read, contribute nothing.
+func storedValues(m *manifest.Manifest) map[string]string {
+	values := make(map[string]string)
+	vars := StoredEnvVars(m)
+	if len(vars) == 0 {
+		return values
+	}
+	store, err := OpenSecretStore()
+	if err != nil {
+		return values
+	}
+	for _, env := range vars {
+		if v, err := store.Get(SecretName(m, env)); err == nil {
+			values[env.Key] = v
+		}
+	}
+	return values
+}
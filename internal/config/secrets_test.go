@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/secrets"
+)
+
+func keychainManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Template: manifest.TemplateInfo{Name: "SaaS Starter"},
+		Env: []manifest.EnvVar{
+			{Key: "SITE_URL", Type: "url"},
+			{Key: "STRIPE_SECRET_KEY", Type: "secret", Store: manifest.StoreKeychain},
+		},
+	}
+}
+
+func useMemoryStore(t *testing.T) *secrets.Memory {
+	t.Helper()
+	store := secrets.NewMemory()
+	SetSecretStore(store)
+	t.Cleanup(func() { SetSecretStore(nil) })
+	return store
+}
+
+func TestStoreSecrets(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store := useMemoryStore(t)
+	m := keychainManifest()
+
+	values := map[string]string{"SITE_URL": "https://example.com", "STRIPE_SECRET_KEY": "sk_live_abc123"}
+	fileValues, err := StoreSecrets(m, values)
+	if err != nil {
+		t.Fatalf("StoreSecrets failed: %s", err)
+	}
+	if fileValues["STRIPE_SECRET_KEY"] != "" || fileValues["SITE_URL"] != "https://example.com" {
+		t.Errorf("file values = %v, want only the stored key emptied", fileValues)
+	}
+	if values["STRIPE_SECRET_KEY"] != "sk_live_abc123" {
+		t.Error("StoreSecrets should not change the values passed in")
+	}
+	if v, err := store.Get("templatr-setup/SaaS Starter/STRIPE_SECRET_KEY"); err != nil || v != "sk_live_abc123" {
+		t.Errorf("stored value = %q, %v", v, err)
+	}
+
+	if err := WriteEnvFiles(m.Env, fileValues); err != nil {
+		t.Fatalf("WriteEnvFiles failed: %s", err)
+	}
+	data, err := os.ReadFile(".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk_live_abc123") {
+		t.Errorf(".env contains the stored secret:\n%s", data)
+	}
+	if !strings.Contains(string(data), "# Kept in the OS credential store: templatr-setup secrets get STRIPE_SECRET_KEY\nSTRIPE_SECRET_KEY=\n") {
+		t.Errorf(".env should have a placeholder for the stored key:\n%s", data)
+	}
+
+	// Post-setup commands still see the value
+	if got := ConfiguredEnv(m)["STRIPE_SECRET_KEY"]; got != "sk_live_abc123" {
+		t.Errorf("ConfiguredEnv STRIPE_SECRET_KEY = %q, want the stored value", got)
+	}
+}
+
+func TestStoreSecrets_EmptyKeepsStored(t *testing.T) {
+	store := useMemoryStore(t)
+	m := keychainManifest()
+	store.Set("templatr-setup/SaaS Starter/STRIPE_SECRET_KEY", "sk_live_abc123")
+
+	if _, err := StoreSecrets(m, map[string]string{"STRIPE_SECRET_KEY": ""}); err != nil {
+		t.Fatalf("StoreSecrets failed: %s", err)
+	}
+	if v, _ := store.Get("templatr-setup/SaaS Starter/STRIPE_SECRET_KEY"); v != "sk_live_abc123" {
+		t.Errorf("an empty value should keep the stored one, got %q", v)
+	}
+}
+
+func TestStoreSecrets_Unsupported(t *testing.T) {
+	open := openSystemStore
+	openSystemStore = func() (secrets.Store, error) {
+		return nil, fmt.Errorf("%w on this platform", secrets.ErrUnsupported)
+	}
+	t.Cleanup(func() { openSystemStore = open })
+
+	values := map[string]string{"STRIPE_SECRET_KEY": "sk_live_abc123"}
+	fileValues, err := StoreSecrets(keychainManifest(), values)
+	if !errors.Is(err, secrets.ErrUnsupported) || !strings.Contains(err.Error(), "STRIPE_SECRET_KEY will be written to the env file") {
+		t.Errorf("err = %v, want an ErrUnsupported warning naming the key", err)
+	}
+	if fileValues["STRIPE_SECRET_KEY"] != "sk_live_abc123" {
+		t.Error("without a store the value should go to the env file as before")
+	}
+}
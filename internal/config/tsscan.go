@@ -0,0 +1,280 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kinds of value found by scanConfigValues.
+const (
+	valueOther   = iota // identifiers, calls, objects: never replaced
+	valueString         // a quoted string
+	valueLiteral        // true, false or a number
+	valueEmpty          // null or undefined, which any field may replace
+)
+
+// configValue is a property value in a config file.
+type configValue struct {
+	start, end int  // byte offsets of the value in the source
+	kind       int  // valueString, valueLiteral, ...
+	quote      byte // the quote character of a string value
+}
+
+// scope is an object, array or parenthesized expression being scanned.
+type scope struct {
+	name   string // path component: the property or variable holding it, "" if anonymous
+	array  bool
+	paren  bool
+	index  int  // current element, for arrays
+	opaque bool // call arguments inside an object: nothing in them is addressed
+}
+
+// leadingNumber matches a number literal at the start of the input.
+var leadingNumber = regexp.MustCompile(`^-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?`)
+
+// scanConfigValues finds the property values in a TypeScript/JavaScript
+// config file, keyed by dotted path. It is a lightweight scanner, not a
+// parser: it skips strings and comments and tracks the nesting of objects
+// and arrays, naming each by the property or variable it is assigned to.
+//
+// In `export const siteConfig = { contact: { email: "x" } }` the string is
+// at "siteConfig.contact.email". Array elements are addressed by index
+// ("siteConfig.links.0.href"), objects passed straight to a top-level call
+// such as defineConfig({...}) add no component, and a path found more than
+// once has several entries.
+func scanConfigValues(src string) map[string][]configValue {
+	values := make(map[string][]configValue)
+	var stack []scope
+
+	var (
+		key        string // property whose value comes next
+		hasKey     bool
+		expectDecl bool   // after const, let, var, type or interface
+		decl       string // the declared name
+		assigned   string // the name an upcoming "= {" is assigned to
+	)
+
+	top := func() *scope {
+		if len(stack) == 0 {
+			return nil
+		}
+		return &stack[len(stack)-1]
+	}
+	// valuePath returns the path of a value starting here: the pending
+	// property, or the current array element.
+	valuePath := func() (string, bool) {
+		var last string
+		switch s := top(); {
+		case hasKey:
+			last = key
+		case s != nil && s.array:
+			last = strconv.Itoa(s.index)
+		default:
+			return "", false
+		}
+		var parts []string
+		for _, s := range stack {
+			if s.opaque {
+				return "", false
+			}
+			if s.name != "" {
+				parts = append(parts, s.name)
+			}
+		}
+		return strings.Join(append(parts, last), "."), true
+	}
+	record := func(v configValue) {
+		if p, ok := valuePath(); ok {
+			values[p] = append(values[p], v)
+		}
+		hasKey = false
+	}
+	// open pushes a scope named after the property, variable or array
+	// element it is the value of.
+	open := func(s scope) {
+		switch t := top(); {
+		case hasKey:
+			s.name = key
+		case assigned != "":
+			s.name = assigned
+		case decl != "":
+			s.name = decl // interface Name { ... }
+		case t != nil && t.array:
+			s.name = strconv.Itoa(t.index)
+		}
+		stack = append(stack, s)
+		hasKey, expectDecl = false, false
+		decl, assigned = "", ""
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(src[i:], "//"):
+			if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(src)
+			}
+
+		case strings.HasPrefix(src[i:], "/*"):
+			if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(src)
+			}
+
+		case c == '"' || c == '\'' || c == '`':
+			end := stringEnd(src, i)
+			if s := top(); !hasKey && (s == nil || !s.array) {
+				if next, ok := colonAfter(src, end); ok {
+					key, hasKey = src[i+1:end-1], true
+					i = next
+					continue
+				}
+			}
+			record(configValue{start: i, end: end, kind: valueString, quote: c})
+			i = end
+
+		case (c == '-' || isDigit(c)) && leadingNumber.MatchString(src[i:]):
+			end := i + len(leadingNumber.FindString(src[i:]))
+			record(configValue{start: i, end: end, kind: valueLiteral})
+			i = end
+
+		case isIdentChar(c):
+			end := i
+			for end < len(src) && isIdentChar(src[end]) {
+				end++
+			}
+			word := src[i:end]
+			switch {
+			case expectDecl:
+				decl, expectDecl = word, false
+			case word == "const" || word == "let" || word == "var" || word == "type" || word == "interface":
+				expectDecl, decl = true, ""
+			case word == "export" || word == "default":
+			default:
+				if s := top(); !hasKey && (s == nil || !s.array) {
+					if next, ok := colonAfter(src, end); ok {
+						key, hasKey = word, true
+						i = next
+						continue
+					}
+				}
+				kind := valueOther
+				switch word {
+				case "true", "false":
+					kind = valueLiteral
+				case "null", "undefined":
+					kind = valueEmpty
+				}
+				record(configValue{start: i, end: end, kind: kind})
+			}
+			i = end
+
+		case c == '{':
+			open(scope{})
+			i++
+
+		case c == '[':
+			open(scope{array: true})
+			i++
+
+		case c == '(':
+			// Arguments of a call inside an object are opaque; a call at the
+			// top level, like defineConfig({...}), is looked through
+			opaque := false
+			for _, s := range stack {
+				if !s.paren || s.opaque {
+					opaque = true
+				}
+			}
+			open(scope{paren: true, opaque: opaque})
+			i++
+
+		case c == '}' || c == ']' || c == ')':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			hasKey = false
+			i++
+
+		case c == ',':
+			if s := top(); s != nil && s.array {
+				s.index++
+			}
+			hasKey = false
+			i++
+
+		case c == '=':
+			if i+1 < len(src) && (src[i+1] == '>' || src[i+1] == '=') {
+				i += 2 // "=>" or "=="
+				hasKey = false
+				continue
+			}
+			if i > 0 && strings.IndexByte("!<>", src[i-1]) >= 0 {
+				i++
+				continue
+			}
+			assigned, decl = decl, ""
+			hasKey, expectDecl = false, false
+			i++
+
+		case c == ';':
+			hasKey, expectDecl = false, false
+			decl, assigned = "", ""
+			i++
+
+		default:
+			i++
+		}
+	}
+	return values
+}
+
+// stringEnd returns the offset just past the string literal starting at i.
+// An unterminated string runs to the end of src.
+func stringEnd(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			if quote != '`' {
+				return j // unterminated
+			}
+		}
+	}
+	return len(src)
+}
+
+// colonAfter reports whether the next token after offset i is the colon
+// of a property, allowing TypeScript's optional "?:", and returns the
+// offset past it.
+func colonAfter(src string, i int) (int, bool) {
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	if i < len(src) && src[i] == '?' {
+		i++
+	}
+	if i < len(src) && src[i] == ':' {
+		return i + 1, true
+	}
+	return 0, false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
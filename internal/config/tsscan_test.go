@@ -0,0 +1,196 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// scannedPaths returns each path found by scanConfigValues with the source
+// text of its values, e.g. "siteConfig.name" -> `"Acme"`.
+func scannedPaths(src string) map[string]string {
+	out := make(map[string]string)
+	for path, values := range scanConfigValues(src) {
+		var texts []string
+		for _, v := range values {
+			texts = append(texts, src[v.start:v.end])
+		}
+		out[path] = strings.Join(texts, " | ")
+	}
+	return out
+}
+
+func TestScanConfigValues(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]string // path -> value text; paths not listed must be absent
+	}{
+		{
+			name: "nested objects",
+			src: `export const siteConfig = {
+  name: "Acme",
+  contact: {
+    email: "hi@acme.com",
+    address: { city: 'Berlin' },
+  },
+};`,
+			want: map[string]string{
+				"siteConfig.name":                 `"Acme"`,
+				"siteConfig.contact.email":        `"hi@acme.com"`,
+				"siteConfig.contact.address.city": `'Berlin'`,
+			},
+		},
+		{
+			name: "same key at different depths",
+			src: `export const siteConfig = {
+  title: "Site",
+  seo: { title: "SEO title", og: { title: "OG title" } },
+};`,
+			want: map[string]string{
+				"siteConfig.title":        `"Site"`,
+				"siteConfig.seo.title":    `"SEO title"`,
+				"siteConfig.seo.og.title": `"OG title"`,
+			},
+		},
+		{
+			name: "several top-level variables",
+			src: `export const siteConfig = { title: "Site" };
+export const seo = { title: "SEO" };`,
+			want: map[string]string{
+				"siteConfig.title": `"Site"`,
+				"seo.title":        `"SEO"`,
+			},
+		},
+		{
+			name: "arrays are indexed",
+			src: `const siteConfig = {
+  keywords: ["saas", "starter"],
+  links: [
+    { label: "Docs", href: "/docs" },
+    { label: "Blog", href: "/blog" },
+  ],
+  matrix: [[1, 2], [3]],
+};`,
+			want: map[string]string{
+				"siteConfig.keywords.0":    `"saas"`,
+				"siteConfig.keywords.1":    `"starter"`,
+				"siteConfig.links.0.label": `"Docs"`,
+				"siteConfig.links.0.href":  `"/docs"`,
+				"siteConfig.links.1.label": `"Blog"`,
+				"siteConfig.links.1.href":  `"/blog"`,
+				"siteConfig.matrix.0.0":    `1`,
+				"siteConfig.matrix.0.1":    `2`,
+				"siteConfig.matrix.1.0":    `3`,
+			},
+		},
+		{
+			name: "literals, null and identifiers",
+			src:  `const c = { port: 3000, ratio: -0.5, on: false, logo: null, env: process.env.NODE_ENV };`,
+			want: map[string]string{
+				"c.port":  `3000`,
+				"c.ratio": `-0.5`,
+				"c.on":    `false`,
+				"c.logo":  `null`,
+				"c.env":   `process.env.NODE_ENV`,
+			},
+		},
+		{
+			name: "quoted keys and type annotations",
+			src: `export const siteConfig: SiteConfig = {
+  "og-image": "/og.png",
+  'theme': "dark",
+};`,
+			want: map[string]string{
+				"siteConfig.og-image": `"/og.png"`,
+				"siteConfig.theme":    `"dark"`,
+			},
+		},
+		{
+			name: "comments and strings with braces are skipped",
+			src: `const siteConfig = {
+  // name: "commented out",
+  /* tagline: "also commented", { */
+  name: "A { tricky } name: 'x'",
+  url: "https://example.com", // trailing comment with }
+  footer: ` + "`" + `Made with {love}` + "`" + `,
+};`,
+			want: map[string]string{
+				"siteConfig.name":   `"A { tricky } name: 'x'"`,
+				"siteConfig.url":    `"https://example.com"`,
+				"siteConfig.footer": "`Made with {love}`",
+			},
+		},
+		{
+			name: "top-level call is looked through, nested calls are opaque",
+			src: `export default defineConfig({
+  site: { name: "Acme" },
+  theme: createTheme({ primary: "#000" }),
+});`,
+			want: map[string]string{
+				"site.name": `"Acme"`,
+				"theme":     `createTheme`,
+			},
+		},
+		{
+			name: "call assigned to a variable is named after it",
+			src:  `export const siteConfig = defineSite({ name: "Acme" });`,
+			want: map[string]string{
+				"siteConfig.name": `"Acme"`,
+			},
+		},
+		{
+			name: "interfaces are named and kept apart",
+			src: `interface SiteConfig { name: string; tags?: string[] }
+export const siteConfig: SiteConfig = { name: "Acme", tags: [] };`,
+			want: map[string]string{
+				"SiteConfig.name": `string`,
+				"SiteConfig.tags": `string`,
+				"siteConfig.name": `"Acme"`,
+			},
+		},
+		{
+			name: "functions in objects are not addressed",
+			src: `const siteConfig = {
+  name: "Acme",
+  format: (v) => ({ name: v }),
+  get title() { return "x" },
+};`,
+			want: map[string]string{
+				"siteConfig.name":   `"Acme"`,
+				"siteConfig.format": ``,
+			},
+		},
+		{
+			name: "duplicate keys are all reported",
+			src:  `const c = { a: "1", a: "2" };`,
+			want: map[string]string{
+				"c.a": `"1" | "2"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scannedPaths(tt.src)
+			for path, want := range tt.want {
+				if want == "" {
+					delete(got, path) // present, value not checked
+					continue
+				}
+				if got[path] != want {
+					t.Errorf("%s = %q, want %q", path, got[path], want)
+				}
+				delete(got, path)
+			}
+			var extra []string
+			for path, v := range got {
+				extra = append(extra, path+"="+v)
+			}
+			sort.Strings(extra)
+			if len(extra) > 0 {
+				t.Errorf("unexpected paths: %s", strings.Join(extra, ", "))
+			}
+		})
+	}
+}
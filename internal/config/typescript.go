@@ -4,65 +4,132 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
 )
 
 // UpdateConfigFile reads a TypeScript/JavaScript config file and replaces
-// values for the given field paths. Uses regex-based pattern matching -
-// intentionally not a full AST parser.
+// values for the given field paths. Uses a lightweight scanner that tracks
+// object nesting - intentionally not a full AST parser.
 //
-// Paths like "siteConfig.name" are matched by finding the key "name"
-// followed by a string value in the file. The last path component is
-// used as the key to match.
-func UpdateConfigFile(path string, fieldValues map[string]string) error {
+// Paths like "siteConfig.contact.email" name the "email" key inside the
+// contact object of siteConfig, so same-named keys elsewhere are left
+// alone (see scanConfigValues). Fields of type "boolean" and "number"
+// (looked up in fields) are written as unquoted literals; everything else
+// as a string. If any path is missing, found more than once or doesn't
+// hold a replaceable value, an error lists them and the file is left
+// unchanged. The file is backed up before it changes (see BackupFile).
+func UpdateConfigFile(path string, fields []manifest.ConfigField, fieldValues map[string]string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("cannot read %s: %w", path, err)
 	}
 
+	types := make(map[string]string, len(fields))
+	for _, f := range fields {
+		types[f.Path] = f.Type
+	}
+
 	content := string(data)
 
-	for fieldPath, newValue := range fieldValues {
-		// Extract the key name (last component of the path)
-		parts := strings.Split(fieldPath, ".")
-		key := parts[len(parts)-1]
+	paths := make([]string, 0, len(fieldValues))
+	for fieldPath := range fieldValues {
+		paths = append(paths, fieldPath)
+	}
+	sort.Strings(paths)
+
+	var failed []string
+	for _, fieldPath := range paths {
+		newValue := fieldValues[fieldPath]
+		fieldType := types[fieldPath]
+		if isLiteralType(fieldType) {
+			newValue = strings.TrimSpace(newValue)
+			if fieldType == "boolean" {
+				newValue = strings.ToLower(newValue)
+			}
+			if newValue == "" {
+				continue // keep the file's current value
+			}
+			if err := checkLiteral(fieldType, newValue); err != nil {
+				return fmt.Errorf("%s: %w", fieldPath, err)
+			}
+		}
 
-		content = replaceFieldValue(content, key, newValue)
+		updated, err := replaceFieldValue(content, fieldPath, newValue, fieldType)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", fieldPath, err))
+			continue
+		}
+		content = updated
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not update %s: %s", path, strings.Join(failed, ", "))
 	}
 
-	return os.WriteFile(path, []byte(content), 0o644)
+	return writeFileWithBackup(path, data, []byte(content))
 }
 
-// replaceFieldValue finds a key-value pattern in TypeScript/JavaScript
-// and replaces the string value.
-//
-// Matches patterns like:
-//
-//	name: "old value",
-//	name: 'old value',
-//	name: "old value"   // with or without trailing comma
-func replaceFieldValue(content, key, newValue string) string {
-	// Pattern: key followed by colon, optional whitespace, then a quoted string
-	// Captures: the full match so we can replace just the value part
-	pattern := fmt.Sprintf(`(\b%s\s*:\s*)("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`, regexp.QuoteMeta(key))
-	re := regexp.MustCompile(pattern)
+// isLiteralType reports whether values of a field type are written as
+// unquoted JavaScript literals.
+func isLiteralType(fieldType string) bool {
+	return fieldType == "boolean" || fieldType == "number"
+}
 
-	return re.ReplaceAllStringFunc(content, func(match string) string {
-		// Find where the value starts
-		loc := re.FindStringSubmatchIndex(match)
-		if loc == nil {
-			return match
+// checkLiteral returns an error if value isn't a valid literal of the type.
+func checkLiteral(fieldType, value string) error {
+	switch fieldType {
+	case "boolean":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+	case "number":
+		if !numberLiteral.MatchString(value) {
+			return fmt.Errorf("%q is not a number", value)
 		}
+	}
+	return nil
+}
 
-		// loc[2]:loc[3] is the key+colon prefix
-		prefix := match[loc[2]:loc[3]]
+// numberLiteral matches integer and decimal literals such as 3000, -1.5 or 1e3.
+var numberLiteral = regexp.MustCompile(`^-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?$`)
 
-		// Determine quote style from original
-		origValue := match[loc[4]:loc[5]]
-		quote := string(origValue[0]) // " or '
+// replaceFieldValue replaces the value at a dotted path in
+// TypeScript/JavaScript source, keeping the original quote style:
+//
+//	siteConfig = { name: "old value" }    // path "siteConfig.name"
+//	siteConfig = { name: 'old value' }
+//
+// For boolean and number fields it also replaces unquoted literals such as
+// "analytics: true" or "port: 3000", and writes the new value unquoted. A
+// null or undefined value takes any field. It returns an error, and the
+// content unchanged, when the path isn't found exactly once or holds
+// something else, such as a number for a string field.
+func replaceFieldValue(content, path, newValue, fieldType string) (string, error) {
+	found := scanConfigValues(content)[path]
+	switch len(found) {
+	case 0:
+		return content, fmt.Errorf("not found")
+	case 1:
+	default:
+		return content, fmt.Errorf("found %d times", len(found))
+	}
+	v := found[0]
 
-		return prefix + quote + escapeJSString(newValue, quote) + quote
-	})
+	var replacement string
+	switch {
+	case isLiteralType(fieldType) && v.kind != valueOther:
+		replacement = newValue
+	case v.kind == valueString:
+		quote := string(v.quote)
+		replacement = quote + escapeJSString(newValue, quote) + quote
+	case v.kind == valueEmpty && !isLiteralType(fieldType):
+		replacement = `"` + escapeJSString(newValue, `"`) + `"`
+	default:
+		return content, fmt.Errorf("not a string value")
+	}
+	return content[:v.start] + replacement + content[v.end:], nil
 }
 
 // escapeJSString escapes a string for use in a JavaScript string literal.
@@ -72,5 +139,8 @@ func escapeJSString(s, quote string) string {
 	s = strings.ReplaceAll(s, "\n", `\n`)
 	s = strings.ReplaceAll(s, "\r", `\r`)
 	s = strings.ReplaceAll(s, "\t", `\t`)
+	if quote == "`" {
+		s = strings.ReplaceAll(s, "${", `\${`)
+	}
 	return s
 }
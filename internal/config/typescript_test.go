@@ -3,8 +3,11 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
 )
 
 func TestUpdateConfigFile(t *testing.T) {
@@ -35,7 +38,7 @@ func TestUpdateConfigFile(t *testing.T) {
 		"siteConfig.links.github":    "https://github.com/mysaas",
 	}
 
-	if err := UpdateConfigFile(path, fieldValues); err != nil {
+	if err := UpdateConfigFile(path, nil, fieldValues); err != nil {
 		t.Fatalf("UpdateConfigFile failed: %s", err)
 	}
 
@@ -69,7 +72,7 @@ func TestUpdateConfigFile_SingleQuotes(t *testing.T) {
 `
 	os.WriteFile(path, []byte(original), 0o644)
 
-	err := UpdateConfigFile(path, map[string]string{
+	err := UpdateConfigFile(path, nil, map[string]string{
 		"config.title": "New Title",
 	})
 	if err != nil {
@@ -90,7 +93,7 @@ func TestUpdateConfigFile_SingleQuotes(t *testing.T) {
 }
 
 func TestUpdateConfigFile_NotFound(t *testing.T) {
-	err := UpdateConfigFile("/nonexistent/site.ts", map[string]string{"a": "b"})
+	err := UpdateConfigFile("/nonexistent/site.ts", nil, map[string]string{"a": "b"})
 	if err == nil {
 		t.Error("expected error for missing file")
 	}
@@ -103,6 +106,8 @@ func TestReplaceFieldValue(t *testing.T) {
 		key      string
 		newValue string
 		expected string
+		// fieldType defaults to a string field
+		fieldType string
 	}{
 		{
 			name:     "double quotes",
@@ -132,14 +137,287 @@ func TestReplaceFieldValue(t *testing.T) {
 			newValue: `he said "hello"`,
 			expected: `  name: "he said \"hello\"",`,
 		},
+		{
+			name:     "string field leaves unquoted literals alone",
+			content:  `  port: 3000,`,
+			key:      "port",
+			newValue: "8080",
+			expected: `  port: 3000,`,
+		},
+		{
+			name:      "boolean",
+			content:   `  analytics: true,`,
+			key:       "analytics",
+			newValue:  "false",
+			expected:  `  analytics: false,`,
+			fieldType: "boolean",
+		},
+		{
+			name:      "boolean replaces a quoted value",
+			content:   `  analytics: "false",`,
+			key:       "analytics",
+			newValue:  "true",
+			expected:  `  analytics: true,`,
+			fieldType: "boolean",
+		},
+		{
+			name:      "integer",
+			content:   `  port: 3000,`,
+			key:       "port",
+			newValue:  "8080",
+			expected:  `  port: 8080,`,
+			fieldType: "number",
+		},
+		{
+			name:      "negative integer without trailing comma",
+			content:   `  offset: -1 }`,
+			key:       "offset",
+			newValue:  "12",
+			expected:  `  offset: 12 }`,
+			fieldType: "number",
+		},
+		{
+			name:      "float",
+			content:   `  ratio: 0.75,`,
+			key:       "ratio",
+			newValue:  "1.5",
+			expected:  `  ratio: 1.5,`,
+			fieldType: "number",
+		},
+		{
+			name:      "number does not match a longer key",
+			content:   `  maxPort: 1, port: 2,`,
+			key:       "port",
+			newValue:  "3",
+			expected:  `  maxPort: 1, port: 3,`,
+			fieldType: "number",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := replaceFieldValue(tt.content, tt.key, tt.newValue)
+			result, _ := replaceFieldValue(tt.content, tt.key, tt.newValue, tt.fieldType)
 			if result != tt.expected {
 				t.Errorf("expected %q, got %q", tt.expected, result)
 			}
 		})
 	}
 }
+
+func TestUpdateConfigFile_TypedFields(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "site.ts")
+	os.WriteFile(path, []byte(`export const siteConfig = {
+  name: "SaaSify",
+  analytics: true,
+  port: 3000,
+  ratio: 0.5,
+};
+`), 0o644)
+
+	fields := []manifest.ConfigField{
+		{Path: "siteConfig.name", Type: "text"},
+		{Path: "siteConfig.analytics", Type: "boolean"},
+		{Path: "siteConfig.port", Type: "number"},
+		{Path: "siteConfig.ratio", Type: "number"},
+	}
+	err := UpdateConfigFile(path, fields, map[string]string{
+		"siteConfig.name":      "Acme",
+		"siteConfig.analytics": "FALSE",
+		"siteConfig.port":      " 8080 ",
+		"siteConfig.ratio":     "",
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfigFile failed: %s", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{`name: "Acme"`, `analytics: false,`, `port: 8080,`, `ratio: 0.5,`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestUpdateConfigFile_InvalidLiteral(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "site.ts")
+	original := "const c = { port: 3000 };\n"
+	os.WriteFile(path, []byte(original), 0o644)
+
+	fields := []manifest.ConfigField{{Path: "c.port", Type: "number"}}
+	if err := UpdateConfigFile(path, fields, map[string]string{"c.port": "lots"}); err == nil {
+		t.Error("expected an error for a non-numeric value")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != original {
+		t.Errorf("file should be unchanged after an error, got:\n%s", data)
+	}
+}
+
+func TestUpdateConfigFile_ScopedPaths(t *testing.T) {
+	original := `export const siteConfig = {
+  title: "Site",
+  contact: {
+    email: "hello@example.com",
+  },
+  seo: {
+    title: "SEO title",
+    og: { title: "OG title" },
+  },
+  team: [
+    { name: "Ada", email: "ada@example.com" },
+    { name: "Linus", email: "linus@example.com" },
+  ],
+};
+`
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   []string // lines that must appear
+		keep   []string // lines that must be untouched
+	}{
+		{
+			name:   "top-level title only",
+			values: map[string]string{"siteConfig.title": "Acme"},
+			want:   []string{`  title: "Acme",`},
+			keep:   []string{`    title: "SEO title",`, `    og: { title: "OG title" },`},
+		},
+		{
+			name:   "nested title only",
+			values: map[string]string{"siteConfig.seo.title": "Acme SEO"},
+			want:   []string{`    title: "Acme SEO",`},
+			keep:   []string{`  title: "Site",`, `    og: { title: "OG title" },`},
+		},
+		{
+			name:   "deepest title",
+			values: map[string]string{"siteConfig.seo.og.title": "Acme OG"},
+			want:   []string{`    og: { title: "Acme OG" },`},
+			keep:   []string{`  title: "Site",`, `    title: "SEO title",`},
+		},
+		{
+			name:   "contact email leaves team emails alone",
+			values: map[string]string{"siteConfig.contact.email": "team@acme.com"},
+			want:   []string{`    email: "team@acme.com",`},
+			keep:   []string{`    { name: "Ada", email: "ada@example.com" },`, `    { name: "Linus", email: "linus@example.com" },`},
+		},
+		{
+			name:   "array element by index",
+			values: map[string]string{"siteConfig.team.1.email": "torvalds@acme.com"},
+			want:   []string{`    { name: "Linus", email: "torvalds@acme.com" },`},
+			keep:   []string{`    email: "hello@example.com",`, `    { name: "Ada", email: "ada@example.com" },`},
+		},
+		{
+			name: "several fields at once",
+			values: map[string]string{
+				"siteConfig.title":         "Acme",
+				"siteConfig.seo.title":     "Acme SEO",
+				"siteConfig.team.0.name":   "Grace",
+				"siteConfig.contact.email": "team@acme.com",
+			},
+			want: []string{`  title: "Acme",`, `    title: "Acme SEO",`, `    { name: "Grace", email: "ada@example.com" },`, `    email: "team@acme.com",`},
+			keep: []string{`    og: { title: "OG title" },`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "site.ts")
+			os.WriteFile(path, []byte(original), 0o644)
+
+			if err := UpdateConfigFile(path, nil, tt.values); err != nil {
+				t.Fatalf("UpdateConfigFile failed: %s", err)
+			}
+			data, _ := os.ReadFile(path)
+			lines := strings.Split(string(data), "\n")
+			for _, want := range append(tt.want, tt.keep...) {
+				if !slices.Contains(lines, want) {
+					t.Errorf("expected line %q, got:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateConfigFile_UnmatchedPaths(t *testing.T) {
+	original := `export const siteConfig = {
+  name: "Acme",
+  port: 3000,
+  dup: "a",
+  dup: "b",
+};
+`
+	tests := []struct {
+		name    string
+		fields  []manifest.ConfigField
+		values  map[string]string
+		wantErr []string
+	}{
+		{
+			name:    "missing path",
+			values:  map[string]string{"siteConfig.name": "New", "siteConfig.tagline": "x", "seo.title": "y"},
+			wantErr: []string{"seo.title (not found)", "siteConfig.tagline (not found)"},
+		},
+		{
+			name:    "last component alone is not enough",
+			values:  map[string]string{"name": "New"},
+			wantErr: []string{"name (not found)"},
+		},
+		{
+			name:    "ambiguous path",
+			values:  map[string]string{"siteConfig.dup": "c"},
+			wantErr: []string{"siteConfig.dup (found 2 times)"},
+		},
+		{
+			name:    "string field on a number",
+			values:  map[string]string{"siteConfig.port": "8080"},
+			wantErr: []string{"siteConfig.port (not a string value)"},
+		},
+		{
+			name:    "object instead of a value",
+			fields:  []manifest.ConfigField{{Path: "siteConfig", Type: "text"}},
+			values:  map[string]string{"siteConfig": "x"},
+			wantErr: []string{"siteConfig (not found)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "site.ts")
+			os.WriteFile(path, []byte(original), 0o644)
+
+			err := UpdateConfigFile(path, tt.fields, tt.values)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q should mention %q", err, want)
+				}
+			}
+			data, _ := os.ReadFile(path)
+			if string(data) != original {
+				t.Errorf("file should be unchanged after an error, got:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestUpdateConfigFile_NullAndTemplateLiterals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.ts")
+	os.WriteFile(path, []byte("export const siteConfig = {\n  logo: null,\n  footer: `Made by ${author}`,\n};\n"), 0o644)
+
+	err := UpdateConfigFile(path, nil, map[string]string{
+		"siteConfig.logo":   "/logo.svg",
+		"siteConfig.footer": "Made with ${love}",
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfigFile failed: %s", err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{`logo: "/logo.svg",`, "footer: `Made with \\${love}`,"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in output, got:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// FieldRules are what a value entered for an env var or config field is
+// checked against.
+type FieldRules struct {
+	Type     string // text, url, email, secret, number, boolean, select
+	Required bool
+	Options  []string // choices for type "select"
+	Pattern  string   // regular expression the value must match
+	Min, Max *float64 // bounds for type "number"
+}
+
+// EnvRules returns the rules for an env var's value.
+func EnvRules(env manifest.EnvVar) FieldRules {
+	return FieldRules{Type: env.Type, Required: env.Required, Options: env.Options, Pattern: env.Pattern, Min: env.Min, Max: env.Max}
+}
+
+// ConfigRules returns the rules for a config field's value.
+func ConfigRules(f manifest.ConfigField) FieldRules {
+	return FieldRules{Type: f.Type, Options: f.Options, Pattern: f.Pattern, Min: f.Min, Max: f.Max}
+}
+
+// ValidateFieldValue checks a value entered for an env var or config field:
+// required fields must not be empty, numbers must parse and be within min
+// and max, URLs need a scheme and host, emails a plausible address,
+// booleans must be true or false, select values one of the options, and
+// any value must match the pattern. Empty optional values are always
+// accepted.
+func ValidateFieldValue(value string, rules FieldRules) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if rules.Required {
+			return fmt.Errorf("a value is required")
+		}
+		return nil
+	}
+
+	switch rules.Type {
+	case "number":
+		if !numberLiteral.MatchString(value) {
+			return fmt.Errorf("must be a number")
+		}
+		n, _ := strconv.ParseFloat(value, 64)
+		if (rules.Min != nil && n < *rules.Min) || (rules.Max != nil && n > *rules.Max) {
+			return fmt.Errorf("must be %s", rangeText(rules.Min, rules.Max))
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be a URL like https://example.com")
+		}
+	case "email":
+		// A bare address whose domain has a dot; no display names
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@")+1:], ".") {
+			return fmt.Errorf("must be an email address like you@example.com")
+		}
+	case "boolean":
+		if v := strings.ToLower(value); v != "true" && v != "false" {
+			return fmt.Errorf("must be true or false")
+		}
+	case "select":
+		if !slices.Contains(rules.Options, value) {
+			return fmt.Errorf("must be one of: %s", strings.Join(rules.Options, ", "))
+		}
+	}
+
+	if rules.Pattern != "" {
+		// Validate reports patterns that don't compile before this is asked
+		re, err := regexp.Compile(rules.Pattern)
+		if err != nil {
+			return fmt.Errorf("can't be checked: invalid pattern %s", rules.Pattern)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("must match %s", rules.Pattern)
+		}
+	}
+	return nil
+}
+
+// NormalizeFieldValue returns a value entered for an env var or config
+// field as it should be written. Secrets, URLs and emails lose the leading
+// and trailing whitespace a paste from the clipboard often brings along;
+// other values are kept as entered.
+func NormalizeFieldValue(value string, rules FieldRules) string {
+	switch rules.Type {
+	case "secret", "url", "email":
+		return strings.TrimSpace(value)
+	}
+	return value
+}
+
+// rangeText describes the bounds of a number in an error message.
+func rangeText(lo, hi *float64) string {
+	switch {
+	case lo != nil && hi != nil:
+		return fmt.Sprintf("between %s and %s", manifest.FormatNumber(*lo), manifest.FormatNumber(*hi))
+	case lo != nil:
+		return "at least " + manifest.FormatNumber(*lo)
+	default:
+		return "at most " + manifest.FormatNumber(*hi)
+	}
+}
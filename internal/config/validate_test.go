@@ -0,0 +1,138 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestValidateFieldValue(t *testing.T) {
+	tests := []struct {
+		value     string
+		fieldType string
+		required  bool
+		options   []string
+		wantErr   bool
+	}{
+		// Required
+		{"", "secret", true, nil, true},
+		{"   ", "text", true, nil, true},
+		{"", "url", false, nil, false},
+		{"sk_live_123", "secret", true, nil, false},
+
+		// Numbers
+		{"3000", "number", false, nil, false},
+		{"-1.5", "number", false, nil, false},
+		{"1e3", "number", false, nil, false},
+		{"3k", "number", false, nil, true},
+		{"NaN", "number", false, nil, true},
+
+		// URLs
+		{"https://example.com", "url", false, nil, false},
+		{"http://localhost:3000/path", "url", false, nil, false},
+		{"example.com", "url", false, nil, true},
+		{"https://", "url", false, nil, true},
+
+		// Emails
+		{"hello@example.com", "email", false, nil, false},
+		{"hello@localhost", "email", false, nil, true},
+		{"hello", "email", false, nil, true},
+		{"Hello <hello@example.com>", "email", false, nil, true},
+
+		// Booleans
+		{"true", "boolean", false, nil, false},
+		{"FALSE", "boolean", false, nil, false},
+		{"yes", "boolean", false, nil, true},
+
+		// Select
+		{"stripe", "select", false, []string{"stripe", "lemonsqueezy"}, false},
+		{"paypal", "select", false, []string{"stripe", "lemonsqueezy"}, true},
+
+		// Text accepts anything
+		{"anything at all", "text", false, nil, false},
+		{"anything at all", "", true, nil, false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateFieldValue(tt.value, FieldRules{Type: tt.fieldType, Required: tt.required, Options: tt.options})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateFieldValue(%q, %q, required=%v) error = %v, wantErr %v", tt.value, tt.fieldType, tt.required, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateFieldValue_Rules(t *testing.T) {
+	one, port := 1.0, 65535.0
+	half := 0.5
+	tests := []struct {
+		name    string
+		value   string
+		rules   FieldRules
+		wantErr string
+	}{
+		{"pattern match", "sk_live_123", FieldRules{Type: "secret", Pattern: "^sk_(test|live)_"}, ""},
+		{"pattern mismatch", "pk_live_123", FieldRules{Type: "secret", Pattern: "^sk_(test|live)_"}, "must match ^sk_(test|live)_"},
+		{"pattern skips empty optional", "", FieldRules{Pattern: "^sk_"}, ""},
+		{"pattern after type", "ftp://example.com", FieldRules{Type: "url", Pattern: "^https://"}, "must match ^https://"},
+		{"invalid pattern", "x", FieldRules{Pattern: "("}, "invalid pattern"},
+		{"in range", "3000", FieldRules{Type: "number", Min: &one, Max: &port}, ""},
+		{"lower bound inclusive", "1", FieldRules{Type: "number", Min: &one, Max: &port}, ""},
+		{"below range", "0", FieldRules{Type: "number", Min: &one, Max: &port}, "must be between 1 and 65535"},
+		{"above range", "70000", FieldRules{Type: "number", Min: &one, Max: &port}, "must be between 1 and 65535"},
+		{"min only", "0.25", FieldRules{Type: "number", Min: &half}, "must be at least 0.5"},
+		{"max only", "1e6", FieldRules{Type: "number", Max: &port}, "must be at most 65535"},
+		{"not a number first", "abc", FieldRules{Type: "number", Min: &one}, "must be a number"},
+		{"bounds ignored for text", "0", FieldRules{Type: "text", Min: &one}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFieldValue(tt.value, tt.rules)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateFieldValue(%q) error = %v, want nil", tt.value, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateFieldValue(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeFieldValue(t *testing.T) {
+	tests := []struct {
+		value     string
+		fieldType string
+		want      string
+	}{
+		{" sk_live_123\n", "secret", "sk_live_123"},
+		{"\thttps://example.com ", "url", "https://example.com"},
+		{"hello@example.com \r\n", "email", "hello@example.com"},
+		{"  ", "secret", ""},
+		{" Acme Inc ", "text", " Acme Inc "},
+		{" Acme Inc ", "", " Acme Inc "},
+	}
+	for _, tt := range tests {
+		if got := NormalizeFieldValue(tt.value, FieldRules{Type: tt.fieldType}); got != tt.want {
+			t.Errorf("NormalizeFieldValue(%q, %q) = %q, want %q", tt.value, tt.fieldType, got, tt.want)
+		}
+	}
+}
+
+func TestEnvRules(t *testing.T) {
+	lo := 1.0
+	env := manifest.EnvVar{Key: "PORT", Type: "number", Required: true, Min: &lo, Pattern: "^[0-9]+$"}
+	rules := EnvRules(env)
+	if rules.Type != "number" || !rules.Required || rules.Min != &lo || rules.Pattern != "^[0-9]+$" {
+		t.Errorf("EnvRules() = %+v, want the env var's type, required, min and pattern", rules)
+	}
+	if err := ValidateFieldValue("", rules); err == nil {
+		t.Error("a required env var should reject an empty value")
+	}
+	if err := ValidateFieldValue("", ConfigRules(manifest.ConfigField{Type: "number", Min: &lo})); err != nil {
+		t.Errorf("config fields are never required, got %v", err)
+	}
+}
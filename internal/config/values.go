@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// environmentsTable is the values file table holding values for one
+// environment of [env_environments], as [environments.production].
+const environmentsTable = "environments"
+
+// Values are answers given to configure up front, from a values file and
+// --set flags, so it can run without prompting.
+type Values struct {
+	Shared       map[string]string            // by env key or config field path
+	Environments map[string]map[string]string // per-environment env values, by environment, then key
+}
+
+// NewValues returns an empty set of values.
+func NewValues() *Values {
+	return &Values{Shared: make(map[string]string), Environments: make(map[string]map[string]string)}
+}
+
+// ParseValues reads a values file: TOML mapping env keys and config field
+// paths to values. A config path can be quoted ("site.name" = "Acme") or
+// written as a table ([site] with name = "Acme"), and the values for one
+// environment go in an [environments.<name>] table. Numbers and booleans
+// are taken as written.
+func ParseValues(data []byte) (*Values, error) {
+	var raw map[string]any
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid values file: %w", err)
+	}
+	v := NewValues()
+	if err := v.add("", raw); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// add records the values of a TOML table whose keys start with prefix.
+func (v *Values) add(prefix string, table map[string]any) error {
+	for _, key := range slices.Sorted(maps.Keys(table)) {
+		path := prefix + key
+		switch val := table[key].(type) {
+		case map[string]any:
+			if err := v.add(path+".", val); err != nil {
+				return err
+			}
+		case string:
+			v.set(path, val)
+		case int64:
+			v.set(path, strconv.FormatInt(val, 10))
+		case float64:
+			v.set(path, strconv.FormatFloat(val, 'f', -1, 64))
+		case bool:
+			v.set(path, strconv.FormatBool(val))
+		default:
+			return fmt.Errorf("invalid values file: %s must be a string, number or boolean", path)
+		}
+	}
+	return nil
+}
+
+// Set records an assignment from --set, KEY=VALUE, over the value the
+// values file gave. environments.<name>.KEY=VALUE sets the value for one
+// environment.
+func (v *Values) Set(assignment string) error {
+	key, value, ok := strings.Cut(assignment, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("invalid --set %q: want KEY=VALUE", assignment)
+	}
+	v.set(key, value)
+	return nil
+}
+
+func (v *Values) set(path, value string) {
+	if rest, ok := strings.CutPrefix(path, environmentsTable+"."); ok {
+		if name, key, ok := strings.Cut(rest, "."); ok {
+			if v.Environments[name] == nil {
+				v.Environments[name] = make(map[string]string)
+			}
+			v.Environments[name][key] = value
+			return
+		}
+	}
+	v.Shared[path] = value
+}
+
+// Resolved are the values configure writes, worked out from Values.
+type Resolved struct {
+	Env              map[string]string            // env values asked once
+	EnvByEnvironment map[string]map[string]string // per-environment env values
+	Config           map[string]string            // config values, by field path
+}
+
+// ValuesError lists what is wrong with the values given to configure.
+type ValuesError struct {
+	Missing []string // required fields left without a value
+	Invalid []string // values that break their field's rules, with why
+	Unknown []string // keys that match no env var or config field
+}
+
+func (e *ValuesError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing required values: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		parts = append(parts, "invalid values: "+strings.Join(e.Invalid, "; "))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown keys: "+strings.Join(e.Unknown, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Resolve works out the value of each field configure would ask for (in
+// environment only, when set): the value given, else the one in the
+// existing env files, else the default. An environment's own var falls
+// back to a value given for all environments. Values are tidied with
+// NormalizeFieldValue and must pass ValidateFieldValue; fields hidden by
+// their when condition are left out. A *ValuesError lists the required
+// fields left empty, the invalid values and the keys that match no field.
+func (v *Values) Resolve(m *manifest.Manifest, existing ExistingEnv, only string) (*Resolved, error) {
+	r := &Resolved{
+		Env:              make(map[string]string),
+		EnvByEnvironment: make(map[string]map[string]string),
+		Config:           make(map[string]string),
+	}
+	verr := &ValuesError{}
+	check := func(name, value string, rules FieldRules) {
+		if strings.TrimSpace(value) == "" && rules.Required {
+			verr.Missing = append(verr.Missing, name)
+		} else if err := ValidateFieldValue(value, rules); err != nil {
+			verr.Invalid = append(verr.Invalid, fmt.Sprintf("%s %s", name, err))
+		}
+	}
+
+	for _, p := range EnvPrompts(m, only) {
+		env, name := p.Var, p.Var.Key
+		values := r.Env
+		if p.Environment != "" {
+			if r.EnvByEnvironment[p.Environment] == nil {
+				r.EnvByEnvironment[p.Environment] = make(map[string]string)
+			}
+			values = r.EnvByEnvironment[p.Environment]
+			name = fmt.Sprintf("%s (%s)", env.Key, p.Environment)
+		}
+		if !p.Shown(r.Env, r.EnvByEnvironment) {
+			continue
+		}
+
+		value, ok := v.Environments[p.Environment][env.Key]
+		if !ok {
+			value, ok = v.Shared[env.Key]
+		}
+		if !ok {
+			if value = existing.Value(p); value == "" {
+				value = env.Default
+			}
+		}
+		rules := EnvRules(env)
+		value = NormalizeFieldValue(value, rules)
+		check(name, value, rules)
+		values[env.Key] = value
+	}
+
+	// Config conditions can refer to the env values and the fields before them
+	shown := maps.Clone(r.Env)
+	for _, cfg := range m.Config {
+		for _, f := range cfg.Fields {
+			if !manifest.Shown(f.When, shown) {
+				continue
+			}
+			value, ok := v.Shared[f.Path]
+			if !ok {
+				value = f.Default
+			}
+			rules := ConfigRules(f)
+			value = NormalizeFieldValue(value, rules)
+			check(f.Path, value, rules)
+			r.Config[f.Path] = value
+			shown[f.Path] = value
+		}
+	}
+
+	verr.Unknown = v.unknown(m)
+	if len(verr.Missing) > 0 || len(verr.Invalid) > 0 || len(verr.Unknown) > 0 {
+		return nil, verr
+	}
+	return r, nil
+}
+
+// unknown returns the keys given that match no field of m, sorted.
+func (v *Values) unknown(m *manifest.Manifest) []string {
+	var unknown []string
+	for key := range v.Shared {
+		known := slices.ContainsFunc(m.Env, func(env manifest.EnvVar) bool { return env.Key == key })
+		for _, cfg := range m.Config {
+			known = known || slices.ContainsFunc(cfg.Fields, func(f manifest.ConfigField) bool { return f.Path == key })
+		}
+		if !known {
+			unknown = append(unknown, key)
+		}
+	}
+	for name, values := range v.Environments {
+		for key := range values {
+			known := IsEnvironment(m, name) && slices.ContainsFunc(m.Env, func(env manifest.EnvVar) bool {
+				return env.Key == key && contains(env.Environments, name)
+			})
+			if !known {
+				unknown = append(unknown, fmt.Sprintf("%s.%s.%s", environmentsTable, name, key))
+			}
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
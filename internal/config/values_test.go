@@ -0,0 +1,226 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func valuesManifest(t *testing.T) *manifest.Manifest {
+	t.Helper()
+	m, err := manifest.Parse([]byte(`
+[template]
+name = "Test"
+version = "1.0.0"
+
+[env_environments]
+names = ["development", "production"]
+
+[[env]]
+key = "SITE_URL"
+label = "Site URL"
+type = "url"
+required = true
+
+[[env]]
+key = "PORT"
+label = "Port"
+type = "number"
+default = "3000"
+
+[[env]]
+key = "API_KEY"
+label = "API key"
+type = "secret"
+required = true
+environments = ["development", "production"]
+
+[[env]]
+key = "EMAIL"
+label = "Email provider"
+type = "select"
+options = ["none", "smtp"]
+default = "none"
+
+[[env]]
+key = "SMTP_HOST"
+label = "SMTP host"
+required = true
+when = "EMAIL == 'smtp'"
+
+[[config]]
+file = "src/config/site.ts"
+label = "Site"
+
+[[config.fields]]
+path = "site.name"
+label = "Site name"
+default = "My Site"
+
+[[config.fields]]
+path = "site.plan"
+label = "Plan"
+type = "select"
+options = ["free", "pro"]
+default = "free"
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %s", err)
+	}
+	return m
+}
+
+func TestParseValues(t *testing.T) {
+	v, err := ParseValues([]byte(`
+SITE_URL = "https://example.com"
+PORT = 8080
+"site.plan" = "pro"
+
+[site]
+name = "Acme"
+
+[environments.production]
+API_KEY = "prod-key"
+`))
+	if err != nil {
+		t.Fatalf("ParseValues failed: %s", err)
+	}
+	wantShared := map[string]string{"SITE_URL": "https://example.com", "PORT": "8080", "site.plan": "pro", "site.name": "Acme"}
+	if !reflect.DeepEqual(v.Shared, wantShared) {
+		t.Errorf("Shared = %v, want %v", v.Shared, wantShared)
+	}
+	wantEnvs := map[string]map[string]string{"production": {"API_KEY": "prod-key"}}
+	if !reflect.DeepEqual(v.Environments, wantEnvs) {
+		t.Errorf("Environments = %v, want %v", v.Environments, wantEnvs)
+	}
+
+	if _, err := ParseValues([]byte(`TAGS = ["a", "b"]`)); err == nil || !strings.Contains(err.Error(), "TAGS") {
+		t.Errorf("expected an error naming the array value, got %v", err)
+	}
+	if _, err := ParseValues([]byte(`SITE_URL = `)); err == nil {
+		t.Error("expected an error for invalid TOML")
+	}
+}
+
+func TestValuesSet(t *testing.T) {
+	v := NewValues()
+	for _, a := range []string{"PORT=8080", "SITE_URL=https://a.example/?q=1", "environments.development.API_KEY=dev", "EMPTY="} {
+		if err := v.Set(a); err != nil {
+			t.Fatalf("Set(%q) failed: %s", a, err)
+		}
+	}
+	if v.Shared["PORT"] != "8080" || v.Shared["SITE_URL"] != "https://a.example/?q=1" || v.Shared["EMPTY"] != "" {
+		t.Errorf("Shared = %v", v.Shared)
+	}
+	if v.Environments["development"]["API_KEY"] != "dev" {
+		t.Errorf("Environments = %v", v.Environments)
+	}
+	for _, a := range []string{"PORT", "=8080"} {
+		if err := v.Set(a); err == nil {
+			t.Errorf("Set(%q): expected an error", a)
+		}
+	}
+}
+
+func TestValuesResolve(t *testing.T) {
+	m := valuesManifest(t)
+	v := NewValues()
+	for _, a := range []string{
+		"SITE_URL= https://example.com ",
+		"API_KEY=shared-key",
+		"environments.production.API_KEY=prod-key",
+		"site.name=Acme",
+	} {
+		if err := v.Set(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := v.Resolve(m, ExistingEnv{}, "")
+	if err != nil {
+		t.Fatalf("Resolve failed: %s", err)
+	}
+	wantEnv := map[string]string{"SITE_URL": "https://example.com", "PORT": "3000", "EMAIL": "none"}
+	if !reflect.DeepEqual(r.Env, wantEnv) {
+		t.Errorf("Env = %v, want %v", r.Env, wantEnv)
+	}
+	wantPerEnv := map[string]map[string]string{
+		"development": {"API_KEY": "shared-key"},
+		"production":  {"API_KEY": "prod-key"},
+	}
+	if !reflect.DeepEqual(r.EnvByEnvironment, wantPerEnv) {
+		t.Errorf("EnvByEnvironment = %v, want %v", r.EnvByEnvironment, wantPerEnv)
+	}
+	wantConfig := map[string]string{"site.name": "Acme", "site.plan": "free"}
+	if !reflect.DeepEqual(r.Config, wantConfig) {
+		t.Errorf("Config = %v, want %v", r.Config, wantConfig)
+	}
+}
+
+func TestValuesResolve_ExistingValues(t *testing.T) {
+	m := valuesManifest(t)
+	existing := ExistingEnv{
+		order: []string{"development", "production"},
+		values: map[string]map[string]string{
+			"development": {"SITE_URL": "https://dev.example.com", "API_KEY": "old-dev"},
+			"production":  {"API_KEY": "old-prod"},
+		},
+	}
+	v := NewValues()
+	v.Set("environments.production.API_KEY=new-prod")
+
+	r, err := v.Resolve(m, existing, "production")
+	if err != nil {
+		t.Fatalf("Resolve failed: %s", err)
+	}
+	if r.Env["SITE_URL"] != "https://dev.example.com" {
+		t.Errorf("SITE_URL = %q, want the existing value", r.Env["SITE_URL"])
+	}
+	if _, ok := r.EnvByEnvironment["development"]; ok {
+		t.Errorf("EnvByEnvironment = %v, want only production", r.EnvByEnvironment)
+	}
+	if r.EnvByEnvironment["production"]["API_KEY"] != "new-prod" {
+		t.Errorf("production API_KEY = %q, want the given value", r.EnvByEnvironment["production"]["API_KEY"])
+	}
+}
+
+func TestValuesResolve_Errors(t *testing.T) {
+	m := valuesManifest(t)
+	v := NewValues()
+	for _, a := range []string{
+		"PORT=eighty",
+		"EMAIL=smtp",
+		"site.plan=enterprise",
+		"SITEURL=https://example.com",
+		"environments.staging.API_KEY=x",
+		"environments.production.PORT=80",
+	} {
+		if err := v.Set(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := v.Resolve(m, ExistingEnv{}, "")
+	var verr *ValuesError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a *ValuesError, got %v", err)
+	}
+	wantMissing := []string{"SITE_URL", "SMTP_HOST", "API_KEY (development)", "API_KEY (production)"}
+	if !reflect.DeepEqual(verr.Missing, wantMissing) {
+		t.Errorf("Missing = %v, want %v", verr.Missing, wantMissing)
+	}
+	wantInvalid := []string{"PORT must be a number", "site.plan must be one of: free, pro"}
+	if !reflect.DeepEqual(verr.Invalid, wantInvalid) {
+		t.Errorf("Invalid = %v, want %v", verr.Invalid, wantInvalid)
+	}
+	wantUnknown := []string{"SITEURL", "environments.production.PORT", "environments.staging.API_KEY"}
+	if !reflect.DeepEqual(verr.Unknown, wantUnknown) {
+		t.Errorf("Unknown = %v, want %v", verr.Unknown, wantUnknown)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "missing required values: SITE_URL, ") {
+		t.Errorf("Error() = %q", msg)
+	}
+}
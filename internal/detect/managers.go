@@ -0,0 +1,138 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Sources of a detected runtime, as reported in RuntimeInfo.Source.
+const (
+	SourcePath     = "path"
+	SourceTemplatr = "templatr" // installed by templatr-setup, reported by the engine from state
+	SourceNvm      = "nvm"
+	SourcePyenv    = "pyenv"
+	SourceAsdf     = "asdf"
+	SourceVolta    = "volta"
+)
+
+// ManagedVersion is a runtime version installed by a version manager,
+// which may not be active in the current shell.
+type ManagedVersion struct {
+	Version  string
+	Path     string // the runtime's executable
+	Source   string // e.g. SourceNvm
+	Activate string // command that puts this version on PATH, e.g. "nvm use 22.14.0"
+}
+
+// versionManager describes where a version manager keeps one runtime:
+// a directory with one subdirectory per version, and the executable's path
+// inside it.
+type versionManager struct {
+	source   string
+	runtime  string // detection name, e.g. "Node.js"
+	root     func(home string) string
+	exe      string // relative to a version directory
+	activate string // command template, %s is the version
+}
+
+func exeName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// envOr returns the environment variable's value, or filepath.Join(home, def...).
+func envOr(name, home string, def ...string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return filepath.Join(append([]string{home}, def...)...)
+}
+
+func asdfRoot(plugin string) func(home string) string {
+	return func(home string) string {
+		return filepath.Join(envOr("ASDF_DATA_DIR", home, ".asdf"), "installs", plugin)
+	}
+}
+
+var versionManagers = []versionManager{
+	{
+		source: SourceNvm, runtime: "Node.js",
+		root:     func(home string) string { return filepath.Join(envOr("NVM_DIR", home, ".nvm"), "versions", "node") },
+		exe:      filepath.Join("bin", "node"),
+		activate: "nvm use %s",
+	},
+	{
+		source: SourceVolta, runtime: "Node.js",
+		root: func(home string) string {
+			return filepath.Join(envOr("VOLTA_HOME", home, ".volta"), "tools", "image", "node")
+		},
+		exe:      filepath.Join("bin", "node"),
+		activate: "volta install node@%s",
+	},
+	{
+		source: SourcePyenv, runtime: "Python",
+		root:     func(home string) string { return filepath.Join(envOr("PYENV_ROOT", home, ".pyenv"), "versions") },
+		exe:      filepath.Join("bin", "python3"),
+		activate: "pyenv shell %s",
+	},
+	{source: SourceAsdf, runtime: "Node.js", root: asdfRoot("nodejs"), exe: filepath.Join("bin", "node"), activate: "asdf shell nodejs %s"},
+	{source: SourceAsdf, runtime: "Python", root: asdfRoot("python"), exe: filepath.Join("bin", "python3"), activate: "asdf shell python %s"},
+	{source: SourceAsdf, runtime: "Go", root: asdfRoot("golang"), exe: filepath.Join("go", "bin", "go"), activate: "asdf shell golang %s"},
+	{source: SourceAsdf, runtime: "Ruby", root: asdfRoot("ruby"), exe: filepath.Join("bin", "ruby"), activate: "asdf shell ruby %s"},
+	{source: SourceAsdf, runtime: "Deno", root: asdfRoot("deno"), exe: filepath.Join("bin", "deno"), activate: "asdf shell deno %s"},
+	{source: SourceAsdf, runtime: "bun", root: asdfRoot("bun"), exe: filepath.Join("bin", "bun"), activate: "asdf shell bun %s"},
+	{source: SourceAsdf, runtime: "Flutter", root: asdfRoot("flutter"), exe: filepath.Join("bin", "flutter"), activate: "asdf shell flutter %s"},
+}
+
+// ManagedVersions returns the versions of a runtime (by detection name,
+// e.g. "Node.js") installed through nvm, Volta, pyenv or asdf, newest
+// first. Only directories named like a version that contain the runtime's
+// executable count.
+func ManagedVersions(name string) []ManagedVersion {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var found []ManagedVersion
+	for _, vm := range versionManagers {
+		if vm.runtime != name {
+			continue
+		}
+		entries, err := os.ReadDir(vm.root(home))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			version := strings.TrimPrefix(e.Name(), "v")
+			if _, err := semver.StrictNewVersion(version); err != nil {
+				continue // e.g. pyenv's "miniconda3-latest" or asdf's "system"
+			}
+			exe := filepath.Join(vm.root(home), e.Name(), exeName(vm.exe))
+			if _, err := os.Stat(exe); err != nil {
+				continue
+			}
+			found = append(found, ManagedVersion{
+				Version:  version,
+				Path:     exe,
+				Source:   vm.source,
+				Activate: strings.ReplaceAll(vm.activate, "%s", version),
+			})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return semver.MustParse(found[i].Version).GreaterThan(semver.MustParse(found[j].Version))
+	})
+	return found
+}
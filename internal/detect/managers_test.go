@@ -0,0 +1,104 @@
+package detect
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeManagerHome sets HOME to a temp dir with an executable at each of the
+// given paths (relative to HOME) and clears the version manager env vars.
+func fakeManagerHome(t *testing.T, exes ...string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("version manager layouts differ on Windows")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, v := range []string{"NVM_DIR", "VOLTA_HOME", "PYENV_ROOT", "ASDF_DATA_DIR"} {
+		t.Setenv(v, "")
+	}
+	for _, exe := range exes {
+		path := filepath.Join(home, exe)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func TestManagedVersions(t *testing.T) {
+	home := fakeManagerHome(t,
+		".nvm/versions/node/v20.11.0/bin/node",
+		".nvm/versions/node/v22.14.0/bin/node",
+		".volta/tools/image/node/18.19.0/bin/node",
+		".asdf/installs/nodejs/21.7.3/bin/node",
+		".pyenv/versions/3.12.8/bin/python3",
+		".pyenv/versions/miniconda3-latest/bin/python3",
+		".asdf/installs/golang/1.22.5/go/bin/go",
+	)
+	os.MkdirAll(filepath.Join(home, ".nvm/versions/node/v23.0.0"), 0o755) // no node binary
+
+	node := ManagedVersions("Node.js")
+	want := []struct{ version, source, activate string }{
+		{"22.14.0", SourceNvm, "nvm use 22.14.0"},
+		{"21.7.3", SourceAsdf, "asdf shell nodejs 21.7.3"},
+		{"20.11.0", SourceNvm, "nvm use 20.11.0"},
+		{"18.19.0", SourceVolta, "volta install node@18.19.0"},
+	}
+	if len(node) != len(want) {
+		t.Fatalf("ManagedVersions(Node.js) = %+v, want %d versions", node, len(want))
+	}
+	for i, w := range want {
+		if node[i].Version != w.version || node[i].Source != w.source || node[i].Activate != w.activate {
+			t.Errorf("node[%d] = %+v, want %+v", i, node[i], w)
+		}
+	}
+	if node[0].Path != filepath.Join(home, ".nvm/versions/node/v22.14.0/bin/node") {
+		t.Errorf("Path = %s, want the nvm node binary", node[0].Path)
+	}
+
+	python := ManagedVersions("Python")
+	if len(python) != 1 || python[0].Version != "3.12.8" || python[0].Source != SourcePyenv {
+		t.Errorf("ManagedVersions(Python) = %+v, want only pyenv 3.12.8", python)
+	}
+	if golang := ManagedVersions("Go"); len(golang) != 1 || golang[0].Version != "1.22.5" {
+		t.Errorf("ManagedVersions(Go) = %+v, want asdf golang 1.22.5", golang)
+	}
+	if rust := ManagedVersions("Rust"); len(rust) != 0 {
+		t.Errorf("ManagedVersions(Rust) = %+v, want none", rust)
+	}
+}
+
+func TestManagedVersions_EnvOverride(t *testing.T) {
+	fakeManagerHome(t)
+	nvmDir := t.TempDir()
+	t.Setenv("NVM_DIR", nvmDir)
+	exe := filepath.Join(nvmDir, "versions", "node", "v22.14.0", "bin", "node")
+	os.MkdirAll(filepath.Dir(exe), 0o755)
+	os.WriteFile(exe, nil, 0o755)
+
+	if got := ManagedVersions("Node.js"); len(got) != 1 || got[0].Path != exe {
+		t.Errorf("NVM_DIR should be honoured, got %+v", got)
+	}
+}
+
+func TestScanRuntimes_FallsBackToManagers(t *testing.T) {
+	fakeManagerHome(t, ".nvm/versions/node/v22.14.0/bin/node")
+	fakeBinaries(t, map[string]string{"git": "echo git version 2.45.0"}, []string{"git"})
+	checks = []runtimeCheck{
+		{Name: "Node.js", Binary: "templatr-missing-node", VersionArg: "--version"},
+		{Name: "Git", Binary: "git", VersionArg: "--version"},
+	}
+
+	results := ScanRuntimes(context.Background())
+	if !results[0].Installed || results[0].Version != "22.14.0" || results[0].Source != SourceNvm {
+		t.Errorf("node should be found through nvm, got %+v", results[0])
+	}
+	if results[1].Source != SourcePath {
+		t.Errorf("git on PATH should have source %q, got %+v", SourcePath, results[1])
+	}
+}
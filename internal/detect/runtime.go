@@ -1,8 +1,11 @@
 package detect
 
 import (
+	"context"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 )
 
 // RuntimeInfo holds the detection result for a single runtime.
@@ -11,6 +14,7 @@ type RuntimeInfo struct {
 	Installed bool
 	Version   string
 	Path      string
+	Source    string // SourcePath, or the version manager it was found in (e.g. SourceNvm)
 }
 
 // runtimeCheck defines how to detect a runtime.
@@ -26,6 +30,7 @@ var checks = []runtimeCheck{
 	{Name: "pnpm", Binary: "pnpm", VersionArg: "--version"},
 	{Name: "yarn", Binary: "yarn", VersionArg: "--version"},
 	{Name: "bun", Binary: "bun", VersionArg: "--version"},
+	{Name: "Deno", Binary: "deno", VersionArg: "--version"},
 	{Name: "Python", Binary: "python3", VersionArg: "--version"},
 	{Name: "pip", Binary: "pip3", VersionArg: "--version"},
 	{Name: "Flutter", Binary: "flutter", VersionArg: "--version"},
@@ -40,29 +45,61 @@ var checks = []runtimeCheck{
 	{Name: "Git", Binary: "git", VersionArg: "--version"},
 }
 
-// ScanRuntimes checks all known runtimes and returns their status.
-func ScanRuntimes() []RuntimeInfo {
-	results := make([]RuntimeInfo, 0, len(checks))
-
-	for _, c := range checks {
-		info := detectRuntime(c)
-		results = append(results, info)
+// maxConcurrentChecks bounds how many version commands run at once.
+const maxConcurrentChecks = 6
+
+// versionTimeout is how long a version command may take. A binary that
+// hangs (e.g. flutter running its first-run upgrade check) is reported as
+// installed with an unknown version instead of blocking the scan.
+var versionTimeout = 3 * time.Second
+
+// ScanRuntimes checks all known runtimes concurrently and returns their
+// status in a fixed order. A runtime that isn't on PATH is reported with
+// the newest version a version manager (nvm, Volta, pyenv, asdf) has
+// installed, if any. Once ctx is cancelled, the checks still running are
+// stopped and the remaining runtimes are reported as not installed.
+func ScanRuntimes(ctx context.Context) []RuntimeInfo {
+	results := make([]RuntimeInfo, len(checks))
+
+	sem := make(chan struct{}, maxConcurrentChecks)
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			info := detectRuntime(ctx, c)
+			if !info.Installed && ctx.Err() == nil {
+				if managed := ManagedVersions(c.Name); len(managed) > 0 {
+					info.Installed = true
+					info.Version = managed[0].Version
+					info.Path = managed[0].Path
+					info.Source = managed[0].Source
+				}
+			}
+			results[i] = info
+		}()
 	}
+	wg.Wait()
 
 	return results
 }
 
 // DetectRuntime checks a specific runtime by binary name.
 func DetectRuntime(binary, versionArg string) RuntimeInfo {
-	return detectRuntime(runtimeCheck{
+	return detectRuntime(context.Background(), runtimeCheck{
 		Name:       binary,
 		Binary:     binary,
 		VersionArg: versionArg,
 	})
 }
 
-func detectRuntime(c runtimeCheck) RuntimeInfo {
+func detectRuntime(ctx context.Context, c runtimeCheck) RuntimeInfo {
 	info := RuntimeInfo{Name: c.Name}
+	if ctx.Err() != nil {
+		return info
+	}
 
 	// Find the binary on PATH.
 	path, err := exec.LookPath(c.Binary)
@@ -85,9 +122,17 @@ func detectRuntime(c runtimeCheck) RuntimeInfo {
 	}
 
 	// Get version.
-	out, err := exec.Command(path, c.VersionArg).CombinedOutput()
+	cmdCtx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(cmdCtx, path, c.VersionArg)
+	// Don't wait on children that outlive the killed command and hold its output open
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
 	outStr := string(out)
 
+	if ctx.Err() != nil {
+		return info // cancelled, not checked
+	}
 	if err != nil {
 		// Windows has stub executables (e.g. python3.exe in WindowsApps) that
 		// appear on PATH but aren't actually installed. Detect these false positives.
@@ -100,12 +145,14 @@ func detectRuntime(c runtimeCheck) RuntimeInfo {
 		}
 		info.Installed = true
 		info.Path = path
+		info.Source = SourcePath
 		info.Version = "installed (version unknown)"
 		return info
 	}
 
 	info.Installed = true
 	info.Path = path
+	info.Source = SourcePath
 	info.Version = parseVersion(outStr)
 	return info
 }
@@ -138,6 +185,7 @@ func parseVersion(output string) string {
 	output = strings.TrimPrefix(output, "rustc ")
 	output = strings.TrimPrefix(output, "ruby ")
 	output = strings.TrimPrefix(output, "php ")
+	output = strings.TrimPrefix(output, "deno ")
 	output = strings.TrimPrefix(output, "git version ")
 	output = strings.TrimPrefix(output, "Dart SDK version: ")
 
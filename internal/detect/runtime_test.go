@@ -1,7 +1,12 @@
 package detect
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestParseVersion(t *testing.T) {
@@ -19,6 +24,8 @@ func TestParseVersion(t *testing.T) {
 		{"git version 2.52.0.windows.1", "2.52.0.windows.1"},
 		{"Dart SDK version: 3.3.0 (stable)", "3.3.0"},
 		{"php 8.3.0 (cli)", "8.3.0"},
+		{"deno 1.44.4 (release, x86_64-unknown-linux-gnu)\nv8 12.6.228.9\ntypescript 5.4.5", "1.44.4"},
+		{"deno 2.0.0 (stable, release, aarch64-apple-darwin)", "2.0.0"},
 		{"10.8.0\n", "10.8.0"},
 		{"10.8.0", "10.8.0"},
 		{"  v20.0.0  \n", "20.0.0"},
@@ -68,7 +75,7 @@ func TestGetSystemInfo(t *testing.T) {
 }
 
 func TestScanRuntimes(t *testing.T) {
-	runtimes := ScanRuntimes()
+	runtimes := ScanRuntimes(context.Background())
 
 	if len(runtimes) == 0 {
 		t.Error("ScanRuntimes() returned no results")
@@ -87,3 +94,91 @@ func TestScanRuntimes(t *testing.T) {
 		}
 	}
 }
+
+// fakeBinaries writes shell scripts that print a version after sleeping,
+// puts them first on PATH and makes them the only checks.
+func fakeBinaries(t *testing.T, scripts map[string]string, order []string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake binaries are shell scripts")
+	}
+	dir := t.TempDir()
+	var fake []runtimeCheck
+	for _, name := range order {
+		script := "#!/bin/sh\n" + scripts[name] + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		fake = append(fake, runtimeCheck{Name: name, Binary: name, VersionArg: "--version"})
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	oldChecks, oldTimeout := checks, versionTimeout
+	checks = fake
+	t.Cleanup(func() { checks, versionTimeout = oldChecks, oldTimeout })
+}
+
+func TestScanRuntimes_ConcurrentAndOrdered(t *testing.T) {
+	scripts := map[string]string{
+		"fake-slow":  "sleep 0.3; echo v1.0.0",
+		"fake-fast":  "echo v2.0.0",
+		"fake-mid":   "sleep 0.2; echo v3.0.0",
+		"fake-quick": "echo v4.0.0",
+	}
+	order := []string{"fake-slow", "fake-fast", "fake-mid", "fake-quick"}
+	fakeBinaries(t, scripts, order)
+
+	start := time.Now()
+	results := ScanRuntimes(context.Background())
+	elapsed := time.Since(start)
+
+	want := []string{"1.0.0", "2.0.0", "3.0.0", "4.0.0"}
+	if len(results) != len(order) {
+		t.Fatalf("got %d results, want %d", len(results), len(order))
+	}
+	for i, r := range results {
+		if r.Name != order[i] || r.Version != want[i] || !r.Installed {
+			t.Errorf("results[%d] = %+v, want %s %s", i, r, order[i], want[i])
+		}
+	}
+	if elapsed > 450*time.Millisecond {
+		t.Errorf("checks should run concurrently, took %s", elapsed)
+	}
+}
+
+func TestScanRuntimes_Timeout(t *testing.T) {
+	scripts := map[string]string{
+		"fake-hung": "sleep 10; echo v1.0.0",
+		"fake-ok":   "echo v2.0.0",
+	}
+	fakeBinaries(t, scripts, []string{"fake-hung", "fake-ok"})
+	versionTimeout = 200 * time.Millisecond
+
+	start := time.Now()
+	results := ScanRuntimes(context.Background())
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("a hung binary should time out, scan took %s", elapsed)
+	}
+	if !results[0].Installed || results[0].Version != "installed (version unknown)" {
+		t.Errorf("hung binary = %+v, want installed with an unknown version", results[0])
+	}
+	if results[1].Version != "2.0.0" {
+		t.Errorf("other checks should be unaffected, got %+v", results[1])
+	}
+}
+
+func TestScanRuntimes_Cancelled(t *testing.T) {
+	fakeBinaries(t, map[string]string{"fake-slow": "sleep 10; echo v1.0.0"}, []string{"fake-slow"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	results := ScanRuntimes(ctx)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("cancelling should stop the scan, took %s", elapsed)
+	}
+	if results[0].Installed {
+		t.Errorf("a check stopped by cancellation should not report the runtime, got %+v", results[0])
+	}
+}
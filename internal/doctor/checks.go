@@ -0,0 +1,287 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/state"
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+// CheckPermissions turns the issues install.Preflight found into results.
+func CheckPermissions(issues []install.PreflightIssue) []Result {
+	if len(issues) == 0 {
+		return []Result{{Check: "permissions", Status: Pass, Message: "All checks passed"}}
+	}
+	var results []Result
+	for _, issue := range issues {
+		results = append(results, Result{Check: "permissions", Status: Fail, Message: issue.Error(), Fix: issue.Fix})
+	}
+	return results
+}
+
+// CheckPath verifies that every bin directory recorded in st is on fresh,
+// the PATH a new shell starts with, or reports freshErr if that couldn't be
+// found out. Entries that point at a specific version rather than its
+// current link are flagged too.
+func CheckPath(st *state.State, fresh []string, freshErr error) []Result {
+	if len(st.PathModifications) == 0 {
+		return nil
+	}
+
+	var results []Result
+	if freshErr != nil {
+		results = append(results, Result{
+			Check:   "path",
+			Status:  Warn,
+			Message: fmt.Sprintf("could not read the PATH of a new shell: %s", freshErr),
+			Fix:     "Open a new terminal and check that the directories below are on PATH.",
+		})
+	}
+	for _, mod := range st.PathModifications {
+		switch {
+		case mod.Method == "powershell_profile" && inProfile(mod):
+			results = append(results, Result{Check: "path", Status: Pass, Message: mod.Value})
+		case freshErr != nil:
+			results = append(results, Result{Check: "path", Status: Warn, Message: mod.Value})
+		case onPath(fresh, mod.Value):
+			results = append(results, Result{Check: "path", Status: Pass, Message: mod.Value})
+		default:
+			results = append(results, Result{
+				Check:   "path",
+				Status:  Fail,
+				Message: fmt.Sprintf("%s is not on PATH in a new shell", mod.Value),
+				Fix:     pathFix(mod),
+			})
+		}
+	}
+
+	for _, mod := range install.StalePathEntries(st) {
+		results = append(results, Result{
+			Check:   "path",
+			Status:  Warn,
+			Message: fmt.Sprintf("%s points at a specific version", mod.Value),
+			Fix:     "Run 'templatr-setup setup' to replace it with a stable current/bin entry.",
+		})
+	}
+	return results
+}
+
+// CheckShellBlocks warns about each templatr-setup block in a shell config
+// file that state.json doesn't account for: blocks left for old versions
+// and second blocks for the same entry, where whichever comes last wins.
+func CheckShellBlocks(untidy []install.UntidyBlock, err error) []Result {
+	if err != nil {
+		return []Result{{Check: "path", Status: Warn, Message: fmt.Sprintf("could not check the shell config files: %s", err)}}
+	}
+	var results []Result
+	for _, u := range untidy {
+		results = append(results, Result{
+			Check:   "path",
+			Status:  Warn,
+			Message: fmt.Sprintf("%s, %s", u.File, u.Describe()),
+			Fix:     "Run 'templatr-setup path tidy' to remove the blocks state.json doesn't need.",
+		})
+	}
+	return results
+}
+
+// pathFix says how to get mod's directory back on PATH.
+func pathFix(mod state.PathModification) string {
+	if mod.Method == "windows_env" {
+		return "Run 'templatr-setup setup' again to re-add it to the user PATH, then open a new terminal."
+	}
+	where := "your shell config"
+	if files := mod.ConfigFiles(); len(files) > 0 {
+		where = strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("The line for it in %s was removed or is overridden by a later PATH line. Run 'templatr-setup setup' again to re-add it.", where)
+}
+
+// inProfile reports whether the line for mod, added through the PowerShell
+// profiles rather than the user environment, is still in one of them; a
+// new PowerShell gets it from there.
+func inProfile(mod state.PathModification) bool {
+	marker, _, _ := strings.Cut(mod.Line, "\n")
+	for _, profile := range mod.ConfigFiles() {
+		if data, err := os.ReadFile(profile); err == nil && strings.Contains(string(data), marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// onPath reports whether dir is one of the entries in path.
+func onPath(path []string, dir string) bool {
+	for _, entry := range path {
+		if samePath(entry, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func samePath(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// networkURLs are the release hosts whose reachability is checked.
+var networkURLs = []string{
+	"https://nodejs.org/dist/index.json",
+	"https://go.dev/dl/?mode=json",
+	"https://api.adoptium.net/v3/info/available_releases",
+}
+
+// CheckNetwork sends a HEAD request to each of urls at once. Any HTTP
+// response counts as reachable; only a failed connection fails.
+func CheckNetwork(ctx context.Context, client *http.Client, urls []string) []Result {
+	results := make([]Result, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = checkURL(ctx, client, u)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func checkURL(ctx context.Context, client *http.Client, u string) Result {
+	host := u
+	if parsed, err := url.Parse(u); err == nil {
+		host = parsed.Host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return Result{Check: "network", Status: Fail, Message: fmt.Sprintf("%s: %s", host, err)}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{
+			Check:   "network",
+			Status:  Fail,
+			Message: fmt.Sprintf("%s is unreachable: %s", host, err),
+			Fix:     "Check your connection and proxy settings (HTTPS_PROXY, or http.proxy and http.ca_bundle via templatr-setup config). Behind a firewall, set [mirrors] in ~/.templatr/config.toml or install with --offline --archives <dir>.",
+		}
+	}
+	resp.Body.Close()
+	return Result{Check: "network", Status: Pass, Message: fmt.Sprintf("%s is reachable", host)}
+}
+
+// Free space below which the disk check warns or fails. Flutter alone
+// takes over 3 GB once extracted.
+const (
+	lowDiskSpace     = 5 << 30
+	tooLowDiskSpace  = 1 << 30
+	diskSpaceFixHint = "Free up space on this volume, or move the runtimes to a larger one with 'templatr-setup state rehome <dir>'."
+)
+
+// CheckDisk reports the space free returns for the volume holding dir.
+func CheckDisk(dir string, free func(dir string) (uint64, error)) Result {
+	n, err := free(dir)
+	if err != nil {
+		return Result{Check: "disk", Status: Warn, Message: fmt.Sprintf("could not determine free space for %s: %s", dir, err)}
+	}
+	msg := fmt.Sprintf("%s free for %s", term.FormatBytes(int64(n)), dir)
+	switch {
+	case n < tooLowDiskSpace:
+		return Result{Check: "disk", Status: Fail, Message: msg, Fix: diskSpaceFixHint}
+	case n < lowDiskSpace:
+		return Result{Check: "disk", Status: Warn, Message: msg + "; large runtimes such as Flutter may not fit", Fix: diskSpaceFixHint}
+	}
+	return Result{Check: "disk", Status: Pass, Message: msg}
+}
+
+// CheckState reports where st and the runtimes in runtimesDir have
+// drifted apart, and runtimes recorded in another directory, as left by
+// changing runtimes_dir without moving them.
+func CheckState(st *state.State, runtimesDir string) []Result {
+	drift, err := state.Reconcile(st, runtimesDir)
+	if err != nil {
+		return []Result{{Check: "state", Status: Warn, Message: err.Error()}}
+	}
+
+	var results []Result
+	if base, err := st.RuntimesBase(); err == nil && base != "" && base != runtimesDir {
+		results = append(results, Result{
+			Check:   "state",
+			Status:  Warn,
+			Message: fmt.Sprintf("the runtimes in state.json are in %s, not the runtimes directory %s", base, runtimesDir),
+			Fix:     fmt.Sprintf("Run 'templatr-setup state rehome %s' to move them there, or set runtimes_dir back to %s.", runtimesDir, base),
+		})
+	}
+	if drift.Empty() {
+		if len(results) == 0 {
+			return []Result{{Check: "state", Status: Pass, Message: "state.json matches what is on disk"}}
+		}
+		return results
+	}
+
+	const fix = "Run 'templatr-setup state repair' to reconcile them."
+	if n := len(drift.Missing); n > 0 {
+		results = append(results, Result{Check: "state", Status: Fail, Message: fmt.Sprintf("%d installation(s) in state.json are missing on disk", n), Fix: fix})
+	}
+	if n := len(drift.Orphans); n > 0 {
+		results = append(results, Result{Check: "state", Status: Warn, Message: fmt.Sprintf("%d runtime version(s) on disk are not in state.json", n), Fix: fix})
+	}
+	if n := len(drift.Repoint) + len(drift.DanglingLinks); n > 0 {
+		results = append(results, Result{Check: "state", Status: Warn, Message: fmt.Sprintf("%d current link(s) point at a deleted version", n), Fix: fix})
+	}
+	return results
+}
+
+// CheckManifest loads and validates the manifest at path.
+func CheckManifest(path string) []Result {
+	m, err := manifest.Load(path)
+	if err != nil {
+		return []Result{{
+			Check:   "manifest",
+			Status:  Fail,
+			Message: err.Error(),
+			Fix:     "Fix the TOML syntax; docs/MANIFEST_SPEC.md describes every section.",
+		}}
+	}
+
+	errs := manifest.Validate(m)
+	if len(errs) == 0 {
+		return []Result{{Check: "manifest", Status: Pass, Message: fmt.Sprintf("%s is valid", displayPath(path))}}
+	}
+	var results []Result
+	for _, e := range errs {
+		r := Result{Check: "manifest", Status: Fail, Message: e.Error(), Fix: fmt.Sprintf("Fix %s in %s.", e.Path, displayPath(path))}
+		if e.Severity == manifest.SeverityWarning {
+			r.Status = Warn
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// displayPath returns path relative to the current directory if it is
+// inside it.
+func displayPath(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
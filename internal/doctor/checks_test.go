@@ -0,0 +1,223 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	if got := CheckPermissions(nil); len(got) != 1 || got[0].Status != Pass {
+		t.Errorf("CheckPermissions(nil) = %+v, want one pass", got)
+	}
+
+	issues := []install.PreflightIssue{{Check: "write", Path: "/home/u/.templatr", Problem: "not writable: permission denied", Fix: "Make it writable."}}
+	got := CheckPermissions(issues)
+	if len(got) != 1 || got[0].Status != Fail || got[0].Message != "/home/u/.templatr: not writable: permission denied" || got[0].Fix != "Make it writable." {
+		t.Errorf("CheckPermissions() = %+v, want the issue as a failure", got)
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	base, err := install.RuntimesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := filepath.Join(base, "node", "current", "bin")
+	python := filepath.Join(base, "python", "3.12.8", "bin")
+
+	st := state.NewState()
+	st.AddPathModification(state.PathModification{Method: "shell_rc", File: "/home/u/.bashrc", Value: node})
+	st.AddPathModification(state.PathModification{Method: "shell_rc", File: "/home/u/.zshrc", Value: python})
+
+	got := CheckPath(st, []string{"/usr/bin", node + string(filepath.Separator)}, nil)
+	want := []struct {
+		status Status
+		msg    string
+	}{
+		{Pass, node},
+		{Fail, python + " is not on PATH in a new shell"},
+		{Warn, python + " points at a specific version"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CheckPath() = %+v, want %d results", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Status != w.status || got[i].Message != w.msg {
+			t.Errorf("result %d = %+v, want %s %q", i, got[i], w.status, w.msg)
+		}
+	}
+	if !strings.Contains(got[1].Fix, "/home/u/.zshrc") || !strings.Contains(got[1].Fix, "templatr-setup setup") {
+		t.Errorf("fix = %q, want the shell config file and how to re-add it", got[1].Fix)
+	}
+}
+
+func TestCheckPath_PowerShellProfile(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "Microsoft.PowerShell_profile.ps1")
+	const bin = `C:\Users\dev\.templatr\runtimes\node\current`
+	line := "# templatr-setup: " + bin + "\n$env:PATH = '" + bin + ";' + $env:PATH"
+	if err := os.WriteFile(profile, []byte("Import-Module posh-git\r\n"+strings.ReplaceAll(line, "\n", "\r\n")+"\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := state.NewState()
+	st.AddPathModification(state.PathModification{Method: "powershell_profile", Files: []string{profile}, Line: line, Value: bin})
+	if got := CheckPath(st, []string{`C:\Windows`}, nil); len(got) != 1 || got[0].Status != Pass {
+		t.Errorf("CheckPath() = %+v, want the profile line to count as on PATH", got)
+	}
+
+	os.WriteFile(profile, []byte("Import-Module posh-git\r\n"), 0o644)
+	got := CheckPath(st, []string{`C:\Windows`}, nil)
+	if len(got) != 1 || got[0].Status != Fail || !strings.Contains(got[0].Fix, profile) {
+		t.Errorf("CheckPath() = %+v, want a failure naming the profile", got)
+	}
+}
+
+func TestCheckPath_ShellError(t *testing.T) {
+	st := state.NewState()
+	st.AddPathModification(state.PathModification{Method: "shell_rc", Value: "/opt/bin"})
+
+	got := CheckPath(st, nil, errors.New("/bin/zsh: exit status 1"))
+	if len(got) != 2 || got[0].Status != Warn || !strings.Contains(got[0].Message, "exit status 1") || got[1].Status != Warn {
+		t.Errorf("CheckPath() = %+v, want warnings instead of failures", got)
+	}
+	if got := CheckPath(state.NewState(), nil, nil); len(got) != 0 {
+		t.Errorf("CheckPath() with nothing recorded = %+v, want no results", got)
+	}
+}
+
+func TestCheckShellBlocks(t *testing.T) {
+	untidy := []install.UntidyBlock{
+		{File: "/home/u/.bashrc", Block: install.ManagedBlock{Value: "/home/u/.templatr/runtimes/node/18.19.0/bin", Start: 3, End: 5}, Reason: install.TidyUnknown},
+		{File: "/home/u/.bashrc", Block: install.ManagedBlock{Value: "JAVA_HOME", Start: 9, End: 11}, Reason: install.TidyDuplicate},
+	}
+	got := CheckShellBlocks(untidy, nil)
+	if len(got) != 2 || got[0].Status != Warn || got[1].Status != Warn {
+		t.Fatalf("CheckShellBlocks() = %+v, want two warnings", got)
+	}
+	if got[1].Message != "/home/u/.bashrc, line 10: a second block for JAVA_HOME" || !strings.Contains(got[1].Fix, "path tidy") {
+		t.Errorf("result = %+v, want the file, line and how to tidy it", got[1])
+	}
+
+	if got := CheckShellBlocks(nil, nil); len(got) != 0 {
+		t.Errorf("CheckShellBlocks() with tidy files = %+v, want no results", got)
+	}
+	if got := CheckShellBlocks(nil, errors.New("permission denied")); len(got) != 1 || got[0].Status != Warn {
+		t.Errorf("CheckShellBlocks() with an error = %+v, want a warning", got)
+	}
+}
+
+func TestCheckNetwork(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+		w.WriteHeader(http.StatusNotFound) // still reachable
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	got := CheckNetwork(context.Background(), up.Client(), []string{up.URL + "/dist/index.json", down.URL})
+	if len(got) != 2 {
+		t.Fatalf("CheckNetwork() = %+v, want a result per URL", got)
+	}
+	if got[0].Status != Pass || got[0].Message != strings.TrimPrefix(up.URL, "http://")+" is reachable" {
+		t.Errorf("result 0 = %+v, want the host reachable", got[0])
+	}
+	if got[1].Status != Fail || !strings.Contains(got[1].Message, "is unreachable") || !strings.Contains(got[1].Fix, "--offline") {
+		t.Errorf("result 1 = %+v, want a failure with a fix", got[1])
+	}
+}
+
+func TestCheckDisk(t *testing.T) {
+	tests := []struct {
+		free uint64
+		err  error
+		want Status
+	}{
+		{100 << 30, nil, Pass},
+		{2 << 30, nil, Warn},
+		{100 << 20, nil, Fail},
+		{0, errors.New("statfs: not supported"), Warn},
+	}
+	for _, tt := range tests {
+		got := CheckDisk("/data", func(dir string) (uint64, error) {
+			if dir != "/data" {
+				t.Errorf("free called for %s", dir)
+			}
+			return tt.free, tt.err
+		})
+		if got.Status != tt.want || got.Check != "disk" {
+			t.Errorf("CheckDisk() with %d bytes free = %+v, want %s", tt.free, got, tt.want)
+		}
+		if tt.want == Fail && got.Fix == "" {
+			t.Errorf("a failed disk check should suggest a fix")
+		}
+	}
+}
+
+func TestCheckState(t *testing.T) {
+	base := t.TempDir()
+	kept := filepath.Join(base, "node", "22.14.0")
+	if err := os.MkdirAll(kept, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	st := state.NewState()
+	st.AddInstallation(state.Installation{Runtime: "node", Version: "22.14.0", Path: kept, Action: "install"})
+	if got := CheckState(st, base); len(got) != 1 || got[0].Status != Pass {
+		t.Errorf("CheckState() = %+v, want a pass", got)
+	}
+
+	st.AddInstallation(state.Installation{Runtime: "python", Version: "3.12.8", Path: filepath.Join(base, "python", "3.12.8"), Action: "install"})
+	got := CheckState(st, base)
+	if len(got) != 1 || got[0].Status != Fail || !strings.Contains(got[0].Message, "1 installation(s)") || !strings.Contains(got[0].Fix, "state repair") {
+		t.Errorf("CheckState() = %+v, want the missing installation to fail", got)
+	}
+
+	// runtimes_dir changed without moving the runtimes
+	st = state.NewState()
+	st.AddInstallation(state.Installation{Runtime: "node", Version: "22.14.0", Path: kept, Action: "install"})
+	elsewhere := t.TempDir()
+	got = CheckState(st, elsewhere)
+	if len(got) != 1 || got[0].Status != Warn || !strings.Contains(got[0].Fix, "state rehome "+elsewhere) {
+		t.Errorf("CheckState() = %+v, want a warning suggesting state rehome", got)
+	}
+}
+
+func TestCheckManifest(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, ".templatr.toml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	got := CheckManifest(write("[template]\nname = \"Shop\"\nversion = \"1.0.0\"\n"))
+	if len(got) != 1 || got[0].Status != Pass {
+		t.Errorf("CheckManifest() of a valid manifest = %+v, want a pass", got)
+	}
+
+	got = CheckManifest(write("[template]\nversion = \"1.0.0\"\n"))
+	if len(got) != 1 || got[0].Status != Fail || got[0].Message != "[template] name is required" || !strings.Contains(got[0].Fix, "template.name") {
+		t.Errorf("CheckManifest() of an invalid manifest = %+v, want the validation error", got)
+	}
+
+	got = CheckManifest(write("[template\n"))
+	if len(got) != 1 || got[0].Status != Fail || !strings.Contains(got[0].Message, "failed to parse manifest") {
+		t.Errorf("CheckManifest() of broken TOML = %+v, want the parse error", got)
+	}
+}
@@ -0,0 +1,213 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/detect"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is one finding of a check, with what to do about it unless it
+// passed.
+type Result struct {
+	Check   string `json:"check"` // "permissions", "path", "network", "disk", "state" or "manifest"
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// Runtime is a runtime found on the system.
+type Runtime struct {
+	Name      string `json:"name"`
+	Installed bool   `json:"installed"`
+	Version   string `json:"version,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Source    string `json:"source,omitempty"` // "path", or the version manager it was found in
+}
+
+// System describes the machine the report was made on.
+type System struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	Home string `json:"home"`
+}
+
+// Report is everything doctor found, in the form attached to issues.
+type Report struct {
+	Version  string    `json:"version"`
+	Commit   string    `json:"commit"`
+	Built    string    `json:"built"`
+	System   System    `json:"system"`
+	Runtimes []Runtime `json:"runtimes"`
+	Checks   []Result  `json:"checks"`
+}
+
+// Options are what Run needs to know about the running tool.
+type Options struct {
+	Version, Commit, Built string
+
+	// ManifestPath is the manifest to validate; empty means .templatr.toml
+	// in the current directory, which is skipped if there is none.
+	ManifestPath string
+}
+
+// Run scans the runtimes and runs every check.
+func Run(ctx context.Context, opts Options) Report {
+	sys := detect.GetSystemInfo()
+	r := Report{
+		Version: opts.Version,
+		Commit:  opts.Commit,
+		Built:   opts.Built,
+		System:  System{OS: sys.OS, Arch: sys.Arch, Home: sys.HomeDir},
+	}
+	for _, info := range detect.ScanRuntimes(ctx) {
+		r.Runtimes = append(r.Runtimes, Runtime{
+			Name:      info.Name,
+			Installed: info.Installed,
+			Version:   info.Version,
+			Path:      info.Path,
+			Source:    info.Source,
+		})
+	}
+
+	r.Checks = append(r.Checks, CheckPermissions(install.Preflight(nil))...)
+
+	st, stErr := state.Load()
+	if stErr == nil {
+		fresh, err := freshPath(ctx)
+		r.Checks = append(r.Checks, CheckPath(st, fresh, err)...)
+		r.Checks = append(r.Checks, CheckShellBlocks(install.UntidyBlocks(st))...)
+	}
+	r.Checks = append(r.Checks, CheckNetwork(ctx, &http.Client{Timeout: networkTimeout}, networkURLs)...)
+
+	runtimesDir, err := install.RuntimesDir()
+	if err == nil {
+		r.Checks = append(r.Checks, CheckDisk(runtimesDir, install.DiskFree))
+	}
+	if stErr != nil {
+		r.Checks = append(r.Checks, Result{
+			Check:   "state",
+			Status:  Fail,
+			Message: stErr.Error(),
+			Fix:     "Move ~/.templatr/state.json aside; the next setup starts a new one, but uninstall won't know about earlier installs.",
+		})
+	} else if err == nil {
+		r.Checks = append(r.Checks, CheckState(st, runtimesDir)...)
+	}
+
+	path := opts.ManifestPath
+	if path == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			path = filepath.Join(cwd, manifest.DefaultManifestName)
+			if _, err := os.Stat(path); err != nil {
+				path = ""
+			}
+		}
+	}
+	if path != "" {
+		r.Checks = append(r.Checks, CheckManifest(path)...)
+	}
+	return r
+}
+
+// Failed reports whether any check failed.
+func (r Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// sections are the headings checks are printed under, in order.
+var sections = []struct{ check, title string }{
+	{"permissions", "Permissions Preflight"},
+	{"path", "PATH Entries"},
+	{"network", "Network"},
+	{"disk", "Disk Space"},
+	{"state", "State File"},
+	{"manifest", "Manifest"},
+}
+
+const rule = "─────────────────────────────────────────────────"
+
+// Print writes the report as text.
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "templatr-setup doctor - System Health Check\n")
+	fmt.Fprintf(w, "Version: %s (commit: %s, built: %s)\n\n", r.Version, r.Commit, r.Built)
+
+	fmt.Fprintf(w, "OS:           %s\n", r.System.OS)
+	fmt.Fprintf(w, "Architecture: %s\n", r.System.Arch)
+	fmt.Fprintf(w, "Home:         %s\n\n", r.System.Home)
+
+	fmt.Fprintln(w, "Runtime Detection:")
+	fmt.Fprintln(w, rule)
+	for _, rt := range r.Runtimes {
+		icon, status := "✗", "not found"
+		if rt.Installed {
+			icon, status = "✓", rt.Version
+			if rt.Source != detect.SourcePath {
+				status += " (" + rt.Source + ", not on PATH)"
+			}
+		}
+		fmt.Fprintf(w, "  %s %-12s %s", icon, rt.Name, status)
+		if rt.Installed && rt.Path != "" {
+			fmt.Fprintf(w, "  (%s)", rt.Path)
+		}
+		fmt.Fprintln(w)
+	}
+
+	for _, sec := range sections {
+		var results []Result
+		for _, c := range r.Checks {
+			if c.Check == sec.check {
+				results = append(results, c)
+			}
+		}
+		if len(results) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s:\n", sec.title)
+		fmt.Fprintln(w, rule)
+		for _, c := range results {
+			fmt.Fprintf(w, "  %s %s\n", c.Status.icon(), c.Message)
+			if c.Fix != "" {
+				fmt.Fprintf(w, "    → %s\n", c.Fix)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func (s Status) icon() string {
+	switch s {
+	case Pass:
+		return "✓"
+	case Warn:
+		return "!"
+	}
+	return "✗"
+}
+
+// networkTimeout is how long each download host may take to answer.
+const networkTimeout = 5 * time.Second
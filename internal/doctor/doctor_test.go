@@ -0,0 +1,79 @@
+package doctor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testReport() Report {
+	return Report{
+		Version: "1.4.0",
+		System:  System{OS: "linux", Arch: "amd64", Home: "/home/u"},
+		Runtimes: []Runtime{
+			{Name: "Node.js", Installed: true, Version: "22.14.0", Path: "/usr/bin/node", Source: "path"},
+			{Name: "Python", Installed: true, Version: "3.12.8", Path: "/home/u/.pyenv/versions/3.12.8/bin/python", Source: "pyenv"},
+			{Name: "Go"},
+		},
+		Checks: []Result{
+			{Check: "network", Status: Fail, Message: "go.dev is unreachable", Fix: "Check your connection."},
+			{Check: "permissions", Status: Pass, Message: "All checks passed"},
+			{Check: "disk", Status: Warn, Message: "2.0 GB free", Fix: "Free up space."},
+		},
+	}
+}
+
+func TestReport_Print(t *testing.T) {
+	var b strings.Builder
+	testReport().Print(&b)
+	out := b.String()
+
+	for _, want := range []string{
+		"Version: 1.4.0",
+		"✓ Node.js      22.14.0  (/usr/bin/node)",
+		"✓ Python       3.12.8 (pyenv, not on PATH)",
+		"✗ Go           not found",
+		"  ✗ go.dev is unreachable\n    → Check your connection.\n",
+		"  ! 2.0 GB free\n    → Free up space.\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	// Sections come in a fixed order, whatever order the checks ran in
+	perm, network, disk := strings.Index(out, "Permissions Preflight:"), strings.Index(out, "Network:"), strings.Index(out, "Disk Space:")
+	if perm < 0 || network < perm || disk < network {
+		t.Errorf("sections out of order:\n%s", out)
+	}
+	if strings.Contains(out, "Manifest:") {
+		t.Errorf("sections without results should be left out:\n%s", out)
+	}
+}
+
+func TestReport_Failed(t *testing.T) {
+	r := testReport()
+	if !r.Failed() {
+		t.Error("Failed() = false with a failed check")
+	}
+	r.Checks = r.Checks[1:]
+	if r.Failed() {
+		t.Error("Failed() = true with only passes and warnings")
+	}
+}
+
+func TestReport_JSON(t *testing.T) {
+	data, err := json.Marshal(testReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"system":{"os":"linux","arch":"amd64","home":"/home/u"}`,
+		`{"name":"Go","installed":false}`,
+		`{"check":"network","status":"fail","message":"go.dev is unreachable","fix":"Check your connection."}`,
+		`{"check":"permissions","status":"pass","message":"All checks passed"}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON is missing %s:\n%s", want, data)
+		}
+	}
+}
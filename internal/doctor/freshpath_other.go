@@ -0,0 +1,57 @@
+//go:build !windows
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// freshPathTimeout bounds how long the login shell may take to start.
+const freshPathTimeout = 10 * time.Second
+
+// pathMarker precedes PATH in the shell's output, so whatever the rc
+// files print themselves is skipped.
+const pathMarker = "__templatr_path__="
+
+// basePath is the PATH the login shell is started with, so it reports what
+// its startup files set up rather than what this process inherited.
+const basePath = "/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin"
+
+// freshPath returns PATH as an interactive login shell sets it up: what a
+// new terminal window gets.
+func freshPath(ctx context.Context) ([]string, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	script := fmt.Sprintf(`printf '\n%s%%s\n' "$PATH"`, pathMarker)
+	if filepath.Base(shell) == "fish" {
+		script = fmt.Sprintf(`printf '\n%s%%s\n' (string join : $PATH)`, pathMarker)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, freshPathTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, shell, "-l", "-i", "-c", script)
+	cmd.Env = append(os.Environ(), "PATH="+basePath)
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("%s: %w", shell, err)
+	}
+	return parseFreshPath(string(out))
+}
+
+// parseFreshPath finds the PATH line freshPath's script printed.
+func parseFreshPath(out string) ([]string, error) {
+	for _, line := range strings.Split(out, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), pathMarker); ok {
+			return filepath.SplitList(value), nil
+		}
+	}
+	return nil, fmt.Errorf("the shell didn't print its PATH")
+}
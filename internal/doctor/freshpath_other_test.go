@@ -0,0 +1,47 @@
+//go:build !windows
+
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFreshPath(t *testing.T) {
+	out := "Welcome back!\n" + pathMarker + "/home/u/.templatr/runtimes/node/current/bin:/usr/bin\n"
+	got, err := parseFreshPath(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/home/u/.templatr/runtimes/node/current/bin", "/usr/bin"}; !slices.Equal(got, want) {
+		t.Errorf("parseFreshPath() = %v, want %v", got, want)
+	}
+	if _, err := parseFreshPath("bash: no job control in this shell\n"); err == nil {
+		t.Error("parseFreshPath() without the marker should fail")
+	}
+}
+
+func TestFreshPath_ReadsShellConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/sh")
+	t.Setenv("ENV", filepath.Join(home, ".shrc")) // read by interactive sh
+	if err := os.WriteFile(filepath.Join(home, ".shrc"), []byte("echo hello\nexport PATH=\"/opt/templatr/bin:$PATH\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", "/inherited/bin:"+os.Getenv("PATH"))
+
+	got, err := freshPath(context.Background())
+	if err != nil {
+		t.Skipf("no usable /bin/sh: %s", err)
+	}
+	if !slices.Contains(got, "/opt/templatr/bin") {
+		t.Errorf("freshPath() = %v, want the directory the shell config adds", got)
+	}
+	if slices.Contains(got, "/inherited/bin") {
+		t.Errorf("freshPath() = %v, should not inherit this process's PATH", got)
+	}
+}
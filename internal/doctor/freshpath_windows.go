@@ -0,0 +1,52 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	winreg "golang.org/x/sys/windows/registry"
+)
+
+// freshPath returns PATH as a new terminal gets it: the system PATH
+// followed by the user PATH, as Explorer builds it from the registry.
+func freshPath(ctx context.Context) ([]string, error) {
+	var path []string
+	for _, k := range []struct {
+		root winreg.Key
+		name string
+	}{
+		{winreg.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`},
+		{winreg.CURRENT_USER, "Environment"},
+	} {
+		value, err := readPath(k.root, k.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PATH from %s: %w", k.name, err)
+		}
+		path = append(path, filepath.SplitList(value)...)
+	}
+	return path, nil
+}
+
+// readPath returns the PATH value of a registry key with %VAR% references
+// expanded, or "" if it has none.
+func readPath(root winreg.Key, name string) (string, error) {
+	key, err := winreg.OpenKey(root, name, winreg.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+
+	value, valType, err := key.GetStringValue("PATH")
+	if errors.Is(err, winreg.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if valType == winreg.EXPAND_SZ {
+		return winreg.ExpandString(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/templatr/templatr-setup/internal/detect"
+)
+
+// Ways a package manager that isn't installed, or is older than
+// manager_version, gets bootstrapped before the package step runs.
+const (
+	BootstrapCorepack  = "corepack"  // pnpm and yarn, enabled through the Node.js install
+	BootstrapEnsurepip = "ensurepip" // pip, through python -m ensurepip
+	BootstrapRuntime   = "runtime"   // a manager that is a runtime itself, such as bun, installed by the runtime step
+	BootstrapNpm       = "npm"       // npm upgrading itself with npm install -g
+)
+
+// bootstrapManager returns how manager can be installed when it wasn't
+// found, or "" if it can't be. pnpm and yarn need Node.js and pip needs
+// Python, either already there or installed by the plan. For a manager that
+// is a runtime in its own right it also returns that runtime's plan, which
+// the runtime step installs after the manifest's runtimes.
+func (p *SetupPlan) bootstrapManager(manager string, detected map[string]detect.RuntimeInfo) (string, *RuntimePlan) {
+	switch manager {
+	case "pnpm", "yarn":
+		if p.providesRuntime("node", detected) {
+			return BootstrapCorepack, nil
+		}
+	case "pip":
+		if p.providesRuntime("python", detected) {
+			return BootstrapEnsurepip, nil
+		}
+	}
+
+	name, ok := managerRuntimes[manager]
+	if !ok || p.hasRuntime(name) {
+		return "", nil
+	}
+	displayName, ok := runtimeDisplayNames[name]
+	if !ok {
+		displayName = name
+	}
+	return BootstrapRuntime, &RuntimePlan{
+		Name:            name,
+		DisplayName:     displayName,
+		RequiredVersion: "latest",
+		Action:          ActionInstall,
+		Note:            fmt.Sprintf("needed by packages.manager = %q", manager),
+		ArchivesDir:     p.ArchivesDir,
+	}
+}
+
+// upgradeManager returns how manager, found but older than the manifest's
+// manager_version, can be upgraded, or "" if it can't be: pnpm and yarn
+// through corepack, which needs Node.js, and npm through itself.
+func (p *SetupPlan) upgradeManager(manager string, detected map[string]detect.RuntimeInfo) string {
+	switch manager {
+	case "pnpm", "yarn":
+		if p.providesRuntime("node", detected) {
+			return BootstrapCorepack
+		}
+	case "npm":
+		return BootstrapNpm
+	}
+	return ""
+}
+
+// providesRuntime reports whether the named runtime is on the system or
+// installed by the plan.
+func (p *SetupPlan) providesRuntime(name string, detected map[string]detect.RuntimeInfo) bool {
+	if p.hasRuntime(name) {
+		return true
+	}
+	detectName, ok := runtimeDetectNames[name]
+	if !ok {
+		detectName = name
+	}
+	info, found := detected[detectName]
+	return found && info.Installed
+}
+
+// hasRuntime reports whether the named runtime is in the plan, whatever
+// its action.
+func (p *SetupPlan) hasRuntime(name string) bool {
+	for _, r := range p.Runtimes {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Label names the package manager for the summary, with its version when
+// it was detected, e.g. "pnpm 7.33.0".
+func (pp *PackagePlan) Label() string {
+	if pp.ManagerVersion != "" {
+		return pp.Manager + " " + pp.ManagerVersion
+	}
+	return pp.Manager
+}
+
+// Status describes the package manager for the summary: "available", the
+// manager_version it doesn't meet and how it will be upgraded, how it will
+// be installed, or "not found".
+func (pp *PackagePlan) Status() string {
+	switch {
+	case pp.ManagerOutdated && pp.Bootstrap != "":
+		return "needs " + pp.RequiredVersion + ", will be upgraded via " + pp.Bootstrap
+	case pp.ManagerOutdated:
+		return "needs " + pp.RequiredVersion
+	case pp.ManagerFound:
+		return "available"
+	case pp.Bootstrap == BootstrapRuntime:
+		return "will be installed with its runtime"
+	case pp.Bootstrap != "":
+		return "will be installed via " + pp.Bootstrap
+	}
+	return "not found"
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/detect"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestBootstrapManager(t *testing.T) {
+	node := map[string]detect.RuntimeInfo{"Node.js": {Name: "Node.js", Installed: true}}
+	python := map[string]detect.RuntimeInfo{"Python": {Name: "Python", Installed: true}}
+	planned := func(name string) []RuntimePlan {
+		return []RuntimePlan{{Name: name, Action: ActionInstall}}
+	}
+
+	tests := []struct {
+		name     string
+		manager  string
+		runtimes []RuntimePlan
+		detected map[string]detect.RuntimeInfo
+		want     string
+	}{
+		{"pnpm with node on the system", "pnpm", nil, node, BootstrapCorepack},
+		{"yarn with node being installed", "yarn", planned("node"), nil, BootstrapCorepack},
+		{"pnpm without node", "pnpm", nil, python, ""},
+		{"pip with python on the system", "pip", nil, python, BootstrapEnsurepip},
+		{"pip with python being installed", "pip", planned("python"), nil, BootstrapEnsurepip},
+		{"pip without python", "pip", nil, node, ""},
+		{"bun adds its runtime", "bun", nil, nil, BootstrapRuntime},
+		{"bun already in the plan", "bun", []RuntimePlan{{Name: "bun", Action: ActionSkip}}, nil, ""},
+		{"composer has no bootstrap", "composer", nil, node, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &SetupPlan{Runtimes: tt.runtimes}
+			before := len(plan.Runtimes)
+			got, rp := plan.bootstrapManager(tt.manager, tt.detected)
+			if got != tt.want {
+				t.Errorf("bootstrapManager(%q) = %q, want %q", tt.manager, got, tt.want)
+			}
+			if len(plan.Runtimes) != before {
+				t.Errorf("runtimes = %+v, want the manifest's left alone", plan.Runtimes)
+			}
+
+			if tt.want == BootstrapRuntime {
+				if rp == nil || rp.Name != "bun" || rp.Action != ActionInstall {
+					t.Errorf("bootstrap runtime = %+v, want bun to install", rp)
+				}
+			} else if rp != nil {
+				t.Errorf("bootstrap runtime = %+v, want none", rp)
+			}
+		})
+	}
+}
+
+func TestInstallRuntimes(t *testing.T) {
+	plan := &SetupPlan{
+		Runtimes: []RuntimePlan{{Name: "node", Action: ActionSkip}},
+		Packages: &PackagePlan{Manager: "bun", Bootstrap: BootstrapRuntime, Runtime: &RuntimePlan{Name: "bun", Action: ActionInstall, DownloadSize: 1 << 20}},
+	}
+	runtimes := plan.InstallRuntimes()
+	if len(runtimes) != 2 || runtimes[0].Name != "node" || runtimes[1].Name != "bun" {
+		t.Fatalf("InstallRuntimes() = %+v, want node then bun", runtimes)
+	}
+	runtimes[0].Action = ActionInstall
+	if plan.Runtimes[0].Action != ActionSkip {
+		t.Error("InstallRuntimes() shares the plan's runtimes")
+	}
+	if !plan.NeedsAction() || plan.DownloadSize() != 1<<20 {
+		t.Errorf("NeedsAction() = %v, DownloadSize() = %d; want the bootstrap runtime counted", plan.NeedsAction(), plan.DownloadSize())
+	}
+}
+
+func TestCheckManager(t *testing.T) {
+	withNode := func(manager, version string) map[string]detect.RuntimeInfo {
+		return map[string]detect.RuntimeInfo{
+			"Node.js": {Name: "Node.js", Version: "22.11.0", Installed: true},
+			manager:   {Name: manager, Version: version, Installed: true},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		manager      string
+		required     string
+		detected     map[string]detect.RuntimeInfo
+		wantFound    bool
+		wantVersion  string
+		wantOutdated bool
+		wantBoot     string
+	}{
+		{"no constraint", "pnpm", "", withNode("pnpm", "7.33.0"), true, "7.33.0", false, ""},
+		{"new enough", "pnpm", ">=9", withNode("pnpm", "9.12.1"), true, "9.12.1", false, ""},
+		{"pnpm too old", "pnpm", ">=9", withNode("pnpm", "7.33.0"), true, "7.33.0", true, BootstrapCorepack},
+		{"yarn too new", "yarn", "^1.22", withNode("yarn", "4.5.0"), true, "4.5.0", true, BootstrapCorepack},
+		{"npm too old", "npm", ">=10", withNode("npm", "9.8.1"), true, "9.8.1", true, BootstrapNpm},
+		{"unparsable version", "pnpm", ">=9", withNode("pnpm", "(version unknown)"), true, "(version unknown)", true, BootstrapCorepack},
+		{"pnpm too old without node", "pnpm", ">=9", map[string]detect.RuntimeInfo{"pnpm": {Name: "pnpm", Version: "7.33.0", Installed: true}},
+			true, "7.33.0", true, ""},
+		{"missing is installed, not upgraded", "pnpm", ">=9", map[string]detect.RuntimeInfo{"Node.js": {Name: "Node.js", Installed: true}},
+			false, "", false, BootstrapCorepack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &SetupPlan{}
+			pp := &PackagePlan{Manager: tt.manager, RequiredVersion: tt.required}
+			plan.checkManager(pp, tt.detected)
+			if pp.ManagerFound != tt.wantFound || pp.ManagerVersion != tt.wantVersion || pp.ManagerOutdated != tt.wantOutdated || pp.Bootstrap != tt.wantBoot {
+				t.Errorf("got found %v, version %q, outdated %v, bootstrap %q; want %v, %q, %v, %q",
+					pp.ManagerFound, pp.ManagerVersion, pp.ManagerOutdated, pp.Bootstrap,
+					tt.wantFound, tt.wantVersion, tt.wantOutdated, tt.wantBoot)
+			}
+		})
+	}
+}
+
+func TestOutdatedManagerNeedsAction(t *testing.T) {
+	plan := &SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []RuntimePlan{{Name: "node", DisplayName: "Node.js", RequiredVersion: ">=20", InstalledVersion: "22.11.0", Action: ActionSkip}},
+		Packages: &PackagePlan{Manager: "pnpm", ManagerFound: true, ManagerVersion: "7.33.0", RequiredVersion: ">=9", ManagerOutdated: true},
+	}
+	if plan.NeedsAction() {
+		t.Error("NeedsAction() should be false when the manager can't be upgraded")
+	}
+	plan.Packages.Bootstrap = BootstrapCorepack
+	if !plan.NeedsAction() {
+		t.Error("NeedsAction() should be true when the manager will be upgraded")
+	}
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	PrintSummary(plan)
+	w.Close()
+	os.Stdout = stdout
+	out, _ := io.ReadAll(r)
+	if want := "Package manager: pnpm 7.33.0 (needs >=9, will be upgraded via corepack)"; !strings.Contains(string(out), want) {
+		t.Errorf("PrintSummary should show %q, got:\n%s", want, out)
+	}
+}
+
+func TestPackagePlanStatus(t *testing.T) {
+	tests := []struct {
+		pp   PackagePlan
+		want string
+	}{
+		{PackagePlan{Manager: "pnpm", ManagerFound: true}, "available"},
+		{PackagePlan{Manager: "pnpm", Bootstrap: BootstrapCorepack}, "will be installed via corepack"},
+		{PackagePlan{Manager: "pip", Bootstrap: BootstrapEnsurepip}, "will be installed via ensurepip"},
+		{PackagePlan{Manager: "bun", Bootstrap: BootstrapRuntime}, "will be installed with its runtime"},
+		{PackagePlan{Manager: "composer"}, "not found"},
+		{PackagePlan{Manager: "pnpm", ManagerFound: true, RequiredVersion: ">=9", ManagerOutdated: true, Bootstrap: BootstrapCorepack},
+			"needs >=9, will be upgraded via corepack"},
+		{PackagePlan{Manager: "npm", ManagerFound: true, RequiredVersion: ">=10", ManagerOutdated: true, Bootstrap: BootstrapNpm},
+			"needs >=10, will be upgraded via npm"},
+		{PackagePlan{Manager: "pnpm", ManagerFound: true, RequiredVersion: ">=9", ManagerOutdated: true}, "needs >=9"},
+	}
+	for _, tt := range tests {
+		if got := tt.pp.Status(); got != tt.want {
+			t.Errorf("Status() for %+v = %q, want %q", tt.pp, got, tt.want)
+		}
+	}
+}
+
+func TestPackagePlanLabel(t *testing.T) {
+	if got := (&PackagePlan{Manager: "pnpm", ManagerVersion: "7.33.0"}).Label(); got != "pnpm 7.33.0" {
+		t.Errorf("Label() = %q, want %q", got, "pnpm 7.33.0")
+	}
+	if got := (&PackagePlan{Manager: "pnpm"}).Label(); got != "pnpm" {
+		t.Errorf("Label() = %q, want %q", got, "pnpm")
+	}
+}
@@ -2,21 +2,46 @@ package engine
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/term"
 )
 
 // PrintSummary prints a human-readable summary table of the setup plan.
 func PrintSummary(plan *SetupPlan) {
+	FprintSummary(os.Stdout, plan)
+}
+
+// FprintSummary writes PrintSummary's table to w.
+func FprintSummary(w io.Writer, plan *SetupPlan) {
 	m := plan.Manifest
 
-	fmt.Printf("Template: %s (%s)\n", m.Template.Name, m.Template.Tier)
+	if m.Template.Name != "" {
+		fmt.Fprintf(w, "Template: %s (%s)\n", m.Template.Name, m.Template.Tier)
+	}
 	if m.Template.Slug != "" {
-		fmt.Printf("Docs:     %s\n", m.Meta.Docs)
+		fmt.Fprintf(w, "Docs:     %s\n", m.Meta.Docs)
+	}
+	if plan.ArchivesDir != "" {
+		fmt.Fprintf(w, "Offline:  installing runtimes from archives in %s\n", plan.ArchivesDir)
+	}
+	if size := plan.DownloadSize(); size > 0 {
+		fmt.Fprintf(w, "Download: approx. %s\n", term.FormatBytes(size))
+	}
+	fmt.Fprintln(w)
+
+	if plan.Changes != nil && !plan.Changes.Empty() {
+		fmt.Fprintln(w, "This template changed since your last setup:")
+		fprintChanges(w, plan.Changes)
+		fmt.Fprintln(w)
 	}
-	fmt.Println()
 
 	if len(plan.Runtimes) == 0 {
-		fmt.Println("No runtimes required by this template.")
+		fmt.Fprintln(w, "No runtimes required by this template.")
+		printDownloads(w, plan.Downloads)
 		return
 	}
 
@@ -26,73 +51,75 @@ func PrintSummary(plan *SetupPlan) {
 		if len(r.DisplayName) > nameW {
 			nameW = len(r.DisplayName)
 		}
-		if len(r.RequiredVersion) > reqW {
-			reqW = len(r.RequiredVersion)
-		}
-		cur := r.InstalledVersion
-		if cur == "" {
-			cur = "-"
+		if len(r.RequiredLabel()) > reqW {
+			reqW = len(r.RequiredLabel())
 		}
+		cur := r.InstalledLabel()
 		if len(cur) > curW {
 			curW = len(cur)
 		}
-		act := r.Action.ActionIcon()
+		act := r.ActionLabel()
 		if len(act) > actW {
 			actW = len(act)
 		}
 	}
 
 	// Print header
-	fmt.Printf("  %-*s  %-*s  %-*s  %s\n", nameW, "Runtime", reqW, "Required", curW, "Installed", "Action")
-	fmt.Printf("  %s  %s  %s  %s\n",
-		strings.Repeat("─", nameW),
-		strings.Repeat("─", reqW),
-		strings.Repeat("─", curW),
-		strings.Repeat("─", actW),
+	fmt.Fprintf(w, "  %-*s  %-*s  %-*s  %s\n", nameW, "Runtime", reqW, "Required", curW, "Installed", "Action")
+	fmt.Fprintf(w, "  %s  %s  %s  %s\n",
+		term.Rule(nameW),
+		term.Rule(reqW),
+		term.Rule(curW),
+		term.Rule(actW),
 	)
 
 	// Print rows
 	for _, r := range plan.Runtimes {
-		cur := r.InstalledVersion
-		if cur == "" {
-			cur = "-"
-		}
+		cur := r.InstalledLabel()
 
 		icon := "  "
-		switch r.Action {
-		case ActionSkip:
+		switch {
+		case r.Action == ActionSkip:
 			icon = "✓ "
-		case ActionInstall:
-			icon = "✗ "
-		case ActionUpgrade:
+		case r.Forced(), r.Action == ActionUpgrade:
 			icon = "⬆ "
+		case r.Action == ActionInstall:
+			icon = "✗ "
 		}
 
-		fmt.Printf("%s%-*s  %-*s  %-*s  %s\n",
+		fmt.Fprintf(w, "%s%-*s  %-*s  %-*s  %s\n",
 			icon,
 			nameW, r.DisplayName,
-			reqW, r.RequiredVersion,
+			reqW, r.RequiredLabel(),
 			curW, cur,
-			r.Action.ActionIcon(),
+			r.ActionLabel(),
 		)
 	}
 
-	fmt.Println()
+	fmt.Fprintln(w)
+	for _, r := range plan.Runtimes {
+		if r.Note != "" {
+			fmt.Fprintf(w, "Note: %s\n", r.Note)
+		}
+	}
 
 	// Summary line
 	installs := 0
 	upgrades := 0
+	reinstalls := 0
 	for _, r := range plan.Runtimes {
-		switch r.Action {
-		case ActionInstall:
+		switch {
+		case r.Forced():
+			reinstalls++
+		case r.Action == ActionInstall:
 			installs++
-		case ActionUpgrade:
+		case r.Action == ActionUpgrade:
 			upgrades++
 		}
 	}
 
-	if installs == 0 && upgrades == 0 {
-		fmt.Println("All runtimes are already installed and satisfy the requirements.")
+	if installs == 0 && upgrades == 0 && reinstalls == 0 {
+		fmt.Fprintln(w, "All runtimes are already installed and satisfy the requirements.")
 	} else {
 		parts := []string{}
 		if installs > 0 {
@@ -101,43 +128,125 @@ func PrintSummary(plan *SetupPlan) {
 		if upgrades > 0 {
 			parts = append(parts, fmt.Sprintf("%d to upgrade", upgrades))
 		}
-		fmt.Printf("Actions needed: %s\n", strings.Join(parts, ", "))
+		if reinstalls > 0 {
+			parts = append(parts, fmt.Sprintf("%d to reinstall", reinstalls))
+		}
+		fmt.Fprintf(w, "Actions needed: %s\n", strings.Join(parts, ", "))
 	}
 
+	printDownloads(w, plan.Downloads)
+
 	// Package manager info
 	if plan.Packages != nil {
-		fmt.Println()
-		managerStatus := "not found"
-		if plan.Packages.ManagerFound {
-			managerStatus = "available"
+		fmt.Fprintln(w)
+		if pp := plan.Packages; pp.DetectedFrom != "" {
+			fmt.Fprintf(w, "Package manager: %s (detected from %s, %s)\n", pp.Label(), pp.DetectedFrom, pp.Status())
+		} else if pp.Manager != "" {
+			fmt.Fprintf(w, "Package manager: %s (%s)\n", pp.Label(), pp.Status())
 		}
-		fmt.Printf("Package manager: %s (%s)\n", plan.Packages.Manager, managerStatus)
-		if plan.Packages.InstallCommand != "" {
-			fmt.Printf("Install command: %s\n", plan.Packages.InstallCommand)
+		if steps := plan.Packages.Steps; len(steps) == 1 && steps[0].Dir == "" {
+			fmt.Fprintf(w, "Install command: %s\n", steps[0].Command)
+		} else if len(steps) > 0 {
+			fmt.Fprintln(w, "Install commands:")
+			for _, step := range steps {
+				fmt.Fprintf(w, "  - %s\n", step)
+			}
 		}
 	}
 
 	// Env vars info
 	if len(m.Env) > 0 {
-		fmt.Println()
+		fmt.Fprintln(w)
 		required := 0
 		for _, e := range m.Env {
 			if e.Required {
 				required++
 			}
 		}
-		fmt.Printf("Environment variables: %d total (%d required)\n", len(m.Env), required)
+		fmt.Fprintf(w, "Environment variables: %d total (%d required)\n", len(m.Env), required)
 	}
 
 	// Config files info
 	if len(m.Config) > 0 {
-		fmt.Println()
+		fmt.Fprintln(w)
 		totalFields := 0
 		for _, c := range m.Config {
 			totalFields += len(c.Fields)
 		}
-		fmt.Printf("Config files: %d file(s), %d field(s) to configure\n", len(m.Config), totalFields)
+		fmt.Fprintf(w, "Config files: %d file(s), %d field(s) to configure\n", len(m.Config), totalFields)
+	}
+
+	fmt.Fprintln(w)
+}
+
+// printDownloads prints one row per [[downloads]] entry.
+func printDownloads(w io.Writer, downloads []DownloadPlan) {
+	if len(downloads) == 0 {
+		return
 	}
 
-	fmt.Println()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Downloads:")
+	for _, d := range downloads {
+		icon, status := "✗ ", "Download"
+		if d.Action == ActionSkip {
+			icon, status = "✓ ", "OK"
+		}
+		auth := ""
+		if d.AuthEnv != "" {
+			auth = fmt.Sprintf(" (auth: $%s)", d.AuthEnv)
+		}
+		fmt.Fprintf(w, "%s%s → %s%s  %s\n", icon, d.Name, d.TargetDir, auth, status)
+	}
+}
+
+// PrintChanges prints the differences between the previously applied manifest
+// and the current one.
+func PrintChanges(d *manifest.Diff) {
+	fprintChanges(os.Stdout, d)
+}
+
+func fprintChanges(w io.Writer, d *manifest.Diff) {
+	if d.OldVersion != d.NewVersion && d.OldVersion != "" {
+		fmt.Fprintf(w, "  Template version: %s → %s\n", d.OldVersion, d.NewVersion)
+	}
+
+	for _, r := range d.Runtimes {
+		switch {
+		case r.Old == "":
+			fmt.Fprintf(w, "  + runtime %s %s\n", r.Name, r.New)
+		case r.New == "":
+			fmt.Fprintf(w, "  - runtime %s %s\n", r.Name, r.Old)
+		default:
+			fmt.Fprintf(w, "  ~ runtime %s %s → %s\n", r.Name, r.Old, r.New)
+		}
+	}
+
+	for _, f := range d.AddedEnv {
+		req := ""
+		if f.Required {
+			req = " (required)"
+		}
+		fmt.Fprintf(w, "  + env %s in %s%s\n", f.Key, f.File, req)
+	}
+	for _, f := range d.RemovedEnv {
+		fmt.Fprintf(w, "  - env %s in %s\n", f.Key, f.File)
+	}
+
+	for _, f := range d.AddedConfig {
+		fmt.Fprintf(w, "  + config %s in %s\n", f.Key, f.File)
+	}
+	for _, f := range d.RemovedConfig {
+		fmt.Fprintf(w, "  - config %s in %s\n", f.Key, f.File)
+	}
+
+	if d.PostSetupChanged {
+		fmt.Fprintln(w, "  ~ post-setup commands changed:")
+		for _, c := range d.OldCommands {
+			fmt.Fprintf(w, "      - %s\n", c)
+		}
+		for _, c := range d.NewCommands {
+			fmt.Fprintf(w, "      + %s\n", c)
+		}
+	}
 }
@@ -0,0 +1,38 @@
+package engine
+
+import "github.com/templatr/templatr-setup/internal/manifest"
+
+// installSteps returns the install commands for pp: its InstallCommand in
+// the manifest's directory, or each [[packages.install]] entry in its own.
+// An entry without a manager uses pp's, or the one its directory's
+// lockfile implies.
+func installSteps(m *manifest.Manifest, pp *PackagePlan) []InstallStep {
+	if len(m.Packages.Install) == 0 {
+		if pp.InstallCommand == "" {
+			return nil
+		}
+		return []InstallStep{{Command: pp.InstallCommand, Manager: pp.Manager}}
+	}
+
+	steps := make([]InstallStep, 0, len(m.Packages.Install))
+	for _, entry := range m.Packages.Install {
+		step := InstallStep{Dir: entry.Dir, Command: entry.Command, Manager: entry.Manager}
+		if step.Manager == "" {
+			step.Manager = pp.Manager
+		}
+		if step.Manager == "" {
+			step.Manager, _, _ = manifest.InferManager(entry.WorkDir(projectDir(m)))
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// String returns the command with its directory, e.g.
+// "pnpm install (in frontend)".
+func (s InstallStep) String() string {
+	if s.Dir == "" {
+		return s.Command
+	}
+	return s.Command + " (in " + s.Dir + ")"
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func writeProjectFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuildPlan_InfersManager(t *testing.T) {
+	isolateDetection(t)
+	dir := writeProjectFiles(t, map[string]string{"yarn.lock": "", "package.json": "{}"})
+
+	plan, err := BuildPlan(&manifest.Manifest{Dir: dir})
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %s", err)
+	}
+	pp := plan.Packages
+	if pp == nil || pp.Manager != "yarn" || pp.InstallCommand != "yarn install" || pp.DetectedFrom != "yarn.lock" {
+		t.Fatalf("Packages = %+v, want yarn inferred from yarn.lock", pp)
+	}
+
+	// An explicit manager is used as is, and so is an explicit command
+	m := &manifest.Manifest{Dir: dir}
+	m.Packages.Manager = "npm"
+	plan, _ = BuildPlan(m)
+	if pp := plan.Packages; pp.Manager != "npm" || pp.InstallCommand != "" || pp.DetectedFrom != "" {
+		t.Errorf("Packages = %+v, want the manifest's npm, not detected", pp)
+	}
+	m = &manifest.Manifest{Dir: dir}
+	m.Packages.InstallCommand = "yarn install --frozen-lockfile"
+	plan, _ = BuildPlan(m)
+	if pp := plan.Packages; pp.Manager != "yarn" || pp.InstallCommand != "yarn install --frozen-lockfile" {
+		t.Errorf("Packages = %+v, want yarn with the manifest's command", pp)
+	}
+
+	// No lockfile and no [packages]: no package step
+	plan, _ = BuildPlan(&manifest.Manifest{Dir: t.TempDir()})
+	if plan.Packages != nil {
+		t.Errorf("Packages = %+v, want nil", plan.Packages)
+	}
+}
+
+func TestBuildPlan_InstallSteps(t *testing.T) {
+	isolateDetection(t)
+	dir := t.TempDir()
+	for sub, file := range map[string]string{"frontend": "pnpm-lock.yaml", "backend": "requirements.txt"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, sub, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &manifest.Manifest{Dir: dir}
+	m.Packages.Install = []manifest.PackageInstall{
+		{Dir: "frontend", Command: "pnpm install"},
+		{Dir: "backend", Command: "pip install -r requirements.txt"},
+		{Dir: "tools", Command: "go mod download", Manager: "go"},
+	}
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %s", err)
+	}
+	pp := plan.Packages
+	if pp == nil {
+		t.Fatal("Packages = nil, want the install entries")
+	}
+	want := []InstallStep{
+		{Dir: "frontend", Command: "pnpm install", Manager: "pnpm"},
+		{Dir: "backend", Command: "pip install -r requirements.txt", Manager: "pip"},
+		{Dir: "tools", Command: "go mod download", Manager: "go"},
+	}
+	if len(pp.Steps) != len(want) {
+		t.Fatalf("Steps = %+v, want %+v", pp.Steps, want)
+	}
+	for i, step := range pp.Steps {
+		if step != want[i] {
+			t.Errorf("Steps[%d] = %+v, want %+v", i, step, want[i])
+		}
+	}
+	if pp.Manager != "pnpm" || pp.InstallCommand != "" {
+		t.Errorf("Manager = %q, InstallCommand = %q, want the first entry's manager and no root command", pp.Manager, pp.InstallCommand)
+	}
+	if got := pp.Steps[0].String(); got != "pnpm install (in frontend)" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// The legacy single command is one step in the manifest's directory
+	m = &manifest.Manifest{Dir: dir}
+	m.Packages.Manager = "npm"
+	m.Packages.InstallCommand = "npm ci"
+	plan, _ = BuildPlan(m)
+	if steps := plan.Packages.Steps; len(steps) != 1 || steps[0] != (InstallStep{Command: "npm ci", Manager: "npm"}) {
+		t.Errorf("Steps = %+v, want the install_command alone", steps)
+	}
+}
@@ -1,12 +1,20 @@
 package engine
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/templatr/templatr-setup/internal/detect"
 	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/state"
 )
 
 // ActionType describes what needs to happen for a runtime.
@@ -23,23 +31,63 @@ type RuntimePlan struct {
 	Name             string     // e.g. "node", "python"
 	DisplayName      string     // e.g. "Node.js", "Python"
 	RequiredVersion  string     // from manifest, e.g. ">=20.0.0" or "latest"
+	RequiredSource   string     // project file RequiredVersion was read from for "auto" requirements, e.g. ".nvmrc"
 	InstalledVersion string     // from detection, e.g. "25.2.1" or ""
 	Action           ActionType // skip, install, upgrade
 	InstalledPath    string     // path to existing binary, if any
+	Source           string     // where InstalledVersion was found: "path", or a version manager such as "nvm"
+	Note             string     // extra detail for the summary, e.g. how to activate a managed version
+	SHA256           string     // archive checksum pinned in [runtimes_checksums] for this platform
+	ArchivesDir      string     // offline mode: install from a pre-fetched archive in this directory
+	DownloadSize     int64      // approx. archive size in bytes, filled in by install.EstimateDownloads; 0 if unknown
+	PlannedAction    ActionType // the action BuildPlan chose, while ApplyOverrides has changed it; empty otherwise
+	ForceReinstall   bool       // Force made this an install although what is installed satisfies the requirement
+	Relink           string     // an earlier install of a satisfying version, in state, that current is pointed at instead of downloading one
 }
 
 // SetupPlan contains the full plan for a setup operation.
 type SetupPlan struct {
-	Manifest *manifest.Manifest
-	Runtimes []RuntimePlan
-	Packages *PackagePlan
+	Manifest  *manifest.Manifest
+	Runtimes  []RuntimePlan
+	Packages  *PackagePlan
+	Downloads []DownloadPlan
+	Changes   *manifest.Diff // differences since the last successful setup, nil on first run
+
+	// ArchivesDir is set in offline mode: runtimes are installed from the
+	// archives in this directory and nothing is fetched from the network.
+	ArchivesDir string
+}
+
+// DownloadPlan describes an extra download declared in the manifest's [[downloads]] section.
+type DownloadPlan struct {
+	Name      string
+	URL       string
+	SHA256    string
+	TargetDir string     // resolved absolute target directory
+	Extract   bool       // extract the archive into TargetDir
+	AuthEnv   string     // env var holding the Authorization value, if any
+	Action    ActionType // skip if an earlier setup placed it and it's still there, install otherwise
 }
 
 // PackagePlan describes the package installation step.
 type PackagePlan struct {
-	Manager        string
-	InstallCommand string
-	ManagerFound   bool
+	Manager         string
+	InstallCommand  string
+	ManagerFound    bool
+	ManagerVersion  string        // from detection, e.g. "7.33.0"; empty if not found
+	RequiredVersion string        // [packages] manager_version, e.g. ">=9"
+	ManagerOutdated bool          // found, but ManagerVersion doesn't satisfy RequiredVersion
+	Bootstrap       string        // how a missing or outdated manager is installed before the package step, e.g. "corepack"; empty if it can't be
+	Runtime         *RuntimePlan  // the runtime a "runtime" Bootstrap installs, such as bun; not one of the plan's Runtimes, which are the manifest's
+	DetectedFrom    string        // project file Manager was inferred from when the manifest doesn't set it, e.g. "pnpm-lock.yaml"
+	Steps           []InstallStep // the install commands to run, in order: InstallCommand or the [[packages.install]] entries
+}
+
+// InstallStep is one package install command and where it runs.
+type InstallStep struct {
+	Dir     string // relative to the manifest's directory; empty for the directory itself
+	Command string
+	Manager string // the entry's manager, or the plan's
 }
 
 // runtimeDisplayNames maps manifest runtime keys to human-readable names.
@@ -53,6 +101,8 @@ var runtimeDisplayNames = map[string]string{
 	"ruby":    "Ruby",
 	"php":     "PHP",
 	"dotnet":  ".NET",
+	"bun":     "Bun",
+	"deno":    "Deno",
 }
 
 // runtimeDetectNames maps manifest runtime keys to detection names used by detect.ScanRuntimes.
@@ -66,6 +116,14 @@ var runtimeDetectNames = map[string]string{
 	"ruby":    "Ruby",
 	"php":     "PHP",
 	"dotnet":  ".NET",
+	"bun":     "bun",
+	"deno":    "Deno",
+}
+
+// managerRuntimes maps package managers that are runtimes in their own right
+// to the runtime key that installs them.
+var managerRuntimes = map[string]string{
+	"bun": "bun",
 }
 
 // managerDetectNames maps package managers to detection names.
@@ -78,22 +136,42 @@ var managerDetectNames = map[string]string{
 	"pub":  "Dart", // pub comes with dart
 }
 
+// offlineArchives is the --archives directory when offline mode is on.
+var offlineArchives string
+
+// SetOffline turns on offline mode for plans built afterwards: runtimes are
+// installed from the archives in dir instead of being downloaded. An empty
+// dir turns it off.
+func SetOffline(dir string) {
+	offlineArchives = dir
+}
+
 // BuildPlan creates a setup plan by comparing manifest requirements against detected runtimes.
 func BuildPlan(m *manifest.Manifest) (*SetupPlan, error) {
 	// Detect what's installed on the system
-	detected := detect.ScanRuntimes()
+	detected := detect.ScanRuntimes(context.Background())
 	detectedMap := make(map[string]detect.RuntimeInfo, len(detected))
 	for _, r := range detected {
 		detectedMap[r.Name] = r
 	}
 
+	// [runtimes] with this OS's table, e.g. [runtimes.darwin], merged over it
+	runtimes := m.RuntimesFor(runtime.GOOS)
+
 	plan := &SetupPlan{
-		Manifest: m,
-		Runtimes: make([]RuntimePlan, 0, len(m.Runtimes)),
+		Manifest:    m,
+		Runtimes:    make([]RuntimePlan, 0, len(runtimes)),
+		ArchivesDir: offlineArchives,
+	}
+
+	// Runtimes installed by earlier setups, which may not be on PATH yet
+	st, err := state.Load()
+	if err != nil {
+		st = state.NewState()
 	}
 
 	// Compare each required runtime against what's installed
-	for name, required := range m.Runtimes {
+	for name, required := range runtimes {
 		detectName, ok := runtimeDetectNames[name]
 		if !ok {
 			detectName = name
@@ -103,16 +181,27 @@ func BuildPlan(m *manifest.Manifest) (*SetupPlan, error) {
 			displayName = name
 		}
 
+		// "auto" requirements come from the template's own version files;
+		// validation has already warned if none could be read
+		required, source, err := manifest.ResolveRequirement(name, required, projectDir(m))
+		if err != nil {
+			required, source = "latest", ""
+		}
+
 		rp := RuntimePlan{
 			Name:            name,
 			DisplayName:     displayName,
 			RequiredVersion: required,
+			RequiredSource:  source,
+			SHA256:          m.RuntimesChecksums[name][manifest.Platform()],
+			ArchivesDir:     offlineArchives,
 		}
 
 		info, found := detectedMap[detectName]
 		if found && info.Installed {
 			rp.InstalledVersion = info.Version
 			rp.InstalledPath = info.Path
+			rp.Source = info.Source
 
 			// Check if installed version satisfies the requirement
 			satisfied, err := versionSatisfies(info.Version, required)
@@ -128,26 +217,237 @@ func BuildPlan(m *manifest.Manifest) (*SetupPlan, error) {
 			rp.Action = ActionInstall
 		}
 
+		// Prefer a satisfying version we installed earlier, then one from a
+		// version manager, over downloading another copy
+		if rp.Action != ActionSkip {
+			useTemplatrInstall(&rp, st)
+		}
+		if rp.Relink == "" && (rp.Action != ActionSkip || (rp.Source != detect.SourcePath && rp.Source != detect.SourceTemplatr)) {
+			useManagedVersion(&rp, detectName)
+		}
+
 		plan.Runtimes = append(plan.Runtimes, rp)
 	}
 
-	// Check package manager availability
-	if m.Packages.Manager != "" {
-		pp := &PackagePlan{
-			Manager:        m.Packages.Manager,
-			InstallCommand: m.Packages.InstallCommand,
-		}
-		managerDetect, ok := managerDetectNames[m.Packages.Manager]
-		if ok {
-			info, found := detectedMap[managerDetect]
-			pp.ManagerFound = found && info.Installed
+	// Without a manager in the manifest, go by the project's lockfile
+	pp := &PackagePlan{
+		Manager:         m.Packages.Manager,
+		InstallCommand:  m.Packages.InstallCommand,
+		RequiredVersion: m.Packages.ManagerVersion,
+	}
+	if pp.Manager == "" {
+		manager, file, command := manifest.InferManager(projectDir(m))
+		pp.Manager, pp.DetectedFrom = manager, file
+		if pp.InstallCommand == "" && len(m.Packages.Install) == 0 {
+			pp.InstallCommand = command
 		}
+	}
+	pp.Steps = installSteps(m, pp)
+	if pp.Manager == "" && len(pp.Steps) > 0 {
+		pp.Manager = pp.Steps[0].Manager
+	}
+
+	if pp.Manager != "" {
+		plan.checkManager(pp, detectedMap)
+	}
+	if pp.Manager != "" || len(pp.Steps) > 0 {
 		plan.Packages = pp
 	}
 
+	// Extra downloads run after runtimes
+	for _, dl := range m.Downloads {
+		dp := DownloadPlan{
+			Name:      dl.Name,
+			URL:       dl.URL,
+			SHA256:    dl.SHA256,
+			TargetDir: downloadTargetDir(m, dl),
+			Extract:   dl.Extract,
+			AuthEnv:   dl.AuthEnv,
+		}
+		dp.Action = downloadAction(dp, st)
+		plan.Downloads = append(plan.Downloads, dp)
+	}
+
+	// Compare against the manifest applied by the previous successful setup
+	if snap, err := state.LoadSnapshot(m); err == nil && snap != nil {
+		plan.Changes = manifest.Compare(snap.Manifest, m)
+	}
+
 	return plan, nil
 }
 
+// checkManager fills in whether pp's manager is available and new enough
+// for manager_version, and how it gets installed or upgraded if not.
+func (p *SetupPlan) checkManager(pp *PackagePlan, detected map[string]detect.RuntimeInfo) {
+	if managerDetect, ok := managerDetectNames[pp.Manager]; ok {
+		info, found := detected[managerDetect]
+		pp.ManagerFound = found && info.Installed
+		if pp.ManagerFound {
+			pp.ManagerVersion = info.Version
+		}
+	}
+	if !pp.ManagerFound {
+		pp.ManagerFound = p.installsRuntime(managerRuntimes[pp.Manager])
+	}
+	if !pp.ManagerFound {
+		pp.Bootstrap, pp.Runtime = p.bootstrapManager(pp.Manager, detected)
+		return
+	}
+
+	// A manager that comes with a runtime being installed has no version yet
+	if pp.RequiredVersion == "" || pp.ManagerVersion == "" {
+		return
+	}
+	if ok, err := versionSatisfies(pp.ManagerVersion, pp.RequiredVersion); err != nil || !ok {
+		pp.ManagerOutdated = true
+		pp.Bootstrap = p.upgradeManager(pp.Manager, detected)
+	}
+}
+
+// useTemplatrInstall picks the newest version recorded in state that is
+// still on disk and meets the requirement. Only the current link is on
+// PATH, so rp is satisfied by the version the link points at; any other
+// is kept in Relink for the runtime step to point the link at it.
+func useTemplatrInstall(rp *RuntimePlan, st *state.State) {
+	insts := st.GetInstallations(rp.Name)
+	for i := len(insts) - 1; i >= 0; i-- {
+		inst := insts[i]
+		if inst.Action == "download" {
+			continue
+		}
+		if info, err := os.Stat(inst.Path); err != nil || !info.IsDir() {
+			continue
+		}
+		if ok, err := versionSatisfies(inst.Version, rp.RequiredVersion); err != nil || !ok {
+			continue
+		}
+		if link := st.GetLink(rp.Name); link == nil || link.Target != inst.Path {
+			rp.Relink = inst.Path
+			rp.Note = fmt.Sprintf("%s %s was installed by templatr-setup in %s - current will be pointed at it", rp.DisplayName, inst.Version, inst.Path)
+			return
+		}
+		rp.Action = ActionSkip
+		rp.InstalledVersion = inst.Version
+		rp.InstalledPath = inst.Path
+		rp.Source = detect.SourceTemplatr
+		rp.Note = fmt.Sprintf("%s %s was installed by templatr-setup in %s - restart your terminal if it isn't found", rp.DisplayName, inst.Version, inst.Path)
+		return
+	}
+}
+
+// useManagedVersion marks rp as satisfied by the newest version manager
+// install that meets the requirement, with a note on how to activate it.
+func useManagedVersion(rp *RuntimePlan, detectName string) {
+	for _, mv := range detect.ManagedVersions(detectName) {
+		if ok, err := versionSatisfies(mv.Version, rp.RequiredVersion); err != nil || !ok {
+			continue
+		}
+		rp.Action = ActionSkip
+		rp.InstalledVersion = mv.Version
+		rp.InstalledPath = mv.Path
+		rp.Source = mv.Source
+		rp.Note = fmt.Sprintf("%s %s is installed with %s but may not be active in your shell - run `%s`", rp.DisplayName, mv.Version, mv.Source, mv.Activate)
+		return
+	}
+}
+
+// RequiredLabel is the requirement as shown in summaries, with the project
+// file it was read from, e.g. ">=20 (package.json)".
+func (r RuntimePlan) RequiredLabel() string {
+	if r.RequiredSource != "" {
+		return r.RequiredVersion + " (" + r.RequiredSource + ")"
+	}
+	return r.RequiredVersion
+}
+
+// InstalledLabel is the installed version as shown in summaries, with its
+// source when that's a version manager, e.g. "22.14.0 (nvm)". It is "-" if
+// nothing is installed.
+func (r RuntimePlan) InstalledLabel() string {
+	if r.InstalledVersion == "" {
+		return "-"
+	}
+	if r.Source != "" && r.Source != detect.SourcePath {
+		return r.InstalledVersion + " (" + r.Source + ")"
+	}
+	return r.InstalledVersion
+}
+
+// downloadTargetDir resolves where a download is placed. Relative target_dir
+// values are resolved against the manifest's directory; an empty target_dir
+// defaults to ~/.templatr/downloads/<name>.
+func downloadTargetDir(m *manifest.Manifest, dl manifest.Download) string {
+	if dl.TargetDir != "" {
+		dir := dl.TargetDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(projectDir(m), dir)
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".templatr", "downloads", dl.Name)
+	}
+	return filepath.Join(home, ".templatr", "downloads", dl.Name)
+}
+
+// downloadAction is ActionSkip when what dp places is already there: the
+// file itself, or for an archive the entries state records extracting into
+// its target directory. target_dir alone says nothing, as it is often
+// shared ("assets", "vendor").
+func downloadAction(dp DownloadPlan, st *state.State) ActionType {
+	if !dp.Extract {
+		if _, err := os.Stat(filepath.Join(dp.TargetDir, DownloadFilename(dp.URL))); err == nil {
+			return ActionSkip
+		}
+		return ActionInstall
+	}
+	for _, inst := range st.GetInstallations(dp.Name) {
+		if inst.Action != "download" || inst.Path != dp.TargetDir {
+			continue
+		}
+		if dp.SHA256 != "" && !strings.EqualFold(inst.Checksum, dp.SHA256) {
+			continue
+		}
+		if placed(inst) {
+			return ActionSkip
+		}
+	}
+	return ActionInstall
+}
+
+// placed reports whether what an extracted download recorded placing is all
+// still on disk: its entries, or its directory for state from before
+// entries were recorded.
+func placed(inst state.Installation) bool {
+	paths := inst.Entries
+	if len(paths) == 0 {
+		paths = []string{inst.Path}
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// DownloadFilename returns the file name from a download URL, ignoring any
+// query string (e.g., S3 presigned signatures).
+func DownloadFilename(rawURL string) string {
+	name := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		name = path.Base(u.Path)
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "download"
+	}
+	return name
+}
+
 // versionSatisfies checks if an installed version satisfies a requirement string.
 // Requirement can be: "latest", ">=20.0.0", "^20.0.0", "~20.0.0", "20.0.0", etc.
 func versionSatisfies(installed, required string) (bool, error) {
@@ -176,16 +476,172 @@ func versionSatisfies(installed, required string) (bool, error) {
 	return c.Check(v), nil
 }
 
-// NeedsAction returns true if the plan has any runtimes that need installation or upgrade.
-func (p *SetupPlan) NeedsAction() bool {
+// projectDir is the directory package commands run in: the manifest's, or
+// the working directory for a manifest that wasn't loaded from a file.
+func projectDir(m *manifest.Manifest) string {
+	if m.Dir != "" {
+		return m.Dir
+	}
+	return "."
+}
+
+// installsRuntime reports whether the plan installs or upgrades the named
+// runtime, making its binaries available before the package step runs.
+func (p *SetupPlan) installsRuntime(name string) bool {
 	for _, r := range p.Runtimes {
-		if r.Action != ActionSkip {
+		if r.Name == name && r.Action != ActionSkip {
 			return true
 		}
 	}
 	return false
 }
 
+// InstallRuntimes returns a copy of the plan's runtimes followed by the one
+// the package manager is bootstrapped with, if any: everything the runtime
+// step goes through.
+func (p *SetupPlan) InstallRuntimes() []RuntimePlan {
+	runtimes := slices.Clone(p.Runtimes)
+	if p.Packages != nil && p.Packages.Runtime != nil {
+		runtimes = append(runtimes, *p.Packages.Runtime)
+	}
+	return runtimes
+}
+
+// NeedsAction returns true if the plan has any runtimes that need installation
+// or upgrade, or any downloads that have not been fetched yet.
+func (p *SetupPlan) NeedsAction() bool {
+	for _, r := range p.InstallRuntimes() {
+		if r.Action != ActionSkip {
+			return true
+		}
+	}
+	for _, d := range p.Downloads {
+		if d.Action != ActionSkip {
+			return true
+		}
+	}
+	return p.Packages != nil && p.Packages.ManagerOutdated && p.Packages.Bootstrap != ""
+}
+
+// DownloadSize returns the approximate number of bytes the plan downloads
+// for the runtimes it installs or upgrades, or 0 if nothing was estimated.
+func (p *SetupPlan) DownloadSize() int64 {
+	var total int64
+	for _, r := range p.InstallRuntimes() {
+		if r.Action != ActionSkip {
+			total += r.DownloadSize
+		}
+	}
+	return total
+}
+
+// ApplyOverrides changes the action of the runtimes named in overrides, as
+// the user chose before confirming. Any runtime can be skipped; install
+// and upgrade are only accepted where they fit what is installed, so a
+// skip can be undone or an installed runtime reinstalled. Nothing is
+// changed if any override is invalid.
+func (p *SetupPlan) ApplyOverrides(overrides map[string]ActionType) error {
+	for name, action := range overrides {
+		i := slices.IndexFunc(p.Runtimes, func(r RuntimePlan) bool { return r.Name == name })
+		if i < 0 {
+			return fmt.Errorf("runtime %q is not in the plan", name)
+		}
+		if err := p.Runtimes[i].checkOverride(action); err != nil {
+			return err
+		}
+	}
+	for i := range p.Runtimes {
+		r := &p.Runtimes[i]
+		action, ok := overrides[r.Name]
+		if !ok {
+			continue
+		}
+		if r.PlannedAction == "" {
+			r.PlannedAction = r.Action
+		}
+		r.Action = action
+		if r.Action == r.PlannedAction {
+			r.PlannedAction = ""
+		}
+	}
+	return nil
+}
+
+// checkOverride returns an error if r's action can't be set to action.
+func (r RuntimePlan) checkOverride(action ActionType) error {
+	switch action {
+	case ActionSkip:
+		return nil
+	case ActionInstall:
+		if r.InstalledVersion != "" && !r.ForceReinstall {
+			return fmt.Errorf("%s %s is already installed; it can be upgraded or skipped", r.DisplayName, r.InstalledVersion)
+		}
+		return nil
+	case ActionUpgrade:
+		if r.InstalledVersion == "" {
+			return fmt.Errorf("%s is not installed, so it can't be upgraded", r.DisplayName)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q for %s", action, r.DisplayName)
+	}
+}
+
+// Overridden reports whether ApplyOverrides changed r's action.
+func (r RuntimePlan) Overridden() bool {
+	return r.PlannedAction != ""
+}
+
+// Only narrows the plan to the runtimes named, as setup --only picks them,
+// in the plan's order; no names keeps them all. Nothing is changed if a
+// name isn't one of the runtimes the manifest requires on this OS.
+func (p *SetupPlan) Only(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	for _, name := range names {
+		if !p.hasRuntime(name) {
+			known := make([]string, len(p.Runtimes))
+			for i, r := range p.Runtimes {
+				known[i] = r.Name
+			}
+			return fmt.Errorf("runtime %q is not in the manifest (it requires: %s)", name, strings.Join(known, ", "))
+		}
+	}
+	p.Runtimes = slices.DeleteFunc(p.Runtimes, func(r RuntimePlan) bool {
+		return !slices.Contains(names, r.Name)
+	})
+	return nil
+}
+
+// Force installs the plan's runtimes again even where what is installed
+// satisfies the requirement, as setup --force does: skips become installs
+// into a fresh versioned directory, and earlier installs aren't relinked.
+func (p *SetupPlan) Force() {
+	for i := range p.Runtimes {
+		r := &p.Runtimes[i]
+		if r.Action == ActionSkip {
+			r.Action, r.ForceReinstall = ActionInstall, true
+		}
+		r.Relink = ""
+	}
+}
+
+// Forced reports whether r is an install Force made of a runtime that was
+// already satisfied, and that the user hasn't since skipped.
+func (r RuntimePlan) Forced() bool {
+	return r.ForceReinstall && r.Action == ActionInstall
+}
+
+// ActionLabel returns r's action for the summary: "Reinstall" for a forced
+// install, otherwise its ActionIcon.
+func (r RuntimePlan) ActionLabel() string {
+	if r.Forced() {
+		return "Reinstall"
+	}
+	return r.Action.ActionIcon()
+}
+
 // ActionIcon returns a display icon for the action type.
 func (a ActionType) ActionIcon() string {
 	switch a {
@@ -1,7 +1,15 @@
 package engine
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/state"
 )
 
 func TestVersionSatisfies(t *testing.T) {
@@ -96,6 +104,199 @@ func TestSetupPlan_NeedsAction(t *testing.T) {
 	if plan3.NeedsAction() {
 		t.Error("NeedsAction() should return false for empty plan")
 	}
+
+	// Plan with only a pending download
+	plan4 := &SetupPlan{
+		Runtimes:  []RuntimePlan{{Action: ActionSkip}},
+		Downloads: []DownloadPlan{{Name: "sdk", Action: ActionInstall}},
+	}
+	if !plan4.NeedsAction() {
+		t.Error("NeedsAction() should return true when a download is pending")
+	}
+}
+
+func TestSetupPlan_InstallsRuntime(t *testing.T) {
+	plan := &SetupPlan{
+		Runtimes: []RuntimePlan{
+			{Name: "node", Action: ActionSkip},
+			{Name: "bun", Action: ActionInstall},
+		},
+	}
+	if !plan.installsRuntime("bun") {
+		t.Error("installsRuntime(bun) should be true when bun is planned for install")
+	}
+	if plan.installsRuntime("node") {
+		t.Error("installsRuntime(node) should be false for a skipped runtime")
+	}
+	if plan.installsRuntime(managerRuntimes["npm"]) {
+		t.Error("npm has no runtime of its own")
+	}
+}
+
+func TestSetupPlan_ApplyOverrides(t *testing.T) {
+	newPlan := func() *SetupPlan {
+		return &SetupPlan{Runtimes: []RuntimePlan{
+			{Name: "node", DisplayName: "Node.js", Action: ActionInstall},
+			{Name: "java", DisplayName: "Java", InstalledVersion: "17.0.2", Action: ActionUpgrade},
+			{Name: "go", DisplayName: "Go", InstalledVersion: "1.24.1", Action: ActionSkip},
+		}}
+	}
+
+	plan := newPlan()
+	if err := plan.ApplyOverrides(map[string]ActionType{"java": ActionSkip, "go": ActionUpgrade}); err != nil {
+		t.Fatal(err)
+	}
+	java, golang := plan.Runtimes[1], plan.Runtimes[2]
+	if java.Action != ActionSkip || java.PlannedAction != ActionUpgrade || !java.Overridden() {
+		t.Errorf("java = %+v, want skipped instead of upgraded", java)
+	}
+	if golang.Action != ActionUpgrade || golang.PlannedAction != ActionSkip {
+		t.Errorf("go = %+v, want reinstalled instead of skipped", golang)
+	}
+	if plan.Runtimes[0].Action != ActionInstall || plan.Runtimes[0].Overridden() {
+		t.Errorf("node = %+v, want it left as planned", plan.Runtimes[0])
+	}
+
+	// Setting the planned action again undoes the override
+	if err := plan.ApplyOverrides(map[string]ActionType{"java": ActionUpgrade}); err != nil {
+		t.Fatal(err)
+	}
+	if java := plan.Runtimes[1]; java.Action != ActionUpgrade || java.Overridden() {
+		t.Errorf("java = %+v, want back to its planned upgrade", java)
+	}
+
+	invalid := []map[string]ActionType{
+		{"ruby": ActionInstall},                     // not in the plan
+		{"node": ActionUpgrade},                     // nothing installed to upgrade
+		{"go": ActionInstall},                       // already installed
+		{"node": "reinstall"},                       // not an action
+		{"java": ActionSkip, "ruby": ActionInstall}, // one bad override rejects them all
+	}
+	for _, overrides := range invalid {
+		plan := newPlan()
+		if err := plan.ApplyOverrides(overrides); err == nil {
+			t.Errorf("ApplyOverrides(%v) should fail", overrides)
+		}
+		if plan.Runtimes[1].Action != ActionUpgrade {
+			t.Errorf("ApplyOverrides(%v) failed but changed the plan: %+v", overrides, plan.Runtimes[1])
+		}
+	}
+}
+
+func TestSetupPlan_Only(t *testing.T) {
+	newPlan := func() *SetupPlan {
+		return &SetupPlan{Runtimes: []RuntimePlan{
+			{Name: "node", DisplayName: "Node.js", Action: ActionInstall},
+			{Name: "java", DisplayName: "Java", InstalledVersion: "17.0.2", Action: ActionUpgrade},
+			{Name: "go", DisplayName: "Go", InstalledVersion: "1.24.1", Action: ActionSkip},
+		}}
+	}
+
+	plan := newPlan()
+	if err := plan.Only([]string{"go", "node"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Runtimes) != 2 || plan.Runtimes[0].Name != "node" || plan.Runtimes[1].Name != "go" {
+		t.Errorf("Only(go, node) = %+v, want node and go in the plan's order", plan.Runtimes)
+	}
+
+	plan = newPlan()
+	if err := plan.Only(nil); err != nil || len(plan.Runtimes) != 3 {
+		t.Errorf("Only(nil) = %v, %d runtimes, want all 3 kept", err, len(plan.Runtimes))
+	}
+
+	plan = newPlan()
+	err := plan.Only([]string{"node", "ruby"})
+	if err == nil || !strings.Contains(err.Error(), `"ruby"`) || !strings.Contains(err.Error(), "node, java, go") {
+		t.Errorf("Only(node, ruby) error = %v, want it to name ruby and the manifest's runtimes", err)
+	}
+	if len(plan.Runtimes) != 3 {
+		t.Errorf("Only(node, ruby) failed but changed the plan: %+v", plan.Runtimes)
+	}
+}
+
+func TestSetupPlan_Force(t *testing.T) {
+	plan := &SetupPlan{Manifest: &manifest.Manifest{}, Runtimes: []RuntimePlan{
+		{Name: "node", DisplayName: "Node.js", Action: ActionInstall},
+		{Name: "java", DisplayName: "Java", InstalledVersion: "17.0.2", Action: ActionUpgrade},
+		{Name: "go", DisplayName: "Go", InstalledVersion: "1.24.1", Action: ActionSkip},
+	}}
+	plan.Force()
+
+	golang := plan.Runtimes[2]
+	if golang.Action != ActionInstall || !golang.Forced() || golang.ActionLabel() != "Reinstall" {
+		t.Errorf("go = %+v, want a forced reinstall", golang)
+	}
+	if !plan.NeedsAction() {
+		t.Error("a forced plan should need action")
+	}
+	for _, r := range plan.Runtimes[:2] {
+		if r.Forced() || r.Overridden() || r.ActionLabel() != r.Action.ActionIcon() {
+			t.Errorf("%s = %+v, want it left as planned", r.Name, r)
+		}
+	}
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	PrintSummary(plan)
+	w.Close()
+	os.Stdout = stdout
+	out, _ := io.ReadAll(r)
+	if !strings.Contains(string(out), "Reinstall") || !strings.Contains(string(out), "1 to reinstall") {
+		t.Errorf("PrintSummary should show the forced reinstall, got:\n%s", out)
+	}
+}
+
+func TestRuntimeNames_InstallableRuntimes(t *testing.T) {
+	for name, want := range map[string]string{"bun": "Bun", "deno": "Deno"} {
+		if got := runtimeDisplayNames[name]; got != want {
+			t.Errorf("display name for %s: got %q, want %q", name, got, want)
+		}
+		if runtimeDetectNames[name] == "" {
+			t.Errorf("no detect name for %s", name)
+		}
+	}
+}
+
+func TestBuildPlan_Offline(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	m := &manifest.Manifest{Runtimes: map[string]string{"fakeruntime": "1.0.0"}}
+	m.Template.Name = "Offline Template"
+
+	SetOffline("/mnt/archives")
+	defer SetOffline("")
+
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %s", err)
+	}
+	if plan.ArchivesDir != "/mnt/archives" {
+		t.Errorf("plan.ArchivesDir = %q, want /mnt/archives", plan.ArchivesDir)
+	}
+	if len(plan.Runtimes) != 1 || plan.Runtimes[0].ArchivesDir != "/mnt/archives" {
+		t.Errorf("runtime plans should carry the archives dir, got %+v", plan.Runtimes)
+	}
+
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	PrintSummary(plan)
+	w.Close()
+	os.Stdout = stdout
+	out, _ := io.ReadAll(r)
+	if !strings.Contains(string(out), "Offline:  installing runtimes from archives in /mnt/archives") {
+		t.Errorf("PrintSummary should mention offline mode, got:\n%s", out)
+	}
+
+	SetOffline("")
+	plan, _ = BuildPlan(m)
+	if plan.ArchivesDir != "" || plan.Runtimes[0].ArchivesDir != "" {
+		t.Error("plans built after SetOffline(\"\") should be online")
+	}
 }
 
 func TestActionType_Icons(t *testing.T) {
@@ -118,3 +319,251 @@ func TestActionType_Icons(t *testing.T) {
 		}
 	}
 }
+
+// isolateDetection gives the test an empty HOME and PATH, so only
+// runtimes it sets up itself are detected.
+func isolateDetection(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, v := range []string{"NVM_DIR", "VOLTA_HOME", "PYENV_ROOT", "ASDF_DATA_DIR"} {
+		t.Setenv(v, "")
+	}
+	t.Setenv("PATH", t.TempDir())
+	return home
+}
+
+func TestBuildPlan_PrefersManagedVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("version manager layouts differ on Windows")
+	}
+	home := isolateDetection(t)
+	for _, v := range []string{"v20.11.0", "v22.14.0"} {
+		exe := filepath.Join(home, ".nvm", "versions", "node", v, "bin", "node")
+		os.MkdirAll(filepath.Dir(exe), 0o755)
+		os.WriteFile(exe, nil, 0o755)
+	}
+
+	m := &manifest.Manifest{Runtimes: map[string]string{"node": "^20.0.0", "python": ">=3.12"}}
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %s", err)
+	}
+
+	var node, python RuntimePlan
+	for _, rp := range plan.Runtimes {
+		switch rp.Name {
+		case "node":
+			node = rp
+		case "python":
+			python = rp
+		}
+	}
+	if node.Action != ActionSkip || node.InstalledVersion != "20.11.0" || node.Source != "nvm" {
+		t.Errorf("node should use the satisfying nvm version, got %+v", node)
+	}
+	if !strings.Contains(node.Note, "nvm use 20.11.0") {
+		t.Errorf("node note should say how to activate it, got %q", node.Note)
+	}
+	if node.InstalledLabel() != "20.11.0 (nvm)" {
+		t.Errorf("InstalledLabel() = %q", node.InstalledLabel())
+	}
+	if python.Action != ActionInstall || python.InstalledLabel() != "-" {
+		t.Errorf("python has no managed version and should be installed, got %+v", python)
+	}
+}
+
+func TestBuildPlan_PrefersTemplatrInstall(t *testing.T) {
+	home := isolateDetection(t) // as in a shell that hasn't sourced the rc file yet
+
+	installDir := filepath.Join(home, ".templatr", "runtimes", "node", "22.14.0")
+	os.MkdirAll(installDir, 0o755)
+	st := state.NewState()
+	st.AddInstallation(state.Installation{Runtime: "node", Version: "22.14.0", Path: installDir, Action: "install"})
+	st.SetLink(state.RuntimeLink{Runtime: "node", Path: filepath.Join(filepath.Dir(installDir), "current"), Target: installDir})
+	st.AddInstallation(state.Installation{Runtime: "go", Version: "1.22.5", Path: filepath.Join(home, "gone"), Action: "install"})
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &manifest.Manifest{Runtimes: map[string]string{"node": ">=22", "go": ">=1.22"}}
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %s", err)
+	}
+	for _, rp := range plan.Runtimes {
+		switch rp.Name {
+		case "node":
+			if rp.Action != ActionSkip || rp.InstalledPath != installDir || rp.Source != "templatr" {
+				t.Errorf("node should reuse our install, got %+v", rp)
+			}
+			if !strings.Contains(rp.Note, "restart your terminal") {
+				t.Errorf("note should mention restarting the shell, got %q", rp.Note)
+			}
+		case "go":
+			if rp.Action != ActionInstall {
+				t.Errorf("an install that is gone from disk should not count, got %+v", rp)
+			}
+		}
+	}
+
+	m.Runtimes = map[string]string{"node": ">=23"}
+	plan, _ = BuildPlan(m)
+	if plan.Runtimes[0].Action != ActionInstall {
+		t.Errorf("an install that doesn't satisfy the requirement should not count, got %+v", plan.Runtimes[0])
+	}
+}
+
+func TestBuildPlan_RelinksEarlierInstall(t *testing.T) {
+	home := isolateDetection(t)
+
+	nodeDir := filepath.Join(home, ".templatr", "runtimes", "node")
+	node20, node22 := filepath.Join(nodeDir, "20.11.0"), filepath.Join(nodeDir, "22.14.0")
+	os.MkdirAll(node20, 0o755)
+	os.MkdirAll(node22, 0o755)
+	st := state.NewState()
+	st.AddInstallation(state.Installation{Runtime: "node", Version: "20.11.0", Path: node20, Action: "install"})
+	st.AddInstallation(state.Installation{Runtime: "node", Version: "22.14.0", Path: node22, Action: "install"})
+	st.SetLink(state.RuntimeLink{Runtime: "node", Path: filepath.Join(nodeDir, "current"), Target: node22})
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// current, and so PATH, has 22: 20 is there but has to be linked
+	m := &manifest.Manifest{Runtimes: map[string]string{"node": "^20"}}
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %s", err)
+	}
+	rp := plan.Runtimes[0]
+	if rp.Action == ActionSkip || rp.Relink != node20 {
+		t.Errorf("node should relink 20.11.0, got %+v", rp)
+	}
+	if !strings.Contains(rp.Note, "current will be pointed at it") {
+		t.Errorf("note should say current is repointed, got %q", rp.Note)
+	}
+
+	// The version current points at satisfies it as it is
+	m.Runtimes = map[string]string{"node": ">=22"}
+	plan, _ = BuildPlan(m)
+	if rp := plan.Runtimes[0]; rp.Action != ActionSkip || rp.Relink != "" || rp.InstalledPath != node22 {
+		t.Errorf("node should skip with the linked 22.14.0, got %+v", rp)
+	}
+
+	// --force downloads again rather than relinking
+	m.Runtimes = map[string]string{"node": "^20"}
+	plan, _ = BuildPlan(m)
+	plan.Force()
+	if rp := plan.Runtimes[0]; rp.Relink != "" {
+		t.Errorf("Force() kept the relink: %+v", rp)
+	}
+}
+
+func TestBuildPlan_AutoRequirement(t *testing.T) {
+	isolateDetection(t)
+	dir := t.TempDir()
+	t.Chdir(t.TempDir()) // version files are read from the manifest's directory
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"engines": {"node": ">=20"}}`), 0o644)
+
+	m := &manifest.Manifest{Dir: dir, Runtimes: map[string]string{"node": "auto", "python": "auto"}}
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rp := range plan.Runtimes {
+		switch rp.Name {
+		case "node":
+			if rp.RequiredVersion != ">=20" || rp.RequiredSource != "package.json" {
+				t.Errorf("node requirement = %q from %q, want >=20 from package.json", rp.RequiredVersion, rp.RequiredSource)
+			}
+			if got := rp.RequiredLabel(); got != ">=20 (package.json)" {
+				t.Errorf("RequiredLabel() = %q", got)
+			}
+		case "python":
+			// No .python-version, so it falls back to latest
+			if rp.RequiredVersion != "latest" || rp.RequiredSource != "" {
+				t.Errorf("python requirement = %q from %q, want latest", rp.RequiredVersion, rp.RequiredSource)
+			}
+		}
+	}
+}
+
+func TestBuildPlan_RuntimesForThisOS(t *testing.T) {
+	isolateDetection(t)
+	m := &manifest.Manifest{
+		Runtimes: map[string]string{"node": ">=20"},
+		RuntimesOS: map[string]map[string]string{
+			runtime.GOOS: {"node": "22.14.0", "go": ">=1.22"},
+			"plan9":      {"python": ">=3.12"},
+		},
+	}
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, rp := range plan.Runtimes {
+		got[rp.Name] = rp.RequiredVersion
+	}
+	if len(got) != 2 || got["node"] != "22.14.0" || got["go"] != ">=1.22" {
+		t.Errorf("plan runtimes = %v, want node 22.14.0 and go >=1.22 from the %s table", got, runtime.GOOS)
+	}
+}
+
+func TestBuildPlan_Downloads(t *testing.T) {
+	home := isolateDetection(t)
+	project := filepath.Join(home, "project")
+	os.MkdirAll(filepath.Join(project, "assets"), 0o755)
+	os.MkdirAll(filepath.Join(project, "sdk"), 0o755)
+
+	m := &manifest.Manifest{Dir: project, Downloads: []manifest.Download{
+		{Name: "logo", URL: "https://example.com/img/logo.svg?sig=1", TargetDir: "assets"},
+		{Name: "sdk", URL: "https://example.com/sdk.tar.gz", TargetDir: "sdk", Extract: true},
+	}}
+	plan, err := BuildPlan(m)
+	if err != nil {
+		t.Fatalf("BuildPlan() error: %s", err)
+	}
+	if got := plan.Downloads[0].TargetDir; got != filepath.Join(project, "assets") {
+		t.Errorf("TargetDir = %q, want it resolved against the manifest's directory", got)
+	}
+	for _, dp := range plan.Downloads {
+		if dp.Action != ActionInstall {
+			t.Errorf("%s: an existing target_dir without the download in it should install, got %s", dp.Name, dp.Action)
+		}
+	}
+
+	os.WriteFile(filepath.Join(project, "assets", "logo.svg"), []byte("<svg/>"), 0o644)
+	lib := filepath.Join(project, "sdk", "lib")
+	st := state.NewState()
+	st.AddInstallation(state.Installation{Runtime: "sdk", Path: filepath.Join(project, "sdk"), Action: "download", Entries: []string{lib}})
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+	plan, _ = BuildPlan(m)
+	if got := plan.Downloads[1].Action; got != ActionInstall {
+		t.Errorf("sdk: an archive whose entries were deleted should install again, got %s", got)
+	}
+
+	os.MkdirAll(lib, 0o755)
+	plan, _ = BuildPlan(m)
+	for _, dp := range plan.Downloads {
+		if dp.Action != ActionSkip {
+			t.Errorf("%s: a download already in place should be skipped, got %s", dp.Name, dp.Action)
+		}
+	}
+}
+
+func TestDownloadFilename(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/sdk/v1/sdk.tar.gz":           "sdk.tar.gz",
+		"https://bucket.s3.amazonaws.com/sdk.zip?X=1&Y=2": "sdk.zip",
+		"https://example.com/":                            "download",
+	}
+	for in, want := range tests {
+		if got := DownloadFilename(in); got != want {
+			t.Errorf("DownloadFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
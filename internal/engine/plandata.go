@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// PlanData is the setup plan serialized as JSON, for the web UI and for
+// setup --dry-run --json.
+type PlanData struct {
+	Template    TemplateData   `json:"template"`
+	NeedsAction bool           `json:"needsAction"` // a runtime or download will be installed
+	Runtimes    []RuntimeData  `json:"runtimes"`
+	Packages    *PackageData   `json:"packages,omitempty"`
+	EnvVars     []EnvVarData   `json:"envVars,omitempty"`
+	Configs     []ConfigData   `json:"configs,omitempty"`
+	Downloads   []DownloadData `json:"downloads,omitempty"`
+	PostSetup   []string       `json:"postSetup,omitempty"` // post-setup commands that run on this OS
+	Changes     *manifest.Diff `json:"changes,omitempty"`   // nil on first setup
+	// Environments declared in [env_environments], in order
+	Environments []string `json:"environments,omitempty"`
+	// Offline mode: runtimes are installed from archives in this directory
+	ArchivesDir string `json:"archivesDir,omitempty"`
+	// Approx. bytes downloaded for the runtimes to install, 0 if unknown
+	DownloadSize int64 `json:"downloadSize,omitempty"`
+}
+
+// TemplateData is template info for the web UI.
+type TemplateData struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Tier     string `json:"tier"`
+	Category string `json:"category"`
+}
+
+// RuntimeData is runtime plan info for the web UI.
+type RuntimeData struct {
+	Name             string `json:"name"`
+	DisplayName      string `json:"displayName"`
+	RequiredVersion  string `json:"requiredVersion"`
+	RequiredSource   string `json:"requiredSource,omitempty"` // project file an "auto" requirement was read from
+	InstalledVersion string `json:"installedVersion"`
+	Source           string `json:"source,omitempty"` // "path", or a version manager such as "nvm"
+	Note             string `json:"note,omitempty"`
+	Action           string `json:"action"`
+	Status           string `json:"status,omitempty"` // "complete" or "failed" once installed or failed this session
+}
+
+// DownloadData is a [[downloads]] entry for the web UI. ID is the key used
+// in runtime/download/install progress messages.
+type DownloadData struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	TargetDir     string `json:"targetDir"`
+	Authenticated bool   `json:"authenticated"`
+	Action        string `json:"action"`
+	Status        string `json:"status,omitempty"` // as for RuntimeData
+}
+
+// PackageData is package manager info for the web UI.
+type PackageData struct {
+	Manager         string            `json:"manager"`
+	InstallCommand  string            `json:"installCommand"`
+	ManagerFound    bool              `json:"managerFound"`
+	ManagerVersion  string            `json:"managerVersion,omitempty"`  // detected version, e.g. "7.33.0"
+	RequiredVersion string            `json:"requiredVersion,omitempty"` // [packages] manager_version, e.g. ">=9"
+	ManagerOutdated bool              `json:"managerOutdated,omitempty"` // found, but older than requiredVersion
+	Bootstrap       string            `json:"bootstrap,omitempty"`       // "corepack", "ensurepip", "runtime" or "npm" when a missing or outdated manager will be installed
+	DetectedFrom    string            `json:"detectedFrom,omitempty"`    // project file the manager was inferred from, e.g. "pnpm-lock.yaml"
+	Steps           []InstallStepData `json:"steps"`
+}
+
+// InstallStepData is one package install command for the web UI.
+type InstallStepData struct {
+	Dir     string `json:"dir,omitempty"`
+	Command string `json:"command"`
+	Manager string `json:"manager,omitempty"`
+}
+
+// EnvVarData is an env var definition for the web UI form.
+type EnvVarData struct {
+	Key         string `json:"key"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Default     string `json:"default"`
+	Required    bool   `json:"required"`
+	Type        string `json:"type"`
+	DocsURL     string `json:"docsUrl,omitempty"`
+	File        string `json:"file,omitempty"`
+	// Environments the var takes a separate value in; empty means one
+	// value shared by all environments.
+	Environments []string `json:"environments,omitempty"`
+	Options      []string `json:"options,omitempty"` // choices for type "select"
+	Pattern      string   `json:"pattern,omitempty"` // regular expression the value must match
+	Min          *float64 `json:"min,omitempty"`     // bounds for type "number"
+	Max          *float64 `json:"max,omitempty"`
+	When         string   `json:"when,omitempty"` // shown only while this holds, e.g. "PAYMENT_PROVIDER == 'stripe'"
+}
+
+// ConfigData is a config file definition for the web UI form.
+type ConfigData struct {
+	File        string          `json:"file"`
+	Label       string          `json:"label"`
+	Description string          `json:"description"`
+	Fields      []ConfigFieldUI `json:"fields"`
+}
+
+// ConfigFieldUI is a single config field for the web UI form.
+type ConfigFieldUI struct {
+	Path        string   `json:"path"`
+	Label       string   `json:"label"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default"`
+	Options     []string `json:"options,omitempty"` // choices for type "select"
+	Pattern     string   `json:"pattern,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	When        string   `json:"when,omitempty"`
+}
+
+// NewPlanData converts a SetupPlan to a PlanData.
+func NewPlanData(plan *SetupPlan) *PlanData {
+	pd := &PlanData{
+		Template: TemplateData{
+			Name:     plan.Manifest.Template.Name,
+			Version:  plan.Manifest.Template.Version,
+			Tier:     plan.Manifest.Template.Tier,
+			Category: plan.Manifest.Template.Category,
+		},
+		NeedsAction:  plan.NeedsAction(),
+		Environments: plan.Manifest.EnvEnvironments.Names,
+		ArchivesDir:  plan.ArchivesDir,
+		DownloadSize: plan.DownloadSize(),
+	}
+
+	if plan.Changes != nil && !plan.Changes.Empty() {
+		pd.Changes = plan.Changes
+	}
+
+	for _, rp := range plan.Runtimes {
+		pd.Runtimes = append(pd.Runtimes, RuntimeData{
+			Name:             rp.Name,
+			DisplayName:      rp.DisplayName,
+			RequiredVersion:  rp.RequiredVersion,
+			RequiredSource:   rp.RequiredSource,
+			InstalledVersion: rp.InstalledVersion,
+			Source:           rp.Source,
+			Note:             rp.Note,
+			Action:           string(rp.Action),
+		})
+	}
+
+	for _, dp := range plan.Downloads {
+		pd.Downloads = append(pd.Downloads, DownloadData{
+			ID:            DownloadID(dp.Name),
+			Name:          dp.Name,
+			TargetDir:     dp.TargetDir,
+			Authenticated: dp.AuthEnv != "",
+			Action:        string(dp.Action),
+		})
+	}
+
+	if plan.Packages != nil {
+		pd.Packages = &PackageData{
+			Manager:         plan.Packages.Manager,
+			InstallCommand:  plan.Packages.InstallCommand,
+			ManagerFound:    plan.Packages.ManagerFound,
+			ManagerVersion:  plan.Packages.ManagerVersion,
+			RequiredVersion: plan.Packages.RequiredVersion,
+			ManagerOutdated: plan.Packages.ManagerOutdated,
+			Bootstrap:       plan.Packages.Bootstrap,
+			DetectedFrom:    plan.Packages.DetectedFrom,
+			Steps:           []InstallStepData{},
+		}
+		for _, step := range plan.Packages.Steps {
+			pd.Packages.Steps = append(pd.Packages.Steps, InstallStepData{Dir: step.Dir, Command: step.Command, Manager: step.Manager})
+		}
+	}
+
+	pd.PostSetup = plan.Manifest.PostSetup.CommandsFor(runtime.GOOS)
+
+	for _, env := range plan.Manifest.Env {
+		pd.EnvVars = append(pd.EnvVars, EnvVarData{
+			Key:         env.Key,
+			Label:       env.Label,
+			Description: env.Description,
+			Default:     env.Default,
+			Required:    env.Required,
+			Type:        env.Type,
+			DocsURL:     env.DocsURL,
+			File:        env.File,
+
+			Environments: env.Environments,
+			Options:      env.Options,
+			Pattern:      env.Pattern,
+			Min:          env.Min,
+			Max:          env.Max,
+			When:         env.When,
+		})
+	}
+
+	for _, cfg := range plan.Manifest.Config {
+		cd := ConfigData{
+			File:        cfg.File,
+			Label:       cfg.Label,
+			Description: cfg.Description,
+		}
+		for _, field := range cfg.Fields {
+			cd.Fields = append(cd.Fields, ConfigFieldUI{
+				Path:        field.Path,
+				Label:       field.Label,
+				Description: field.Description,
+				Type:        field.Type,
+				Default:     field.Default,
+				Options:     field.Options,
+				Pattern:     field.Pattern,
+				Min:         field.Min,
+				Max:         field.Max,
+				When:        field.When,
+			})
+		}
+		pd.Configs = append(pd.Configs, cd)
+	}
+
+	return pd
+}
+
+// WritePlanJSON writes the plan to w as indented PlanData JSON, as setup
+// --dry-run --json prints it. Constraints such as ">=20" are left
+// unescaped.
+func WritePlanJSON(w io.Writer, plan *SetupPlan) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(NewPlanData(plan)); err != nil {
+		return fmt.Errorf("encoding the plan: %w", err)
+	}
+	return nil
+}
+
+// DownloadID returns the progress-message key for a download so it can't
+// collide with a runtime name.
+func DownloadID(name string) string {
+	return "download:" + name
+}
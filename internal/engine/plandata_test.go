@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestNewPlanData_Offline(t *testing.T) {
+	plan := &SetupPlan{Manifest: &manifest.Manifest{}, ArchivesDir: "/mnt/archives"}
+
+	data, err := json.Marshal(NewPlanData(plan))
+	if err != nil {
+		t.Fatalf("marshal failed: %s", err)
+	}
+	if !strings.Contains(string(data), `"archivesDir":"/mnt/archives"`) {
+		t.Errorf("plan data should carry the archives dir in offline mode, got %s", data)
+	}
+
+	plan.ArchivesDir = ""
+	data, _ = json.Marshal(NewPlanData(plan))
+	if strings.Contains(string(data), "archivesDir") {
+		t.Errorf("archivesDir should be omitted when online, got %s", data)
+	}
+}
+
+func TestNewPlanData_SelectOptions(t *testing.T) {
+	plan := &SetupPlan{Manifest: &manifest.Manifest{
+		Env: []manifest.EnvVar{
+			{Key: "PAYMENT_PROVIDER", Type: "select", Options: []string{"stripe", "lemonsqueezy"}, Default: "stripe"},
+			{Key: "SITE_URL", Type: "url"},
+		},
+		Config: []manifest.ConfigFile{{
+			File:   "src/config/site.ts",
+			Fields: []manifest.ConfigField{{Path: "siteConfig.db", Type: "select", Options: []string{"postgres", "sqlite"}}},
+		}},
+	}}
+
+	data, err := json.Marshal(NewPlanData(plan))
+	if err != nil {
+		t.Fatalf("marshal failed: %s", err)
+	}
+
+	var decoded struct {
+		EnvVars []struct {
+			Key     string   `json:"key"`
+			Options []string `json:"options"`
+		} `json:"envVars"`
+		Configs []struct {
+			Fields []struct {
+				Options []string `json:"options"`
+			} `json:"fields"`
+		} `json:"configs"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %s", err)
+	}
+	if len(decoded.EnvVars) != 2 || strings.Join(decoded.EnvVars[0].Options, ",") != "stripe,lemonsqueezy" {
+		t.Errorf("env options not serialized: %s", data)
+	}
+	if strings.Count(string(data), `"options"`) != 2 {
+		t.Errorf("options should be omitted for fields without choices: %s", data)
+	}
+	if len(decoded.Configs) != 1 || strings.Join(decoded.Configs[0].Fields[0].Options, ",") != "postgres,sqlite" {
+		t.Errorf("config field options not serialized: %s", data)
+	}
+}
+
+func TestNewPlanData_FieldRules(t *testing.T) {
+	lo, hi := 1.0, 65535.0
+	plan := &SetupPlan{Manifest: &manifest.Manifest{
+		Env: []manifest.EnvVar{
+			{Key: "STRIPE_SECRET_KEY", Type: "secret", Pattern: "^sk_(test|live)_"},
+			{Key: "PORT", Type: "number", Min: &lo, Max: &hi},
+		},
+		Config: []manifest.ConfigFile{{
+			File:   "src/config/site.ts",
+			Fields: []manifest.ConfigField{{Path: "siteConfig.workers", Type: "number", Min: &lo}},
+		}},
+	}}
+
+	data, err := json.Marshal(NewPlanData(plan))
+	if err != nil {
+		t.Fatalf("marshal failed: %s", err)
+	}
+	for _, want := range []string{`"pattern":"^sk_(test|live)_"`, `"min":1,"max":65535`, `"type":"number","default":"","min":1}`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("plan data = %s, missing %s", data, want)
+		}
+	}
+}
+
+func TestWritePlanJSON(t *testing.T) {
+	plan := &SetupPlan{
+		Manifest: &manifest.Manifest{
+			Template: manifest.TemplateInfo{Name: "Shop", Version: "1.2.0"},
+			Env:      []manifest.EnvVar{{Key: "SITE_URL", Type: "url", Required: true}},
+			Config: []manifest.ConfigFile{{
+				File:   "src/config/site.ts",
+				Fields: []manifest.ConfigField{{Path: "site.name", Default: "Shop"}},
+			}},
+		},
+		Runtimes: []RuntimePlan{
+			{Name: "node", DisplayName: "Node.js", RequiredVersion: ">=20", RequiredSource: ".nvmrc", InstalledVersion: "18.19.0", Action: ActionUpgrade},
+			{Name: "python", DisplayName: "Python", RequiredVersion: ">=3.12", InstalledVersion: "3.12.4", Action: ActionSkip},
+		},
+		Packages: &PackagePlan{Manager: "pnpm", InstallCommand: "pnpm install", ManagerFound: false, Bootstrap: "corepack",
+			Steps: []InstallStep{{Command: "pnpm install", Manager: "pnpm"}}},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePlanJSON(&buf, plan); err != nil {
+		t.Fatalf("WritePlanJSON failed: %s", err)
+	}
+	var got PlanData
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %s\n%s", err, buf.String())
+	}
+	if !got.NeedsAction || got.Template.Name != "Shop" {
+		t.Errorf("got needsAction %v, template %+v, want the shop needing action", got.NeedsAction, got.Template)
+	}
+	if len(got.Runtimes) != 2 || got.Runtimes[0].Action != "upgrade" || got.Runtimes[0].RequiredVersion != ">=20" ||
+		got.Runtimes[0].RequiredSource != ".nvmrc" || got.Runtimes[1].Action != "skip" {
+		t.Errorf("runtimes = %+v", got.Runtimes)
+	}
+	if got.Packages == nil || got.Packages.Manager != "pnpm" || got.Packages.ManagerFound || got.Packages.Bootstrap != "corepack" {
+		t.Errorf("packages = %+v", got.Packages)
+	}
+	if got.Packages != nil && (got.Packages.ManagerVersion != "" || got.Packages.ManagerOutdated) {
+		t.Errorf("packages = %+v, want no version for a missing manager", got.Packages)
+	}
+	if len(got.EnvVars) != 1 || got.EnvVars[0].Key != "SITE_URL" || !got.EnvVars[0].Required {
+		t.Errorf("envVars = %+v", got.EnvVars)
+	}
+	if len(got.Configs) != 1 || got.Configs[0].Fields[0].Path != "site.name" {
+		t.Errorf("configs = %+v", got.Configs)
+	}
+
+	plan.Runtimes[0].Action = ActionSkip
+	buf.Reset()
+	WritePlanJSON(&buf, plan)
+	if !strings.Contains(buf.String(), `"needsAction": false`) || !strings.Contains(buf.String(), `"requiredVersion": ">=20"`) {
+		t.Errorf("needsAction should be false with every runtime satisfied, and constraints unescaped:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package fsutil
+
+import "os"
+
+// replaceFile renames src over dst, which rename(2) does atomically.
+func replaceFile(src, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// syncDir flushes a directory entry change, such as a rename, to disk.
+// Errors are ignored: not every filesystem supports syncing directories.
+func syncDir(dir string) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	f.Sync()
+	f.Close()
+}
@@ -0,0 +1,53 @@
+package fsutil
+
+import (
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	kernel32        = syscall.NewLazyDLL("kernel32.dll")
+	procMoveFileExW = kernel32.NewProc("MoveFileExW")
+)
+
+const (
+	moveFileReplaceExisting = 0x1
+	moveFileWriteThrough    = 0x8
+
+	errorSharingViolation syscall.Errno = 32
+)
+
+// replaceFile renames src over an existing dst. MoveFileEx replaces dst in
+// one step; it fails while another process (often a virus scanner or an
+// editor) has dst open, so it is retried for a moment before giving up.
+func replaceFile(src, dst string) error {
+	from, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.UTF16PtrFromString(dst)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		r, _, err := procMoveFileExW.Call(
+			uintptr(unsafe.Pointer(from)),
+			uintptr(unsafe.Pointer(to)),
+			moveFileReplaceExisting|moveFileWriteThrough,
+		)
+		if r != 0 {
+			return nil
+		}
+		if attempt >= 10 || !(errors.Is(err, syscall.ERROR_ACCESS_DENIED) || errors.Is(err, errorSharingViolation)) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// syncDir is a no-op on Windows: MOVEFILE_WRITE_THROUGH already flushes
+// the rename, and directories can't be opened for syncing.
+func syncDir(dir string) {}
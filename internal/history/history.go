@@ -0,0 +1,135 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	historyFile = ".templatr/history.jsonl"
+
+	// maxHistoryBytes caps the history file; older records are pruned first.
+	maxHistoryBytes = 1 << 20
+)
+
+// historyPath returns the full path to the history file.
+func historyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, historyFile), nil
+}
+
+// Append writes r as one line to ~/.templatr/history.jsonl.
+func Append(r *SetupReport) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	return appendTo(path, r, maxHistoryBytes)
+}
+
+// Load reads all readable records from ~/.templatr/history.jsonl.
+// Malformed lines are skipped and counted rather than failing the read.
+func Load() ([]SetupReport, int, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, 0, err
+	}
+	return loadFrom(path)
+}
+
+func appendTo(path string, r *SetupReport, maxBytes int64) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal setup report: %w", err)
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return prune(path, maxBytes)
+}
+
+// prune drops the oldest lines until the file fits within maxBytes.
+func prune(path string, maxBytes int64) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() <= maxBytes {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Keep at most half the cap so pruning doesn't run on every append.
+	keep := maxBytes / 2
+	for int64(len(data)) > keep {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			data = nil
+			break
+		}
+		data = data[i+1:]
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to prune history file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func loadFrom(path string) ([]SetupReport, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to read history file: %w", err)
+	}
+	defer f.Close()
+
+	var reports []SetupReport
+	skipped := 0
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r SetupReport
+		if err := json.Unmarshal(line, &r); err != nil || r.Template == "" {
+			skipped++
+			continue
+		}
+		reports = append(reports, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return reports, skipped, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	return reports, skipped, nil
+}
@@ -0,0 +1,116 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	for _, tmpl := range []string{"saas", "crm"} {
+		r := &SetupReport{Template: tmpl, Mode: "plain", StartedAt: time.Now().UTC(), Success: true}
+		if err := appendTo(path, r, maxHistoryBytes); err != nil {
+			t.Fatalf("appendTo failed: %s", err)
+		}
+	}
+
+	reports, skipped, err := loadFrom(path)
+	if err != nil {
+		t.Fatalf("loadFrom failed: %s", err)
+	}
+	if skipped != 0 {
+		t.Errorf("skipped = %d, want 0", skipped)
+	}
+	if len(reports) != 2 || reports[0].Template != "saas" || reports[1].Template != "crm" {
+		t.Errorf("unexpected reports: %+v", reports)
+	}
+}
+
+func TestLoad_SkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := strings.Join([]string{
+		`{"template":"saas","mode":"tui","success":true,"bytes_downloaded":100}`,
+		`{"template":"saas","mode":"tui","succ`, // truncated write
+		``,
+		`not json at all`,
+		`{"mode":"tui"}`, // missing template
+		`{"template":"crm","mode":"web","success":false,"error_kind":"network"}`,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, skipped, err := loadFrom(path)
+	if err != nil {
+		t.Fatalf("loadFrom failed: %s", err)
+	}
+	if len(reports) != 2 {
+		t.Errorf("got %d reports, want 2", len(reports))
+	}
+	if skipped != 3 {
+		t.Errorf("skipped = %d, want 3", skipped)
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	reports, skipped, err := loadFrom(filepath.Join(t.TempDir(), "nope.jsonl"))
+	if err != nil || reports != nil || skipped != 0 {
+		t.Errorf("expected empty result for missing file, got %v %d %v", reports, skipped, err)
+	}
+}
+
+func TestAppend_PrunesOldestRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	const maxBytes = 2000
+
+	for i := 0; i < 100; i++ {
+		r := &SetupReport{Template: "t" + strings.Repeat("x", i%5), Mode: "plain", DurationMs: int64(i)}
+		if err := appendTo(path, r, maxBytes); err != nil {
+			t.Fatalf("appendTo failed: %s", err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > maxBytes {
+		t.Errorf("history size %d exceeds cap %d", info.Size(), maxBytes)
+	}
+
+	reports, skipped, err := loadFrom(path)
+	if err != nil || skipped != 0 {
+		t.Fatalf("pruned file should still parse cleanly: skipped=%d err=%v", skipped, err)
+	}
+	if len(reports) == 0 || reports[len(reports)-1].DurationMs != 99 {
+		t.Errorf("newest record should be kept, got %+v", reports[len(reports)-1:])
+	}
+}
+
+func TestAddResults_BootstrapRuntime(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{Template: manifest.TemplateInfo{Name: "App"}},
+		Runtimes: []engine.RuntimePlan{{Name: "node", Action: engine.ActionUpgrade}},
+		Packages: &engine.PackagePlan{Manager: "bun", Bootstrap: engine.BootstrapRuntime, Runtime: &engine.RuntimePlan{Name: "bun", Action: engine.ActionInstall}},
+	}
+	r := NewReport(plan, "plain")
+	r.AddResults(plan, []install.InstallResult{{Runtime: "node", Version: "22.11.0"}, {Runtime: "bun", Version: "1.2.0"}})
+
+	if len(r.Runtimes) != 2 {
+		t.Fatalf("runtimes = %+v, want node and bun", r.Runtimes)
+	}
+	if node := r.Runtimes[0]; node.Action != "upgrade" || node.Bootstrap {
+		t.Errorf("node = %+v, want an upgrade from [runtimes]", node)
+	}
+	if bun := r.Runtimes[1]; bun.Action != "install" || !bun.Bootstrap {
+		t.Errorf("bun = %+v, want an install for the package manager", bun)
+	}
+}
@@ -0,0 +1,156 @@
+package history
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+)
+
+// Error kinds used to group failures in stats.
+const (
+	ErrorKindNetwork    = "network"
+	ErrorKindChecksum   = "checksum"
+	ErrorKindPermission = "permission"
+	ErrorKindExtract    = "extract"
+	ErrorKindResolve    = "resolve"
+	ErrorKindOther      = "other"
+)
+
+// SetupReport summarizes a single setup run. One compact JSON record per run
+// is appended to the history file.
+type SetupReport struct {
+	Template        string          `json:"template"`
+	TemplateVersion string          `json:"template_version,omitempty"`
+	Mode            string          `json:"mode"` // "tui", "plain", or "web"
+	StartedAt       time.Time       `json:"started_at"`
+	DurationMs      int64           `json:"duration_ms"`
+	Success         bool            `json:"success"`
+	ErrorKind       string          `json:"error_kind,omitempty"`
+	Runtimes        []RuntimeReport `json:"runtimes,omitempty"`
+	BytesDownloaded int64           `json:"bytes_downloaded"`
+
+	bootstrap string // the runtime installed for the package manager, if any
+}
+
+// RuntimeReport records what happened to one runtime (or [[downloads]] entry).
+type RuntimeReport struct {
+	Name       string `json:"name"`
+	Version    string `json:"version,omitempty"`
+	Action     string `json:"action"`           // skip, install, upgrade, download
+	Cached     bool   `json:"cached,omitempty"` // satisfied without downloading
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	Bootstrap  bool   `json:"bootstrap,omitempty"` // installed for the package manager rather than listed in [runtimes]
+}
+
+// NewReport starts a report for the given plan. Runtimes that are already
+// satisfied are recorded as cache hits immediately.
+func NewReport(plan *engine.SetupPlan, mode string) *SetupReport {
+	r := &SetupReport{
+		Template:        templateKey(plan),
+		TemplateVersion: plan.Manifest.Template.Version,
+		Mode:            mode,
+		StartedAt:       time.Now().UTC(),
+	}
+	if plan.Packages != nil && plan.Packages.Runtime != nil {
+		r.bootstrap = plan.Packages.Runtime.Name
+	}
+
+	for _, rp := range plan.Runtimes {
+		if rp.Action == engine.ActionSkip {
+			r.Runtimes = append(r.Runtimes, RuntimeReport{
+				Name:    rp.Name,
+				Version: rp.InstalledVersion,
+				Action:  string(rp.Action),
+				Cached:  true,
+			})
+		}
+	}
+	for _, dp := range plan.Downloads {
+		if dp.Action == engine.ActionSkip {
+			r.Runtimes = append(r.Runtimes, RuntimeReport{Name: dp.Name, Action: install.ActionDownload, Cached: true})
+		}
+	}
+
+	return r
+}
+
+// AddResult records an installed runtime or download.
+func (r *SetupReport) AddResult(action string, res install.InstallResult) {
+	r.Runtimes = append(r.Runtimes, RuntimeReport{
+		Name:       res.Runtime,
+		Version:    res.Version,
+		Action:     action,
+		DurationMs: res.Duration.Milliseconds(),
+		Bytes:      res.Bytes,
+		Bootstrap:  r.bootstrap != "" && res.Runtime == r.bootstrap,
+	})
+	r.BytesDownloaded += res.Bytes
+}
+
+// AddResults records results from install.ExecutePlan, looking up each
+// runtime's planned action.
+func (r *SetupReport) AddResults(plan *engine.SetupPlan, results []install.InstallResult) {
+	runtimes := plan.InstallRuntimes()
+	actions := make(map[string]string, len(runtimes))
+	for _, rp := range runtimes {
+		actions[rp.Name] = string(rp.Action)
+	}
+	for _, res := range results {
+		action, ok := actions[res.Runtime]
+		if !ok {
+			action = install.ActionDownload
+		}
+		r.AddResult(action, res)
+	}
+}
+
+// Finish sets the duration and outcome of the run.
+func (r *SetupReport) Finish(err error) {
+	r.DurationMs = time.Since(r.StartedAt).Milliseconds()
+	r.Success = err == nil
+	if err != nil {
+		r.ErrorKind = ClassifyError(err)
+	}
+}
+
+// ClassifyError maps an install error to a coarse kind for aggregation.
+// The error text itself is not stored, since it may contain paths or URLs.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	msg := strings.ToLower(err.Error())
+
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return ErrorKindPermission
+	case errors.As(err, &netErr), errors.As(err, &urlErr), strings.Contains(msg, "http "):
+		return ErrorKindNetwork
+	case strings.Contains(msg, "checksum"):
+		return ErrorKindChecksum
+	case strings.Contains(msg, "extract"), strings.Contains(msg, "archive"):
+		return ErrorKindExtract
+	case strings.Contains(msg, "resolve version"):
+		return ErrorKindResolve
+	default:
+		return ErrorKindOther
+	}
+}
+
+// templateKey identifies the template in history, preferring the slug.
+func templateKey(plan *engine.SetupPlan) string {
+	if plan.Manifest.Template.Slug != "" {
+		return plan.Manifest.Template.Slug
+	}
+	return plan.Manifest.Template.Name
+}
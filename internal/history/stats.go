@@ -0,0 +1,82 @@
+package history
+
+import "sort"
+
+// Stats is the aggregate view of local setup history.
+type Stats struct {
+	Runs            int              `json:"runs"`
+	Successes       int              `json:"successes"`
+	Failures        int              `json:"failures"`
+	PerTemplate     map[string]int   `json:"per_template"`
+	BytesDownloaded int64            `json:"bytes_downloaded"`
+	CacheHits       int              `json:"cache_hits"`
+	CacheLookups    int              `json:"cache_lookups"`
+	CacheHitRate    float64          `json:"cache_hit_rate"` // 0..1
+	AvgInstallMs    map[string]int64 `json:"avg_install_ms"` // per runtime, installs only
+	FailuresByKind  map[string]int   `json:"failures_by_kind"`
+	SkippedRecords  int              `json:"skipped_records,omitempty"`
+}
+
+// Aggregate computes stats over the given reports.
+func Aggregate(reports []SetupReport) Stats {
+	s := Stats{
+		PerTemplate:    make(map[string]int),
+		AvgInstallMs:   make(map[string]int64),
+		FailuresByKind: make(map[string]int),
+	}
+
+	installTotals := make(map[string]int64)
+	installCounts := make(map[string]int64)
+
+	for _, r := range reports {
+		s.Runs++
+		s.PerTemplate[r.Template]++
+		s.BytesDownloaded += r.BytesDownloaded
+
+		if r.Success {
+			s.Successes++
+		} else {
+			s.Failures++
+			kind := r.ErrorKind
+			if kind == "" {
+				kind = ErrorKindOther
+			}
+			s.FailuresByKind[kind]++
+		}
+
+		for _, rt := range r.Runtimes {
+			s.CacheLookups++
+			if rt.Cached {
+				s.CacheHits++
+				continue
+			}
+			installTotals[rt.Name] += rt.DurationMs
+			installCounts[rt.Name]++
+		}
+	}
+
+	if s.CacheLookups > 0 {
+		s.CacheHitRate = float64(s.CacheHits) / float64(s.CacheLookups)
+	}
+	for name, total := range installTotals {
+		s.AvgInstallMs[name] = total / installCounts[name]
+	}
+
+	return s
+}
+
+// SortedKeys returns the keys of a count map ordered by count (descending),
+// then name, for stable table output.
+func SortedKeys[V int | int64](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	reports := []SetupReport{
+		{
+			Template: "saas", Success: true, BytesDownloaded: 3000,
+			Runtimes: []RuntimeReport{
+				{Name: "node", Action: "install", DurationMs: 4000, Bytes: 3000},
+				{Name: "python", Action: "skip", Cached: true},
+			},
+		},
+		{
+			Template: "saas", Success: true,
+			Runtimes: []RuntimeReport{
+				{Name: "node", Action: "skip", Cached: true},
+				{Name: "python", Action: "skip", Cached: true},
+			},
+		},
+		{
+			Template: "crm", Success: false, ErrorKind: ErrorKindNetwork, BytesDownloaded: 1000,
+			Runtimes: []RuntimeReport{
+				{Name: "node", Action: "upgrade", DurationMs: 2000, Bytes: 1000},
+			},
+		},
+		{Template: "crm", Success: false},
+	}
+
+	s := Aggregate(reports)
+
+	if s.Runs != 4 || s.Successes != 2 || s.Failures != 2 {
+		t.Errorf("runs/successes/failures = %d/%d/%d, want 4/2/2", s.Runs, s.Successes, s.Failures)
+	}
+	if s.PerTemplate["saas"] != 2 || s.PerTemplate["crm"] != 2 {
+		t.Errorf("PerTemplate = %v", s.PerTemplate)
+	}
+	if s.BytesDownloaded != 4000 {
+		t.Errorf("BytesDownloaded = %d, want 4000", s.BytesDownloaded)
+	}
+	if s.CacheHits != 3 || s.CacheLookups != 5 {
+		t.Errorf("cache = %d/%d, want 3/5", s.CacheHits, s.CacheLookups)
+	}
+	if s.CacheHitRate != 0.6 {
+		t.Errorf("CacheHitRate = %v, want 0.6", s.CacheHitRate)
+	}
+	if s.AvgInstallMs["node"] != 3000 {
+		t.Errorf("AvgInstallMs[node] = %d, want 3000", s.AvgInstallMs["node"])
+	}
+	if _, ok := s.AvgInstallMs["python"]; ok {
+		t.Error("cached-only runtimes should not have an average install time")
+	}
+	if s.FailuresByKind[ErrorKindNetwork] != 1 || s.FailuresByKind[ErrorKindOther] != 1 {
+		t.Errorf("FailuresByKind = %v", s.FailuresByKind)
+	}
+}
+
+func TestAggregate_Empty(t *testing.T) {
+	s := Aggregate(nil)
+	if s.Runs != 0 || s.CacheHitRate != 0 {
+		t.Errorf("unexpected stats for empty history: %+v", s)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	keys := SortedKeys(map[string]int{"b": 2, "a": 2, "c": 5})
+	want := []string{"c", "a", "b"}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("SortedKeys = %v, want %v", keys, want)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("failed to create dir: %w", fs.ErrPermission), ErrorKindPermission},
+		{errors.New("download returned HTTP 403 for https://example.com"), ErrorKindNetwork},
+		{errors.New("checksum mismatch for node.tar.gz"), ErrorKindChecksum},
+		{errors.New("extraction failed: unexpected EOF"), ErrorKindExtract},
+		{errors.New("failed to resolve version for Node.js: no match"), ErrorKindResolve},
+		{errors.New("something else"), ErrorKindOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
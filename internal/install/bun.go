@@ -0,0 +1,142 @@
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// BunInstaller handles Bun installation from the oven-sh/bun GitHub releases.
+type BunInstaller struct{}
+
+func (b *BunInstaller) Name() string { return "bun" }
+
+const (
+	bunReleasesURL = "https://api.github.com/repos/oven-sh/bun/releases?per_page=100"
+	bunDownloadURL = "https://github.com/oven-sh/bun/releases/download"
+)
+
+func (b *BunInstaller) ResolveVersion(requirement string) (string, error) {
+	data, err := FetchGitHubJSON(bunReleasesURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch Bun releases: %w", err)
+	}
+	return resolveBunVersion(data, requirement)
+}
+
+func (b *BunInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	platform, err := bunPlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("bun-%s.zip", platform)
+	downloadURL := fmt.Sprintf("%s/bun-v%s/%s", bunDownloadURL, version, filename)
+	checksumURL := fmt.Sprintf("%s/bun-v%s/SHASUMS256.txt", bunDownloadURL, version)
+
+	// Every platform's zip has the same name, so keep the version in the temp file
+	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("bun-v%s-%s.zip", version, platform))
+	defer os.Remove(tmpFile)
+
+	err = fetchArchive(ctx, b.Name(), downloadURL, tmpFile, progress, func() (string, error) {
+		return FetchChecksumFromURL(checksumURL, filename)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download Bun: %w", err)
+	}
+
+	// Extract and flatten (strips the top-level bun-<platform>/ dir)
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
+		return fmt.Errorf("failed to extract Bun: %w", err)
+	}
+
+	return nil
+}
+
+// BinDir is the install directory itself: the archive holds only the bun binary.
+func (b *BunInstaller) BinDir(installDir string) string {
+	return installDir
+}
+
+func (b *BunInstaller) EnvVars(installDir string) map[string]string { return nil }
+
+// ArchivePattern matches the GitHub release asset for offline installs.
+// Asset names carry no version, so the archive must be the intended one.
+func (b *BunInstaller) ArchivePattern(version string) (string, error) {
+	platform, err := bunPlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("bun-%s.zip", platform), nil
+}
+
+// resolveBunVersion picks the highest stable release satisfying requirement
+// from the GitHub releases JSON. Tags look like "bun-v1.1.30".
+func resolveBunVersion(data []byte, requirement string) (string, error) {
+	var releases []githubRelease
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return "", fmt.Errorf("failed to parse Bun releases: %w", err)
+	}
+
+	var constraint *semver.Constraints
+	if requirement != "latest" {
+		c, err := semver.NewConstraint(requirement)
+		if err != nil {
+			return "", fmt.Errorf("invalid Bun version requirement %q: %w", requirement, err)
+		}
+		constraint = c
+	}
+
+	var best *semver.Version
+	for _, r := range releases {
+		if r.Draft || r.Prerelease || !strings.HasPrefix(r.TagName, "bun-v") {
+			continue
+		}
+		v, err := semver.NewVersion(strings.TrimPrefix(r.TagName, "bun-v"))
+		if err != nil {
+			continue
+		}
+		if constraint != nil && !constraint.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best = v
+		}
+	}
+
+	if best == nil {
+		if requirement == "latest" {
+			return "", fmt.Errorf("no Bun releases found")
+		}
+		return "", fmt.Errorf("no Bun version satisfying %s found", requirement)
+	}
+	return best.Original(), nil
+}
+
+// bunPlatform returns the platform part of Bun's release asset names,
+// e.g. "darwin-aarch64" for bun-darwin-aarch64.zip.
+func bunPlatform(goos, goarch string) (string, error) {
+	var arch string
+	switch goarch {
+	case "amd64":
+		arch = "x64"
+	case "arm64":
+		arch = "aarch64"
+	default:
+		return "", fmt.Errorf("no Bun builds available for %s/%s", goos, goarch)
+	}
+
+	switch {
+	case goos == "darwin" || goos == "linux":
+		return goos + "-" + arch, nil
+	case goos == "windows" && arch == "x64":
+		return "windows-x64", nil
+	default:
+		return "", fmt.Errorf("no Bun builds available for %s/%s", goos, goarch)
+	}
+}
@@ -0,0 +1,117 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CacheDir returns the directory holding verified runtime archives
+// (~/.templatr/cache/), shared across templates.
+func CacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".templatr", "cache"), nil
+}
+
+// cachePath is where an archive with the given name and checksum is cached.
+// The checksum is part of the key, so a re-published file never matches a
+// stale entry.
+func cachePath(filename, sha256 string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strings.ToLower(sha256)+"-"+filepath.Base(filename)), nil
+}
+
+// DownloadWithCache downloads url to destPath like DownloadFile, reusing a
+// cached copy when one matches expectedHash. On a miss (or a corrupt cache
+// entry) the file is downloaded, verified against expectedHash, and added
+// to the cache. With an empty expectedHash nothing can be verified, so the
+// cache is bypassed.
+func DownloadWithCache(ctx context.Context, url, filename, expectedHash, destPath string, progress ProgressFunc) error {
+	if expectedHash == "" {
+		return DownloadFile(ctx, url, destPath, progress)
+	}
+
+	cached, err := cachePath(filename, expectedHash)
+	if err == nil {
+		if VerifyChecksum(cached, expectedHash) == nil {
+			if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", destPath, err)
+			}
+			if err := copyFile(cached, destPath); err != nil {
+				return fmt.Errorf("failed to copy %s from cache: %w", filename, err)
+			}
+			return nil
+		}
+		os.Remove(cached) // missing or corrupt
+	}
+
+	if err := DownloadFile(ctx, url, destPath, progress); err != nil {
+		return err
+	}
+	reportEvent(ctx, ProgressEvent{Phase: PhaseVerifying})
+	if err := VerifyChecksum(destPath, expectedHash); err != nil {
+		return err
+	}
+
+	// Caching is best effort; the download itself already succeeded.
+	if cached != "" {
+		if os.MkdirAll(filepath.Dir(cached), 0o755) == nil {
+			tmp := cached + ".tmp"
+			if copyFile(destPath, tmp) == nil {
+				os.Rename(tmp, cached)
+			}
+			os.Remove(tmp)
+		}
+	}
+	return nil
+}
+
+// CacheUsage returns the number of files in the download cache and their
+// total size. A missing cache directory is empty, not an error.
+func CacheUsage() (files int, bytes int64, err error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return 0, 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() {
+			continue
+		}
+		files++
+		bytes += info.Size()
+	}
+	return files, bytes, nil
+}
+
+// CleanCache deletes the download cache and reports what was removed.
+func CleanCache() (files int, bytes int64, err error) {
+	files, bytes, err = CacheUsage()
+	if err != nil {
+		return 0, 0, err
+	}
+	dir, err := CacheDir()
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, 0, fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+	return files, bytes, nil
+}
@@ -0,0 +1,145 @@
+package install
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+// countingServer serves content and counts the requests it receives.
+func countingServer(t *testing.T, content string) (*httptest.Server, *atomic.Int32, string) {
+	t.Helper()
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(ts.Close)
+	sum := sha256.Sum256([]byte(content))
+	return ts, &hits, hex.EncodeToString(sum[:])
+}
+
+func tempHome(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+}
+
+func TestInstallSingleRuntime_WarmCacheSkipsNetwork(t *testing.T) {
+	tempHome(t)
+
+	ts, hits, sum := countingServer(t, "runtime archive")
+	registerFake(t, &fakeInstaller{url: ts.URL})
+
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall, SHA256: sum}
+
+	if _, err := InstallSingleRuntime(context.Background(), rp, "first-template", logger.New(), nil, ExecuteOptions{}); err != nil {
+		t.Fatalf("first install: %s", err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("first install should download once, got %d requests", got)
+	}
+
+	hits.Store(0)
+	if _, err := InstallSingleRuntime(context.Background(), rp, "second-template", logger.New(), nil, ExecuteOptions{}); err != nil {
+		t.Fatalf("second install: %s", err)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Errorf("second install with a warm cache should make no requests, got %d", got)
+	}
+}
+
+func TestDownloadWithCache_CorruptEntryRefetched(t *testing.T) {
+	tempHome(t)
+
+	ts, hits, sum := countingServer(t, "runtime archive")
+	cached, err := cachePath("runtime.zip", sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(cached), 0o755)
+	os.WriteFile(cached, []byte("truncated"), 0o644)
+
+	dest := filepath.Join(t.TempDir(), "runtime.zip")
+	if err := DownloadWithCache(context.Background(), ts.URL, "runtime.zip", sum, dest, nil); err != nil {
+		t.Fatalf("DownloadWithCache() error: %s", err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("corrupt cache entry should be re-downloaded, got %d requests", got)
+	}
+	if data, _ := os.ReadFile(cached); string(data) != "runtime archive" {
+		t.Errorf("cache entry should be replaced, got %q", data)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "runtime archive" {
+		t.Errorf("dest = %q, want the downloaded archive", data)
+	}
+}
+
+func TestDownloadWithCache_MismatchNotCached(t *testing.T) {
+	tempHome(t)
+
+	ts, _, _ := countingServer(t, "tampered archive")
+	wrong := strings.Repeat("0", 64)
+
+	err := DownloadWithCache(context.Background(), ts.URL, "runtime.zip", wrong, filepath.Join(t.TempDir(), "runtime.zip"), nil)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if files, _, _ := CacheUsage(); files != 0 {
+		t.Errorf("a file failing verification should not be cached, found %d entries", files)
+	}
+}
+
+func TestDownloadWithCache_NoHashBypassesCache(t *testing.T) {
+	tempHome(t)
+
+	ts, hits, _ := countingServer(t, "runtime archive")
+	dest := filepath.Join(t.TempDir(), "runtime.zip")
+	for i := 0; i < 2; i++ {
+		if err := DownloadWithCache(context.Background(), ts.URL, "runtime.zip", "", dest, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("without a checksum every call should download, got %d requests", got)
+	}
+	if files, _, _ := CacheUsage(); files != 0 {
+		t.Errorf("unverified downloads should not be cached, found %d entries", files)
+	}
+}
+
+func TestCleanCache(t *testing.T) {
+	tempHome(t)
+
+	if files, size, err := CleanCache(); err != nil || files != 0 || size != 0 {
+		t.Fatalf("CleanCache() on a missing cache = %d, %d, %v; want 0, 0, nil", files, size, err)
+	}
+
+	ts, _, sum := countingServer(t, "runtime archive")
+	if err := DownloadWithCache(context.Background(), ts.URL, "runtime.zip", sum, filepath.Join(t.TempDir(), "runtime.zip"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	files, size, err := CleanCache()
+	if err != nil {
+		t.Fatalf("CleanCache() error: %s", err)
+	}
+	if files != 1 || size != int64(len("runtime archive")) {
+		t.Errorf("CleanCache() = %d files, %d bytes; want 1, %d", files, size, len("runtime archive"))
+	}
+	dir, _ := CacheDir()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("cache directory should be removed")
+	}
+}
@@ -0,0 +1,60 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	pinnedMu sync.Mutex
+	pinned   = map[string]string{} // runtime -> archive sha256 from [runtimes_checksums]
+)
+
+// pinChecksum sets the checksum the named runtime's archive must match, as
+// declared in the manifest for this platform. An empty sum clears it.
+func pinChecksum(runtimeName, sum string) {
+	pinnedMu.Lock()
+	defer pinnedMu.Unlock()
+	if sum == "" {
+		delete(pinned, runtimeName)
+		return
+	}
+	pinned[runtimeName] = sum
+}
+
+func pinnedChecksum(runtimeName string) string {
+	pinnedMu.Lock()
+	defer pinnedMu.Unlock()
+	return pinned[runtimeName]
+}
+
+// fetchArchive downloads a runtime archive to archivePath through the
+// download cache, verifying it before it is extracted. A checksum pinned in
+// the manifest takes precedence and upstream is not consulted at all;
+// otherwise upstream supplies the expected hash, where an empty result skips
+// verification (and caching), with a warning, for sources that publish none.
+func fetchArchive(ctx context.Context, runtimeName, url, archivePath string, progress ProgressFunc, upstream func() (string, error)) error {
+	filename := filepath.Base(archivePath)
+
+	if expected := pinnedChecksum(runtimeName); expected != "" {
+		err := DownloadWithCache(ctx, url, filename, expected, archivePath, progress)
+		if errors.Is(err, ErrChecksumMismatch) {
+			return fmt.Errorf("pinned in [runtimes_checksums]: %w", err)
+		}
+		return err
+	}
+
+	expected, err := upstream()
+	if err != nil {
+		return err
+	}
+	if expected == "" {
+		if log := currentInstallLogger(); log != nil {
+			log.Warn("No checksum published for %s; it will not be verified - pin one in [runtimes_checksums] to verify it", filename)
+		}
+	}
+	return DownloadWithCache(ctx, url, filename, expected, archivePath, progress)
+}
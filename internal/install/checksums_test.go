@@ -0,0 +1,130 @@
+package install
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+// fakeInstaller fetches one archive from url and verifies it like the real
+// installers do, trusting upstreamHash when nothing is pinned.
+type fakeInstaller struct {
+	url          string
+	upstreamHash string
+}
+
+func (f *fakeInstaller) Name() string { return "fake" }
+
+func (f *fakeInstaller) ResolveVersion(requirement string) (string, error) { return "1.0.0", nil }
+
+func (f *fakeInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	tmpFile := filepath.Join(os.TempDir(), "fake-runtime-"+version+".zip")
+	defer os.Remove(tmpFile)
+
+	if err := fetchArchive(ctx, f.Name(), f.url, tmpFile, progress, func() (string, error) { return f.upstreamHash, nil }); err != nil {
+		return err
+	}
+	return os.MkdirAll(targetDir, 0o755)
+}
+
+func (f *fakeInstaller) BinDir(installDir string) string { return installDir }
+
+func (f *fakeInstaller) EnvVars(installDir string) map[string]string { return nil }
+
+func archiveServer(t *testing.T, content string) (*httptest.Server, string) {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(ts.Close)
+	sum := sha256.Sum256([]byte(content))
+	return ts, hex.EncodeToString(sum[:])
+}
+
+func registerFake(t *testing.T, f Installer) {
+	t.Helper()
+	Register(f)
+	t.Cleanup(func() {
+		delete(registry, f.Name())
+		pinChecksum(f.Name(), "")
+	})
+}
+
+func TestInstallSingleRuntime_PinnedChecksumMismatch(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	ts, actual := archiveServer(t, "runtime archive")
+	registerFake(t, &fakeInstaller{url: ts.URL, upstreamHash: actual})
+
+	wrong := strings.Repeat("0", 64)
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall, SHA256: wrong}
+
+	_, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), nil, ExecuteOptions{})
+	if err == nil {
+		t.Fatal("expected install to abort on a pinned checksum mismatch")
+	}
+	for _, want := range []string{"[runtimes_checksums]", "expected " + wrong, "got " + actual} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %q", err, want)
+		}
+	}
+
+	runtimesDir, _ := RuntimesDir()
+	if _, err := os.Stat(filepath.Join(runtimesDir, "fake", "1.0.0")); !os.IsNotExist(err) {
+		t.Error("runtime should not be installed after a checksum mismatch")
+	}
+}
+
+func TestFetchArchive_PinnedTakesPrecedence(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	ts, actual := archiveServer(t, "runtime archive")
+	archive := filepath.Join(t.TempDir(), "runtime.zip")
+
+	pinChecksum("fake", actual)
+	t.Cleanup(func() { pinChecksum("fake", "") })
+
+	upstreamCalled := false
+	err := fetchArchive(context.Background(), "fake", ts.URL, archive, nil, func() (string, error) {
+		upstreamCalled = true
+		return strings.Repeat("f", 64), nil
+	})
+	if err != nil {
+		t.Errorf("pinned checksum should be used instead of upstream, got %s", err)
+	}
+	if upstreamCalled {
+		t.Error("upstream checksum should not be fetched when one is pinned")
+	}
+
+	pinChecksum("fake", "")
+	if err := fetchArchive(context.Background(), "fake", ts.URL, archive, nil, func() (string, error) { return strings.Repeat("f", 64), nil }); err == nil {
+		t.Error("expected upstream mismatch once the pin is cleared")
+	}
+	log := logger.New()
+	log.SetLevel(logger.ERROR) // keep the warning out of test output
+	if err := log.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	setInstallLogger(log)
+	t.Cleanup(func() { setInstallLogger(nil) })
+	if err := fetchArchive(context.Background(), "fake", ts.URL, archive, nil, func() (string, error) { return "", nil }); err != nil {
+		t.Errorf("no checksum from either source should skip verification, got %s", err)
+	}
+	if data, _ := os.ReadFile(log.FilePath()); !strings.Contains(string(data), "runtime.zip; it will not be verified") {
+		t.Errorf("an unverified archive should be warned about, log:\n%s", data)
+	}
+}
@@ -0,0 +1,274 @@
+package install
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// RuntimeVersion is one installed version of a runtime under RuntimesDir().
+type RuntimeVersion struct {
+	Runtime string
+	Version string
+	Path    string
+	Size    int64 // bytes on disk
+	Tracked bool  // recorded in state.json
+	Active  bool  // the current link or a PATH entry points at it
+}
+
+// ListRuntimeVersions returns every version directory under RuntimesDir(),
+// plus any recorded in st that still exist, sorted by runtime and then
+// newest version first.
+func ListRuntimeVersions(st *state.State) ([]RuntimeVersion, error) {
+	base, err := RuntimesDir()
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]*RuntimeVersion{}
+	runtimeDirs, err := os.ReadDir(base)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", base, err)
+	}
+	for _, rd := range runtimeDirs {
+		if !rd.IsDir() {
+			continue
+		}
+		versionDirs, err := os.ReadDir(filepath.Join(base, rd.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(base, rd.Name()), err)
+		}
+		for _, vd := range versionDirs {
+			// Skip the current link, which is a directory on Windows
+			if !vd.IsDir() || vd.Name() == currentLinkName || strings.HasSuffix(vd.Name(), ".tmp") {
+				continue
+			}
+			path := filepath.Join(base, rd.Name(), vd.Name())
+			found[path] = &RuntimeVersion{Runtime: rd.Name(), Version: vd.Name(), Path: path}
+		}
+	}
+
+	for _, inst := range st.Installations {
+		if inst.Action == ActionDownload {
+			continue
+		}
+		if v, ok := found[inst.Path]; ok {
+			v.Tracked = true
+			continue
+		}
+		if info, err := os.Stat(inst.Path); err == nil && info.IsDir() {
+			found[inst.Path] = &RuntimeVersion{Runtime: inst.Runtime, Version: inst.Version, Path: inst.Path, Tracked: true}
+		}
+	}
+
+	versions := make([]RuntimeVersion, 0, len(found))
+	for _, v := range found {
+		v.Active = isActive(st, *v)
+		v.Size = dirSize(v.Path)
+		versions = append(versions, *v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Runtime != versions[j].Runtime {
+			return versions[i].Runtime < versions[j].Runtime
+		}
+		return newerVersion(versions[i].Version, versions[j].Version)
+	})
+	return versions, nil
+}
+
+// PrunableVersions returns the versions in versions (as sorted by
+// ListRuntimeVersions) beyond the newest keep of each runtime.
+func PrunableVersions(versions []RuntimeVersion, keep int) []RuntimeVersion {
+	var prune []RuntimeVersion
+	seen := map[string]int{}
+	for _, v := range versions {
+		seen[v.Runtime]++
+		if seen[v.Runtime] > keep {
+			prune = append(prune, v)
+		}
+	}
+	return prune
+}
+
+// RemoveRuntimeVersions deletes the given versions and forgets them in st.
+// A runtime whose current link pointed at a removed version is repointed at
+// its newest remaining one. PATH entries and env vars pointing into a
+// removed version are reverted, and if that leaves the runtime off PATH,
+// its current/bin is added instead. It returns the bytes freed.
+func RemoveRuntimeVersions(st *state.State, remove []RuntimeVersion, log *logger.Logger) (int64, error) {
+	removed := map[string]bool{}
+	var freed int64
+	for _, v := range remove {
+		if err := os.RemoveAll(v.Path); err != nil {
+			return freed, fmt.Errorf("failed to remove %s: %w", v.Path, err)
+		}
+		if v.Tracked {
+			st.RemoveInstallation(v.Runtime, v.Version)
+		}
+		removed[v.Path] = true
+		freed += v.Size
+		log.Info("Removed %s %s (%s)", v.Runtime, v.Version, v.Path)
+	}
+
+	remaining, err := ListRuntimeVersions(st)
+	if err != nil {
+		return freed, err
+	}
+	newest := map[string]string{}
+	for _, v := range remaining {
+		if _, ok := newest[v.Runtime]; !ok {
+			newest[v.Runtime] = v.Path
+		}
+	}
+
+	offPath := map[string]bool{}
+	for _, mod := range append([]state.PathModification(nil), st.PathModifications...) {
+		if !inRemoved(mod.Value, removed) {
+			continue
+		}
+		if err := RemoveFromPath(mod); err != nil {
+			log.Warn("Could not remove PATH entry %s: %s", mod.Value, err)
+			continue
+		}
+		st.RemovePathModification(mod.Value)
+		if rt, _ := versionedRuntime(mod.Value); rt != "" {
+			offPath[rt] = true
+		}
+	}
+	for _, env := range append([]state.EnvModification(nil), st.EnvModifications...) {
+		if !inRemoved(env.Value, removed) {
+			continue
+		}
+		if err := RemoveEnvVar(env); err != nil {
+			log.Warn("Could not remove %s: %s", env.Name, err)
+			continue
+		}
+		st.RemoveEnvModification(env.Name)
+	}
+
+	done := map[string]bool{}
+	for _, v := range remove {
+		if done[v.Runtime] {
+			continue
+		}
+		done[v.Runtime] = true
+
+		link := st.GetLink(v.Runtime)
+		stale := link != nil && removed[link.Target]
+		if !stale && !offPath[v.Runtime] {
+			continue
+		}
+		next := newest[v.Runtime]
+		installer := GetInstaller(v.Runtime)
+		if next == "" || installer == nil {
+			if link != nil && stale {
+				if err := RemoveCurrent(link.Path); err != nil {
+					log.Warn("%s", err)
+				}
+				st.RemoveLink(v.Runtime)
+			}
+			continue
+		}
+
+		if offPath[v.Runtime] && !onPath(st, v.Runtime) {
+			activateRuntime(installer, engine.RuntimePlan{Name: v.Runtime, DisplayName: v.Runtime}, next, st, log, false)
+			continue
+		}
+		path, err := LinkCurrent(next)
+		if err != nil {
+			log.Warn("Could not repoint %s: %s", link.Path, err)
+			continue
+		}
+		st.SetLink(state.RuntimeLink{Runtime: v.Runtime, Path: path, Target: next})
+		log.Info("Pointed %s at %s", path, filepath.Base(next))
+	}
+
+	return freed, nil
+}
+
+// isActive reports whether the runtime's current link, a PATH entry or an
+// env var points at v.
+func isActive(st *state.State, v RuntimeVersion) bool {
+	if link := st.GetLink(v.Runtime); link != nil && link.Target == v.Path {
+		return true
+	}
+	for _, mod := range st.PathModifications {
+		if within(mod.Value, v.Path) {
+			return true
+		}
+	}
+	for _, env := range st.EnvModifications {
+		if within(env.Value, v.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// onPath reports whether st still has a PATH entry for the runtime.
+func onPath(st *state.State, runtimeName string) bool {
+	base, err := RuntimesDir()
+	if err != nil {
+		return false
+	}
+	for _, mod := range st.PathModifications {
+		if within(mod.Value, filepath.Join(base, runtimeName)) {
+			return true
+		}
+	}
+	return false
+}
+
+func inRemoved(path string, removed map[string]bool) bool {
+	for dir := range removed {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// newerVersion orders version directory names newest first, falling back
+// to a plain string comparison for names that aren't versions.
+func newerVersion(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA == nil && errB == nil {
+		return va.GreaterThan(vb)
+	}
+	if errA == nil || errB == nil {
+		return errA == nil
+	}
+	return a > b
+}
+
+// dirSize returns the total size of the files below dir, without following
+// symlinks. Unreadable entries are skipped.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
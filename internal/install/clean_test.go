@@ -0,0 +1,173 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// fakeRuntimesTree creates version dirs with a bin/ and a 1 KB file each
+// and records the tracked ones in a new state.
+func fakeRuntimesTree(t *testing.T, tracked map[string][]string, untracked map[string][]string) *state.State {
+	t.Helper()
+	base, err := RuntimesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := state.NewState()
+	create := func(rt, v string) string {
+		dir := filepath.Join(base, rt, v)
+		os.MkdirAll(filepath.Join(dir, "bin"), 0o755)
+		os.WriteFile(filepath.Join(dir, "bin", rt), make([]byte, 1024), 0o755)
+		return dir
+	}
+	for rt, versions := range tracked {
+		for _, v := range versions {
+			st.AddInstallation(state.Installation{Runtime: rt, Version: v, Path: create(rt, v), Action: "install"})
+		}
+	}
+	for rt, versions := range untracked {
+		for _, v := range versions {
+			create(rt, v)
+		}
+	}
+	return st
+}
+
+func quietLogger() *logger.Logger {
+	log := logger.New()
+	log.SetLevel(logger.ERROR)
+	return log
+}
+
+func TestListRuntimeVersions(t *testing.T) {
+	preflightHome(t)
+	st := fakeRuntimesTree(t,
+		map[string][]string{"node": {"20.11.0", "22.14.0"}},
+		map[string][]string{"node": {"9.11.2"}, "flutter": {"3.24.5"}},
+	)
+	base, _ := RuntimesDir()
+	if _, err := LinkCurrent(filepath.Join(base, "node", "22.14.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := ListRuntimeVersions(st)
+	if err != nil {
+		t.Fatalf("ListRuntimeVersions() error: %s", err)
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Runtime+"@"+v.Version)
+		if v.Size != 1024 {
+			t.Errorf("%s %s: Size = %d, want 1024", v.Runtime, v.Version, v.Size)
+		}
+	}
+	want := "flutter@3.24.5 node@22.14.0 node@20.11.0 node@9.11.2"
+	if strings.Join(got, " ") != want {
+		t.Errorf("versions = %v, want %s (newest first, without the current link)", got, want)
+	}
+	if versions[0].Tracked || !versions[1].Tracked || versions[3].Tracked {
+		t.Errorf("Tracked should reflect state.json: %+v", versions)
+	}
+}
+
+func TestPrunableVersions(t *testing.T) {
+	versions := []RuntimeVersion{
+		{Runtime: "flutter", Version: "3.24.5"},
+		{Runtime: "node", Version: "22.14.0"},
+		{Runtime: "node", Version: "20.11.0"},
+		{Runtime: "node", Version: "18.19.0"},
+	}
+	tests := []struct {
+		keep int
+		want string
+	}{
+		{1, "20.11.0 18.19.0"},
+		{2, "18.19.0"},
+		{3, ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range PrunableVersions(versions, tt.keep) {
+			got = append(got, v.Version)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("keep %d: pruned %v, want %q", tt.keep, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveRuntimeVersions(t *testing.T) {
+	skipOnWindows(t)
+	home := preflightHome(t)
+	st := fakeRuntimesTree(t,
+		map[string][]string{"node": {"20.11.0", "22.14.0"}, "go": {"1.22.5"}},
+		map[string][]string{"node": {"18.19.0"}},
+	)
+	base, _ := RuntimesDir()
+	old := filepath.Join(base, "node", "20.11.0")
+
+	// An older release put the versioned bin dir on PATH; current points at it
+	mod, _, err := AddToPath(filepath.Join(old, "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.AddPathModification(*mod)
+	link, err := LinkCurrent(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SetLink(state.RuntimeLink{Runtime: "node", Path: link, Target: old})
+
+	versions, err := ListRuntimeVersions(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prune := PrunableVersions(versions, 1)
+	freed, err := RemoveRuntimeVersions(st, prune, quietLogger())
+	if err != nil {
+		t.Fatalf("RemoveRuntimeVersions() error: %s", err)
+	}
+	if freed != 2048 {
+		t.Errorf("freed %d bytes, want 2048", freed)
+	}
+
+	for _, v := range []string{"20.11.0", "18.19.0"} {
+		if _, err := os.Stat(filepath.Join(base, "node", v)); !os.IsNotExist(err) {
+			t.Errorf("node %s should be removed", v)
+		}
+	}
+	for _, dir := range []string{filepath.Join(base, "node", "22.14.0"), filepath.Join(base, "go", "1.22.5")} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s should be kept", dir)
+		}
+	}
+
+	if len(st.GetInstallations("node")) != 1 || st.GetInstallations("node")[0].Version != "22.14.0" {
+		t.Errorf("state should only keep node 22.14.0, got %+v", st.GetInstallations("node"))
+	}
+	if l := st.GetLink("node"); l == nil || l.Target != filepath.Join(base, "node", "22.14.0") {
+		t.Errorf("current link should move to 22.14.0, got %+v", l)
+	}
+	if target, _ := os.Readlink(link); target != "22.14.0" {
+		t.Errorf("current -> %q, want 22.14.0", target)
+	}
+
+	rc, _ := os.ReadFile(filepath.Join(home, ".bashrc"))
+	if strings.Contains(string(rc), "20.11.0") {
+		t.Errorf("PATH entry for the removed version should be gone:\n%s", rc)
+	}
+	currentBin := GetInstaller("node").BinDir(link)
+	if !strings.Contains(string(rc), currentBin) {
+		t.Errorf("node should stay on PATH through %s:\n%s", currentBin, rc)
+	}
+	for _, m := range st.PathModifications {
+		if strings.Contains(m.Value, "20.11.0") {
+			t.Errorf("state still records %s", m.Value)
+		}
+	}
+}
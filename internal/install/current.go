@@ -0,0 +1,333 @@
+package install
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// currentLinkName is the per-runtime link to the active version, e.g.
+// ~/.templatr/runtimes/node/current -> 22.14.0.
+const currentLinkName = "current"
+
+// LinkCurrent creates or repoints the "current" link next to installDir
+// (a version directory such as ~/.templatr/runtimes/node/22.14.0) so it
+// points at installDir, and returns the link's path. On Windows the link is
+// a directory junction, which needs no special privileges.
+func LinkCurrent(installDir string) (string, error) {
+	link := filepath.Join(filepath.Dir(installDir), currentLinkName)
+	if runtime.GOOS == "windows" {
+		return link, linkJunction(installDir, link)
+	}
+
+	// Build the new link beside the old one and rename it over, so the
+	// runtime never drops off PATH mid-upgrade. The target is relative so
+	// the link survives ~/.templatr being moved.
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(installDir), tmp); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", link, err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to update %s: %w", link, err)
+	}
+	return link, nil
+}
+
+// linkJunction replaces link with a directory junction to target.
+func linkJunction(target, link string) error {
+	// os.Remove deletes the junction itself, never what it points at
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", link, err)
+	}
+	out, err := exec.Command("cmd", "/c", "mklink", "/J", link, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mklink /J %s failed: %s: %w", link, strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// RemoveCurrent deletes a runtime's "current" link. A real directory at
+// that path is left alone.
+func RemoveCurrent(link string) error {
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", link, err)
+	}
+	return nil
+}
+
+// activateRuntime points the runtime's current link at targetDir, then adds
+// the link's bin dir to PATH and sets the runtime's env vars through the
+// link, recording all of it in st. After the first install those are
+// already in place, so upgrades leave shell config files alone. If the link
+// can't be created, targetDir is used directly as before. With noPath only
+// this process's environment is changed, and the changes come back as
+// Manual for the user to make.
+func activateRuntime(installer Installer, rp engine.RuntimePlan, targetDir string, st *state.State, log *logger.Logger, noPath bool) (string, []EnvChange) {
+	activeDir := targetDir
+	if link, err := LinkCurrent(targetDir); err != nil {
+		log.Warn("Could not link %s as the current %s: %s", targetDir, rp.DisplayName, err)
+	} else {
+		log.Info("Pointed %s at %s", link, filepath.Base(targetDir))
+		st.SetLink(state.RuntimeLink{Runtime: rp.Name, Path: link, Target: targetDir})
+		activeDir = link
+	}
+
+	binDir := installer.BinDir(activeDir)
+	if noPath {
+		return binDir, manualChanges(binDir, installer.EnvVars(activeDir), log)
+	}
+	log.Info("Adding %s to PATH...", binDir)
+
+	pathEntry, envChanges, err := AddToPath(binDir)
+	if err != nil {
+		log.Warn("Failed to add %s to PATH: %s", binDir, err)
+		log.Warn("You may need to manually add %s to your PATH", binDir)
+	} else if pathEntry != nil {
+		recordPathEntry(st, *pathEntry)
+	}
+
+	// Set runtime-specific env vars (e.g., JAVA_HOME, GOROOT)
+	for envName, envValue := range installer.EnvVars(activeDir) {
+		log.Info("Setting %s=%s", envName, envValue)
+		envEntry, changes, err := SetEnvVar(envName, envValue)
+		if err != nil {
+			log.Warn("Failed to set %s: %s", envName, err)
+		} else if envEntry != nil {
+			st.AddEnvModification(*envEntry)
+		}
+		envChanges = append(envChanges, changes...)
+	}
+
+	return binDir, envChanges
+}
+
+// relinkRuntime points rp's current link at the version installed earlier
+// in rp.Relink and puts it on PATH, rather than downloading it again. The
+// template is recorded as using it, so uninstalling the one that installed
+// it keeps it.
+func relinkRuntime(installer Installer, rp engine.RuntimePlan, templateSlug string, log *logger.Logger, events EventFunc, opts ExecuteOptions) (*InstallResult, error) {
+	start := time.Now()
+	version := filepath.Base(rp.Relink)
+	log.Info("Pointing %s at %s, installed earlier", rp.DisplayName, rp.Relink)
+	events.emit(ProgressEvent{Phase: PhaseLinking})
+
+	var binDir string
+	var envChanges []EnvChange
+	err := state.WithLock(func(st *state.State) error {
+		for _, inst := range st.GetInstallations(rp.Name) {
+			if inst.Path == rp.Relink {
+				version = inst.Version
+			}
+		}
+		binDir, envChanges = activateRuntime(installer, rp, rp.Relink, st, log, opts.NoPath)
+		if templateSlug != "" {
+			st.AddUse(rp.Name, rp.Relink, templateSlug)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record %s %s: %w", rp.DisplayName, version, err)
+	}
+
+	log.Info("%s %s is now current", rp.DisplayName, version)
+	return &InstallResult{
+		Runtime:     rp.Name,
+		Version:     version,
+		InstallPath: rp.Relink,
+		BinDir:      binDir,
+		Duration:    time.Since(start),
+		EnvChanges:  envChanges,
+	}, nil
+}
+
+// manualChanges sets binDir and envVars for this process only, so the rest
+// of the setup can run the runtime, and returns them as Manual changes,
+// logging the lines to add for the user's shell.
+func manualChanges(binDir string, envVars map[string]string, log *logger.Logger) []EnvChange {
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	changes := []EnvChange{{Kind: ChangePath, Name: "PATH", Value: binDir, Manual: true}}
+	for _, name := range slices.Sorted(maps.Keys(envVars)) {
+		os.Setenv(name, envVars[name])
+		changes = append(changes, EnvChange{Kind: ChangeEnv, Name: name, Value: envVars[name], Manual: true})
+	}
+
+	shell := detectShell(runtime.GOOS, os.Getenv("SHELL"))
+	log.Info("Left PATH unchanged (--no-path); add these lines to %s:", manualFile(shell))
+	for _, line := range manualLines(shell, changes) {
+		log.Info("  %s", line)
+	}
+	return changes
+}
+
+// StalePathEntries returns the PATH entries in st that point into a
+// versioned runtime directory rather than its current link. Earlier
+// releases added one of these per installed version.
+func StalePathEntries(st *state.State) []state.PathModification {
+	var stale []state.PathModification
+	for _, mod := range st.PathModifications {
+		if rt, _ := versionedRuntime(mod.Value); rt != "" {
+			stale = append(stale, mod)
+		}
+	}
+	return stale
+}
+
+// versionedRuntime returns the runtime and version of a path inside
+// ~/.templatr/runtimes/<runtime>/<version>/, or empty strings.
+func versionedRuntime(path string) (runtimeName, version string) {
+	base, err := RuntimesDir()
+	if err != nil {
+		return "", ""
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return "", ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || parts[0] == ".." || parts[1] == currentLinkName {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// managedRuntime returns the runtime a path inside
+// ~/.templatr/runtimes/<runtime>/ belongs to, whether through a version
+// directory or the current link, or an empty string.
+func managedRuntime(path string) string {
+	base, err := RuntimesDir()
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || parts[0] == ".." {
+		return ""
+	}
+	return parts[0]
+}
+
+// recordPathEntry records a PATH change in st. A shell config block for the
+// same runtime is rewritten in place by AddToPath, so the entries it
+// replaced are forgotten.
+func recordPathEntry(st *state.State, entry state.PathModification) {
+	rt := managedRuntime(entry.Value)
+	for _, mod := range append([]state.PathModification(nil), st.PathModifications...) {
+		if mod.Value == entry.Value || (rt != "" && mod.Method != "windows_env" && managedRuntime(mod.Value) == rt) {
+			st.RemovePathModification(mod.Value)
+		}
+	}
+	st.AddPathModification(entry)
+}
+
+// ConsolidatePath replaces stale versioned PATH entries with a single
+// current/bin entry per runtime. Each runtime's current link is created for
+// its most recently installed version if it has none; env vars pointing at
+// a version directory move to the link too. It returns the bin dirs left
+// on PATH.
+func ConsolidatePath(st *state.State, log *logger.Logger) ([]string, error) {
+	stale := StalePathEntries(st)
+	if len(stale) == 0 {
+		return nil, nil
+	}
+
+	var kept []string
+	done := map[string]bool{}
+	for _, mod := range stale {
+		rt, _ := versionedRuntime(mod.Value)
+		if done[rt] {
+			continue
+		}
+		done[rt] = true
+
+		installer := GetInstaller(rt)
+		link := st.GetLink(rt)
+		if installer == nil {
+			continue
+		}
+		if link == nil {
+			latest := latestInstallation(st, rt)
+			if latest == "" {
+				continue // nothing on disk to link; leave the entry alone
+			}
+			path, err := LinkCurrent(latest)
+			if err != nil {
+				return kept, err
+			}
+			st.SetLink(state.RuntimeLink{Runtime: rt, Path: path, Target: latest})
+			link = st.GetLink(rt)
+		}
+
+		binDir := installer.BinDir(link.Path)
+		pathEntry, _, err := AddToPath(binDir)
+		if err != nil {
+			return kept, fmt.Errorf("failed to add %s to PATH: %w", binDir, err)
+		}
+		if pathEntry != nil {
+			recordPathEntry(st, *pathEntry)
+		}
+		kept = append(kept, binDir)
+
+		for _, env := range append([]state.EnvModification(nil), st.EnvModifications...) {
+			if envRT, _ := versionedRuntime(env.Value); envRT != rt {
+				continue
+			}
+			if err := RemoveEnvVar(env); err != nil {
+				log.Warn("Could not remove %s: %s", env.Name, err)
+				continue
+			}
+			st.RemoveEnvModification(env.Name)
+			value := installer.EnvVars(link.Path)[env.Name]
+			if value == "" {
+				continue
+			}
+			if entry, _, err := SetEnvVar(env.Name, value); err != nil {
+				log.Warn("Failed to set %s: %s", env.Name, err)
+			} else if entry != nil {
+				st.AddEnvModification(*entry)
+			}
+		}
+	}
+
+	for _, mod := range stale {
+		if rt, _ := versionedRuntime(mod.Value); st.GetLink(rt) == nil {
+			continue
+		}
+		if err := RemoveFromPath(mod); err != nil {
+			log.Warn("Could not remove PATH entry %s: %s", mod.Value, err)
+			continue
+		}
+		st.RemovePathModification(mod.Value)
+		log.Info("Removed stale PATH entry %s", mod.Value)
+	}
+	return kept, nil
+}
+
+// latestInstallation returns the path of the most recently recorded
+// installation of a runtime that still exists on disk.
+func latestInstallation(st *state.State, runtimeName string) string {
+	insts := st.GetInstallations(runtimeName)
+	for i := len(insts) - 1; i >= 0; i-- {
+		if rt, _ := versionedRuntime(insts[i].Path); rt != runtimeName {
+			continue
+		}
+		if info, err := os.Stat(insts[i].Path); err == nil && info.IsDir() {
+			return insts[i].Path
+		}
+	}
+	return ""
+}
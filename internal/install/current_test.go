@@ -0,0 +1,255 @@
+package install
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("current links are junctions on Windows")
+	}
+}
+
+// versionFake installs whatever version it is told to, without downloading.
+type versionFake struct {
+	fakeInstaller
+	version string
+}
+
+func (f *versionFake) ResolveVersion(requirement string) (string, error) { return f.version, nil }
+
+func (f *versionFake) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	return os.MkdirAll(filepath.Join(targetDir, "bin"), 0o755)
+}
+
+func (f *versionFake) BinDir(installDir string) string { return filepath.Join(installDir, "bin") }
+
+func TestLinkCurrent(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	v1 := filepath.Join(dir, "node", "20.11.0")
+	v2 := filepath.Join(dir, "node", "22.14.0")
+	os.MkdirAll(v1, 0o755)
+	os.MkdirAll(v2, 0o755)
+
+	link, err := LinkCurrent(v1)
+	if err != nil {
+		t.Fatalf("LinkCurrent() error: %s", err)
+	}
+	if link != filepath.Join(dir, "node", "current") {
+		t.Errorf("link = %s, want node/current", link)
+	}
+	if _, err := LinkCurrent(v2); err != nil {
+		t.Fatalf("repointing failed: %s", err)
+	}
+	if target, _ := os.Readlink(link); target != "22.14.0" {
+		t.Errorf("current -> %q, want a relative link to 22.14.0", target)
+	}
+
+	if err := RemoveCurrent(link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(v2); err != nil {
+		t.Error("removing the link must not remove the version it points at")
+	}
+}
+
+func TestInstallSingleRuntime_UpgradeRepointsCurrent(t *testing.T) {
+	skipOnWindows(t)
+	home := preflightHome(t)
+
+	fake := &versionFake{version: "1.0.0"}
+	registerFake(t, fake)
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall}
+
+	first, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), nil, ExecuteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.version = "2.0.0"
+	rp.Action = engine.ActionUpgrade
+	second, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), nil, ExecuteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runtimes, _ := RuntimesDir()
+	currentBin := filepath.Join(runtimes, "fake", "current", "bin")
+	if first.BinDir != currentBin || second.BinDir != currentBin {
+		t.Errorf("BinDir = %s, %s; want %s for both", first.BinDir, second.BinDir, currentBin)
+	}
+	if target, _ := os.Readlink(filepath.Join(runtimes, "fake", "current")); target != "2.0.0" {
+		t.Errorf("current -> %q, want 2.0.0", target)
+	}
+
+	rc, _ := os.ReadFile(filepath.Join(home, ".bashrc"))
+	if n := strings.Count(string(rc), "export PATH="); n != 1 {
+		t.Errorf(".bashrc should get one PATH line across upgrades, got %d:\n%s", n, rc)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := st.GetLink("fake")
+	if link == nil || link.Target != second.InstallPath {
+		t.Errorf("state link = %+v, want target %s", link, second.InstallPath)
+	}
+	if len(st.PathModifications) != 1 {
+		t.Errorf("expected one recorded PATH entry, got %d", len(st.PathModifications))
+	}
+}
+
+func TestInstallSingleRuntime_Relink(t *testing.T) {
+	skipOnWindows(t)
+	preflightHome(t)
+
+	fake := &versionFake{version: "1.0.0"}
+	registerFake(t, fake)
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall}
+	first, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), nil, ExecuteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.version = "2.0.0"
+	if _, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), nil, ExecuteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Back to 1.0.0 without downloading it again
+	fake.version = "3.0.0"
+	rp.Relink = first.InstallPath
+	result, err := InstallSingleRuntime(context.Background(), rp, "other-template", logger.New(), nil, ExecuteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "1.0.0" || result.InstallPath != first.InstallPath {
+		t.Errorf("result = %+v, want 1.0.0 in %s", result, first.InstallPath)
+	}
+	runtimes, _ := RuntimesDir()
+	if _, err := os.Stat(filepath.Join(runtimes, "fake", "3.0.0")); !os.IsNotExist(err) {
+		t.Error("relinking installed a new version")
+	}
+	if target, _ := os.Readlink(filepath.Join(runtimes, "fake", "current")); target != "1.0.0" {
+		t.Errorf("current -> %q, want 1.0.0", target)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Installations) != 2 {
+		t.Errorf("installations = %+v, want the two recorded before", st.Installations)
+	}
+	if inst := st.Installations[0]; !slices.Contains(inst.UsedBy, "other-template") {
+		t.Errorf("1.0.0 = %+v, want it recorded as used by other-template", inst)
+	}
+}
+
+// envFake is a versionFake that sets FAKE_HOME, like JAVA_HOME.
+type envFake struct {
+	versionFake
+}
+
+func (f *envFake) EnvVars(installDir string) map[string]string {
+	return map[string]string{"FAKE_HOME": installDir}
+}
+
+func TestInstallSingleRuntime_NoPath(t *testing.T) {
+	skipOnWindows(t)
+	home := preflightHome(t)
+	t.Setenv("PATH", os.Getenv("PATH"))
+	t.Setenv("FAKE_HOME", "")
+
+	registerFake(t, &envFake{versionFake{version: "1.0.0"}})
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall}
+
+	result, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), nil, ExecuteOptions{NoPath: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, ".bashrc")); !os.IsNotExist(err) {
+		t.Error("--no-path must not write shell config files")
+	}
+	runtimes, _ := RuntimesDir()
+	current := filepath.Join(runtimes, "fake", "current")
+	want := []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: filepath.Join(current, "bin"), Manual: true},
+		{Kind: ChangeEnv, Name: "FAKE_HOME", Value: current, Manual: true},
+	}
+	if len(result.EnvChanges) != len(want) || result.EnvChanges[0] != want[0] || result.EnvChanges[1] != want[1] {
+		t.Errorf("EnvChanges = %+v, want %+v", result.EnvChanges, want)
+	}
+	if !strings.HasPrefix(os.Getenv("PATH"), filepath.Join(current, "bin")) || os.Getenv("FAKE_HOME") != current {
+		t.Error("the runtime should still be on this process's PATH for the rest of the setup")
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.PathModifications) != 0 || len(st.EnvModifications) != 0 {
+		t.Errorf("no PATH or env modification should be recorded, got %+v and %+v", st.PathModifications, st.EnvModifications)
+	}
+	if len(st.Installations) != 1 || !st.Installations[0].NoPath {
+		t.Errorf("installation should be recorded with NoPath, got %+v", st.Installations)
+	}
+}
+
+func TestConsolidatePath(t *testing.T) {
+	skipOnWindows(t)
+	home := preflightHome(t)
+	log := logger.New()
+
+	runtimes, _ := RuntimesDir()
+	st := state.NewState()
+	for _, v := range []string{"20.11.0", "22.14.0"} {
+		dir := filepath.Join(runtimes, "node", v)
+		os.MkdirAll(filepath.Join(dir, "bin"), 0o755)
+		st.AddInstallation(state.Installation{Runtime: "node", Version: v, Path: dir, Action: "install"})
+		mod, _, err := AddToPath(filepath.Join(dir, "bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		st.AddPathModification(*mod)
+	}
+
+	if stale := StalePathEntries(st); len(stale) != 2 {
+		t.Fatalf("expected 2 stale entries, got %v", stale)
+	}
+
+	kept, err := ConsolidatePath(st, log)
+	if err != nil {
+		t.Fatalf("ConsolidatePath() error: %s", err)
+	}
+	currentBin := GetInstaller("node").BinDir(filepath.Join(runtimes, "node", "current"))
+	if len(kept) != 1 || kept[0] != currentBin {
+		t.Errorf("kept = %v, want [%s]", kept, currentBin)
+	}
+	if target, _ := os.Readlink(filepath.Join(runtimes, "node", "current")); target != "22.14.0" {
+		t.Errorf("current -> %q, want the latest install", target)
+	}
+
+	rc, _ := os.ReadFile(filepath.Join(home, ".bashrc"))
+	if strings.Contains(string(rc), "20.11.0") || strings.Contains(string(rc), "22.14.0") {
+		t.Errorf("versioned PATH lines should be removed:\n%s", rc)
+	}
+	if !strings.Contains(string(rc), currentBin) {
+		t.Errorf(".bashrc should have the current/bin entry:\n%s", rc)
+	}
+	if stale := StalePathEntries(st); len(stale) != 0 {
+		t.Errorf("nothing should be stale after consolidating, got %v", stale)
+	}
+}
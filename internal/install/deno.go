@@ -0,0 +1,144 @@
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// DenoInstaller handles Deno installation from the denoland/deno GitHub releases.
+type DenoInstaller struct{}
+
+func (d *DenoInstaller) Name() string { return "deno" }
+
+const (
+	denoReleasesURL = "https://api.github.com/repos/denoland/deno/releases?per_page=100"
+	denoDownloadURL = "https://github.com/denoland/deno/releases/download"
+)
+
+func (d *DenoInstaller) ResolveVersion(requirement string) (string, error) {
+	data, err := FetchGitHubJSON(denoReleasesURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch Deno releases: %w", err)
+	}
+	return resolveDenoVersion(data, requirement)
+}
+
+func (d *DenoInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	target, err := denoTarget(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("deno-%s.zip", target)
+	downloadURL := fmt.Sprintf("%s/v%s/%s", denoDownloadURL, version, filename)
+
+	// Asset names don't include the version, so add it to the temp file
+	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("deno-v%s-%s.zip", version, target))
+	defer os.Remove(tmpFile)
+
+	// Each asset has its own checksum file next to it
+	err = fetchArchive(ctx, d.Name(), downloadURL, tmpFile, progress, func() (string, error) {
+		return fetchSHA256Digest(downloadURL + ".sha256sum")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download Deno: %w", err)
+	}
+
+	// The zip contains just the deno executable
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
+		return fmt.Errorf("failed to extract Deno: %w", err)
+	}
+
+	return nil
+}
+
+// BinDir is the install directory itself: deno (or deno.exe) sits at the root.
+func (d *DenoInstaller) BinDir(installDir string) string {
+	return installDir
+}
+
+func (d *DenoInstaller) EnvVars(installDir string) map[string]string { return nil }
+
+// ArchivePattern matches the GitHub release asset for offline installs.
+// Asset names carry no version, so the archive must be the intended one.
+func (d *DenoInstaller) ArchivePattern(version string) (string, error) {
+	target, err := denoTarget(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deno-%s.zip", target), nil
+}
+
+// resolveDenoVersion picks the highest stable release satisfying requirement
+// from the GitHub releases JSON. Tags look like "v1.44.4".
+func resolveDenoVersion(data []byte, requirement string) (string, error) {
+	var releases []githubRelease
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return "", fmt.Errorf("failed to parse Deno releases: %w", err)
+	}
+
+	var constraint *semver.Constraints
+	if requirement != "latest" {
+		c, err := semver.NewConstraint(requirement)
+		if err != nil {
+			return "", fmt.Errorf("invalid Deno version requirement %q: %w", requirement, err)
+		}
+		constraint = c
+	}
+
+	var best *semver.Version
+	for _, r := range releases {
+		if r.Draft || r.Prerelease || !strings.HasPrefix(r.TagName, "v") {
+			continue
+		}
+		v, err := semver.NewVersion(strings.TrimPrefix(r.TagName, "v"))
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if constraint != nil && !constraint.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best = v
+		}
+	}
+
+	if best == nil {
+		if requirement == "latest" {
+			return "", fmt.Errorf("no Deno releases found")
+		}
+		return "", fmt.Errorf("no Deno version satisfying %s found", requirement)
+	}
+	return best.Original(), nil
+}
+
+// denoTarget returns the Rust target triple used in Deno's release asset
+// names, e.g. "aarch64-apple-darwin" for deno-aarch64-apple-darwin.zip.
+func denoTarget(goos, goarch string) (string, error) {
+	var arch string
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	default:
+		return "", fmt.Errorf("no Deno builds available for %s/%s", goos, goarch)
+	}
+
+	switch {
+	case goos == "darwin":
+		return arch + "-apple-darwin", nil
+	case goos == "linux":
+		return arch + "-unknown-linux-gnu", nil
+	case goos == "windows" && arch == "x86_64":
+		return "x86_64-pc-windows-msvc", nil
+	default:
+		return "", fmt.Errorf("no Deno builds available for %s/%s", goos, goarch)
+	}
+}
@@ -0,0 +1,169 @@
+package install
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+// footprint is a rough size profile for a runtime: the archive size when
+// release metadata doesn't list one, and how much bigger the install gets
+// once extracted (the archive itself is kept in the download cache).
+type footprint struct {
+	download int64
+	factor   int64
+}
+
+const mb = 1024 * 1024
+
+var footprints = map[string]footprint{
+	"node":    {50 * mb, 4},
+	"python":  {45 * mb, 4},
+	"flutter": {1100 * mb, 3},
+	"java":    {200 * mb, 2},
+	"go":      {75 * mb, 4},
+	"rust":    {300 * mb, 4}, // rustup downloads the toolchain itself
+	"php":     {30 * mb, 3},
+	"bun":     {40 * mb, 3},
+	"deno":    {45 * mb, 3},
+}
+
+// defaultFactor applies to runtimes without a footprint whose download size
+// was estimated anyway (e.g. from an offline archive).
+const defaultFactor = 3
+
+// downloadSizer is implemented by installers whose release metadata lists
+// the archive size, so the estimate doesn't have to rely on footprints.
+type downloadSizer interface {
+	DownloadSize(version string) (int64, error)
+}
+
+// diskFree is a package variable so tests can simulate a full disk.
+var diskFree = freeSpace
+
+// DiskFree returns the bytes available on the filesystem that holds dir,
+// or will once dir is created.
+func DiskFree(dir string) (uint64, error) {
+	target := existingAncestor(dir)
+	if target == "" {
+		return 0, fmt.Errorf("no existing parent directory of %s", dir)
+	}
+	return diskFree(target)
+}
+
+// EstimateDownloads fills in DownloadSize for every runtime the plan
+// downloads to install or upgrade: from the archive on disk in offline
+// mode, from release metadata where the installer has it, or else from a
+// typical size for the runtime. Estimates are best effort and never fail the plan.
+func EstimateDownloads(plan *engine.SetupPlan) {
+	runtimes := make([]*engine.RuntimePlan, 0, len(plan.Runtimes)+1)
+	for i := range plan.Runtimes {
+		runtimes = append(runtimes, &plan.Runtimes[i])
+	}
+	if plan.Packages != nil && plan.Packages.Runtime != nil {
+		runtimes = append(runtimes, plan.Packages.Runtime)
+	}
+	for _, rp := range runtimes {
+		if rp.Action == engine.ActionSkip || rp.Relink != "" || rp.DownloadSize > 0 {
+			continue
+		}
+		rp.DownloadSize = estimateDownload(*rp)
+	}
+}
+
+func estimateDownload(rp engine.RuntimePlan) int64 {
+	installer := GetInstaller(rp.Name)
+	if installer == nil {
+		return 0
+	}
+
+	if rp.ArchivesDir != "" {
+		if size, ok := offlineArchiveSize(installer, rp); ok {
+			return size
+		}
+	} else if sizer, ok := installer.(downloadSizer); ok {
+		if version, err := installer.ResolveVersion(rp.RequiredVersion); err == nil {
+			if size, err := sizer.DownloadSize(version); err == nil && size > 0 {
+				return size
+			}
+		}
+	}
+	return footprints[rp.Name].download
+}
+
+// offlineArchiveSize returns the size of the archive offline mode would
+// install for rp.
+func offlineArchiveSize(installer Installer, rp engine.RuntimePlan) (int64, bool) {
+	oi, ok := installer.(offlineInstaller)
+	if !ok {
+		return 0, false
+	}
+	version, err := offlineVersion(rp)
+	if err != nil {
+		return 0, false
+	}
+	pattern, err := oi.ArchivePattern(version)
+	if err != nil {
+		return 0, false
+	}
+	archive, err := findArchive(rp.ArchivesDir, pattern)
+	if err != nil {
+		return 0, false
+	}
+	info, err := os.Stat(archive)
+	if err != nil {
+		return 0, false
+	}
+	return info.Size(), true
+}
+
+// requiredSpace estimates the disk space the plan's runtime installs take:
+// each download times its runtime's expansion factor.
+func requiredSpace(plan *engine.SetupPlan) int64 {
+	var total int64
+	for _, rp := range plan.InstallRuntimes() {
+		if rp.Action == engine.ActionSkip || rp.Relink != "" {
+			continue
+		}
+		fp, known := footprints[rp.Name]
+		size := rp.DownloadSize
+		if size == 0 {
+			size = fp.download
+		}
+		factor := fp.factor
+		if !known {
+			factor = defaultFactor
+		}
+		total += size * factor
+	}
+	return total
+}
+
+// checkDiskSpace verifies that the filesystem holding runtimesDir has room
+// for the plan's runtimes. It returns nil when there is enough space or when
+// free space can't be determined.
+func checkDiskSpace(plan *engine.SetupPlan, runtimesDir string) *PreflightIssue {
+	required := requiredSpace(plan)
+	if required == 0 {
+		return nil
+	}
+	dir := existingAncestor(runtimesDir)
+	if dir == "" {
+		return nil
+	}
+	free, err := diskFree(dir)
+	if err != nil {
+		return nil
+	}
+	if uint64(required) <= free {
+		return nil
+	}
+	return &PreflightIssue{
+		Check:   "disk",
+		Path:    dir,
+		Problem: fmt.Sprintf("not enough disk space: setup needs about %s but only %s is available", term.FormatBytes(required), term.FormatBytes(int64(free))),
+		Fix:     "Free up space on this volume, or move ~/.templatr to a larger volume and symlink it back.",
+	}
+}
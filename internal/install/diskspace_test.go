@@ -0,0 +1,107 @@
+package install
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+func stubDiskFree(t *testing.T, free uint64) {
+	t.Helper()
+	orig := diskFree
+	diskFree = func(string) (uint64, error) { return free, nil }
+	t.Cleanup(func() { diskFree = orig })
+}
+
+func TestPreflight_NotEnoughDiskSpace(t *testing.T) {
+	preflightHome(t)
+	stubProbes(t, nil, nil)
+	stubDiskFree(t, 100*mb)
+
+	plan := &engine.SetupPlan{Runtimes: []engine.RuntimePlan{
+		{Name: "node", Action: engine.ActionInstall, DownloadSize: 50 * mb},
+		{Name: "java", Action: engine.ActionUpgrade, DownloadSize: 200 * mb},
+		{Name: "flutter", Action: engine.ActionSkip},
+	}}
+	issue := findIssue(Preflight(plan), "disk")
+	if issue == nil {
+		t.Fatal("expected a disk issue")
+	}
+	// node 50 MB x4 + java 200 MB x2; the skipped flutter doesn't count
+	if !strings.Contains(issue.Problem, "needs about 600.0 MB but only 100.0 MB is available") {
+		t.Errorf("Problem should state required vs available space, got %q", issue.Problem)
+	}
+	if issue.Fix == "" {
+		t.Error("expected remediation text")
+	}
+
+	stubDiskFree(t, 1<<40)
+	if issue := findIssue(Preflight(plan), "disk"); issue != nil {
+		t.Errorf("expected no disk issue with enough space, got %v", issue)
+	}
+}
+
+func TestPreflight_DiskSpaceUsesFootprints(t *testing.T) {
+	preflightHome(t)
+	stubProbes(t, nil, nil)
+	stubDiskFree(t, 3000*mb)
+
+	// Flutter's typical 1.1 GB download expands to about 3.3 GB
+	plan := &engine.SetupPlan{Runtimes: []engine.RuntimePlan{{Name: "flutter", Action: engine.ActionInstall}}}
+	if issue := findIssue(Preflight(plan), "disk"); issue == nil {
+		t.Error("expected a disk issue from flutter's typical footprint")
+	}
+}
+
+func TestExecutePlan_NotEnoughDiskSpace(t *testing.T) {
+	tempHome(t)
+	stubDiskFree(t, mb)
+
+	ts, hits, _ := countingServer(t, "runtime archive")
+	registerFake(t, &fakeInstaller{url: ts.URL})
+
+	plan := &engine.SetupPlan{Runtimes: []engine.RuntimePlan{
+		{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall, DownloadSize: 10 * mb},
+	}}
+	_, err := ExecutePlan(context.Background(), plan, logger.New(), nil, ExecuteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "not enough disk space") {
+		t.Fatalf("expected a disk space error, got %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("nothing should be downloaded when the disk is full, got %d requests", hits.Load())
+	}
+}
+
+func TestEstimateDownloads_Offline(t *testing.T) {
+	tempHome(t)
+
+	archives := t.TempDir()
+	archive := filepath.Join(archives, "fake-runtime-v1.2.3-linux.zip")
+	writeFixtureZip(t, archive)
+	registerFake(t, &offlineFake{})
+
+	plan := &engine.SetupPlan{Runtimes: []engine.RuntimePlan{
+		offlinePlan(archives, "1.2.3", ""),
+		{Name: "fake", Action: engine.ActionSkip},
+	}}
+	EstimateDownloads(plan)
+
+	info, err := os.Stat(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Runtimes[0].DownloadSize; got != info.Size() {
+		t.Errorf("DownloadSize = %d, want the archive size %d", got, info.Size())
+	}
+	if got := plan.Runtimes[1].DownloadSize; got != 0 {
+		t.Errorf("skipped runtimes should not be estimated, got %d", got)
+	}
+	if got := plan.DownloadSize(); got != info.Size() {
+		t.Errorf("plan DownloadSize() = %d, want %d", got, info.Size())
+	}
+}
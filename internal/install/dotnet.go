@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"runtime"
@@ -15,7 +16,7 @@ func (d *DotnetInstaller) ResolveVersion(requirement string) (string, error) {
 	return "", fmt.Errorf(".NET installer not yet implemented - install .NET manually from https://dot.net/download")
 }
 
-func (d *DotnetInstaller) Install(version, targetDir string, progress ProgressFunc) error {
+func (d *DotnetInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
 	return fmt.Errorf(".NET installer not yet implemented - install .NET %s manually from https://dot.net/download", version)
 }
 
@@ -4,10 +4,13 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,43 +22,140 @@ import (
 type ProgressFunc func(downloaded, total int64)
 
 // DownloadFile downloads a file from the given URL to destPath.
-func DownloadFile(url, destPath string, progress ProgressFunc) error {
-	resp, err := http.Get(url)
+func DownloadFile(ctx context.Context, url, destPath string, progress ProgressFunc) error {
+	return DownloadFileWithHeaders(ctx, url, destPath, nil, progress)
+}
+
+// DownloadFileWithHeaders downloads a file like DownloadFile, sending the given
+// extra request headers (e.g., Authorization for private sources).
+//
+// Data is written to destPath + ".part" and renamed into place once complete.
+// A partial file left by an interrupted download (this call or an earlier
+// run) is resumed with a Range request; servers that don't honour it send
+// the whole file and the partial file is truncated. Transient failures are
+// retried up to MaxAttempts times, and a mirror URL from runtimeURL that 404s
+// falls back to the official one. Cancelling ctx stops the transfer, removes
+// the partial file, and returns ErrCancelled.
+func DownloadFileWithHeaders(ctx context.Context, url, destPath string, headers map[string]string, progress ProgressFunc) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", destPath, err)
+	}
+	partPath := destPath + ".part"
+
+	err := withMirrorFallback(url, func(url string) error {
+		return withRetry(ctx, url, func() error {
+			return downloadPart(ctx, url, partPath, headers, progress)
+		})
+	})
+	if errors.Is(err, ErrCancelled) {
+		// An aborted download isn't resumed later, so don't leave it behind
+		os.Remove(partPath)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to download %s: %w", url, err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download returned HTTP %d for %s", resp.StatusCode, url)
+	if err := os.Rename(partPath, destPath); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", destPath, err)
 	}
+	return nil
+}
 
-	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
-		return fmt.Errorf("failed to create directory for %s: %w", destPath, err)
+// downloadPart makes one attempt at fetching url into partPath, resuming
+// from its current size.
+func downloadPart(ctx context.Context, url, partPath string, headers map[string]string, progress ProgressFunc) error {
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid download url %s: %w", url, err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
 
-	out, err := os.Create(destPath)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", destPath, err)
+		return transient(fmt.Errorf("failed to download %s: %w", url, err))
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp.Header.Get("Content-Range")) == offset:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// Fresh download: no partial file, or the server ignored the Range.
+		offset = 0
+		flags |= os.O_TRUNC
+	case offset > 0 && (resp.StatusCode == http.StatusRequestedRangeNotSatisfiable || resp.StatusCode == http.StatusPartialContent):
+		// The partial file doesn't line up with what the server has (it may
+		// have changed); start again from zero.
+		resp.Body.Close()
+		if err := os.Remove(partPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", partPath, err)
+		}
+		return downloadPart(ctx, url, partPath, headers, progress)
+	default:
+		return statusError(resp.StatusCode, fmt.Errorf("download returned HTTP %d for %s", resp.StatusCode, url))
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", partPath, err)
 	}
 	defer out.Close()
 
 	reader := io.Reader(resp.Body)
 	if progress != nil {
+		total := resp.ContentLength
+		if total >= 0 {
+			total += offset
+		}
+		progress(offset, total)
 		reader = &progressReader{
-			reader:   resp.Body,
-			total:    resp.ContentLength,
-			progress: progress,
+			reader:     resp.Body,
+			total:      total,
+			downloaded: offset,
+			progress:   progress,
 		}
 	}
 
 	if _, err := io.Copy(out, reader); err != nil {
-		return fmt.Errorf("failed to write %s: %w", destPath, err)
+		// Keep the partial file only if the server can pick up where it left off.
+		if resp.StatusCode != http.StatusPartialContent && resp.Header.Get("Accept-Ranges") != "bytes" {
+			out.Close()
+			os.Remove(partPath)
+		}
+		err = fmt.Errorf("failed to write %s: %w", partPath, err)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return err // local disk problem, not the connection
+		}
+		return transient(err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", partPath, err)
 	}
-
 	return nil
 }
 
+// contentRangeStart returns the first byte position of a Content-Range
+// header such as "bytes 1000-1999/2000", or -1 if it can't be parsed.
+func contentRangeStart(header string) int64 {
+	var start, end int64
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/", &start, &end); err != nil {
+		return -1
+	}
+	return start
+}
+
 type progressReader struct {
 	reader     io.Reader
 	progress   ProgressFunc
@@ -70,6 +170,10 @@ func (pr *progressReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// ErrChecksumMismatch is returned (wrapped) by VerifyChecksum when a file's
+// hash differs from the expected one.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
 // VerifyChecksum checks that a file's SHA256 hash matches the expected value.
 func VerifyChecksum(filePath, expectedHash string) error {
 	f, err := os.Open(filePath)
@@ -85,28 +189,42 @@ func VerifyChecksum(filePath, expectedHash string) error {
 
 	actual := hex.EncodeToString(h.Sum(nil))
 	if !strings.EqualFold(actual, expectedHash) {
-		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filepath.Base(filePath), expectedHash, actual)
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, filepath.Base(filePath), expectedHash, actual)
 	}
 	return nil
 }
 
 // FetchChecksumFromURL downloads a SHASUMS256.txt-style file and returns the hash for the given filename.
 func FetchChecksumFromURL(url, filename string) (string, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch checksums from %s: %w", url, err)
-	}
-	defer resp.Body.Close()
+	var body []byte
+	err := withMirrorFallback(url, func(url string) error {
+		return withRetry(context.Background(), url, func() error {
+			resp, err := http.Get(url)
+			if err != nil {
+				return transient(fmt.Errorf("failed to fetch checksums from %s: %w", url, err))
+			}
+			defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("checksums URL returned HTTP %d: %s", resp.StatusCode, url)
-	}
+			if resp.StatusCode != http.StatusOK {
+				return statusError(resp.StatusCode, fmt.Errorf("checksums URL returned HTTP %d: %s", resp.StatusCode, url))
+			}
 
-	body, err := io.ReadAll(resp.Body)
+			body, err = io.ReadAll(resp.Body)
+			if err != nil {
+				return transient(fmt.Errorf("failed to read checksums: %w", err))
+			}
+			return nil
+		})
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to read checksums: %w", err)
+		return "", err
 	}
+	return findChecksum(body, filename, url)
+}
 
+// findChecksum returns the hash for filename in body, a SHASUMS256.txt-style
+// file fetched from url.
+func findChecksum(body []byte, filename, url string) (string, error) {
 	for _, line := range strings.Split(string(body), "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -125,8 +243,31 @@ func FetchChecksumFromURL(url, filename string) (string, error) {
 	return "", fmt.Errorf("checksum not found for %s in %s", filename, url)
 }
 
-// ExtractTarGz extracts a .tar.gz archive to destDir.
-func ExtractTarGz(archivePath, destDir string) error {
+// fetchSHA256Digest downloads a checksum file for a single archive (such as
+// "archive.zip.sha256sum") and returns the first SHA256 hex digest in it.
+// Both sha256sum output and PowerShell's Get-FileHash table are accepted.
+func fetchSHA256Digest(url string) (string, error) {
+	data, err := FetchJSON(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum from %s: %w", url, err)
+	}
+	for _, field := range strings.Fields(string(data)) {
+		field = strings.TrimLeft(field, "*")
+		if len(field) != sha256.Size*2 {
+			continue
+		}
+		if _, err := hex.DecodeString(field); err == nil {
+			return strings.ToLower(field), nil
+		}
+	}
+	return "", fmt.Errorf("no SHA256 digest found in %s", url)
+}
+
+// ExtractTarGz extracts a .tar.gz archive to destDir, stopping with
+// ErrCancelled between entries once ctx is cancelled. The entries are
+// reported as extracting events as they are written; their total isn't
+// known without reading the archive twice.
+func ExtractTarGz(ctx context.Context, archivePath, destDir string) error {
 	f, err := os.Open(archivePath)
 	if err != nil {
 		return err
@@ -142,7 +283,11 @@ func ExtractTarGz(archivePath, destDir string) error {
 	tr := tar.NewReader(gr)
 	cleanDest := filepath.Clean(destDir)
 
-	for {
+	for done := int64(0); ; done++ {
+		reportEvent(ctx, ProgressEvent{Phase: PhaseExtracting, Done: done})
+		if ctx.Err() != nil {
+			return ErrCancelled
+		}
 		hdr, err := tr.Next()
 		if err == io.EOF {
 			break
@@ -190,8 +335,10 @@ func ExtractTarGz(archivePath, destDir string) error {
 	return nil
 }
 
-// ExtractZip extracts a .zip archive to destDir.
-func ExtractZip(archivePath, destDir string) error {
+// ExtractZip extracts a .zip archive to destDir, stopping with ErrCancelled
+// between entries once ctx is cancelled. The entries are reported as
+// extracting events as they are written.
+func ExtractZip(ctx context.Context, archivePath, destDir string) error {
 	r, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to open zip: %w", err)
@@ -200,7 +347,12 @@ func ExtractZip(archivePath, destDir string) error {
 
 	cleanDest := filepath.Clean(destDir)
 
-	for _, f := range r.File {
+	total := int64(len(r.File))
+	for i, f := range r.File {
+		reportEvent(ctx, ProgressEvent{Phase: PhaseExtracting, Done: int64(i), Total: total})
+		if ctx.Err() != nil {
+			return ErrCancelled
+		}
 		target := filepath.Join(destDir, f.Name)
 
 		if !strings.HasPrefix(filepath.Clean(target), cleanDest) {
@@ -234,17 +386,18 @@ func ExtractZip(archivePath, destDir string) error {
 			return copyErr
 		}
 	}
+	reportEvent(ctx, ProgressEvent{Phase: PhaseExtracting, Done: total, Total: total})
 	return nil
 }
 
 // ExtractArchive detects format from filename and extracts accordingly.
-func ExtractArchive(archivePath, destDir string) error {
+func ExtractArchive(ctx context.Context, archivePath, destDir string) error {
 	lower := strings.ToLower(archivePath)
 	switch {
 	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
-		return ExtractTarGz(archivePath, destDir)
+		return ExtractTarGz(ctx, archivePath, destDir)
 	case strings.HasSuffix(lower, ".zip"):
-		return ExtractZip(archivePath, destDir)
+		return ExtractZip(ctx, archivePath, destDir)
 	default:
 		return fmt.Errorf("unsupported archive format: %s", filepath.Base(archivePath))
 	}
@@ -253,7 +406,7 @@ func ExtractArchive(archivePath, destDir string) error {
 // ExtractAndFlatten extracts an archive and moves the contents of the single
 // top-level directory to targetDir. Many runtime archives (Node, Go, etc.)
 // contain a single top-level directory that we want to strip.
-func ExtractAndFlatten(archivePath, targetDir string) error {
+func ExtractAndFlatten(ctx context.Context, archivePath, targetDir string) error {
 	// Extract to a temp directory next to the target
 	tmpDir, err := os.MkdirTemp(filepath.Dir(targetDir), "extract-*")
 	if err != nil {
@@ -261,7 +414,7 @@ func ExtractAndFlatten(archivePath, targetDir string) error {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	if err := ExtractArchive(archivePath, tmpDir); err != nil {
+	if err := ExtractArchive(ctx, archivePath, tmpDir); err != nil {
 		return fmt.Errorf("extraction failed: %w", err)
 	}
 
@@ -353,8 +506,22 @@ func copyFile(src, dst string) error {
 	return err
 }
 
-// RuntimesDir returns the base directory for installed runtimes (~/.templatr/runtimes/).
+// runtimesDir is runtimes_dir from the user configuration.
+var runtimesDir string
+
+// SetRuntimesDir sets where runtimes are installed for the rest of the
+// run, from runtimes_dir in ~/.templatr/config.toml or --runtimes-dir. An
+// empty dir means the default.
+func SetRuntimesDir(dir string) {
+	runtimesDir = dir
+}
+
+// RuntimesDir returns the base directory for installed runtimes
+// (~/.templatr/runtimes/ unless SetRuntimesDir chose another).
 func RuntimesDir() (string, error) {
+	if runtimesDir != "" {
+		return runtimesDir, nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
@@ -383,16 +550,28 @@ func PlatformArch() string {
 }
 
 // FetchJSON is a helper that fetches a URL and returns the response body as bytes.
+// Transient failures are retried up to MaxAttempts times, and a mirror URL
+// from runtimeURL that 404s falls back to the official one.
 func FetchJSON(url string) ([]byte, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
-	}
-	defer resp.Body.Close()
+	var body []byte
+	err := withMirrorFallback(url, func(url string) error {
+		return withRetry(context.Background(), url, func() error {
+			resp, err := http.Get(url)
+			if err != nil {
+				return transient(fmt.Errorf("failed to fetch %s: %w", url, err))
+			}
+			defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
-	}
+			if resp.StatusCode != http.StatusOK {
+				return statusError(resp.StatusCode, fmt.Errorf("HTTP %d from %s", resp.StatusCode, url))
+			}
 
-	return io.ReadAll(resp.Body)
+			body, err = io.ReadAll(resp.Body)
+			if err != nil {
+				return transient(fmt.Errorf("failed to read %s: %w", url, err))
+			}
+			return nil
+		})
+	})
+	return body, err
 }
@@ -1,13 +1,19 @@
 package install
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestVerifyChecksum_Match(t *testing.T) {
@@ -52,7 +58,7 @@ func TestDownloadFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	destFile := filepath.Join(tmpDir, "downloaded.txt")
 
-	err := DownloadFile(ts.URL, destFile, nil)
+	err := DownloadFile(context.Background(), ts.URL, destFile, nil)
 	if err != nil {
 		t.Fatalf("download failed: %s", err)
 	}
@@ -82,7 +88,7 @@ func TestDownloadFile_WithProgress(t *testing.T) {
 		lastTotal = total
 	}
 
-	err := DownloadFile(ts.URL, destFile, progress)
+	err := DownloadFile(context.Background(), ts.URL, destFile, progress)
 	if err != nil {
 		t.Fatalf("download failed: %s", err)
 	}
@@ -104,12 +110,179 @@ func TestDownloadFile_404(t *testing.T) {
 	tmpDir := t.TempDir()
 	destFile := filepath.Join(tmpDir, "downloaded.txt")
 
-	err := DownloadFile(ts.URL, destFile, nil)
+	err := DownloadFile(context.Background(), ts.URL, destFile, nil)
 	if err == nil {
 		t.Error("expected error for 404")
 	}
 }
 
+// rangeServer serves content with Range support via http.ServeContent and
+// records the Range header of each request.
+func rangeServer(t *testing.T, content []byte, ranges *[]string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "archive.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestDownloadFile_ResumesPartial(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	var ranges []string
+	ts := rangeServer(t, content, &ranges)
+
+	destFile := filepath.Join(t.TempDir(), "node.tar.gz")
+	os.WriteFile(destFile+".part", content[:400], 0o644)
+
+	var first, last int64 = -1, 0
+	var lastTotal int64
+	progress := func(downloaded, total int64) {
+		if first < 0 {
+			first = downloaded
+		}
+		if downloaded < last {
+			t.Errorf("progress went backwards: %d after %d", downloaded, last)
+		}
+		last, lastTotal = downloaded, total
+	}
+
+	if err := DownloadFile(context.Background(), ts.URL, destFile, progress); err != nil {
+		t.Fatalf("download failed: %s", err)
+	}
+
+	if len(ranges) != 1 || ranges[0] != "bytes=400-" {
+		t.Errorf("expected a single resumed request, got ranges %q", ranges)
+	}
+	if first != 400 || last != 1000 || lastTotal != 1000 {
+		t.Errorf("progress: first %d, last %d/%d; want 400, 1000/1000", first, last, lastTotal)
+	}
+	data, _ := os.ReadFile(destFile)
+	if !bytes.Equal(data, content) {
+		t.Error("resumed file does not match the original")
+	}
+	if _, err := os.Stat(destFile + ".part"); !os.IsNotExist(err) {
+		t.Error("partial file should be renamed into place")
+	}
+}
+
+func TestDownloadFile_RetriesInterruptedDownload(t *testing.T) {
+	fastRetries(t)
+	content := []byte(strings.Repeat("abcdefghij", 100))
+	var requests atomic.Int32
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if requests.Add(1) == 1 {
+			// Promise the whole file, send 80% of it, then drop the connection.
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", "1000")
+			w.Write(content[:800])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "flutter.zip", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	destFile := filepath.Join(t.TempDir(), "flutter.zip")
+	if err := DownloadFile(context.Background(), ts.URL, destFile, nil); err != nil {
+		t.Fatalf("download failed: %s", err)
+	}
+
+	if len(ranges) != 2 || ranges[1] != "bytes=800-" {
+		t.Errorf("expected the retry to resume at 800, got ranges %q", ranges)
+	}
+	data, _ := os.ReadFile(destFile)
+	if !bytes.Equal(data, content) {
+		t.Error("downloaded file does not match the original")
+	}
+}
+
+func TestDownloadFile_NoRangeSupport(t *testing.T) {
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Write([]byte("fresh content"))
+	}))
+	defer ts.Close()
+
+	destFile := filepath.Join(t.TempDir(), "downloaded.txt")
+	os.WriteFile(destFile+".part", []byte("stale partial data that is longer"), 0o644)
+
+	if err := DownloadFile(context.Background(), ts.URL, destFile, nil); err != nil {
+		t.Fatalf("download failed: %s", err)
+	}
+	if len(ranges) != 1 || ranges[0] == "" {
+		t.Errorf("expected one ranged request, got %q", ranges)
+	}
+	data, _ := os.ReadFile(destFile)
+	if string(data) != "fresh content" {
+		t.Errorf("expected partial file to be truncated, got %q", data)
+	}
+}
+
+func TestDownloadFile_PartialLargerThanRemote(t *testing.T) {
+	content := []byte("short")
+	var ranges []string
+	ts := rangeServer(t, content, &ranges)
+
+	destFile := filepath.Join(t.TempDir(), "downloaded.txt")
+	os.WriteFile(destFile+".part", []byte("something much longer than the file"), 0o644)
+
+	if err := DownloadFile(context.Background(), ts.URL, destFile, nil); err != nil {
+		t.Fatalf("download failed: %s", err)
+	}
+	if len(ranges) != 2 || ranges[1] != "" {
+		t.Errorf("expected an unsatisfiable range then a fresh request, got %q", ranges)
+	}
+	data, _ := os.ReadFile(destFile)
+	if string(data) != "short" {
+		t.Errorf("got %q", data)
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 400-999/1000": 400,
+		"bytes 0-0/*":        0,
+		"":                   -1,
+		"items 1-2/3":        -1,
+	}
+	for header, want := range tests {
+		if got := contentRangeStart(header); got != want {
+			t.Errorf("%q: got %d, want %d", header, got, want)
+		}
+	}
+}
+
+func TestFetchSHA256Digest(t *testing.T) {
+	const digest = "4d8a4ad6acba0a5b4ff7fbc0a1ba6b5d1e989fa0fd7d4b0c9a7d1e6c7e1b2f3a"
+	bodies := map[string]string{
+		"/sha256sum":  digest + "  deno-x86_64-unknown-linux-gnu.zip\n",
+		"/powershell": "\r\nAlgorithm       Hash                                                                   Path\r\n---------       ----                                                                   ----\r\nSHA256          " + strings.ToUpper(digest) + "       D:\\a\\deno-x86_64-pc-windows-msvc.zip\r\n",
+		"/empty":      "not a checksum\n",
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(bodies[r.URL.Path]))
+	}))
+	defer ts.Close()
+
+	for _, path := range []string{"/sha256sum", "/powershell"} {
+		got, err := fetchSHA256Digest(ts.URL + path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", path, err)
+		}
+		if got != digest {
+			t.Errorf("%s: got %q, want %q", path, got, digest)
+		}
+	}
+	if _, err := fetchSHA256Digest(ts.URL + "/empty"); err == nil {
+		t.Error("expected error when no digest is present")
+	}
+}
+
 func TestFetchChecksumFromURL(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("abc123  file1.tar.gz\ndef456  file2.tar.gz\n"))
@@ -143,7 +316,7 @@ func TestExtractAndFlatten_Zip(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	// Create a zip file manually is complex, so we just test ExtractZip error cases
-	err := ExtractZip(filepath.Join(tmpDir, "nonexistent.zip"), tmpDir)
+	err := ExtractZip(context.Background(), filepath.Join(tmpDir, "nonexistent.zip"), tmpDir)
 	if err == nil {
 		t.Error("expected error for nonexistent zip")
 	}
@@ -154,7 +327,7 @@ func TestExtractArchive_UnsupportedFormat(t *testing.T) {
 	testFile := filepath.Join(tmpDir, "test.rar")
 	os.WriteFile(testFile, []byte("fake"), 0o644)
 
-	err := ExtractArchive(testFile, tmpDir)
+	err := ExtractArchive(context.Background(), testFile, tmpDir)
 	if err == nil {
 		t.Error("expected error for unsupported format")
 	}
@@ -188,3 +361,80 @@ func TestRuntimesDir(t *testing.T) {
 		t.Error("expected absolute path")
 	}
 }
+
+// slowServer sends the first chunk of a large file and then stalls until the
+// client goes away, so a download can be cancelled mid-transfer.
+func slowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1048576")
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Write(bytes.Repeat([]byte("x"), 4096))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// cancelOnProgress returns a progress func that cancels once bytes arrive.
+func cancelOnProgress(cancel context.CancelFunc) ProgressFunc {
+	return func(downloaded, total int64) {
+		if downloaded > 0 {
+			cancel()
+		}
+	}
+}
+
+// cancelOnDownload is cancelOnProgress for an install's events.
+func cancelOnDownload(cancel context.CancelFunc) EventFunc {
+	progress := cancelOnProgress(cancel)
+	return func(ev ProgressEvent) {
+		if ev.Phase == PhaseDownloading {
+			progress(ev.Done, ev.Total)
+		}
+	}
+}
+
+func TestDownloadFile_Cancelled(t *testing.T) {
+	ts := slowServer(t)
+	dest := filepath.Join(t.TempDir(), "runtime.tar.gz")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- DownloadFile(ctx, ts.URL, dest, cancelOnProgress(cancel)) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCancelled) {
+			t.Fatalf("expected ErrCancelled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("cancelling should stop the download")
+	}
+
+	for _, path := range []string{dest, dest + ".part"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after cancelling", filepath.Base(path))
+		}
+	}
+}
+
+func TestExtractAndFlatten_Cancelled(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "runtime.zip")
+	writeFixtureZip(t, archive)
+	parent := t.TempDir()
+	target := filepath.Join(parent, "1.0.0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := ExtractAndFlatten(ctx, archive, target); !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	if entries, _ := os.ReadDir(parent); len(entries) != 0 {
+		t.Errorf("cancelled extract should leave nothing behind, found %d entries", len(entries))
+	}
+}
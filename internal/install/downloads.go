@@ -0,0 +1,152 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// ActionDownload is the state.Installation action recorded for [[downloads]] entries.
+const ActionDownload = "download"
+
+// InstallDownload fetches one [[downloads]] entry: sends the auth header if
+// configured, verifies the checksum, extracts or copies into the target
+// directory, and records state so uninstall can remove it. It reports its
+// phases to events as it goes.
+func InstallDownload(ctx context.Context, dp engine.DownloadPlan, templateSlug string, log *logger.Logger, events EventFunc) (*InstallResult, error) {
+	start := time.Now()
+	setInstallLogger(log)
+	headers, err := downloadHeaders(dp, log)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := engine.DownloadFilename(dp.URL)
+	tmpDir, err := os.MkdirTemp("", "templatr-download-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, filename)
+	log.Info("Downloading %s...", dp.Name)
+	counter := &byteCounter{next: events.downloading}
+	if err := DownloadFileWithHeaders(ctx, dp.URL, archivePath, headers, counter.progress); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", dp.Name, err)
+	}
+
+	if dp.SHA256 != "" {
+		events.emit(ProgressEvent{Phase: PhaseVerifying})
+		if err := VerifyChecksum(archivePath, dp.SHA256); err != nil {
+			return nil, err
+		}
+		log.Info("Checksum verified for %s", dp.Name)
+	}
+
+	// installPath is what uninstall removes: only the placed file, or for
+	// an archive the entries it placed, as target_dir may be shared
+	installPath := dp.TargetDir
+	var entries []string
+	if dp.Extract {
+		log.Info("Extracting %s to %s...", dp.Name, dp.TargetDir)
+		extracted := filepath.Join(tmpDir, "extracted")
+		if err := ExtractAndFlatten(withEvents(ctx, events), archivePath, extracted); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", dp.Name, err)
+		}
+		if entries, err = placeEntries(extracted, dp.TargetDir); err != nil {
+			return nil, fmt.Errorf("failed to place %s: %w", dp.Name, err)
+		}
+	} else {
+		if err := os.MkdirAll(dp.TargetDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dp.TargetDir, err)
+		}
+		installPath = filepath.Join(dp.TargetDir, filename)
+		if err := copyFile(archivePath, installPath); err != nil {
+			return nil, fmt.Errorf("failed to place %s: %w", dp.Name, err)
+		}
+	}
+
+	err = state.WithLock(func(st *state.State) error {
+		st.AddInstallation(state.Installation{
+			Runtime:  dp.Name,
+			Path:     installPath,
+			Template: templateSlug,
+			Checksum: dp.SHA256,
+			Action:   ActionDownload,
+			Entries:  entries,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record %s: %w", dp.Name, err)
+	}
+
+	log.Info("%s downloaded to %s", dp.Name, installPath)
+
+	return &InstallResult{
+		Runtime:     dp.Name,
+		InstallPath: installPath,
+		Duration:    time.Since(start),
+		Bytes:       counter.Total(),
+	}, nil
+}
+
+// placeEntries moves each entry of src into dir, replacing one of the same
+// name but leaving the rest of dir alone, and returns the paths it placed.
+func placeEntries(src, dir string) ([]string, error) {
+	items, err := os.ReadDir(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var placed []string
+	for _, item := range items {
+		from, to := filepath.Join(src, item.Name()), filepath.Join(dir, item.Name())
+		if err := os.RemoveAll(to); err != nil {
+			return placed, err
+		}
+		if err := os.Rename(from, to); err != nil {
+			// Rename can fail across filesystems - fall back to copy
+			if item.IsDir() {
+				err = copyDir(from, to)
+			} else {
+				err = copyFile(from, to)
+			}
+			if err != nil {
+				return placed, err
+			}
+		}
+		placed = append(placed, to)
+	}
+	return placed, nil
+}
+
+// downloadHeaders builds request headers for a download. The auth value is
+// read from the environment, registered as a secret with the logger, and
+// never persisted. Values without a scheme are sent as bearer tokens.
+func downloadHeaders(dp engine.DownloadPlan, log *logger.Logger) (map[string]string, error) {
+	if dp.AuthEnv == "" {
+		return nil, nil
+	}
+
+	token := strings.TrimSpace(os.Getenv(dp.AuthEnv))
+	if token == "" {
+		return nil, fmt.Errorf("download %s requires %s to be set", dp.Name, dp.AuthEnv)
+	}
+	log.AddSecret(token)
+
+	if !strings.Contains(token, " ") {
+		token = "Bearer " + token
+	}
+	return map[string]string{"Authorization": token}, nil
+}
@@ -0,0 +1,200 @@
+package install
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+const testSDKContent = "private sdk"
+
+// authServer serves testSDKContent only when the bearer token matches.
+func authServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(testSDKContent))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func testSDKHash() string {
+	h := sha256.Sum256([]byte(testSDKContent))
+	return hex.EncodeToString(h[:])
+}
+
+func TestDownloadFileWithHeaders(t *testing.T) {
+	ts := authServer(t, "s3cret")
+	dest := filepath.Join(t.TempDir(), "sdk.bin")
+
+	if err := DownloadFileWithHeaders(context.Background(), ts.URL, dest, map[string]string{"Authorization": "Bearer s3cret"}, nil); err != nil {
+		t.Fatalf("download with header failed: %s", err)
+	}
+	if err := DownloadFile(context.Background(), ts.URL, dest, nil); err == nil {
+		t.Error("expected download without header to fail")
+	}
+}
+
+func TestInstallDownload_Authenticated(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	t.Setenv("SDK_TOKEN", "s3cret")
+
+	ts := authServer(t, "s3cret")
+	target := filepath.Join(t.TempDir(), "vendor")
+	dp := engine.DownloadPlan{
+		Name:      "sdk",
+		URL:       ts.URL + "/sdk.bin?X-Amz-Signature=abc",
+		SHA256:    testSDKHash(),
+		TargetDir: target,
+		AuthEnv:   "SDK_TOKEN",
+		Action:    engine.ActionInstall,
+	}
+
+	result, err := InstallDownload(context.Background(), dp, "test-template", logger.New(), nil)
+	if err != nil {
+		t.Fatalf("InstallDownload failed: %s", err)
+	}
+
+	wantPath := filepath.Join(target, "sdk.bin")
+	if result.InstallPath != wantPath {
+		t.Errorf("InstallPath = %q, want %q", result.InstallPath, wantPath)
+	}
+	data, err := os.ReadFile(wantPath)
+	if err != nil || string(data) != testSDKContent {
+		t.Fatalf("downloaded file = %q, %v", data, err)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatalf("state load failed: %s", err)
+	}
+	if len(st.Installations) != 1 {
+		t.Fatalf("expected 1 recorded installation, got %d", len(st.Installations))
+	}
+	inst := st.Installations[0]
+	if inst.Action != ActionDownload || inst.Path != wantPath || inst.Checksum != testSDKHash() {
+		t.Errorf("recorded installation = %+v", inst)
+	}
+
+	raw, _ := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".templatr", "state.json"))
+	if strings.Contains(string(raw), "s3cret") {
+		t.Error("auth token was written to the state file")
+	}
+}
+
+func TestInstallDownload_MissingAuthEnv(t *testing.T) {
+	t.Setenv("SDK_TOKEN", "")
+
+	dp := engine.DownloadPlan{
+		Name:      "sdk",
+		URL:       "https://example.com/sdk.bin",
+		SHA256:    testSDKHash(),
+		TargetDir: t.TempDir(),
+		AuthEnv:   "SDK_TOKEN",
+	}
+
+	if _, err := InstallDownload(context.Background(), dp, "", logger.New(), nil); err == nil {
+		t.Error("expected error when auth env var is unset")
+	}
+}
+
+func TestInstallDownload_WrongToken(t *testing.T) {
+	t.Setenv("SDK_TOKEN", "wrong")
+
+	ts := authServer(t, "s3cret")
+	dp := engine.DownloadPlan{
+		Name:      "sdk",
+		URL:       ts.URL + "/sdk.bin",
+		SHA256:    testSDKHash(),
+		TargetDir: t.TempDir(),
+		AuthEnv:   "SDK_TOKEN",
+	}
+
+	if _, err := InstallDownload(context.Background(), dp, "", logger.New(), nil); err == nil {
+		t.Error("expected error for rejected token")
+	}
+}
+
+func TestInstallDownload_ChecksumMismatch(t *testing.T) {
+	t.Setenv("SDK_TOKEN", "s3cret")
+
+	ts := authServer(t, "s3cret")
+	target := t.TempDir()
+	dp := engine.DownloadPlan{
+		Name:      "sdk",
+		URL:       ts.URL + "/sdk.bin",
+		SHA256:    "0000000000000000000000000000000000000000000000000000000000000000",
+		TargetDir: target,
+		AuthEnv:   "SDK_TOKEN",
+	}
+
+	if _, err := InstallDownload(context.Background(), dp, "", logger.New(), nil); err == nil {
+		t.Fatal("expected checksum mismatch error")
+	}
+	if _, err := os.Stat(filepath.Join(target, "sdk.bin")); !os.IsNotExist(err) {
+		t.Error("file should not be placed when the checksum does not match")
+	}
+}
+
+func TestInstallDownload_ExtractIntoSharedDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	archive := filepath.Join(t.TempDir(), "sdk.zip")
+	writeFixtureZip(t, archive)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, archive)
+	}))
+	t.Cleanup(ts.Close)
+
+	// The target directory holds the user's own files, which stay
+	target := t.TempDir()
+	readme := filepath.Join(target, "README.md")
+	os.WriteFile(readme, []byte("mine"), 0o644)
+	dp := engine.DownloadPlan{Name: "sdk", URL: ts.URL + "/sdk.zip", TargetDir: target, Extract: true, Action: engine.ActionInstall}
+
+	if _, err := InstallDownload(context.Background(), dp, "", logger.New(), nil); err != nil {
+		t.Fatalf("InstallDownload failed: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "bin", "fake")); err != nil {
+		t.Errorf("archive not extracted into %s: %s", target, err)
+	}
+	if _, err := os.Stat(readme); err != nil {
+		t.Errorf("extracting removed the user's file: %s", err)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := st.Installations[0]
+	if inst.Path != target || len(inst.Entries) != 1 || inst.Entries[0] != filepath.Join(target, "bin") {
+		t.Fatalf("recorded installation = %+v, want bin recorded as placed in %s", inst, target)
+	}
+
+	// Uninstall removes what was placed and nothing else
+	if _, err := st.UndoInstallation("sdk", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "bin")); !os.IsNotExist(err) {
+		t.Error("uninstall left the extracted entries")
+	}
+	if _, err := os.Stat(readme); err != nil {
+		t.Errorf("uninstall removed the user's file: %s", err)
+	}
+}
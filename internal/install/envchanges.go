@@ -0,0 +1,259 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Kinds of environment change.
+const (
+	ChangePath = "path" // a directory added to PATH
+	ChangeEnv  = "env"  // a variable such as JAVA_HOME
+)
+
+// windowsEnvKey is where user-level environment variables live on Windows.
+const windowsEnvKey = `HKCU\Environment`
+
+// EnvChange is one persistent environment modification made while installing
+// a runtime, or a note that none was needed.
+type EnvChange struct {
+	Kind      string `json:"kind"`                // ChangePath or ChangeEnv
+	Name      string `json:"name"`                // "PATH" or the variable name
+	Value     string `json:"value"`               // directory added to PATH, or the variable's value
+	File      string `json:"file,omitempty"`      // shell rc file modified (Unix)
+	Line      string `json:"line,omitempty"`      // exact line appended to File
+	Registry  string `json:"registry,omitempty"`  // registry value written (Windows)
+	Unchanged bool   `json:"unchanged,omitempty"` // already configured, nothing written
+	Manual    bool   `json:"manual,omitempty"`    // left for the user to make (--no-path), nothing written
+}
+
+// EnvSummary is the "Environment changes" section shown after install.
+type EnvSummary struct {
+	Shell      string      `json:"shell"` // zsh, bash, fish, sh, or powershell
+	Changes    []EnvChange `json:"changes"`
+	Activation []string    `json:"activation,omitempty"` // commands that apply the changes to the current shell
+	Manual     []string    `json:"manual,omitempty"`     // lines for the user to add to ManualFile, for --no-path
+	ManualFile string      `json:"manualFile,omitempty"` // the config file Shell reads them from
+}
+
+// SummarizeEnvChanges collects the environment changes from install results
+// and picks activation commands for the user's shell.
+func SummarizeEnvChanges(results []InstallResult) EnvSummary {
+	var changes []EnvChange
+	for _, r := range results {
+		changes = append(changes, r.EnvChanges...)
+	}
+	home, _ := os.UserHomeDir()
+	return summarizeEnvChanges(changes, runtime.GOOS, os.Getenv("SHELL"), home)
+}
+
+func summarizeEnvChanges(changes []EnvChange, goos, shellPath, home string) EnvSummary {
+	s := EnvSummary{Shell: detectShell(goos, shellPath), Changes: []EnvChange{}}
+	var manual []EnvChange
+	for _, c := range changes {
+		if c.Manual {
+			manual = append(manual, c)
+			continue
+		}
+		c.File = displayPath(c.File, home)
+		s.Changes = append(s.Changes, c)
+	}
+	if len(manual) > 0 {
+		s.Manual = manualLines(s.Shell, manual)
+		s.ManualFile = manualFile(s.Shell)
+	}
+
+	switch s.Shell {
+	case "powershell":
+		s.Activation = powershellActivation(s.Changes)
+	case "fish":
+		s.Activation = fishActivation(s.Changes)
+	default:
+		s.Activation = posixActivation(s.Shell, s.Changes)
+	}
+	return s
+}
+
+// manualLines returns the lines that make changes in shell's config file,
+// as the tool would have written them.
+func manualLines(shell string, changes []EnvChange) []string {
+	var lines []string
+	for _, c := range changes {
+		var line string
+		switch {
+		case shell == "powershell" && c.Kind == ChangePath:
+			line = profileLine(c.Value)
+		case shell == "powershell":
+			line = fmt.Sprintf(`$env:%s = '%s'`, c.Name, strings.ReplaceAll(c.Value, "'", "''"))
+		case c.Kind == ChangePath:
+			line = pathLine(c.Value, shell == "fish")
+		default:
+			line = envLine(c.Name, c.Value, shell == "fish")
+		}
+		if !containsString(lines, line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// manualFile names the config file shell reads at startup, for the
+// manual lines.
+func manualFile(shell string) string {
+	switch shell {
+	case "powershell":
+		return "$PROFILE"
+	case "zsh":
+		return "~/.zshrc"
+	case "bash":
+		return "~/.bashrc"
+	case "fish":
+		return "~/" + filepath.ToSlash(fishConfigFile)
+	}
+	return "~/.profile"
+}
+
+// fishActivation sources config.fish if we modified it. fish doesn't read
+// the other rc files, so changes found only there (from before fish was
+// detected, or already present) are applied directly.
+func fishActivation(changes []EnvChange) []string {
+	rcFile := "~/" + filepath.ToSlash(fishConfigFile)
+	var cmds []string
+	for _, c := range changes {
+		if !c.Unchanged && c.File == rcFile {
+			cmds = append(cmds, "source "+rcFile)
+			break
+		}
+	}
+	inFish := make(map[string]bool) // values set by sourcing rcFile
+	for _, c := range changes {
+		if c.File == rcFile {
+			inFish[c.Kind+c.Name+c.Value] = true
+		}
+	}
+	for _, c := range changes {
+		if inFish[c.Kind+c.Name+c.Value] {
+			continue
+		}
+		if c.Kind == ChangePath {
+			cmds = append(cmds, fmt.Sprintf("fish_add_path -U %s", c.Value))
+		} else {
+			cmds = append(cmds, fmt.Sprintf("set -Ux %s %q", c.Name, c.Value))
+		}
+	}
+	return cmds
+}
+
+// Modified reports whether anything was written.
+func (s EnvSummary) Modified() bool {
+	for _, c := range s.Changes {
+		if !c.Unchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Lines describes each change in plain text, one per line.
+func (s EnvSummary) Lines() []string {
+	var lines []string
+	for _, c := range s.Changes {
+		switch {
+		case c.Unchanged && c.Kind == ChangePath:
+			lines = append(lines, fmt.Sprintf("PATH already includes %s - no changes needed", c.Value))
+		case c.Unchanged:
+			lines = append(lines, fmt.Sprintf("%s is already set - no changes needed", c.Name))
+		case c.Registry != "" && c.Kind == ChangePath:
+			lines = append(lines, fmt.Sprintf("Added %s to %s", c.Value, c.Registry))
+		case c.Registry != "":
+			lines = append(lines, fmt.Sprintf("Set %s = %s", c.Registry, c.Value))
+		default:
+			lines = append(lines, fmt.Sprintf("Added to %s: %s", c.File, c.Line))
+		}
+	}
+	return lines
+}
+
+// detectShell names the user's shell from $SHELL, or powershell on Windows.
+func detectShell(goos, shellPath string) string {
+	if goos == "windows" {
+		return "powershell"
+	}
+	switch name := filepath.Base(shellPath); name {
+	case "zsh", "bash", "fish":
+		return name
+	}
+	return "sh"
+}
+
+// posixActivation sources the rc file the shell reads if we modified it;
+// lines written only to other files are repeated verbatim, which works in
+// any POSIX shell.
+func posixActivation(shell string, changes []EnvChange) []string {
+	rcFile := map[string]string{"zsh": "~/.zshrc", "bash": "~/.bashrc"}[shell]
+
+	sourced := make(map[string]bool) // lines applied by sourcing rcFile
+	for _, c := range changes {
+		if !c.Unchanged && rcFile != "" && c.File == rcFile {
+			sourced[c.Line] = true
+		}
+	}
+
+	var cmds []string
+	if len(sourced) > 0 {
+		cmds = append(cmds, "source "+rcFile)
+	}
+	for _, c := range changes {
+		if c.Unchanged || c.Line == "" || sourced[c.Line] || containsString(cmds, c.Line) {
+			continue
+		}
+		cmds = append(cmds, c.Line)
+	}
+	return cmds
+}
+
+// powershellActivation reloads PATH from the user environment, or runs
+// the profile again for PATH lines written to it when the user environment
+// couldn't be changed, and sets the env vars.
+func powershellActivation(changes []EnvChange) []string {
+	var cmds []string
+	pathRefreshed, profileRun := false, false
+	for _, c := range changes {
+		if c.Unchanged {
+			continue
+		}
+		if c.Kind == ChangePath {
+			switch {
+			case c.File != "" && !profileRun:
+				cmds = append(cmds, ". $PROFILE")
+				profileRun = true
+			case strings.HasPrefix(c.Registry, windowsEnvKey) && !pathRefreshed:
+				cmds = append(cmds, `$env:Path = [Environment]::GetEnvironmentVariable("Path", "User") + ";" + [Environment]::GetEnvironmentVariable("Path", "Machine")`)
+				pathRefreshed = true
+			}
+			continue
+		}
+		cmds = append(cmds, fmt.Sprintf(`$env:%s = "%s"`, c.Name, c.Value))
+	}
+	return cmds
+}
+
+// displayPath shortens paths under home to ~/...
+func displayPath(path, home string) string {
+	if home != "" && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package install
+
+import (
+	"strings"
+	"testing"
+)
+
+func unixChanges(home string) []EnvChange {
+	return []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: home + "/.templatr/runtimes/node/bin", File: home + "/.zshrc", Line: `export PATH="` + home + `/.templatr/runtimes/node/bin:$PATH"`},
+		{Kind: ChangePath, Name: "PATH", Value: home + "/.templatr/runtimes/node/bin", File: home + "/.bashrc", Line: `export PATH="` + home + `/.templatr/runtimes/node/bin:$PATH"`},
+		{Kind: ChangeEnv, Name: "JAVA_HOME", Value: home + "/.templatr/runtimes/java", File: home + "/.profile", Line: `export JAVA_HOME="` + home + `/.templatr/runtimes/java"`},
+	}
+}
+
+func TestSummarizeEnvChanges_Shells(t *testing.T) {
+	const home = "/home/dev"
+	nodeLine := `export PATH="/home/dev/.templatr/runtimes/node/bin:$PATH"`
+	javaLine := `export JAVA_HOME="/home/dev/.templatr/runtimes/java"`
+
+	tests := []struct {
+		name      string
+		goos      string
+		shellPath string
+		wantShell string
+		want      []string
+	}{
+		{"zsh", "darwin", "/bin/zsh", "zsh", []string{"source ~/.zshrc", javaLine}},
+		{"bash", "linux", "/usr/bin/bash", "bash", []string{"source ~/.bashrc", javaLine}},
+		{"sh fallback", "linux", "/bin/dash", "sh", []string{nodeLine, javaLine}},
+		{"no SHELL", "linux", "", "sh", []string{nodeLine, javaLine}},
+		{"fish", "linux", "/usr/local/bin/fish", "fish", []string{
+			"fish_add_path -U /home/dev/.templatr/runtimes/node/bin",
+			"fish_add_path -U /home/dev/.templatr/runtimes/node/bin",
+			`set -Ux JAVA_HOME "/home/dev/.templatr/runtimes/java"`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := summarizeEnvChanges(unixChanges(home), tt.goos, tt.shellPath, home)
+			if s.Shell != tt.wantShell {
+				t.Errorf("shell: got %q, want %q", s.Shell, tt.wantShell)
+			}
+			if strings.Join(s.Activation, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("activation:\ngot  %q\nwant %q", s.Activation, tt.want)
+			}
+			if !s.Modified() {
+				t.Error("expected Modified to be true")
+			}
+		})
+	}
+}
+
+func TestSummarizeEnvChanges_FishConfig(t *testing.T) {
+	const home = "/home/dev"
+	config := home + "/.config/fish/config.fish"
+	changes := []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: home + "/.templatr/runtimes/node/current/bin", File: home + "/.bashrc", Line: `export PATH="/home/dev/.templatr/runtimes/node/current/bin:$PATH"`},
+		{Kind: ChangePath, Name: "PATH", Value: home + "/.templatr/runtimes/node/current/bin", File: config, Line: `fish_add_path -g "/home/dev/.templatr/runtimes/node/current/bin"`},
+		{Kind: ChangePath, Name: "PATH", Value: home + "/.templatr/runtimes/go/current/bin", File: home + "/.bashrc", Line: `export PATH="/home/dev/.templatr/runtimes/go/current/bin:$PATH"`},
+	}
+	s := summarizeEnvChanges(changes, "linux", "/usr/bin/fish", home)
+	want := []string{
+		"source ~/.config/fish/config.fish",
+		"fish_add_path -U /home/dev/.templatr/runtimes/go/current/bin",
+	}
+	if strings.Join(s.Activation, "\n") != strings.Join(want, "\n") {
+		t.Errorf("activation:\ngot  %q\nwant %q", s.Activation, want)
+	}
+}
+
+func TestSummarizeEnvChanges_Lines(t *testing.T) {
+	s := summarizeEnvChanges(unixChanges("/home/dev"), "linux", "/bin/bash", "/home/dev")
+	want := []string{
+		`Added to ~/.zshrc: export PATH="/home/dev/.templatr/runtimes/node/bin:$PATH"`,
+		`Added to ~/.bashrc: export PATH="/home/dev/.templatr/runtimes/node/bin:$PATH"`,
+		`Added to ~/.profile: export JAVA_HOME="/home/dev/.templatr/runtimes/java"`,
+	}
+	if got := s.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSummarizeEnvChanges_Windows(t *testing.T) {
+	changes := []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: `C:\Users\dev\.templatr\runtimes\node`, Registry: windowsEnvKey + `\PATH`},
+		{Kind: ChangePath, Name: "PATH", Value: `C:\Users\dev\.templatr\runtimes\python`, Registry: windowsEnvKey + `\PATH`},
+		{Kind: ChangeEnv, Name: "JAVA_HOME", Value: `C:\Users\dev\.templatr\runtimes\java`, Registry: windowsEnvKey + `\JAVA_HOME`},
+	}
+	s := summarizeEnvChanges(changes, "windows", "", `C:\Users\dev`)
+
+	if s.Shell != "powershell" {
+		t.Errorf("shell: got %q", s.Shell)
+	}
+	if len(s.Activation) != 2 {
+		t.Fatalf("expected one PATH refresh and one variable, got %q", s.Activation)
+	}
+	if !strings.HasPrefix(s.Activation[0], "$env:Path = ") {
+		t.Errorf("expected PATH refresh first, got %q", s.Activation[0])
+	}
+	if s.Activation[1] != `$env:JAVA_HOME = "C:\Users\dev\.templatr\runtimes\java"` {
+		t.Errorf("unexpected variable command %q", s.Activation[1])
+	}
+
+	lines := s.Lines()
+	if lines[0] != `Added C:\Users\dev\.templatr\runtimes\node to HKCU\Environment\PATH` {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+	if lines[2] != `Set HKCU\Environment\JAVA_HOME = C:\Users\dev\.templatr\runtimes\java` {
+		t.Errorf("unexpected line %q", lines[2])
+	}
+}
+
+func TestSummarizeEnvChanges_WindowsProfile(t *testing.T) {
+	profile := `D:\Documents\WindowsPowerShell\Microsoft.PowerShell_profile.ps1`
+	changes := []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: `C:\Users\dev\.templatr\runtimes\node`, File: profile,
+			Line: `$env:PATH = 'C:\Users\dev\.templatr\runtimes\node;' + $env:PATH`},
+		{Kind: ChangePath, Name: "PATH", Value: `C:\Users\dev\.templatr\runtimes\node`,
+			Registry: `HKCU\Software\Microsoft\Command Processor\AutoRun`},
+	}
+	s := summarizeEnvChanges(changes, "windows", "", `C:\Users\dev`)
+
+	if len(s.Activation) != 1 || s.Activation[0] != ". $PROFILE" {
+		t.Errorf("expected the profile to be run again, got %q", s.Activation)
+	}
+	want := []string{
+		`Added to ` + profile + `: $env:PATH = 'C:\Users\dev\.templatr\runtimes\node;' + $env:PATH`,
+		`Added C:\Users\dev\.templatr\runtimes\node to HKCU\Software\Microsoft\Command Processor\AutoRun`,
+	}
+	if got := s.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSummarizeEnvChanges_Unchanged(t *testing.T) {
+	changes := []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: "/home/dev/.templatr/runtimes/go/bin", Unchanged: true},
+		{Kind: ChangeEnv, Name: "GOROOT", Value: "/home/dev/.templatr/runtimes/go", Unchanged: true},
+	}
+
+	for _, shell := range []string{"/bin/zsh", "/bin/sh"} {
+		s := summarizeEnvChanges(changes, "linux", shell, "/home/dev")
+		if s.Modified() {
+			t.Errorf("%s: expected Modified to be false", shell)
+		}
+		if len(s.Activation) != 0 {
+			t.Errorf("%s: expected no activation commands, got %q", shell, s.Activation)
+		}
+		lines := s.Lines()
+		if lines[0] != "PATH already includes /home/dev/.templatr/runtimes/go/bin - no changes needed" ||
+			lines[1] != "GOROOT is already set - no changes needed" {
+			t.Errorf("%s: unexpected lines %q", shell, lines)
+		}
+	}
+
+	if s := summarizeEnvChanges(changes, "windows", "", ""); len(s.Activation) != 0 {
+		t.Errorf("windows: expected no activation commands, got %q", s.Activation)
+	}
+}
+
+func TestSummarizeEnvChanges_Manual(t *testing.T) {
+	changes := []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: "/home/dev/.templatr/runtimes/java/current/bin", Manual: true},
+		{Kind: ChangeEnv, Name: "JAVA_HOME", Value: "/home/dev/.templatr/runtimes/java/current", Manual: true},
+		{Kind: ChangePath, Name: "PATH", Value: "/home/dev/.templatr/runtimes/go/current/bin", Manual: true},
+		{Kind: ChangeEnv, Name: "GOROOT", Value: "/home/dev/.templatr/runtimes/go/current", Manual: true},
+	}
+	posix := []string{
+		`export PATH="/home/dev/.templatr/runtimes/java/current/bin:$PATH"`,
+		`export JAVA_HOME="/home/dev/.templatr/runtimes/java/current"`,
+		`export PATH="/home/dev/.templatr/runtimes/go/current/bin:$PATH"`,
+		`export GOROOT="/home/dev/.templatr/runtimes/go/current"`,
+	}
+
+	tests := []struct {
+		name      string
+		shellPath string
+		wantFile  string
+		want      []string
+	}{
+		{"zsh", "/bin/zsh", "~/.zshrc", posix},
+		{"bash", "/bin/bash", "~/.bashrc", posix},
+		{"sh", "/bin/dash", "~/.profile", posix},
+		{"fish", "/usr/bin/fish", "~/.config/fish/config.fish", []string{
+			`fish_add_path -g "/home/dev/.templatr/runtimes/java/current/bin"`,
+			`set -gx JAVA_HOME "/home/dev/.templatr/runtimes/java/current"`,
+			`fish_add_path -g "/home/dev/.templatr/runtimes/go/current/bin"`,
+			`set -gx GOROOT "/home/dev/.templatr/runtimes/go/current"`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := summarizeEnvChanges(changes, "linux", tt.shellPath, "/home/dev")
+			if s.ManualFile != tt.wantFile {
+				t.Errorf("manual file: got %q, want %q", s.ManualFile, tt.wantFile)
+			}
+			if strings.Join(s.Manual, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("manual:\ngot  %q\nwant %q", s.Manual, tt.want)
+			}
+			if len(s.Changes) != 0 || len(s.Activation) != 0 || s.Modified() {
+				t.Errorf("manual changes must not be reported as made, got %+v", s)
+			}
+		})
+	}
+}
+
+func TestSummarizeEnvChanges_ManualPowerShell(t *testing.T) {
+	changes := []EnvChange{
+		{Kind: ChangePath, Name: "PATH", Value: `C:\Users\o'neil\.templatr\runtimes\java\current\bin`, Manual: true},
+		{Kind: ChangeEnv, Name: "JAVA_HOME", Value: `C:\Users\o'neil\.templatr\runtimes\java\current`, Manual: true},
+	}
+	s := summarizeEnvChanges(changes, "windows", "", `C:\Users\o'neil`)
+
+	want := []string{
+		`$env:PATH = 'C:\Users\o''neil\.templatr\runtimes\java\current\bin;' + $env:PATH`,
+		`$env:JAVA_HOME = 'C:\Users\o''neil\.templatr\runtimes\java\current'`,
+	}
+	if s.ManualFile != "$PROFILE" {
+		t.Errorf("manual file: got %q, want $PROFILE", s.ManualFile)
+	}
+	if strings.Join(s.Manual, "\n") != strings.Join(want, "\n") {
+		t.Errorf("manual:\ngot  %q\nwant %q", s.Manual, want)
+	}
+}
+
+func TestSummarizeEnvChanges_ManualAlongsideWritten(t *testing.T) {
+	changes := append(unixChanges("/home/dev"),
+		EnvChange{Kind: ChangePath, Name: "PATH", Value: "/home/dev/.templatr/runtimes/go/current/bin", Manual: true})
+	s := summarizeEnvChanges(changes, "linux", "/bin/bash", "/home/dev")
+
+	if len(s.Changes) != 3 {
+		t.Errorf("expected the three written changes, got %+v", s.Changes)
+	}
+	if len(s.Manual) != 1 || s.Manual[0] != `export PATH="/home/dev/.templatr/runtimes/go/current/bin:$PATH"` {
+		t.Errorf("unexpected manual lines %q", s.Manual)
+	}
+	if containsString(s.Activation, s.Manual[0]) {
+		t.Errorf("manual lines must not be in the activation commands, got %q", s.Activation)
+	}
+}
@@ -0,0 +1,57 @@
+package install
+
+import "context"
+
+// The phases of an install a ProgressEvent reports, in the order an install
+// goes through them. Verifying and extracting are skipped by installers
+// that have nothing to verify or extract.
+const (
+	PhaseResolving   = "resolving"   // picking the version to install
+	PhaseDownloading = "downloading" // Done and Total are bytes
+	PhaseVerifying   = "verifying"   // checking the archive's checksum
+	PhaseExtracting  = "extracting"  // Done and Total are files; Total is 0 for tar archives
+	PhaseLinking     = "linking"     // putting the runtime on PATH and recording it
+)
+
+// ProgressEvent is a step of an install, reported as it happens. A phase
+// starts with its first event; downloading and extracting send more as
+// they go.
+type ProgressEvent struct {
+	Phase string
+	Done  int64
+	Total int64 // 0 if unknown
+}
+
+// EventFunc is called with each ProgressEvent of an install. A nil EventFunc
+// reports nothing.
+type EventFunc func(ProgressEvent)
+
+func (e EventFunc) emit(ev ProgressEvent) {
+	if e != nil {
+		e(ev)
+	}
+}
+
+// downloading is a ProgressFunc reporting the bytes of a download as
+// PhaseDownloading events.
+func (e EventFunc) downloading(downloaded, total int64) {
+	e.emit(ProgressEvent{Phase: PhaseDownloading, Done: downloaded, Total: total})
+}
+
+type eventsKey struct{}
+
+// withEvents returns ctx carrying events, for the steps an Installer runs
+// that only get a context, such as verifying and extracting its archive.
+func withEvents(ctx context.Context, events EventFunc) context.Context {
+	if events == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, eventsKey{}, events)
+}
+
+// reportEvent sends ev to the EventFunc ctx carries, if any.
+func reportEvent(ctx context.Context, ev ProgressEvent) {
+	if events, ok := ctx.Value(eventsKey{}).(EventFunc); ok {
+		events(ev)
+	}
+}
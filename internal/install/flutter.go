@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -34,18 +35,27 @@ type flutterRelease struct {
 	SHA256  string `json:"sha256"`
 }
 
-func (f *FlutterInstaller) ResolveVersion(requirement string) (string, error) {
-	platform := flutterPlatform()
-	url := fmt.Sprintf("https://storage.googleapis.com/flutter_infra_release/releases/releases_%s.json", platform)
+// flutterReleaseIndex fetches the Flutter release index for this platform,
+// newest first.
+func flutterReleaseIndex() (*flutterReleases, error) {
+	url := runtimeURL("flutter", fmt.Sprintf("https://storage.googleapis.com/flutter_infra_release/releases/releases_%s.json", flutterPlatform()))
 
 	data, err := FetchJSON(url)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch Flutter releases: %w", err)
+		return nil, fmt.Errorf("failed to fetch Flutter releases: %w", err)
 	}
 
 	var releases flutterReleases
 	if err := json.Unmarshal(data, &releases); err != nil {
-		return "", fmt.Errorf("failed to parse Flutter releases: %w", err)
+		return nil, fmt.Errorf("failed to parse Flutter releases: %w", err)
+	}
+	return &releases, nil
+}
+
+func (f *FlutterInstaller) ResolveVersion(requirement string) (string, error) {
+	releases, err := flutterReleaseIndex()
+	if err != nil {
+		return "", err
 	}
 
 	// Filter to stable releases
@@ -82,17 +92,28 @@ func (f *FlutterInstaller) ResolveVersion(requirement string) (string, error) {
 	return stable[0].Version, nil
 }
 
-func (f *FlutterInstaller) Install(version, targetDir string, progress ProgressFunc) error {
-	platform := flutterPlatform()
-	url := fmt.Sprintf("https://storage.googleapis.com/flutter_infra_release/releases/releases_%s.json", platform)
-
-	data, err := FetchJSON(url)
+// ListVersions lists the Flutter releases with their channel. Only stable
+// releases can be installed.
+func (f *FlutterInstaller) ListVersions() ([]AvailableVersion, error) {
+	releases, err := flutterReleaseIndex()
 	if err != nil {
-		return fmt.Errorf("failed to fetch Flutter releases: %w", err)
+		return nil, err
+	}
+	var versions []AvailableVersion
+	seen := map[string]bool{} // macOS lists each release once per architecture
+	for _, r := range releases.Releases {
+		if seen[r.Version+"/"+r.Channel] {
+			continue
+		}
+		seen[r.Version+"/"+r.Channel] = true
+		versions = append(versions, AvailableVersion{Version: r.Version, Channel: r.Channel})
 	}
+	return versions, nil
+}
 
-	var releases flutterReleases
-	if err := json.Unmarshal(data, &releases); err != nil {
+func (f *FlutterInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	releases, err := flutterReleaseIndex()
+	if err != nil {
 		return err
 	}
 
@@ -109,25 +130,19 @@ func (f *FlutterInstaller) Install(version, targetDir string, progress ProgressF
 		return fmt.Errorf("Flutter %s not found in stable releases", version)
 	}
 
-	downloadURL := releases.BaseURL + "/" + target.Archive
+	downloadURL := runtimeURL("flutter", releases.BaseURL+"/"+target.Archive)
 	filename := filepath.Base(target.Archive)
 
 	tmpFile := filepath.Join(os.TempDir(), filename)
 	defer os.Remove(tmpFile)
 
-	if err := DownloadFile(downloadURL, tmpFile, progress); err != nil {
+	// Download (or reuse from the cache) and verify checksum
+	if err := fetchArchive(ctx, f.Name(), downloadURL, tmpFile, progress, func() (string, error) { return target.SHA256, nil }); err != nil {
 		return fmt.Errorf("failed to download Flutter: %w", err)
 	}
 
-	// Verify checksum
-	if target.SHA256 != "" {
-		if err := VerifyChecksum(tmpFile, target.SHA256); err != nil {
-			return fmt.Errorf("Flutter checksum verification failed: %w", err)
-		}
-	}
-
 	// Extract - Flutter archive has a "flutter/" top-level dir
-	if err := ExtractAndFlatten(tmpFile, targetDir); err != nil {
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
 		return fmt.Errorf("failed to extract Flutter: %w", err)
 	}
 
@@ -140,6 +155,20 @@ func (f *FlutterInstaller) BinDir(installDir string) string {
 
 func (f *FlutterInstaller) EnvVars(installDir string) map[string]string { return nil }
 
+// ArchivePattern matches the flutter.dev stable archive for offline installs,
+// e.g. flutter_linux_3.24.3-stable.tar.xz or flutter_macos_arm64_3.24.3-stable.zip.
+func (f *FlutterInstaller) ArchivePattern(version string) (string, error) {
+	platform := flutterPlatform()
+	if platform == "macos" && runtime.GOARCH == "arm64" {
+		platform += "_arm64"
+	}
+	ext := "zip"
+	if platform == "linux" {
+		ext = "tar.xz"
+	}
+	return fmt.Sprintf("flutter_%s_%s-stable.%s", platform, version, ext), nil
+}
+
 func flutterPlatform() string {
 	switch runtime.GOOS {
 	case "darwin":
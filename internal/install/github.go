@@ -0,0 +1,84 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GitHubToken returns the token to authenticate GitHub API requests with,
+// from TEMPLATR_GITHUB_TOKEN or else GITHUB_TOKEN (set on most CI runners).
+// Unauthenticated requests are limited to 60 an hour per IP address.
+func GitHubToken() string {
+	if token := os.Getenv("TEMPLATR_GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
+
+// NewGitHubRequest builds a GET request for the GitHub API, authenticated
+// when a token is set in the environment.
+func NewGitHubRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if token := GitHubToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// GitHubResponseError describes a failed GitHub API response. Rate limiting
+// gets an error that says when the limit resets and how to raise it.
+func GitHubResponseError(resp *http.Response) error {
+	url := resp.Request.URL.String()
+	limited := (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if !limited {
+		return statusError(resp.StatusCode, fmt.Errorf("HTTP %d from %s", resp.StatusCode, url))
+	}
+
+	msg := "GitHub API rate limit exceeded"
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		msg += fmt.Sprintf(" (resets at %s)", time.Unix(reset, 0).Format("15:04 MST"))
+	}
+	if GitHubToken() == "" {
+		msg += "; set GITHUB_TOKEN or TEMPLATR_GITHUB_TOKEN to a GitHub token to raise the limit"
+	}
+	// Retrying before the reset won't help, so this is not transient
+	return statusError(resp.StatusCode, fmt.Errorf("%s: %s", msg, url))
+}
+
+// FetchGitHubJSON is FetchJSON for the GitHub API: requests carry a token
+// when one is set, and rate limiting produces a helpful error.
+func FetchGitHubJSON(url string) ([]byte, error) {
+	var body []byte
+	err := withRetry(context.Background(), url, func() error {
+		req, err := NewGitHubRequest(context.Background(), url)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return transient(fmt.Errorf("failed to fetch %s: %w", url, err))
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return GitHubResponseError(resp)
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return transient(fmt.Errorf("failed to read %s: %w", url, err))
+		}
+		return nil
+	})
+	return body, err
+}
@@ -0,0 +1,87 @@
+package install
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchGitHubJSON_SendsToken(t *testing.T) {
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		templatr, github string
+		want             string
+	}{
+		{"", "", ""},
+		{"", "ci-token", "Bearer ci-token"},
+		{"my-token", "ci-token", "Bearer my-token"},
+	}
+	for _, tt := range tests {
+		t.Setenv("TEMPLATR_GITHUB_TOKEN", tt.templatr)
+		t.Setenv("GITHUB_TOKEN", tt.github)
+		if _, err := FetchGitHubJSON(ts.URL); err != nil {
+			t.Fatalf("FetchGitHubJSON() error: %s", err)
+		}
+		if auth != tt.want {
+			t.Errorf("TEMPLATR_GITHUB_TOKEN=%q GITHUB_TOKEN=%q: Authorization = %q, want %q", tt.templatr, tt.github, auth, tt.want)
+		}
+	}
+}
+
+func TestFetchGitHubJSON_RateLimited(t *testing.T) {
+	t.Setenv("TEMPLATR_GITHUB_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+
+	reset := time.Now().Add(20 * time.Minute).Unix()
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	_, err := FetchGitHubJSON(ts.URL)
+	if err == nil {
+		t.Fatal("expected a rate limit error")
+	}
+	resetAt := time.Unix(reset, 0).Format("15:04 MST")
+	for _, want := range []string{"rate limit exceeded", "resets at " + resetAt, "GITHUB_TOKEN", "TEMPLATR_GITHUB_TOKEN"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %q", err, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("rate limited requests should not be retried, got %d calls", calls)
+	}
+
+	// With a token set there's nothing more to suggest
+	t.Setenv("GITHUB_TOKEN", "ci-token")
+	_, err = FetchGitHubJSON(ts.URL)
+	if err == nil || strings.Contains(err.Error(), "set GITHUB_TOKEN") {
+		t.Errorf("error with a token set should not suggest setting one, got %v", err)
+	}
+}
+
+func TestFetchGitHubJSON_Forbidden(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	_, err := FetchGitHubJSON(ts.URL)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 403") || strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("a 403 that isn't rate limiting should be reported as is, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -32,15 +33,24 @@ type goFile struct {
 	Kind     string `json:"kind"` // "archive", "installer", "source"
 }
 
-func (g *GoInstaller) ResolveVersion(requirement string) (string, error) {
-	data, err := FetchJSON("https://go.dev/dl/?mode=json")
+// goReleases fetches the Go release index from go.dev, newest first.
+func goReleases() ([]goVersion, error) {
+	data, err := FetchJSON(runtimeURL("go", "https://go.dev/dl/?mode=json"))
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch Go versions: %w", err)
+		return nil, fmt.Errorf("failed to fetch Go versions: %w", err)
 	}
 
 	var versions []goVersion
 	if err := json.Unmarshal(data, &versions); err != nil {
-		return "", fmt.Errorf("failed to parse Go versions: %w", err)
+		return nil, fmt.Errorf("failed to parse Go versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (g *GoInstaller) ResolveVersion(requirement string) (string, error) {
+	versions, err := goReleases()
+	if err != nil {
+		return "", err
 	}
 
 	// Filter to stable versions
@@ -78,60 +88,44 @@ func (g *GoInstaller) ResolveVersion(requirement string) (string, error) {
 	return goVersionClean(stable[0].Version), nil
 }
 
-func (g *GoInstaller) Install(version, targetDir string, progress ProgressFunc) error {
-	data, err := FetchJSON("https://go.dev/dl/?mode=json")
+// ListVersions lists the Go releases on go.dev with their channel.
+func (g *GoInstaller) ListVersions() ([]AvailableVersion, error) {
+	releases, err := goReleases()
 	if err != nil {
-		return fmt.Errorf("failed to fetch Go versions: %w", err)
-	}
-
-	var versions []goVersion
-	if err := json.Unmarshal(data, &versions); err != nil {
-		return err
-	}
-
-	// Find the version
-	goVer := "go" + version
-	var target *goVersion
-	for i, v := range versions {
-		if v.Version == goVer {
-			target = &versions[i]
-			break
-		}
+		return nil, err
 	}
-
-	if target == nil {
-		return fmt.Errorf("Go %s not found in release list", version)
-	}
-
-	// Find the archive for our platform
-	var file *goFile
-	for i, f := range target.Files {
-		if f.OS == runtime.GOOS && f.Arch == runtime.GOARCH && f.Kind == "archive" {
-			file = &target.Files[i]
-			break
+	versions := make([]AvailableVersion, 0, len(releases))
+	for _, r := range releases {
+		channel := "unstable"
+		if r.Stable {
+			channel = "stable"
 		}
+		versions = append(versions, AvailableVersion{Version: goVersionClean(r.Version), Channel: channel})
 	}
+	return versions, nil
+}
 
-	if file == nil {
-		return fmt.Errorf("no Go %s archive found for %s/%s", version, runtime.GOOS, runtime.GOARCH)
+func (g *GoInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	file, err := goArchive(version)
+	if err != nil {
+		return err
 	}
 
-	downloadURL := "https://go.dev/dl/" + file.Filename
+	downloadURL := runtimeURL("go", "https://go.dev/dl/"+file.Filename)
 	tmpFile := filepath.Join(os.TempDir(), file.Filename)
 	defer os.Remove(tmpFile)
 
-	if err := DownloadFile(downloadURL, tmpFile, progress); err != nil {
+	if err := fetchArchive(ctx, g.Name(), downloadURL, tmpFile, progress, func() (string, error) { return file.SHA256, nil }); err != nil {
 		return fmt.Errorf("failed to download Go: %w", err)
 	}
-
-	if file.SHA256 != "" {
-		if err := VerifyChecksum(tmpFile, file.SHA256); err != nil {
-			return fmt.Errorf("Go checksum verification failed: %w", err)
+	if verifySignatures {
+		if err := verifyFileSignature(g.Name(), tmpFile, runtimeURL("go", "https://go.dev/dl/"+file.Filename+".asc")); err != nil {
+			return fmt.Errorf("failed to verify Go: %w", err)
 		}
 	}
 
 	// Go archives have a "go/" top-level directory
-	if err := ExtractAndFlatten(tmpFile, targetDir); err != nil {
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
 		return fmt.Errorf("failed to extract Go: %w", err)
 	}
 
@@ -148,6 +142,56 @@ func (g *GoInstaller) EnvVars(installDir string) map[string]string {
 	}
 }
 
+// ArchivePattern matches the go.dev archive for offline installs.
+func (g *GoInstaller) ArchivePattern(version string) (string, error) {
+	return fmt.Sprintf("go%s.%s-%s.%s", version, runtime.GOOS, runtime.GOARCH, PlatformExt()), nil
+}
+
+// DownloadSize returns the archive size listed in go.dev's release metadata.
+func (g *GoInstaller) DownloadSize(version string) (int64, error) {
+	file, err := goArchive(version)
+	if err != nil {
+		return 0, err
+	}
+	return file.Size, nil
+}
+
+// goArchive looks up the archive of Go version for the current platform.
+func goArchive(version string) (*goFile, error) {
+	versions, err := goReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the version
+	goVer := "go" + version
+	var target *goVersion
+	for i, v := range versions {
+		if v.Version == goVer {
+			target = &versions[i]
+			break
+		}
+	}
+
+	if target == nil {
+		return nil, fmt.Errorf("Go %s not found in release list", version)
+	}
+
+	// Find the archive for our platform
+	var file *goFile
+	for i, f := range target.Files {
+		if f.OS == runtime.GOOS && f.Arch == runtime.GOARCH && f.Kind == "archive" {
+			file = &target.Files[i]
+			break
+		}
+	}
+
+	if file == nil {
+		return nil, fmt.Errorf("no Go %s archive found for %s/%s", version, runtime.GOOS, runtime.GOARCH)
+	}
+	return file, nil
+}
+
 // goVersionClean removes the "go" prefix from version strings.
 func goVersionClean(v string) string {
 	return strings.TrimPrefix(v, "go")
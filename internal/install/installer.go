@@ -1,8 +1,12 @@
 package install
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/templatr/templatr-setup/internal/engine"
 	"github.com/templatr/templatr-setup/internal/logger"
@@ -20,7 +24,8 @@ type Installer interface {
 
 	// Install downloads and installs the runtime to targetDir.
 	// targetDir is the final location, e.g. ~/.templatr/runtimes/node/22.14.0/
-	Install(version, targetDir string, progress ProgressFunc) error
+	// Cancelling ctx aborts the download or extraction with ErrCancelled.
+	Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error
 
 	// BinDir returns the path to the directory containing executables
 	// within an installation directory.
@@ -32,6 +37,14 @@ type Installer interface {
 	EnvVars(installDir string) map[string]string
 }
 
+// ErrCancelled is returned (possibly wrapped) when an installation is
+// stopped by cancelling its context, e.g. ctrl+c in the TUI.
+var ErrCancelled = errors.New("installation cancelled")
+
+// ErrResolve is returned (wrapped) when no version of a runtime could be
+// found for its requirement.
+var ErrResolve = errors.New("failed to resolve version")
+
 // registry holds all registered installers.
 var registry = map[string]Installer{}
 
@@ -55,6 +68,8 @@ func init() {
 	Register(&RubyInstaller{})
 	Register(&PHPInstaller{})
 	Register(&DotnetInstaller{})
+	Register(&BunInstaller{})
+	Register(&DenoInstaller{})
 }
 
 // InstallResult records what was installed for a single runtime.
@@ -63,112 +78,218 @@ type InstallResult struct {
 	Version     string
 	InstallPath string
 	BinDir      string
+	Duration    time.Duration // wall time for resolve, download, and install
+	Bytes       int64         // bytes downloaded
+	EnvChanges  []EnvChange   // PATH and env var modifications made, or left to the user, for this runtime
+}
+
+// ExecuteOptions change how ExecutePlan and InstallSingleRuntime set up
+// the runtimes they install.
+type ExecuteOptions struct {
+	// NoPath leaves shell config files and the user environment alone,
+	// from --no-path: the PATH entries and env vars that would have been
+	// written come back as Manual changes for the user to add.
+	NoPath bool `json:"noPath,omitempty"`
+}
+
+// byteCounter wraps a ProgressFunc and totals bytes across every file an
+// installer downloads (progress restarts at zero for each file).
+type byteCounter struct {
+	next    ProgressFunc
+	done    int64 // bytes from finished files
+	current int64 // bytes so far in the current file
+}
+
+func (c *byteCounter) progress(downloaded, total int64) {
+	if downloaded < c.current {
+		c.done += c.current
+	}
+	c.current = downloaded
+	if c.next != nil {
+		c.next(downloaded, total)
+	}
+}
+
+// Total returns the number of bytes downloaded so far.
+func (c *byteCounter) Total() int64 {
+	return c.done + c.current
 }
 
 // ExecutePlan runs the installation plan: resolves versions, downloads,
-// installs, updates PATH, and records state.
-func ExecutePlan(plan *engine.SetupPlan, log *logger.Logger, progress ProgressFunc) ([]InstallResult, error) {
+// installs, updates PATH, fetches [[downloads]] entries, and records state.
+// Each runtime and download reports its phases to events as it goes.
+// Cancelling ctx stops it at the current download or extract; runtimes
+// already installed stay installed and recorded.
+func ExecutePlan(ctx context.Context, plan *engine.SetupPlan, log *logger.Logger, events EventFunc, opts ExecuteOptions) ([]InstallResult, error) {
+	setInstallLogger(log)
 	runtimesBase, err := RuntimesDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine runtimes directory: %w", err)
 	}
 
-	st, err := state.Load()
-	if err != nil {
-		log.Warn("Could not load state file, starting fresh: %s", err)
-		st = state.NewState()
+	// Fail before anything is downloaded rather than halfway through an extract
+	if issue := checkDiskSpace(plan, runtimesBase); issue != nil {
+		return nil, issue
 	}
 
+	RecordUses(plan, log)
+
 	var results []InstallResult
 
-	for _, rp := range plan.Runtimes {
+	for _, rp := range plan.InstallRuntimes() {
 		if rp.Action == engine.ActionSkip {
 			continue
 		}
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
 
 		installer := GetInstaller(rp.Name)
 		if installer == nil {
 			return results, fmt.Errorf("no installer available for runtime %q", rp.Name)
 		}
 
+		if rp.Relink != "" {
+			result, err := relinkRuntime(installer, rp, plan.Manifest.Template.Slug, log, events, opts)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, *result)
+			continue
+		}
+
 		log.Info("Resolving latest version for %s (requires %s)...", rp.DisplayName, rp.RequiredVersion)
+		events.emit(ProgressEvent{Phase: PhaseResolving})
 
-		version, err := installer.ResolveVersion(rp.RequiredVersion)
+		version, err := resolveVersion(installer, rp)
 		if err != nil {
-			return results, fmt.Errorf("failed to resolve version for %s: %w", rp.DisplayName, err)
+			return results, fmt.Errorf("%w for %s: %w", ErrResolve, rp.DisplayName, err)
 		}
 		log.Info("Will install %s %s", rp.DisplayName, version)
 
 		targetDir := filepath.Join(runtimesBase, rp.Name, version)
 		log.Info("Installing %s %s to %s...", rp.DisplayName, version, targetDir)
 
-		if err := installer.Install(version, targetDir, progress); err != nil {
-			return results, fmt.Errorf("failed to install %s %s: %w", rp.DisplayName, version, err)
-		}
-
-		binDir := installer.BinDir(targetDir)
-		log.Info("Adding %s to PATH...", binDir)
-
-		pathEntry, err := AddToPath(binDir)
-		if err != nil {
-			log.Warn("Failed to add %s to PATH: %s", binDir, err)
-			log.Warn("You may need to manually add %s to your PATH", binDir)
-		} else if pathEntry != nil {
-			st.AddPathModification(*pathEntry)
+		pinChecksum(rp.Name, rp.SHA256)
+		if rp.SHA256 != "" {
+			log.Info("Verifying %s against the checksum pinned in the manifest", rp.DisplayName)
+		} else if rp.ArchivesDir != "" {
+			log.Warn("No checksum pinned for %s in [runtimes_checksums]; its archive will not be verified", rp.DisplayName)
 		}
 
-		// Set runtime-specific env vars (e.g., JAVA_HOME, GOROOT)
-		envVars := installer.EnvVars(targetDir)
-		for envName, envValue := range envVars {
-			log.Info("Setting %s=%s", envName, envValue)
-			envEntry, err := SetEnvVar(envName, envValue)
-			if err != nil {
-				log.Warn("Failed to set %s: %s", envName, err)
-			} else if envEntry != nil {
-				st.AddEnvModification(*envEntry)
-			}
+		counter := &byteCounter{next: events.downloading}
+		if err := installCancellable(withEvents(ctx, events), installer, rp, version, targetDir, counter.progress, log); err != nil {
+			return results, fmt.Errorf("failed to install %s %s: %w", rp.DisplayName, version, err)
 		}
-
-		st.AddInstallation(state.Installation{
-			Runtime:         rp.Name,
-			Version:         version,
-			Path:            targetDir,
-			Template:        plan.Manifest.Template.Slug,
-			Action:          string(rp.Action),
-			PreviousVersion: rp.InstalledVersion,
-			PreviousPath:    rp.InstalledPath,
+		events.emit(ProgressEvent{Phase: PhaseLinking})
+
+		// Each runtime is recorded as soon as it is in place, so a cancel or
+		// failure later on leaves the earlier ones uninstallable
+		var binDir string
+		var envChanges []EnvChange
+		err = state.WithLock(func(st *state.State) error {
+			binDir, envChanges = activateRuntime(installer, rp, targetDir, st, log, opts.NoPath)
+			st.AddInstallation(state.Installation{
+				Runtime:         rp.Name,
+				Version:         version,
+				Path:            targetDir,
+				Template:        plan.Manifest.Template.Slug,
+				Action:          string(rp.Action),
+				PreviousVersion: rp.InstalledVersion,
+				PreviousPath:    rp.InstalledPath,
+				NoPath:          opts.NoPath,
+			})
+			return nil
 		})
+		if err != nil {
+			return results, fmt.Errorf("failed to record %s %s: %w", rp.DisplayName, version, err)
+		}
 
 		results = append(results, InstallResult{
 			Runtime:     rp.Name,
 			Version:     version,
 			InstallPath: targetDir,
 			BinDir:      binDir,
+			Duration:    time.Since(start),
+			Bytes:       counter.Total(),
+			EnvChanges:  envChanges,
 		})
 
 		log.Info("%s %s installed successfully", rp.DisplayName, version)
 	}
 
-	if err := st.Save(); err != nil {
-		log.Warn("Failed to save state file: %s", err)
+	if ctx.Err() != nil {
+		return results, ErrCancelled
+	}
+
+	// Extra downloads run after runtimes and record their own state
+	for _, dp := range plan.Downloads {
+		if dp.Action == engine.ActionSkip {
+			continue
+		}
+		result, err := InstallDownload(ctx, dp, plan.Manifest.Template.Slug, log, events)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *result)
 	}
 
 	return results, nil
 }
 
+// RecordUses notes that the plan's template relies on each runtime it skips
+// because the tool installed it earlier, so uninstalling the template that
+// installed it keeps it. Failing to record this only costs that protection,
+// so it is logged rather than returned.
+func RecordUses(plan *engine.SetupPlan, log *logger.Logger) {
+	slug := plan.Manifest.Template.Slug
+	base, err := RuntimesDir()
+	if slug == "" || err != nil {
+		return
+	}
+	var shared []engine.RuntimePlan
+	for _, rp := range plan.Runtimes {
+		if rp.Action == engine.ActionSkip && rp.InstalledPath != "" && within(rp.InstalledPath, base) {
+			shared = append(shared, rp)
+		}
+	}
+	if len(shared) == 0 {
+		return
+	}
+
+	err = state.WithLock(func(st *state.State) error {
+		for _, rp := range shared {
+			st.AddUse(rp.Name, rp.InstalledPath, slug)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn("Could not record that %s uses runtimes installed earlier: %s", slug, err)
+	}
+}
+
 // InstallSingleRuntime installs one runtime: resolves version, downloads,
-// sets PATH and env vars, and records state. Used by the TUI for per-runtime progress.
-func InstallSingleRuntime(rp engine.RuntimePlan, templateSlug string, log *logger.Logger, progress ProgressFunc) (*InstallResult, error) {
+// sets PATH and env vars, and records state, reporting each phase to events.
+// Used by the TUI for per-runtime progress.
+func InstallSingleRuntime(ctx context.Context, rp engine.RuntimePlan, templateSlug string, log *logger.Logger, events EventFunc, opts ExecuteOptions) (*InstallResult, error) {
+	start := time.Now()
+	setInstallLogger(log)
 	installer := GetInstaller(rp.Name)
 	if installer == nil {
 		return nil, fmt.Errorf("no installer available for runtime %q", rp.Name)
 	}
 
+	if rp.Relink != "" {
+		return relinkRuntime(installer, rp, templateSlug, log, events, opts)
+	}
+
 	log.Info("Resolving version for %s (requires %s)...", rp.DisplayName, rp.RequiredVersion)
+	events.emit(ProgressEvent{Phase: PhaseResolving})
 
-	version, err := installer.ResolveVersion(rp.RequiredVersion)
+	version, err := resolveVersion(installer, rp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve version for %s: %w", rp.DisplayName, err)
+		return nil, fmt.Errorf("%w for %s: %w", ErrResolve, rp.DisplayName, err)
 	}
 	log.Info("Will install %s %s", rp.DisplayName, version)
 
@@ -180,50 +301,37 @@ func InstallSingleRuntime(rp engine.RuntimePlan, templateSlug string, log *logge
 	targetDir := filepath.Join(runtimesBase, rp.Name, version)
 	log.Info("Installing %s %s to %s...", rp.DisplayName, version, targetDir)
 
-	if err := installer.Install(version, targetDir, progress); err != nil {
-		return nil, fmt.Errorf("failed to install %s %s: %w", rp.DisplayName, version, err)
-	}
-
-	binDir := installer.BinDir(targetDir)
-	log.Info("Adding %s to PATH...", binDir)
-
-	// Load state
-	st, stErr := state.Load()
-	if stErr != nil {
-		st = state.NewState()
+	pinChecksum(rp.Name, rp.SHA256)
+	if rp.SHA256 != "" {
+		log.Info("Verifying %s against the checksum pinned in the manifest", rp.DisplayName)
+	} else if rp.ArchivesDir != "" {
+		log.Warn("No checksum pinned for %s in [runtimes_checksums]; its archive will not be verified", rp.DisplayName)
 	}
 
-	pathEntry, err := AddToPath(binDir)
-	if err != nil {
-		log.Warn("Failed to add %s to PATH: %s", binDir, err)
-	} else if pathEntry != nil {
-		st.AddPathModification(*pathEntry)
-	}
-
-	// Set env vars
-	envVars := installer.EnvVars(targetDir)
-	for envName, envValue := range envVars {
-		log.Info("Setting %s=%s", envName, envValue)
-		envEntry, err := SetEnvVar(envName, envValue)
-		if err != nil {
-			log.Warn("Failed to set %s: %s", envName, err)
-		} else if envEntry != nil {
-			st.AddEnvModification(*envEntry)
-		}
+	counter := &byteCounter{next: events.downloading}
+	if err := installCancellable(withEvents(ctx, events), installer, rp, version, targetDir, counter.progress, log); err != nil {
+		return nil, fmt.Errorf("failed to install %s %s: %w", rp.DisplayName, version, err)
 	}
+	events.emit(ProgressEvent{Phase: PhaseLinking})
 
-	st.AddInstallation(state.Installation{
-		Runtime:         rp.Name,
-		Version:         version,
-		Path:            targetDir,
-		Template:        templateSlug,
-		Action:          string(rp.Action),
-		PreviousVersion: rp.InstalledVersion,
-		PreviousPath:    rp.InstalledPath,
+	var binDir string
+	var envChanges []EnvChange
+	err = state.WithLock(func(st *state.State) error {
+		binDir, envChanges = activateRuntime(installer, rp, targetDir, st, log, opts.NoPath)
+		st.AddInstallation(state.Installation{
+			Runtime:         rp.Name,
+			Version:         version,
+			Path:            targetDir,
+			Template:        templateSlug,
+			Action:          string(rp.Action),
+			PreviousVersion: rp.InstalledVersion,
+			PreviousPath:    rp.InstalledPath,
+			NoPath:          opts.NoPath,
+		})
+		return nil
 	})
-
-	if err := st.Save(); err != nil {
-		log.Warn("Failed to save state: %s", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record %s %s: %w", rp.DisplayName, version, err)
 	}
 
 	log.Info("%s %s installed successfully", rp.DisplayName, version)
@@ -233,5 +341,26 @@ func InstallSingleRuntime(rp engine.RuntimePlan, templateSlug string, log *logge
 		Version:     version,
 		InstallPath: targetDir,
 		BinDir:      binDir,
+		Duration:    time.Since(start),
+		Bytes:       counter.Total(),
+		EnvChanges:  envChanges,
 	}, nil
 }
+
+// installCancellable runs installRuntime and, if ctx was cancelled, removes
+// the partially installed targetDir (unless it predates this install) and
+// returns ErrCancelled.
+func installCancellable(ctx context.Context, installer Installer, rp engine.RuntimePlan, version, targetDir string, progress ProgressFunc, log *logger.Logger) error {
+	_, statErr := os.Stat(targetDir)
+	fresh := os.IsNotExist(statErr)
+
+	err := installRuntime(ctx, installer, rp, version, targetDir, progress)
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if fresh {
+		os.RemoveAll(targetDir)
+	}
+	log.Warn("%s installation cancelled", rp.DisplayName)
+	return ErrCancelled
+}
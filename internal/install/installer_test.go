@@ -1,12 +1,21 @@
 package install
 
 import (
+	"context"
+	"errors"
+	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/state"
 )
 
 func TestGetInstaller_Registered(t *testing.T) {
-	runtimes := []string{"node", "python", "flutter", "java", "go", "rust", "ruby", "php", "dotnet"}
+	runtimes := []string{"node", "python", "flutter", "java", "go", "rust", "ruby", "php", "dotnet", "bun", "deno"}
 
 	for _, name := range runtimes {
 		installer := GetInstaller(name)
@@ -81,11 +90,130 @@ func TestRubyInstaller_ResolveVersion_NotImplemented(t *testing.T) {
 	}
 }
 
-func TestPHPInstaller_ResolveVersion_NotImplemented(t *testing.T) {
+func TestPHPPlatform(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		wantOS       string
+		wantArch     string
+		wantErr      bool
+	}{
+		{"linux", "amd64", "linux", "x86_64", false},
+		{"linux", "arm64", "linux", "aarch64", false},
+		{"darwin", "arm64", "macos", "aarch64", false},
+		{"darwin", "amd64", "macos", "x86_64", false},
+		{"windows", "amd64", "windows", "x64", false},
+		{"windows", "arm64", "", "", true},
+		{"freebsd", "amd64", "", "", true},
+	}
+
+	for _, tt := range tests {
+		osName, arch, err := phpPlatform(tt.goos, tt.goarch)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s/%s: unexpected error %v", tt.goos, tt.goarch, err)
+			continue
+		}
+		if osName != tt.wantOS || arch != tt.wantArch {
+			t.Errorf("%s/%s: got %s-%s, want %s-%s", tt.goos, tt.goarch, osName, arch, tt.wantOS, tt.wantArch)
+		}
+	}
+}
+
+func TestParseStaticPHPIndex(t *testing.T) {
+	data := []byte(`[
+		{"name": "php-8.2.22-cli-linux-x86_64.tar.gz", "is_dir": false},
+		{"name": "php-8.3.10-cli-linux-x86_64.tar.gz", "is_dir": false},
+		{"name": "php-8.3.10-cli-linux-x86_64.tar.gz.sha256", "is_dir": false},
+		{"name": "php-8.3.10-cli-macos-aarch64.tar.gz", "is_dir": false},
+		{"name": "php-8.3.10-fpm-linux-x86_64.tar.gz", "is_dir": false},
+		{"name": "archive", "is_dir": true}
+	]`)
+
+	builds, err := parseStaticPHPIndex(data, "linux", "x86_64")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(builds) != 2 {
+		t.Fatalf("expected 2 linux builds, got %+v", builds)
+	}
+	if builds[0].Version != "8.2.22" || builds[0].ChecksumURL != "" {
+		t.Errorf("unexpected first build %+v", builds[0])
+	}
+	b := builds[1]
+	if b.Version != "8.3.10" || b.URL != phpStaticBaseURL+"php-8.3.10-cli-linux-x86_64.tar.gz" {
+		t.Errorf("unexpected build %+v", b)
+	}
+	if b.ChecksumURL != b.URL+".sha256" {
+		t.Errorf("expected checksum URL next to the archive, got %q", b.ChecksumURL)
+	}
+}
+
+func TestParseWindowsPHPReleases(t *testing.T) {
+	data := []byte(`{
+		"8.3": {
+			"version": "8.3.10",
+			"ts-vs16-x64": {"zip": {"path": "php-8.3.10-Win32-vs16-x64.zip", "sha256": "tsHash"}},
+			"nts-vs16-x64": {"zip": {"path": "php-8.3.10-nts-Win32-vs16-x64.zip", "sha256": "ntsHash"}},
+			"nts-vs16-x86": {"zip": {"path": "php-8.3.10-nts-Win32-vs16-x86.zip", "sha256": "x86Hash"}}
+		},
+		"8.4": {
+			"version": "8.4.1",
+			"nts-vs17-x64": {"zip": {"path": "php-8.4.1-nts-Win32-vs17-x64.zip", "sha256": "newHash"}}
+		}
+	}`)
+
+	builds, err := parseWindowsPHPReleases(data, "x64")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got := map[string]phpBuild{}
+	for _, b := range builds {
+		got[b.Version] = b
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected one build per minor version, got %+v", builds)
+	}
+	if b := got["8.3.10"]; b.Filename != "php-8.3.10-nts-Win32-vs16-x64.zip" || b.SHA256 != "ntsHash" {
+		t.Errorf("expected the nts x64 zip for 8.3, got %+v", b)
+	}
+	if b := got["8.4.1"]; b.URL != phpWindowsBaseURL+"php-8.4.1-nts-Win32-vs17-x64.zip" {
+		t.Errorf("unexpected 8.4 build %+v", b)
+	}
+}
+
+func TestResolvePHPBuild(t *testing.T) {
+	builds := []phpBuild{{Version: "8.2.22"}, {Version: "8.4.1"}, {Version: "8.3.10"}, {Version: "not-a-version"}}
+
+	tests := []struct {
+		requirement string
+		want        string
+		wantErr     bool
+	}{
+		{"latest", "8.4.1", false},
+		{">=8.2.0", "8.4.1", false},
+		{"^8.2", "8.4.1", false},
+		{"~8.3.0", "8.3.10", false},
+		{"<8.3", "8.2.22", false},
+		{">=9.0.0", "", true},
+		{"not a constraint", "", true},
+	}
+
+	for _, tt := range tests {
+		b, err := resolvePHPBuild(builds, tt.requirement)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.requirement, err)
+			continue
+		}
+		if b.Version != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.requirement, b.Version, tt.want)
+		}
+	}
+}
+
+func TestPHPInstaller_BinDir(t *testing.T) {
 	p := &PHPInstaller{}
-	_, err := p.ResolveVersion("latest")
-	if err == nil {
-		t.Error("expected error from unimplemented php installer")
+	dir := filepath.Join("home", "user", ".templatr", "runtimes", "php", "8.3.10")
+	if got := p.BinDir(dir); got != dir {
+		t.Errorf("expected php at the install root %q, got %q", dir, got)
 	}
 }
 
@@ -96,3 +224,233 @@ func TestDotnetInstaller_ResolveVersion_NotImplemented(t *testing.T) {
 		t.Error("expected error from unimplemented dotnet installer")
 	}
 }
+
+func TestBunPlatform(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		want         string
+		wantErr      bool
+	}{
+		{"darwin", "arm64", "darwin-aarch64", false},
+		{"darwin", "amd64", "darwin-x64", false},
+		{"linux", "amd64", "linux-x64", false},
+		{"linux", "arm64", "linux-aarch64", false},
+		{"windows", "amd64", "windows-x64", false},
+		{"windows", "arm64", "", true},
+		{"linux", "386", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := bunPlatform(tt.goos, tt.goarch)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s/%s: unexpected error %v", tt.goos, tt.goarch, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s/%s: got %q, want %q", tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
+func TestResolveBunVersion(t *testing.T) {
+	data := []byte(`[
+		{"tag_name": "bun-v1.2.0-canary.1", "prerelease": true},
+		{"tag_name": "bun-v1.1.30", "assets": [{"name": "bun-linux-x64.zip"}, {"name": "SHASUMS256.txt"}]},
+		{"tag_name": "bun-v1.1.8"},
+		{"tag_name": "bun-v1.0.36"},
+		{"tag_name": "bun-v1.3.0", "draft": true},
+		{"tag_name": "canary"}
+	]`)
+
+	tests := []struct {
+		requirement string
+		want        string
+		wantErr     bool
+	}{
+		{"latest", "1.1.30", false},
+		{">=1.1.0", "1.1.30", false},
+		{"~1.1.5", "1.1.30", false},
+		{"<1.1.0", "1.0.36", false},
+		{">=2.0.0", "", true},
+		{"not a constraint", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveBunVersion(data, tt.requirement)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.requirement, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.requirement, got, tt.want)
+		}
+	}
+}
+
+func TestBunInstaller_BinDir(t *testing.T) {
+	b := &BunInstaller{}
+	dir := filepath.Join("home", "user", ".templatr", "runtimes", "bun", "1.1.30")
+	if got := b.BinDir(dir); got != dir {
+		t.Errorf("expected bun at the install root %q, got %q", dir, got)
+	}
+}
+
+func TestDenoTarget(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		want         string
+		wantErr      bool
+	}{
+		{"darwin", "arm64", "aarch64-apple-darwin", false},
+		{"darwin", "amd64", "x86_64-apple-darwin", false},
+		{"linux", "amd64", "x86_64-unknown-linux-gnu", false},
+		{"linux", "arm64", "aarch64-unknown-linux-gnu", false},
+		{"windows", "amd64", "x86_64-pc-windows-msvc", false},
+		{"windows", "arm64", "", true},
+		{"freebsd", "amd64", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := denoTarget(tt.goos, tt.goarch)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s/%s: unexpected error %v", tt.goos, tt.goarch, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s/%s: got %q, want %q", tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDenoVersion(t *testing.T) {
+	data := []byte(`[
+		{"tag_name": "v2.0.0-rc.1", "prerelease": true},
+		{"tag_name": "v1.46.3"},
+		{"tag_name": "v1.44.4"},
+		{"tag_name": "v1.43.6"},
+		{"tag_name": "std/0.224.0"}
+	]`)
+
+	tests := []struct {
+		requirement string
+		want        string
+		wantErr     bool
+	}{
+		{"latest", "1.46.3", false},
+		{">=1.44.0", "1.46.3", false},
+		{"~1.44.0", "1.44.4", false},
+		{"<1.44.0", "1.43.6", false},
+		{">=2.0.0", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveDenoVersion(data, tt.requirement)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.requirement, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.requirement, got, tt.want)
+		}
+	}
+}
+
+func TestInstallSingleRuntime_Cancelled(t *testing.T) {
+	tempHome(t)
+	ts := slowServer(t)
+	registerFake(t, &fakeInstaller{url: ts.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall}
+	_, err := InstallSingleRuntime(ctx, rp, "test-template", logger.New(), cancelOnDownload(cancel), ExecuteOptions{})
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+
+	tmpFile := filepath.Join(os.TempDir(), "fake-runtime-1.0.0.zip")
+	for _, path := range []string{tmpFile, tmpFile + ".part"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after cancelling", path)
+		}
+	}
+	runtimes, _ := RuntimesDir()
+	if _, err := os.Stat(filepath.Join(runtimes, "fake", "1.0.0")); !os.IsNotExist(err) {
+		t.Error("the partial target directory should be removed")
+	}
+}
+
+func TestInstallSingleRuntime_Phases(t *testing.T) {
+	tempHome(t)
+	ts, sum := archiveServer(t, "runtime archive")
+	registerFake(t, &fakeInstaller{url: ts.URL, upstreamHash: sum})
+
+	var phases []string
+	record := func(ev ProgressEvent) {
+		if len(phases) == 0 || phases[len(phases)-1] != ev.Phase {
+			phases = append(phases, ev.Phase)
+		}
+	}
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall}
+	if _, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), record, ExecuteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{PhaseResolving, PhaseDownloading, PhaseVerifying, PhaseLinking}
+	if !slices.Equal(phases, want) {
+		t.Errorf("phases = %v, want %v", phases, want)
+	}
+}
+
+func TestExecutePlan_CancelledKeepsEarlierRuntimes(t *testing.T) {
+	tempHome(t)
+	stubDiskFree(t, 1<<40)
+
+	done, _, _ := countingServer(t, "runtime archive")
+	registerFake(t, &fakeInstaller{url: done.URL})
+	slow := slowServer(t)
+	second := &namedFake{name: "fake2", fakeInstaller: fakeInstaller{url: slow.URL}}
+	registerFake(t, second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	plan := &engine.SetupPlan{Manifest: &manifest.Manifest{}, Runtimes: []engine.RuntimePlan{
+		{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall},
+		{Name: "fake2", DisplayName: "Fake 2", RequiredVersion: "latest", Action: engine.ActionInstall},
+	}}
+	second.progress = cancelOnProgress(cancel)
+
+	results, err := ExecutePlan(ctx, plan, logger.New(), nil, ExecuteOptions{})
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	if len(results) != 1 || results[0].Runtime != "fake" {
+		t.Fatalf("the runtime installed before cancelling should be reported, got %+v", results)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.GetInstallations("fake")) != 1 {
+		t.Error("the runtime installed before cancelling should be recorded in state")
+	}
+}
+
+// namedFake is a fakeInstaller registered under another name, with its own
+// progress callback so a test can cancel during its download only.
+type namedFake struct {
+	fakeInstaller
+	name     string
+	progress ProgressFunc
+}
+
+func (f *namedFake) Name() string { return f.name }
+
+func (f *namedFake) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	tmpFile := filepath.Join(os.TempDir(), f.name+"-"+version+".zip")
+	defer os.Remove(tmpFile)
+	return fetchArchive(ctx, f.name, f.url, tmpFile, f.progress, func() (string, error) { return "", nil })
+}
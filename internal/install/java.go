@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -44,6 +45,33 @@ type adoptiumVer struct {
 	Semver   string `json:"semver"`
 }
 
+// adoptiumAPI is the base URL of the Adoptium API.
+var adoptiumAPI = "https://api.adoptium.net/v3"
+
+// adoptiumReleases is the response of the available_releases endpoint.
+type adoptiumReleases struct {
+	AvailableReleases    []int `json:"available_releases"`
+	AvailableLTSReleases []int `json:"available_lts_releases"`
+}
+
+// adoptiumLatest fetches the latest Temurin JDK builds of a major version
+// for the current platform.
+func adoptiumLatest(major string) ([]adoptiumAsset, error) {
+	apiURL := fmt.Sprintf("%s/assets/latest/%s/hotspot?architecture=%s&image_type=jdk&os=%s&vendor=eclipse",
+		adoptiumAPI, major, javaArch(), javaOS())
+
+	data, err := FetchJSON(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Adoptium releases: %w", err)
+	}
+
+	var assets []adoptiumAsset
+	if err := json.Unmarshal(data, &assets); err != nil {
+		return nil, fmt.Errorf("failed to parse Adoptium response: %w", err)
+	}
+	return assets, nil
+}
+
 func (j *JavaInstaller) ResolveVersion(requirement string) (string, error) {
 	// Determine the major version to fetch
 	major := 21 // default to latest LTS
@@ -70,18 +98,9 @@ func (j *JavaInstaller) ResolveVersion(requirement string) (string, error) {
 		}
 	}
 
-	// Fetch from Adoptium API
-	apiURL := fmt.Sprintf("https://api.adoptium.net/v3/assets/latest/%d/hotspot?architecture=%s&image_type=jdk&os=%s&vendor=eclipse",
-		major, javaArch(), javaOS())
-
-	data, err := FetchJSON(apiURL)
+	assets, err := adoptiumLatest(strconv.Itoa(major))
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch Adoptium releases: %w", err)
-	}
-
-	var assets []adoptiumAsset
-	if err := json.Unmarshal(data, &assets); err != nil {
-		return "", fmt.Errorf("failed to parse Adoptium response: %w", err)
+		return "", err
 	}
 
 	if len(assets) == 0 {
@@ -91,45 +110,57 @@ func (j *JavaInstaller) ResolveVersion(requirement string) (string, error) {
 	return assets[0].Version.Semver, nil
 }
 
-func (j *JavaInstaller) Install(version, targetDir string, progress ProgressFunc) error {
-	// Determine major version from the version string
-	parts := strings.Split(version, ".")
-	major := parts[0]
-
-	apiURL := fmt.Sprintf("https://api.adoptium.net/v3/assets/latest/%s/hotspot?architecture=%s&image_type=jdk&os=%s&vendor=eclipse",
-		major, javaArch(), javaOS())
-
-	data, err := FetchJSON(apiURL)
+// ListVersions lists the latest Temurin JDK build of every major version
+// Adoptium publishes for this platform, marking LTS releases.
+func (j *JavaInstaller) ListVersions() ([]AvailableVersion, error) {
+	data, err := FetchJSON(adoptiumAPI + "/info/available_releases")
 	if err != nil {
-		return fmt.Errorf("failed to fetch Adoptium releases: %w", err)
+		return nil, fmt.Errorf("failed to fetch Adoptium releases: %w", err)
+	}
+	var releases adoptiumReleases
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse Adoptium response: %w", err)
+	}
+	lts := map[int]bool{}
+	for _, m := range releases.AvailableLTSReleases {
+		lts[m] = true
 	}
 
-	var assets []adoptiumAsset
-	if err := json.Unmarshal(data, &assets); err != nil {
-		return err
+	// Majors are listed oldest first
+	var versions []AvailableVersion
+	for i := len(releases.AvailableReleases) - 1; i >= 0; i-- {
+		major := releases.AvailableReleases[i]
+		assets, err := adoptiumLatest(strconv.Itoa(major))
+		if err != nil {
+			return nil, err
+		}
+		if len(assets) == 0 {
+			continue // no build for this platform
+		}
+		versions = append(versions, AvailableVersion{Version: assets[0].Version.Semver, LTS: lts[major]})
 	}
+	return versions, nil
+}
 
-	if len(assets) == 0 {
-		return fmt.Errorf("no Adoptium JDK found")
+func (j *JavaInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	asset, err := javaAsset(version)
+	if err != nil {
+		return err
 	}
 
-	asset := assets[0]
 	tmpFile := filepath.Join(os.TempDir(), asset.Binary.Package.Name)
 	defer os.Remove(tmpFile)
 
-	if err := DownloadFile(asset.Binary.Package.Link, tmpFile, progress); err != nil {
+	// Download (or reuse from the cache) and verify checksum
+	err = fetchArchive(ctx, j.Name(), asset.Binary.Package.Link, tmpFile, progress, func() (string, error) {
+		return asset.Binary.Package.Checksum, nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to download Java: %w", err)
 	}
 
-	// Verify checksum
-	if asset.Binary.Package.Checksum != "" {
-		if err := VerifyChecksum(tmpFile, asset.Binary.Package.Checksum); err != nil {
-			return fmt.Errorf("Java checksum verification failed: %w", err)
-		}
-	}
-
 	// Extract - Adoptium archives have a top-level jdk-* dir
-	if err := ExtractAndFlatten(tmpFile, targetDir); err != nil {
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
 		return fmt.Errorf("failed to extract Java: %w", err)
 	}
 
@@ -146,6 +177,41 @@ func (j *JavaInstaller) EnvVars(installDir string) map[string]string {
 	}
 }
 
+// ArchivePattern matches the Adoptium JDK archive for offline installs, e.g.
+// OpenJDK21U-jdk_x64_linux_hotspot_21.0.2_13.tar.gz (the build number varies).
+func (j *JavaInstaller) ArchivePattern(version string) (string, error) {
+	major, _, _ := strings.Cut(version, ".")
+	return fmt.Sprintf("OpenJDK%sU-jdk_%s_%s_hotspot_%s_*.%s", major, javaArch(), javaOS(), version, PlatformExt()), nil
+}
+
+// DownloadSize returns the package size listed in the Adoptium API.
+func (j *JavaInstaller) DownloadSize(version string) (int64, error) {
+	asset, err := javaAsset(version)
+	if err != nil {
+		return 0, err
+	}
+	return asset.Binary.Package.Size, nil
+}
+
+// javaAsset looks up the latest Adoptium JDK build in version's major line
+// for the current platform.
+func javaAsset(version string) (*adoptiumAsset, error) {
+	// Determine major version from the version string
+	parts := strings.Split(version, ".")
+	major := parts[0]
+
+	assets, err := adoptiumLatest(major)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("no Adoptium JDK found")
+	}
+
+	return &assets[0], nil
+}
+
 func javaOS() string {
 	switch runtime.GOOS {
 	case "darwin":
@@ -0,0 +1,199 @@
+package install
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/state"
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+// ManagedInstallation is an installation recorded in state.json, with what
+// is on disk now.
+type ManagedInstallation struct {
+	Runtime     string   `json:"runtime"`
+	Version     string   `json:"version,omitempty"`
+	Path        string   `json:"path"`
+	Template    string   `json:"template,omitempty"`
+	UsedBy      []string `json:"used_by,omitempty"` // other templates relying on it
+	InstalledAt string   `json:"installed_at"`
+	Action      string   `json:"action"`
+	Size        int64    `json:"size_bytes"`
+	Missing     bool     `json:"missing"` // the path no longer exists
+}
+
+// Listing is everything the tool has installed or changed, as shown by
+// the list command.
+type Listing struct {
+	Installations     []ManagedInstallation    `json:"installations"`
+	PathModifications []state.PathModification `json:"path_modifications"`
+	EnvModifications  []state.EnvModification  `json:"env_modifications"`
+}
+
+// ListManaged returns the installations recorded in st, optionally only
+// those of one runtime, with their size on disk. With a runtime, only the
+// PATH and env var changes pointing into its installations are included.
+func ListManaged(st *state.State, runtime string) Listing {
+	runtime = strings.ToLower(runtime)
+	l := Listing{
+		Installations:     []ManagedInstallation{},
+		PathModifications: []state.PathModification{},
+		EnvModifications:  []state.EnvModification{},
+	}
+
+	var dirs []string
+	if link := st.GetLink(runtime); link != nil {
+		dirs = append(dirs, link.Path)
+	}
+	for _, inst := range st.GetInstallations(runtime) {
+		m := ManagedInstallation{
+			Runtime:     inst.Runtime,
+			Version:     inst.Version,
+			Path:        inst.Path,
+			Template:    inst.Template,
+			UsedBy:      inst.UsedBy,
+			InstalledAt: inst.InstalledAt,
+			Action:      inst.Action,
+		}
+		if _, err := os.Stat(inst.Path); err != nil {
+			m.Missing = true
+		} else {
+			m.Size = dirSize(inst.Path)
+		}
+		l.Installations = append(l.Installations, m)
+		dirs = append(dirs, inst.Path)
+	}
+
+	ours := func(value string) bool {
+		if runtime == "" {
+			return true
+		}
+		for _, dir := range dirs {
+			if within(value, dir) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, mod := range st.PathModifications {
+		if ours(mod.Value) {
+			l.PathModifications = append(l.PathModifications, mod)
+		}
+	}
+	for _, mod := range st.EnvModifications {
+		if ours(mod.Value) {
+			l.EnvModifications = append(l.EnvModifications, mod)
+		}
+	}
+	return l
+}
+
+// Missing returns the installations whose path no longer exists.
+func (l Listing) Missing() []ManagedInstallation {
+	var missing []ManagedInstallation
+	for _, m := range l.Installations {
+		if m.Missing {
+			missing = append(missing, m)
+		}
+	}
+	return missing
+}
+
+// Print writes the listing as a table, followed by the PATH and env var
+// changes.
+func (l Listing) Print(w io.Writer) {
+	rows := make([][]string, 0, len(l.Installations))
+	for _, m := range l.Installations {
+		version := m.Version
+		if m.Action == ActionDownload {
+			version = "(download)"
+		}
+		size := term.FormatBytes(m.Size)
+		if m.Missing {
+			size = "-"
+		}
+		template := strings.Join(append([]string{m.Template}, m.UsedBy...), ", ")
+		if m.Template == "" {
+			template = "-"
+		}
+		rows = append(rows, []string{m.Runtime, version, size, installedDate(m.InstalledAt), template, m.Path})
+	}
+
+	header := []string{"Runtime", "Version", "Size", "Installed", "Template", "Path"}
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	last := len(header) - 1
+	line := func(cells []string) string {
+		var b strings.Builder
+		b.WriteString(" ")
+		for i, cell := range cells {
+			if i == last {
+				fmt.Fprintf(&b, " %s", cell)
+			} else {
+				fmt.Fprintf(&b, " %-*s ", widths[i], cell)
+			}
+		}
+		return b.String()
+	}
+	rules := make([]string, len(header))
+	for i := range header {
+		rules[i] = strings.Repeat("─", widths[i])
+	}
+
+	fmt.Fprintln(w, line(header))
+	fmt.Fprintln(w, line(rules))
+	for i, row := range rows {
+		out := line(row)
+		if l.Installations[i].Missing {
+			out += "  ! missing on disk"
+		}
+		fmt.Fprintln(w, out)
+	}
+
+	if len(l.PathModifications) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "PATH entries:")
+		for _, mod := range l.PathModifications {
+			fmt.Fprintf(w, "  %s  (%s)\n", mod.Value, modLocation(mod.Method, mod.File))
+		}
+	}
+	if len(l.EnvModifications) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Environment variables:")
+		for _, mod := range l.EnvModifications {
+			fmt.Fprintf(w, "  %s=%s  (%s)\n", mod.Name, mod.Value, modLocation(mod.Method, mod.File))
+		}
+	}
+}
+
+// installedDate returns the date part of an RFC 3339 timestamp in local
+// time, or "-" if there isn't one.
+func installedDate(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02")
+}
+
+// modLocation describes where a PATH or env var change was made.
+func modLocation(method, file string) string {
+	if file != "" {
+		return file
+	}
+	if method == "windows_env" {
+		return "user environment"
+	}
+	return method
+}
@@ -0,0 +1,109 @@
+package install
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+func TestListManaged(t *testing.T) {
+	preflightHome(t)
+	st := fakeRuntimesTree(t, map[string][]string{"node": {"22.14.0"}, "python": {"3.12.8"}}, nil)
+	st.Installations[0].Template = "nextjs-saas"
+	base, _ := RuntimesDir()
+	node := filepath.Join(base, "node", "22.14.0")
+	python := filepath.Join(base, "python", "3.12.8")
+	st.AddPathModification(state.PathModification{Method: "shell_rc", File: "/home/u/.zshrc", Value: filepath.Join(node, "bin")})
+	st.AddPathModification(state.PathModification{Method: "shell_rc", File: "/home/u/.zshrc", Value: filepath.Join(python, "bin")})
+	st.AddEnvModification(state.EnvModification{Name: "NODE_HOME", Value: node, Method: "shell_rc", File: "/home/u/.zshrc"})
+
+	// Deleted by hand since it was installed
+	if err := os.RemoveAll(python); err != nil {
+		t.Fatal(err)
+	}
+
+	l := ListManaged(st, "")
+	if len(l.Installations) != 2 || len(l.PathModifications) != 2 || len(l.EnvModifications) != 1 {
+		t.Fatalf("ListManaged() = %+v, want 2 installations, 2 PATH entries and 1 env var", l)
+	}
+	byRuntime := map[string]ManagedInstallation{}
+	for _, m := range l.Installations {
+		byRuntime[m.Runtime] = m
+	}
+	if m := byRuntime["node"]; m.Missing || m.Size != 1024 {
+		t.Errorf("node = %+v, want present with 1024 bytes", m)
+	}
+	if m := byRuntime["python"]; !m.Missing || m.Size != 0 {
+		t.Errorf("python = %+v, want missing", m)
+	}
+	if missing := l.Missing(); len(missing) != 1 || missing[0].Runtime != "python" {
+		t.Errorf("Missing() = %+v, want python", missing)
+	}
+
+	l = ListManaged(st, "Node")
+	if len(l.Installations) != 1 || l.Installations[0].Runtime != "node" {
+		t.Errorf("filtered installations = %+v, want only node", l.Installations)
+	}
+	if len(l.PathModifications) != 1 || l.PathModifications[0].Value != filepath.Join(node, "bin") {
+		t.Errorf("filtered PATH entries = %+v, want only node's", l.PathModifications)
+	}
+	if len(l.EnvModifications) != 1 {
+		t.Errorf("filtered env vars = %+v, want NODE_HOME", l.EnvModifications)
+	}
+
+	if l := ListManaged(st, "ruby"); l.Installations == nil || len(l.Installations) != 0 || len(l.PathModifications) != 0 {
+		t.Errorf("ListManaged(ruby) = %+v, want empty, non-nil lists", l)
+	}
+}
+
+func TestListingPrint(t *testing.T) {
+	l := Listing{
+		Installations: []ManagedInstallation{
+			{Runtime: "node", Version: "22.14.0", Path: "/r/node/22.14.0", Template: "nextjs-saas", InstalledAt: "2026-03-14T09:15:02Z", Action: "install", Size: 3 * 1024 * 1024},
+			{Runtime: "python", Version: "3.12.8", Path: "/r/python/3.12.8", InstalledAt: "bad", Action: "install", Missing: true},
+			{Runtime: "fonts", Path: "/p/fonts", Action: ActionDownload, Size: 512},
+		},
+		PathModifications: []state.PathModification{{Method: "shell_rc", File: "/home/u/.zshrc", Value: "/r/node/current/bin"}},
+		EnvModifications:  []state.EnvModification{{Name: "JAVA_HOME", Value: "/r/java/current", Method: "windows_env"}},
+	}
+
+	var buf bytes.Buffer
+	l.Print(&buf)
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+
+	header := lines[0]
+	if !strings.HasPrefix(header, "  Runtime") || !strings.HasSuffix(header, "Path") {
+		t.Errorf("header = %q", header)
+	}
+	if !strings.HasPrefix(lines[1], "  ───────") {
+		t.Errorf("rule = %q", lines[1])
+	}
+	// Every column starts at the same offset in each row
+	col := strings.Index(header, "Template")
+	for _, row := range lines[2:5] {
+		if strings.TrimSpace(row[:col]) == "" || row[col-1] != ' ' || row[col] == ' ' {
+			t.Errorf("row %q is not aligned with the Template column", row)
+		}
+	}
+
+	for _, want := range []string{
+		"3.0 MB", "nextjs-saas", "/r/node/22.14.0",
+		"(download)", "512 B",
+		"/r/node/current/bin  (/home/u/.zshrc)",
+		"JAVA_HOME=/r/java/current  (user environment)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+	if !strings.HasSuffix(lines[3], "/r/python/3.12.8  ! missing on disk") {
+		t.Errorf("python row = %q, want it flagged missing", lines[3])
+	}
+	if strings.Contains(lines[2], "missing") || strings.Contains(lines[4], "missing") {
+		t.Error("only the missing path should be flagged")
+	}
+}
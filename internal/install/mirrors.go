@@ -0,0 +1,94 @@
+package install
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/templatr/templatr-setup/internal/userconfig"
+)
+
+// officialBases are the official download hosts that a [mirrors] entry in
+// ~/.templatr/config.toml can replace, keyed by runtime. A mirror must lay
+// out files the same way below its base URL.
+var officialBases = map[string]string{
+	"node":    "https://nodejs.org/dist",
+	"go":      "https://go.dev/dl",
+	"python":  "https://github.com/indygreg/python-build-standalone/releases/download",
+	"flutter": "https://storage.googleapis.com",
+}
+
+var (
+	fallbackMu sync.Mutex
+	fallbacks  = map[string]string{} // mirror URL -> official URL handed out by runtimeURL
+)
+
+// loadMirrors reads the [mirrors] section of the user configuration file.
+// A missing file means no mirrors.
+func loadMirrors() (map[string]string, error) {
+	cfg, err := userconfig.Load()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Mirrors, nil
+}
+
+// runtimeURL returns the URL to fetch officialURL from, applying the mirror
+// configured for runtimeName. For example, with
+//
+//	[mirrors]
+//	node = "https://npmmirror.com/mirrors/node"
+//
+// https://nodejs.org/dist/v22.14.0/SHASUMS256.txt becomes
+// https://npmmirror.com/mirrors/node/v22.14.0/SHASUMS256.txt. URLs outside
+// the runtime's official base are returned unchanged. If the mirror turns
+// out not to have the file, the download helpers fall back to officialURL.
+func runtimeURL(runtimeName, officialURL string) string {
+	base, ok := officialBases[runtimeName]
+	if !ok || !strings.HasPrefix(officialURL, base+"/") {
+		return officialURL
+	}
+
+	log := currentInstallLogger()
+	mirrors, err := loadMirrors()
+	if err != nil {
+		if log != nil {
+			log.Warn("Ignoring download mirrors: %s", err)
+		}
+		return officialURL
+	}
+	mirror := strings.TrimRight(mirrors[runtimeName], "/")
+	if mirror == "" {
+		return officialURL
+	}
+
+	mirrored := mirror + strings.TrimPrefix(officialURL, base)
+	fallbackMu.Lock()
+	fallbacks[mirrored] = officialURL
+	fallbackMu.Unlock()
+
+	if log != nil {
+		log.Info("Using %s mirror %s for %s", runtimeName, mirror, mirrored)
+	}
+	return mirrored
+}
+
+// withMirrorFallback calls fetch with url and, if url came from a mirror
+// that answered 404, once more with the official URL.
+func withMirrorFallback(url string, fetch func(url string) error) error {
+	err := fetch(url)
+	if err == nil || !isNotFound(err) {
+		return err
+	}
+
+	fallbackMu.Lock()
+	official, ok := fallbacks[url]
+	fallbackMu.Unlock()
+	if !ok {
+		return err
+	}
+
+	if log := currentInstallLogger(); log != nil {
+		log.Warn("Mirror has no %s, falling back to %s", url, official)
+	}
+	return fetch(official)
+}
@@ -0,0 +1,179 @@
+package install
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/userconfig"
+)
+
+func writeUserConfig(t *testing.T, content string) {
+	t.Helper()
+	path, err := userconfig.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuntimeURL_NoMirrors(t *testing.T) {
+	tempHome(t)
+
+	url := "https://nodejs.org/dist/v22.14.0/node-v22.14.0-linux-x64.tar.gz"
+	if got := runtimeURL("node", url); got != url {
+		t.Errorf("runtimeURL() without a config file = %s, want %s", got, url)
+	}
+}
+
+func TestRuntimeURL_Mirrors(t *testing.T) {
+	tempHome(t)
+	writeUserConfig(t, `
+[mirrors]
+node = "https://npmmirror.com/mirrors/node/"
+go = "https://golang.google.cn/dl"
+python = "https://mirror.example.cn/python-build-standalone"
+flutter = "https://storage.flutter-io.cn"
+`)
+
+	tests := []struct {
+		runtime string
+		url     string
+		want    string
+	}{
+		{"node", "https://nodejs.org/dist/v22.14.0/SHASUMS256.txt", "https://npmmirror.com/mirrors/node/v22.14.0/SHASUMS256.txt"},
+		{"node", "https://nodejs.org/dist/index.json", "https://npmmirror.com/mirrors/node/index.json"},
+		{"go", "https://go.dev/dl/?mode=json", "https://golang.google.cn/dl/?mode=json"},
+		{"go", "https://go.dev/dl/go1.22.5.linux-amd64.tar.gz", "https://golang.google.cn/dl/go1.22.5.linux-amd64.tar.gz"},
+		{
+			"python",
+			"https://github.com/indygreg/python-build-standalone/releases/download/20241219/cpython-3.12.8+20241219-x86_64-unknown-linux-gnu-install_only.tar.gz",
+			"https://mirror.example.cn/python-build-standalone/20241219/cpython-3.12.8+20241219-x86_64-unknown-linux-gnu-install_only.tar.gz",
+		},
+		{
+			"flutter",
+			"https://storage.googleapis.com/flutter_infra_release/releases/releases_linux.json",
+			"https://storage.flutter-io.cn/flutter_infra_release/releases/releases_linux.json",
+		},
+		// Not under the runtime's official base, or no mirror for the runtime
+		{"node", "https://example.com/dist/v22.14.0/SHASUMS256.txt", "https://example.com/dist/v22.14.0/SHASUMS256.txt"},
+		{"bun", "https://github.com/oven-sh/bun/releases/download/bun-v1.1.30/bun-linux-x64.zip", "https://github.com/oven-sh/bun/releases/download/bun-v1.1.30/bun-linux-x64.zip"},
+	}
+	for _, tt := range tests {
+		if got := runtimeURL(tt.runtime, tt.url); got != tt.want {
+			t.Errorf("runtimeURL(%s, %s) = %s, want %s", tt.runtime, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRuntimeURL_InvalidConfig(t *testing.T) {
+	tempHome(t)
+	writeUserConfig(t, "[mirrors\nnode = ")
+
+	url := "https://nodejs.org/dist/index.json"
+	if got := runtimeURL("node", url); got != url {
+		t.Errorf("runtimeURL() with an unreadable config = %s, want the official URL", got)
+	}
+}
+
+// withOfficialBase points a runtime's official base at a test server.
+func withOfficialBase(t *testing.T, runtimeName, base string) {
+	t.Helper()
+	old, had := officialBases[runtimeName]
+	officialBases[runtimeName] = base
+	t.Cleanup(func() {
+		if had {
+			officialBases[runtimeName] = old
+		} else {
+			delete(officialBases, runtimeName)
+		}
+	})
+}
+
+func TestMirrorFallback_NotFound(t *testing.T) {
+	tempHome(t)
+
+	var mirrorHits atomic.Int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHits.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer mirror.Close()
+	official := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("official " + r.URL.Path))
+	}))
+	defer official.Close()
+
+	withOfficialBase(t, "node", official.URL+"/dist")
+	writeUserConfig(t, "[mirrors]\nnode = \""+mirror.URL+"/node\"\n")
+
+	log := logger.New()
+	log.SetLevel(logger.ERROR) // keep INFO and WARN lines out of test output
+	if err := log.Init(); err != nil {
+		t.Fatal(err)
+	}
+	setInstallLogger(log)
+	t.Cleanup(func() { setInstallLogger(nil) })
+
+	url := runtimeURL("node", official.URL+"/dist/v1.0.0/node.tar.gz")
+	if !strings.HasPrefix(url, mirror.URL+"/node/") {
+		t.Fatalf("runtimeURL() = %s, want the mirror", url)
+	}
+
+	dest := filepath.Join(t.TempDir(), "node.tar.gz")
+	if err := DownloadFile(context.Background(), url, dest, nil); err != nil {
+		t.Fatalf("DownloadFile() should fall back to the official host, got %s", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "official /dist/v1.0.0/node.tar.gz" {
+		t.Errorf("downloaded %q, want the official file", data)
+	}
+
+	body, err := FetchJSON(runtimeURL("node", official.URL+"/dist/index.json"))
+	if err != nil {
+		t.Fatalf("FetchJSON() should fall back to the official host, got %s", err)
+	}
+	if string(body) != "official /dist/index.json" {
+		t.Errorf("FetchJSON() = %q, want the official file", body)
+	}
+	if got := mirrorHits.Load(); got != 2 {
+		t.Errorf("mirror should be tried first for each request, got %d hits", got)
+	}
+
+	log.Close()
+	data, err := os.ReadFile(log.FilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Using node mirror " + mirror.URL + "/node", "falling back to " + official.URL + "/dist/index.json"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log should contain %q, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestMirrorFallback_OnlyForMirrorURLs(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	calls := 0
+	err := withMirrorFallback(ts.URL+"/missing", func(url string) error {
+		calls++
+		_, err := FetchJSON(url)
+		return err
+	})
+	if !isNotFound(err) {
+		t.Fatalf("expected a 404, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("a URL that didn't come from a mirror should not be retried, got %d calls", calls)
+	}
+}
@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -24,25 +25,38 @@ type nodeRelease struct {
 	LTS     interface{} `json:"lts"` // false or string like "Jod"
 }
 
-func (n *NodeInstaller) ResolveVersion(requirement string) (string, error) {
-	data, err := FetchJSON("https://nodejs.org/dist/index.json")
+// nodeReleases fetches the Node.js release index, newest first.
+func nodeReleases() ([]nodeRelease, error) {
+	data, err := FetchJSON(runtimeURL("node", "https://nodejs.org/dist/index.json"))
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch Node.js versions: %w", err)
+		return nil, fmt.Errorf("failed to fetch Node.js versions: %w", err)
 	}
 
 	var releases []nodeRelease
 	if err := json.Unmarshal(data, &releases); err != nil {
-		return "", fmt.Errorf("failed to parse Node.js versions: %w", err)
+		return nil, fmt.Errorf("failed to parse Node.js versions: %w", err)
+	}
+	return releases, nil
+}
+
+// ltsName returns the release's LTS codename, or "" for a Current release.
+func (r nodeRelease) ltsName() string {
+	// LTS field is a string when it's an LTS version
+	name, _ := r.LTS.(string)
+	return name
+}
+
+func (n *NodeInstaller) ResolveVersion(requirement string) (string, error) {
+	releases, err := nodeReleases()
+	if err != nil {
+		return "", err
 	}
 
 	// Filter to LTS versions only
 	var ltsReleases []nodeRelease
 	for _, r := range releases {
-		if r.LTS != nil && r.LTS != false {
-			// LTS field is a string when it's an LTS version
-			if _, ok := r.LTS.(string); ok {
-				ltsReleases = append(ltsReleases, r)
-			}
+		if r.ltsName() != "" {
+			ltsReleases = append(ltsReleases, r)
 		}
 	}
 
@@ -76,33 +90,46 @@ func (n *NodeInstaller) ResolveVersion(requirement string) (string, error) {
 	return strings.TrimPrefix(ltsReleases[0].Version, "v"), nil
 }
 
-func (n *NodeInstaller) Install(version, targetDir string, progress ProgressFunc) error {
+// ListVersions lists every Node.js release, marking LTS lines.
+func (n *NodeInstaller) ListVersions() ([]AvailableVersion, error) {
+	releases, err := nodeReleases()
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]AvailableVersion, 0, len(releases))
+	for _, r := range releases {
+		versions = append(versions, AvailableVersion{Version: strings.TrimPrefix(r.Version, "v"), LTS: r.ltsName() != "", Codename: r.ltsName()})
+	}
+	return versions, nil
+}
+
+func (n *NodeInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
 	osName := nodeOS()
 	arch := nodeArch()
 	ext := PlatformExt()
 	filename := fmt.Sprintf("node-v%s-%s-%s.%s", version, osName, arch, ext)
-	downloadURL := fmt.Sprintf("https://nodejs.org/dist/v%s/%s", version, filename)
-	checksumURL := fmt.Sprintf("https://nodejs.org/dist/v%s/SHASUMS256.txt", version)
+	downloadURL := runtimeURL("node", fmt.Sprintf("https://nodejs.org/dist/v%s/%s", version, filename))
+	checksumURL := runtimeURL("node", fmt.Sprintf("https://nodejs.org/dist/v%s/SHASUMS256.txt", version))
 
 	// Download to temp file
 	tmpFile := filepath.Join(os.TempDir(), filename)
 	defer os.Remove(tmpFile)
 
-	if err := DownloadFile(downloadURL, tmpFile, progress); err != nil {
-		return fmt.Errorf("failed to download Node.js: %w", err)
-	}
-
-	// Verify checksum
-	expectedHash, err := FetchChecksumFromURL(checksumURL, filename)
+	// Download (or reuse from the cache) and verify against SHASUMS256.txt,
+	// itself checked against SHASUMS256.txt.sig with --verify-signatures
+	err := fetchArchive(ctx, n.Name(), downloadURL, tmpFile, progress, func() (string, error) {
+		if verifySignatures {
+			sigURL := runtimeURL("node", fmt.Sprintf("https://nodejs.org/dist/v%s/SHASUMS256.txt.sig", version))
+			return fetchSignedChecksum(n.Name(), checksumURL, sigURL, filename)
+		}
+		return FetchChecksumFromURL(checksumURL, filename)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to fetch Node.js checksum: %w", err)
-	}
-	if err := VerifyChecksum(tmpFile, expectedHash); err != nil {
-		return fmt.Errorf("Node.js checksum verification failed: %w", err)
+		return fmt.Errorf("failed to download Node.js: %w", err)
 	}
 
 	// Extract and flatten (strips the top-level node-vX.Y.Z-os-arch/ dir)
-	if err := ExtractAndFlatten(tmpFile, targetDir); err != nil {
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
 		return fmt.Errorf("failed to extract Node.js: %w", err)
 	}
 
@@ -118,6 +145,11 @@ func (n *NodeInstaller) BinDir(installDir string) string {
 
 func (n *NodeInstaller) EnvVars(installDir string) map[string]string { return nil }
 
+// ArchivePattern matches the nodejs.org archive for offline installs.
+func (n *NodeInstaller) ArchivePattern(version string) (string, error) {
+	return fmt.Sprintf("node-v%s-%s-%s.%s", version, nodeOS(), nodeArch(), PlatformExt()), nil
+}
+
 // nodeOS returns the OS name used in Node.js download URLs.
 func nodeOS() string {
 	switch runtime.GOOS {
@@ -0,0 +1,113 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/templatr/templatr-setup/internal/engine"
+)
+
+// offlineInstaller is implemented by installers whose official archive can be
+// installed as-is, which is what offline mode needs: the archive is found in
+// the --archives directory and extracted, with no metadata fetched.
+type offlineInstaller interface {
+	// ArchivePattern returns a filepath.Match pattern for the name of the
+	// official archive of version on the current platform, e.g.
+	// "node-v22.14.0-linux-x64.tar.gz".
+	ArchivePattern(version string) (string, error)
+}
+
+// resolveVersion resolves the version to install for rp, from the manifest
+// alone in offline mode.
+func resolveVersion(installer Installer, rp engine.RuntimePlan) (string, error) {
+	if rp.ArchivesDir != "" {
+		return offlineVersion(rp)
+	}
+	return installer.ResolveVersion(rp.RequiredVersion)
+}
+
+// installRuntime runs installer.Install, or installs from the archives
+// directory in offline mode.
+func installRuntime(ctx context.Context, installer Installer, rp engine.RuntimePlan, version, targetDir string, progress ProgressFunc) error {
+	if rp.ArchivesDir != "" {
+		return installOffline(ctx, installer, version, targetDir, rp.ArchivesDir)
+	}
+	return installer.Install(ctx, version, targetDir, progress)
+}
+
+// offlineVersion is ResolveVersion for offline mode: without release
+// metadata only an exact version pinned in the manifest can be installed.
+func offlineVersion(rp engine.RuntimePlan) (string, error) {
+	version := strings.TrimPrefix(strings.TrimPrefix(rp.RequiredVersion, "="), "v")
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		return "", fmt.Errorf("offline mode needs an exact version for %s in [runtimes] (e.g. \"22.14.0\"), got %q", rp.DisplayName, rp.RequiredVersion)
+	}
+	return version, nil
+}
+
+// installOffline installs a runtime from a pre-fetched archive in
+// archivesDir instead of downloading it. The archive is verified against the
+// checksum pinned in the manifest when there is one.
+func installOffline(ctx context.Context, installer Installer, version, targetDir, archivesDir string) error {
+	oi, ok := installer.(offlineInstaller)
+	if !ok {
+		return fmt.Errorf("%s can't be installed in offline mode", installer.Name())
+	}
+	pattern, err := oi.ArchivePattern(version)
+	if err != nil {
+		return err
+	}
+
+	archive, err := findArchive(archivesDir, pattern)
+	if err != nil {
+		return err
+	}
+
+	if expected := pinnedChecksum(installer.Name()); expected != "" {
+		reportEvent(ctx, ProgressEvent{Phase: PhaseVerifying})
+		if err := VerifyChecksum(archive, expected); err != nil {
+			return fmt.Errorf("pinned in [runtimes_checksums]: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetDir), 0o755); err != nil {
+		return err
+	}
+	if err := ExtractAndFlatten(ctx, archive, targetDir); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", filepath.Base(archive), err)
+	}
+	return nil
+}
+
+// findArchive returns the single file in dir whose name matches pattern.
+func findArchive(dir, pattern string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read archives directory: %w", err)
+	}
+
+	var matches []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, e.Name()); ok {
+			matches = append(matches, e.Name())
+		}
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no archive matching %s in %s", pattern, dir)
+	case 1:
+		return filepath.Join(dir, matches[0]), nil
+	default:
+		return "", fmt.Errorf("more than one archive matching %s in %s: %s", pattern, dir, strings.Join(matches, ", "))
+	}
+}
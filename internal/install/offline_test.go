@@ -0,0 +1,205 @@
+package install
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+// offlineFake is a fakeInstaller that also supports offline installs. Its
+// url points nowhere: offline mode must not touch the network.
+type offlineFake struct {
+	fakeInstaller
+	resolved bool
+}
+
+func (f *offlineFake) ResolveVersion(requirement string) (string, error) {
+	f.resolved = true
+	return "9.9.9", nil
+}
+
+func (f *offlineFake) ArchivePattern(version string) (string, error) {
+	return "fake-runtime-v" + version + "-*.zip", nil
+}
+
+// writeFixtureZip writes a zip with a single top-level fake-runtime/ dir
+// holding bin/fake, like an official runtime archive, and returns its sha256.
+func writeFixtureZip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("fake-runtime/bin/fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("#!/bin/sh\necho fake\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	data, _ := os.ReadFile(path)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func offlinePlan(archives, version, sum string) engine.RuntimePlan {
+	return engine.RuntimePlan{
+		Name:            "fake",
+		DisplayName:     "Fake",
+		RequiredVersion: version,
+		Action:          engine.ActionInstall,
+		SHA256:          sum,
+		ArchivesDir:     archives,
+	}
+}
+
+func TestInstallSingleRuntime_Offline(t *testing.T) {
+	tempHome(t)
+
+	archives := t.TempDir()
+	sum := writeFixtureZip(t, filepath.Join(archives, "fake-runtime-v1.2.3-linux.zip"))
+	fake := &offlineFake{fakeInstaller: fakeInstaller{url: "http://127.0.0.1:0/unreachable"}}
+	registerFake(t, fake)
+
+	result, err := InstallSingleRuntime(context.Background(), offlinePlan(archives, "1.2.3", sum), "test-template", logger.New(), nil, ExecuteOptions{})
+	if err != nil {
+		t.Fatalf("offline install failed: %s", err)
+	}
+	if fake.resolved {
+		t.Error("offline mode should not resolve versions through the installer")
+	}
+	if result.Version != "1.2.3" {
+		t.Errorf("Version = %q, want the version pinned in the manifest", result.Version)
+	}
+	if _, err := os.Stat(filepath.Join(result.InstallPath, "bin", "fake")); err != nil {
+		t.Errorf("archive should be extracted into %s: %s", result.InstallPath, err)
+	}
+	if result.Bytes != 0 {
+		t.Errorf("nothing should be downloaded offline, got %d bytes", result.Bytes)
+	}
+}
+
+func TestInstallSingleRuntime_OfflinePhases(t *testing.T) {
+	tempHome(t)
+
+	archives := t.TempDir()
+	sum := writeFixtureZip(t, filepath.Join(archives, "fake-runtime-v1.2.3-linux.zip"))
+	registerFake(t, &offlineFake{fakeInstaller: fakeInstaller{url: "http://127.0.0.1:0/unreachable"}})
+
+	var events []ProgressEvent
+	record := func(ev ProgressEvent) { events = append(events, ev) }
+	if _, err := InstallSingleRuntime(context.Background(), offlinePlan(archives, "1.2.3", sum), "test-template", logger.New(), record, ExecuteOptions{}); err != nil {
+		t.Fatalf("offline install failed: %s", err)
+	}
+
+	want := []ProgressEvent{
+		{Phase: PhaseResolving},
+		{Phase: PhaseVerifying},
+		{Phase: PhaseExtracting, Done: 0, Total: 1},
+		{Phase: PhaseExtracting, Done: 1, Total: 1},
+		{Phase: PhaseLinking},
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}
+
+func TestInstallSingleRuntime_OfflineChecksumMismatch(t *testing.T) {
+	tempHome(t)
+
+	archives := t.TempDir()
+	writeFixtureZip(t, filepath.Join(archives, "fake-runtime-v1.2.3-linux.zip"))
+	registerFake(t, &offlineFake{})
+
+	wrong := strings.Repeat("0", 64)
+	_, err := InstallSingleRuntime(context.Background(), offlinePlan(archives, "1.2.3", wrong), "test-template", logger.New(), nil, ExecuteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "[runtimes_checksums]") {
+		t.Fatalf("expected a pinned checksum mismatch, got %v", err)
+	}
+}
+
+func TestInstallSingleRuntime_OfflineWithoutChecksum(t *testing.T) {
+	tempHome(t)
+
+	archives := t.TempDir()
+	writeFixtureZip(t, filepath.Join(archives, "fake-runtime-v1.2.3-linux.zip"))
+	registerFake(t, &offlineFake{})
+
+	if _, err := InstallSingleRuntime(context.Background(), offlinePlan(archives, "1.2.3", ""), "test-template", logger.New(), nil, ExecuteOptions{}); err != nil {
+		t.Errorf("offline install without a pinned checksum should still succeed, got %s", err)
+	}
+}
+
+func TestInstallSingleRuntime_OfflineNeedsExactVersion(t *testing.T) {
+	tempHome(t)
+
+	archives := t.TempDir()
+	fake := &offlineFake{}
+	registerFake(t, fake)
+
+	for _, req := range []string{"latest", ">=20.0.0", "^1.2", "1.2"} {
+		_, err := InstallSingleRuntime(context.Background(), offlinePlan(archives, req, ""), "test-template", logger.New(), nil, ExecuteOptions{})
+		if err == nil || !strings.Contains(err.Error(), "exact version") {
+			t.Errorf("requirement %q: expected an exact version error, got %v", req, err)
+		}
+	}
+	if fake.resolved {
+		t.Error("offline mode should never fall back to the installer's ResolveVersion")
+	}
+}
+
+func TestInstallSingleRuntime_OfflineMissingArchive(t *testing.T) {
+	tempHome(t)
+
+	registerFake(t, &offlineFake{})
+
+	_, err := InstallSingleRuntime(context.Background(), offlinePlan(t.TempDir(), "1.2.3", ""), "test-template", logger.New(), nil, ExecuteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no archive matching fake-runtime-v1.2.3-*.zip") {
+		t.Errorf("expected a missing archive error, got %v", err)
+	}
+}
+
+func TestInstallSingleRuntime_OfflineUnsupported(t *testing.T) {
+	tempHome(t)
+
+	registerFake(t, &fakeInstaller{})
+
+	_, err := InstallSingleRuntime(context.Background(), offlinePlan(t.TempDir(), "1.2.3", ""), "test-template", logger.New(), nil, ExecuteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "can't be installed in offline mode") {
+		t.Errorf("expected an unsupported offline install error, got %v", err)
+	}
+}
+
+func TestFindArchive_Ambiguous(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "cpython-3.12.8+20241206-x86_64-unknown-linux-gnu-install_only.tar.gz"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "cpython-3.12.8+20241219-x86_64-unknown-linux-gnu-install_only.tar.gz"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "cpython-3.12.8+20241219-x86_64-unknown-linux-gnu-install_only_stripped.tar.gz"), nil, 0o644)
+
+	_, err := findArchive(dir, "cpython-3.12.8+*-x86_64-unknown-linux-gnu-install_only.tar.gz")
+	if err == nil || !strings.Contains(err.Error(), "more than one archive") {
+		t.Errorf("expected an ambiguous match error, got %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "cpython-3.12.8+20241206-x86_64-unknown-linux-gnu-install_only.tar.gz"))
+	got, err := findArchive(dir, "cpython-3.12.8+*-x86_64-unknown-linux-gnu-install_only.tar.gz")
+	if err != nil {
+		t.Fatalf("findArchive() error: %s", err)
+	}
+	if filepath.Base(got) != "cpython-3.12.8+20241219-x86_64-unknown-linux-gnu-install_only.tar.gz" {
+		t.Errorf("findArchive() = %s, want the install_only archive", got)
+	}
+}
@@ -3,115 +3,41 @@ package install
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/templatr/templatr-setup/internal/state"
 )
 
 // SetEnvVar sets a persistent user-level environment variable (e.g., JAVA_HOME).
-// Returns the state entry for tracking.
-func SetEnvVar(name, value string) (*state.EnvModification, error) {
-	if runtime.GOOS == "windows" {
-		return setEnvVarWindows(name, value)
-	}
-	return setEnvVarUnix(name, value)
+// Returns the state entry for tracking and the modifications made, for the
+// "Environment changes" summary.
+func SetEnvVar(name, value string) (*state.EnvModification, []EnvChange, error) {
+	return setEnvVar(name, value)
 }
 
 // RemoveEnvVar removes a persistent user-level environment variable.
 func RemoveEnvVar(entry state.EnvModification) error {
-	if runtime.GOOS == "windows" {
-		return removeEnvVarWindows(entry)
-	}
-	return removeEnvVarUnix(entry)
+	return removeEnvVar(entry)
 }
 
 // AddToPath adds a directory to the user's PATH.
-// Returns the state entry for tracking, or nil if no modification was needed.
-func AddToPath(binDir string) (*state.PathModification, error) {
-	if runtime.GOOS == "windows" {
-		return addToPathWindows(binDir)
-	}
-	return addToPathUnix(binDir)
+// Returns the state entry for tracking (nil if no modification was needed)
+// and the modifications made, for the "Environment changes" summary.
+func AddToPath(binDir string) (*state.PathModification, []EnvChange, error) {
+	return addToPath(binDir)
 }
 
 // RemoveFromPath removes a directory from the user's PATH.
 func RemoveFromPath(entry state.PathModification) error {
-	if runtime.GOOS == "windows" {
-		return removeFromPathWindows(entry)
-	}
-	return removeFromPathUnix(entry)
+	return removeFromPath(entry)
 }
 
-// addToPathWindows adds to the user-level PATH on Windows via PowerShell.
-func addToPathWindows(binDir string) (*state.PathModification, error) {
-	// Read current user PATH
-	cmd := exec.Command("powershell", "-NoProfile", "-Command",
-		`[Environment]::GetEnvironmentVariable("PATH", "User")`)
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read user PATH: %w", err)
-	}
-
-	currentPath := strings.TrimSpace(string(out))
-
-	// Check if already in PATH
-	for _, p := range strings.Split(currentPath, ";") {
-		if strings.EqualFold(strings.TrimSpace(p), binDir) {
-			return nil, nil // already there
-		}
-	}
-
-	// Prepend to PATH
-	var newPath string
-	if currentPath == "" {
-		newPath = binDir
-	} else {
-		newPath = binDir + ";" + currentPath
-	}
-
-	cmd = exec.Command("powershell", "-NoProfile", "-Command",
-		fmt.Sprintf(`[Environment]::SetEnvironmentVariable("PATH", "%s", "User")`,
-			strings.ReplaceAll(newPath, `"`, `\"`)))
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to set user PATH: %w", err)
-	}
-
-	// Also update current process PATH
-	os.Setenv("PATH", binDir+";"+os.Getenv("PATH"))
-
-	return &state.PathModification{
-		Method: "windows_env",
-		Value:  binDir,
-	}, nil
-}
-
-// removeFromPathWindows removes a directory from user-level PATH on Windows.
-func removeFromPathWindows(entry state.PathModification) error {
-	cmd := exec.Command("powershell", "-NoProfile", "-Command",
-		`[Environment]::GetEnvironmentVariable("PATH", "User")`)
-	out, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("failed to read user PATH: %w", err)
-	}
-
-	currentPath := strings.TrimSpace(string(out))
-	parts := strings.Split(currentPath, ";")
-	var filtered []string
-	for _, p := range parts {
-		if !strings.EqualFold(strings.TrimSpace(p), entry.Value) {
-			filtered = append(filtered, p)
-		}
-	}
-
-	newPath := strings.Join(filtered, ";")
-	cmd = exec.Command("powershell", "-NoProfile", "-Command",
-		fmt.Sprintf(`[Environment]::SetEnvironmentVariable("PATH", "%s", "User")`,
-			strings.ReplaceAll(newPath, `"`, `\"`)))
-	return cmd.Run()
-}
+// fishConfigFile is fish's config file, relative to home. fish doesn't read
+// .bashrc or .zshrc, so it gets its own lines in fish syntax.
+var fishConfigFile = filepath.Join(".config", "fish", "config.fish")
 
 // shellConfigFiles returns the shell config files to modify on the current system.
 func shellConfigFiles() []string {
@@ -131,6 +57,9 @@ func shellConfigFiles() []string {
 	if strings.Contains(shell, "bash") || fileExists(filepath.Join(home, ".bashrc")) {
 		files = append(files, filepath.Join(home, ".bashrc"))
 	}
+	if filepath.Base(shell) == "fish" || fileExists(filepath.Join(home, fishConfigFile)) {
+		files = append(files, filepath.Join(home, fishConfigFile))
+	}
 
 	// If no shell detected, default to both common configs
 	if len(files) == 0 {
@@ -144,86 +73,286 @@ func shellConfigFiles() []string {
 	return files
 }
 
-// addToPathUnix appends an export line to shell config files.
-func addToPathUnix(binDir string) (*state.PathModification, error) {
-	exportLine := fmt.Sprintf(`export PATH="%s:$PATH"`, binDir)
-	marker := fmt.Sprintf("# templatr-setup: %s", binDir)
-	fullLine := marker + "\n" + exportLine
+// isFishConfig reports whether rcFile is fish's config file.
+func isFishConfig(rcFile string) bool {
+	return strings.HasSuffix(rcFile, string(filepath.Separator)+fishConfigFile)
+}
+
+// shellMethod is the state method for a change written to rcFile.
+func shellMethod(rcFile string) string {
+	if isFishConfig(rcFile) {
+		return "fish"
+	}
+	return "shell_rc"
+}
+
+// pathLine is the shell config line prepending dir to PATH, in fish
+// syntax if fish is set.
+func pathLine(dir string, fish bool) string {
+	if fish {
+		// -g rather than -U: a universal variable would outlive the line,
+		// so removing it on uninstall wouldn't undo it
+		return fmt.Sprintf(`fish_add_path -g "%s"`, dir)
+	}
+	return fmt.Sprintf(`export PATH="%s:$PATH"`, dir)
+}
+
+// envLine is the shell config line setting name to value, in fish syntax
+// if fish is set.
+func envLine(name, value string, fish bool) string {
+	if fish {
+		return fmt.Sprintf(`set -gx %s "%s"`, name, value)
+	}
+	return fmt.Sprintf(`export %s="%s"`, name, value)
+}
+
+// profileLine is the PowerShell line prepending dir to PATH. Single quotes
+// keep $ and backticks in dir literal.
+func profileLine(dir string) string {
+	return fmt.Sprintf(`$env:PATH = '%s;' + $env:PATH`, strings.ReplaceAll(dir, "'", "''"))
+}
+
+// shellMarkerPrefix starts the comment marking each block the tool writes
+// to a shell config file; the PATH directory or env var name follows.
+const shellMarkerPrefix = "# templatr-setup: "
+
+// utf8BOM starts the PowerShell profiles the tool creates. Without it
+// Windows PowerShell 5.1 reads a profile in the ANSI code page, garbling
+// non-ASCII paths such as ones under a user name with accents.
+const utf8BOM = "\ufeff"
+
+// addToPathUnix appends an export line to shell config files, or a
+// fish_add_path line to fish's. A file that already has a block for the
+// same runtime, for any version or its current link, gets that block
+// rewritten in place instead, so upgrades don't pile up PATH lines.
+func addToPathUnix(binDir string) (*state.PathModification, []EnvChange, error) {
+	marker := shellMarkerPrefix + binDir
+	line := func(rcFile string) string { return pathLine(binDir, isFishConfig(rcFile)) }
+	var replaces func(value string) bool
+	if rt := managedRuntime(binDir); rt != "" {
+		replaces = func(value string) bool { return managedRuntime(value) == rt }
+	}
 
 	files := shellConfigFiles()
 	if len(files) == 0 {
-		return nil, fmt.Errorf("no shell config files found")
+		return nil, nil, fmt.Errorf("no shell config files found")
 	}
 
-	var modifiedFile string
+	written, modified := writeShellBlocks(files, marker, line, replaces)
+
+	// Also update current process PATH
+	os.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+
+	if len(modified) == 0 {
+		return nil, []EnvChange{{Kind: ChangePath, Name: "PATH", Value: binDir, Unchanged: true}}, nil
+	}
+
+	var changes []EnvChange
+	for _, rcFile := range modified {
+		changes = append(changes, EnvChange{Kind: ChangePath, Name: "PATH", Value: binDir, File: rcFile, Line: line(rcFile)})
+	}
+	recorded := modified[len(modified)-1]
+	return &state.PathModification{
+		Method: shellMethod(recorded),
+		File:   recorded,
+		Files:  written,
+		Line:   marker + "\n" + line(recorded),
+		Value:  binDir,
+	}, changes, nil
+}
+
+// appendToShellConfigs appends marker and the line for each rc file to the
+// files that don't already contain marker, and returns the files it
+// modified.
+func appendToShellConfigs(files []string, marker string, line func(rcFile string) string) []string {
+	_, modified := writeShellBlocks(files, marker, line, nil)
+	return modified
+}
+
+// writeShellBlocks makes each file hold one block of marker and the line
+// for it. A block whose marker value satisfies replaces is rewritten in
+// place rather than a new one appended; further such blocks, and copies of
+// marker's, are dropped. It returns the files that now hold the block and
+// those of them it modified.
+func writeShellBlocks(files []string, marker string, line func(rcFile string) string, replaces func(value string) bool) (written, modified []string) {
 	for _, rcFile := range files {
 		content, err := os.ReadFile(rcFile)
 		if err != nil && !os.IsNotExist(err) {
 			continue
 		}
 
-		// Skip if already added
-		if strings.Contains(string(content), marker) {
+		updated, changed := rewriteShellBlock(string(content), marker, line(rcFile), replaces)
+		if !changed {
+			written = append(written, rcFile)
 			continue
 		}
+		if os.IsNotExist(err) && strings.HasSuffix(rcFile, ".ps1") {
+			updated = utf8BOM + updated
+		}
 
-		f, err := os.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-		if err != nil {
+		// ~/.config/fish may not exist yet
+		if err := os.MkdirAll(filepath.Dir(rcFile), 0o755); err != nil {
 			continue
 		}
+		// Written in place rather than renamed over, so an rc file that is
+		// a symlink into a dotfiles repo stays one
+		if err := os.WriteFile(rcFile, []byte(updated), 0o644); err != nil {
+			continue
+		}
+		written = append(written, rcFile)
+		modified = append(modified, rcFile)
+	}
+	return written, modified
+}
 
-		if _, err := fmt.Fprintf(f, "\n%s\n", fullLine); err != nil {
-			f.Close()
+// rewriteShellBlock returns content holding exactly one block of marker
+// followed by line, where the first existing block for marker or one whose
+// value satisfies replaces was, or appended at the end if there was none.
+// It reports whether content changed.
+func rewriteShellBlock(content, marker, line string, replaces func(value string) bool) (string, bool) {
+	value := strings.TrimPrefix(marker, shellMarkerPrefix)
+	lines := strings.Split(content, "\n")
+	placed := false
+
+	out := editBlocks(lines, parseManagedBlocks(lines), func(b ManagedBlock) []string {
+		if b.Value != value && (replaces == nil || !replaces(b.Value)) {
+			return lines[b.Start:b.End]
+		}
+		if placed {
+			return nil
+		}
+		placed = true
+		if b.Value == value {
+			// Already there: keep it as written
+			return lines[b.Start:b.End]
+		}
+		return []string{marker, line}
+	})
+
+	if !placed {
+		return content + fmt.Sprintf("\n%s\n%s\n", marker, line), true
+	}
+	updated := strings.Join(out, "\n")
+	return updated, updated != content
+}
+
+// ManagedBlock is a block the tool wrote to a shell config file or
+// PowerShell profile: a "# templatr-setup: " marker naming the PATH
+// directory or env var, and the line it marks.
+type ManagedBlock struct {
+	Value      string // the PATH directory or env var name in the marker
+	Line       string // the marked line, or "" if the marker is the last line
+	Start, End int    // the block is lines [Start, End) of the file, from 0
+}
+
+// ParseManagedBlocks returns the blocks in rcFile, in the order they
+// appear.
+func ParseManagedBlocks(rcFile string) ([]ManagedBlock, error) {
+	content, err := os.ReadFile(rcFile)
+	if err != nil {
+		return nil, err
+	}
+	return parseManagedBlocks(strings.Split(string(content), "\n")), nil
+}
+
+// parseManagedBlocks finds the blocks in lines. A marker followed by a
+// blank line or another marker, as a hand edit can leave, is a block on
+// its own, so neither is taken for its line.
+func parseManagedBlocks(lines []string) []ManagedBlock {
+	var blocks []ManagedBlock
+	for i := 0; i < len(lines); i++ {
+		value, ok := strings.CutPrefix(strings.TrimSpace(strings.TrimPrefix(lines[i], utf8BOM)), shellMarkerPrefix)
+		if !ok {
 			continue
 		}
-		f.Close()
-		modifiedFile = rcFile
+		b := ManagedBlock{Value: value, Start: i, End: i + 1}
+		if next := i + 1; next < len(lines) && isBlockLine(lines[next]) {
+			b.Line = strings.TrimSpace(lines[next])
+			b.End++
+			i++
+		}
+		blocks = append(blocks, b)
 	}
+	return blocks
+}
 
-	// Also update current process PATH
-	os.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+// isBlockLine reports whether line, following a marker, can be the line
+// the marker is for.
+func isBlockLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return line != "" && !strings.HasPrefix(line, shellMarkerPrefix)
+}
 
-	if modifiedFile != "" {
-		return &state.PathModification{
-			Method: "shell_rc",
-			File:   modifiedFile,
-			Line:   fullLine,
-			Value:  binDir,
-		}, nil
+// editBlocks returns lines with each of blocks replaced by what edit
+// returns for it: nil drops the block, lines[b.Start:b.End] keeps it.
+// Every line outside the blocks is kept as it was.
+func editBlocks(lines []string, blocks []ManagedBlock, edit func(b ManagedBlock) []string) []string {
+	var out []string
+	next := 0
+	for _, b := range blocks {
+		out = append(out, lines[next:b.Start]...)
+		out = append(out, edit(b)...)
+		next = b.End
 	}
+	return append(out, lines[next:]...)
+}
 
-	return nil, nil
+// removeBlocks rewrites rcFile without the blocks drop picks, and reports
+// whether it removed any. The rest of the file is kept byte for byte.
+func removeBlocks(rcFile string, drop func(b ManagedBlock) bool) (bool, error) {
+	content, err := os.ReadFile(rcFile)
+	if err != nil {
+		return false, err
+	}
+	lines := strings.Split(string(content), "\n")
+	removed := false
+	out := editBlocks(lines, parseManagedBlocks(lines), func(b ManagedBlock) []string {
+		if drop(b) {
+			removed = true
+			return nil
+		}
+		return lines[b.Start:b.End]
+	})
+	if !removed {
+		return false, nil
+	}
+	return true, os.WriteFile(rcFile, []byte(strings.Join(out, "\n")), 0o644)
 }
 
-// removeFromPathUnix removes the export line from shell config files.
+// removeFromPathUnix removes the export or fish_add_path line from every
+// shell config file it was written to.
 func removeFromPathUnix(entry state.PathModification) error {
-	if entry.File == "" {
+	files := entry.ConfigFiles()
+	if len(files) == 0 {
 		return nil
 	}
+	return removeFromShellConfigs(files, shellMarkerPrefix+entry.Value)
+}
 
-	content, err := os.ReadFile(entry.File)
-	if err != nil {
+// removeFromShellConfigs removes the line following each marker from files,
+// the first of which must exist, and from any other shell config file it
+// may also have been written to; older state records only one of them.
+func removeFromShellConfigs(files []string, marker string) error {
+	if err := removeMarkedLine(files[0], marker); err != nil {
 		return err
 	}
-
-	marker := fmt.Sprintf("# templatr-setup: %s", entry.Value)
-	lines := strings.Split(string(content), "\n")
-	var filtered []string
-	skipNext := false
-
-	for _, line := range lines {
-		if strings.Contains(line, marker) {
-			skipNext = true
+	for _, rcFile := range slices.Concat(files[1:], shellConfigFiles()) {
+		if rcFile == files[0] {
 			continue
 		}
-		if skipNext {
-			skipNext = false
-			continue
+		if err := removeMarkedLine(rcFile, marker); err != nil && !os.IsNotExist(err) {
+			return err
 		}
-		filtered = append(filtered, line)
 	}
+	return nil
+}
 
-	return os.WriteFile(entry.File, []byte(strings.Join(filtered, "\n")), 0o644)
+// removeMarkedLine removes every block for marker from rcFile. The file is
+// left untouched if it has none.
+func removeMarkedLine(rcFile, marker string) error {
+	value := strings.TrimPrefix(marker, shellMarkerPrefix)
+	_, err := removeBlocks(rcFile, func(b ManagedBlock) bool { return b.Value == value })
+	return err
 }
 
 func fileExists(path string) bool {
@@ -233,103 +362,39 @@ func fileExists(path string) bool {
 
 // --- Environment variable management ---
 
-func setEnvVarWindows(name, value string) (*state.EnvModification, error) {
-	cmd := exec.Command("powershell", "-NoProfile", "-Command",
-		fmt.Sprintf(`[Environment]::SetEnvironmentVariable("%s", "%s", "User")`,
-			name, strings.ReplaceAll(value, `"`, `\""`)))
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to set %s: %w", name, err)
-	}
-
-	os.Setenv(name, value)
-
-	return &state.EnvModification{
-		Name:   name,
-		Value:  value,
-		Method: "windows_env",
-	}, nil
-}
-
-func removeEnvVarWindows(entry state.EnvModification) error {
-	cmd := exec.Command("powershell", "-NoProfile", "-Command",
-		fmt.Sprintf(`[Environment]::SetEnvironmentVariable("%s", $null, "User")`, entry.Name))
-	return cmd.Run()
-}
-
-func setEnvVarUnix(name, value string) (*state.EnvModification, error) {
-	exportLine := fmt.Sprintf(`export %s="%s"`, name, value)
-	marker := fmt.Sprintf("# templatr-setup: %s", name)
-	fullLine := marker + "\n" + exportLine
+func setEnvVarUnix(name, value string) (*state.EnvModification, []EnvChange, error) {
+	marker := shellMarkerPrefix + name
+	line := func(rcFile string) string { return envLine(name, value, isFishConfig(rcFile)) }
 
 	files := shellConfigFiles()
 	if len(files) == 0 {
-		return nil, fmt.Errorf("no shell config files found")
+		return nil, nil, fmt.Errorf("no shell config files found")
 	}
 
-	var modifiedFile string
-	for _, rcFile := range files {
-		content, err := os.ReadFile(rcFile)
-		if err != nil && !os.IsNotExist(err) {
-			continue
-		}
-
-		if strings.Contains(string(content), marker) {
-			continue
-		}
-
-		f, err := os.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-		if err != nil {
-			continue
-		}
-
-		if _, err := fmt.Fprintf(f, "\n%s\n", fullLine); err != nil {
-			f.Close()
-			continue
-		}
-		f.Close()
-		modifiedFile = rcFile
-	}
+	modified := appendToShellConfigs(files, marker, line)
 
 	os.Setenv(name, value)
 
-	if modifiedFile != "" {
-		return &state.EnvModification{
-			Name:   name,
-			Value:  value,
-			Method: "shell_rc",
-			File:   modifiedFile,
-		}, nil
+	if len(modified) == 0 {
+		return nil, []EnvChange{{Kind: ChangeEnv, Name: name, Value: value, Unchanged: true}}, nil
 	}
 
-	return nil, nil
+	var changes []EnvChange
+	for _, rcFile := range modified {
+		changes = append(changes, EnvChange{Kind: ChangeEnv, Name: name, Value: value, File: rcFile, Line: line(rcFile)})
+	}
+	recorded := modified[len(modified)-1]
+	return &state.EnvModification{
+		Name:   name,
+		Value:  value,
+		Method: shellMethod(recorded),
+		File:   recorded,
+	}, changes, nil
 }
 
 func removeEnvVarUnix(entry state.EnvModification) error {
 	if entry.File == "" {
 		return nil
 	}
-
-	content, err := os.ReadFile(entry.File)
-	if err != nil {
-		return err
-	}
-
-	marker := fmt.Sprintf("# templatr-setup: %s", entry.Name)
-	lines := strings.Split(string(content), "\n")
-	var filtered []string
-	skipNext := false
-
-	for _, line := range lines {
-		if strings.Contains(line, marker) {
-			skipNext = true
-			continue
-		}
-		if skipNext {
-			skipNext = false
-			continue
-		}
-		filtered = append(filtered, line)
-	}
-
-	return os.WriteFile(entry.File, []byte(strings.Join(filtered, "\n")), 0o644)
+	return removeFromShellConfigs([]string{entry.File}, shellMarkerPrefix+entry.Name)
 }
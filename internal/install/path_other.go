@@ -0,0 +1,32 @@
+//go:build !windows
+
+package install
+
+import "github.com/templatr/templatr-setup/internal/state"
+
+func addToPath(binDir string) (*state.PathModification, []EnvChange, error) {
+	return addToPathUnix(binDir)
+}
+
+func removeFromPath(entry state.PathModification) error {
+	return removeFromPathUnix(entry)
+}
+
+func setEnvVar(name, value string) (*state.EnvModification, []EnvChange, error) {
+	return setEnvVarUnix(name, value)
+}
+
+func removeEnvVar(entry state.EnvModification) error {
+	return removeEnvVarUnix(entry)
+}
+
+// probeUserEnv is only needed on Windows, where PATH lives in the registry.
+func probeUserEnv() error {
+	return nil
+}
+
+// blockFiles returns the shell config files the tool writes its PATH and
+// env var blocks to.
+func blockFiles() []string {
+	return shellConfigFiles()
+}
@@ -0,0 +1,338 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fishHome isolates HOME with a fish config holding existing content.
+func fishHome(t *testing.T) (home, config, original string) {
+	t.Helper()
+	home = preflightHome(t)
+	t.Setenv("SHELL", "/usr/bin/fish")
+	t.Setenv("PATH", os.Getenv("PATH"))
+	config = filepath.Join(home, ".config", "fish", "config.fish")
+	original = "set -g fish_greeting\n"
+	if err := os.MkdirAll(filepath.Dir(config), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return home, config, original
+}
+
+func TestAddToPath_Fish(t *testing.T) {
+	skipOnWindows(t)
+	home, config, original := fishHome(t)
+	binDir := filepath.Join(home, ".templatr", "runtimes", "node", "current", "bin")
+
+	mod, changes, err := AddToPath(binDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod == nil || mod.Method != "fish" || mod.File != config {
+		t.Fatalf("AddToPath() = %+v, want a fish entry for %s", mod, config)
+	}
+	wantLine := `fish_add_path -g "` + binDir + `"`
+	if len(changes) != 1 || changes[0].Line != wantLine {
+		t.Errorf("changes = %+v, want one with %s", changes, wantLine)
+	}
+	data, _ := os.ReadFile(config)
+	if !strings.Contains(string(data), "# templatr-setup: "+binDir+"\n"+wantLine) {
+		t.Errorf("config.fish missing the marked line:\n%s", data)
+	}
+	if fileExists(filepath.Join(home, ".bashrc")) || fileExists(filepath.Join(home, ".zshrc")) {
+		t.Error("a fish-only user should not get a .bashrc or .zshrc")
+	}
+
+	// A second run changes nothing
+	if again, _, _ := AddToPath(binDir); again != nil {
+		t.Errorf("second AddToPath() = %+v, want no change", again)
+	}
+
+	if err := RemoveFromPath(*mod); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(config)
+	if strings.TrimRight(string(data), "\n") != strings.TrimRight(original, "\n") {
+		t.Errorf("config.fish after removal = %q, want %q", data, original)
+	}
+}
+
+func TestSetEnvVar_Fish(t *testing.T) {
+	skipOnWindows(t)
+	t.Setenv("JAVA_HOME", "")
+	home, config, original := fishHome(t)
+	value := filepath.Join(home, ".templatr", "runtimes", "java", "current")
+
+	mod, _, err := SetEnvVar("JAVA_HOME", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod == nil || mod.Method != "fish" || mod.File != config {
+		t.Fatalf("SetEnvVar() = %+v, want a fish entry for %s", mod, config)
+	}
+	data, _ := os.ReadFile(config)
+	if !strings.Contains(string(data), "# templatr-setup: JAVA_HOME\n"+`set -gx JAVA_HOME "`+value+`"`) {
+		t.Errorf("config.fish missing the marked line:\n%s", data)
+	}
+
+	if err := RemoveEnvVar(*mod); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(config)
+	if strings.TrimRight(string(data), "\n") != strings.TrimRight(original, "\n") {
+		t.Errorf("config.fish after removal = %q, want %q", data, original)
+	}
+}
+
+func TestAddToPath_FishDetectedFromShell(t *testing.T) {
+	skipOnWindows(t)
+	home := preflightHome(t)
+	t.Setenv("SHELL", "/opt/homebrew/bin/fish")
+	t.Setenv("PATH", os.Getenv("PATH"))
+
+	mod, _, err := AddToPath(filepath.Join(home, "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod == nil || mod.File != filepath.Join(home, ".config", "fish", "config.fish") {
+		t.Errorf("AddToPath() = %+v, want config.fish created", mod)
+	}
+}
+
+func TestRemoveFromPath_AllShells(t *testing.T) {
+	skipOnWindows(t)
+	home, config, _ := fishHome(t)
+	bashrc := filepath.Join(home, ".bashrc")
+	os.WriteFile(bashrc, []byte("alias ll='ls -l'\n"), 0o644)
+	binDir := filepath.Join(home, "bin")
+
+	mod, changes, err := AddToPath(binDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].File != bashrc || changes[1].File != config {
+		t.Fatalf("changes = %+v, want .bashrc and config.fish", changes)
+	}
+	if !strings.HasPrefix(changes[0].Line, "export PATH=") {
+		t.Errorf(".bashrc line = %q, want POSIX syntax", changes[0].Line)
+	}
+
+	// state records one file; the line goes from both
+	if err := RemoveFromPath(*mod); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{bashrc, config} {
+		if data, _ := os.ReadFile(f); strings.Contains(string(data), binDir) {
+			t.Errorf("%s still mentions %s:\n%s", f, binDir, data)
+		}
+	}
+}
+
+func TestAddToPath_UpgradeRewritesExistingBlock(t *testing.T) {
+	skipOnWindows(t)
+	home := preflightHome(t)
+	t.Setenv("SHELL", "/bin/zsh")
+	t.Setenv("PATH", os.Getenv("PATH"))
+	bashrc := filepath.Join(home, ".bashrc")
+	zshrc := filepath.Join(home, ".zshrc")
+	originals := map[string]string{bashrc: "alias ll='ls -l'\n", zshrc: "setopt autocd\n"}
+	for f, content := range originals {
+		os.WriteFile(f, []byte(content), 0o644)
+	}
+
+	runtimes, _ := RuntimesDir()
+	oldBin := filepath.Join(runtimes, "node", "20.11.0", "bin")
+	if _, _, err := AddToPath(oldBin); err != nil {
+		t.Fatal(err)
+	}
+	// Something the user added after the block must stay below it
+	for f := range originals {
+		data, _ := os.ReadFile(f)
+		os.WriteFile(f, append(data, "export EDITOR=vim\n"...), 0o644)
+	}
+
+	newBin := filepath.Join(runtimes, "node", "current", "bin")
+	mod, changes, err := AddToPath(newBin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod == nil || len(mod.Files) != 2 || len(changes) != 2 {
+		t.Fatalf("AddToPath() = %+v, %+v, want both rc files rewritten and recorded", mod, changes)
+	}
+	for f := range originals {
+		data, _ := os.ReadFile(f)
+		content := string(data)
+		if n := strings.Count(content, shellMarkerPrefix); n != 1 {
+			t.Errorf("%s has %d templatr-setup blocks, want 1:\n%s", f, n, content)
+		}
+		if strings.Contains(content, oldBin) || !strings.Contains(content, newBin) {
+			t.Errorf("%s should have the 20.11.0 block replaced by current:\n%s", f, content)
+		}
+		if strings.Index(content, newBin) > strings.Index(content, "EDITOR") {
+			t.Errorf("%s should have the block rewritten in place, not appended:\n%s", f, content)
+		}
+	}
+
+	// Another runtime still gets its own block
+	goBin := filepath.Join(runtimes, "go", "current", "bin")
+	if other, _, _ := AddToPath(goBin); other == nil {
+		t.Error("a different runtime should add a new block")
+	}
+	if again, _, _ := AddToPath(newBin); again != nil {
+		t.Errorf("AddToPath() again = %+v, want no change", again)
+	}
+
+	if err := RemoveFromPath(*mod); err != nil {
+		t.Fatal(err)
+	}
+	for f := range originals {
+		data, _ := os.ReadFile(f)
+		content := string(data)
+		if strings.Contains(content, newBin) || !strings.Contains(content, goBin) {
+			t.Errorf("%s should keep only the go block:\n%s", f, content)
+		}
+		if !strings.HasPrefix(content, originals[f]) || !strings.Contains(content, "export EDITOR=vim") {
+			t.Errorf("%s lost the user's own lines:\n%s", f, content)
+		}
+	}
+}
+
+// messyBashrc is a .bashrc left by several releases: versioned blocks for
+// two Node versions, a current one, a variable, and the user's own lines
+// and comments between them, one of them indented and CRLF-terminated.
+const messyBashrc = `# ~/.bashrc
+alias ll='ls -la'
+
+# templatr-setup: /home/dev/.templatr/runtimes/node/18.19.0/bin
+export PATH="/home/dev/.templatr/runtimes/node/18.19.0/bin:$PATH"
+# my own node, keep below
+export NODE_OPTIONS=--max-old-space-size=4096
+
+# templatr-setup: /home/dev/.templatr/runtimes/node/20.11.0/bin
+export PATH="/home/dev/.templatr/runtimes/node/20.11.0/bin:$PATH"
+   # templatr-setup: JAVA_HOME` + "\r" + `
+export JAVA_HOME="/home/dev/.templatr/runtimes/java/current"` + "\r" + `
+# templatr-setup: /home/dev/.templatr/runtimes/node/current/bin
+export PATH="/home/dev/.templatr/runtimes/node/current/bin:$PATH"
+# templatr-setup: /home/dev/.templatr/runtimes/node/current/bin2
+`
+
+func TestParseManagedBlocks(t *testing.T) {
+	rc := filepath.Join(t.TempDir(), ".bashrc")
+	os.WriteFile(rc, []byte(messyBashrc), 0o644)
+
+	blocks, err := ParseManagedBlocks(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ManagedBlock{
+		{Value: "/home/dev/.templatr/runtimes/node/18.19.0/bin", Line: `export PATH="/home/dev/.templatr/runtimes/node/18.19.0/bin:$PATH"`, Start: 3, End: 5},
+		{Value: "/home/dev/.templatr/runtimes/node/20.11.0/bin", Line: `export PATH="/home/dev/.templatr/runtimes/node/20.11.0/bin:$PATH"`, Start: 8, End: 10},
+		{Value: "JAVA_HOME", Line: `export JAVA_HOME="/home/dev/.templatr/runtimes/java/current"`, Start: 10, End: 12},
+		{Value: "/home/dev/.templatr/runtimes/node/current/bin", Line: `export PATH="/home/dev/.templatr/runtimes/node/current/bin:$PATH"`, Start: 12, End: 14},
+		{Value: "/home/dev/.templatr/runtimes/node/current/bin2", Start: 14, End: 15},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("ParseManagedBlocks() = %+v, want %d blocks", blocks, len(want))
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, blocks[i], want[i])
+		}
+	}
+
+	if _, err := ParseManagedBlocks(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("a missing file should fail with a not-exist error, got %v", err)
+	}
+}
+
+func TestRemoveMarkedLine_Surgical(t *testing.T) {
+	rc := filepath.Join(t.TempDir(), ".bashrc")
+	os.WriteFile(rc, []byte(messyBashrc), 0o644)
+
+	// The marker for current/bin must not take current/bin2's with it
+	if err := removeMarkedLine(rc, shellMarkerPrefix+"/home/dev/.templatr/runtimes/node/current/bin"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(rc)
+	want := strings.Replace(messyBashrc, "# templatr-setup: /home/dev/.templatr/runtimes/node/current/bin\n"+
+		`export PATH="/home/dev/.templatr/runtimes/node/current/bin:$PATH"`+"\n", "", 1)
+	if string(data) != want {
+		t.Errorf("got:\n%q\nwant:\n%q", data, want)
+	}
+
+	// The CRLF block of an indented marker goes too, and nothing else
+	if err := removeMarkedLine(rc, shellMarkerPrefix+"JAVA_HOME"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(rc)
+	want = strings.Replace(want, "   # templatr-setup: JAVA_HOME\r\n"+`export JAVA_HOME="/home/dev/.templatr/runtimes/java/current"`+"\r\n", "", 1)
+	if string(data) != want {
+		t.Errorf("got:\n%q\nwant:\n%q", data, want)
+	}
+}
+
+func TestRewriteShellBlock_KeepsUserLines(t *testing.T) {
+	skipOnWindows(t)
+	SetRuntimesDir("/home/dev/.templatr/runtimes")
+	t.Cleanup(func() { SetRuntimesDir("") })
+
+	binDir := "/home/dev/.templatr/runtimes/node/current/bin"
+	marker := shellMarkerPrefix + binDir
+	line := pathLine(binDir, false)
+	replaces := func(value string) bool { return managedRuntime(value) == "node" }
+
+	got, changed := rewriteShellBlock(messyBashrc, marker, line, replaces)
+	if !changed {
+		t.Fatal("the versioned blocks should be rewritten")
+	}
+	want := `# ~/.bashrc
+alias ll='ls -la'
+
+# templatr-setup: /home/dev/.templatr/runtimes/node/current/bin
+export PATH="/home/dev/.templatr/runtimes/node/current/bin:$PATH"
+# my own node, keep below
+export NODE_OPTIONS=--max-old-space-size=4096
+
+   # templatr-setup: JAVA_HOME` + "\r" + `
+export JAVA_HOME="/home/dev/.templatr/runtimes/java/current"` + "\r" + `
+`
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestWriteShellBlocks_ProfileBOM(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "WindowsPowerShell", "Microsoft.PowerShell_profile.ps1")
+	existing := filepath.Join(dir, "existing.ps1")
+	os.WriteFile(existing, []byte("Set-PSReadLineOption -EditMode Emacs\n"), 0o644)
+
+	const value = `C:\Users\José\.templatr\runtimes\node\current`
+	marker := shellMarkerPrefix + value
+	line := func(string) string { return profileLine(value) }
+	if written, _ := writeShellBlocks([]string{profile, existing}, marker, line, nil); len(written) != 2 {
+		t.Fatalf("written = %q, want both profiles", written)
+	}
+
+	data, _ := os.ReadFile(profile)
+	if !strings.HasPrefix(string(data), utf8BOM) {
+		t.Errorf("a new profile should start with a UTF-8 BOM, got %q", data)
+	}
+	if blocks := parseManagedBlocks(strings.Split(string(data), "\n")); len(blocks) != 1 || blocks[0].Value != value {
+		t.Errorf("blocks = %+v, want the one for %s", blocks, value)
+	}
+	if data, _ := os.ReadFile(existing); strings.HasPrefix(string(data), utf8BOM) {
+		t.Errorf("an existing profile should be left without a BOM, got %q", data)
+	}
+
+	// A marker on the first line, after a BOM, is still found
+	if blocks := parseManagedBlocks([]string{utf8BOM + marker, profileLine(value)}); len(blocks) != 1 || blocks[0].Value != value {
+		t.Errorf("blocks = %+v, want the marker after the BOM", blocks)
+	}
+}
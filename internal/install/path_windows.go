@@ -0,0 +1,252 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/templatr/templatr-setup/internal/state"
+	winreg "golang.org/x/sys/windows/registry"
+)
+
+var (
+	user32                 = syscall.NewLazyDLL("user32.dll")
+	procSendMessageTimeout = user32.NewProc("SendMessageTimeoutW")
+)
+
+const (
+	hwndBroadcast      = 0xffff
+	wmSettingChange    = 0x001a
+	smtoAbortIfHung    = 0x0002
+	broadcastTimeoutMs = 5000
+)
+
+// addToPath prepends binDir to the user PATH in HKCU\Environment, or, if
+// that can't be changed, adds it through the PowerShell profiles and cmd's
+// AutoRun instead.
+func addToPath(binDir string) (*state.PathModification, []EnvChange, error) {
+	entry, changes, err := addToUserPath(binDir)
+	if err == nil {
+		return entry, changes, nil
+	}
+	entry, changes, perr := addToPathProfile(binDir)
+	if perr != nil {
+		return nil, nil, fmt.Errorf("%w, and the PowerShell profile fallback failed: %s", err, perr)
+	}
+	return entry, changes, nil
+}
+
+// addToUserPath prepends binDir to the user PATH in HKCU\Environment.
+func addToUserPath(binDir string) (*state.PathModification, []EnvChange, error) {
+	change := EnvChange{Kind: ChangePath, Name: "PATH", Value: binDir, Registry: windowsEnvKey + `\PATH`}
+
+	key, err := openUserEnv()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read user PATH: %w", err)
+	}
+	defer key.Close()
+
+	current, valType, err := readUserVar(key, "PATH")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read user PATH: %w", err)
+	}
+	newPath, changed := prependPathEntry(current, binDir)
+	if !changed {
+		change.Unchanged = true
+		return nil, []EnvChange{change}, nil
+	}
+
+	// PATH is usually REG_EXPAND_SZ with %USERPROFILE%-style entries; keep it so
+	if valType == winreg.NONE {
+		valType = winreg.EXPAND_SZ
+	}
+	if err := writeUserVar(key, "PATH", newPath, valType); err != nil {
+		return nil, nil, fmt.Errorf("failed to set user PATH: %w", err)
+	}
+	broadcastEnvChange()
+
+	// Also update current process PATH
+	os.Setenv("PATH", binDir+";"+os.Getenv("PATH"))
+
+	return &state.PathModification{
+		Method: "windows_env",
+		Value:  binDir,
+	}, []EnvChange{change}, nil
+}
+
+// removeFromPath removes entry's directory from the user PATH, or from
+// the PowerShell profiles and AutoRun it was added to instead.
+func removeFromPath(entry state.PathModification) error {
+	if entry.Method == powershellProfileMethod {
+		return removeFromPathProfile(entry)
+	}
+	key, err := openUserEnv()
+	if err != nil {
+		return fmt.Errorf("failed to read user PATH: %w", err)
+	}
+	defer key.Close()
+
+	current, valType, err := readUserVar(key, "PATH")
+	if err != nil {
+		return fmt.Errorf("failed to read user PATH: %w", err)
+	}
+	newPath, changed := removePathEntry(current, entry.Value)
+	if !changed {
+		return nil
+	}
+	if err := writeUserVar(key, "PATH", newPath, valType); err != nil {
+		return fmt.Errorf("failed to set user PATH: %w", err)
+	}
+	broadcastEnvChange()
+	return nil
+}
+
+// setEnvVar sets a user environment variable in HKCU\Environment.
+func setEnvVar(name, value string) (*state.EnvModification, []EnvChange, error) {
+	key, err := openUserEnv()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	defer key.Close()
+
+	_, valType, err := readUserVar(key, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	if err := writeUserVar(key, name, value, valType); err != nil {
+		return nil, nil, fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	broadcastEnvChange()
+
+	os.Setenv(name, value)
+
+	return &state.EnvModification{
+		Name:   name,
+		Value:  value,
+		Method: "windows_env",
+	}, []EnvChange{{
+		Kind:     ChangeEnv,
+		Name:     name,
+		Value:    value,
+		Registry: windowsEnvKey + `\` + name,
+	}}, nil
+}
+
+// removeEnvVar deletes a user environment variable. One that is already
+// gone is not an error.
+func removeEnvVar(entry state.EnvModification) error {
+	key, err := openUserEnv()
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", entry.Name, err)
+	}
+	defer key.Close()
+
+	if err := key.DeleteValue(entry.Name); err != nil && !errors.Is(err, winreg.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", entry.Name, err)
+	}
+	broadcastEnvChange()
+	return nil
+}
+
+// openUserEnv opens HKCU\Environment for reading and writing.
+func openUserEnv() (winreg.Key, error) {
+	return winreg.OpenKey(winreg.CURRENT_USER, "Environment", winreg.QUERY_VALUE|winreg.SET_VALUE)
+}
+
+// probeUserEnv checks that HKCU\Environment can be opened for writing,
+// for Preflight.
+func probeUserEnv() error {
+	key, err := openUserEnv()
+	if err != nil {
+		return err
+	}
+	return key.Close()
+}
+
+// readUserVar returns a variable's raw value, unexpanded, and its type, or
+// winreg.NONE if it isn't set.
+func readUserVar(key winreg.Key, name string) (string, uint32, error) {
+	value, valType, err := key.GetStringValue(name)
+	if errors.Is(err, winreg.ErrNotExist) {
+		return "", winreg.NONE, nil
+	}
+	return value, valType, err
+}
+
+// writeUserVar writes a variable, keeping REG_EXPAND_SZ so %VAR% references
+// in it still expand. A new variable is REG_EXPAND_SZ only if it has one.
+func writeUserVar(key winreg.Key, name, value string, valType uint32) error {
+	if valType == winreg.EXPAND_SZ || (valType == winreg.NONE && strings.Contains(value, "%")) {
+		return key.SetExpandStringValue(name, value)
+	}
+	return key.SetStringValue(name, value)
+}
+
+// broadcastEnvChange tells running programs, Explorer in particular, that
+// the user environment changed, so processes they start see it without a
+// logoff. Programs that don't answer within the timeout are skipped.
+func broadcastEnvChange() {
+	env, err := syscall.UTF16PtrFromString("Environment")
+	if err != nil {
+		return
+	}
+	var result uintptr
+	procSendMessageTimeout.Call(
+		hwndBroadcast,
+		wmSettingChange,
+		0,
+		uintptr(unsafe.Pointer(env)),
+		smtoAbortIfHung,
+		broadcastTimeoutMs,
+		uintptr(unsafe.Pointer(&result)),
+	)
+}
+
+// prependPathEntry returns path with dir added in front, or path unchanged
+// and false if it already has dir. Entries are compared as Windows does:
+// case-insensitively, ignoring surrounding spaces, quotes and a trailing
+// backslash. Other entries are kept exactly as they were.
+func prependPathEntry(path, dir string) (string, bool) {
+	if strings.TrimSpace(path) == "" {
+		return dir, true
+	}
+	for _, p := range strings.Split(path, ";") {
+		if samePathEntry(p, dir) {
+			return path, false
+		}
+	}
+	return dir + ";" + path, true
+}
+
+// removePathEntry returns path without any entry matching dir, and whether
+// one was found.
+func removePathEntry(path, dir string) (string, bool) {
+	parts := strings.Split(path, ";")
+	kept := parts[:0]
+	for _, p := range parts {
+		if !samePathEntry(p, dir) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(parts) {
+		return path, false
+	}
+	return strings.Join(kept, ";"), true
+}
+
+func samePathEntry(entry, dir string) bool {
+	clean := func(s string) string {
+		s = strings.Trim(strings.TrimSpace(s), `"`)
+		return strings.TrimRight(s, `\`)
+	}
+	return clean(entry) != "" && strings.EqualFold(clean(entry), clean(dir))
+}
+
+// blockFiles returns the PowerShell profiles the tool writes its PATH
+// blocks to when the user environment can't be changed.
+func blockFiles() []string {
+	return powershellProfiles()
+}
@@ -0,0 +1,51 @@
+package install
+
+import "testing"
+
+func TestPrependPathEntry(t *testing.T) {
+	const dir = `C:\Users\dev\.templatr\runtimes\node\current`
+	tests := []struct {
+		name, path, want string
+		changed          bool
+	}{
+		{"empty", "", dir, true},
+		{"prepends", `%USERPROFILE%\bin;C:\Tools`, dir + `;%USERPROFILE%\bin;C:\Tools`, true},
+		{"keeps quotes and blanks", `"C:\Program Files\x";;C:\y`, dir + `;"C:\Program Files\x";;C:\y`, true},
+		{"already there", `C:\Tools;` + dir, `C:\Tools;` + dir, false},
+		{"different case", `c:\users\DEV\.templatr\runtimes\node\current;C:\Tools`, `c:\users\DEV\.templatr\runtimes\node\current;C:\Tools`, false},
+		{"trailing backslash", `C:\Tools;` + dir + `\`, `C:\Tools;` + dir + `\`, false},
+		{"quoted", `"` + dir + `"`, `"` + dir + `"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := prependPathEntry(tt.path, dir)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("prependPathEntry(%q) = %q, %v, want %q, %v", tt.path, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestRemovePathEntry(t *testing.T) {
+	const dir = `C:\Users\dev\.templatr\runtimes\node\current`
+	tests := []struct {
+		name, path, want string
+		changed          bool
+	}{
+		{"first", dir + `;%USERPROFILE%\bin;C:\Tools`, `%USERPROFILE%\bin;C:\Tools`, true},
+		{"middle, other case", `C:\Tools;C:\USERS\dev\.templatr\runtimes\node\current\;C:\y`, `C:\Tools;C:\y`, true},
+		{"every copy", dir + `;C:\Tools;` + dir, `C:\Tools`, true},
+		{"only entry", dir, "", true},
+		{"absent", `C:\Tools;"C:\Program Files\x"`, `C:\Tools;"C:\Program Files\x"`, false},
+		{"empty", "", "", false},
+		{"no prefix match", dir + `2;C:\Tools`, dir + `2;C:\Tools`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := removePathEntry(tt.path, dir)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("removePathEntry(%q) = %q, %v, want %q, %v", tt.path, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
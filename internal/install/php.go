@@ -1,29 +1,269 @@
 package install
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
 )
 
-// PHPInstaller handles PHP installation.
+// PHPInstaller handles PHP installation: static CLI builds from static-php
+// on macOS and Linux, official builds from windows.php.net on Windows.
 type PHPInstaller struct{}
 
 func (p *PHPInstaller) Name() string { return "php" }
 
+const (
+	phpStaticBaseURL   = "https://dl.static-php.dev/static-php-cli/common/"
+	phpWindowsBaseURL  = "https://windows.php.net/downloads/releases/"
+	phpWindowsIndexURL = phpWindowsBaseURL + "releases.json"
+)
+
+// phpBuild is a downloadable PHP archive for the current platform.
+type phpBuild struct {
+	Version     string
+	Filename    string
+	URL         string
+	SHA256      string // published inline (Windows)
+	ChecksumURL string // sha256sum-style file next to the archive (static-php)
+}
+
+// staticPHPEntry is one file in the static-php directory listing.
+type staticPHPEntry struct {
+	Name  string `json:"name"` // "php-8.3.10-cli-linux-x86_64.tar.gz"
+	IsDir bool   `json:"is_dir"`
+}
+
+// windowsPHPArchive is one build variant in windows.php.net's releases.json,
+// e.g. the "nts-vs16-x64" entry of a minor version.
+type windowsPHPArchive struct {
+	Zip struct {
+		Path   string `json:"path"` // "php-8.3.10-nts-Win32-vs16-x64.zip"
+		SHA256 string `json:"sha256"`
+	} `json:"zip"`
+}
+
 func (p *PHPInstaller) ResolveVersion(requirement string) (string, error) {
-	return "", fmt.Errorf("PHP installer not yet implemented - install PHP manually from https://www.php.net/downloads")
+	builds, err := phpBuilds()
+	if err != nil {
+		return "", err
+	}
+	build, err := resolvePHPBuild(builds, requirement)
+	if err != nil {
+		return "", err
+	}
+	return build.Version, nil
 }
 
-func (p *PHPInstaller) Install(version, targetDir string, progress ProgressFunc) error {
-	return fmt.Errorf("PHP installer not yet implemented - install PHP %s manually from https://www.php.net/downloads", version)
+func (p *PHPInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	builds, err := phpBuilds()
+	if err != nil {
+		return err
+	}
+
+	var build *phpBuild
+	for i, b := range builds {
+		if b.Version == version {
+			build = &builds[i]
+			break
+		}
+	}
+	if build == nil {
+		return fmt.Errorf("no PHP %s build found for %s/%s", version, runtime.GOOS, runtime.GOARCH)
+	}
+
+	tmpFile := filepath.Join(os.TempDir(), build.Filename)
+	defer os.Remove(tmpFile)
+
+	err = fetchArchive(ctx, p.Name(), build.URL, tmpFile, progress, func() (string, error) {
+		if build.SHA256 == "" && build.ChecksumURL != "" {
+			return FetchChecksumFromURL(build.ChecksumURL, build.Filename)
+		}
+		return build.SHA256, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download PHP: %w", err)
+	}
+
+	// Static builds contain just the php binary; Windows zips have php.exe
+	// at the root. Either way it ends up directly in targetDir.
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
+		return fmt.Errorf("failed to extract PHP: %w", err)
+	}
+
+	return nil
 }
 
+// BinDir is the install directory itself: php (or php.exe) sits at the root.
 func (p *PHPInstaller) BinDir(installDir string) string {
+	return installDir
+}
+
+func (p *PHPInstaller) EnvVars(installDir string) map[string]string { return nil }
+
+// ArchivePattern matches the static-php (or windows.php.net) archive for
+// offline installs.
+func (p *PHPInstaller) ArchivePattern(version string) (string, error) {
+	osName, arch, err := phpPlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
 	if runtime.GOOS == "windows" {
-		return installDir
+		// The compiler in the name varies by version: vs16, vs17, ...
+		return fmt.Sprintf("php-%s-nts-Win32-vs*-%s.zip", version, arch), nil
 	}
-	return filepath.Join(installDir, "bin")
+	return fmt.Sprintf("php-%s-cli-%s-%s.tar.gz", version, osName, arch), nil
 }
 
-func (p *PHPInstaller) EnvVars(installDir string) map[string]string { return nil }
+// phpBuilds lists the PHP archives available for the current platform.
+func phpBuilds() ([]phpBuild, error) {
+	osName, arch, err := phpPlatform(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return nil, err
+	}
+
+	if runtime.GOOS == "windows" {
+		data, err := FetchJSON(phpWindowsIndexURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch PHP releases: %w", err)
+		}
+		return parseWindowsPHPReleases(data, arch)
+	}
+
+	data, err := FetchJSON(phpStaticBaseURL + "?format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PHP builds: %w", err)
+	}
+	return parseStaticPHPIndex(data, osName, arch)
+}
+
+// phpPlatform maps GOOS/GOARCH to the names used in PHP build filenames.
+func phpPlatform(goos, goarch string) (osName, arch string, err error) {
+	switch goos {
+	case "linux", "windows":
+		osName = goos
+	case "darwin":
+		osName = "macos"
+	default:
+		return "", "", fmt.Errorf("no PHP builds available for %s", goos)
+	}
+
+	switch {
+	case goos == "windows" && goarch == "amd64":
+		arch = "x64"
+	case goos != "windows" && goarch == "amd64":
+		arch = "x86_64"
+	case goos != "windows" && goarch == "arm64":
+		arch = "aarch64"
+	default:
+		return "", "", fmt.Errorf("no PHP builds available for %s/%s", goos, goarch)
+	}
+	return osName, arch, nil
+}
+
+// parseStaticPHPIndex picks the CLI archives for osName/arch out of the
+// static-php directory listing.
+func parseStaticPHPIndex(data []byte, osName, arch string) ([]phpBuild, error) {
+	var entries []staticPHPEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse PHP build list: %w", err)
+	}
+
+	files := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		files[e.Name] = true
+	}
+
+	suffix := fmt.Sprintf("-cli-%s-%s.tar.gz", osName, arch)
+	var builds []phpBuild
+	for _, e := range entries {
+		if e.IsDir || !strings.HasPrefix(e.Name, "php-") || !strings.HasSuffix(e.Name, suffix) {
+			continue
+		}
+		b := phpBuild{
+			Version:  strings.TrimSuffix(strings.TrimPrefix(e.Name, "php-"), suffix),
+			Filename: e.Name,
+			URL:      phpStaticBaseURL + e.Name,
+		}
+		if files[e.Name+".sha256"] {
+			b.ChecksumURL = b.URL + ".sha256"
+		}
+		builds = append(builds, b)
+	}
+	return builds, nil
+}
+
+// parseWindowsPHPReleases picks the non-thread-safe zip for arch from each
+// minor version in windows.php.net's releases.json.
+func parseWindowsPHPReleases(data []byte, arch string) ([]phpBuild, error) {
+	var releases map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse PHP releases: %w", err)
+	}
+
+	var builds []phpBuild
+	for _, release := range releases {
+		var version string
+		if err := json.Unmarshal(release["version"], &version); err != nil {
+			continue
+		}
+		for key, raw := range release {
+			// Keys look like "nts-vs16-x64"; the compiler varies by version.
+			if !strings.HasPrefix(key, "nts-") || !strings.HasSuffix(key, "-"+arch) {
+				continue
+			}
+			var archive windowsPHPArchive
+			if err := json.Unmarshal(raw, &archive); err != nil || archive.Zip.Path == "" {
+				continue
+			}
+			builds = append(builds, phpBuild{
+				Version:  version,
+				Filename: archive.Zip.Path,
+				URL:      phpWindowsBaseURL + archive.Zip.Path,
+				SHA256:   archive.Zip.SHA256,
+			})
+			break
+		}
+	}
+	return builds, nil
+}
+
+// resolvePHPBuild returns the highest version satisfying requirement.
+func resolvePHPBuild(builds []phpBuild, requirement string) (phpBuild, error) {
+	var constraint *semver.Constraints
+	if requirement != "latest" {
+		c, err := semver.NewConstraint(requirement)
+		if err != nil {
+			return phpBuild{}, fmt.Errorf("invalid PHP version requirement %q: %w", requirement, err)
+		}
+		constraint = c
+	}
+
+	var best *semver.Version
+	var bestBuild phpBuild
+	for _, b := range builds {
+		v, err := semver.NewVersion(b.Version)
+		if err != nil {
+			continue
+		}
+		if constraint != nil && !constraint.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best, bestBuild = v, b
+		}
+	}
+
+	if best == nil {
+		if requirement == "latest" {
+			return phpBuild{}, fmt.Errorf("no PHP builds found for %s/%s", runtime.GOOS, runtime.GOARCH)
+		}
+		return phpBuild{}, fmt.Errorf("no PHP version satisfying %s found", requirement)
+	}
+	return bestBuild, nil
+}
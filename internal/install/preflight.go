@@ -0,0 +1,193 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+)
+
+// PreflightIssue describes a permission problem found before setup changes anything.
+type PreflightIssue struct {
+	Check   string // "write", "disk", "shell_rc", "exec", or "registry"
+	Path    string // the directory or file that failed, if any
+	Problem string
+	Fix     string // remediation text shown to the user
+}
+
+func (i PreflightIssue) Error() string {
+	if i.Path != "" {
+		return fmt.Sprintf("%s: %s", i.Path, i.Problem)
+	}
+	return i.Problem
+}
+
+// Probes are package variables so tests can simulate managed machines
+// (noexec mounts, a locked-down registry) without root access.
+var (
+	execProbe     = probeExec
+	registryProbe = probeUserEnv
+)
+
+// Preflight verifies that setup will be able to write its directories, fit
+// the plan's runtimes on disk, modify shell config files, execute installed
+// binaries, and update PATH.
+// All problems are returned together so they can be reported before any
+// download begins. A nil plan runs every check (used by doctor).
+func Preflight(plan *engine.SetupPlan) []PreflightIssue {
+	var issues []PreflightIssue
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return []PreflightIssue{{
+			Check:   "write",
+			Problem: fmt.Sprintf("cannot determine home directory: %s", err),
+			Fix:     "Set the HOME (or USERPROFILE on Windows) environment variable.",
+		}}
+	}
+
+	base := filepath.Join(home, ".templatr")
+	runtimesDir, _ := RuntimesDir() // only fails without a home directory
+	for _, dir := range []string{runtimesDir, base, filepath.Join(base, "logs")} {
+		if err := checkWritable(dir); err != nil {
+			owner := base
+			if !strings.HasPrefix(dir, base) {
+				owner = dir // runtimes_dir outside ~/.templatr
+			}
+			issues = append(issues, PreflightIssue{
+				Check:   "write",
+				Path:    dir,
+				Problem: fmt.Sprintf("not writable: %s", err),
+				Fix:     fmt.Sprintf("Make %s writable by your user (e.g., 'sudo chown -R $USER %s').", dir, owner),
+			})
+		}
+	}
+
+	if plan != nil && !installsRuntimes(plan) {
+		return issues
+	}
+
+	if plan != nil {
+		if issue := checkDiskSpace(plan, runtimesDir); issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		if err := registryProbe(); err != nil {
+			issues = append(issues, PreflightIssue{
+				Check:   "registry",
+				Path:    windowsEnvKey,
+				Problem: fmt.Sprintf("the user environment cannot be modified: %s", err),
+				Fix:     "Ask your IT admin to allow changes to " + windowsEnvKey + " for your user, or add the runtime bin directories to PATH manually after setup.",
+			})
+		}
+		return issues
+	}
+
+	for _, rc := range shellConfigFiles() {
+		if err := checkFileWritable(rc); err != nil {
+			issues = append(issues, PreflightIssue{
+				Check:   "shell_rc",
+				Path:    rc,
+				Problem: fmt.Sprintf("cannot be modified: %s", err),
+				Fix:     fmt.Sprintf("Your dotfiles may be managed. Make %s writable, or add the runtime bin directories to PATH in your managed config.", rc),
+			})
+		}
+	}
+
+	if dir := existingAncestor(runtimesDir); dir != "" && checkWritable(dir) == nil {
+		if err := execProbe(dir); err != nil {
+			issues = append(issues, PreflightIssue{
+				Check:   "exec",
+				Path:    dir,
+				Problem: fmt.Sprintf("installed binaries cannot be executed here: %s", err),
+				Fix:     "The volume is likely mounted noexec. Move ~/.templatr to an executable volume and symlink it back.",
+			})
+		}
+	}
+
+	return issues
+}
+
+// installsRuntimes returns true if the plan will install or upgrade any runtime,
+// which is when PATH and shell config files get modified.
+func installsRuntimes(plan *engine.SetupPlan) bool {
+	for _, r := range plan.InstallRuntimes() {
+		if r.Action != engine.ActionSkip {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWritable verifies that dir, or its nearest existing ancestor if dir
+// does not exist yet, accepts new files. It does not create dir.
+func checkWritable(dir string) error {
+	target := existingAncestor(dir)
+	if target == "" {
+		return fmt.Errorf("no existing parent directory")
+	}
+
+	f, err := os.CreateTemp(target, ".templatr-preflight-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// checkFileWritable verifies that an existing file can be opened for append,
+// or that its directory accepts new files if it does not exist.
+func checkFileWritable(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err == nil {
+		return f.Close()
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return checkWritable(filepath.Dir(path))
+	}
+	return err
+}
+
+// existingAncestor returns dir or the closest parent of dir that exists.
+func existingAncestor(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// probeExec writes a tiny shell script into dir and runs it. This catches
+// noexec mounts without parsing platform-specific mount tables.
+func probeExec(dir string) error {
+	f, err := os.CreateTemp(dir, ".templatr-exec-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+
+	if _, err := f.WriteString("#!/bin/sh\nexit 0\n"); err != nil {
+		f.Close()
+		return err
+	}
+	f.Close()
+
+	if err := os.Chmod(name, 0o755); err != nil {
+		return err
+	}
+	return exec.Command(name).Run()
+}
@@ -0,0 +1,142 @@
+package install
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+)
+
+// skipIfPermissionsIgnored skips chmod-based tests where permissions aren't enforced.
+func skipIfPermissionsIgnored(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("chmod-based permission tests are not supported on Windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("running as root; file permissions are not enforced")
+	}
+}
+
+// preflightHome isolates HOME and SHELL so Preflight checks temp paths only.
+func preflightHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("SHELL", "/bin/bash")
+	return home
+}
+
+func stubProbes(t *testing.T, execErr, regErr error) {
+	t.Helper()
+	origExec, origReg, origFree := execProbe, registryProbe, diskFree
+	execProbe = func(string) error { return execErr }
+	registryProbe = func() error { return regErr }
+	diskFree = func(string) (uint64, error) { return 1 << 40, nil }
+	t.Cleanup(func() {
+		execProbe, registryProbe, diskFree = origExec, origReg, origFree
+	})
+}
+
+func installPlan() *engine.SetupPlan {
+	return &engine.SetupPlan{Runtimes: []engine.RuntimePlan{{Name: "node", Action: engine.ActionInstall}}}
+}
+
+func findIssue(issues []PreflightIssue, check string) *PreflightIssue {
+	for i := range issues {
+		if issues[i].Check == check {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+func TestPreflight_AllWritable(t *testing.T) {
+	preflightHome(t)
+	stubProbes(t, nil, nil)
+
+	if issues := Preflight(installPlan()); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestPreflight_ReadOnlyHome(t *testing.T) {
+	skipIfPermissionsIgnored(t)
+	home := preflightHome(t)
+	stubProbes(t, nil, nil)
+
+	if err := os.Chmod(home, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(home, 0o755) })
+
+	issue := findIssue(Preflight(installPlan()), "write")
+	if issue == nil {
+		t.Fatal("expected a write issue for a read-only home")
+	}
+	if issue.Fix == "" {
+		t.Error("expected remediation text")
+	}
+}
+
+func TestPreflight_ManagedShellRC(t *testing.T) {
+	skipIfPermissionsIgnored(t)
+	home := preflightHome(t)
+	stubProbes(t, nil, nil)
+
+	rc := filepath.Join(home, ".bashrc")
+	if err := os.WriteFile(rc, []byte("# managed\n"), 0o444); err != nil {
+		t.Fatal(err)
+	}
+
+	issue := findIssue(Preflight(installPlan()), "shell_rc")
+	if issue == nil || issue.Path != rc {
+		t.Fatalf("expected shell_rc issue for %s, got %+v", rc, issue)
+	}
+}
+
+func TestPreflight_Noexec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec probe is not used on Windows")
+	}
+	preflightHome(t)
+	stubProbes(t, errors.New("permission denied"), nil)
+
+	issue := findIssue(Preflight(installPlan()), "exec")
+	if issue == nil {
+		t.Fatal("expected exec issue when the probe fails")
+	}
+}
+
+func TestPreflight_SkipsRuntimeChecksWhenNothingToInstall(t *testing.T) {
+	preflightHome(t)
+	stubProbes(t, errors.New("permission denied"), errors.New("blocked"))
+
+	plan := &engine.SetupPlan{Runtimes: []engine.RuntimePlan{{Name: "node", Action: engine.ActionSkip}}}
+	if issues := Preflight(plan); len(issues) != 0 {
+		t.Errorf("expected no issues when no runtimes are installed, got %v", issues)
+	}
+}
+
+func TestProbeExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec probe is not used on Windows")
+	}
+	if err := probeExec(t.TempDir()); err != nil {
+		t.Errorf("probeExec on a normal temp dir failed: %s", err)
+	}
+}
+
+func TestCheckWritable_MissingDirUsesAncestor(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "does", "not", "exist")
+	if err := checkWritable(dir); err != nil {
+		t.Errorf("expected nearest ancestor to be writable: %s", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("checkWritable should not create the directory")
+	}
+}
@@ -0,0 +1,270 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/state"
+	winreg "golang.org/x/sys/windows/registry"
+)
+
+// powershellProfileMethod is the state method for a PATH entry added
+// through the PowerShell profiles, for when HKCU\Environment can't be
+// changed (a locked-down registry, policy).
+const powershellProfileMethod = "powershell_profile"
+
+const (
+	profileFile     = "Microsoft.PowerShell_profile.ps1" // $PROFILE, for the current user and host
+	autoRunKey      = `Software\Microsoft\Command Processor`
+	autoRunValue    = "AutoRun"
+	autoRunSep      = " & "
+	autoRunRegistry = `HKCU\` + autoRunKey + `\` + autoRunValue
+)
+
+// powershellHosts are the PowerShells whose profiles get PATH lines: the
+// directory under Documents each keeps its profile in, and its executable.
+var powershellHosts = []struct{ dir, exe string }{
+	{"PowerShell", "pwsh"},              // PowerShell 7
+	{"WindowsPowerShell", "powershell"}, // Windows PowerShell 5.1
+}
+
+// addToPathProfile adds a line prepending binDir to $env:PATH to the
+// PowerShell profiles, and a set command to cmd's AutoRun. Like the Unix
+// shell config files, a profile that already has a block for the same
+// runtime gets it rewritten in place.
+func addToPathProfile(binDir string) (*state.PathModification, []EnvChange, error) {
+	files := powershellProfiles()
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no PowerShell profile found")
+	}
+	marker := shellMarkerPrefix + binDir
+	line := profileLine(binDir)
+	var replaces func(value string) bool
+	if rt := managedRuntime(binDir); rt != "" {
+		replaces = func(value string) bool { return managedRuntime(value) == rt }
+	}
+
+	written, modified := writeShellBlocks(files, marker, func(string) string { return line }, replaces)
+	if len(written) == 0 {
+		return nil, nil, fmt.Errorf("could not write %s", strings.Join(files, ", "))
+	}
+	var changes []EnvChange
+	for _, profile := range modified {
+		changes = append(changes, EnvChange{Kind: ChangePath, Name: "PATH", Value: binDir, File: profile, Line: line})
+	}
+	// cmd doesn't read the profiles; AutoRun is only a bonus
+	if changed, err := updateAutoRun(func(current string) (string, bool) {
+		return autoRunWithPath(current, binDir, replaces)
+	}); err == nil && changed {
+		changes = append(changes, EnvChange{Kind: ChangePath, Name: "PATH", Value: binDir, Registry: autoRunRegistry})
+	}
+
+	// Also update current process PATH
+	os.Setenv("PATH", binDir+";"+os.Getenv("PATH"))
+
+	if len(changes) == 0 {
+		return nil, []EnvChange{{Kind: ChangePath, Name: "PATH", Value: binDir, Unchanged: true}}, nil
+	}
+	recorded := written[len(written)-1]
+	if len(modified) > 0 {
+		recorded = modified[len(modified)-1]
+	}
+	return &state.PathModification{
+		Method: powershellProfileMethod,
+		File:   recorded,
+		Files:  written,
+		Line:   marker + "\n" + line,
+		Value:  binDir,
+	}, changes, nil
+}
+
+// removeFromPathProfile removes entry's line from every PowerShell profile
+// and its set command from AutoRun.
+func removeFromPathProfile(entry state.PathModification) error {
+	marker := shellMarkerPrefix + entry.Value
+	var files []string
+	for _, profile := range slices.Concat(entry.ConfigFiles(), powershellProfiles()) {
+		if !slices.Contains(files, profile) {
+			files = append(files, profile)
+		}
+	}
+	for _, profile := range files {
+		if err := removeMarkedLine(profile, marker); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	_, err := updateAutoRun(func(current string) (string, bool) {
+		return autoRunWithoutPath(current, entry.Value)
+	})
+	return err
+}
+
+// powershellProfiles returns the profiles to write PATH lines to.
+func powershellProfiles() []string {
+	docs := documentsDir()
+	if docs == "" {
+		return nil
+	}
+	installed := func(exe string) bool {
+		_, err := exec.LookPath(exe)
+		return err == nil
+	}
+	return selectProfiles(docs, installed, fileExists)
+}
+
+// selectProfiles picks the profiles under docs the way shellConfigFiles
+// picks rc files: each PowerShell's that is installed or whose profile
+// already exists, or Windows PowerShell's, which comes with Windows, when
+// neither is found.
+func selectProfiles(docs string, installed func(exe string) bool, exists func(path string) bool) []string {
+	var files []string
+	for _, host := range powershellHosts {
+		profile := filepath.Join(docs, host.dir, profileFile)
+		if installed(host.exe) || exists(profile) {
+			files = append(files, profile)
+		}
+	}
+	if len(files) == 0 {
+		files = append(files, filepath.Join(docs, "WindowsPowerShell", profileFile))
+	}
+	return files
+}
+
+// documentsDir returns the Documents folder PowerShell keeps profiles in,
+// which is where the shell folder points when OneDrive redirects it rather
+// than always %USERPROFILE%\Documents.
+func documentsDir() string {
+	key, err := winreg.OpenKey(winreg.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`, winreg.QUERY_VALUE)
+	if err == nil {
+		defer key.Close()
+		if value, valType, err := key.GetStringValue("Personal"); err == nil && value != "" {
+			if valType == winreg.EXPAND_SZ {
+				value, err = winreg.ExpandString(value)
+			}
+			if err == nil {
+				return value
+			}
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Documents")
+}
+
+// updateAutoRun rewrites cmd's AutoRun with edit, creating it if needed,
+// and reports whether it changed.
+func updateAutoRun(edit func(current string) (string, bool)) (bool, error) {
+	key, _, err := winreg.CreateKey(winreg.CURRENT_USER, autoRunKey, winreg.QUERY_VALUE|winreg.SET_VALUE)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", autoRunRegistry, err)
+	}
+	defer key.Close()
+
+	current, valType, err := readUserVar(key, autoRunValue)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", autoRunRegistry, err)
+	}
+	updated, changed := edit(current)
+	if !changed {
+		return false, nil
+	}
+	if updated == "" {
+		if err := key.DeleteValue(autoRunValue); err != nil && !errors.Is(err, winreg.ErrNotExist) {
+			return false, fmt.Errorf("failed to remove %s: %w", autoRunRegistry, err)
+		}
+		return true, nil
+	}
+	// %PATH% must reach cmd unexpanded, so a new value isn't REG_EXPAND_SZ
+	if valType == winreg.NONE {
+		valType = winreg.SZ
+	}
+	if err := writeUserVar(key, autoRunValue, updated, valType); err != nil {
+		return false, fmt.Errorf("failed to set %s: %w", autoRunRegistry, err)
+	}
+	return true, nil
+}
+
+// autoRunCommand is the AutoRun command prepending dir to PATH. cmd runs
+// AutoRun again in every nested cmd, so it only does so while PATH doesn't
+// have dir yet; %PATH:dir;=% is PATH with dir removed, ignoring case.
+func autoRunCommand(dir string) string {
+	return `if "%PATH:` + dir + `;=%"=="%PATH%" ` + plainAutoRunCommand(dir)
+}
+
+// plainAutoRunCommand is the unguarded command earlier releases wrote,
+// which each nested cmd prepended again.
+func plainAutoRunCommand(dir string) string {
+	return `set "PATH=` + dir + `;%PATH%"`
+}
+
+var autoRunPathPattern = regexp.MustCompile(`set "PATH=([^"]*);%PATH%"`)
+
+// autoRunWithPath returns autoRun with the command for dir appended,
+// after any commands of its own, unless it already has it. Commands for
+// paths that satisfy replaces are dropped, as upgrades rewrite a shell
+// config block. It reports whether autoRun changed.
+func autoRunWithPath(autoRun, dir string, replaces func(value string) bool) (string, bool) {
+	updated := autoRun
+	if replaces != nil {
+		for _, m := range autoRunPathPattern.FindAllStringSubmatch(autoRun, -1) {
+			if !samePathEntry(m[1], dir) && replaces(m[1]) {
+				updated, _ = autoRunWithoutPath(updated, m[1])
+			}
+		}
+	}
+	updated, _ = removeAutoRunCommand(updated, plainAutoRunCommand(dir))
+	cmd := autoRunCommand(dir)
+	if !hasAutoRunCommand(updated, cmd) {
+		if updated == "" {
+			updated = cmd
+		} else {
+			updated += autoRunSep + cmd
+		}
+	}
+	return updated, updated != autoRun
+}
+
+// autoRunWithoutPath returns autoRun without the commands for dir, as this
+// or an earlier release wrote them, leaving the user's own commands as
+// they were, and reports whether it had any.
+func autoRunWithoutPath(autoRun, dir string) (string, bool) {
+	updated, _ := removeAutoRunCommand(autoRun, autoRunCommand(dir))
+	updated, _ = removeAutoRunCommand(updated, plainAutoRunCommand(dir))
+	return updated, updated != autoRun
+}
+
+// removeAutoRunCommand returns autoRun without each copy of cmd, and
+// reports whether it had any.
+func removeAutoRunCommand(autoRun, cmd string) (string, bool) {
+	updated := autoRun
+	for hasAutoRunCommand(updated, cmd) {
+		switch {
+		case updated == cmd:
+			updated = ""
+		case strings.HasPrefix(updated, cmd+autoRunSep):
+			updated = strings.TrimPrefix(updated, cmd+autoRunSep)
+		case strings.HasSuffix(updated, autoRunSep+cmd):
+			updated = strings.TrimSuffix(updated, autoRunSep+cmd)
+		default:
+			updated = strings.Replace(updated, autoRunSep+cmd+autoRunSep, autoRunSep, 1)
+		}
+	}
+	return updated, updated != autoRun
+}
+
+// hasAutoRunCommand reports whether cmd is one of the commands in autoRun,
+// as joined by autoRunWithPath.
+func hasAutoRunCommand(autoRun, cmd string) bool {
+	return autoRun == cmd ||
+		strings.HasPrefix(autoRun, cmd+autoRunSep) ||
+		strings.HasSuffix(autoRun, autoRunSep+cmd) ||
+		strings.Contains(autoRun, autoRunSep+cmd+autoRunSep)
+}
@@ -0,0 +1,128 @@
+package install
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfileLine(t *testing.T) {
+	tests := []struct{ dir, want string }{
+		{`C:\Users\dev\.templatr\runtimes\node\current`, `$env:PATH = 'C:\Users\dev\.templatr\runtimes\node\current;' + $env:PATH`},
+		{`C:\Users\o'brien\$tools`, `$env:PATH = 'C:\Users\o''brien\$tools;' + $env:PATH`},
+	}
+	for _, tt := range tests {
+		if got := profileLine(tt.dir); got != tt.want {
+			t.Errorf("profileLine(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestSelectProfiles(t *testing.T) {
+	const docs = `C:\Users\dev\OneDrive\Documents`
+	pwsh := filepath.Join(docs, "PowerShell", profileFile)
+	winps := filepath.Join(docs, "WindowsPowerShell", profileFile)
+	tests := []struct {
+		name      string
+		installed []string
+		existing  []string
+		want      []string
+	}{
+		{"both installed", []string{"pwsh", "powershell"}, nil, []string{pwsh, winps}},
+		{"windows powershell only", []string{"powershell"}, nil, []string{winps}},
+		{"pwsh profile left behind", []string{"powershell"}, []string{pwsh}, []string{pwsh, winps}},
+		{"nothing found", nil, nil, []string{winps}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installed := func(exe string) bool { return strings.Contains(strings.Join(tt.installed, " "), exe) }
+			exists := func(path string) bool { return strings.Contains(strings.Join(tt.existing, "|"), path) }
+			if got := selectProfiles(docs, installed, exists); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectProfiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileBlock(t *testing.T) {
+	const current = `C:\Users\dev\.templatr\runtimes\node\current`
+	marker := shellMarkerPrefix + current
+	line := profileLine(current)
+	replaces := func(value string) bool { return managedRuntimeIn(value, "node") }
+
+	profile := "Set-PSReadLineOption -EditMode Emacs\n"
+	got, changed := rewriteShellBlock(profile, marker, line, replaces)
+	if !changed || got != profile+"\n"+marker+"\n"+line+"\n" {
+		t.Fatalf("new block: got %q, %v", got, changed)
+	}
+	if again, changed := rewriteShellBlock(got, marker, line, replaces); changed || again != got {
+		t.Errorf("second write changed the profile: %q", again)
+	}
+
+	// A block for a version directory of the same runtime is rewritten
+	old := `C:\Users\dev\.templatr\runtimes\node\22.11.0`
+	versioned := profile + "\n" + shellMarkerPrefix + old + "\n" + profileLine(old) + "\n"
+	got, changed = rewriteShellBlock(versioned, marker, line, replaces)
+	if !changed || strings.Contains(got, old) || !strings.Contains(got, line) {
+		t.Errorf("versioned block: got %q, %v", got, changed)
+	}
+}
+
+// managedRuntimeIn reports whether value is a directory in rt's runtimes
+// directory, like managedRuntime without needing it to be RuntimesDir.
+func managedRuntimeIn(value, rt string) bool {
+	return strings.Contains(value, `\runtimes\`+rt+`\`)
+}
+
+func TestAutoRunWithPath(t *testing.T) {
+	const dir = `C:\Users\dev\.templatr\runtimes\node\current`
+	cmd := `if "%PATH:` + dir + `;=%"=="%PATH%" set "PATH=` + dir + `;%PATH%"`
+	tests := []struct {
+		name, autoRun, want string
+		changed             bool
+	}{
+		{"empty", "", cmd, true},
+		{"unguarded command upgraded", `cls & set "PATH=` + dir + `;%PATH%"`, `cls & ` + cmd, true},
+		{"after the user's commands", `doskey /macrofile=C:\macros.txt`, `doskey /macrofile=C:\macros.txt & ` + cmd, true},
+		{"keeps && chains", `cd /d C:\ && cls`, `cd /d C:\ && cls & ` + cmd, true},
+		{"already there", `cls & ` + cmd, `cls & ` + cmd, false},
+		{"other runtime kept", `set "PATH=C:\go\bin;%PATH%"`, `set "PATH=C:\go\bin;%PATH%" & ` + cmd, true},
+		{"same runtime replaced", `cls & set "PATH=C:\Users\dev\.templatr\runtimes\node\22.11.0;%PATH%"`, `cls & ` + cmd, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := autoRunWithPath(tt.autoRun, dir, func(value string) bool { return managedRuntimeIn(value, "node") })
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("autoRunWithPath(%q) = %q, %v, want %q, %v", tt.autoRun, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestAutoRunWithoutPath(t *testing.T) {
+	const dir = `C:\Users\dev\.templatr\runtimes\node\current`
+	cmd := `if "%PATH:` + dir + `;=%"=="%PATH%" set "PATH=` + dir + `;%PATH%"`
+	plain := `set "PATH=` + dir + `;%PATH%"`
+	tests := []struct {
+		name, autoRun, want string
+		changed             bool
+	}{
+		{"only command", cmd, "", true},
+		{"unguarded, from an earlier release", `cls & ` + plain, `cls`, true},
+		{"first", cmd + ` & cls`, `cls`, true},
+		{"last", `cls & ` + cmd, `cls`, true},
+		{"middle", `cls & ` + cmd + ` & doskey /macrofile=C:\macros.txt`, `cls & doskey /macrofile=C:\macros.txt`, true},
+		{"every copy", cmd + ` & cls & ` + cmd, `cls`, true},
+		{"absent", `cls & set "PATH=C:\go\bin;%PATH%"`, `cls & set "PATH=C:\go\bin;%PATH%"`, false},
+		{"part of another command", `echo ` + cmd + `x`, `echo ` + cmd + `x`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := autoRunWithoutPath(tt.autoRun, dir)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("autoRunWithoutPath(%q) = %q, %v, want %q, %v", tt.autoRun, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package install
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
@@ -18,8 +20,10 @@ func (p *PythonInstaller) Name() string { return "python" }
 
 // githubRelease represents a GitHub release.
 type githubRelease struct {
-	TagName string        `json:"tag_name"`
-	Assets  []githubAsset `json:"assets"`
+	TagName    string        `json:"tag_name"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	Assets     []githubAsset `json:"assets"`
 }
 
 // githubAsset represents a release asset.
@@ -28,26 +32,42 @@ type githubAsset struct {
 	BrowserDownloadURL string `json:"browser_download_url"`
 }
 
-func (p *PythonInstaller) ResolveVersion(requirement string) (string, error) {
-	// Fetch the latest release from python-build-standalone
-	data, err := FetchJSON("https://api.github.com/repos/indygreg/python-build-standalone/releases/latest")
+// pythonReleaseURL is the python-build-standalone release that Python
+// versions are resolved and installed from.
+var pythonReleaseURL = "https://api.github.com/repos/indygreg/python-build-standalone/releases/latest"
+
+// latestPythonRelease fetches the latest python-build-standalone release.
+func latestPythonRelease() (*githubRelease, error) {
+	data, err := FetchGitHubJSON(pythonReleaseURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch python-build-standalone releases: %w", err)
+		return nil, fmt.Errorf("failed to fetch python-build-standalone releases: %w", err)
 	}
 
 	var release githubRelease
 	if err := json.Unmarshal(data, &release); err != nil {
-		return "", fmt.Errorf("failed to parse release info: %w", err)
+		return nil, fmt.Errorf("failed to parse release info: %w", err)
 	}
+	return &release, nil
+}
 
-	// Extract Python version from asset names
-	// Assets look like: cpython-3.13.2+20250212-x86_64-unknown-linux-gnu-install_only_stripped.tar.gz
+// pythonVersions returns the Python versions built in a release.
+// Assets look like: cpython-3.13.2+20250212-x86_64-unknown-linux-gnu-install_only_stripped.tar.gz
+func pythonVersions(release *githubRelease) map[string]bool {
 	versions := map[string]bool{}
 	for _, asset := range release.Assets {
 		if v := extractPythonVersion(asset.Name); v != "" {
 			versions[v] = true
 		}
 	}
+	return versions
+}
+
+func (p *PythonInstaller) ResolveVersion(requirement string) (string, error) {
+	release, err := latestPythonRelease()
+	if err != nil {
+		return "", err
+	}
+	versions := pythonVersions(release)
 
 	if requirement == "latest" {
 		// Return the highest version found
@@ -92,33 +112,46 @@ func (p *PythonInstaller) ResolveVersion(requirement string) (string, error) {
 	return "", fmt.Errorf("no Python version satisfying %s found", requirement)
 }
 
-func (p *PythonInstaller) Install(version, targetDir string, progress ProgressFunc) error {
-	// Fetch the release to find the correct asset URL
-	data, err := FetchJSON("https://api.github.com/repos/indygreg/python-build-standalone/releases/latest")
+// ListVersions lists the Python versions in the latest
+// python-build-standalone release, which is all ResolveVersion looks at.
+func (p *PythonInstaller) ListVersions() ([]AvailableVersion, error) {
+	release, err := latestPythonRelease()
 	if err != nil {
-		return fmt.Errorf("failed to fetch release: %w", err)
+		return nil, err
 	}
+	var versions []AvailableVersion
+	for v := range pythonVersions(release) {
+		versions = append(versions, AvailableVersion{Version: v})
+	}
+	sort.Slice(versions, func(i, j int) bool { return newerVersion(versions[i].Version, versions[j].Version) })
+	return versions, nil
+}
 
-	var release githubRelease
-	if err := json.Unmarshal(data, &release); err != nil {
+func (p *PythonInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
+	// Fetch the release to find the correct asset URL
+	release, err := latestPythonRelease()
+	if err != nil {
 		return err
 	}
 
 	target := pythonTarget()
-	var assetURL, assetName string
+	var assetURL, assetName, sumsURL string
 
-	// Find the install_only asset for our platform
+	// Find the first install_only asset for our platform, and the checksum list
 	for _, asset := range release.Assets {
+		if asset.Name == "SHA256SUMS" {
+			sumsURL = runtimeURL("python", asset.BrowserDownloadURL)
+			continue
+		}
 		if !strings.Contains(asset.Name, "cpython-"+version) {
 			continue
 		}
 		if !strings.Contains(asset.Name, target) {
 			continue
 		}
-		if strings.Contains(asset.Name, "install_only") {
-			assetURL = asset.BrowserDownloadURL
+		if assetURL == "" && strings.Contains(asset.Name, "install_only") {
+			assetURL = runtimeURL("python", asset.BrowserDownloadURL)
 			assetName = asset.Name
-			break
 		}
 	}
 
@@ -126,16 +159,22 @@ func (p *PythonInstaller) Install(version, targetDir string, progress ProgressFu
 		return fmt.Errorf("no Python %s binary found for %s", version, target)
 	}
 
-	// Download
 	tmpFile := filepath.Join(os.TempDir(), assetName)
 	defer os.Remove(tmpFile)
 
-	if err := DownloadFile(assetURL, tmpFile, progress); err != nil {
+	// Newer releases publish a SHA256SUMS asset; older ones have nothing to verify against
+	err = fetchArchive(ctx, p.Name(), assetURL, tmpFile, progress, func() (string, error) {
+		if sumsURL == "" {
+			return "", nil
+		}
+		return FetchChecksumFromURL(sumsURL, assetName)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to download Python: %w", err)
 	}
 
 	// python-build-standalone archives have a "python/" top-level dir
-	if err := ExtractAndFlatten(tmpFile, targetDir); err != nil {
+	if err := ExtractAndFlatten(ctx, tmpFile, targetDir); err != nil {
 		return fmt.Errorf("failed to extract Python: %w", err)
 	}
 
@@ -151,6 +190,12 @@ func (p *PythonInstaller) BinDir(installDir string) string {
 
 func (p *PythonInstaller) EnvVars(installDir string) map[string]string { return nil }
 
+// ArchivePattern matches the python-build-standalone install_only archive for
+// offline installs; the release date in the name varies.
+func (p *PythonInstaller) ArchivePattern(version string) (string, error) {
+	return fmt.Sprintf("cpython-%s+*-%s-install_only.tar.gz", version, pythonTarget()), nil
+}
+
 // extractPythonVersion pulls the Python version from an asset name like
 // "cpython-3.13.2+20250212-x86_64-unknown-linux-gnu-install_only.tar.gz"
 func extractPythonVersion(name string) string {
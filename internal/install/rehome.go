@@ -0,0 +1,139 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// RehomeResult describes what Rehome did.
+type RehomeResult struct {
+	From, To      string
+	Moved         bool // the directory was moved, rather than found already at To
+	Installations int  // installations whose paths were rewritten
+	PathEntries   int  // PATH entries rewritten in shell config files or the user environment
+	EnvVars       int  // env vars such as JAVA_HOME rewritten
+}
+
+// Rehome moves the runtimes directory from from to to, unless it was
+// already moved there by hand, and rewrites every path st records inside
+// it: installations, current links, and the PATH entries and env vars set
+// for them, whose shell config lines (or user environment on Windows) are
+// rewritten too. The runtimes directory for the rest of the run becomes
+// to; making that stick is for the caller, through runtimes_dir.
+func Rehome(st *state.State, from, to string, log *logger.Logger) (*RehomeResult, error) {
+	from, to = filepath.Clean(from), filepath.Clean(to)
+	if from == to {
+		return nil, fmt.Errorf("runtimes are already in %s", to)
+	}
+	if within(to, from) || within(from, to) {
+		return nil, fmt.Errorf("can't move %s to %s: one is inside the other", from, to)
+	}
+
+	result := &RehomeResult{From: from, To: to}
+	switch {
+	case dirExists(from) && isEmptyDir(to):
+		// Current links are made again below; a junction can't be copied
+		for _, link := range st.Links {
+			RemoveCurrent(link.Path)
+		}
+		if err := moveDir(from, to); err != nil {
+			for _, link := range st.Links {
+				LinkCurrent(link.Target)
+			}
+			return nil, err
+		}
+		result.Moved = true
+		log.Info("Moved %s to %s", from, to)
+	case dirExists(from):
+		return nil, fmt.Errorf("%s already holds files - pick an empty or new directory", to)
+	case !dirExists(to):
+		return nil, fmt.Errorf("neither %s nor %s exists", from, to)
+	}
+	SetRuntimesDir(to)
+
+	moved, pathMods, envMods := st.Rehome(from, to)
+	result.Installations = moved
+
+	for _, link := range st.Links {
+		if _, err := LinkCurrent(link.Target); err != nil {
+			log.Warn("Could not relink %s: %s", link.Path, err)
+		}
+	}
+
+	// The old lines go first: a new value for a line already there would
+	// be left as it is
+	for _, old := range pathMods {
+		if err := RemoveFromPath(old); err != nil {
+			log.Warn("Could not remove the PATH entry for %s: %s", old.Value, err)
+		}
+		newValue := strings.Replace(old.Value, from, to, 1)
+		entry, _, err := AddToPath(newValue)
+		if err != nil {
+			log.Warn("Could not add %s to PATH: %s", newValue, err)
+			continue
+		}
+		if entry != nil {
+			recordPathEntry(st, *entry)
+		}
+		result.PathEntries++
+		log.Info("PATH entry %s is now %s", old.Value, newValue)
+	}
+	for _, old := range envMods {
+		if err := RemoveEnvVar(old); err != nil {
+			log.Warn("Could not remove %s: %s", old.Name, err)
+		}
+		newValue := strings.Replace(old.Value, from, to, 1)
+		entry, _, err := SetEnvVar(old.Name, newValue)
+		if err != nil {
+			log.Warn("Could not set %s: %s", old.Name, err)
+			continue
+		}
+		if entry != nil {
+			st.RemoveEnvModification(old.Name)
+			st.AddEnvModification(*entry)
+		}
+		result.EnvVars++
+		log.Info("%s is now %s", old.Name, newValue)
+	}
+	return result, nil
+}
+
+// moveDir renames from to to, or copies it and removes from when they are
+// on different volumes.
+func moveDir(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(to), err)
+	}
+	os.Remove(to) // empty, or rename fails
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	if err := copyDir(from, to); err != nil {
+		os.RemoveAll(to)
+		return fmt.Errorf("failed to copy %s to %s: %w", from, to, err)
+	}
+	if err := os.RemoveAll(from); err != nil {
+		return fmt.Errorf("copied the runtimes to %s but could not remove %s: %w", to, from, err)
+	}
+	return nil
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// isEmptyDir reports whether path is missing or an empty directory.
+func isEmptyDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return true
+	}
+	return err == nil && len(entries) == 0
+}
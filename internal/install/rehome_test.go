@@ -0,0 +1,169 @@
+package install
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// homeFake is a fakeInstaller that also sets an env var pointing at its
+// install, as Java's JAVA_HOME does.
+type homeFake struct{ fakeInstaller }
+
+func (f *homeFake) EnvVars(installDir string) map[string]string {
+	return map[string]string{"FAKE_HOME": installDir}
+}
+
+// customRuntimesDir installs the runtimes of the test in a directory
+// outside ~/.templatr and returns it.
+func customRuntimesDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "data", "runtimes")
+	SetRuntimesDir(dir)
+	t.Cleanup(func() { SetRuntimesDir("") })
+	return dir
+}
+
+func installFake(t *testing.T) {
+	t.Helper()
+	ts, sum := archiveServer(t, "runtime archive")
+	registerFake(t, &homeFake{fakeInstaller{url: ts.URL, upstreamHash: sum}})
+	rp := engine.RuntimePlan{Name: "fake", DisplayName: "Fake", RequiredVersion: "latest", Action: engine.ActionInstall}
+	if _, err := InstallSingleRuntime(context.Background(), rp, "test-template", logger.New(), nil, ExecuteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallSingleRuntime_CustomRuntimesDir(t *testing.T) {
+	skipOnWindows(t)
+	tempHome(t)
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv("PATH", os.Getenv("PATH"))
+	dir := customRuntimesDir(t)
+
+	installFake(t)
+
+	installed := filepath.Join(dir, "fake", "1.0.0")
+	if _, err := os.Stat(installed); err != nil {
+		t.Fatalf("runtime not installed in the custom directory: %s", err)
+	}
+	home, _ := os.UserHomeDir()
+	if _, err := os.Stat(filepath.Join(home, ".templatr", "runtimes")); !os.IsNotExist(err) {
+		t.Error("nothing should be installed in ~/.templatr/runtimes")
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "fake", "current")
+	if insts := st.GetInstallations("fake"); len(insts) != 1 || insts[0].Path != installed {
+		t.Errorf("state installations = %+v, want the absolute path %s", insts, installed)
+	}
+	if len(st.PathModifications) != 1 || st.PathModifications[0].Value != link {
+		t.Errorf("state PATH entries = %+v, want %s", st.PathModifications, link)
+	}
+	if base, _ := st.RuntimesBase(); base != dir {
+		t.Errorf("RuntimesBase() = %s, want %s", base, dir)
+	}
+
+	// uninstall removes it from the custom directory
+	if _, err := st.UndoInstallation("fake", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(installed); !os.IsNotExist(err) {
+		t.Error("uninstall should remove the runtime from the custom directory")
+	}
+}
+
+func TestRehome(t *testing.T) {
+	skipOnWindows(t)
+	tempHome(t)
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv("PATH", os.Getenv("PATH"))
+	from := customRuntimesDir(t)
+	installFake(t)
+
+	to := filepath.Join(t.TempDir(), "volume", "runtimes")
+	var result *RehomeResult
+	err := state.WithLock(func(st *state.State) (err error) {
+		result, err = Rehome(st, from, to, logger.New())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Moved || result.Installations != 1 || result.PathEntries != 1 || result.EnvVars != 1 {
+		t.Errorf("Rehome() = %+v, want the directory moved and one of each rewritten", result)
+	}
+	if got, _ := RuntimesDir(); got != to {
+		t.Errorf("RuntimesDir() = %s, want %s", got, to)
+	}
+	if _, err := os.Stat(from); !os.IsNotExist(err) {
+		t.Errorf("%s should be gone after the move", from)
+	}
+	link := filepath.Join(to, "fake", "current")
+	if target, err := filepath.EvalSymlinks(link); err != nil || target != filepath.Join(to, "fake", "1.0.0") {
+		t.Errorf("current link resolves to %s, %v, want the moved version", target, err)
+	}
+
+	home, _ := os.UserHomeDir()
+	bashrc, _ := os.ReadFile(filepath.Join(home, ".bashrc"))
+	if strings.Contains(string(bashrc), from) || !strings.Contains(string(bashrc), `export PATH="`+link+`:$PATH"`) || !strings.Contains(string(bashrc), `export FAKE_HOME="`+link+`"`) {
+		t.Errorf(".bashrc should only point into %s:\n%s", to, bashrc)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base, _ := st.RuntimesBase(); base != to {
+		t.Errorf("state RuntimesBase() = %s, want %s", base, to)
+	}
+	if len(st.PathModifications) != 1 || st.PathModifications[0].Value != link {
+		t.Errorf("state PATH entries = %+v, want only %s", st.PathModifications, link)
+	}
+	if len(st.EnvModifications) != 1 || st.EnvModifications[0].Value != link {
+		t.Errorf("state env vars = %+v, want FAKE_HOME=%s", st.EnvModifications, link)
+	}
+
+	// Moving back onto a directory that has runtimes in it is refused
+	if err := os.MkdirAll(filepath.Join(from, "node", "22.14.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rehome(st, to, from, logger.New()); err == nil {
+		t.Error("Rehome() onto a non-empty directory should fail")
+	}
+}
+
+func TestRehome_AlreadyMoved(t *testing.T) {
+	skipOnWindows(t)
+	tempHome(t)
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv("PATH", os.Getenv("PATH"))
+	from := customRuntimesDir(t)
+	installFake(t)
+
+	// The user moved the directory by hand
+	to := filepath.Join(t.TempDir(), "moved")
+	if err := os.Rename(from, to); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := state.Load()
+	result, err := Rehome(st, from, to, logger.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Moved || result.Installations != 1 {
+		t.Errorf("Rehome() = %+v, want only the paths rewritten", result)
+	}
+	if insts := st.GetInstallations("fake"); insts[0].Path != filepath.Join(to, "fake", "1.0.0") {
+		t.Errorf("installation path = %s, want it in %s", insts[0].Path, to)
+	}
+}
@@ -0,0 +1,122 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+// MaxAttempts is how many times a download or metadata fetch is tried before
+// giving up. Only transient failures (dropped connections, 5xx responses)
+// are retried; tests set it to 1 to fail fast.
+var MaxAttempts = 3
+
+// retryDelay is the wait before the first retry; it doubles after each one.
+var retryDelay = time.Second
+
+var (
+	installLogMu sync.Mutex
+	installLog   *logger.Logger // records retries and mirror use; nil until an install starts
+)
+
+// setInstallLogger sets the logger that retry attempts and mirror use are
+// reported to. The installers' download helpers don't take a logger, so the
+// install entry points set it once for the run.
+func setInstallLogger(log *logger.Logger) {
+	installLogMu.Lock()
+	defer installLogMu.Unlock()
+	installLog = log
+}
+
+// currentInstallLogger returns the logger set by setInstallLogger, or nil.
+func currentInstallLogger() *logger.Logger {
+	installLogMu.Lock()
+	defer installLogMu.Unlock()
+	return installLog
+}
+
+// ErrNetwork matches (with errors.Is) a download or metadata fetch that
+// failed on the network: a dropped connection or an unexpected HTTP status.
+var ErrNetwork = errors.New("network error")
+
+// transientError marks a failure that may succeed if tried again.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string        { return e.err.Error() }
+func (e *transientError) Unwrap() error        { return e.err }
+func (e *transientError) Is(target error) bool { return target == ErrNetwork }
+
+// transient marks err as worth retrying.
+func transient(err error) error {
+	return &transientError{err: err}
+}
+
+// httpStatusError is an unexpected HTTP response status, kept so callers can
+// tell a missing file (404) apart from other failures.
+type httpStatusError struct {
+	code int
+	err  error
+}
+
+func (e *httpStatusError) Error() string        { return e.err.Error() }
+func (e *httpStatusError) Unwrap() error        { return e.err }
+func (e *httpStatusError) Is(target error) bool { return target == ErrNetwork }
+
+// statusError marks an HTTP status error as transient when the server is
+// at fault (5xx); client errors such as 404 won't change on retry.
+func statusError(code int, err error) error {
+	err = &httpStatusError{code: code, err: err}
+	if code >= 500 {
+		return transient(err)
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	var s *httpStatusError
+	return errors.As(err, &s) && s.code == http.StatusNotFound
+}
+
+func isTransient(err error) bool {
+	var t *transientError
+	return errors.As(err, &t)
+}
+
+// withRetry calls fn up to MaxAttempts times with exponential backoff,
+// stopping at the first success or non-transient error. what names the
+// operation in the log, e.g. the URL being fetched. Once ctx is cancelled
+// no more attempts are made and ErrCancelled is returned.
+func withRetry(ctx context.Context, what string, fn func() error) error {
+	delay := retryDelay
+	var err error
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		err = fn()
+		if err != nil && ctx.Err() != nil {
+			return ErrCancelled
+		}
+		if err == nil || !isTransient(err) {
+			return err
+		}
+		if attempt == MaxAttempts {
+			break
+		}
+
+		if log := currentInstallLogger(); log != nil {
+			log.Warn("Attempt %d of %d for %s failed: %s (retrying in %s)", attempt, MaxAttempts, what, err, delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrCancelled
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+// fastRetries shortens the backoff so retry tests don't sleep.
+func fastRetries(t *testing.T) {
+	t.Helper()
+	old := retryDelay
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = old })
+}
+
+// flakyServer fails the first `failures` requests with status, then serves body.
+func flakyServer(t *testing.T, failures int32, status int, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+func TestFetchJSON_RetriesServerErrors(t *testing.T) {
+	fastRetries(t)
+	ts, requests := flakyServer(t, 2, http.StatusBadGateway, `{"ok":true}`)
+
+	data, err := FetchJSON(ts.URL)
+	if err != nil {
+		t.Fatalf("expected success after retries, got %s", err)
+	}
+	if string(data) != `{"ok":true}` {
+		t.Errorf("unexpected body %q", data)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestFetchJSON_GivesUpAfterMaxAttempts(t *testing.T) {
+	fastRetries(t)
+	ts, requests := flakyServer(t, 10, http.StatusServiceUnavailable, "")
+
+	if _, err := FetchJSON(ts.URL); err == nil || !strings.Contains(err.Error(), "HTTP 503") || !errors.Is(err, ErrNetwork) {
+		t.Errorf("expected HTTP 503 error matching ErrNetwork, got %v", err)
+	}
+	if n := requests.Load(); n != int32(MaxAttempts) {
+		t.Errorf("expected %d requests, got %d", MaxAttempts, n)
+	}
+}
+
+func TestDownloadFile_NoRetryOn404(t *testing.T) {
+	fastRetries(t)
+	ts, requests := flakyServer(t, 10, http.StatusNotFound, "")
+
+	if err := DownloadFile(context.Background(), ts.URL, filepath.Join(t.TempDir(), "f"), nil); !errors.Is(err, ErrNetwork) {
+		t.Fatalf("expected an ErrNetwork error for 404, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("404 should not be retried, got %d requests", n)
+	}
+}
+
+func TestDownloadFile_MaxAttemptsOne(t *testing.T) {
+	fastRetries(t)
+	old := MaxAttempts
+	MaxAttempts = 1
+	t.Cleanup(func() { MaxAttempts = old })
+
+	ts, requests := flakyServer(t, 1, http.StatusBadGateway, "content")
+	if err := DownloadFile(context.Background(), ts.URL, filepath.Join(t.TempDir(), "f"), nil); err == nil {
+		t.Fatal("expected error with retries disabled")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestDownloadFile_RetriesConnectionErrors(t *testing.T) {
+	fastRetries(t)
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			panic(http.ErrAbortHandler) // connection reset before any response
+		}
+		w.Write([]byte("content"))
+	}))
+	defer ts.Close()
+
+	dest := filepath.Join(t.TempDir(), "f")
+	if err := DownloadFile(context.Background(), ts.URL, dest, nil); err != nil {
+		t.Fatalf("expected success after retry, got %s", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "content" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
+
+func TestWithRetry_LogsAttempts(t *testing.T) {
+	fastRetries(t)
+	t.Setenv("HOME", t.TempDir())
+	log := logger.New()
+	log.SetLevel(logger.ERROR) // keep WARN lines out of test output
+	if err := log.Init(); err != nil {
+		t.Fatalf("logger init: %s", err)
+	}
+	setInstallLogger(log)
+	t.Cleanup(func() { setInstallLogger(nil) })
+
+	calls := 0
+	err := withRetry(context.Background(), "https://example.com/index.json", func() error {
+		calls++
+		if calls < 3 {
+			return transient(errors.New("connection reset by peer"))
+		}
+		return nil
+	})
+	log.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, _ := os.ReadFile(log.FilePath())
+	for _, want := range []string{
+		"Attempt 1 of 3 for https://example.com/index.json failed: connection reset by peer",
+		"Attempt 2 of 3 for https://example.com/index.json failed: connection reset by peer",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestWithRetry_PermanentError(t *testing.T) {
+	fastRetries(t)
+	calls := 0
+	err := withRetry(context.Background(), "x", func() error {
+		calls++
+		return errors.New("checksum mismatch")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected one call and an error, got %d calls, err %v", calls, err)
+	}
+}
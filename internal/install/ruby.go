@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 )
@@ -14,7 +15,7 @@ func (r *RubyInstaller) ResolveVersion(requirement string) (string, error) {
 	return "", fmt.Errorf("Ruby installer not yet implemented - install Ruby manually from https://www.ruby-lang.org/en/downloads/")
 }
 
-func (r *RubyInstaller) Install(version, targetDir string, progress ProgressFunc) error {
+func (r *RubyInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
 	return fmt.Errorf("Ruby installer not yet implemented - install Ruby %s manually from https://www.ruby-lang.org/en/downloads/", version)
 }
 
@@ -0,0 +1,41 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// ParseRuntimeSpecs parses `install` arguments of the form
+// runtime[@version], e.g. "node@>=20" or "python@3.12", into [runtimes]
+// requirements. A bare runtime name means "latest".
+func ParseRuntimeSpecs(args []string) (map[string]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no runtimes given - supported: %s", strings.Join(manifest.SupportedRuntimes(), ", "))
+	}
+
+	specs := make(map[string]string, len(args))
+	for _, arg := range args {
+		name, version, _ := strings.Cut(arg, "@")
+		name = strings.ToLower(strings.TrimSpace(name))
+		version = strings.TrimSpace(version)
+		if version == "" {
+			version = "latest"
+		}
+		if _, err := FindInstaller(name); err != nil {
+			return nil, err
+		}
+		if _, dup := specs[name]; dup {
+			return nil, fmt.Errorf("%s is given more than once", name)
+		}
+		specs[name] = version
+	}
+	return specs, nil
+}
+
+// RuntimesManifest returns a manifest that requires only the given
+// runtimes, so they can be planned and installed without a .templatr.toml.
+func RuntimesManifest(specs map[string]string) *manifest.Manifest {
+	return &manifest.Manifest{Runtimes: specs}
+}
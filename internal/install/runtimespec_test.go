@@ -0,0 +1,81 @@
+package install
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+func TestParseRuntimeSpecs(t *testing.T) {
+	specs, err := ParseRuntimeSpecs([]string{"node@>=20", "Python@3.12", "go"})
+	if err != nil {
+		t.Fatalf("ParseRuntimeSpecs() error: %s", err)
+	}
+	want := map[string]string{"node": ">=20", "python": "3.12", "go": "latest"}
+	for name, version := range want {
+		if specs[name] != version {
+			t.Errorf("specs[%s] = %q, want %q", name, specs[name], version)
+		}
+	}
+	if len(specs) != len(want) {
+		t.Errorf("specs = %v, want %v", specs, want)
+	}
+}
+
+func TestParseRuntimeSpecs_Errors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "no runtimes given"},
+		{[]string{"nodejs@22"}, `unknown runtime "nodejs" - supported: bun, deno, dotnet, flutter, go, java, node, php, python, ruby, rust`},
+		{[]string{"node@20", "node@22"}, "node is given more than once"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRuntimeSpecs(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseRuntimeSpecs(%v) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestInstallRuntimesWithoutManifest(t *testing.T) {
+	tempHome(t)
+	stubDiskFree(t, 1<<40)
+
+	ts, _, _ := countingServer(t, "runtime archive")
+	registerFake(t, &fakeInstaller{url: ts.URL})
+
+	specs, err := ParseRuntimeSpecs([]string{"fake@1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := engine.BuildPlan(RuntimesManifest(specs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Runtimes) != 1 || plan.Runtimes[0].Action != engine.ActionInstall || plan.Runtimes[0].RequiredVersion != "1" {
+		t.Fatalf("plan runtimes = %+v, want one install of fake 1", plan.Runtimes)
+	}
+
+	results, err := ExecutePlan(context.Background(), plan, logger.New(), nil, ExecuteOptions{})
+	if err != nil {
+		t.Fatalf("ExecutePlan() error: %s", err)
+	}
+	if len(results) != 1 || results[0].Version != "1.0.0" {
+		t.Fatalf("results = %+v, want fake 1.0.0", results)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	insts := st.GetInstallations("fake")
+	if len(insts) != 1 || insts[0].Path != results[0].InstallPath || insts[0].Template != "" {
+		t.Errorf("state should record the install without a template, got %+v", insts)
+	}
+}
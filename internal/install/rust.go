@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -19,22 +20,22 @@ func (r *RustInstaller) ResolveVersion(requirement string) (string, error) {
 	return "stable", nil
 }
 
-func (r *RustInstaller) Install(version, targetDir string, progress ProgressFunc) error {
+func (r *RustInstaller) Install(ctx context.Context, version, targetDir string, progress ProgressFunc) error {
 	target := rustTarget()
 
 	if runtime.GOOS == "windows" {
-		return r.installWindows(targetDir, target, progress)
+		return r.installWindows(ctx, targetDir, target, progress)
 	}
-	return r.installUnix(targetDir, target, progress)
+	return r.installUnix(ctx, targetDir, target, progress)
 }
 
-func (r *RustInstaller) installUnix(targetDir, target string, progress ProgressFunc) error {
+func (r *RustInstaller) installUnix(ctx context.Context, targetDir, target string, progress ProgressFunc) error {
 	// Download rustup-init
 	url := fmt.Sprintf("https://static.rust-lang.org/rustup/dist/%s/rustup-init", target)
 	tmpFile := filepath.Join(os.TempDir(), "rustup-init")
 	defer os.Remove(tmpFile)
 
-	if err := DownloadFile(url, tmpFile, progress); err != nil {
+	if err := fetchArchive(ctx, r.Name(), url, tmpFile, progress, func() (string, error) { return "", nil }); err != nil {
 		return fmt.Errorf("failed to download rustup: %w", err)
 	}
 
@@ -47,7 +48,7 @@ func (r *RustInstaller) installUnix(targetDir, target string, progress ProgressF
 	cargoHome := filepath.Join(targetDir, ".cargo")
 	rustupHome := filepath.Join(targetDir, ".rustup")
 
-	cmd := exec.Command(tmpFile, "--default-toolchain", "stable", "-y", "--no-modify-path")
+	cmd := exec.CommandContext(ctx, tmpFile, "--default-toolchain", "stable", "-y", "--no-modify-path")
 	cmd.Env = append(os.Environ(),
 		"CARGO_HOME="+cargoHome,
 		"RUSTUP_HOME="+rustupHome,
@@ -62,19 +63,19 @@ func (r *RustInstaller) installUnix(targetDir, target string, progress ProgressF
 	return nil
 }
 
-func (r *RustInstaller) installWindows(targetDir, target string, progress ProgressFunc) error {
+func (r *RustInstaller) installWindows(ctx context.Context, targetDir, target string, progress ProgressFunc) error {
 	url := fmt.Sprintf("https://static.rust-lang.org/rustup/dist/%s/rustup-init.exe", target)
 	tmpFile := filepath.Join(os.TempDir(), "rustup-init.exe")
 	defer os.Remove(tmpFile)
 
-	if err := DownloadFile(url, tmpFile, progress); err != nil {
+	if err := fetchArchive(ctx, r.Name(), url, tmpFile, progress, func() (string, error) { return "", nil }); err != nil {
 		return fmt.Errorf("failed to download rustup: %w", err)
 	}
 
 	cargoHome := filepath.Join(targetDir, ".cargo")
 	rustupHome := filepath.Join(targetDir, ".rustup")
 
-	cmd := exec.Command(tmpFile, "--default-toolchain", "stable", "-y", "--no-modify-path")
+	cmd := exec.CommandContext(ctx, tmpFile, "--default-toolchain", "stable", "-y", "--no-modify-path")
 	cmd.Env = append(os.Environ(),
 		"CARGO_HOME="+cargoHome,
 		"RUSTUP_HOME="+rustupHome,
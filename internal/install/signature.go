@@ -0,0 +1,118 @@
+package install
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// verifySignatures is set by --verify-signatures or verify_signatures.
+var verifySignatures bool
+
+// SetVerifySignatures makes installers whose runtime publishes PGP
+// signatures (Node.js, Go) check them with the keys in KeysDir before
+// trusting a download, for the rest of the run.
+func SetVerifySignatures(on bool) {
+	verifySignatures = on
+}
+
+// ErrSignature is returned (wrapped) when a signature doesn't verify.
+var ErrSignature = errors.New("signature verification failed")
+
+// KeysDir returns the directory holding the PGP release keys signatures
+// are checked against, one armored <runtime>.asc file per runtime
+// (~/.templatr/keys).
+func KeysDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".templatr", "keys"), nil
+}
+
+// releaseKeys reads the armored public keys runtimeName's releases are
+// signed with from KeysDir.
+func releaseKeys(runtimeName string) (openpgp.EntityList, error) {
+	dir, err := KeysDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, runtimeName+".asc")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no release keys to verify %s signatures with: add the project's armored public keys to %s", runtimeName, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the keys in %s: %w", path, err)
+	}
+	return keyring, nil
+}
+
+// VerifySignature checks that signature, a detached PGP signature either
+// armored or binary, was made over signed by one of the keys in keyring.
+// what names the file that was signed, for the error.
+func VerifySignature(signed io.Reader, signature []byte, keyring openpgp.EntityList, what string) error {
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, signed, bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, signed, bytes.NewReader(signature), nil)
+	}
+	if errors.Is(err, io.EOF) {
+		err = errors.New("not a PGP signature")
+	}
+	if err != nil {
+		return fmt.Errorf("%w for %s: %s", ErrSignature, what, err)
+	}
+	return nil
+}
+
+// fetchSignedChecksum is FetchChecksumFromURL for a checksums file
+// signed at sigURL, whose signature is checked with runtimeName's release
+// keys before the checksum is taken from it.
+func fetchSignedChecksum(runtimeName, url, sigURL, filename string) (string, error) {
+	keyring, err := releaseKeys(runtimeName)
+	if err != nil {
+		return "", err
+	}
+	body, err := FetchJSON(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksums: %w", err)
+	}
+	sig, err := FetchJSON(sigURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the signature of %s: %w", filepath.Base(url), err)
+	}
+	if err := VerifySignature(bytes.NewReader(body), sig, keyring, url); err != nil {
+		return "", err
+	}
+	return findChecksum(body, filename, url)
+}
+
+// verifyFileSignature checks the downloaded file at path against the
+// detached signature at sigURL with runtimeName's release keys.
+func verifyFileSignature(runtimeName, path, sigURL string) error {
+	keyring, err := releaseKeys(runtimeName)
+	if err != nil {
+		return err
+	}
+	sig, err := FetchJSON(sigURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the signature of %s: %w", filepath.Base(path), err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	return VerifySignature(f, sig, keyring, filepath.Base(path))
+}
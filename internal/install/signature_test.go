@@ -0,0 +1,137 @@
+package install
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// signingKey returns a new PGP key to sign test fixtures with.
+func signingKey(t *testing.T, name string) *openpgp.Entity {
+	t.Helper()
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+// armoredPublicKey returns entity's public key as a <runtime>.asc file has it.
+func armoredPublicKey(t *testing.T, entity *openpgp.Entity) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func sign(t *testing.T, entity *openpgp.Entity, data string, armored bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	if armored {
+		err = openpgp.ArmoredDetachSign(&buf, entity, strings.NewReader(data), nil)
+	} else {
+		err = openpgp.DetachSign(&buf, entity, strings.NewReader(data), nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifySignature(t *testing.T) {
+	release, other := signingKey(t, "release"), signingKey(t, "other")
+	keyring := openpgp.EntityList{release}
+	const data = "abc123  node-v22.14.0-linux-x64.tar.gz\n"
+
+	tampered := sign(t, release, data, false)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name      string
+		data      string
+		signature []byte
+		ok        bool
+	}{
+		{"binary signature", data, sign(t, release, data, false), true},
+		{"armored signature", data, sign(t, release, data, true), true},
+		{"tampered data", strings.Replace(data, "abc123", "def456", 1), sign(t, release, data, false), false},
+		{"tampered signature", data, tampered, false},
+		{"another key", data, sign(t, other, data, true), false},
+		{"not a signature", data, []byte("hello"), false},
+		{"empty signature", data, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(strings.NewReader(tt.data), tt.signature, keyring, "SHASUMS256.txt")
+			if tt.ok {
+				if err != nil {
+					t.Errorf("VerifySignature() = %v, want it to verify", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSignature) || !strings.Contains(err.Error(), "SHASUMS256.txt") {
+				t.Errorf("VerifySignature() = %v, want ErrSignature naming SHASUMS256.txt", err)
+			}
+		})
+	}
+}
+
+func TestFetchSignedChecksum(t *testing.T) {
+	tempHome(t)
+	release := signingKey(t, "release")
+	const shasums = "abc123  node-v22.14.0-linux-x64.tar.gz\n"
+	sig := sign(t, release, shasums, false)
+
+	body := shasums
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHASUMS256.txt":
+			w.Write([]byte(body))
+		case "/SHASUMS256.txt.sig":
+			w.Write(sig)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	url := ts.URL + "/SHASUMS256.txt"
+
+	if _, err := fetchSignedChecksum("node", url, url+".sig", "node-v22.14.0-linux-x64.tar.gz"); err == nil || !strings.Contains(err.Error(), "node.asc") {
+		t.Fatalf("fetchSignedChecksum() without keys = %v, want an error naming node.asc", err)
+	}
+
+	dir, _ := KeysDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "node.asc"), armoredPublicKey(t, release), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := fetchSignedChecksum("node", url, url+".sig", "node-v22.14.0-linux-x64.tar.gz")
+	if err != nil || sum != "abc123" {
+		t.Fatalf("fetchSignedChecksum() = %q, %v, want abc123", sum, err)
+	}
+
+	// A compromised mirror swapping the checksum can't sign it
+	body = strings.Replace(shasums, "abc123", "def456", 1)
+	if _, err := fetchSignedChecksum("node", url, url+".sig", "node-v22.14.0-linux-x64.tar.gz"); !errors.Is(err, ErrSignature) {
+		t.Errorf("fetchSignedChecksum() of tampered checksums = %v, want ErrSignature", err)
+	}
+}
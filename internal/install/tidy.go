@@ -0,0 +1,163 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// Reasons TidyShellConfigs removes a block.
+const (
+	TidyDuplicate = "duplicate" // another block in the file is for the same PATH directory or variable
+	TidyUnknown   = "unknown"   // state.json has no such PATH entry or variable, e.g. an old version's
+)
+
+// UntidyBlock is a block in a shell config file that state.json doesn't
+// account for. Which of several blocks for a runtime wins depends on their
+// order in the file, so these can hide the version setup last installed.
+type UntidyBlock struct {
+	File   string
+	Block  ManagedBlock
+	Reason string // TidyDuplicate or TidyUnknown
+}
+
+// Describe says what the block is and why it would go, for doctor and
+// path tidy.
+func (u UntidyBlock) Describe() string {
+	where := fmt.Sprintf("line %d", u.Block.Start+1)
+	if u.Reason == TidyDuplicate {
+		return fmt.Sprintf("%s: a second block for %s", where, u.Block.Value)
+	}
+	return fmt.Sprintf("%s: a block for %s, which state.json doesn't record", where, u.Block.Value)
+}
+
+// UntidyBlocks returns the blocks in the files the tool writes PATH and
+// env var lines to that don't match st: blocks for directories and
+// variables st has no record of, and all but one of the blocks for one it
+// has. The one kept is the first whose line is as the tool would write it
+// now, or else the first.
+func UntidyBlocks(st *state.State) ([]UntidyBlock, error) {
+	var untidy []UntidyBlock
+	for _, rcFile := range tidyFiles(st) {
+		blocks, err := ParseManagedBlocks(rcFile)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rcFile, err)
+		}
+		untidy = append(untidy, untidyIn(rcFile, blocks, st)...)
+	}
+	return untidy, nil
+}
+
+// untidyIn picks the blocks of rcFile that st doesn't account for, in the
+// order they appear.
+func untidyIn(rcFile string, blocks []ManagedBlock, st *state.State) []UntidyBlock {
+	drop := make([]string, len(blocks)) // the reason for each block, or ""
+	kept := make(map[string]int)        // value -> index of the block kept for it
+	for i, b := range blocks {
+		want, recorded := recordedLine(rcFile, b.Value, st)
+		k, seen := kept[b.Value]
+		switch {
+		case !recorded:
+			drop[i] = TidyUnknown
+		case !seen:
+			kept[b.Value] = i
+		case blocks[k].Line != want && b.Line == want:
+			drop[k] = TidyDuplicate
+			kept[b.Value] = i
+		default:
+			drop[i] = TidyDuplicate
+		}
+	}
+
+	var untidy []UntidyBlock
+	for i, reason := range drop {
+		if reason != "" {
+			untidy = append(untidy, UntidyBlock{File: rcFile, Block: blocks[i], Reason: reason})
+		}
+	}
+	return untidy
+}
+
+// recordedLine returns the line the tool would write to rcFile for the
+// PATH directory or variable value names, and whether st records it.
+func recordedLine(rcFile, value string, st *state.State) (string, bool) {
+	fish := isFishConfig(rcFile)
+	for _, mod := range st.PathModifications {
+		if mod.Value != value {
+			continue
+		}
+		if strings.HasSuffix(rcFile, ".ps1") {
+			return profileLine(value), true
+		}
+		return pathLine(value, fish), true
+	}
+	for _, mod := range st.EnvModifications {
+		if mod.Name == value {
+			return envLine(mod.Name, mod.Value, fish), true
+		}
+	}
+	return "", false
+}
+
+// TidyShellConfigs removes the UntidyBlocks from their files, keeping the
+// rest of each file byte for byte, and returns what it removed.
+func TidyShellConfigs(st *state.State) ([]UntidyBlock, error) {
+	untidy, err := UntidyBlocks(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []UntidyBlock
+	for _, rcFile := range tidyFiles(st) {
+		var drop []UntidyBlock
+		for _, u := range untidy {
+			if u.File == rcFile {
+				drop = append(drop, u)
+			}
+		}
+		if len(drop) == 0 {
+			continue
+		}
+		_, err := removeBlocks(rcFile, func(b ManagedBlock) bool {
+			for _, u := range drop {
+				if u.Block == b {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return removed, fmt.Errorf("failed to tidy %s: %w", rcFile, err)
+		}
+		removed = append(removed, drop...)
+	}
+	return removed, nil
+}
+
+// tidyFiles returns the files to look for blocks in: those the tool writes
+// to on this system and those st records writing to.
+func tidyFiles(st *state.State) []string {
+	var files []string
+	add := func(rcFile string) {
+		if rcFile != "" && !containsString(files, rcFile) {
+			files = append(files, rcFile)
+		}
+	}
+	for _, rcFile := range blockFiles() {
+		add(rcFile)
+	}
+	for _, mod := range st.PathModifications {
+		for _, rcFile := range mod.ConfigFiles() {
+			add(rcFile)
+		}
+	}
+	for _, mod := range st.EnvModifications {
+		add(mod.File)
+	}
+	return files
+}
@@ -0,0 +1,139 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/state"
+)
+
+// tidyHome writes rc as ~/.bashrc, with the runtimes under ~/.templatr, and
+// returns the home directory and the state that setup would have left.
+func tidyHome(t *testing.T, rc string) (string, *state.State) {
+	t.Helper()
+	skipOnWindows(t)
+	home := preflightHome(t)
+	rc = strings.ReplaceAll(rc, "$HOME", home)
+	if err := os.WriteFile(filepath.Join(home, ".bashrc"), []byte(rc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runtimes := filepath.Join(home, ".templatr", "runtimes")
+	st := state.NewState()
+	st.AddPathModification(state.PathModification{Method: "shell_rc", Files: []string{filepath.Join(home, ".bashrc")}, Value: filepath.Join(runtimes, "node", "current", "bin")})
+	st.AddEnvModification(state.EnvModification{Name: "JAVA_HOME", Value: filepath.Join(runtimes, "java", "current"), Method: "shell_rc", File: filepath.Join(home, ".bashrc")})
+	return home, st
+}
+
+const untidyBashrc = `# ~/.bashrc
+export EDITOR=vim
+
+# templatr-setup: $HOME/.templatr/runtimes/node/18.19.0/bin
+export PATH="$HOME/.templatr/runtimes/node/18.19.0/bin:$PATH"
+# nvm, loaded after templatr's node on purpose
+export NVM_DIR="$HOME/.nvm"
+
+# templatr-setup: $HOME/.templatr/runtimes/node/current/bin
+export PATH="/somewhere/else:$PATH"
+
+# templatr-setup: JAVA_HOME
+export JAVA_HOME="$HOME/.templatr/runtimes/java/current"
+  # a comment between our blocks
+# templatr-setup: $HOME/.templatr/runtimes/node/current/bin
+export PATH="$HOME/.templatr/runtimes/node/current/bin:$PATH"
+
+# templatr-setup: JAVA_HOME
+export JAVA_HOME="$HOME/.templatr/runtimes/java/17.0.2"
+alias gs='git status'
+`
+
+// tidiedBashrc is untidyBashrc once tidied: the block for 18.19.0 and the
+// second blocks for current/bin and JAVA_HOME are gone, the blank lines
+// and user lines around them are not.
+const tidiedBashrc = `# ~/.bashrc
+export EDITOR=vim
+
+# nvm, loaded after templatr's node on purpose
+export NVM_DIR="$HOME/.nvm"
+
+
+# templatr-setup: JAVA_HOME
+export JAVA_HOME="$HOME/.templatr/runtimes/java/current"
+  # a comment between our blocks
+# templatr-setup: $HOME/.templatr/runtimes/node/current/bin
+export PATH="$HOME/.templatr/runtimes/node/current/bin:$PATH"
+
+alias gs='git status'
+`
+
+func TestUntidyBlocks(t *testing.T) {
+	home, st := tidyHome(t, untidyBashrc)
+	bashrc := filepath.Join(home, ".bashrc")
+
+	untidy, err := UntidyBlocks(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		start  int
+		value  string
+		reason string
+	}{
+		{3, home + "/.templatr/runtimes/node/18.19.0/bin", TidyUnknown},
+		// Written by hand: the block further down is as setup writes it
+		{8, home + "/.templatr/runtimes/node/current/bin", TidyDuplicate},
+		{17, "JAVA_HOME", TidyDuplicate},
+	}
+	if len(untidy) != len(want) {
+		t.Fatalf("UntidyBlocks() = %+v, want %d blocks", untidy, len(want))
+	}
+	for i, w := range want {
+		u := untidy[i]
+		if u.File != bashrc || u.Block.Start != w.start || u.Block.Value != w.value || u.Reason != w.reason {
+			t.Errorf("block %d = %+v, want %s at line %d for %s", i, u, w.reason, w.start+1, w.value)
+		}
+	}
+	if got := untidy[0].Describe(); got != "line 4: a block for "+home+"/.templatr/runtimes/node/18.19.0/bin, which state.json doesn't record" {
+		t.Errorf("Describe() = %q", got)
+	}
+}
+
+func TestTidyShellConfigs(t *testing.T) {
+	home, st := tidyHome(t, untidyBashrc)
+	bashrc := filepath.Join(home, ".bashrc")
+
+	removed, err := TidyShellConfigs(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("removed %+v, want 3 blocks", removed)
+	}
+	data, _ := os.ReadFile(bashrc)
+	if want := strings.ReplaceAll(tidiedBashrc, "$HOME", home); string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+
+	// Tidy again: nothing left to do, and the file isn't touched
+	if removed, err := TidyShellConfigs(st); err != nil || len(removed) != 0 {
+		t.Errorf("second TidyShellConfigs() = %+v, %v; want nothing removed", removed, err)
+	}
+}
+
+func TestTidyShellConfigs_Untouched(t *testing.T) {
+	rc := "# ~/.bashrc\n# templatr-setup: $HOME/.templatr/runtimes/node/current/bin\nexport PATH=\"$HOME/.templatr/runtimes/node/current/bin:$PATH\"\n"
+	home, st := tidyHome(t, rc)
+	zshrc := filepath.Join(home, ".zshrc")
+
+	// Another shell's file holding the same entries is tidied on its own
+	os.WriteFile(zshrc, []byte(strings.ReplaceAll(rc, "$HOME", home)+"setopt autocd"), 0o644)
+	if removed, err := TidyShellConfigs(st); err != nil || len(removed) != 0 {
+		t.Errorf("TidyShellConfigs() = %+v, %v; want a tidy file left alone", removed, err)
+	}
+	data, _ := os.ReadFile(zshrc)
+	if !strings.HasSuffix(string(data), "\nsetopt autocd") {
+		t.Errorf(".zshrc changed:\n%s", data)
+	}
+}
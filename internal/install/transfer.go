@@ -0,0 +1,141 @@
+package install
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/term"
+)
+
+const (
+	// reportInterval is the least time between progress reports, so a
+	// download reports at most about 10 times a second.
+	reportInterval = 100 * time.Millisecond
+	// reportStep is the change in percent reported sooner than that.
+	reportStep = 1.0
+	// speedWindow is how far back the rolling transfer speed looks.
+	speedWindow = 3 * time.Second
+)
+
+// TransferStats is a download's progress for display.
+type TransferStats struct {
+	Downloaded int64
+	Total      int64         // 0 if unknown
+	Percent    float64       // 0 to 100; 0 if Total is unknown
+	Speed      float64       // bytes per second over the last speedWindow; 0 until measured
+	ETA        time.Duration // time left at Speed; 0 if unknown
+}
+
+// SpeedText returns Speed such as "2.3 MB/s", or "" until it is measured.
+func (s TransferStats) SpeedText() string {
+	if s.Speed <= 0 {
+		return ""
+	}
+	return term.FormatBytes(int64(s.Speed)) + "/s"
+}
+
+// ETAText returns ETA such as "45s" or "3m05s", or "" if it is unknown.
+func (s TransferStats) ETAText() string {
+	if s.ETA <= 0 {
+		return ""
+	}
+	d := s.ETA.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// transferSample is how many bytes had been downloaded at a time.
+type transferSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// Transfer follows a download's progress for display. It works out a
+// rolling speed and the time left, and says which updates are worth
+// showing, since a ProgressFunc is called on every read.
+type Transfer struct {
+	now      func() time.Time
+	samples  []transferSample // oldest first, spanning about speedWindow
+	reported time.Time        // when progress was last shown; zero before the first
+	percent  float64          // the percent last shown
+	last     int64            // bytes at the previous update
+}
+
+// NewTransfer returns a Transfer for a download starting now.
+func NewTransfer() *Transfer {
+	return &Transfer{now: time.Now}
+}
+
+// Update records downloaded of total bytes and returns the stats, and
+// whether to show them: the first update of each file, the last one, and
+// in between once reportInterval has passed or the percent moved by
+// reportStep.
+func (t *Transfer) Update(downloaded, total int64) (TransferStats, bool) {
+	now := t.now()
+
+	// Progress restarts at zero for each file an installer downloads
+	if downloaded < t.last {
+		t.samples, t.reported = nil, time.Time{}
+	}
+	t.last = downloaded
+	// A sample per reportInterval is plenty for the speed
+	if n := len(t.samples); n == 0 || now.Sub(t.samples[n-1].at) >= reportInterval {
+		t.samples = append(t.samples, transferSample{at: now, bytes: downloaded})
+	}
+	for len(t.samples) > 2 && now.Sub(t.samples[1].at) >= speedWindow {
+		t.samples = t.samples[1:]
+	}
+
+	stats := TransferStats{Downloaded: downloaded, Total: total}
+	if total > 0 {
+		stats.Percent = float64(downloaded) / float64(total) * 100
+	}
+	if first := t.samples[0]; now.After(first.at) {
+		stats.Speed = float64(downloaded-first.bytes) / now.Sub(first.at).Seconds()
+	}
+	if total > downloaded && stats.Speed > 0 {
+		stats.ETA = time.Duration(float64(total-downloaded) / stats.Speed * float64(time.Second))
+	}
+
+	show := t.reported.IsZero() ||
+		(total > 0 && downloaded >= total) ||
+		now.Sub(t.reported) >= reportInterval ||
+		stats.Percent-t.percent >= reportStep
+	if show {
+		t.reported, t.percent = now, stats.Percent
+	}
+	return stats, show
+}
+
+// ThrottledProgress returns a ProgressFunc that passes report the stats of
+// the updates a Transfer says to show.
+func ThrottledProgress(report func(TransferStats)) ProgressFunc {
+	t := NewTransfer()
+	return func(downloaded, total int64) {
+		if stats, ok := t.Update(downloaded, total); ok {
+			report(stats)
+		}
+	}
+}
+
+// ThrottledEvents returns an EventFunc that passes report the first event
+// of each phase as it starts, then the downloading and extracting events a
+// Transfer says to show, with their stats.
+func ThrottledEvents(report func(ProgressEvent, TransferStats)) EventFunc {
+	var phase string
+	var t *Transfer
+	return func(ev ProgressEvent) {
+		if ev.Phase != phase {
+			phase, t = ev.Phase, NewTransfer()
+		}
+		if stats, ok := t.Update(ev.Done, ev.Total); ok {
+			report(ev, stats)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package install
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeClock is a synthetic clock for a Transfer.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func newFakeTransfer() (*Transfer, *fakeClock) {
+	c := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return &Transfer{now: c.now}, c
+}
+
+func TestTransfer_Throttle(t *testing.T) {
+	tr, clock := newFakeTransfer()
+	const total = 100_000
+
+	shown := 0
+	update := func(downloaded int64) bool {
+		_, ok := tr.Update(downloaded, total)
+		if ok {
+			shown++
+		}
+		return ok
+	}
+
+	if !update(0) {
+		t.Error("the first update should be shown")
+	}
+	// Reads a millisecond apart, each well under 1%
+	for i := int64(1); i <= 50; i++ {
+		clock.advance(time.Millisecond)
+		update(i * 10)
+	}
+	if shown != 1 {
+		t.Errorf("shown %d updates in 50ms of small reads, want only the first", shown)
+	}
+	clock.advance(time.Millisecond)
+	if !update(2_000) {
+		t.Error("an update 1.5% on should be shown")
+	}
+	clock.advance(reportInterval)
+	if !update(2_010) {
+		t.Error("an update reportInterval after the last shown should be shown")
+	}
+	clock.advance(time.Millisecond)
+	if !update(total) {
+		t.Error("the last update should be shown")
+	}
+
+	// A second file starts again from zero and is shown at once
+	clock.advance(time.Millisecond)
+	if !update(0) {
+		t.Error("the first update of the next file should be shown")
+	}
+}
+
+func TestTransfer_SpeedAndETA(t *testing.T) {
+	tr, clock := newFakeTransfer()
+	const total = 10 << 20
+
+	stats, _ := tr.Update(0, total)
+	if stats.Speed != 0 || stats.ETA != 0 || stats.SpeedText() != "" || stats.ETAText() != "" {
+		t.Errorf("first update = %+v, want no speed or ETA yet", stats)
+	}
+
+	// 1 MB/s for two seconds
+	var downloaded int64
+	for range 20 {
+		clock.advance(100 * time.Millisecond)
+		downloaded += 100 << 10
+		stats, _ = tr.Update(downloaded, total)
+	}
+	if stats.Speed < 1000<<10 || stats.Speed > 1050<<10 {
+		t.Errorf("speed = %.0f, want about 1 MB/s", stats.Speed)
+	}
+	if got := stats.SpeedText(); got != "1000.0 KB/s" {
+		t.Errorf("SpeedText() = %q", got)
+	}
+	if stats.ETA < 7*time.Second || stats.ETA > 9*time.Second {
+		t.Errorf("ETA = %s, want about 8s for the remaining 8 MB", stats.ETA)
+	}
+	if got := stats.ETAText(); got != "8s" {
+		t.Errorf("ETAText() = %q, want 8s", got)
+	}
+
+	// The speed follows the last speedWindow, so it drops after a stall
+	for range 40 {
+		clock.advance(100 * time.Millisecond)
+		downloaded += 10 << 10
+		stats, _ = tr.Update(downloaded, total)
+	}
+	if stats.Speed > 150<<10 {
+		t.Errorf("speed after slowing to 100 KB/s = %.0f, want the old rate forgotten", stats.Speed)
+	}
+	if stats.Percent <= 0 || stats.Percent >= 100 {
+		t.Errorf("percent = %f", stats.Percent)
+	}
+}
+
+func TestTransferStats_ETAText(t *testing.T) {
+	for _, tt := range []struct {
+		eta  time.Duration
+		want string
+	}{
+		{0, ""},
+		{45 * time.Second, "45s"},
+		{3*time.Minute + 5*time.Second, "3m05s"},
+		{time.Hour + 2*time.Minute + 30*time.Second, "1h02m"},
+	} {
+		if got := (TransferStats{ETA: tt.eta}).ETAText(); got != tt.want {
+			t.Errorf("ETAText(%s) = %q, want %q", tt.eta, got, tt.want)
+		}
+	}
+}
+
+func TestThrottledProgress(t *testing.T) {
+	var reports []TransferStats
+	progress := ThrottledProgress(func(s TransferStats) { reports = append(reports, s) })
+	for i := int64(0); i <= 1000; i++ {
+		progress(i*100, 100_000)
+	}
+	// The first, one per percent at most, and the last
+	if n := len(reports); n < 2 || n > 102 {
+		t.Errorf("%d reports for 1001 updates, want about one per percent", n)
+	}
+	if last := reports[len(reports)-1]; last.Downloaded != 100_000 || last.Percent != 100 {
+		t.Errorf("last report = %+v, want the download finished", last)
+	}
+}
+
+func TestThrottledEvents(t *testing.T) {
+	var phases []string
+	var extracted []int64
+	events := ThrottledEvents(func(ev ProgressEvent, s TransferStats) {
+		phases = append(phases, ev.Phase)
+		if ev.Phase == PhaseExtracting {
+			extracted = append(extracted, s.Downloaded)
+		}
+	})
+	events(ProgressEvent{Phase: PhaseResolving})
+	for i := int64(0); i <= 1000; i++ {
+		events(ProgressEvent{Phase: PhaseDownloading, Done: i * 100, Total: 100_000})
+	}
+	events(ProgressEvent{Phase: PhaseVerifying})
+	for i := int64(0); i <= 3; i++ {
+		events(ProgressEvent{Phase: PhaseExtracting, Done: i, Total: 3})
+	}
+	events(ProgressEvent{Phase: PhaseLinking})
+
+	if phases[0] != PhaseResolving || phases[len(phases)-1] != PhaseLinking {
+		t.Errorf("phases = %v, want resolving first and linking last", phases)
+	}
+	if n := len(phases); n > 110 {
+		t.Errorf("%d reports, want the downloading and extracting events throttled", n)
+	}
+	// Each of the 3 files is over reportStep percent of the archive
+	if want := []int64{0, 1, 2, 3}; !slices.Equal(extracted, want) {
+		t.Errorf("extracted = %v, want %v", extracted, want)
+	}
+}
@@ -0,0 +1,71 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// AvailableVersion is a runtime release an installer can resolve to.
+type AvailableVersion struct {
+	Version  string `json:"version"`
+	Channel  string `json:"channel,omitempty"`  // e.g. "stable" or "beta" for Flutter and Go
+	LTS      bool   `json:"lts,omitempty"`      // long-term support release (Node.js, Java)
+	Codename string `json:"codename,omitempty"` // Node.js LTS codename, e.g. "Jod"
+}
+
+// VersionLister is implemented by installers that can list the versions in
+// their release index, newest first.
+type VersionLister interface {
+	ListVersions() ([]AvailableVersion, error)
+}
+
+// FindInstaller returns the installer registered for name, or an error
+// listing the supported runtimes.
+func FindInstaller(name string) (Installer, error) {
+	if installer := GetInstaller(strings.ToLower(name)); installer != nil {
+		return installer, nil
+	}
+	return nil, fmt.Errorf("unknown runtime %q - supported: %s", name, strings.Join(manifest.SupportedRuntimes(), ", "))
+}
+
+// ListVersions returns the versions installer can resolve, newest first.
+// Installers without a release index report only what "latest" resolves to.
+func ListVersions(installer Installer) ([]AvailableVersion, error) {
+	lister, ok := installer.(VersionLister)
+	if !ok {
+		version, err := installer.ResolveVersion("latest")
+		if err != nil {
+			return nil, err
+		}
+		return []AvailableVersion{{Version: version}}, nil
+	}
+
+	return lister.ListVersions()
+}
+
+// FilterVersions keeps the versions that satisfy a semver constraint such
+// as ">=20". An empty constraint keeps everything.
+func FilterVersions(versions []AvailableVersion, constraint string) ([]AvailableVersion, error) {
+	if constraint == "" {
+		return versions, nil
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint %q: %w", constraint, err)
+	}
+
+	var filtered []AvailableVersion
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v.Version)
+		if err != nil {
+			continue
+		}
+		if c.Check(sv) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,162 @@
+package install
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// indexServer serves fixed JSON bodies by request path.
+func indexServer(t *testing.T, bodies map[string]string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func versionStrings(versions []AvailableVersion) string {
+	var s []string
+	for _, v := range versions {
+		s = append(s, v.Version)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestListVersions_Node(t *testing.T) {
+	tempHome(t)
+	ts := indexServer(t, map[string]string{"/index.json": `[
+		{"version": "v23.7.0", "lts": false},
+		{"version": "v22.14.0", "lts": "Jod"},
+		{"version": "v20.18.3", "lts": "Iron"},
+		{"version": "v20.9.0", "lts": "Iron"}
+	]`})
+	writeUserConfig(t, "[mirrors]\nnode = \""+ts.URL+"\"\n")
+
+	versions, err := ListVersions(&NodeInstaller{})
+	if err != nil {
+		t.Fatalf("ListVersions() error: %s", err)
+	}
+	if got := versionStrings(versions); got != "23.7.0 22.14.0 20.18.3 20.9.0" {
+		t.Errorf("versions = %s, want newest first", got)
+	}
+	if versions[0].LTS || !versions[1].LTS || versions[1].Codename != "Jod" {
+		t.Errorf("LTS should be marked: %+v", versions[:2])
+	}
+
+	filtered, err := FilterVersions(versions, ">=20 <22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versionStrings(filtered); got != "20.18.3 20.9.0" {
+		t.Errorf("filtered = %s, want the 20.x releases", got)
+	}
+}
+
+func TestListVersions_GoAndFlutterChannels(t *testing.T) {
+	tempHome(t)
+	ts := indexServer(t, map[string]string{
+		"/": `[
+			{"version": "go1.24rc1", "stable": false},
+			{"version": "go1.23.4", "stable": true},
+			{"version": "go1.22.5", "stable": true}
+		]`,
+		"/flutter_infra_release/releases/releases_" + flutterPlatform() + ".json": `{"releases": [
+			{"version": "3.27.0-0.1.pre", "channel": "beta"},
+			{"version": "3.24.5", "channel": "stable"},
+			{"version": "3.24.5", "channel": "stable"},
+			{"version": "3.22.3", "channel": "stable"}
+		]}`,
+	})
+	writeUserConfig(t, "[mirrors]\ngo = \""+ts.URL+"\"\nflutter = \""+ts.URL+"\"\n")
+
+	goVersions, err := ListVersions(&GoInstaller{})
+	if err != nil {
+		t.Fatalf("ListVersions(go) error: %s", err)
+	}
+	if got := versionStrings(goVersions); got != "1.24rc1 1.23.4 1.22.5" {
+		t.Errorf("go versions = %s", got)
+	}
+	if goVersions[0].Channel != "unstable" || goVersions[1].Channel != "stable" {
+		t.Errorf("go channels not marked: %+v", goVersions)
+	}
+
+	flutter, err := ListVersions(&FlutterInstaller{})
+	if err != nil {
+		t.Fatalf("ListVersions(flutter) error: %s", err)
+	}
+	if got := versionStrings(flutter); got != "3.27.0-0.1.pre 3.24.5 3.22.3" {
+		t.Errorf("flutter versions = %s, want duplicates dropped", got)
+	}
+	if flutter[0].Channel != "beta" {
+		t.Errorf("flutter channel = %q, want beta", flutter[0].Channel)
+	}
+
+	// Constraints without a pre-release leave betas out
+	stable, _ := FilterVersions(flutter, ">=3.24")
+	if got := versionStrings(stable); got != "3.24.5" {
+		t.Errorf("filtered = %s", got)
+	}
+}
+
+func TestListVersions_PythonAndJava(t *testing.T) {
+	ts := indexServer(t, map[string]string{
+		"/python": `{"assets": [
+			{"name": "cpython-3.12.8+20241219-x86_64-unknown-linux-gnu-install_only.tar.gz"},
+			{"name": "cpython-3.13.1+20241219-x86_64-unknown-linux-gnu-install_only.tar.gz"},
+			{"name": "cpython-3.9.21+20241219-x86_64-unknown-linux-gnu-install_only.tar.gz"},
+			{"name": "SHA256SUMS"}
+		]}`,
+		"/info/available_releases":  `{"available_releases": [8, 11, 17, 21, 23], "available_lts_releases": [8, 11, 17, 21]}`,
+		"/assets/latest/8/hotspot":  `[]`,
+		"/assets/latest/11/hotspot": `[{"version": {"semver": "11.0.25+9"}}]`,
+		"/assets/latest/17/hotspot": `[{"version": {"semver": "17.0.13+11"}}]`,
+		"/assets/latest/21/hotspot": `[{"version": {"semver": "21.0.5+11"}}]`,
+		"/assets/latest/23/hotspot": `[{"version": {"semver": "23.0.1+11"}}]`,
+	})
+	oldPython, oldAdoptium := pythonReleaseURL, adoptiumAPI
+	pythonReleaseURL, adoptiumAPI = ts.URL+"/python", ts.URL
+	t.Cleanup(func() { pythonReleaseURL, adoptiumAPI = oldPython, oldAdoptium })
+
+	python, err := ListVersions(&PythonInstaller{})
+	if err != nil {
+		t.Fatalf("ListVersions(python) error: %s", err)
+	}
+	if got := versionStrings(python); got != "3.13.1 3.12.8 3.9.21" {
+		t.Errorf("python versions = %s", got)
+	}
+
+	java, err := ListVersions(&JavaInstaller{})
+	if err != nil {
+		t.Fatalf("ListVersions(java) error: %s", err)
+	}
+	if got := versionStrings(java); got != "23.0.1+11 21.0.5+11 17.0.13+11 11.0.25+9" {
+		t.Errorf("java versions = %s, want majors without a build skipped", got)
+	}
+	if java[0].LTS || !java[1].LTS {
+		t.Errorf("java LTS not marked: %+v", java[:2])
+	}
+}
+
+func TestListVersions_Fallback(t *testing.T) {
+	versions, err := ListVersions(&fakeInstaller{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versionStrings(versions); got != "1.0.0" {
+		t.Errorf("installers without an index should list only the latest version, got %s", got)
+	}
+}
+
+func TestFilterVersions_InvalidConstraint(t *testing.T) {
+	if _, err := FilterVersions([]AvailableVersion{{Version: "1.0.0"}}, ">=one"); err == nil || !strings.Contains(err.Error(), "invalid constraint") {
+		t.Errorf("expected an invalid constraint error, got %v", err)
+	}
+}
@@ -1,11 +1,14 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -36,6 +39,81 @@ func (l Level) String() string {
 	}
 }
 
+// ParseLevel returns the level named s, e.g. "debug" or "WARN".
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	}
+	return INFO, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
+// Format is how log entries are written.
+type Format int
+
+const (
+	FormatText Format = iota // "[15:04:05] INFO: msg" in the file, the message alone on stdout
+	FormatJSON               // one Record per line, in the file and on stdout
+)
+
+// ParseFormat returns the format named s: "text" or "json".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("unknown log format %q (want text or json)", s)
+}
+
+// Record is one log entry, as written by FormatJSON and sent to the web
+// UI. Msg is already masked.
+type Record struct {
+	TS     time.Time         `json:"ts"`
+	Level  string            `json:"level"` // DEBUG, INFO, WARN or ERROR
+	Msg    string            `json:"msg"`
+	Fields map[string]string `json:"fields,omitempty"` // e.g. stream=output for a command's output
+}
+
+// OutputRecord returns the record for a line of command output, which
+// Output has already masked.
+func OutputRecord(line string) Record {
+	return Record{TS: time.Now(), Level: INFO.String(), Msg: line, Fields: map[string]string{"stream": "output"}}
+}
+
+// defaultLevel, defaultFormat and defaultMaxSize are the stdout level, the
+// format and the file size limit of new loggers.
+var (
+	defaultLevel   = INFO
+	defaultFormat  = FormatText
+	defaultMaxSize = int64(DefaultMaxFileSize)
+)
+
+// SetDefaultLevel sets the stdout level of loggers created by New from now
+// on (--verbose, --quiet or TEMPLATR_LOG_LEVEL).
+func SetDefaultLevel(level Level) {
+	defaultLevel = level
+}
+
+// SetDefaultMaxFileSize sets the file size limit of loggers created by New
+// from now on (--log-max-size).
+func SetDefaultMaxFileSize(n int64) {
+	defaultMaxSize = n
+}
+
+// SetDefaultFormat sets the format of loggers created by New from now on
+// (--log-format).
+func SetDefaultFormat(format Format) {
+	defaultFormat = format
+}
+
 // Logger provides structured logging to both stdout and a log file.
 type Logger struct {
 	mu          sync.Mutex
@@ -43,20 +121,40 @@ type Logger struct {
 	file        *os.File
 	filePath    string
 	writers     []io.Writer
-	secrets     map[string]bool // secret values to mask in output
+	secrets     map[string]bool  // secret values to mask in output
+	patterns    []*regexp.Regexp // secret shapes to mask in output
 	initialized bool
+	format      Format
+	stdout      io.Writer // nil means os.Stdout
+	stderr      io.Writer // nil means os.Stderr
+	maxSize     int64     // bytes per file before rolling to the next part; 0 means no limit
+	written     int64     // bytes in the current file
+	runPath     string    // the run's first file, without .log
+	part        int       // number of the current file within the run; 0 for the first
+	subscribers []chan Record
 }
 
 const (
-	maxLogFiles = 10
+	maxLogFiles = 10 // runs kept, along with all their rolled parts
 	logDir      = ".templatr/logs"
+
+	// DefaultMaxFileSize is how large a log file grows before the run rolls
+	// over to setup-<timestamp>.1.log, .2.log and so on.
+	DefaultMaxFileSize = 10 << 20
+
+	// subscriberBuffer is how far a subscriber can fall behind before it
+	// misses records.
+	subscriberBuffer = 256
 )
 
 // New creates a new logger. Call Init() to set up the log file.
 func New() *Logger {
 	return &Logger{
-		level:   INFO,
-		secrets: make(map[string]bool),
+		level:    defaultLevel,
+		format:   defaultFormat,
+		maxSize:  defaultMaxSize,
+		secrets:  make(map[string]bool),
+		patterns: slices.Clone(defaultSecretPatterns),
 	}
 }
 
@@ -80,7 +178,10 @@ func (l *Logger) Init() error {
 
 	// Create new log file
 	timestamp := time.Now().Format("2006-01-02_150405")
-	l.filePath = filepath.Join(dir, fmt.Sprintf("setup-%s.log", timestamp))
+	l.runPath = filepath.Join(dir, "setup-"+timestamp)
+	l.part = 0
+	l.written = 0
+	l.filePath = partPath(l.runPath, 0)
 
 	f, err := os.Create(l.filePath)
 	if err != nil {
@@ -91,8 +192,10 @@ func (l *Logger) Init() error {
 	l.writers = []io.Writer{f}
 	l.initialized = true
 
-	// Write header
-	l.writeToFile("=== templatr-setup log started at %s ===\n", time.Now().Format(time.RFC3339))
+	// Write header; every JSON line is a record, which has its own time
+	if l.format == FormatText {
+		l.writeToFile("=== templatr-setup log started at %s ===\n", time.Now().Format(time.RFC3339))
+	}
 
 	return nil
 }
@@ -104,14 +207,33 @@ func (l *Logger) SetLevel(level Level) {
 	l.level = level
 }
 
-// AddSecret adds a value that should be masked in all log output.
-func (l *Logger) AddSecret(secret string) {
-	if secret == "" {
-		return
-	}
+// SetMaxFileSize sets how many bytes a log file may hold before the run
+// rolls over to its next numbered file; 0 turns rolling off.
+func (l *Logger) SetMaxFileSize(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxSize = n
+}
+
+// SetFormat sets how entries are written to the log file and stdout.
+func (l *Logger) SetFormat(format Format) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = format
+}
+
+// Format returns how entries are written.
+func (l *Logger) Format() Format {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.secrets[secret] = true
+	return l.format
+}
+
+// Enabled reports whether messages at level are shown on stdout.
+func (l *Logger) Enabled(level Level) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return level >= l.level
 }
 
 // Debug logs a debug message (file only, unless verbose).
@@ -134,7 +256,83 @@ func (l *Logger) Error(format string, args ...interface{}) {
 	l.log(ERROR, format, args...)
 }
 
-// FilePath returns the path to the current log file.
+// Printf writes plain progress text to stdout, as fmt.Printf would, with
+// secrets masked. It is shown at the INFO level, so --quiet silences it,
+// and is not recorded in the log file. With FormatJSON each line becomes
+// an INFO record, and progress redrawn after a \r is left out.
+func (l *Logger) Printf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if INFO < l.level {
+		return
+	}
+	if l.format == FormatText {
+		io.WriteString(l.console(INFO), l.maskSecrets(msg))
+		return
+	}
+	if strings.HasPrefix(msg, "\r") {
+		return
+	}
+	for _, line := range strings.Split(msg, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			writeRecord(l.console(INFO), Record{TS: time.Now(), Level: INFO.String(), Msg: l.maskSecrets(line)})
+		}
+	}
+}
+
+// Record returns the entry an INFO, WARN, ... call would write, with
+// secrets masked, without writing it.
+func (l *Logger) Record(level Level, format string, args ...interface{}) Record {
+	msg := fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Record{TS: time.Now(), Level: level.String(), Msg: l.maskSecrets(msg)}
+}
+
+// Output returns a writer for a command's output. Each complete line is
+// masked, recorded in the log file and written to w, if not nil, in a
+// single Write. With FormatJSON each line is also a record on stdout.
+// Close passes on a final line that has no newline.
+func (l *Logger) Output(w io.Writer) io.WriteCloser {
+	return &outputWriter{log: l, out: w}
+}
+
+// Subscribe returns a channel that receives the entries shown on stdout
+// (those at the stdout level or above) and each line of command output, as
+// records, until unsubscribe is called. A subscriber that falls
+// subscriberBuffer records behind misses the newer ones rather than
+// holding up the setup.
+func (l *Logger) Subscribe() (records <-chan Record, unsubscribe func()) {
+	ch := make(chan Record, subscriberBuffer)
+	l.mu.Lock()
+	l.subscribers = append(l.subscribers, ch)
+	l.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.subscribers = slices.DeleteFunc(l.subscribers, func(c chan Record) bool { return c == ch })
+			close(ch)
+		})
+	}
+}
+
+// publish sends rec to the subscribers. l.mu must be held.
+func (l *Logger) publish(rec Record) {
+	for _, ch := range l.subscribers {
+		select {
+		case ch <- rec:
+		default:
+		}
+	}
+}
+
+// FilePath returns the path to the current log file: the run's latest
+// part once it has rolled over.
 func (l *Logger) FilePath() string {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -146,7 +344,9 @@ func (l *Logger) Close() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	if l.file != nil {
-		l.writeToFile("=== templatr-setup log ended at %s ===\n", time.Now().Format(time.RFC3339))
+		if l.format == FormatText {
+			l.writeToFile("=== templatr-setup log ended at %s ===\n", time.Now().Format(time.RFC3339))
+		}
 		l.file.Close()
 		l.file = nil
 	}
@@ -174,15 +374,10 @@ func RecentLogFilesInDir(dir string, max int) ([]string, error) {
 	}
 
 	var files []string
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasPrefix(e.Name(), "setup-") && strings.HasSuffix(e.Name(), ".log") {
-			files = append(files, filepath.Join(dir, e.Name()))
-		}
+	for _, f := range logFiles(dir, entries) {
+		files = append(files, f.path)
 	}
 
-	// Sort descending by name (timestamp-based names sort chronologically)
-	sort.Sort(sort.Reverse(sort.StringSlice(files)))
-
 	if max > 0 && len(files) > max {
 		files = files[:max]
 	}
@@ -191,44 +386,139 @@ func RecentLogFilesInDir(dir string, max int) ([]string, error) {
 }
 
 func (l *Logger) log(level Level, format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	msg = l.maskSecrets(msg)
-
-	timestamp := time.Now().Format("15:04:05")
-	line := fmt.Sprintf("[%s] %s: %s", timestamp, level, msg)
+	rec := l.Record(level, format, args...)
+	msg := rec.Msg
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	// Always write to log file (all levels)
 	if l.initialized {
-		l.writeToFile("%s\n", line)
+		if l.format == FormatJSON {
+			l.recordToFile(rec)
+		} else {
+			l.writeToFile("[%s] %s: %s\n", rec.TS.Format("15:04:05"), level, msg)
+		}
 	}
 
 	// Write to stdout only if level >= configured level
 	if level >= l.level {
+		l.publish(rec)
+		if l.format == FormatJSON {
+			// One stream of records, so errors go to stdout too
+			writeRecord(l.console(INFO), rec)
+			return
+		}
+		w := l.console(level)
 		switch level {
 		case ERROR:
-			fmt.Fprintf(os.Stderr, "  ERROR: %s\n", msg)
+			fmt.Fprintf(w, "  ERROR: %s\n", msg)
 		case WARN:
-			fmt.Printf("  WARN: %s\n", msg)
+			fmt.Fprintf(w, "  WARN: %s\n", msg)
 		default:
-			fmt.Printf("  %s\n", msg)
+			fmt.Fprintf(w, "  %s\n", msg)
 		}
 	}
 }
 
-func (l *Logger) writeToFile(format string, args ...interface{}) {
-	if l.file != nil {
-		fmt.Fprintf(l.file, format, args...)
+// console returns where messages at level are shown: stderr for errors,
+// stdout for the rest.
+func (l *Logger) console(level Level) io.Writer {
+	if level == ERROR {
+		if l.stderr != nil {
+			return l.stderr
+		}
+		return os.Stderr
 	}
+	if l.stdout != nil {
+		return l.stdout
+	}
+	return os.Stdout
 }
 
-func (l *Logger) maskSecrets(msg string) string {
-	for secret := range l.secrets {
-		msg = strings.ReplaceAll(msg, secret, "****")
+// outputWriter splits command output into lines for Output.
+type outputWriter struct {
+	log     *Logger
+	out     io.Writer
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.partial = append(o.partial, p...)
+	for {
+		i := bytes.IndexByte(o.partial, '\n')
+		if i < 0 {
+			break
+		}
+		o.line(string(o.partial[:i]))
+		o.partial = o.partial[i+1:]
 	}
-	return msg
+	return len(p), nil
+}
+
+func (o *outputWriter) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.partial) > 0 {
+		o.line(string(o.partial))
+		o.partial = nil
+	}
+	return nil
+}
+
+// line records one line of output. Progress bars redraw their line after a
+// \r, so only the text after the last one is kept.
+func (o *outputWriter) line(s string) {
+	s = strings.TrimRight(s, "\r")
+	if i := strings.LastIndexByte(s, '\r'); i >= 0 {
+		s = s[i+1:]
+	}
+
+	l := o.log
+	l.mu.Lock()
+	s = l.maskSecrets(s)
+	rec := OutputRecord(s)
+	if l.format == FormatJSON {
+		if l.initialized {
+			l.recordToFile(rec)
+		}
+		if INFO >= l.level {
+			writeRecord(l.console(INFO), rec)
+		}
+	} else if l.initialized {
+		l.writeToFile("[%s] OUTPUT: %s\n", rec.TS.Format("15:04:05"), s)
+	}
+	if INFO >= l.level {
+		l.publish(rec)
+	}
+	l.mu.Unlock()
+
+	// A failing UI mustn't fail the command
+	if o.out != nil {
+		io.WriteString(o.out, s+"\n")
+	}
+}
+
+// writeRecord writes rec to w as one line of JSON.
+func writeRecord(w io.Writer, rec Record) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	w.Write(append(data, '\n'))
+}
+
+func (l *Logger) recordToFile(rec Record) {
+	if data, err := json.Marshal(rec); err == nil {
+		l.writeFile(append(data, '\n'))
+	}
+}
+
+func (l *Logger) writeToFile(format string, args ...interface{}) {
+	l.writeFile([]byte(fmt.Sprintf(format, args...)))
 }
 
 func (l *Logger) rotateFiles(dir string) {
@@ -237,20 +527,15 @@ func (l *Logger) rotateFiles(dir string) {
 		return
 	}
 
-	var logFiles []string
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasPrefix(e.Name(), "setup-") && strings.HasSuffix(e.Name(), ".log") {
-			logFiles = append(logFiles, filepath.Join(dir, e.Name()))
+	// Keep only the most recent runs, removing every part of the oldest
+	// to make room for the new one
+	var runs []string
+	for _, f := range logFiles(dir, entries) {
+		if !slices.Contains(runs, f.run) {
+			runs = append(runs, f.run)
 		}
-	}
-
-	// Keep only the most recent files
-	if len(logFiles) >= maxLogFiles {
-		sort.Strings(logFiles)
-		// Remove oldest files to make room
-		toRemove := len(logFiles) - maxLogFiles + 1
-		for i := 0; i < toRemove; i++ {
-			os.Remove(logFiles[i])
+		if len(runs) >= maxLogFiles {
+			os.Remove(f.path)
 		}
 	}
 }
@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -128,3 +131,245 @@ func TestLevel_String(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]Level{"debug": DEBUG, "INFO": INFO, "warn": WARN, "Warning": WARN, " error ": ERROR} {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %s, %v, want %s", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) should fail")
+	}
+}
+
+func TestLogger_LevelFiltering(t *testing.T) {
+	tests := []struct {
+		level      Level
+		wantStdout []string
+		wantStderr []string
+	}{
+		{DEBUG, []string{"  debug line", "  info line", "  WARN: warn line", "plain line"}, []string{"  ERROR: error line"}},
+		{INFO, []string{"  info line", "  WARN: warn line", "plain line"}, []string{"  ERROR: error line"}},
+		{WARN, []string{"  WARN: warn line"}, []string{"  ERROR: error line"}},
+		{ERROR, nil, []string{"  ERROR: error line"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			l := New()
+			l.stdout, l.stderr = &stdout, &stderr
+			l.SetLevel(tt.level)
+
+			l.Debug("debug line")
+			l.Info("info line")
+			l.Warn("warn line")
+			l.Error("error line")
+			l.Printf("plain line\n")
+
+			if got := lines(stdout.String()); !slices.Equal(got, tt.wantStdout) {
+				t.Errorf("stdout = %q, want %q", got, tt.wantStdout)
+			}
+			if got := lines(stderr.String()); !slices.Equal(got, tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", got, tt.wantStderr)
+			}
+			if got := l.Enabled(INFO); got != (tt.level <= INFO) {
+				t.Errorf("Enabled(INFO) = %v at %s", got, tt.level)
+			}
+		})
+	}
+}
+
+func TestLogger_QuietStillRecordsFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	var stdout strings.Builder
+	l := New()
+	l.stdout = &stdout
+	l.SetLevel(ERROR)
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("resolved node 22.14.0")
+	l.Info("Installing Node.js")
+	l.Close()
+
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing below ERROR", stdout.String())
+	}
+	data, _ := os.ReadFile(l.FilePath())
+	for _, want := range []string{"DEBUG: resolved node 22.14.0", "INFO: Installing Node.js"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log file should record %q whatever the level:\n%s", want, data)
+		}
+	}
+}
+
+func TestSetDefaultLevel(t *testing.T) {
+	t.Cleanup(func() { SetDefaultLevel(INFO) })
+	SetDefaultLevel(DEBUG)
+	if !New().Enabled(DEBUG) {
+		t.Error("New() should use the default level")
+	}
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func TestLogger_Output(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	l := New()
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	l.AddSecret("sk_live_123")
+
+	var out strings.Builder
+	w := l.Output(&out)
+	// Lines split across writes, a progress bar redrawn with \r, CRLF, and
+	// a final line with no newline
+	w.Write([]byte("added 12 pack"))
+	w.Write([]byte("ages\nusing key sk_live_123\r\n"))
+	w.Write([]byte("[#   ] 10%\r[####] 100%\nnot finished"))
+	w.Close()
+	l.Close()
+
+	want := "added 12 packages\nusing key ****\n[####] 100%\nnot finished\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	data, _ := os.ReadFile(l.FilePath())
+	if strings.Contains(string(data), "sk_live_123") || !strings.Contains(string(data), "OUTPUT: using key ****") {
+		t.Errorf("log file should record the masked output:\n%s", data)
+	}
+}
+
+func TestLogger_Subscribe(t *testing.T) {
+	var stdout strings.Builder
+	l := New()
+	l.stdout, l.stderr = &stdout, &stdout
+	l.SetLevel(INFO)
+	l.AddSecret("sk_live_123")
+	records, unsubscribe := l.Subscribe()
+
+	l.Debug("below the level")
+	l.Info("Installing Node.js")
+	w := l.Output(io.Discard)
+	w.Write([]byte("using key sk_live_123\n"))
+	l.Error("failed")
+
+	want := []Record{
+		{Level: "INFO", Msg: "Installing Node.js"},
+		{Level: "INFO", Msg: "using key ****", Fields: map[string]string{"stream": "output"}},
+		{Level: "ERROR", Msg: "failed"},
+	}
+	for i, w := range want {
+		got := <-records
+		if got.Level != w.Level || got.Msg != w.Msg || got.Fields["stream"] != w.Fields["stream"] {
+			t.Errorf("record %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	unsubscribe()
+	unsubscribe()
+	l.Info("after unsubscribing")
+	if rec, ok := <-records; ok {
+		t.Errorf("got %+v after unsubscribing, want the channel closed", rec)
+	}
+
+	// A subscriber that doesn't keep up misses entries instead of blocking
+	_, unsubscribe = l.Subscribe()
+	defer unsubscribe()
+	for range subscriberBuffer + 1 {
+		l.Info("line")
+	}
+}
+
+func TestLogger_JSONFormat(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	var stdout strings.Builder
+	l := New()
+	l.stdout = &stdout
+	l.SetFormat(FormatJSON)
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	l.AddSecret("sk_live_123")
+
+	l.Debug("resolving node")
+	l.Info("Using key sk_live_123")
+	l.Error(`failed: "quoted"`)
+	w := l.Output(nil)
+	w.Write([]byte("token sk_live_123\n"))
+	w.Close()
+	l.Printf("\nInstallation complete!\n")
+	l.Printf("\r  Downloading... 10%%")
+	l.Close()
+
+	data, err := os.ReadFile(l.FilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := decodeRecords(t, string(data))
+	want := []Record{
+		{Level: "DEBUG", Msg: "resolving node"},
+		{Level: "INFO", Msg: "Using key ****"},
+		{Level: "ERROR", Msg: `failed: "quoted"`},
+		{Level: "INFO", Msg: "token ****", Fields: map[string]string{"stream": "output"}},
+	}
+	checkRecords(t, "log file", file, want)
+
+	// stdout skips DEBUG below the INFO level, and gets the Printf text
+	// but not its progress redraws
+	checkRecords(t, "stdout", decodeRecords(t, stdout.String()), append(want[1:], Record{Level: "INFO", Msg: "Installation complete!"}))
+	if strings.Contains(string(data)+stdout.String(), "sk_live_123") {
+		t.Error("a secret was serialized unmasked")
+	}
+}
+
+func decodeRecords(t *testing.T, s string) []Record {
+	t.Helper()
+	var recs []Record
+	for _, line := range lines(s) {
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("line %q is not a JSON record: %s", line, err)
+		}
+		if rec.TS.IsZero() {
+			t.Errorf("record %q has no time", line)
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func checkRecords(t *testing.T, where string, got, want []Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s has %d records, want %d: %+v", where, len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Level != want[i].Level || got[i].Msg != want[i].Msg || got[i].Fields["stream"] != want[i].Fields["stream"] {
+			t.Errorf("%s record %d = %+v, want %+v", where, i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("JSON"); err != nil || f != FormatJSON {
+		t.Errorf("ParseFormat(JSON) = %d, %v", f, err)
+	}
+	if f, err := ParseFormat("text"); err != nil || f != FormatText {
+		t.Errorf("ParseFormat(text) = %d, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) should fail")
+	}
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// logFile is a log file of one run: setup-<timestamp>.log, or a part it
+// rolled over to, setup-<timestamp>.<n>.log.
+type logFile struct {
+	path string
+	run  string // setup-<timestamp>
+	part int    // 0 for the run's first file
+}
+
+// logFiles returns the log files among entries of dir, newest first: by
+// run, then by part within a run.
+func logFiles(dir string, entries []os.DirEntry) []logFile {
+	var files []logFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if run, part, ok := parseLogName(e.Name()); ok {
+			files = append(files, logFile{path: filepath.Join(dir, e.Name()), run: run, part: part})
+		}
+	}
+	slices.SortFunc(files, func(a, b logFile) int {
+		// Timestamp-based run names sort chronologically
+		return cmp.Or(strings.Compare(b.run, a.run), cmp.Compare(b.part, a.part))
+	})
+	return files
+}
+
+// parseLogName splits a log file name into its run and part number.
+func parseLogName(name string) (run string, part int, ok bool) {
+	base, ok := strings.CutSuffix(name, ".log")
+	if !ok || !strings.HasPrefix(base, "setup-") {
+		return "", 0, false
+	}
+	i := strings.LastIndexByte(base, '.')
+	if i < 0 {
+		return base, 0, true
+	}
+	part, err := strconv.Atoi(base[i+1:])
+	if err != nil || part < 1 {
+		return "", 0, false
+	}
+	return base[:i], part, true
+}
+
+// partPath returns the path of part n of the run whose first file is
+// runPath plus ".log".
+func partPath(runPath string, n int) string {
+	if n == 0 {
+		return runPath + ".log"
+	}
+	return fmt.Sprintf("%s.%d.log", runPath, n)
+}
+
+// writeFile appends p to the log file, first rolling over to the run's
+// next part if p would take the current one past maxSize.
+func (l *Logger) writeFile(p []byte) {
+	if l.file == nil {
+		return
+	}
+	if l.maxSize > 0 && l.written > 0 && l.written+int64(len(p)) > l.maxSize {
+		l.roll()
+	}
+	n, _ := l.file.Write(p)
+	l.written += int64(n)
+}
+
+// roll switches to the run's next part. If it can't be created, logging
+// carries on in the current one.
+func (l *Logger) roll() {
+	next := partPath(l.runPath, l.part+1)
+	f, err := os.Create(next)
+	if err != nil {
+		return
+	}
+	var written int
+	if l.format == FormatText {
+		written, _ = fmt.Fprintf(f, "=== templatr-setup log continued from %s ===\n", filepath.Base(l.filePath))
+	}
+
+	l.file.Close()
+	l.file = f
+	l.writers = []io.Writer{f}
+	l.filePath = next
+	l.part++
+	l.written = int64(written)
+}
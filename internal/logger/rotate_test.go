@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLogger_RollsOverAtMaxSize(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	l := New()
+	l.stdout = &strings.Builder{}
+	l.SetMaxFileSize(300)
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	first := l.FilePath()
+
+	w := l.Output(nil)
+	for i := range 20 {
+		w.Write([]byte(strings.Repeat("x", 40) + string(rune('a'+i)) + "\n"))
+	}
+	w.Close()
+	l.Info("done")
+	l.Close()
+
+	dir := filepath.Dir(first)
+	run := strings.TrimSuffix(first, ".log")
+	if l.FilePath() == first || !strings.HasPrefix(l.FilePath(), run+".") {
+		t.Fatalf("FilePath() = %s, want a numbered part of %s", l.FilePath(), first)
+	}
+	files, err := RecentLogFilesInDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 3 || files[0] != l.FilePath() || files[len(files)-1] != first {
+		t.Fatalf("files = %v, want the parts newest first, ending with %s", files, first)
+	}
+
+	var all strings.Builder
+	for i, f := range slices.Backward(files) {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 300 {
+			t.Errorf("%s is %d bytes, want at most the 300 byte limit", f, len(data))
+		}
+		if i < len(files)-1 && !strings.HasPrefix(string(data), "=== templatr-setup log continued from ") {
+			t.Errorf("%s should say which file it continues", f)
+		}
+		all.Write(data)
+	}
+	for i := range 20 {
+		if line := strings.Repeat("x", 40) + string(rune('a'+i)); !strings.Contains(all.String(), line) {
+			t.Errorf("line %q is missing from the parts", line)
+		}
+	}
+	if !strings.Contains(all.String(), "INFO: done") {
+		t.Error("entries after a roll should go to the new part")
+	}
+}
+
+func TestLogger_NoLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	l := New()
+	l.stdout = &strings.Builder{}
+	l.SetMaxFileSize(0)
+	if err := l.Init(); err != nil {
+		t.Fatal(err)
+	}
+	first := l.FilePath()
+	for range 100 {
+		l.Info("%s", strings.Repeat("y", 100))
+	}
+	l.Close()
+	if l.FilePath() != first {
+		t.Errorf("FilePath() = %s, want no roll without a limit", l.FilePath())
+	}
+}
+
+func TestRecentLogFilesInDir_Parts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"setup-2026-02-18_100000.log",
+		"setup-2026-02-18_100000.1.log",
+		"setup-2026-02-18_100000.2.log",
+		"setup-2026-02-18_100000.10.log",
+		"setup-2026-02-19_090000.log",
+		"setup-2026-02-17_080000.1.log",
+		"setup-2026-02-18_100000.old.log",
+		"notes.log",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("test"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := RecentLogFilesInDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, filepath.Base(f))
+	}
+	want := []string{
+		"setup-2026-02-19_090000.log",
+		"setup-2026-02-18_100000.10.log",
+		"setup-2026-02-18_100000.2.log",
+		"setup-2026-02-18_100000.1.log",
+		"setup-2026-02-18_100000.log",
+		"setup-2026-02-17_080000.1.log",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("RecentLogFilesInDir() = %v, want %v", got, want)
+	}
+}
+
+func TestRotateFiles_KeepsWholeRuns(t *testing.T) {
+	dir := t.TempDir()
+	var runs []string
+	for i := range maxLogFiles + 2 {
+		run := "setup-2026-02-" + string(rune('1'+i/10)) + string(rune('0'+i%10)) + "_100000"
+		runs = append(runs, run)
+		for _, name := range []string{run + ".log", run + ".1.log", run + ".2.log"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("test"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	New().rotateFiles(dir)
+
+	files, err := RecentLogFilesInDir(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3*(maxLogFiles-1) {
+		t.Errorf("%d files left, want the parts of %d runs", len(files), maxLogFiles-1)
+	}
+	for i, run := range runs {
+		kept := i >= len(runs)-(maxLogFiles-1)
+		for _, part := range []string{".log", ".1.log", ".2.log"} {
+			_, err := os.Stat(filepath.Join(dir, run+part))
+			if kept != (err == nil) {
+				t.Errorf("%s%s kept = %v, want %v", run, part, err == nil, kept)
+			}
+		}
+	}
+}
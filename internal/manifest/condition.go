@@ -0,0 +1,76 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Condition is a parsed `when` expression: the field only applies while
+// the value of Key, an env var key or config field path, equals (or with
+// Op "!=", differs from) Value.
+type Condition struct {
+	Key   string
+	Op    string // "==" or "!="
+	Value string
+}
+
+var (
+	conditionKey     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	conditionLiteral = regexp.MustCompile(`^[^\s'"]+$`)
+)
+
+// ParseCondition parses a `when` expression of the form KEY == 'value' or
+// KEY != 'value'. The value may be single- or double-quoted, or a bare word
+// such as true or 3000.
+func ParseCondition(expr string) (Condition, error) {
+	var c Condition
+	i := strings.Index(expr, "==")
+	if j := strings.Index(expr, "!="); j >= 0 && (i < 0 || j < i) {
+		i = j
+	}
+	if i < 0 {
+		return c, fmt.Errorf("when %q must compare a field, as in KEY == 'value' or KEY != 'value'", expr)
+	}
+	c.Key = strings.TrimSpace(expr[:i])
+	c.Op = expr[i : i+2]
+	literal := strings.TrimSpace(expr[i+2:])
+
+	if !conditionKey.MatchString(c.Key) {
+		return c, fmt.Errorf("when %q must start with an env key or config path, not %q", expr, c.Key)
+	}
+	switch {
+	case len(literal) >= 2 && (literal[0] == '\'' || literal[0] == '"'):
+		quote := literal[0]
+		if literal[len(literal)-1] != quote || strings.IndexByte(literal[1:len(literal)-1], quote) >= 0 {
+			return c, fmt.Errorf("when %q has an unterminated string", expr)
+		}
+		c.Value = literal[1 : len(literal)-1]
+	case conditionLiteral.MatchString(literal):
+		c.Value = literal
+	default:
+		return c, fmt.Errorf("when %q must compare against a value, such as 'stripe' or true", expr)
+	}
+	return c, nil
+}
+
+// Holds reports whether the condition is met by values, keyed by env key
+// or config path. A key without a value compares as empty.
+func (c Condition) Holds(values map[string]string) bool {
+	equal := strings.TrimSpace(values[c.Key]) == c.Value
+	if c.Op == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+// Shown reports whether a field with the `when` expression applies given
+// values. Fields without one always do, as do fields whose expression
+// doesn't parse, since Validate reports those.
+func Shown(when string, values map[string]string) bool {
+	if when == "" {
+		return true
+	}
+	c, err := ParseCondition(when)
+	return err != nil || c.Holds(values)
+}
@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    Condition
+		wantErr string
+	}{
+		{expr: "PAYMENT_PROVIDER == 'stripe'", want: Condition{"PAYMENT_PROVIDER", "==", "stripe"}},
+		{expr: `PAYMENT_PROVIDER != "stripe"`, want: Condition{"PAYMENT_PROVIDER", "!=", "stripe"}},
+		{expr: "ANALYTICS==true", want: Condition{"ANALYTICS", "==", "true"}},
+		{expr: "  siteConfig.payments.provider  ==  'lemon squeezy'  ", want: Condition{"siteConfig.payments.provider", "==", "lemon squeezy"}},
+		{expr: "MODE == ''", want: Condition{"MODE", "==", ""}},
+		{expr: `NAME == "it's"`, want: Condition{"NAME", "==", "it's"}},
+		{expr: "PAYMENT_PROVIDER", wantErr: "must compare a field"},
+		{expr: "PAYMENT_PROVIDER = 'stripe'", wantErr: "must compare a field"},
+		{expr: "== 'stripe'", wantErr: "must start with an env key"},
+		{expr: "1KEY == 'x'", wantErr: "must start with an env key"},
+		{expr: "KEY == 'stripe", wantErr: "unterminated string"},
+		{expr: "KEY == 'a'b'", wantErr: "unterminated string"},
+		{expr: "KEY ==", wantErr: "must compare against a value"},
+		{expr: "KEY == two words", wantErr: "must compare against a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseCondition(tt.expr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseCondition() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCondition() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCondition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShown(t *testing.T) {
+	values := map[string]string{"PAYMENT_PROVIDER": "stripe", "PORT": " 3000 "}
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{"", true},
+		{"PAYMENT_PROVIDER == 'stripe'", true},
+		{"PAYMENT_PROVIDER != 'stripe'", false},
+		{"PAYMENT_PROVIDER == 'lemonsqueezy'", false},
+		{"PORT == 3000", true},
+		{"MISSING == ''", true},
+		{"MISSING != ''", false},
+		{"not an expression", true},
+	}
+	for _, tt := range tests {
+		if got := Shown(tt.when, values); got != tt.want {
+			t.Errorf("Shown(%q) = %v, want %v", tt.when, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// runtimeBinaries are the commands each runtime's install puts on PATH.
+var runtimeBinaries = map[string][]string{
+	"node":    {"node", "npm", "npx", "corepack"},
+	"python":  {"python", "python3", "pip", "pip3"},
+	"flutter": {"flutter", "dart"},
+	"java":    {"java", "javac", "jar"},
+	"go":      {"go", "gofmt"},
+	"rust":    {"rustc", "cargo", "rustup"},
+	"ruby":    {"ruby", "gem", "bundle", "bundler"},
+	"php":     {"php"},
+	"dotnet":  {"dotnet"},
+	"bun":     {"bun", "bunx"},
+	"deno":    {"deno"},
+}
+
+// shellBuiltins are commands the shell runs itself, in sh or cmd.exe.
+var shellBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "cd": true, "command": true, "echo": true,
+	"eval": true, "exec": true, "exit": true, "export": true, "false": true,
+	"printf": true, "pwd": true, "set": true, "source": true, "test": true,
+	"true": true, "type": true, "unset": true,
+	"call": true, "copy": true, "del": true, "dir": true, "md": true,
+	"mkdir": true, "move": true, "rd": true, "ren": true, "start": true,
+}
+
+// DeepValidate runs the checks Validate leaves out because they need the
+// template's files: that they are in dir, where the manifest was loaded
+// from. Config files must exist, env files must be writable inside dir,
+// runtime requirements must be version constraints, post-setup commands
+// must run programs setup installs or that are on PATH, and no env key may
+// be set twice in the same file. Like Validate, it reports every problem.
+func (m *Manifest) DeepValidate(dir string) []ValidationError {
+	var v validator
+
+	// Runtime requirements, with "auto" and "file:" read from dir
+	v.deepRuntimes("runtimes", m.Runtimes, dir)
+	for goos, table := range m.RuntimesOS {
+		v.deepRuntimes("runtimes."+goos, table, dir)
+	}
+
+	// Config files are edited in place, so they must already be there
+	for i, cfg := range m.Config {
+		if cfg.File == "" {
+			continue
+		}
+		section := fmt.Sprintf("config.%d", i)
+		if !insideDir(cfg.File) {
+			v.add(section, "file", "%s is outside the template directory", cfg.File)
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, cfg.File))
+		switch {
+		case os.IsNotExist(err):
+			v.add(section, "file", "%s does not exist", cfg.File)
+		case err != nil:
+			v.add(section, "file", "%s: %s", cfg.File, err)
+		case info.IsDir():
+			v.add(section, "file", "%s is a directory", cfg.File)
+		}
+	}
+
+	// Env files
+	checked := make(map[string]bool)
+	for i, env := range m.Env {
+		section := fmt.Sprintf("env.%d", i)
+		for _, file := range m.envFiles(env) {
+			if checked[file] {
+				continue
+			}
+			checked[file] = true
+			if err := checkEnvFile(dir, file); err != nil {
+				v.add(section, "file", "%s", err)
+			}
+		}
+	}
+	v.duplicateKeys(m)
+
+	// Working directories
+	for i, entry := range m.Packages.Install {
+		v.deepDir(fmt.Sprintf("packages.install.%d", i), dir, entry.Dir)
+	}
+	for i, cmd := range m.PostSetup.Commands {
+		section := fmt.Sprintf("post_setup.commands.%d", i)
+		if v.deepDir(section, dir, cmd.Dir) {
+			v.deepCommand(section, m, dir, cmd)
+		}
+	}
+
+	return v.errs
+}
+
+// deepRuntimes checks that each requirement in table, or what it reads
+// from the project in dir, is "latest" or a version constraint.
+func (v *validator) deepRuntimes(section string, table map[string]string, dir string) {
+	for name, required := range table {
+		if !validRuntimes[strings.ToLower(name)] {
+			continue // Validate reports it
+		}
+		constraint, _, err := ResolveRequirement(name, required, dir)
+		if err != nil || constraint == "latest" {
+			continue // Validate warns that setup falls back to "latest"
+		}
+		if _, err := semver.NewConstraint(constraint); err != nil {
+			v.add(section, name, "%q is not a version constraint such as \">=20.0.0\", \"^3.12\" or \"latest\"", constraint)
+		}
+	}
+}
+
+// envFiles returns the files env is written to, one per environment it is
+// in when [env_environments] is declared.
+func (m *Manifest) envFiles(env EnvVar) []string {
+	target := envTarget(env)
+	if len(m.EnvEnvironments.Names) == 0 {
+		return []string{target}
+	}
+	names := env.Environments
+	if len(names) == 0 {
+		names = m.EnvEnvironments.Names
+	}
+	var files []string
+	for _, name := range names {
+		files = append(files, m.environmentFile(target, name))
+	}
+	return files
+}
+
+// environmentFile mirrors config.EnvironmentFile without importing the
+// config package.
+func (m *Manifest) environmentFile(target, name string) string {
+	pattern := m.EnvEnvironments.FilePattern
+	if pattern == "" {
+		pattern = "{file}.{env}"
+	}
+	return strings.NewReplacer("{file}", target, "{env}", name).Replace(pattern)
+}
+
+// checkEnvFile verifies that configure will be able to write file, relative
+// to dir: it stays inside dir, isn't a directory, and it or the directory
+// it goes in is writable.
+func checkEnvFile(dir, file string) error {
+	if !insideDir(file) {
+		return fmt.Errorf("%s is outside the template directory", file)
+	}
+	path := filepath.Join(dir, file)
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", file)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", file, err)
+		}
+		return f.Close()
+	}
+
+	// Configure creates the file and any directories it goes in, so the
+	// nearest one that exists must accept new files
+	parent := filepath.Dir(path)
+	for {
+		info, err := os.Stat(parent)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s can't be created: %s is not a directory", file, parent)
+			}
+			break
+		}
+		if filepath.Dir(parent) == parent {
+			return fmt.Errorf("%s can't be created: %w", file, err)
+		}
+		parent = filepath.Dir(parent)
+	}
+	probe, err := os.CreateTemp(parent, ".templatr-validate-*")
+	if err != nil {
+		return fmt.Errorf("%s can't be created: %w", file, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// duplicateKeys reports env keys set twice in the same file. Two entries
+// for different environments of one file don't clash.
+func (v *validator) duplicateKeys(m *Manifest) {
+	type slot struct{ file, key string }
+	first := make(map[slot]int) // slot -> index of the env entry that set it
+	for i, env := range m.Env {
+		if env.Key == "" {
+			continue
+		}
+		for _, file := range m.envFiles(env) {
+			s := slot{file, env.Key}
+			if j, ok := first[s]; ok {
+				v.add(fmt.Sprintf("env.%d", i), "key", "duplicate key %q in %s - also set by env.%d", env.Key, file, j)
+				break
+			}
+			first[s] = i
+		}
+	}
+}
+
+// deepDir checks that a command's working directory exists, reporting
+// whether it does (an empty dir is the template directory).
+func (v *validator) deepDir(section, dir, sub string) bool {
+	if sub == "" || isAbsDir(sub) {
+		return true // Validate reports absolute dirs
+	}
+	info, err := os.Stat(filepath.Join(dir, sub))
+	if err != nil || !info.IsDir() {
+		v.add(section, "dir", "directory %s does not exist", sub)
+		return false
+	}
+	return true
+}
+
+// deepCommand checks that the program a post-setup command runs will be
+// there after setup: a shell builtin, a script in the template, a binary of
+// a runtime or package manager the manifest declares, or failing those
+// something already on PATH, which customers may not have.
+func (v *validator) deepCommand(section string, m *Manifest, dir string, cmd Command) {
+	program := commandProgram(cmd.Run)
+	if program == "" || shellBuiltins[program] {
+		return
+	}
+
+	if strings.ContainsAny(program, `/\`) {
+		if _, err := os.Stat(filepath.Join(dir, cmd.Dir, program)); err != nil {
+			v.add(section, "run", "%s does not exist", program)
+		}
+		return
+	}
+	if slices.Contains(m.providedBinaries(), program) {
+		return
+	}
+
+	// A command for another OS can't be looked up here
+	if len(cmd.OS) > 0 && !slices.Contains(cmd.OS, runtime.GOOS) {
+		return
+	}
+	if _, err := exec.LookPath(program); err == nil {
+		v.warn(section, "run", "%s is not installed by setup - it must already be on the user's PATH", program)
+		return
+	}
+	v.add(section, "run", "%s is not installed by any runtime or package manager in the manifest, and is not on PATH", program)
+}
+
+// providedBinaries returns the programs on PATH once setup has installed
+// the manifest's runtimes and package managers.
+func (m *Manifest) providedBinaries() []string {
+	var bins []string
+	for name := range m.Runtimes {
+		bins = append(bins, runtimeBinaries[strings.ToLower(name)]...)
+	}
+	for _, table := range m.RuntimesOS {
+		for name := range table {
+			bins = append(bins, runtimeBinaries[strings.ToLower(name)]...)
+		}
+	}
+	if m.Packages.Manager != "" {
+		bins = append(bins, m.Packages.Manager)
+	}
+	for _, entry := range m.Packages.Install {
+		if entry.Manager != "" {
+			bins = append(bins, entry.Manager)
+		}
+	}
+	return bins
+}
+
+// commandProgram returns the program a shell command line runs: its first
+// word after any VAR=value assignments, unquoted and without a Windows
+// executable extension.
+func commandProgram(run string) string {
+	for _, word := range strings.Fields(run) {
+		if strings.Contains(word, "=") && !strings.ContainsAny(word, `/\`) {
+			continue
+		}
+		word = strings.Trim(word, `"'`)
+		for _, ext := range []string{".exe", ".cmd", ".bat"} {
+			if trimmed, ok := strings.CutSuffix(strings.ToLower(word), ext); ok {
+				word = word[:len(trimmed)]
+			}
+		}
+		return word
+	}
+	return ""
+}
+
+// insideDir reports whether a relative path stays inside the directory it
+// is relative to.
+func insideDir(path string) bool {
+	if filepath.IsAbs(path) || isAbsDir(path) {
+		return false
+	}
+	clean := filepath.Clean(path)
+	return clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
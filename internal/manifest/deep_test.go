@@ -0,0 +1,296 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// deepDir creates a template directory holding files, which map a path
+// relative to it to their content.
+func deepDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// deepPaths returns the messages of errs by path, with severity prefixed
+// to warnings.
+func deepPaths(errs []ValidationError) map[string]string {
+	paths := make(map[string]string)
+	for _, e := range errs {
+		msg := e.Message
+		if e.Severity == SeverityWarning {
+			msg = "warning: " + msg
+		}
+		paths[e.Path] = msg
+	}
+	return paths
+}
+
+func TestDeepValidate_Valid(t *testing.T) {
+	dir := deepDir(t, map[string]string{
+		"src/site.ts":     "export const siteConfig = {}\n",
+		".nvmrc":          "v22.14.0\n",
+		"scripts/seed.sh": "#!/bin/sh\n",
+		"ios/Podfile":     "",
+	})
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Shop", Version: "1.0.0"},
+		Runtimes: map[string]string{"node": "auto", "python": "^3.12", "go": "latest"},
+		Packages: PackageConfig{Manager: "pnpm", Install: []PackageInstall{{Dir: "ios", Command: "pnpm install"}}},
+		Env: []EnvVar{
+			{Key: "API_KEY"},
+			{Key: "API_KEY", File: "server/.env"}, // same key, other file
+		},
+		Config: []ConfigFile{{File: "src/site.ts"}},
+		PostSetup: PostSetup{Commands: []Command{
+			{Run: "pnpm run build"},
+			{Run: "NODE_ENV=production npx prisma generate"},
+			{Run: "python3 -m venv .venv"},
+			{Run: "./scripts/seed.sh --demo"},
+			{Run: `"go" vet ./...`},
+			{Run: "cd ios && pod install"},
+			{Run: "npm.cmd test"},
+			{Run: "git init", OS: []string{"plan9"}},
+		}},
+	}
+
+	if errs := m.DeepValidate(dir); len(errs) != 0 {
+		t.Errorf("DeepValidate() = %v, want no errors", errs)
+	}
+}
+
+func TestDeepValidate_RuntimeConstraints(t *testing.T) {
+	dir := deepDir(t, map[string]string{
+		".python-version": "3.12.8\n",
+		"versions/node":   "banana\n",
+	})
+	m := &Manifest{
+		Runtimes: map[string]string{
+			"node":    ">=banana",
+			"python":  "auto",
+			"go":      "1.22.x",
+			"java":    "file:versions/node", // not a constraint either
+			"flutter": "file:missing",       // Validate warns about it
+			"cobol":   ">=1",                // Validate reports it
+		},
+		RuntimesOS: map[string]map[string]string{"darwin": {"rust": "stable"}},
+	}
+
+	got := deepPaths(m.DeepValidate(dir))
+	want := map[string]string{
+		"runtimes.node":        `">=banana" is not a version constraint such as ">=20.0.0", "^3.12" or "latest"`,
+		"runtimes.darwin.rust": `"stable" is not a version constraint such as ">=20.0.0", "^3.12" or "latest"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DeepValidate() = %v, want errors at %v", got, want)
+	}
+	for path, msg := range want {
+		if got[path] != msg {
+			t.Errorf("%s: got %q, want %q", path, got[path], msg)
+		}
+	}
+}
+
+func TestDeepValidate_ConfigFiles(t *testing.T) {
+	dir := deepDir(t, map[string]string{"src/site.ts": "", "src/config/x": ""})
+	m := &Manifest{Config: []ConfigFile{
+		{File: "src/site.ts"},
+		{File: "src/missing.ts"},
+		{File: "src/config"},
+		{File: "../shared/site.ts"},
+		{File: ""}, // Validate reports it
+	}}
+
+	got := deepPaths(m.DeepValidate(dir))
+	want := map[string]string{
+		"config.1.file": "src/missing.ts does not exist",
+		"config.2.file": "src/config is a directory",
+		"config.3.file": "../shared/site.ts is outside the template directory",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DeepValidate() = %v, want errors at %v", got, want)
+	}
+	for path, msg := range want {
+		if got[path] != msg {
+			t.Errorf("%s: got %q, want %q", path, got[path], msg)
+		}
+	}
+}
+
+func TestDeepValidate_EnvFiles(t *testing.T) {
+	dir := deepDir(t, map[string]string{".env": "A=1\n", "config": "", "envdir/.keep": ""})
+	if err := os.Mkdir(filepath.Join(dir, ".env.d"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manifest{Env: []EnvVar{
+		{Key: "A"},                            // existing .env
+		{Key: "B", File: "backend/deep/.env"}, // directories are created
+		{Key: "C", File: ".env.d"},            // a directory
+		{Key: "D", File: "config/.env"},       // inside a file
+		{Key: "E", File: "../.env"},           // outside the template
+		{Key: "F", File: "envdir/.env.local"}, // new file in an existing directory
+		{Key: "G", File: "backend/deep/.env"}, // checked once per file
+	}}
+
+	got := deepPaths(m.DeepValidate(dir))
+	want := map[string]string{
+		"env.2.file": ".env.d is a directory",
+		"env.3.file": "config/.env can't be created: " + filepath.Join(dir, "config") + " is not a directory",
+		"env.4.file": "../.env is outside the template directory",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DeepValidate() = %v, want errors at %v", got, want)
+	}
+	for path, msg := range want {
+		if got[path] != msg {
+			t.Errorf("%s: got %q, want %q", path, got[path], msg)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "backend")); !os.IsNotExist(err) {
+		t.Error("DeepValidate() should not create directories")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".templatr-validate-") {
+			t.Errorf("probe file %s was left behind", e.Name())
+		}
+	}
+}
+
+func TestDeepValidate_ReadOnlyEnvFile(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("file permissions aren't enforced here")
+	}
+	dir := deepDir(t, map[string]string{"locked/.env": "A=1\n"})
+	if err := os.Chmod(filepath.Join(dir, "locked", ".env"), 0o444); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "locked"), 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(dir, "locked"), 0o755) })
+
+	m := &Manifest{Env: []EnvVar{{Key: "A", File: "locked/.env"}, {Key: "B", File: "locked/.env.local"}}}
+	got := deepPaths(m.DeepValidate(dir))
+	if !strings.HasPrefix(got["env.0.file"], "locked/.env is not writable") {
+		t.Errorf("env.0.file = %q, want a read-only file reported", got["env.0.file"])
+	}
+	if !strings.HasPrefix(got["env.1.file"], "locked/.env.local can't be created") {
+		t.Errorf("env.1.file = %q, want a read-only directory reported", got["env.1.file"])
+	}
+}
+
+func TestDeepValidate_DuplicateEnvKeys(t *testing.T) {
+	dir := deepDir(t, nil)
+	m := &Manifest{
+		EnvEnvironments: EnvEnvironments{Names: []string{"development", "production"}},
+		Env: []EnvVar{
+			{Key: "API_URL", Environments: []string{"development"}},
+			{Key: "API_URL", Environments: []string{"production"}}, // other environment: fine
+			{Key: "SECRET"},
+			{Key: "SECRET", Environments: []string{"production"}}, // SECRET is already in every environment
+			{Key: "PORT", File: "api/.env"},
+			{Key: "PORT"}, // other file: fine
+		},
+	}
+
+	got := deepPaths(m.DeepValidate(dir))
+	want := map[string]string{
+		"env.3.key": `duplicate key "SECRET" in .env.production - also set by env.2`,
+	}
+	if len(got) != len(want) || got["env.3.key"] != want["env.3.key"] {
+		t.Errorf("DeepValidate() = %v, want %v", got, want)
+	}
+
+	// Without environments, a repeated key in the same file clashes
+	m = &Manifest{Env: []EnvVar{{Key: "A"}, {Key: "B"}, {Key: "A", File: ".env"}}}
+	got = deepPaths(m.DeepValidate(dir))
+	if got["env.2.key"] != `duplicate key "A" in .env - also set by env.0` || len(got) != 1 {
+		t.Errorf("DeepValidate() = %v, want env.2 reported as a duplicate", got)
+	}
+}
+
+func TestDeepValidate_PostSetupCommands(t *testing.T) {
+	dir := deepDir(t, map[string]string{"scripts/seed.sh": "", "app/run.sh": ""})
+	t.Setenv("PATH", dir) // nothing but the template directory
+	m := &Manifest{
+		Runtimes: map[string]string{"node": ">=20"},
+		Packages: PackageConfig{Install: []PackageInstall{{Dir: "app", Command: "yarn", Manager: "yarn"}, {Dir: "web", Command: "npm ci"}}},
+		PostSetup: PostSetup{Commands: []Command{
+			{Run: "npm run build"},
+			{Run: "yarn lint"},
+			{Run: "prisma generate"},
+			{Run: "./scripts/missing.sh"},
+			{Run: "./run.sh", Dir: "app"},
+			{Run: "pod install", OS: []string{"plan9"}}, // can't be looked up here
+			{Run: "npm test", Dir: "missing"},
+			{Run: "FOO=1 BAR=2 cargo build"},
+			{Run: "echo done"},
+		}},
+	}
+
+	got := deepPaths(m.DeepValidate(dir))
+	want := map[string]string{
+		"packages.install.1.dir":    "directory web does not exist",
+		"post_setup.commands.2.run": "prisma is not installed by any runtime or package manager in the manifest, and is not on PATH",
+		"post_setup.commands.3.run": "./scripts/missing.sh does not exist",
+		"post_setup.commands.6.dir": "directory missing does not exist",
+		"post_setup.commands.7.run": "cargo is not installed by any runtime or package manager in the manifest, and is not on PATH",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DeepValidate() = %v, want errors at %v", got, want)
+	}
+	for path, msg := range want {
+		if got[path] != msg {
+			t.Errorf("%s: got %q, want %q", path, got[path], msg)
+		}
+	}
+}
+
+func TestDeepValidate_CommandOnPath(t *testing.T) {
+	bin := t.TempDir()
+	name := "templatr-test-tool"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	m := &Manifest{PostSetup: PostSetup{Commands: []Command{{Run: "templatr-test-tool --init"}}}}
+	errs := m.DeepValidate(t.TempDir())
+	if len(errs) != 1 || errs[0].Severity != SeverityWarning || errs[0].Message != "templatr-test-tool is not installed by setup - it must already be on the user's PATH" {
+		t.Errorf("DeepValidate() = %v, want a warning for a program only on PATH", errs)
+	}
+}
+
+func TestCommandProgram(t *testing.T) {
+	tests := map[string]string{
+		"npm run build":           "npm",
+		"NODE_ENV=prod npm start": "npm",
+		`"./bin/setup" --fast`:    "./bin/setup",
+		"npx.CMD prisma":          "npx",
+		"python.exe -m pip":       "python",
+		"   ":                     "",
+		"A=1":                     "",
+	}
+	for run, want := range tests {
+		if got := commandProgram(run); got != want {
+			t.Errorf("commandProgram(%q) = %q, want %q", run, got, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"sort"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// RuntimeChange describes a runtime requirement that was added, removed,
+// or changed between two manifests. Old is empty for added runtimes and
+// New is empty for removed ones.
+type RuntimeChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// FieldChange describes an env var or config field that was added or removed.
+// For env vars Key is the variable name and File the target env file; for
+// config fields Key is the field path and File the config file.
+type FieldChange struct {
+	Key      string `json:"key"`
+	Label    string `json:"label,omitempty"`
+	File     string `json:"file,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// Diff is the structural difference between a previously applied manifest
+// and the current one.
+type Diff struct {
+	OldVersion       string          `json:"oldVersion,omitempty"`
+	NewVersion       string          `json:"newVersion,omitempty"`
+	Runtimes         []RuntimeChange `json:"runtimes,omitempty"`
+	AddedEnv         []FieldChange   `json:"addedEnv,omitempty"`
+	RemovedEnv       []FieldChange   `json:"removedEnv,omitempty"`
+	AddedConfig      []FieldChange   `json:"addedConfig,omitempty"`
+	RemovedConfig    []FieldChange   `json:"removedConfig,omitempty"`
+	PostSetupChanged bool            `json:"postSetupChanged,omitempty"`
+	OldCommands      []string        `json:"oldCommands,omitempty"`
+	NewCommands      []string        `json:"newCommands,omitempty"`
+}
+
+// Empty returns true if the two manifests are equivalent for setup purposes.
+// A template version bump alone is not considered a change.
+func (d *Diff) Empty() bool {
+	return len(d.Runtimes) == 0 &&
+		len(d.AddedEnv) == 0 && len(d.RemovedEnv) == 0 &&
+		len(d.AddedConfig) == 0 && len(d.RemovedConfig) == 0 &&
+		!d.PostSetupChanged
+}
+
+// IsNewEnv returns true if the env key was added in the current manifest.
+func (d *Diff) IsNewEnv(key string) bool {
+	for _, f := range d.AddedEnv {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNewConfigField returns true if the config field was added in the current manifest.
+func (d *Diff) IsNewConfigField(file, path string) bool {
+	for _, f := range d.AddedConfig {
+		if f.File == file && f.Key == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare computes the differences between a previously applied manifest
+// and the current one. Results are sorted so output is stable.
+func Compare(old, cur *Manifest) *Diff {
+	d := &Diff{
+		OldVersion: old.Template.Version,
+		NewVersion: cur.Template.Version,
+	}
+
+	// Runtimes on this OS: added, removed, or constraint changed
+	oldRuntimes, curRuntimes := old.RuntimesFor(runtime.GOOS), cur.RuntimesFor(runtime.GOOS)
+	for name, newReq := range curRuntimes {
+		oldReq, ok := oldRuntimes[name]
+		if !ok {
+			d.Runtimes = append(d.Runtimes, RuntimeChange{Name: name, New: newReq})
+		} else if oldReq != newReq {
+			d.Runtimes = append(d.Runtimes, RuntimeChange{Name: name, Old: oldReq, New: newReq})
+		}
+	}
+	for name, oldReq := range oldRuntimes {
+		if _, ok := curRuntimes[name]; !ok {
+			d.Runtimes = append(d.Runtimes, RuntimeChange{Name: name, Old: oldReq})
+		}
+	}
+	sort.Slice(d.Runtimes, func(i, j int) bool { return d.Runtimes[i].Name < d.Runtimes[j].Name })
+
+	// Env vars, keyed by target file + key
+	d.AddedEnv = envOnlyIn(cur.Env, old.Env)
+	d.RemovedEnv = envOnlyIn(old.Env, cur.Env)
+
+	// Config fields, keyed by file + path
+	d.AddedConfig = configOnlyIn(cur.Config, old.Config)
+	d.RemovedConfig = configOnlyIn(old.Config, cur.Config)
+
+	// Post-setup commands
+	oldCommands, curCommands := commandStrings(old.PostSetup.Commands), commandStrings(cur.PostSetup.Commands)
+	if !equalStrings(oldCommands, curCommands) {
+		d.PostSetupChanged = true
+		d.OldCommands = oldCommands
+		d.NewCommands = curCommands
+	}
+
+	return d
+}
+
+// Hash returns a SHA256 hash of the manifest's canonical TOML encoding.
+// Used to detect whether a manifest changed since it was last applied.
+func Hash(m *Manifest) string {
+	data, err := toml.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	// OS runtime tables aren't part of the encoding
+	if len(m.RuntimesOS) > 0 {
+		extra, err := toml.Marshal(m.RuntimesOS)
+		if err != nil {
+			return ""
+		}
+		data = append(data, extra...)
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// commandStrings returns each command with its OS filter, e.g. "pod install (darwin)".
+func commandStrings(cmds []Command) []string {
+	var out []string
+	for _, c := range cmds {
+		out = append(out, c.String())
+	}
+	return out
+}
+
+// envOnlyIn returns env vars present in a but not in b.
+func envOnlyIn(a, b []EnvVar) []FieldChange {
+	seen := make(map[string]bool, len(b))
+	for _, env := range b {
+		seen[envTarget(env)+"\x00"+env.Key] = true
+	}
+
+	var out []FieldChange
+	for _, env := range a {
+		if !seen[envTarget(env)+"\x00"+env.Key] {
+			out = append(out, FieldChange{
+				Key:      env.Key,
+				Label:    env.Label,
+				File:     envTarget(env),
+				Required: env.Required,
+			})
+		}
+	}
+	return out
+}
+
+// configOnlyIn returns config fields present in a but not in b.
+func configOnlyIn(a, b []ConfigFile) []FieldChange {
+	seen := make(map[string]bool)
+	for _, cfg := range b {
+		for _, f := range cfg.Fields {
+			seen[cfg.File+"\x00"+f.Path] = true
+		}
+	}
+
+	var out []FieldChange
+	for _, cfg := range a {
+		for _, f := range cfg.Fields {
+			if !seen[cfg.File+"\x00"+f.Path] {
+				out = append(out, FieldChange{Key: f.Path, Label: f.Label, File: cfg.File})
+			}
+		}
+	}
+	return out
+}
+
+// envTarget mirrors config.EnvFileTarget without importing the config package.
+func envTarget(env EnvVar) string {
+	if env.File != "" {
+		return env.File
+	}
+	return ".env"
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
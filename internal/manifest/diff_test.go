@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func baseManifest() *Manifest {
+	return &Manifest{
+		Template: TemplateInfo{Name: "SaaS Starter", Slug: "saas-starter", Version: "1.0.0"},
+		Runtimes: map[string]string{
+			"node":   ">=20.0.0",
+			"python": ">=3.11",
+		},
+		Env: []EnvVar{
+			{Key: "API_URL", Label: "API URL", Required: true},
+		},
+		Config: []ConfigFile{
+			{File: "site.config.json", Fields: []ConfigField{{Path: "name", Label: "Site name"}}},
+		},
+		PostSetup: PostSetup{Commands: []Command{{Run: "npm run build"}}},
+	}
+}
+
+func TestCompare_Unchanged(t *testing.T) {
+	d := Compare(baseManifest(), baseManifest())
+	if !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+	if Hash(baseManifest()) != Hash(baseManifest()) {
+		t.Error("expected hash to be stable for identical manifests")
+	}
+}
+
+func TestCompare_VersionBumpOnly(t *testing.T) {
+	cur := baseManifest()
+	cur.Template.Version = "1.1.0"
+
+	d := Compare(baseManifest(), cur)
+	if !d.Empty() {
+		t.Errorf("version bump alone should not count as a change, got %+v", d)
+	}
+	if d.OldVersion != "1.0.0" || d.NewVersion != "1.1.0" {
+		t.Errorf("versions = %q -> %q, want 1.0.0 -> 1.1.0", d.OldVersion, d.NewVersion)
+	}
+	if Hash(baseManifest()) == Hash(cur) {
+		t.Error("expected hash to change with the template version")
+	}
+}
+
+func TestCompare_RuntimeChanges(t *testing.T) {
+	cur := baseManifest()
+	cur.Runtimes["node"] = ">=22.0.0"
+	delete(cur.Runtimes, "python")
+	cur.Runtimes["go"] = ">=1.22"
+
+	d := Compare(baseManifest(), cur)
+	want := []RuntimeChange{
+		{Name: "go", New: ">=1.22"},
+		{Name: "node", Old: ">=20.0.0", New: ">=22.0.0"},
+		{Name: "python", Old: ">=3.11"},
+	}
+	if len(d.Runtimes) != len(want) {
+		t.Fatalf("got %d runtime changes, want %d: %+v", len(d.Runtimes), len(want), d.Runtimes)
+	}
+	for i, w := range want {
+		if d.Runtimes[i] != w {
+			t.Errorf("runtime change %d = %+v, want %+v", i, d.Runtimes[i], w)
+		}
+	}
+}
+
+func TestCompare_EnvAddedAndRemoved(t *testing.T) {
+	cur := baseManifest()
+	cur.Env = []EnvVar{
+		{Key: "STRIPE_KEY", Required: true, File: ".env.local"},
+	}
+
+	d := Compare(baseManifest(), cur)
+	if len(d.AddedEnv) != 1 || d.AddedEnv[0].Key != "STRIPE_KEY" || !d.AddedEnv[0].Required || d.AddedEnv[0].File != ".env.local" {
+		t.Errorf("AddedEnv = %+v, want required STRIPE_KEY in .env.local", d.AddedEnv)
+	}
+	if len(d.RemovedEnv) != 1 || d.RemovedEnv[0].Key != "API_URL" || d.RemovedEnv[0].File != ".env" {
+		t.Errorf("RemovedEnv = %+v, want API_URL in .env", d.RemovedEnv)
+	}
+	if !d.IsNewEnv("STRIPE_KEY") || d.IsNewEnv("API_URL") {
+		t.Error("IsNewEnv reported the wrong keys")
+	}
+}
+
+func TestCompare_EnvMovedFile(t *testing.T) {
+	cur := baseManifest()
+	cur.Env[0].File = ".env.local"
+
+	d := Compare(baseManifest(), cur)
+	if len(d.AddedEnv) != 1 || len(d.RemovedEnv) != 1 {
+		t.Errorf("moving an env var to another file should add and remove it, got %+v", d)
+	}
+}
+
+func TestCompare_ConfigFieldAdded(t *testing.T) {
+	cur := baseManifest()
+	cur.Config[0].Fields = append(cur.Config[0].Fields, ConfigField{Path: "seo.title", Label: "SEO title"})
+
+	d := Compare(baseManifest(), cur)
+	if len(d.AddedConfig) != 1 || d.AddedConfig[0].Key != "seo.title" {
+		t.Errorf("AddedConfig = %+v, want seo.title", d.AddedConfig)
+	}
+	if len(d.RemovedConfig) != 0 {
+		t.Errorf("RemovedConfig = %+v, want none", d.RemovedConfig)
+	}
+	if !d.IsNewConfigField("site.config.json", "seo.title") || d.IsNewConfigField("site.config.json", "name") {
+		t.Error("IsNewConfigField reported the wrong fields")
+	}
+}
+
+func TestCompare_PostSetupChanged(t *testing.T) {
+	cur := baseManifest()
+	cur.PostSetup.Commands = []Command{{Run: "npm run build"}, {Run: "npm run seed"}}
+
+	d := Compare(baseManifest(), cur)
+	if !d.PostSetupChanged {
+		t.Fatal("expected PostSetupChanged")
+	}
+	if len(d.OldCommands) != 1 || len(d.NewCommands) != 2 {
+		t.Errorf("commands = %v -> %v", d.OldCommands, d.NewCommands)
+	}
+	if d.Empty() {
+		t.Error("diff with changed post-setup commands should not be empty")
+	}
+}
@@ -0,0 +1,318 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ParseFrom parses a manifest read from source, a file path or URL, and
+// merges it over the manifests its extends list names. Relative entries
+// are resolved against source; an empty source means a manifest in the
+// current directory.
+func ParseFrom(data []byte, source string) (*Manifest, error) {
+	return parseExtending(data, source, nil)
+}
+
+// cycleError is an extends chain that leads back to a manifest in it.
+type cycleError struct{ chain []string }
+
+func (e *cycleError) Error() string {
+	return "extends cycle: " + strings.Join(e.chain, " -> ")
+}
+
+// parseExtending parses data and merges it over its parents, loaded first
+// and in order. chain holds the manifests that led here, to detect cycles.
+func parseExtending(data []byte, source string, chain []string) (*Manifest, error) {
+	m, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Extends) == 0 {
+		return m, nil
+	}
+
+	chain = append(slices.Clip(chain), sourceKey(source))
+	var base *Manifest
+	for _, ref := range m.Extends {
+		parent, err := loadParent(source, ref, chain)
+		var cycle *cycleError
+		if errors.As(err, &cycle) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extends %q: %w", ref, err)
+		}
+		if base == nil {
+			base = parent
+		} else {
+			base = merge(base, parent)
+		}
+	}
+	return merge(base, m), nil
+}
+
+// loadParent reads and parses the manifest ref names, relative to source.
+func loadParent(source, ref string, chain []string) (*Manifest, error) {
+	parentSource, err := resolveExtends(source, ref)
+	if err != nil {
+		return nil, err
+	}
+	if key := sourceKey(parentSource); slices.Contains(chain, key) {
+		return nil, &cycleError{chain: append(slices.Clip(chain), key)}
+	}
+
+	var data []byte
+	if IsURL(parentSource) {
+		data, err = fetch(parentSource)
+	} else {
+		data, err = os.ReadFile(parentSource)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseExtending(data, parentSource, chain)
+}
+
+// resolveExtends returns where an extends entry points: a URL as is, or a
+// path against the directory, or URL, of the manifest that names it. A
+// fetched manifest can only extend other URLs.
+func resolveExtends(source, ref string) (string, error) {
+	if IsURL(ref) {
+		return ref, nil
+	}
+	if IsURL(source) {
+		if filepath.IsAbs(ref) || isAbsDir(ref) {
+			return "", fmt.Errorf("a manifest fetched from a URL can't extend a local file")
+		}
+		base, err := url.Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("invalid manifest URL: %w", err)
+		}
+		rel, err := url.Parse(filepath.ToSlash(ref))
+		if err != nil {
+			return "", fmt.Errorf("invalid path: %w", err)
+		}
+		return base.ResolveReference(rel).String(), nil
+	}
+	if filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	return filepath.Join(filepath.Dir(sourceKey(source)), filepath.FromSlash(ref)), nil
+}
+
+// sourceKey identifies a manifest in an extends chain: its URL, or its
+// absolute path.
+func sourceKey(source string) string {
+	if IsURL(source) {
+		return source
+	}
+	if source == "" {
+		source = DefaultManifestName
+	}
+	if abs, err := filepath.Abs(source); err == nil {
+		return abs
+	}
+	return source
+}
+
+// merge returns child laid over parent. The child is what a template
+// declares itself, so it wins wherever both say something:
+//
+//   - [template], extends and the loaded-from Dir and Source are the child's.
+//   - [runtimes], its OS tables and [runtimes_checksums] merge by key, the
+//     child's requirement replacing the parent's for the same runtime.
+//   - [[env]] entries merge by key and target file: a child entry replaces
+//     the parent's in place, and new keys are appended after the parent's.
+//   - [[config]] entries merge by file, their label and description
+//     overridden if the child sets them, and their fields merge by path the
+//     way env entries do by key.
+//   - [[downloads]] merge by name like env entries.
+//   - [packages] fields the child sets override the parent's. Setting
+//     install_command or [[packages.install]] replaces both, since only one
+//     may be used, and changing the manager without a command drops the
+//     parent's command for the old manager. Global packages are combined.
+//   - [env_environments] names and file_pattern are the child's if it sets
+//     them. [env_options] write_example is on if either turns it on.
+//   - post_setup commands run the parent's first, then the child's that
+//     aren't already there. The message is the child's if it has one.
+//   - [meta] min_tool_version is the higher of the two, docs the child's if
+//     set.
+//
+// Paths in a parent, such as config files and command dirs, are relative to
+// the extending template, where setup runs.
+func merge(parent, child *Manifest) *Manifest {
+	m := *child
+	m.Runtimes = mergeRuntimes(parent.Runtimes, child.Runtimes)
+	m.RuntimesOS = mergeTables(parent.RuntimesOS, child.RuntimesOS)
+	m.RuntimesChecksums = mergeTables(parent.RuntimesChecksums, child.RuntimesChecksums)
+	m.Packages = mergePackages(parent.Packages, child.Packages)
+	m.Env = mergeByKey(parent.Env, child.Env, func(e EnvVar) string { return envTarget(e) + "\x00" + e.Key }, replace)
+	m.EnvEnvironments = parent.EnvEnvironments
+	if len(child.EnvEnvironments.Names) > 0 {
+		m.EnvEnvironments.Names = child.EnvEnvironments.Names
+	}
+	if child.EnvEnvironments.FilePattern != "" {
+		m.EnvEnvironments.FilePattern = child.EnvEnvironments.FilePattern
+	}
+	m.EnvOptions.WriteExample = parent.EnvOptions.WriteExample || child.EnvOptions.WriteExample
+	m.Config = mergeByKey(parent.Config, child.Config, func(c ConfigFile) string { return path.Clean(filepath.ToSlash(c.File)) }, mergeConfigFile)
+	m.Downloads = mergeByKey(parent.Downloads, child.Downloads, func(d Download) string { return d.Name }, replace)
+	m.PostSetup = mergePostSetup(parent.PostSetup, child.PostSetup)
+	m.Meta = mergeMeta(parent.Meta, child.Meta)
+	return &m
+}
+
+// mergeByKey returns parent's entries with each one child has the same key
+// for combined with the child's in place, followed by the rest of child's
+// entries in their order.
+func mergeByKey[T any](parent, child []T, key func(T) string, combine func(parent, child T) T) []T {
+	if len(parent) == 0 && len(child) == 0 {
+		return nil
+	}
+	merged := slices.Clone(parent)
+	fromParent := make(map[string]int, len(parent)) // key -> index of a parent entry not yet combined
+	for i, p := range parent {
+		if _, ok := fromParent[key(p)]; !ok {
+			fromParent[key(p)] = i
+		}
+	}
+	for _, c := range child {
+		if i, ok := fromParent[key(c)]; ok {
+			merged[i] = combine(merged[i], c)
+			delete(fromParent, key(c))
+			continue
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// replace is the combine function for entries the child replaces whole.
+func replace[T any](_, child T) T {
+	return child
+}
+
+func mergeConfigFile(parent, child ConfigFile) ConfigFile {
+	merged := parent
+	if child.Label != "" {
+		merged.Label = child.Label
+	}
+	if child.Description != "" {
+		merged.Description = child.Description
+	}
+	merged.Fields = mergeByKey(parent.Fields, child.Fields, func(f ConfigField) string { return f.Path }, replace)
+	return merged
+}
+
+// mergeRuntimes lays child's requirements over parent's. Runtime names are
+// case-insensitive, so Node in the child replaces node in the parent.
+func mergeRuntimes(parent, child map[string]string) map[string]string {
+	if len(parent) == 0 && len(child) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(parent)+len(child))
+	for name, required := range parent {
+		merged[name] = required
+	}
+	for name, required := range child {
+		for existing := range merged {
+			if strings.EqualFold(existing, name) {
+				delete(merged, existing)
+			}
+		}
+		merged[name] = required
+	}
+	return merged
+}
+
+// mergeTables merges two levels of tables, such as [runtimes.darwin] or
+// [runtimes_checksums.node], by key at each level.
+func mergeTables(parent, child map[string]map[string]string) map[string]map[string]string {
+	if len(parent) == 0 && len(child) == 0 {
+		return nil
+	}
+	merged := make(map[string]map[string]string, len(parent)+len(child))
+	for name, table := range parent {
+		merged[name] = mergeRuntimes(nil, table)
+	}
+	for name, table := range child {
+		merged[name] = mergeRuntimes(merged[name], table)
+	}
+	return merged
+}
+
+func mergePackages(parent, child PackageConfig) PackageConfig {
+	merged := parent
+	if child.Manager != "" && child.Manager != parent.Manager {
+		merged.Manager = child.Manager
+		// The parent's command was for its manager; the engine infers one
+		// for the child's, or the child sets its own below
+		merged.InstallCommand, merged.ManagerVersion = "", ""
+	}
+	if child.ManagerVersion != "" {
+		merged.ManagerVersion = child.ManagerVersion
+	}
+	if child.InstallCommand != "" || len(child.Install) > 0 {
+		merged.InstallCommand, merged.Install = child.InstallCommand, child.Install
+	}
+	for _, pkg := range child.Global {
+		if !slices.Contains(merged.Global, pkg) {
+			merged.Global = append(slices.Clip(merged.Global), pkg)
+		}
+	}
+	if child.Timeout != "" {
+		merged.Timeout = child.Timeout
+	}
+	return merged
+}
+
+func mergePostSetup(parent, child PostSetup) PostSetup {
+	merged := PostSetup{Commands: slices.Clone(parent.Commands), Message: parent.Message}
+	for _, cmd := range child.Commands {
+		if !slices.ContainsFunc(parent.Commands, func(p Command) bool {
+			return p.Run == cmd.Run && p.Dir == cmd.Dir && slices.Equal(p.OS, cmd.OS)
+		}) {
+			merged.Commands = append(merged.Commands, cmd)
+		}
+	}
+	if child.Message != "" {
+		merged.Message = child.Message
+	}
+	return merged
+}
+
+func mergeMeta(parent, child Meta) Meta {
+	merged := child
+	if merged.Docs == "" {
+		merged.Docs = parent.Docs
+	}
+	if higherVersion(parent.MinToolVersion, child.MinToolVersion) {
+		merged.MinToolVersion = parent.MinToolVersion
+	}
+	return merged
+}
+
+// higherVersion reports whether version a is set and above b. A version
+// that doesn't parse loses, so Validate reports the one that was written.
+func higherVersion(a, b string) bool {
+	if a == "" {
+		return false
+	}
+	va, err := semver.NewVersion(strings.TrimPrefix(a, "v"))
+	if err != nil {
+		return false
+	}
+	vb, err := semver.NewVersion(strings.TrimPrefix(b, "v"))
+	if err != nil {
+		return b == ""
+	}
+	return va.GreaterThan(vb)
+}
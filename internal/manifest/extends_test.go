@@ -0,0 +1,488 @@
+package manifest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent string
+		child  string
+		check  func(t *testing.T, m *Manifest)
+	}{
+		{
+			name: "template is the child's",
+			parent: `[template]
+name = "Base"
+version = "2.0.0"
+tier = "pro"`,
+			child: `[template]
+name = "Landing"
+version = "1.0.0"`,
+			check: func(t *testing.T, m *Manifest) {
+				if m.Template.Name != "Landing" || m.Template.Version != "1.0.0" || m.Template.Tier != "" {
+					t.Errorf("Template = %+v, want only the child's", m.Template)
+				}
+			},
+		},
+		{
+			name: "runtimes override by name",
+			parent: `[runtimes]
+node = ">=18"
+python = ">=3.10"
+[runtimes.darwin]
+node = ">=18"`,
+			child: `[runtimes]
+Node = ">=20"
+go = ">=1.22"
+[runtimes.darwin]
+python = ">=3.11"`,
+			check: func(t *testing.T, m *Manifest) {
+				want := map[string]string{"Node": ">=20", "python": ">=3.10", "go": ">=1.22"}
+				if !reflect.DeepEqual(m.Runtimes, want) {
+					t.Errorf("Runtimes = %v, want %v", m.Runtimes, want)
+				}
+				wantOS := map[string]map[string]string{"darwin": {"node": ">=18", "python": ">=3.11"}}
+				if !reflect.DeepEqual(m.RuntimesOS, wantOS) {
+					t.Errorf("RuntimesOS = %v, want %v", m.RuntimesOS, wantOS)
+				}
+			},
+		},
+		{
+			name: "conflicting env keys",
+			parent: `[[env]]
+key = "API_URL"
+label = "API URL"
+default = "https://base.example.com"
+[[env]]
+key = "SECRET"
+label = "Secret"
+type = "secret"`,
+			child: `[[env]]
+key = "API_URL"
+label = "Landing API"
+default = "https://landing.example.com"
+[[env]]
+key = "SITE_NAME"
+label = "Site name"`,
+			check: func(t *testing.T, m *Manifest) {
+				got := envSummary(m.Env)
+				want := []string{".env API_URL Landing API", ".env SECRET Secret", ".env SITE_NAME Site name"}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Env = %v, want %v", got, want)
+				}
+				// The child's entry replaces the parent's whole
+				if m.Env[0].Default != "https://landing.example.com" || m.Env[0].Type != "" {
+					t.Errorf("API_URL = %+v, want the child's entry", m.Env[0])
+				}
+			},
+		},
+		{
+			name: "same env key in another file",
+			parent: `[[env]]
+key = "PORT"
+label = "Port"`,
+			child: `[[env]]
+key = "PORT"
+label = "API port"
+file = "api/.env"`,
+			check: func(t *testing.T, m *Manifest) {
+				got := envSummary(m.Env)
+				want := []string{".env PORT Port", "api/.env PORT API port"}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Env = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "nested config fields merge by path",
+			parent: `[[config]]
+file = "config/site.json"
+label = "Site"
+description = "Shared site settings"
+[[config.fields]]
+path = "site.name"
+label = "Name"
+type = "text"
+[[config.fields]]
+path = "site.url"
+label = "URL"
+type = "url"`,
+			child: `[[config]]
+file = "./config/site.json"
+label = "Landing site"
+[[config.fields]]
+path = "site.url"
+label = "Landing URL"
+type = "url"
+default = "https://landing.example.com"
+[[config.fields]]
+path = "theme.color"
+label = "Color"
+type = "text"
+[[config]]
+file = "config/app.json"
+label = "App"
+[[config.fields]]
+path = "app.mode"
+label = "Mode"
+type = "text"`,
+			check: func(t *testing.T, m *Manifest) {
+				if len(m.Config) != 2 {
+					t.Fatalf("Config has %d files, want 2", len(m.Config))
+				}
+				site := m.Config[0]
+				if site.File != "config/site.json" || site.Label != "Landing site" || site.Description != "Shared site settings" {
+					t.Errorf("site config = %q %q %q, want the parent's file and description with the child's label", site.File, site.Label, site.Description)
+				}
+				var fields []string
+				for _, f := range site.Fields {
+					fields = append(fields, f.Path+" "+f.Label)
+				}
+				want := []string{"site.name Name", "site.url Landing URL", "theme.color Color"}
+				if !reflect.DeepEqual(fields, want) {
+					t.Errorf("site fields = %v, want %v", fields, want)
+				}
+				if m.Config[1].File != "config/app.json" {
+					t.Errorf("Config[1] = %q, want the child's new file appended", m.Config[1].File)
+				}
+			},
+		},
+		{
+			name: "packages manager change drops the parent's command",
+			parent: `[packages]
+manager = "npm"
+install_command = "npm ci"
+global = ["typescript"]`,
+			child: `[packages]
+manager = "pnpm"
+global = ["typescript", "turbo"]`,
+			check: func(t *testing.T, m *Manifest) {
+				if m.Packages.Manager != "pnpm" || m.Packages.InstallCommand != "" {
+					t.Errorf("Packages = %+v, want pnpm with no command", m.Packages)
+				}
+				if want := []string{"typescript", "turbo"}; !reflect.DeepEqual(m.Packages.Global, want) {
+					t.Errorf("Global = %v, want %v", m.Packages.Global, want)
+				}
+			},
+		},
+		{
+			name: "packages command kept for the same manager",
+			parent: `[packages]
+manager = "npm"
+install_command = "npm ci"`,
+			child: `[packages]
+timeout = "20m"`,
+			check: func(t *testing.T, m *Manifest) {
+				if m.Packages.Manager != "npm" || m.Packages.InstallCommand != "npm ci" || m.Packages.Timeout != "20m" {
+					t.Errorf("Packages = %+v, want npm ci with the child's timeout", m.Packages)
+				}
+			},
+		},
+		{
+			name: "packages manager_version dropped with the parent's manager",
+			parent: `[packages]
+manager = "yarn"
+manager_version = "^1.22"`,
+			child: `[packages]
+manager = "pnpm"`,
+			check: func(t *testing.T, m *Manifest) {
+				if m.Packages.Manager != "pnpm" || m.Packages.ManagerVersion != "" {
+					t.Errorf("Packages = %+v, want pnpm with no manager_version", m.Packages)
+				}
+			},
+		},
+		{
+			name: "post_setup runs the parent's commands first",
+			parent: `[post_setup]
+message = "Base ready"
+[[post_setup.commands]]
+run = "npm run build"
+[[post_setup.commands]]
+run = "npm test"`,
+			child: `[[post_setup.commands]]
+run = "npm test"
+[[post_setup.commands]]
+run = "npm run seed"`,
+			check: func(t *testing.T, m *Manifest) {
+				var runs []string
+				for _, c := range m.PostSetup.Commands {
+					runs = append(runs, c.Run)
+				}
+				want := []string{"npm run build", "npm test", "npm run seed"}
+				if !reflect.DeepEqual(runs, want) {
+					t.Errorf("commands = %v, want %v", runs, want)
+				}
+				if m.PostSetup.Message != "Base ready" {
+					t.Errorf("Message = %q, want the parent's when the child has none", m.PostSetup.Message)
+				}
+			},
+		},
+		{
+			name: "write_example set by the parent stays on",
+			parent: `[env_options]
+write_example = true`,
+			child: `[template]
+name = "Landing"`,
+			check: func(t *testing.T, m *Manifest) {
+				if !m.EnvOptions.WriteExample {
+					t.Error("EnvOptions.WriteExample = false, want the parent's true")
+				}
+			},
+		},
+		{
+			name: "min_tool_version is the higher",
+			parent: `[meta]
+min_tool_version = "1.4.0"
+docs = "https://docs.example.com"`,
+			child: `[meta]
+min_tool_version = "1.2.0"`,
+			check: func(t *testing.T, m *Manifest) {
+				if m.Meta.MinToolVersion != "1.4.0" || m.Meta.Docs != "https://docs.example.com" {
+					t.Errorf("Meta = %+v, want 1.4.0 and the parent's docs", m.Meta)
+				}
+			},
+		},
+		{
+			name: "invalid min_tool_version in the child is kept",
+			parent: `[meta]
+min_tool_version = "1.4.0"`,
+			child: `[meta]
+min_tool_version = "soon"`,
+			check: func(t *testing.T, m *Manifest) {
+				if m.Meta.MinToolVersion != "soon" {
+					t.Errorf("MinToolVersion = %q, want the child's for Validate to report", m.Meta.MinToolVersion)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, err := Parse([]byte(tt.parent))
+			if err != nil {
+				t.Fatalf("Parse(parent) error = %v", err)
+			}
+			child, err := Parse([]byte(tt.child))
+			if err != nil {
+				t.Fatalf("Parse(child) error = %v", err)
+			}
+			tt.check(t, merge(parent, child))
+		})
+	}
+}
+
+func envSummary(env []EnvVar) []string {
+	var out []string
+	for _, e := range env {
+		out = append(out, envTarget(e)+" "+e.Key+" "+e.Label)
+	}
+	return out
+}
+
+func TestLoad_Extends(t *testing.T) {
+	dir := deepDir(t, map[string]string{
+		"shared/base.templatr.toml": `
+[runtimes]
+node = ">=18"
+
+[[env]]
+key = "API_URL"
+label = "API URL"
+`,
+		"shared/next.templatr.toml": `
+extends = ["base.templatr.toml"]
+
+[template]
+name = "Next base"
+version = "1.0.0"
+
+[packages]
+manager = "npm"
+`,
+		"landing/.templatr.toml": `
+extends = ["../shared/next.templatr.toml"]
+
+[template]
+name = "Landing"
+version = "1.0.0"
+
+[runtimes]
+node = ">=20"
+
+[[env]]
+key = "SITE_NAME"
+label = "Site name"
+`,
+	})
+
+	m, err := Load(filepath.Join(dir, "landing", ".templatr.toml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Template.Name != "Landing" || m.Runtimes["node"] != ">=20" || m.Packages.Manager != "npm" {
+		t.Errorf("Load() = %q node %q manager %q, want Landing with node >=20 and npm", m.Template.Name, m.Runtimes["node"], m.Packages.Manager)
+	}
+	if got, want := envSummary(m.Env), []string{".env API_URL API URL", ".env SITE_NAME Site name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Env = %v, want %v", got, want)
+	}
+	if m.Dir != filepath.Join(dir, "landing") {
+		t.Errorf("Dir = %q, want the extending template's directory", m.Dir)
+	}
+}
+
+func TestLoad_ExtendsLaterParentWins(t *testing.T) {
+	dir := deepDir(t, map[string]string{
+		"a.toml": "[runtimes]\nnode = \">=18\"\npython = \">=3.10\"\n",
+		"b.toml": "[runtimes]\nnode = \">=20\"\n",
+		".templatr.toml": `
+extends = ["a.toml", "b.toml"]
+
+[template]
+name = "Test"
+version = "1.0.0"
+`,
+	})
+
+	m, err := Load(filepath.Join(dir, ".templatr.toml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]string{"node": ">=20", "python": ">=3.10"}
+	if !reflect.DeepEqual(m.Runtimes, want) {
+		t.Errorf("Runtimes = %v, want %v", m.Runtimes, want)
+	}
+}
+
+func TestLoad_ExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr []string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				".templatr.toml": "extends = [\"shared/a.toml\"]\n[template]\nname = \"Test\"\nversion = \"1.0.0\"\n",
+				"shared/a.toml":  "extends = [\"b.toml\"]\n",
+				"shared/b.toml":  "extends = [\"../.templatr.toml\"]\n",
+			},
+			wantErr: []string{"extends cycle: ", ".templatr.toml -> ", "a.toml -> ", "b.toml -> "},
+		},
+		{
+			name: "extends itself",
+			files: map[string]string{
+				".templatr.toml": "extends = [\".templatr.toml\"]\n",
+			},
+			wantErr: []string{"extends cycle: "},
+		},
+		{
+			name: "missing parent",
+			files: map[string]string{
+				".templatr.toml": "extends = [\"base.toml\"]\n",
+			},
+			wantErr: []string{`extends "base.toml"`},
+		},
+		{
+			name: "invalid parent",
+			files: map[string]string{
+				".templatr.toml": "extends = [\"base.toml\"]\n",
+				"base.toml":      "[runtimes\n",
+			},
+			wantErr: []string{`extends "base.toml"`, "failed to parse manifest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := deepDir(t, tt.files)
+			_, err := Load(filepath.Join(dir, ".templatr.toml"))
+			if err == nil {
+				t.Fatal("Load() error = nil, want an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_ExtendsValidatesMerged(t *testing.T) {
+	// The parent alone has no template, and the child alone no env entry;
+	// only the merged manifest is checked
+	dir := deepDir(t, map[string]string{
+		"base.toml": "[[env]]\nkey = \"PORT\"\nlabel = \"Port\"\ntype = \"cobol\"\n",
+		".templatr.toml": `
+extends = ["base.toml"]
+
+[template]
+name = "Test"
+version = "1.0.0"
+`,
+	})
+
+	m, err := Load(filepath.Join(dir, ".templatr.toml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	errs := Validate(m)
+	if len(errs) != 1 || errs[0].Path != "env.0.type" {
+		t.Errorf("Validate() = %v, want only the merged env entry's type reported", errs)
+	}
+}
+
+func TestLoad_ExtendsURL(t *testing.T) {
+	srv := serveManifests(t, true, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/templates/landing/.templatr.toml":
+			w.Write([]byte("extends = [\"../shared/base.toml\"]\n[template]\nname = \"Landing\"\nversion = \"1.0.0\"\n"))
+		case "/templates/shared/base.toml":
+			w.Write([]byte("[runtimes]\nnode = \">=20\"\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	m, err := Load(srv.URL + "/templates/landing/.templatr.toml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Runtimes["node"] != ">=20" {
+		t.Errorf("Runtimes = %v, want node from the fetched parent", m.Runtimes)
+	}
+
+	// A local manifest can extend a URL too
+	dir := deepDir(t, map[string]string{
+		".templatr.toml": "extends = [\"" + srv.URL + "/templates/shared/base.toml\"]\n[template]\nname = \"Local\"\nversion = \"1.0.0\"\n",
+	})
+	m, err = Load(filepath.Join(dir, ".templatr.toml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Runtimes["node"] != ">=20" {
+		t.Errorf("Runtimes = %v, want node from the fetched parent", m.Runtimes)
+	}
+}
+
+func TestLoad_ExtendsURLCantReadLocalFiles(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "base.toml")
+	if err := os.WriteFile(secret, []byte("[runtimes]\nnode = \">=20\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := serveManifests(t, true, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("extends = [\"" + filepath.ToSlash(secret) + "\"]\n[template]\nname = \"Remote\"\nversion = \"1.0.0\"\n"))
+	})
+
+	_, err := Load(srv.URL + "/.templatr.toml")
+	if err == nil || !strings.Contains(err.Error(), "can't extend a local file") {
+		t.Errorf("Load() error = %v, want a fetched manifest refused a local file", err)
+	}
+}
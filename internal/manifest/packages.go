@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommandTimeout returns how long each package and post-setup command may
+// run, or zero for no limit. An invalid timeout, which Validate reports,
+// also means no limit.
+func (p PackageConfig) CommandTimeout() time.Duration {
+	d, err := time.ParseDuration(p.Timeout)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// SlashDir returns Dir with forward slashes, so a dir written on Windows
+// such as backend\api works everywhere.
+func (p PackageInstall) SlashDir() string {
+	return slashDir(p.Dir)
+}
+
+// WorkDir returns the directory the command runs in: Dir resolved against
+// manifestDir, or manifestDir itself.
+func (p PackageInstall) WorkDir(manifestDir string) string {
+	return workDir(manifestDir, p.Dir)
+}
+
+// projectManagers maps the lockfiles and manifests a project may have to
+// the package manager they imply, most specific first: a pnpm project has
+// a package.json too.
+var projectManagers = []struct {
+	file    string
+	manager string
+}{
+	{"pnpm-lock.yaml", "pnpm"},
+	{"yarn.lock", "yarn"},
+	{"bun.lockb", "bun"},
+	{"bun.lock", "bun"},
+	{"package-lock.json", "npm"},
+	{"package.json", "npm"},
+	{"requirements.txt", "pip"},
+	{"pyproject.toml", "pip"},
+	{"pubspec.yaml", "pub"},
+}
+
+// InferManager returns the package manager for the project in dir, the
+// file it was inferred from and the command that installs the project's
+// packages with it. All three are empty if dir has none of the files.
+func InferManager(dir string) (manager, file, command string) {
+	for _, pm := range projectManagers {
+		path := filepath.Join(dir, pm.file)
+		if !fileExists(path) {
+			continue
+		}
+		return pm.manager, pm.file, defaultInstallCommand(pm.manager, path)
+	}
+	return "", "", ""
+}
+
+// defaultInstallCommand returns the install command for manager, given the
+// file it was inferred from.
+func defaultInstallCommand(manager, path string) string {
+	switch manager {
+	case "pip":
+		if filepath.Base(path) == "requirements.txt" {
+			return "pip install -r requirements.txt"
+		}
+		return "pip install ."
+	case "pub":
+		// Flutter apps need flutter's pub, which resolves the Flutter SDK
+		if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), "sdk: flutter") {
+			return "flutter pub get"
+		}
+		return "dart pub get"
+	}
+	return manager + " install"
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
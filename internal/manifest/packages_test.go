@@ -0,0 +1,34 @@
+package manifest
+
+import "testing"
+
+func TestInferManager(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantManager string
+		wantFile    string
+		wantCommand string
+	}{
+		{"pnpm lockfile", map[string]string{"pnpm-lock.yaml": "", "package.json": "{}"}, "pnpm", "pnpm-lock.yaml", "pnpm install"},
+		{"yarn lockfile", map[string]string{"yarn.lock": "", "package.json": "{}"}, "yarn", "yarn.lock", "yarn install"},
+		{"bun binary lockfile", map[string]string{"bun.lockb": "", "package.json": "{}"}, "bun", "bun.lockb", "bun install"},
+		{"bun text lockfile", map[string]string{"bun.lock": ""}, "bun", "bun.lock", "bun install"},
+		{"npm lockfile", map[string]string{"package-lock.json": "{}", "package.json": "{}"}, "npm", "package-lock.json", "npm install"},
+		{"package.json only", map[string]string{"package.json": "{}"}, "npm", "package.json", "npm install"},
+		{"requirements.txt", map[string]string{"requirements.txt": "flask\n", "pyproject.toml": ""}, "pip", "requirements.txt", "pip install -r requirements.txt"},
+		{"pyproject.toml", map[string]string{"pyproject.toml": "[project]\n"}, "pip", "pyproject.toml", "pip install ."},
+		{"flutter pubspec", map[string]string{"pubspec.yaml": "dependencies:\n  flutter:\n    sdk: flutter\n"}, "pub", "pubspec.yaml", "flutter pub get"},
+		{"dart pubspec", map[string]string{"pubspec.yaml": "name: cli\n"}, "pub", "pubspec.yaml", "dart pub get"},
+		{"nothing to go by", map[string]string{"go.mod": "module x\n"}, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, file, command := InferManager(deepDir(t, tt.files))
+			if manager != tt.wantManager || file != tt.wantFile || command != tt.wantCommand {
+				t.Errorf("InferManager() = %q, %q, %q, want %q, %q, %q",
+					manager, file, command, tt.wantManager, tt.wantFile, tt.wantCommand)
+			}
+		})
+	}
+}
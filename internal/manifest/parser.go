@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +13,8 @@ const DefaultManifestName = ".templatr.toml"
 
 // Load reads and parses a .templatr.toml file from the given path.
 // If path is empty, it looks for .templatr.toml in the current directory.
+// An https:// URL is fetched instead; its manifest's commands run in the
+// current directory. The manifests its extends list names are merged in.
 func Load(path string) (*Manifest, error) {
 	if path == "" {
 		cwd, err := os.Getwd()
@@ -21,27 +24,127 @@ func Load(path string) (*Manifest, error) {
 		path = filepath.Join(cwd, DefaultManifestName)
 	}
 
-	data, err := os.ReadFile(path)
+	data, err := ReadSource(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("manifest file not found: %s\n\nMake sure you're in a Templatr template directory that contains a %s file", path, DefaultManifestName)
-		}
-		return nil, fmt.Errorf("failed to read manifest: %w", err)
+		return nil, err
 	}
 
-	var m Manifest
-	if err := toml.Unmarshal(data, &m); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	m, err := ParseFrom(data, path)
+	if err != nil {
+		return nil, err
 	}
-
-	return &m, nil
+	dir := filepath.Dir(path)
+	if IsURL(path) {
+		m.Source, dir = path, "."
+	}
+	if m.Dir, err = filepath.Abs(dir); err != nil {
+		return nil, fmt.Errorf("failed to resolve manifest directory: %w", err)
+	}
+	return m, nil
 }
 
 // Parse parses raw TOML content into a Manifest.
 func Parse(data []byte) (*Manifest, error) {
 	var m Manifest
-	if err := toml.Unmarshal(data, &m); err != nil {
+	raw := rawManifest{Manifest: &m}
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if err := raw.decode(); err != nil {
 		return nil, fmt.Errorf("failed to parse manifest: %w", err)
 	}
 	return &m, nil
 }
+
+// ParseError turns an error from Load or Parse into a validation result
+// with no path, carrying the line and column of a TOML syntax or type error
+// when the decoder reports one.
+func ParseError(err error) ValidationError {
+	e := ValidationError{Message: err.Error(), Severity: SeverityError}
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		e.Line, e.Column = decodeErr.Position()
+	}
+	return e
+}
+
+// rawManifest decodes the sections that accept more than one form: a
+// [runtimes] entry is a requirement or an OS table such as [runtimes.darwin],
+// and a post_setup command is a string or a table with run, os, dir and
+// required.
+type rawManifest struct {
+	*Manifest
+	Runtimes  map[string]any `toml:"runtimes"`
+	PostSetup struct {
+		Commands []any  `toml:"commands"`
+		Message  string `toml:"message"`
+	} `toml:"post_setup"`
+}
+
+// decode fills in the Manifest's runtimes and post-setup commands.
+func (r *rawManifest) decode() error {
+	m := r.Manifest
+	if r.Runtimes != nil {
+		m.Runtimes = map[string]string{}
+	}
+	for key, value := range r.Runtimes {
+		switch v := value.(type) {
+		case string:
+			m.Runtimes[key] = v
+		case map[string]any:
+			table := make(map[string]string, len(v))
+			for name, req := range v {
+				s, ok := req.(string)
+				if !ok {
+					return fmt.Errorf("runtimes.%s.%s must be a version string", key, name)
+				}
+				table[name] = s
+			}
+			if m.RuntimesOS == nil {
+				m.RuntimesOS = map[string]map[string]string{}
+			}
+			m.RuntimesOS[key] = table
+		default:
+			return fmt.Errorf("runtimes.%s must be a version string or an OS table", key)
+		}
+	}
+
+	m.PostSetup.Message = r.PostSetup.Message
+	for i, value := range r.PostSetup.Commands {
+		switch v := value.(type) {
+		case string:
+			m.PostSetup.Commands = append(m.PostSetup.Commands, Command{Run: v})
+		case map[string]any:
+			run, ok := v["run"].(string)
+			if !ok {
+				return fmt.Errorf("post_setup.commands.%d needs a run string", i)
+			}
+			cmd := Command{Run: run}
+			if osList, ok := v["os"].([]any); ok {
+				for _, o := range osList {
+					s, ok := o.(string)
+					if !ok {
+						return fmt.Errorf("post_setup.commands.%d.os must be a list of strings", i)
+					}
+					cmd.OS = append(cmd.OS, s)
+				}
+			} else if _, ok := v["os"]; ok {
+				return fmt.Errorf("post_setup.commands.%d.os must be a list of strings", i)
+			}
+			if dir, ok := v["dir"]; ok {
+				if cmd.Dir, ok = dir.(string); !ok {
+					return fmt.Errorf("post_setup.commands.%d.dir must be a string", i)
+				}
+			}
+			if required, ok := v["required"]; ok {
+				if cmd.Required, ok = required.(bool); !ok {
+					return fmt.Errorf("post_setup.commands.%d.required must be true or false", i)
+				}
+			}
+			m.PostSetup.Commands = append(m.PostSetup.Commands, cmd)
+		default:
+			return fmt.Errorf("post_setup.commands.%d must be a string or a table with run, os, dir and required", i)
+		}
+	}
+	return nil
+}
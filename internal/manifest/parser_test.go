@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -51,6 +52,9 @@ docs = "https://example.com"
 	if m.Template.Name != "Test Template" {
 		t.Errorf("Template.Name = %q, want %q", m.Template.Name, "Test Template")
 	}
+	if m.Dir != dir {
+		t.Errorf("Dir = %q, want the manifest's directory %q", m.Dir, dir)
+	}
 	if m.Template.Version != "1.0.0" {
 		t.Errorf("Template.Version = %q, want %q", m.Template.Version, "1.0.0")
 	}
@@ -107,6 +111,35 @@ flutter = ">=3.22.0"
 	}
 }
 
+func TestParse_RuntimesChecksums(t *testing.T) {
+	m, err := Parse([]byte(`
+[template]
+name = "Pinned"
+version = "1.0.0"
+
+[runtimes]
+node = "22.14.0"
+
+[runtimes_checksums.node]
+linux-x64 = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+darwin-arm64 = "BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	sums := m.RuntimesChecksums["node"]
+	if len(sums) != 2 {
+		t.Fatalf("len(RuntimesChecksums[node]) = %d, want 2", len(sums))
+	}
+	if sums["linux-x64"] != "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" {
+		t.Errorf("linux-x64 = %q", sums["linux-x64"])
+	}
+	if m.RuntimesChecksums["python"]["linux-x64"] != "" {
+		t.Error("missing runtimes should have no checksum")
+	}
+}
+
 func TestLoad_ConfigFields(t *testing.T) {
 	content := `
 [template]
@@ -175,6 +208,35 @@ func TestLoad_InvalidTOML(t *testing.T) {
 	}
 }
 
+func TestParseError_Position(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		line, column int
+	}{
+		{"syntax", "[template]\nname = \"Test\"\n[runtimes\n", 3, 10},
+		{"type", "[template]\nname = 1\n", 2, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			if err == nil {
+				t.Fatal("Parse() expected error")
+			}
+			e := ParseError(err)
+			if e.Path != "" || e.Severity != SeverityError || e.Line != tt.line || e.Column != tt.column {
+				t.Errorf("ParseError() = %q at %d:%d (path %q), want a pathless error at %d:%d", e.Message, e.Line, e.Column, e.Path, tt.line, tt.column)
+			}
+		})
+	}
+
+	// Errors without a position, such as a missing file, still come through
+	_, err := Load("/nonexistent/path/.templatr.toml")
+	if e := ParseError(err); e.Line != 0 || e.Message != err.Error() {
+		t.Errorf("ParseError() = %q at line %d, want the message without a position", e.Message, e.Line)
+	}
+}
+
 func TestLoad_EmptyFile(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, ".templatr.toml")
@@ -224,3 +286,277 @@ version = "1.0.0"
 		t.Errorf("Template.Name = %q, want %q", m.Template.Name, "Auto Detect")
 	}
 }
+
+func TestParse_RuntimesOSOverrides(t *testing.T) {
+	m, err := Parse([]byte(`
+[template]
+name = "Flutter App"
+version = "1.0.0"
+
+[runtimes]
+flutter = ">=3.19.0"
+java = ">=17"
+
+[runtimes.darwin]
+flutter = "3.24.0"
+ruby = ">=3.1"
+
+[runtimes.windows]
+java = ">=21"
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(m.Runtimes) != 2 || m.Runtimes["flutter"] != ">=3.19.0" {
+		t.Errorf("Runtimes = %v, want only the base entries", m.Runtimes)
+	}
+
+	tests := []struct {
+		goos string
+		want map[string]string
+	}{
+		// The OS table wins over the base entry and can add runtimes
+		{"darwin", map[string]string{"flutter": "3.24.0", "java": ">=17", "ruby": ">=3.1"}},
+		{"windows", map[string]string{"flutter": ">=3.19.0", "java": ">=21"}},
+		{"linux", map[string]string{"flutter": ">=3.19.0", "java": ">=17"}},
+	}
+	for _, tt := range tests {
+		got := m.RuntimesFor(tt.goos)
+		if len(got) != len(tt.want) {
+			t.Errorf("RuntimesFor(%s) = %v, want %v", tt.goos, got, tt.want)
+			continue
+		}
+		for name, req := range tt.want {
+			if got[name] != req {
+				t.Errorf("RuntimesFor(%s)[%s] = %q, want %q", tt.goos, name, got[name], req)
+			}
+		}
+	}
+
+	if m.Runtimes["flutter"] != ">=3.19.0" {
+		t.Error("RuntimesFor should not modify the base runtimes")
+	}
+}
+
+func TestParse_RuntimesInvalidValue(t *testing.T) {
+	if _, err := Parse([]byte("[runtimes]\nnode = 20\n")); err == nil {
+		t.Error("Parse() should reject a non-string requirement")
+	}
+	if _, err := Parse([]byte("[runtimes.darwin]\nnode = 20\n")); err == nil {
+		t.Error("Parse() should reject a non-string requirement in an OS table")
+	}
+}
+
+func TestParse_PostSetupCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"flat", `
+[post_setup]
+commands = ["flutter pub get", "pod install"]
+`},
+		{"mixed", `
+[post_setup]
+commands = ["flutter pub get", {run = "pod install", os = ["darwin"]}]
+`},
+		{"tables", `
+[[post_setup.commands]]
+run = "flutter pub get"
+
+[[post_setup.commands]]
+run = "pod install"
+os = ["darwin"]
+
+[[post_setup.commands]]
+run = "powershell -File setup.ps1"
+os = ["windows"]
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(m.PostSetup.Commands) < 2 || m.PostSetup.Commands[0].Run != "flutter pub get" || m.PostSetup.Commands[1].Run != "pod install" {
+				t.Fatalf("Commands = %+v", m.PostSetup.Commands)
+			}
+			if len(m.PostSetup.Commands[0].OS) != 0 {
+				t.Errorf("plain command should run everywhere, got os %v", m.PostSetup.Commands[0].OS)
+			}
+			if tt.name == "flat" {
+				return
+			}
+			linux := m.PostSetup.CommandsFor("linux")
+			if len(linux) != 1 || linux[0] != "flutter pub get" {
+				t.Errorf("CommandsFor(linux) = %v", linux)
+			}
+			darwin := m.PostSetup.CommandsFor("darwin")
+			if len(darwin) != 2 || darwin[1] != "pod install" {
+				t.Errorf("CommandsFor(darwin) = %v", darwin)
+			}
+		})
+	}
+}
+
+func TestParse_PostSetupCommandErrors(t *testing.T) {
+	for _, content := range []string{
+		"[post_setup]\ncommands = [1]\n",
+		"[[post_setup.commands]]\nos = [\"darwin\"]\n",
+		"[[post_setup.commands]]\nrun = \"pod install\"\nos = \"darwin\"\n",
+		"[[post_setup.commands]]\nrun = \"pod install\"\ndir = 1\n",
+		"[[post_setup.commands]]\nrun = \"pod install\"\nrequired = \"yes\"\n",
+	} {
+		if _, err := Parse([]byte(content)); err == nil {
+			t.Errorf("Parse(%q) should fail", content)
+		}
+	}
+}
+
+func TestParse_PostSetupCommandDir(t *testing.T) {
+	m, err := Parse([]byte(`
+[[post_setup.commands]]
+run = "pod install"
+os = ["darwin"]
+dir = "ios"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := m.PostSetup.Commands[0]
+	if c.Dir != "ios" || c.String() != "pod install (darwin; in ios)" {
+		t.Errorf("command = %+v (%s), want dir ios", c, c)
+	}
+	if m.Dir != "" {
+		t.Errorf("Parse() Dir = %q, want empty for a manifest from memory", m.Dir)
+	}
+}
+
+func TestParse_PostSetupCommandRequired(t *testing.T) {
+	m, err := Parse([]byte(`
+[[post_setup.commands]]
+run = "npx prisma migrate dev"
+required = true
+
+[[post_setup.commands]]
+run = "npm run db:seed"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds := m.PostSetup.Commands
+	if !cmds[0].Required || cmds[1].Required {
+		t.Errorf("commands = %+v, want only the first required", cmds)
+	}
+	if got := cmds[0].String(); got != "npx prisma migrate dev (required)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestCommand_WorkDir(t *testing.T) {
+	base := filepath.Join("home", "me", "site")
+	for _, tt := range []struct{ dir, want string }{
+		{"", base},
+		{"ios", filepath.Join(base, "ios")},
+		{"ios/App", filepath.Join(base, "ios", "App")},
+		{`ios\App`, filepath.Join(base, "ios", "App")},
+		{"../shared", filepath.Join("home", "me", "shared")},
+	} {
+		if got := (Command{Run: "pod install", Dir: tt.dir}).WorkDir(base); got != tt.want {
+			t.Errorf("WorkDir() with dir %q = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestCommand_UnmarshalJSON(t *testing.T) {
+	// Snapshots written before os filters stored commands as strings
+	var ps PostSetup
+	if err := json.Unmarshal([]byte(`{"Commands": ["npm run build", {"Run": "pod install", "OS": ["darwin"]}]}`), &ps); err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.Commands) != 2 || ps.Commands[0].Run != "npm run build" || ps.Commands[1].String() != "pod install (darwin)" {
+		t.Errorf("Commands = %+v", ps.Commands)
+	}
+}
+
+func TestParse_PackagesInstall(t *testing.T) {
+	m, err := Parse([]byte(`
+[packages]
+manager = "pnpm"
+
+[[packages.install]]
+dir = "frontend"
+command = "pnpm install"
+
+[[packages.install]]
+dir = 'backend\api'
+command = "pip install -r requirements.txt"
+manager = "pip"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PackageInstall{
+		{Dir: "frontend", Command: "pnpm install"},
+		{Dir: `backend\api`, Command: "pip install -r requirements.txt", Manager: "pip"},
+	}
+	if len(m.Packages.Install) != len(want) {
+		t.Fatalf("Install = %+v, want %+v", m.Packages.Install, want)
+	}
+	for i, entry := range m.Packages.Install {
+		if entry != want[i] {
+			t.Errorf("Install[%d] = %+v, want %+v", i, entry, want[i])
+		}
+	}
+	base := filepath.Join("home", "me", "app")
+	if got := m.Packages.Install[1].WorkDir(base); got != filepath.Join(base, "backend", "api") {
+		t.Errorf("WorkDir() = %q, want backend/api under the manifest's directory", got)
+	}
+	if m.Packages.InstallCommand != "" {
+		t.Errorf("InstallCommand = %q, want it left empty", m.Packages.InstallCommand)
+	}
+}
+
+func TestParse_FieldRules(t *testing.T) {
+	m, err := Parse([]byte(`
+[template]
+name = "Test"
+version = "1.0.0"
+
+[[env]]
+key = "STRIPE_SECRET_KEY"
+type = "secret"
+pattern = "^sk_(test|live)_"
+
+[[env]]
+key = "PORT"
+type = "number"
+min = 1
+max = 65535
+
+[[config]]
+file = "site.json"
+
+[[config.fields]]
+path = "ratio"
+type = "number"
+min = 0.5
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := m.Env[0].Pattern; got != "^sk_(test|live)_" {
+		t.Errorf("Env[0].Pattern = %q", got)
+	}
+	if port := m.Env[1]; port.Min == nil || *port.Min != 1 || port.Max == nil || *port.Max != 65535 {
+		t.Errorf("PORT bounds = %v, %v; want 1 and 65535", port.Min, port.Max)
+	}
+	if f := m.Config[0].Fields[0]; f.Min == nil || *f.Min != 0.5 || f.Max != nil {
+		t.Errorf("ratio bounds = %v, %v; want 0.5 and none", f.Min, f.Max)
+	}
+	if m.Env[0].Min != nil {
+		t.Errorf("Env[0].Min = %v, want nil when unset", *m.Env[0].Min)
+	}
+}
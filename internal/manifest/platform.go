@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// validOS is the set of operating systems accepted in [runtimes.<os>]
+// tables and post-setup command os lists, as reported by runtime.GOOS.
+var validOS = map[string]bool{
+	"darwin":  true,
+	"linux":   true,
+	"windows": true,
+}
+
+// RuntimesFor returns the runtime requirements on goos: the base
+// [runtimes] entries with that OS's table, e.g. [runtimes.darwin], merged
+// over them.
+func (m *Manifest) RuntimesFor(goos string) map[string]string {
+	merged := make(map[string]string, len(m.Runtimes))
+	for name, req := range m.Runtimes {
+		merged[name] = req
+	}
+	for name, req := range m.RuntimesOS[goos] {
+		merged[name] = req
+	}
+	return merged
+}
+
+// declaresRuntime reports whether the runtime appears in [runtimes] or any
+// OS table.
+func (m *Manifest) declaresRuntime(name string) bool {
+	if _, ok := m.Runtimes[name]; ok {
+		return true
+	}
+	for _, table := range m.RuntimesOS {
+		if _, ok := table[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandsFor returns the post-setup commands that run on goos, in order.
+func (p PostSetup) CommandsFor(goos string) []string {
+	var cmds []string
+	for _, c := range p.Commands {
+		if c.AppliesTo(goos) {
+			cmds = append(cmds, c.Run)
+		}
+	}
+	return cmds
+}
+
+// AppliesTo reports whether the command runs on goos.
+func (c Command) AppliesTo(goos string) bool {
+	return len(c.OS) == 0 || slices.Contains(c.OS, goos)
+}
+
+// String returns the command with its OS filter and directory, e.g.
+// "pod install (darwin; in ios)".
+func (c Command) String() string {
+	var notes []string
+	if len(c.OS) > 0 {
+		notes = append(notes, strings.Join(c.OS, ", "))
+	}
+	if c.Dir != "" {
+		notes = append(notes, "in "+c.Dir)
+	}
+	if c.Required {
+		notes = append(notes, "required")
+	}
+	if len(notes) == 0 {
+		return c.Run
+	}
+	return c.Run + " (" + strings.Join(notes, "; ") + ")"
+}
+
+// SlashDir returns Dir with forward slashes, so a dir written on Windows
+// such as ios\App works everywhere.
+func (c Command) SlashDir() string {
+	return slashDir(c.Dir)
+}
+
+// WorkDir returns the directory the command runs in: Dir resolved against
+// manifestDir, or manifestDir itself.
+func (c Command) WorkDir(manifestDir string) string {
+	return workDir(manifestDir, c.Dir)
+}
+
+func slashDir(dir string) string {
+	return strings.ReplaceAll(dir, `\`, "/")
+}
+
+func workDir(manifestDir, dir string) string {
+	if dir == "" {
+		return manifestDir
+	}
+	return filepath.Join(manifestDir, filepath.FromSlash(slashDir(dir)))
+}
+
+// isAbsDir reports whether dir, in either slash style, is absolute on
+// some OS, which a dir relative to the manifest must not be.
+func isAbsDir(dir string) bool {
+	dir = slashDir(dir)
+	return strings.HasPrefix(dir, "/") || (len(dir) > 1 && dir[1] == ':')
+}
+
+// UnmarshalJSON accepts a plain string as well as the object form, so
+// snapshots saved before commands had an os filter still load.
+func (c *Command) UnmarshalJSON(data []byte) error {
+	var run string
+	if err := json.Unmarshal(data, &run); err == nil {
+		*c = Command{Run: run}
+		return nil
+	}
+	type command Command
+	return json.Unmarshal(data, (*command)(c))
+}
@@ -0,0 +1,144 @@
+package manifest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// RequirementAuto is the [runtimes] value that reads the requirement from
+// the project's own version files, e.g. node = "auto".
+const RequirementAuto = "auto"
+
+// requirementFilePrefix names a specific project file to read the
+// requirement from, e.g. node = "file:.nvmrc".
+const requirementFilePrefix = "file:"
+
+// projectVersionFiles lists, per runtime, the project files "auto" looks
+// at, in order of preference.
+var projectVersionFiles = map[string][]string{
+	"node":   {"package.json", ".nvmrc", ".node-version"},
+	"python": {".python-version"},
+	"go":     {"go.mod"},
+}
+
+// FromProject reports whether a [runtimes] value reads its requirement
+// from a project file ("auto" or "file:<path>").
+func FromProject(required string) bool {
+	return required == RequirementAuto || strings.HasPrefix(required, requirementFilePrefix)
+}
+
+// ResolveRequirement turns an "auto" or "file:<path>" requirement for the
+// named runtime into a version constraint read from the project in dir.
+// It returns the constraint and the file it came from, relative to dir.
+// Any other requirement is returned unchanged with an empty source.
+func ResolveRequirement(name, required, dir string) (constraint, source string, err error) {
+	if !FromProject(required) {
+		return required, "", nil
+	}
+
+	var candidates []string
+	if file, ok := strings.CutPrefix(required, requirementFilePrefix); ok {
+		if file == "" {
+			return "", "", fmt.Errorf("%q does not name a file", required)
+		}
+		candidates = []string{file}
+	} else {
+		candidates = projectVersionFiles[name]
+		if len(candidates) == 0 {
+			return "", "", fmt.Errorf("%q is not supported for %s - use a version or \"file:<path>\"", RequirementAuto, name)
+		}
+	}
+
+	for _, file := range candidates {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if os.IsNotExist(err) && len(candidates) > 1 {
+			continue
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		constraint, err := parseProjectFile(name, file, data)
+		if err != nil {
+			if len(candidates) > 1 {
+				continue // e.g. a package.json without engines.node
+			}
+			return "", "", fmt.Errorf("%s: %w", file, err)
+		}
+		return constraint, file, nil
+	}
+	return "", "", fmt.Errorf("no %s version found in %s", name, strings.Join(candidates, ", "))
+}
+
+// parseProjectFile reads the runtime's requirement from file, choosing the
+// format by file name.
+func parseProjectFile(name, file string, data []byte) (string, error) {
+	switch filepath.Base(file) {
+	case "package.json":
+		return ParsePackageJSONEngines(data, name)
+	case "go.mod":
+		return ParseGoModVersion(data)
+	default:
+		return ParseVersionFile(data)
+	}
+}
+
+// ParsePackageJSONEngines returns the "engines" entry for the runtime in a
+// package.json, e.g. ">=20" for "engines": {"node": ">=20"}.
+func ParsePackageJSONEngines(data []byte, name string) (string, error) {
+	var pkg struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	constraint := strings.TrimSpace(pkg.Engines[name])
+	if constraint == "" {
+		return "", fmt.Errorf("no engines.%s entry", name)
+	}
+	return checkConstraint(constraint)
+}
+
+// ParseVersionFile returns the version in an .nvmrc, .node-version or
+// .python-version file: the first line that isn't blank or a comment, with
+// any "v" prefix removed. The aliases "node" and "stable" mean "latest".
+func ParseVersionFile(data []byte) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "node" || line == "stable" {
+			return "latest", nil
+		}
+		return checkConstraint(strings.TrimPrefix(line, "v"))
+	}
+	return "", fmt.Errorf("no version found")
+}
+
+// ParseGoModVersion returns the minimum Go version from a go.mod's go
+// directive, e.g. ">=1.22" for "go 1.22".
+func ParseGoModVersion(data []byte) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "go" {
+			return checkConstraint(">=" + fields[1])
+		}
+	}
+	return "", fmt.Errorf("no go directive")
+}
+
+// checkConstraint returns c if it parses as a version constraint.
+func checkConstraint(c string) (string, error) {
+	if _, err := semver.NewConstraint(c); err != nil {
+		return "", fmt.Errorf("unsupported version %q", c)
+	}
+	return c, nil
+}
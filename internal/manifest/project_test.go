@@ -0,0 +1,178 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProjectFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParsePackageJSONEngines(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{`{"engines": {"node": ">=20"}}`, ">=20", false},
+		{`{"engines": {"node": ">=18 <21"}}`, ">=18 <21", false},
+		{`{"engines": {"node": "^20.0.0 || >=22"}}`, "^20.0.0 || >=22", false},
+		{`{"name": "app"}`, "", true},
+		{`{"engines": {"npm": ">=10"}}`, "", true},
+		{`{"engines": {"node": "not a version"}}`, "", true},
+		{`{not json`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePackageJSONEngines([]byte(tt.data), "node")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePackageJSONEngines(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePackageJSONEngines(%s) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersionFile(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"20\n", "20", false},
+		{"v22.14.0", "22.14.0", false},
+		{"# pinned for CI\n\n3.12.4\n", "3.12.4", false},
+		{"  3.12  \r\n", "3.12", false},
+		{"node\n", "latest", false},
+		{"lts/iron\n", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersionFile([]byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersionFile(%q) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersionFile(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseGoModVersion(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"module example.com/app\n\ngo 1.22\n", ">=1.22", false},
+		{"module example.com/app\n\ngo 1.23.4\n\ntoolchain go1.24.0\n", ">=1.23.4", false},
+		{"module example.com/app\n", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseGoModVersion([]byte(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGoModVersion(%q) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseGoModVersion(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRequirement(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "package.json", `{"engines": {"node": ">=20"}}`)
+	writeProjectFile(t, dir, ".nvmrc", "22.14.0\n")
+	writeProjectFile(t, dir, ".python-version", "3.12\n")
+
+	tests := []struct {
+		name, required   string
+		want, wantSource string
+	}{
+		{"node", ">=18", ">=18", ""},
+		{"node", "auto", ">=20", "package.json"},
+		{"node", "file:.nvmrc", "22.14.0", ".nvmrc"},
+		{"python", "auto", "3.12", ".python-version"},
+	}
+	for _, tt := range tests {
+		got, source, err := ResolveRequirement(tt.name, tt.required, dir)
+		if err != nil {
+			t.Errorf("ResolveRequirement(%s, %q) error: %v", tt.name, tt.required, err)
+			continue
+		}
+		if got != tt.want || source != tt.wantSource {
+			t.Errorf("ResolveRequirement(%s, %q) = %q, %q, want %q, %q", tt.name, tt.required, got, source, tt.want, tt.wantSource)
+		}
+	}
+}
+
+func TestResolveRequirement_FallsThroughCandidates(t *testing.T) {
+	dir := t.TempDir()
+	// package.json without engines, so .node-version is used
+	writeProjectFile(t, dir, "package.json", `{"name": "app"}`)
+	writeProjectFile(t, dir, ".node-version", "v20.11.1\n")
+
+	got, source, err := ResolveRequirement("node", "auto", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "20.11.1" || source != ".node-version" {
+		t.Errorf("ResolveRequirement() = %q, %q, want 20.11.1, .node-version", got, source)
+	}
+}
+
+func TestResolveRequirement_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, required string
+	}{
+		{"node", "auto"},        // no project files
+		{"go", "auto"},          // no go.mod
+		{"rust", "auto"},        // no known files for rust
+		{"node", "file:.nvmrc"}, // named file missing
+		{"node", "file:"},       // no file named
+	}
+	for _, tt := range tests {
+		if _, _, err := ResolveRequirement(tt.name, tt.required, dir); err == nil {
+			t.Errorf("ResolveRequirement(%s, %q) should fail", tt.name, tt.required)
+		}
+	}
+}
+
+func TestValidate_AutoRequirementMissingFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Test", Version: "1.0.0"},
+		Runtimes: map[string]string{"node": "auto"},
+	}
+
+	errs := Validate(m)
+	if len(errs) != 1 {
+		t.Fatalf("Validate() returned %d results, want 1: %v", len(errs), errs)
+	}
+	if errs[0].Severity != SeverityWarning || errs[0].Path != "runtimes.node" {
+		t.Errorf("got %+v, want a warning for runtimes.node", errs[0])
+	}
+	if HasErrors(errs) {
+		t.Error("a missing version file should not block setup")
+	}
+
+	writeProjectFile(t, ".", ".nvmrc", "22\n")
+	if errs := Validate(m); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no results once .nvmrc exists", errs)
+	}
+
+	// Read from the manifest's directory, not the working directory
+	m.Dir = t.TempDir()
+	if errs := Validate(m); len(errs) != 1 {
+		t.Errorf("Validate() = %v, want the warning for a manifest in a directory without .nvmrc", errs)
+	}
+}
@@ -0,0 +1,110 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Limits on a manifest fetched from a URL.
+const (
+	remoteTimeout   = 30 * time.Second
+	MaxManifestSize = 1 << 20 // 1 MB; real manifests are a few KB
+)
+
+// ErrFetch is returned (wrapped) when a manifest URL can't be fetched.
+var ErrFetch = errors.New("failed to fetch manifest")
+
+var (
+	remoteClient  = &http.Client{Timeout: remoteTimeout, CheckRedirect: checkRedirect}
+	allowInsecure bool // plain http:// URLs are accepted (--insecure-manifest)
+)
+
+// SetAllowInsecure sets whether manifests may be fetched over plain
+// http://. They can't by default, since anyone on the network could swap
+// the commands setup runs.
+func SetAllowInsecure(allow bool) {
+	allowInsecure = allow
+}
+
+// IsURL reports whether a manifest path is an http:// or https:// URL
+// rather than a file.
+func IsURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// Redact returns rawURL with any password in it masked, for logs. Values
+// that aren't URLs are returned as they are.
+func Redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// ReadSource returns the manifest at path, fetching it if path is a URL.
+func ReadSource(path string) ([]byte, error) {
+	if IsURL(path) {
+		return fetch(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("manifest file not found: %s\n\nMake sure you're in a Templatr template directory that contains a %s file", path, DefaultManifestName)
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return data, nil
+}
+
+// fetch downloads the manifest at rawURL, refusing plain http unless
+// allowed and bodies over MaxManifestSize.
+func fetch(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest URL: %w", err)
+	}
+	if u.Scheme == "http" && !allowInsecure {
+		return nil, fmt.Errorf("refusing to fetch a manifest over plain http: %s - use https, or --insecure-manifest if you trust the network", u.Redacted())
+	}
+
+	resp, err := remoteClient.Get(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFetch, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned %s", ErrFetch, u.Redacted(), resp.Status)
+	}
+	if resp.ContentLength > MaxManifestSize {
+		return nil, fmt.Errorf("manifest at %s is %d bytes, larger than the %d byte limit", u.Redacted(), resp.ContentLength, MaxManifestSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFetch, err)
+	}
+	if len(data) > MaxManifestSize {
+		return nil, fmt.Errorf("manifest at %s is larger than the %d byte limit", u.Redacted(), MaxManifestSize)
+	}
+	return data, nil
+}
+
+// checkRedirect applies fetch's plain-http refusal to redirects too, so an
+// https URL can't hand the request on to http://.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != "https" && !allowInsecure {
+		return fmt.Errorf("refusing to follow a redirect to %s - manifests are only fetched over https, use --insecure-manifest if you trust the network", req.URL.Redacted())
+	}
+	return nil
+}
@@ -0,0 +1,553 @@
+package manifest
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Scaffold is a starter manifest inferred from a project directory, kept
+// in the sections init asks the author to confirm one at a time.
+type Scaffold struct {
+	Template TemplateInfo
+	Runtimes []ScaffoldRuntime
+	Packages PackageConfig
+	Env      []EnvVar
+
+	PackagesFrom string // file the package manager was inferred from
+	EnvFrom      string // example env file the env entries were read from
+}
+
+// ScaffoldRuntime is a runtime the project needs, with the file its
+// requirement was read from. An empty Source means none said, and the
+// requirement is "latest".
+type ScaffoldRuntime struct {
+	Name        string
+	Requirement string
+	Source      string
+}
+
+// envExampleFiles are the files env entries are read from, in order of
+// preference.
+var envExampleFiles = []string{".env.example", ".env.sample", ".env.template"}
+
+// NewScaffold inspects the project in dir - package.json, pyproject.toml,
+// pubspec.yaml, go.mod, version files, lockfiles and an example env file -
+// and returns the manifest it suggests.
+func NewScaffold(dir string) (*Scaffold, error) {
+	p, err := readProject(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scaffold{}
+	name := humanize(p.name)
+	if name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+		}
+		name = humanize(filepath.Base(abs))
+	}
+	s.SetName(name)
+	s.Template.Version = p.version
+	if s.Template.Version == "" {
+		s.Template.Version = "1.0.0"
+	}
+
+	s.Runtimes = scaffoldRuntimes(dir, p)
+	if manager, file, command := InferManager(dir); manager != "" {
+		s.Packages = PackageConfig{Manager: manager, InstallCommand: command}
+		s.PackagesFrom = file
+	}
+
+	for _, name := range envExampleFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		s.Env = ParseEnvExample(data)
+		s.EnvFrom = name
+		break
+	}
+	return s, nil
+}
+
+// SetName sets the template's name and the slug made from it.
+func (s *Scaffold) SetName(name string) {
+	s.Template.Name = name
+	s.Template.Slug = slugify(name)
+}
+
+// project is what the project's own manifests say about it.
+type project struct {
+	name, version string
+	python        string // requires-python, or poetry's python dependency
+	flutter       string // pubspec.yaml environment.flutter
+	pubspec       bool
+}
+
+// readProject reads the name, version and runtime requirements from the
+// first of package.json, pyproject.toml and pubspec.yaml that has them,
+// falling back to the last element of go.mod's module path for the name.
+func readProject(dir string) (project, error) {
+	var p project
+
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return p, fmt.Errorf("package.json: invalid JSON: %w", err)
+		}
+		// Drop the scope of "@acme/landing"
+		p.name = pkg.Name[strings.LastIndex(pkg.Name, "/")+1:]
+		p.version = pkg.Version
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "pyproject.toml")); err == nil {
+		var py struct {
+			Project struct {
+				Name           string `toml:"name"`
+				Version        string `toml:"version"`
+				RequiresPython string `toml:"requires-python"`
+			} `toml:"project"`
+			Tool struct {
+				Poetry struct {
+					Name         string         `toml:"name"`
+					Version      string         `toml:"version"`
+					Dependencies map[string]any `toml:"dependencies"`
+				} `toml:"poetry"`
+			} `toml:"tool"`
+		}
+		if err := toml.Unmarshal(data, &py); err != nil {
+			return p, fmt.Errorf("pyproject.toml: %w", err)
+		}
+		p.python = pep440Constraint(py.Project.RequiresPython)
+		if v, ok := py.Tool.Poetry.Dependencies["python"].(string); ok && p.python == "" {
+			p.python = v
+		}
+		p.name = cmp.Or(p.name, py.Project.Name, py.Tool.Poetry.Name)
+		p.version = cmp.Or(p.version, py.Project.Version, py.Tool.Poetry.Version)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "pubspec.yaml")); err == nil {
+		p.pubspec = true
+		name, version, flutter := parsePubspec(data)
+		p.flutter = flutter
+		p.name = cmp.Or(p.name, name)
+		// A pub version may carry a build number, as in 1.2.0+4
+		p.version = cmp.Or(p.version, strings.SplitN(version, "+", 2)[0])
+	}
+
+	if p.name == "" {
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+					module = strings.Trim(strings.TrimSpace(module), `"`)
+					p.name = module[strings.LastIndex(module, "/")+1:]
+					break
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// scaffoldRuntimes returns the runtimes the project's files point to, with
+// requirements read the way "auto" reads them when there is one.
+func scaffoldRuntimes(dir string, p project) []ScaffoldRuntime {
+	var runtimes []ScaffoldRuntime
+	add := func(name, requirement, source string) {
+		if _, err := checkConstraint(requirement); err != nil || requirement == "" {
+			requirement, source = "latest", ""
+		}
+		runtimes = append(runtimes, ScaffoldRuntime{Name: name, Requirement: requirement, Source: source})
+	}
+	exists := func(files ...string) bool {
+		for _, file := range files {
+			if fileExists(filepath.Join(dir, file)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if exists(projectVersionFiles["node"]...) {
+		constraint, source, _ := ResolveRequirement("node", RequirementAuto, dir)
+		add("node", constraint, source)
+	}
+	if exists("pyproject.toml", "requirements.txt", ".python-version") {
+		if p.python != "" {
+			add("python", p.python, "pyproject.toml")
+		} else {
+			constraint, source, _ := ResolveRequirement("python", RequirementAuto, dir)
+			add("python", constraint, source)
+		}
+	}
+	if p.pubspec {
+		add("flutter", p.flutter, "pubspec.yaml")
+	}
+	if exists("go.mod") {
+		constraint, source, _ := ResolveRequirement("go", RequirementAuto, dir)
+		add("go", constraint, source)
+	}
+	return runtimes
+}
+
+// pep440Constraint converts a Python version specifier such as
+// ">=3.10,<4" or "~=3.11" to a version constraint, or returns "" if it
+// can't.
+func pep440Constraint(spec string) string {
+	var parts []string
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ReplaceAll(strings.TrimSpace(part), " ", "")
+		switch {
+		case part == "":
+			continue
+		case strings.HasPrefix(part, "~="):
+			// ~=3.11 allows any 3.x from 3.11; ~=3.11.2 any 3.11.x from 3.11.2
+			version := part[2:]
+			if strings.Count(version, ".") >= 2 {
+				part = "~" + version
+			} else {
+				part = "^" + version
+			}
+		case strings.HasPrefix(part, "==="):
+			part = "=" + part[3:]
+		case strings.HasPrefix(part, "=="):
+			part = part[2:]
+		}
+		parts = append(parts, part)
+	}
+	c, err := checkConstraint(strings.Join(parts, ", "))
+	if err != nil {
+		return ""
+	}
+	return c
+}
+
+// parsePubspec reads the top-level name and version of a pubspec.yaml and
+// the flutter entry of its environment block, without a YAML parser.
+func parsePubspec(data []byte) (name, version, flutter string) {
+	inEnvironment := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		if line[0] != ' ' && line[0] != '\t' {
+			inEnvironment = key == "environment"
+			switch key {
+			case "name":
+				name = value
+			case "version":
+				version = value
+			}
+			continue
+		}
+		if inEnvironment && key == "flutter" {
+			flutter = value
+		}
+	}
+	return name, version, flutter
+}
+
+// ParseEnvExample turns the KEY=value lines of an example env file into
+// env entries. The comment lines just above a key become its description,
+// and the key and example value suggest its label, type and whether it is
+// required: a key left blank must be filled in. Values of secrets are not
+// copied.
+func ParseEnvExample(data []byte) []EnvVar {
+	var (
+		vars    []EnvVar
+		comment []string
+		seen    = make(map[string]bool)
+	)
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			comment = nil
+			continue
+		}
+		if text, ok := strings.CutPrefix(line, "#"); ok {
+			text = strings.TrimSpace(text)
+			// Skip rulers such as "# -----" and "# ====="
+			if strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+				comment = append(comment, text)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || seen[key] {
+			comment = nil
+			continue
+		}
+		seen[key] = true
+
+		v := EnvVar{
+			Key:         key,
+			Label:       envLabel(key),
+			Description: strings.Join(comment, " "),
+			Default:     envExampleValue(value),
+		}
+		v.Type = envType(key, v.Default)
+		if v.Type == "secret" {
+			v.Default = ""
+		}
+		v.Required = v.Default == ""
+		vars = append(vars, v)
+		comment = nil
+	}
+	return vars
+}
+
+// envExampleValue unquotes an env file value, or strips a trailing comment
+// from an unquoted one.
+func envExampleValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// envType guesses the field type of an env key from its name and example
+// value.
+func envType(key, value string) string {
+	upper := strings.ToUpper(key)
+	lower := strings.ToLower(value)
+	for _, word := range []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "PRIVATE"} {
+		if strings.Contains(upper, word) {
+			return "secret"
+		}
+	}
+	switch {
+	case strings.HasSuffix(upper, "_KEY"):
+		return "secret"
+	case strings.Contains(value, "://") || strings.HasSuffix(upper, "_URL") || strings.HasSuffix(upper, "_URI"):
+		return "url"
+	case strings.Contains(upper, "EMAIL") || (strings.Contains(value, "@") && !strings.ContainsAny(value, " /:")):
+		return "email"
+	case lower == "true" || lower == "false":
+		return "boolean"
+	case value != "" && strings.Trim(value, "0123456789") == "":
+		return "number"
+	}
+	return "text"
+}
+
+// envPrefixes are framework prefixes left out of env labels, so
+// NEXT_PUBLIC_SITE_URL is labelled "Site URL".
+var envPrefixes = []string{"NEXT_PUBLIC_", "VITE_", "REACT_APP_", "NUXT_PUBLIC_", "PUBLIC_", "EXPO_PUBLIC_"}
+
+// acronyms are words kept upper case in labels.
+var acronyms = map[string]bool{
+	"API": true, "AWS": true, "CDN": true, "DB": true, "DSN": true, "HTTP": true,
+	"ID": true, "JWT": true, "S3": true, "SMTP": true, "SSL": true, "URI": true, "URL": true,
+}
+
+// envLabel turns an env key such as DATABASE_URL into "Database URL".
+func envLabel(key string) string {
+	upper := strings.ToUpper(key)
+	for _, prefix := range envPrefixes {
+		if rest, ok := strings.CutPrefix(upper, prefix); ok && rest != "" {
+			key = key[len(prefix):]
+			break
+		}
+	}
+	words := strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' || r == '.' })
+	for i, word := range words {
+		if acronyms[strings.ToUpper(word)] {
+			words[i] = strings.ToUpper(word)
+		} else {
+			words[i] = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// humanize turns a package or folder name such as "my_saas-app" into a
+// display name, "My Saas App". Names that already have spaces or capitals
+// are kept.
+func humanize(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.ContainsAny(name, " ") || strings.ToLower(name) != name {
+		return name
+	}
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// slugify turns a display name into a lower-case, dash-separated slug.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// Sections a scaffold is rendered in, in order.
+const (
+	SectionTemplate = "template"
+	SectionRuntimes = "runtimes"
+	SectionPackages = "packages"
+	SectionEnv      = "env"
+)
+
+// Sections returns the sections the scaffold has content for.
+func (s *Scaffold) Sections() []string {
+	var sections []string
+	for _, name := range []string{SectionTemplate, SectionRuntimes, SectionPackages, SectionEnv} {
+		if s.Section(name) != "" {
+			sections = append(sections, name)
+		}
+	}
+	return sections
+}
+
+// Section renders one section as TOML, or returns "" if it is empty.
+func (s *Scaffold) Section(name string) string {
+	var b strings.Builder
+	switch name {
+	case SectionTemplate:
+		b.WriteString("[template]\n")
+		fmt.Fprintf(&b, "name = %s\n", tomlString(s.Template.Name))
+		fmt.Fprintf(&b, "version = %s\n", tomlString(s.Template.Version))
+		if s.Template.Slug != "" {
+			fmt.Fprintf(&b, "slug = %s\n", tomlString(s.Template.Slug))
+		}
+
+	case SectionRuntimes:
+		if len(s.Runtimes) == 0 {
+			return ""
+		}
+		b.WriteString("[runtimes]\n")
+		for _, rt := range s.Runtimes {
+			fmt.Fprintf(&b, "%s = %s", rt.Name, tomlString(rt.Requirement))
+			if rt.Source != "" {
+				fmt.Fprintf(&b, " # from %s", rt.Source)
+			} else {
+				b.WriteString(" # nothing in the project says which version - set a minimum")
+			}
+			b.WriteByte('\n')
+		}
+
+	case SectionPackages:
+		if s.Packages.Manager == "" {
+			return ""
+		}
+		if s.PackagesFrom != "" {
+			fmt.Fprintf(&b, "# Inferred from %s\n", s.PackagesFrom)
+		}
+		b.WriteString("[packages]\n")
+		fmt.Fprintf(&b, "manager = %s\n", tomlString(s.Packages.Manager))
+		fmt.Fprintf(&b, "install_command = %s\n", tomlString(s.Packages.InstallCommand))
+
+	case SectionEnv:
+		if len(s.Env) == 0 {
+			return ""
+		}
+		if s.EnvFrom != "" {
+			fmt.Fprintf(&b, "# Read from %s\n", s.EnvFrom)
+		}
+		for i, env := range s.Env {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString("[[env]]\n")
+			fmt.Fprintf(&b, "key = %s\n", tomlString(env.Key))
+			fmt.Fprintf(&b, "label = %s\n", tomlString(env.Label))
+			if env.Description != "" {
+				fmt.Fprintf(&b, "description = %s\n", tomlString(env.Description))
+			}
+			fmt.Fprintf(&b, "default = %s\n", tomlString(env.Default))
+			fmt.Fprintf(&b, "required = %t\n", env.Required)
+			fmt.Fprintf(&b, "type = %s\n", tomlString(env.Type))
+		}
+	}
+	return b.String()
+}
+
+// Render returns the scaffold as a .templatr.toml, with a header pointing
+// at what to check next.
+func (s *Scaffold) Render() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# .templatr.toml - %s\n", s.Template.Name)
+	b.WriteString("# Generated by templatr-setup init. Review each section, then run\n")
+	b.WriteString("# 'templatr-setup validate --deep'. Docs: https://templatr.io/tools/setup\n")
+	for _, name := range s.Sections() {
+		b.WriteByte('\n')
+		b.WriteString(s.Section(name))
+	}
+	return []byte(b.String())
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package manifest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateScaffold = flag.Bool("update", false, "rewrite the scaffold golden files")
+
+// TestNewScaffold_Fixtures scaffolds each project under testdata/scaffold
+// and compares the result with its want.templatr.toml. Run with -update to
+// rewrite them.
+func TestNewScaffold_Fixtures(t *testing.T) {
+	fixtures, err := os.ReadDir(filepath.Join("testdata", "scaffold"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name(), func(t *testing.T) {
+			dir := filepath.Join("testdata", "scaffold", fixture.Name())
+			s, err := NewScaffold(dir)
+			if err != nil {
+				t.Fatalf("NewScaffold() error: %s", err)
+			}
+			got := s.Render()
+
+			golden := filepath.Join(dir, "want.templatr.toml")
+			if *updateScaffold {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+			}
+
+			// What init writes must load and validate
+			m, err := Parse(got)
+			if err != nil {
+				t.Fatalf("Parse() error: %s", err)
+			}
+			if errs := Validate(m); HasErrors(errs) {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+}
+
+func TestPEP440Constraint(t *testing.T) {
+	tests := map[string]string{
+		">=3.10":        ">=3.10",
+		">=3.10, <4":    ">=3.10, <4",
+		"~=3.11":        "^3.11",
+		"~=3.11.2":      "~3.11.2",
+		"==3.12.*":      "3.12.*",
+		"":              "",
+		"not a version": "",
+	}
+	for spec, want := range tests {
+		if got := pep440Constraint(spec); got != want {
+			t.Errorf("pep440Constraint(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestParseEnvExample_QuotesAndDuplicates(t *testing.T) {
+	vars := ParseEnvExample([]byte("GREETING='hello # world'\nGREETING=again\nnot an assignment\nAPP_ID=42\n"))
+	if len(vars) != 2 {
+		t.Fatalf("ParseEnvExample() = %+v, want GREETING once and APP_ID", vars)
+	}
+	if vars[0].Default != "hello # world" || vars[0].Type != "text" {
+		t.Errorf("GREETING = %+v, want the quoted value kept whole", vars[0])
+	}
+	if vars[1].Label != "App ID" || vars[1].Type != "number" {
+		t.Errorf("APP_ID = %+v, want label %q and type number", vars[1], "App ID")
+	}
+}
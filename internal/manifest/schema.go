@@ -2,13 +2,27 @@ package manifest
 
 // Manifest represents the full .templatr.toml file structure.
 type Manifest struct {
-	Template  TemplateInfo      `toml:"template"`
-	Runtimes  map[string]string `toml:"runtimes"`
-	Packages  PackageConfig     `toml:"packages"`
-	Env       []EnvVar          `toml:"env"`
-	Config    []ConfigFile      `toml:"config"`
-	PostSetup PostSetup         `toml:"post_setup"`
-	Meta      Meta              `toml:"meta"`
+	Extends           []string                     `toml:"extends,omitempty"` // manifests this one is merged over, as paths relative to it or URLs
+	Template          TemplateInfo                 `toml:"template"`
+	Runtimes          map[string]string            `toml:"runtimes"`
+	RuntimesOS        map[string]map[string]string `toml:"-"`                            // [runtimes.darwin] etc: GOOS -> runtime -> requirement, merged over Runtimes
+	RuntimesChecksums map[string]map[string]string `toml:"runtimes_checksums,omitempty"` // runtime -> platform key ("linux-x64") -> sha256
+	Packages          PackageConfig                `toml:"packages"`
+	Env               []EnvVar                     `toml:"env"`
+	EnvEnvironments   EnvEnvironments              `toml:"env_environments,omitempty"`
+	EnvOptions        EnvOptions                   `toml:"env_options,omitempty"`
+	Config            []ConfigFile                 `toml:"config"`
+	Downloads         []Download                   `toml:"downloads,omitempty"`
+	PostSetup         PostSetup                    `toml:"post_setup"`
+	Meta              Meta                         `toml:"meta"`
+
+	// Dir is the directory the manifest was loaded from, where commands
+	// run. Empty for a manifest parsed from memory, meaning the process's
+	// working directory.
+	Dir string `toml:"-" json:"-"`
+
+	// Source is the URL the manifest was fetched from; empty for a file.
+	Source string `toml:"-" json:"-"`
 }
 
 // TemplateInfo identifies the template.
@@ -22,9 +36,20 @@ type TemplateInfo struct {
 
 // PackageConfig defines the package manager and install command.
 type PackageConfig struct {
-	Manager        string   `toml:"manager"`
-	InstallCommand string   `toml:"install_command"`
-	Global         []string `toml:"global,omitempty"`
+	Manager        string           `toml:"manager"`
+	ManagerVersion string           `toml:"manager_version,omitempty"` // constraint on the installed manager, e.g. ">=9"
+	InstallCommand string           `toml:"install_command"`
+	Install        []PackageInstall `toml:"install,omitempty"` // [[packages.install]], in place of install_command
+	Global         []string         `toml:"global,omitempty"`
+	Timeout        string           `toml:"timeout,omitempty"` // per command, e.g. "10m"; empty means no limit
+}
+
+// PackageInstall is one [[packages.install]] entry: an install command run
+// in a subdirectory, such as frontend/ or backend/ in a monorepo.
+type PackageInstall struct {
+	Dir     string `toml:"dir"`               // relative to the manifest's directory
+	Command string `toml:"command"`           // e.g. "pnpm install"
+	Manager string `toml:"manager,omitempty"` // defaults to [packages] manager
 }
 
 // EnvVar defines a single environment variable for an env file.
@@ -34,9 +59,30 @@ type EnvVar struct {
 	Description string `toml:"description"`
 	Default     string `toml:"default"`
 	Required    bool   `toml:"required"`
-	Type        string `toml:"type"` // text, url, email, secret, number, boolean
+	Type        string `toml:"type"` // text, url, email, secret, number, boolean, select
 	DocsURL     string `toml:"docs_url,omitempty"`
 	File        string `toml:"file,omitempty"` // Target env file (default: ".env")
+	// Environments this var takes a separate value in; empty means the
+	// same value is copied to every environment's file.
+	Environments []string `toml:"environments,omitempty"`
+	Options      []string `toml:"options,omitempty"` // choices for type "select"
+	Pattern      string   `toml:"pattern,omitempty"` // regular expression the value must match
+	Min          *float64 `toml:"min,omitempty"`     // bounds for type "number"
+	Max          *float64 `toml:"max,omitempty"`
+	When         string   `toml:"when,omitempty"`  // only asked while true, e.g. "PAYMENT_PROVIDER == 'stripe'"
+	Store        string   `toml:"store,omitempty"` // "keychain": kept in the OS credential store, not the env file
+}
+
+// EnvEnvironments declares the environments that get their own env files,
+// e.g. .env.development and .env.production.
+type EnvEnvironments struct {
+	Names       []string `toml:"names"`
+	FilePattern string   `toml:"file_pattern,omitempty"` // default: "{file}.{env}"
+}
+
+// EnvOptions controls how env files are written.
+type EnvOptions struct {
+	WriteExample bool `toml:"write_example,omitempty"` // also write a .env.example next to each env file
 }
 
 // ConfigFile defines a configuration file to edit (e.g., site.ts).
@@ -52,14 +98,39 @@ type ConfigField struct {
 	Path        string `toml:"path"`    // e.g. "siteConfig.name"
 	Label       string `toml:"label"`
 	Description string `toml:"description,omitempty"`
-	Type        string `toml:"type"`    // text, url, email, number, boolean
+	Type        string `toml:"type"`    // text, url, email, number, boolean, select
 	Default     string `toml:"default"`
+	Options     []string `toml:"options,omitempty"` // choices for type "select"
+	Pattern     string   `toml:"pattern,omitempty"` // regular expression the value must match
+	Min         *float64 `toml:"min,omitempty"`     // bounds for type "number"
+	Max         *float64 `toml:"max,omitempty"`
+	When        string   `toml:"when,omitempty"` // only asked while true, e.g. "PAYMENT_PROVIDER == 'stripe'"
+}
+
+// Download defines an extra file or archive fetched after runtimes are installed,
+// such as a private SDK served from an authenticated URL.
+type Download struct {
+	Name      string `toml:"name"`
+	URL       string `toml:"url"`
+	SHA256    string `toml:"sha256,omitempty"`
+	TargetDir string `toml:"target_dir,omitempty"` // default: ~/.templatr/downloads/<name>
+	Extract   bool   `toml:"extract,omitempty"`    // extract a .tar.gz/.zip into target_dir
+	AuthEnv   string `toml:"auth_env,omitempty"`   // env var holding the Authorization header value
 }
 
 // PostSetup defines commands and messages to show after setup completes.
 type PostSetup struct {
-	Commands []string `toml:"commands"`
-	Message  string   `toml:"message"`
+	Commands []Command `toml:"commands"`
+	Message  string    `toml:"message"`
+}
+
+// Command is a post-setup command, written either as a plain string or as
+// a table with run, os, dir and required.
+type Command struct {
+	Run      string   `toml:"run"`
+	OS       []string `toml:"os,omitempty"`       // only run on these GOOS values, e.g. "darwin"; empty means everywhere
+	Dir      string   `toml:"dir,omitempty"`      // working directory relative to the manifest's, e.g. "ios"
+	Required bool     `toml:"required,omitempty"` // a failure skips the commands after it
 }
 
 // Meta contains tool behavior configuration.
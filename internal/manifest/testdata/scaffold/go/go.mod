@@ -0,0 +1,3 @@
+module github.com/acme/api-server
+
+go 1.22
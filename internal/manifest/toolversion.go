@@ -0,0 +1,40 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ErrDevBuild is returned by CheckToolVersion when the running tool is a
+// development build, whose version can't be compared. Callers warn and
+// continue.
+var ErrDevBuild = errors.New("development build")
+
+// CheckToolVersion returns an error if current, the running tool's
+// version, is older than min_tool_version. Prerelease builds are older than
+// the release they precede, so 1.5.0-rc.1 does not satisfy "1.5.0".
+func (m Meta) CheckToolVersion(current string) error {
+	if m.MinToolVersion == "" {
+		return nil
+	}
+	required, err := semver.NewVersion(strings.TrimPrefix(m.MinToolVersion, "v"))
+	if err != nil {
+		return fmt.Errorf("invalid min_tool_version %q: %w", m.MinToolVersion, err)
+	}
+
+	if current == "dev" || current == "" {
+		return fmt.Errorf("%w: cannot check that it is at least %s as this template requires", ErrDevBuild, required)
+	}
+	cur, err := semver.NewVersion(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return fmt.Errorf("%w: cannot check that version %q is at least %s as this template requires", ErrDevBuild, current, required)
+	}
+
+	if cur.LessThan(required) {
+		return fmt.Errorf("this template requires templatr-setup %s or newer, but this is %s - run 'templatr-setup update' to upgrade", required, cur)
+	}
+	return nil
+}
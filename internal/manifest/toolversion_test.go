@@ -0,0 +1,60 @@
+package manifest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckToolVersion(t *testing.T) {
+	tests := []struct {
+		min, current string
+		wantErr      bool
+		wantDev      bool
+	}{
+		{"", "1.0.0", false, false},
+		{"", "dev", false, false},
+		{"1.2.0", "1.2.0", false, false},
+		{"1.2.0", "v1.2.0", false, false},
+		{"v1.2.0", "1.3.1", false, false},
+		{"1.2", "1.2.0", false, false},
+		{"1.2.0", "1.1.9", true, false},
+		{"2.0.0", "1.9.0", true, false},
+		{"1.5.0", "1.5.0-rc.1", true, false}, // a prerelease precedes its release
+		{"1.4.0", "1.5.0-rc.1", false, false},
+		{"1.5.0-rc.2", "1.5.0-rc.1", true, false},
+		{"1.5.0-rc.1", "1.5.0-rc.1", false, false},
+		{"1.0.0", "dev", true, true},
+		{"1.0.0", "", true, true},
+		{"1.0.0", "abc1234", true, true},
+		{"not-a-version", "1.0.0", true, false},
+	}
+	for _, tt := range tests {
+		err := Meta{MinToolVersion: tt.min}.CheckToolVersion(tt.current)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckToolVersion(min %q, current %q) error = %v, wantErr %v", tt.min, tt.current, err, tt.wantErr)
+			continue
+		}
+		if got := errors.Is(err, ErrDevBuild); got != tt.wantDev {
+			t.Errorf("CheckToolVersion(min %q, current %q) dev = %v, want %v", tt.min, tt.current, got, tt.wantDev)
+		}
+	}
+}
+
+func TestCheckToolVersion_SuggestsUpdate(t *testing.T) {
+	err := Meta{MinToolVersion: "2.0.0"}.CheckToolVersion("1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "templatr-setup update") {
+		t.Errorf("error %v should point at templatr-setup update", err)
+	}
+}
+
+func TestValidate_InvalidMinToolVersion(t *testing.T) {
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Test", Version: "1.0.0"},
+		Meta:     Meta{MinToolVersion: "soon"},
+	}
+	errs := Validate(m)
+	if len(errs) != 1 || errs[0].Path != "meta.min_tool_version" {
+		t.Errorf("Validate() = %v, want one error for meta.min_tool_version", errs)
+	}
+}
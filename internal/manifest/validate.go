@@ -2,7 +2,16 @@ package manifest
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
 )
 
 // validRuntimes is the set of runtimes the tool knows how to install.
@@ -16,6 +25,18 @@ var validRuntimes = map[string]bool{
 	"ruby":    true,
 	"php":     true,
 	"dotnet":  true,
+	"bun":     true,
+	"deno":    true,
+}
+
+// validPlatforms is the set of platform keys accepted in [runtimes_checksums].
+var validPlatforms = map[string]bool{
+	"darwin-x64":    true,
+	"darwin-arm64":  true,
+	"linux-x64":     true,
+	"linux-arm64":   true,
+	"windows-x64":   true,
+	"windows-arm64": true,
 }
 
 // validManagers is the set of supported package managers.
@@ -39,68 +60,445 @@ var validFieldTypes = map[string]bool{
 	"secret":  true,
 	"number":  true,
 	"boolean": true,
+	"select":  true,
+}
+
+// StoreKeychain is the [[env]] store value that keeps a secret in the OS
+// credential store instead of the env file.
+const StoreKeychain = "keychain"
+
+// Severity levels for validation results.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationError is a single validation problem tied to a field path such
+// as "env.2.type" or "config.0.fields.1.path", so UIs can map it to a field.
+type ValidationError struct {
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+	Line     int    `json:"line,omitempty"`   // 1-based position of a TOML parse error
+	Column   int    `json:"column,omitempty"` // in the manifest; 0 when unknown
+
+	section string // path prefix shown in CLI output, e.g. "env.2"
+}
+
+// Error formats the problem the way the CLI has always printed it,
+// e.g. "[env.2] unknown type ...".
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.section, e.Message)
+}
+
+// HasErrors returns true if any result has error severity (warnings alone
+// do not block setup).
+func HasErrors(errs []ValidationError) bool {
+	for _, e := range errs {
+		if e.Severity != SeverityWarning {
+			return true
+		}
+	}
+	return false
+}
+
+// validator collects validation results.
+type validator struct {
+	errs []ValidationError
+}
+
+// add records an error for field within section; field may be empty when
+// the problem applies to the whole section.
+func (v *validator) add(section, field, format string, args ...interface{}) {
+	path := section
+	if field != "" {
+		path = section + "." + field
+	}
+	v.errs = append(v.errs, ValidationError{
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+		Severity: SeverityError,
+		section:  section,
+	})
+}
+
+// warn records a warning, which is shown but does not block setup.
+func (v *validator) warn(section, field, format string, args ...interface{}) {
+	v.add(section, field, format, args...)
+	v.errs[len(v.errs)-1].Severity = SeverityWarning
 }
 
 // Validate checks the manifest for required fields and valid values.
-func Validate(m *Manifest) []error {
-	var errs []error
+// All problems are collected; it does not stop at the first one.
+func Validate(m *Manifest) []ValidationError {
+	var v validator
 
 	// Template section
 	if m.Template.Name == "" {
-		errs = append(errs, fmt.Errorf("[template] name is required"))
+		v.add("template", "name", "name is required")
 	}
 	if m.Template.Version == "" {
-		errs = append(errs, fmt.Errorf("[template] version is required"))
+		v.add("template", "version", "version is required")
 	}
 
-	// Runtimes
-	for name := range m.Runtimes {
-		if !validRuntimes[strings.ToLower(name)] {
-			errs = append(errs, fmt.Errorf("[runtimes] unknown runtime %q - supported: %s", name, runtimeList()))
+	// Runtimes, including per-OS tables such as [runtimes.darwin], with
+	// version files read from the manifest's directory
+	dir := m.Dir
+	if dir == "" {
+		dir = "."
+	}
+	v.runtimes("runtimes", m.Runtimes, dir)
+	for goos, table := range m.RuntimesOS {
+		if !validOS[goos] {
+			v.add("runtimes", goos, "unknown os %q - supported: %s", goos, osList())
+			continue
+		}
+		v.runtimes("runtimes."+goos, table, dir)
+	}
+
+	// Pinned runtime checksums
+	for name, platforms := range m.RuntimesChecksums {
+		if !m.declaresRuntime(name) {
+			v.add("runtimes_checksums", name, "runtime %q is not declared in [runtimes]", name)
+		}
+		for platform, sum := range platforms {
+			field := name + "." + platform
+			if !validPlatforms[platform] {
+				v.add("runtimes_checksums", field, "unknown platform %q - supported: %s", platform, platformList())
+			}
+			if !validSHA256(sum) {
+				v.add("runtimes_checksums", field, "sha256 must be 64 hex characters")
+			}
 		}
 	}
 
 	// Packages
 	if m.Packages.Manager != "" && !validManagers[m.Packages.Manager] {
-		errs = append(errs, fmt.Errorf("[packages] unknown manager %q - supported: %s", m.Packages.Manager, managerList()))
+		v.add("packages", "manager", "unknown manager %q - supported: %s", m.Packages.Manager, managerList())
+	}
+	if c := m.Packages.ManagerVersion; c != "" {
+		if _, err := semver.NewConstraint(c); err != nil {
+			v.add("packages", "manager_version", "%q is not a version constraint such as \">=9\" or \"^1.22\"", c)
+		}
+	}
+	if m.Packages.InstallCommand != "" && len(m.Packages.Install) > 0 {
+		v.add("packages", "install", "use either install_command or [[packages.install]], not both")
+	}
+	for i, entry := range m.Packages.Install {
+		section := fmt.Sprintf("packages.install.%d", i)
+		if strings.TrimSpace(entry.Command) == "" {
+			v.add(section, "command", "command is required")
+		}
+		if entry.Manager != "" && !validManagers[entry.Manager] {
+			v.add(section, "manager", "unknown manager %q - supported: %s", entry.Manager, managerList())
+		}
+		if isAbsDir(entry.Dir) {
+			v.add(section, "dir", "dir must be relative to the manifest's directory")
+		}
+	}
+	if t := m.Packages.Timeout; t != "" {
+		if d, err := time.ParseDuration(t); err != nil || d <= 0 {
+			v.add("packages", "timeout", "timeout %q is not a duration such as \"10m\" or \"90s\"", t)
+		}
+	}
+
+	// Env environments
+	declaredEnvs := make(map[string]bool)
+	for i, name := range m.EnvEnvironments.Names {
+		if name == "" {
+			v.add("env_environments", "names", "environment %d has an empty name", i)
+		} else if declaredEnvs[name] {
+			v.add("env_environments", "names", "duplicate environment %q", name)
+		}
+		declaredEnvs[name] = true
+	}
+	if p := m.EnvEnvironments.FilePattern; p != "" && !strings.Contains(p, "{env}") {
+		v.add("env_environments", "file_pattern", "file_pattern must contain {env}")
 	}
 
-	// Env vars
+	// Env vars. earlier holds the fields a when condition can refer to,
+	// true for env vars that take a value per environment.
+	earlier := make(map[string]bool)
 	for i, env := range m.Env {
+		section := fmt.Sprintf("env.%d", i)
 		if env.Key == "" {
-			errs = append(errs, fmt.Errorf("[env.%d] key is required", i))
+			v.add(section, "key", "key is required")
 		}
 		if env.Type != "" && !validFieldTypes[env.Type] {
-			errs = append(errs, fmt.Errorf("[env.%d] unknown type %q - supported: %s", i, env.Type, fieldTypeList()))
+			v.add(section, "type", "unknown type %q - supported: %s", env.Type, fieldTypeList())
+		}
+		v.options(section, env.Type, env.Options, env.Default)
+		v.rules(section, env.Type, env.Pattern, env.Min, env.Max, env.Default)
+		v.when(section, env.When, earlier, len(env.Environments) == 0)
+		switch {
+		case env.Store == "":
+		case env.Store != StoreKeychain:
+			v.add(section, "store", "unknown store %q - supported: %s", env.Store, StoreKeychain)
+		case env.Type != "secret":
+			v.add(section, "store", "store is only for fields of type \"secret\"")
+		case len(env.Environments) > 0:
+			v.add(section, "store", "store can't be used with environments; the stored value is shared")
+		}
+		for _, name := range env.Environments {
+			if !declaredEnvs[name] {
+				v.add(section, "environments", "environment %q is not declared in [env_environments]", name)
+			}
+		}
+		if env.Key != "" {
+			earlier[env.Key] = len(env.Environments) > 0
 		}
 	}
 
 	// Config files
 	for i, cfg := range m.Config {
+		section := fmt.Sprintf("config.%d", i)
 		if cfg.File == "" {
-			errs = append(errs, fmt.Errorf("[config.%d] file path is required", i))
+			v.add(section, "file", "file path is required")
 		}
 		for j, field := range cfg.Fields {
+			fieldSection := fmt.Sprintf("config.%d.fields.%d", i, j)
 			if field.Path == "" {
-				errs = append(errs, fmt.Errorf("[config.%d.fields.%d] path is required", i, j))
+				v.add(fieldSection, "path", "path is required")
 			}
 			if field.Type != "" && !validFieldTypes[field.Type] {
-				errs = append(errs, fmt.Errorf("[config.%d.fields.%d] unknown type %q", i, j, field.Type))
+				v.add(fieldSection, "type", "unknown type %q", field.Type)
+			}
+			v.options(fieldSection, field.Type, field.Options, field.Default)
+			v.rules(fieldSection, field.Type, field.Pattern, field.Min, field.Max, field.Default)
+			v.when(fieldSection, field.When, earlier, true)
+			if field.Path != "" {
+				earlier[field.Path] = false
 			}
 		}
 	}
 
-	return errs
+	// Downloads
+	seenDownloads := make(map[string]bool)
+	for i, dl := range m.Downloads {
+		section := fmt.Sprintf("downloads.%d", i)
+		if dl.Name == "" {
+			v.add(section, "name", "name is required")
+		} else if seenDownloads[dl.Name] {
+			v.add(section, "name", "duplicate name %q", dl.Name)
+		}
+		seenDownloads[dl.Name] = true
+
+		if dl.URL == "" {
+			v.add(section, "url", "url is required")
+		} else if !strings.HasPrefix(dl.URL, "https://") && !strings.HasPrefix(dl.URL, "http://") {
+			v.add(section, "url", "url must be http or https")
+		} else if dl.AuthEnv != "" && !strings.HasPrefix(dl.URL, "https://") {
+			v.add(section, "url", "url must be https for authenticated downloads")
+		}
+		if dl.SHA256 != "" && !validSHA256(dl.SHA256) {
+			v.add(section, "sha256", "sha256 must be 64 hex characters")
+		}
+		if dl.AuthEnv != "" && dl.SHA256 == "" {
+			v.add(section, "sha256", "sha256 is required for authenticated downloads")
+		}
+		if dl.Extract && !isArchiveURL(dl.URL) {
+			v.add(section, "extract", "extract requires a .tar.gz, .tgz, or .zip url")
+		}
+		if dl.Extract && dl.TargetDir != "" && containsDir(dl.TargetDir, dir) {
+			v.add(section, "target_dir", "target_dir can't be the manifest's directory or one above it when extract is set")
+		}
+	}
+
+	// Post-setup commands
+	for i, cmd := range m.PostSetup.Commands {
+		section := fmt.Sprintf("post_setup.commands.%d", i)
+		if strings.TrimSpace(cmd.Run) == "" {
+			v.add(section, "run", "run is required")
+		}
+		for _, goos := range cmd.OS {
+			if !validOS[goos] {
+				v.add(section, "os", "unknown os %q - supported: %s", goos, osList())
+			}
+		}
+		if isAbsDir(cmd.Dir) {
+			v.add(section, "dir", "dir must be relative to the manifest's directory")
+		}
+	}
+
+	// Meta
+	if mv := m.Meta.MinToolVersion; mv != "" {
+		if _, err := semver.NewVersion(strings.TrimPrefix(mv, "v")); err != nil {
+			v.add("meta", "min_tool_version", "min_tool_version %q is not a version", mv)
+		}
+	}
+
+	return v.errs
 }
 
-func runtimeList() string {
+// runtimes checks one table of runtime requirements, for a template in dir.
+func (v *validator) runtimes(section string, table map[string]string, dir string) {
+	for name, required := range table {
+		if !validRuntimes[strings.ToLower(name)] {
+			v.add(section, name, "unknown runtime %q - supported: %s", name, runtimeList())
+			continue
+		}
+		// "auto" and "file:" requirements are read from the template directory
+		if _, _, err := ResolveRequirement(name, required, dir); err != nil {
+			v.warn(section, name, "%s - using \"latest\"", err)
+		}
+	}
+}
+
+// options checks the choices of a select field: there must be some, and a
+// default must be one of them.
+func (v *validator) options(section, fieldType string, options []string, def string) {
+	if fieldType != "select" {
+		if len(options) > 0 {
+			v.warn(section, "options", "options are only used by select fields")
+		}
+		return
+	}
+	if len(options) == 0 {
+		v.add(section, "options", "options are required for select fields")
+		return
+	}
+	if def != "" && !slices.Contains(options, def) {
+		v.add(section, "default", "default %q is not one of the options: %s", def, strings.Join(options, ", "))
+	}
+}
+
+// rules checks a field's pattern and number bounds: the pattern must
+// compile, min and max only apply to numbers and must be in order, and a
+// default must pass them.
+func (v *validator) rules(section, fieldType, pattern string, lo, hi *float64, def string) {
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.add(section, "pattern", "pattern %q is not a valid regular expression: %s", pattern, err)
+		} else if def != "" && !re.MatchString(def) {
+			v.add(section, "default", "default %q doesn't match the pattern %s", def, pattern)
+		}
+	}
+
+	if lo == nil && hi == nil {
+		return
+	}
+	if fieldType != "number" {
+		v.warn(section, "min", "min and max are only used by number fields")
+		return
+	}
+	if lo != nil && hi != nil && *lo > *hi {
+		v.add(section, "max", "max %s is below min %s", FormatNumber(*hi), FormatNumber(*lo))
+		return
+	}
+	n, err := strconv.ParseFloat(def, 64)
+	if def == "" || err != nil {
+		return
+	}
+	if (lo != nil && n < *lo) || (hi != nil && n > *hi) {
+		v.add(section, "default", "default %s is outside the range %s", def, FormatRange(lo, hi))
+	}
+}
+
+// when checks a field's condition: it must parse and refer to a field asked
+// before this one. A shared field, asked once for every environment, can't
+// depend on a value that differs between them.
+func (v *validator) when(section, when string, earlier map[string]bool, shared bool) {
+	if when == "" {
+		return
+	}
+	c, err := ParseCondition(when)
+	if err != nil {
+		v.add(section, "when", "%s", err)
+		return
+	}
+	perEnv, ok := earlier[c.Key]
+	if !ok {
+		v.add(section, "when", "when refers to %q, which isn't a field declared before this one", c.Key)
+	} else if perEnv && shared {
+		v.add(section, "when", "when can't depend on %q, which takes a value per environment", c.Key)
+	}
+}
+
+// FormatNumber formats a min or max bound as it would be written, 1 rather
+// than 1e+00.
+func FormatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// FormatRange describes the bounds of a number field, such as "1 to 65535",
+// ">= 1" or "<= 100".
+func FormatRange(lo, hi *float64) string {
+	switch {
+	case lo != nil && hi != nil:
+		return FormatNumber(*lo) + " to " + FormatNumber(*hi)
+	case lo != nil:
+		return ">= " + FormatNumber(*lo)
+	case hi != nil:
+		return "<= " + FormatNumber(*hi)
+	}
+	return ""
+}
+
+// validSHA256 reports whether s looks like a hex-encoded SHA256 digest.
+func validSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range strings.ToLower(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isArchiveURL reports whether the URL path ends in a supported archive extension.
+func isArchiveURL(u string) bool {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	lower := strings.ToLower(u)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") || strings.HasSuffix(lower, ".zip")
+}
+
+// SupportedRuntimes returns the runtime keys accepted in [runtimes], sorted.
+func SupportedRuntimes() []string {
 	names := make([]string, 0, len(validRuntimes))
 	for k := range validRuntimes {
 		names = append(names, k)
 	}
+	sort.Strings(names)
+	return names
+}
+
+func runtimeList() string {
+	return strings.Join(SupportedRuntimes(), ", ")
+}
+
+func osList() string {
+	names := make([]string, 0, len(validOS))
+	for k := range validOS {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func platformList() string {
+	names := make([]string, 0, len(validPlatforms))
+	for k := range validPlatforms {
+		names = append(names, k)
+	}
+	sort.Strings(names)
 	return strings.Join(names, ", ")
 }
 
+// Platform returns the [runtimes_checksums] key for the running system,
+// e.g. "linux-x64" or "darwin-arm64".
+func Platform() string {
+	arch := runtime.GOARCH
+	if arch == "amd64" {
+		arch = "x64"
+	}
+	return runtime.GOOS + "-" + arch
+}
+
 func managerList() string {
 	names := make([]string, 0, len(validManagers))
 	for k := range validManagers {
@@ -116,3 +514,21 @@ func fieldTypeList() string {
 	}
 	return strings.Join(names, ", ")
 }
+
+// containsDir reports whether target, relative to dir if it isn't absolute,
+// is dir itself or one of its parents.
+func containsDir(target, dir string) bool {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absTarget, absDir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -1,7 +1,10 @@
 package manifest
 
 import (
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidate_ValidManifest(t *testing.T) {
@@ -79,6 +82,57 @@ func TestValidate_UnknownRuntime(t *testing.T) {
 	}
 }
 
+func TestValidate_BunRuntime(t *testing.T) {
+	m := &Manifest{
+		Template: TemplateInfo{Name: "T", Version: "1.0.0"},
+		Runtimes: map[string]string{"bun": ">=1.1.0"},
+		Packages: PackageConfig{Manager: "bun"},
+	}
+
+	if errs := Validate(m); len(errs) != 0 {
+		t.Errorf("Validate() returned %d errors, want 0: %v", len(errs), errs)
+	}
+}
+
+func TestValidate_RuntimesChecksums(t *testing.T) {
+	valid := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	m := &Manifest{
+		Template: TemplateInfo{Name: "T", Version: "1.0.0"},
+		Runtimes: map[string]string{"node": "22.14.0"},
+		RuntimesChecksums: map[string]map[string]string{
+			"node":   {"linux-x64": valid, "solaris-sparc": valid, "windows-x64": "abc"},
+			"python": {"linux-x64": valid},
+		},
+	}
+
+	got := map[string]bool{}
+	for _, e := range Validate(m) {
+		got[e.Path] = true
+	}
+	for _, path := range []string{
+		"runtimes_checksums.node.solaris-sparc",
+		"runtimes_checksums.node.windows-x64",
+		"runtimes_checksums.python",
+	} {
+		if !got[path] {
+			t.Errorf("expected an error for %s, got %v", path, got)
+		}
+	}
+	if got["runtimes_checksums.node.linux-x64"] {
+		t.Error("valid entry should not be reported")
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	want := runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOARCH == "amd64" {
+		want = runtime.GOOS + "-x64"
+	}
+	if got := Platform(); got != want {
+		t.Errorf("Platform() = %q, want %q", got, want)
+	}
+}
+
 func TestValidate_UnknownPackageManager(t *testing.T) {
 	m := &Manifest{
 		Template: TemplateInfo{Name: "T", Version: "1.0.0"},
@@ -214,3 +268,441 @@ func TestValidate_AllValidFieldTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate_Downloads(t *testing.T) {
+	hash := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	base := func() *Manifest {
+		return &Manifest{Template: TemplateInfo{Name: "Test", Version: "1.0.0"}}
+	}
+
+	tests := []struct {
+		name    string
+		dl      Download
+		wantErr bool
+	}{
+		{"valid public", Download{Name: "sdk", URL: "https://example.com/sdk.bin"}, false},
+		{"valid authenticated", Download{Name: "sdk", URL: "https://example.com/sdk.zip", SHA256: hash, AuthEnv: "SDK_TOKEN", Extract: true}, false},
+		{"missing name", Download{URL: "https://example.com/sdk.bin"}, true},
+		{"missing url", Download{Name: "sdk"}, true},
+		{"bad scheme", Download{Name: "sdk", URL: "ftp://example.com/sdk.bin"}, true},
+		{"auth without sha256", Download{Name: "sdk", URL: "https://example.com/sdk.bin", AuthEnv: "SDK_TOKEN"}, true},
+		{"auth over plain http", Download{Name: "sdk", URL: "http://example.com/sdk.bin", SHA256: hash, AuthEnv: "SDK_TOKEN"}, true},
+		{"public over plain http", Download{Name: "sdk", URL: "http://example.com/sdk.bin"}, false},
+		{"malformed sha256", Download{Name: "sdk", URL: "https://example.com/sdk.bin", SHA256: "abc"}, true},
+		{"extract non-archive", Download{Name: "sdk", URL: "https://example.com/sdk.bin", Extract: true}, true},
+		{"extract below the manifest", Download{Name: "sdk", URL: "https://example.com/sdk.zip", TargetDir: "vendor/sdk", Extract: true}, false},
+		{"extract into the manifest's directory", Download{Name: "sdk", URL: "https://example.com/sdk.zip", TargetDir: ".", Extract: true}, true},
+		{"extract above the manifest", Download{Name: "sdk", URL: "https://example.com/sdk.zip", TargetDir: "..", Extract: true}, true},
+		{"copy into the manifest's directory", Download{Name: "sdk", URL: "https://example.com/sdk.bin", TargetDir: "."}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base()
+			m.Downloads = []Download{tt.dl}
+			errs := Validate(m)
+			if tt.wantErr && len(errs) == 0 {
+				t.Error("expected validation error")
+			}
+			if !tt.wantErr && len(errs) != 0 {
+				t.Errorf("unexpected errors: %v", errs)
+			}
+		})
+	}
+}
+
+func TestValidate_DuplicateDownloadName(t *testing.T) {
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Test", Version: "1.0.0"},
+		Downloads: []Download{
+			{Name: "sdk", URL: "https://example.com/a.bin"},
+			{Name: "sdk", URL: "https://example.com/b.bin"},
+		},
+	}
+	if errs := Validate(m); len(errs) != 1 {
+		t.Errorf("expected 1 error for duplicate name, got %v", errs)
+	}
+}
+
+func TestValidate_MultipleStructuredErrors(t *testing.T) {
+	m := &Manifest{
+		Template: TemplateInfo{Version: "1.0.0"},
+		Env: []EnvVar{
+			{Key: "OK"},
+			{Key: "ALSO_OK"},
+			{Key: "", Type: "color"},
+		},
+		Config: []ConfigFile{
+			{File: "site.json", Fields: []ConfigField{{Path: "name"}, {Path: ""}}},
+		},
+	}
+
+	errs := Validate(m)
+	want := map[string]string{
+		"template.name":          "[template] name is required",
+		"env.2.key":              "[env.2] key is required",
+		"env.2.type":             "",
+		"config.0.fields.1.path": "[config.0.fields.1] path is required",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for _, e := range errs {
+		formatted, ok := want[e.Path]
+		if !ok {
+			t.Errorf("unexpected error path %q: %s", e.Path, e)
+			continue
+		}
+		if formatted != "" && e.Error() != formatted {
+			t.Errorf("Error() = %q, want %q", e.Error(), formatted)
+		}
+		if e.Severity != SeverityError {
+			t.Errorf("%s: severity = %q, want %q", e.Path, e.Severity, SeverityError)
+		}
+	}
+	if !HasErrors(errs) {
+		t.Error("HasErrors() should be true")
+	}
+}
+
+func TestHasErrors_WarningsOnly(t *testing.T) {
+	errs := []ValidationError{{Path: "runtimes.node", Message: "x", Severity: SeverityWarning}}
+	if HasErrors(errs) {
+		t.Error("HasErrors() should be false when there are only warnings")
+	}
+	if HasErrors(nil) {
+		t.Error("HasErrors() should be false for no results")
+	}
+}
+
+func TestValidate_EnvEnvironments(t *testing.T) {
+	m := &Manifest{
+		Template:        TemplateInfo{Name: "Test", Version: "1.0.0"},
+		EnvEnvironments: EnvEnvironments{Names: []string{"development", "production", "production"}, FilePattern: ".env.local"},
+		Env: []EnvVar{
+			{Key: "API_URL", Environments: []string{"development", "staging"}},
+			{Key: "SHARED"},
+		},
+	}
+
+	paths := make(map[string]bool)
+	for _, e := range Validate(m) {
+		paths[e.Path] = true
+	}
+	for _, want := range []string{"env_environments.names", "env_environments.file_pattern", "env.0.environments"} {
+		if !paths[want] {
+			t.Errorf("expected error at %s, got %v", want, paths)
+		}
+	}
+	if len(paths) != 3 {
+		t.Errorf("unexpected errors: %v", paths)
+	}
+}
+
+func TestValidate_EnvironmentWithoutDeclaration(t *testing.T) {
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Test", Version: "1.0.0"},
+		Env:      []EnvVar{{Key: "API_URL", Environments: []string{"production"}}},
+	}
+
+	errs := Validate(m)
+	if len(errs) != 1 || errs[0].Path != "env.0.environments" {
+		t.Errorf("expected undeclared environment error, got %v", errs)
+	}
+}
+
+func TestValidate_OSConditions(t *testing.T) {
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Test", Version: "1.0.0"},
+		Runtimes: map[string]string{"flutter": ">=3.19.0"},
+		RuntimesOS: map[string]map[string]string{
+			"darwin": {"ruby": ">=3.1"},
+			"macos":  {"ruby": ">=3.1"},
+			"linux":  {"cocoa": "1.0.0"},
+		},
+		RuntimesChecksums: map[string]map[string]string{
+			"ruby": {"darwin-arm64": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		},
+		PostSetup: PostSetup{Commands: []Command{
+			{Run: "pod install", OS: []string{"darwin"}},
+			{Run: "setup.ps1", OS: []string{"win32"}},
+			{Run: " "},
+			{Run: "pod install", Dir: "ios/App"},
+			{Run: "pod install", Dir: "/usr/local/app"},
+			{Run: "setup.ps1", Dir: `C:\app`},
+		}},
+	}
+
+	want := map[string]bool{
+		"runtimes.macos":            true,
+		"runtimes.linux.cocoa":      true,
+		"post_setup.commands.1.os":  true,
+		"post_setup.commands.2.run": true,
+		"post_setup.commands.4.dir": true,
+		"post_setup.commands.5.dir": true,
+	}
+	errs := Validate(m)
+	for _, e := range errs {
+		if !want[e.Path] {
+			t.Errorf("unexpected error %s: %s", e.Path, e.Message)
+		}
+		delete(want, e.Path)
+	}
+	for path := range want {
+		t.Errorf("missing error for %s", path)
+	}
+}
+
+func TestValidate_SelectFields(t *testing.T) {
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Test", Version: "1.0.0"},
+		Env: []EnvVar{
+			{Key: "PAYMENT_PROVIDER", Type: "select", Options: []string{"stripe", "lemonsqueezy"}, Default: "stripe"},
+			{Key: "DATABASE", Type: "select"},
+			{Key: "REGION", Type: "select", Options: []string{"eu", "us"}, Default: "asia"},
+			{Key: "SITE_URL", Type: "url", Options: []string{"a"}},
+		},
+		Config: []ConfigFile{{
+			File: "site.ts",
+			Fields: []ConfigField{
+				{Path: "site.theme", Type: "select", Options: []string{"light", "dark"}},
+				{Path: "site.font", Type: "select", Default: "serif"},
+			},
+		}},
+	}
+
+	want := map[string]string{
+		"env.1.options":             SeverityError,
+		"env.2.default":             SeverityError,
+		"env.3.options":             SeverityWarning,
+		"config.0.fields.1.options": SeverityError,
+	}
+	for _, e := range Validate(m) {
+		severity, ok := want[e.Path]
+		if !ok {
+			t.Errorf("unexpected result %s: %s", e.Path, e.Message)
+			continue
+		}
+		if e.Severity != severity {
+			t.Errorf("%s severity = %s, want %s", e.Path, e.Severity, severity)
+		}
+		delete(want, e.Path)
+	}
+	for path := range want {
+		t.Errorf("missing result for %s", path)
+	}
+}
+
+func TestValidate_FieldRules(t *testing.T) {
+	one, ten := 1.0, 10.0
+	m := &Manifest{
+		Template: TemplateInfo{Name: "Test", Version: "1.0.0"},
+		Env: []EnvVar{
+			{Key: "STRIPE_SECRET_KEY", Type: "secret", Pattern: "^sk_(test|live)_"},
+			{Key: "BROKEN", Pattern: "^(sk_"},
+			{Key: "PREFIX", Pattern: "^pk_", Default: "sk_test_1"},
+			{Key: "PORT", Type: "number", Min: &one, Max: &ten, Default: "5"},
+			{Key: "WORKERS", Type: "number", Min: &ten, Max: &one},
+			{Key: "RETRIES", Type: "number", Max: &ten, Default: "11"},
+			{Key: "NAME", Type: "text", Min: &one},
+		},
+		Config: []ConfigFile{{
+			File: "site.ts",
+			Fields: []ConfigField{
+				{Path: "site.slug", Pattern: "^[a-z-]+$", Default: "my-site"},
+				{Path: "site.port", Type: "number", Min: &ten, Default: "3"},
+				{Path: "site.id", Pattern: "[", Default: "x"},
+			},
+		}},
+	}
+
+	want := map[string]string{
+		"env.1.pattern":             SeverityError,
+		"env.2.default":             SeverityError,
+		"env.4.max":                 SeverityError,
+		"env.5.default":             SeverityError,
+		"env.6.min":                 SeverityWarning,
+		"config.0.fields.1.default": SeverityError,
+		"config.0.fields.2.pattern": SeverityError,
+	}
+	for _, e := range Validate(m) {
+		severity, ok := want[e.Path]
+		if !ok {
+			t.Errorf("unexpected result %s: %s", e.Path, e.Message)
+			continue
+		}
+		if e.Severity != severity {
+			t.Errorf("%s severity = %s, want %s", e.Path, e.Severity, severity)
+		}
+		delete(want, e.Path)
+	}
+	for path := range want {
+		t.Errorf("missing result for %s", path)
+	}
+}
+
+func TestValidate_When(t *testing.T) {
+	m := &Manifest{
+		Template:        TemplateInfo{Name: "Test", Version: "1.0.0"},
+		EnvEnvironments: EnvEnvironments{Names: []string{"development", "production"}},
+		Env: []EnvVar{
+			{Key: "PAYMENT_PROVIDER", Type: "select", Options: []string{"stripe", "lemonsqueezy"}},
+			{Key: "STRIPE_SECRET_KEY", When: "PAYMENT_PROVIDER == 'stripe'"},
+			{Key: "LEMON_API_KEY", When: "PAYMENT_PROVIDER = 'lemonsqueezy'"},
+			{Key: "WEBHOOK", When: "LATER == 'x'"},
+			{Key: "LATER"},
+			{Key: "DB_URL", Environments: []string{"production"}},
+			{Key: "DB_POOL", When: "DB_URL != ''"},
+			{Key: "DB_SSL", When: "DB_URL != ''", Environments: []string{"production"}},
+			{Key: "SELF", When: "SELF == 'x'"},
+		},
+		Config: []ConfigFile{{
+			File: "site.ts",
+			Fields: []ConfigField{
+				{Path: "site.payments", When: "PAYMENT_PROVIDER == 'stripe'"},
+				{Path: "site.stripe.key", When: "site.payments != ''"},
+				{Path: "site.db", When: "DB_URL != ''"},
+			},
+		}},
+	}
+
+	want := map[string]string{
+		"env.2.when":             "must compare a field",
+		"env.3.when":             "isn't a field declared before this one",
+		"env.6.when":             "takes a value per environment",
+		"env.8.when":             "isn't a field declared before this one",
+		"config.0.fields.2.when": "takes a value per environment",
+	}
+	for _, e := range Validate(m) {
+		msg, ok := want[e.Path]
+		if !ok {
+			t.Errorf("unexpected result %s: %s", e.Path, e.Message)
+			continue
+		}
+		if !strings.Contains(e.Message, msg) || e.Severity != SeverityError {
+			t.Errorf("%s = %s %q, want an error containing %q", e.Path, e.Severity, e.Message, msg)
+		}
+		delete(want, e.Path)
+	}
+	for path := range want {
+		t.Errorf("missing result for %s", path)
+	}
+}
+
+func TestValidate_Store(t *testing.T) {
+	m := &Manifest{
+		Template:        TemplateInfo{Name: "Test", Version: "1.0.0"},
+		EnvEnvironments: EnvEnvironments{Names: []string{"production"}},
+		Env: []EnvVar{
+			{Key: "STRIPE_SECRET_KEY", Type: "secret", Store: "keychain"},
+			{Key: "VAULT_TOKEN", Type: "secret", Store: "vault"},
+			{Key: "SITE_URL", Type: "url", Store: "keychain"},
+			{Key: "DB_PASSWORD", Type: "secret", Store: "keychain", Environments: []string{"production"}},
+		},
+	}
+
+	want := map[string]string{
+		"env.1.store": `unknown store "vault"`,
+		"env.2.store": "only for fields of type",
+		"env.3.store": "can't be used with environments",
+	}
+	for _, e := range Validate(m) {
+		msg, ok := want[e.Path]
+		if !ok {
+			t.Errorf("unexpected result %s: %s", e.Path, e.Message)
+			continue
+		}
+		if !strings.Contains(e.Message, msg) || e.Severity != SeverityError {
+			t.Errorf("%s = %s %q, want an error containing %q", e.Path, e.Severity, e.Message, msg)
+		}
+		delete(want, e.Path)
+	}
+	for path := range want {
+		t.Errorf("missing result for %s", path)
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	lo, hi := 1.0, 65535.0
+	tests := []struct {
+		lo, hi *float64
+		want   string
+	}{
+		{&lo, &hi, "1 to 65535"},
+		{&lo, nil, ">= 1"},
+		{nil, &hi, "<= 65535"},
+	}
+	for _, tt := range tests {
+		if got := FormatRange(tt.lo, tt.hi); got != tt.want {
+			t.Errorf("FormatRange() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestValidate_ManagerVersion(t *testing.T) {
+	for constraint, wantErr := range map[string]bool{"": false, ">=9": false, "^1.22": false, ">=8, <10": false, "nine": true, ">=": true} {
+		m := &Manifest{Template: TemplateInfo{Name: "Test", Version: "1.0.0"}}
+		m.Packages.Manager = "pnpm"
+		m.Packages.ManagerVersion = constraint
+		errs := Validate(m)
+		if wantErr && (len(errs) != 1 || errs[0].Path != "packages.manager_version") {
+			t.Errorf("manager_version %q: errors = %v, want one for packages.manager_version", constraint, errs)
+		}
+		if !wantErr && len(errs) != 0 {
+			t.Errorf("manager_version %q: unexpected errors %v", constraint, errs)
+		}
+	}
+}
+
+func TestValidate_PackagesTimeout(t *testing.T) {
+	for timeout, wantErr := range map[string]bool{"": false, "10m": false, "90s": false, "ten minutes": true, "10": true, "-1m": true, "0s": true} {
+		m := &Manifest{Template: TemplateInfo{Name: "Test", Version: "1.0.0"}}
+		m.Packages.Timeout = timeout
+		errs := Validate(m)
+		if wantErr && (len(errs) != 1 || errs[0].Path != "packages.timeout") {
+			t.Errorf("timeout %q: errors = %v, want one for packages.timeout", timeout, errs)
+		}
+		if !wantErr && len(errs) != 0 {
+			t.Errorf("timeout %q: unexpected errors %v", timeout, errs)
+		}
+		if got := m.Packages.CommandTimeout(); wantErr && got != 0 {
+			t.Errorf("CommandTimeout() for %q = %s, want no limit", timeout, got)
+		}
+	}
+	if got := (PackageConfig{Timeout: "10m"}).CommandTimeout(); got != 10*time.Minute {
+		t.Errorf("CommandTimeout() = %s, want 10m", got)
+	}
+}
+
+func TestValidate_PackagesInstall(t *testing.T) {
+	m := &Manifest{Template: TemplateInfo{Name: "Test", Version: "1.0.0"}}
+	m.Packages.Install = []PackageInstall{
+		{Dir: "frontend", Command: "pnpm install"},
+		{Dir: "backend", Command: " "},
+		{Dir: "api", Command: "npm ci", Manager: "npx"},
+		{Dir: "/srv/app", Command: "npm ci"},
+		{Dir: `C:\app`, Command: "npm ci"},
+	}
+	want := map[string]bool{
+		"packages.install.1.command": true,
+		"packages.install.2.manager": true,
+		"packages.install.3.dir":     true,
+		"packages.install.4.dir":     true,
+	}
+	for _, e := range Validate(m) {
+		if !want[e.Path] {
+			t.Errorf("unexpected error %s: %s", e.Path, e.Message)
+		}
+		delete(want, e.Path)
+	}
+	for path := range want {
+		t.Errorf("missing error for %s", path)
+	}
+
+	// The legacy single command and the entries don't mix
+	m.Packages.Install = m.Packages.Install[:1]
+	m.Packages.InstallCommand = "npm install"
+	if errs := Validate(m); len(errs) != 1 || errs[0].Path != "packages.install" {
+		t.Errorf("errors = %v, want one for packages.install", errs)
+	}
+}
@@ -0,0 +1,144 @@
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+// lookPath and bootstrapShell are the exec layer EnsureManager goes
+// through, and latestRelease the registry lookup, replaced in tests.
+var (
+	lookPath       = exec.LookPath
+	bootstrapShell = func(r runner, command string) error { return r.shell(command, "", nil) }
+	latestRelease  = npmLatest
+)
+
+// corepackPackages are the npm packages corepack installs each manager
+// from, where that isn't the manager's own name.
+var corepackPackages = map[string]string{
+	"yarn": "@yarnpkg/cli-dist", // yarn 2 and later; "yarn" on npm is 1.x
+}
+
+// EnsureManager installs the plan's package manager if it wasn't found,
+// or upgrades it if it is older than manager_version, and the plan says
+// how: pnpm and yarn are enabled through corepack with the Node.js that is
+// now installed, npm through npm install -g, and pip through python -m
+// ensurepip. A manager that is a runtime itself, such as bun, was
+// installed by the runtime step and only has to be on PATH by now. Output
+// goes to the log file and to out, if not nil.
+func EnsureManager(ctx context.Context, plan *engine.SetupPlan, log *logger.Logger, out io.Writer) error {
+	pp := plan.Packages
+	if pp == nil || pp.Bootstrap == "" || (pp.ManagerFound && !pp.ManagerOutdated) {
+		return nil
+	}
+	// The runtime step may have brought it along
+	if !pp.ManagerOutdated {
+		if _, err := lookPath(pp.Manager); err == nil {
+			return nil
+		}
+	}
+
+	var command string
+	switch pp.Bootstrap {
+	case engine.BootstrapCorepack:
+		spec, err := corepackSpec(pp)
+		if err != nil {
+			return err
+		}
+		command = fmt.Sprintf("corepack enable && corepack prepare %s --activate", spec)
+	case engine.BootstrapNpm:
+		command = "npm install -g " + managerSpec(pp)
+	case engine.BootstrapEnsurepip:
+		command = pythonCommand() + " -m ensurepip"
+	case engine.BootstrapRuntime:
+		return fmt.Errorf("%s is not on PATH after installing its runtime", pp.Manager)
+	default:
+		return fmt.Errorf("unknown bootstrap %q for %s", pp.Bootstrap, pp.Manager)
+	}
+
+	verb := "install"
+	if pp.ManagerOutdated {
+		verb = "upgrade"
+		log.Info("Upgrading %s to %s via %s: %s", pp.Label(), pp.RequiredVersion, pp.Bootstrap, command)
+	} else {
+		log.Info("Installing %s via %s: %s", pp.Manager, pp.Bootstrap, command)
+	}
+	if err := bootstrapShell(newRunner(ctx, plan.Manifest, log, out), command); err != nil {
+		return fmt.Errorf("could not %s %s via %s: %w", verb, pp.Manager, pp.Bootstrap, err)
+	}
+	return nil
+}
+
+// managerSpec is the package spec npm installs pp's manager with:
+// name@latest, or name@ the manager_version range, quoted for the
+// shell and with its commas dropped, as npm ranges join with spaces.
+func managerSpec(pp *engine.PackagePlan) string {
+	if pp.RequiredVersion == "" {
+		return pp.Manager + "@latest"
+	}
+	spec := strings.Join(strings.Fields(strings.ReplaceAll(pp.RequiredVersion, ",", " ")), " ")
+	return `"` + pp.Manager + "@" + spec + `"`
+}
+
+// corepackSpec is the spec corepack prepare installs pp's manager with.
+// corepack takes only an exact version or a tag, so a manager_version range
+// becomes the latest release, when that satisfies it.
+func corepackSpec(pp *engine.PackagePlan) (string, error) {
+	if pp.RequiredVersion == "" {
+		return pp.Manager + "@latest", nil
+	}
+	exact := strings.TrimPrefix(strings.TrimSpace(pp.RequiredVersion), "=")
+	if v, err := semver.StrictNewVersion(strings.TrimPrefix(exact, "v")); err == nil {
+		return pp.Manager + "@" + v.String(), nil
+	}
+
+	c, err := semver.NewConstraint(pp.RequiredVersion)
+	if err != nil {
+		return "", fmt.Errorf("manager_version %q is not a version range: %w", pp.RequiredVersion, err)
+	}
+	pkg := pp.Manager
+	if p, ok := corepackPackages[pkg]; ok {
+		pkg = p
+	}
+	latest, err := latestRelease(pkg)
+	if err != nil {
+		return "", fmt.Errorf("could not look up the latest %s for manager_version %q: %w", pp.Manager, pp.RequiredVersion, err)
+	}
+	if v, err := semver.NewVersion(latest); err != nil || !c.Check(v) {
+		return "", fmt.Errorf("the latest %s, %s, doesn't satisfy manager_version %q, and corepack only installs exact versions - set manager_version to one", pp.Manager, latest, pp.RequiredVersion)
+	}
+	return pp.Manager + "@" + latest, nil
+}
+
+// npmLatest returns the version the npm registry tags latest for pkg.
+func npmLatest(pkg string) (string, error) {
+	body, err := install.FetchJSON("https://registry.npmjs.org/" + pkg + "/latest")
+	if err != nil {
+		return "", err
+	}
+	var release struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(body, &release); err != nil || release.Version == "" {
+		return "", fmt.Errorf("unexpected response from the npm registry for %s", pkg)
+	}
+	return release.Version, nil
+}
+
+// pythonCommand returns the Python executable to run ensurepip with,
+// preferring python3 where both exist.
+func pythonCommand() string {
+	if _, err := lookPath("python3"); err == nil {
+		return "python3"
+	}
+	return "python"
+}
@@ -0,0 +1,126 @@
+package packages
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// fakeExec replaces the exec layer: only the names in onPath are found,
+// and bootstrap commands are recorded instead of run, failing with err.
+// The latest pnpm is 10.2.0 and the latest yarn 4.6.0.
+func fakeExec(t *testing.T, onPath []string, err error) *[]string {
+	t.Helper()
+	origLook, origShell, origLatest := lookPath, bootstrapShell, latestRelease
+	t.Cleanup(func() { lookPath, bootstrapShell, latestRelease = origLook, origShell, origLatest })
+	latestRelease = func(pkg string) (string, error) {
+		if pkg == "@yarnpkg/cli-dist" {
+			return "4.6.0", nil
+		}
+		return "10.2.0", nil
+	}
+
+	var ran []string
+	lookPath = func(file string) (string, error) {
+		for _, name := range onPath {
+			if name == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+	bootstrapShell = func(r runner, command string) error {
+		ran = append(ran, command)
+		return err
+	}
+	return &ran
+}
+
+func TestEnsureManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		pp      *engine.PackagePlan
+		onPath  []string
+		want    string // the command run, if any
+		wantErr bool
+	}{
+		{"no packages", nil, nil, "", false},
+		{"manager found", &engine.PackagePlan{Manager: "pnpm", ManagerFound: true}, nil, "", false},
+		{"no bootstrap", &engine.PackagePlan{Manager: "composer"}, nil, "", false},
+		{"pnpm via corepack", &engine.PackagePlan{Manager: "pnpm", Bootstrap: engine.BootstrapCorepack}, nil,
+			"corepack enable && corepack prepare pnpm@latest --activate", false},
+		{"yarn via corepack", &engine.PackagePlan{Manager: "yarn", Bootstrap: engine.BootstrapCorepack}, nil,
+			"corepack enable && corepack prepare yarn@latest --activate", false},
+		{"pnpm already on PATH", &engine.PackagePlan{Manager: "pnpm", Bootstrap: engine.BootstrapCorepack}, []string{"pnpm"}, "", false},
+		{"pip via python3", &engine.PackagePlan{Manager: "pip", Bootstrap: engine.BootstrapEnsurepip}, []string{"python3", "python"},
+			"python3 -m ensurepip", false},
+		{"pip via python", &engine.PackagePlan{Manager: "pip", Bootstrap: engine.BootstrapEnsurepip}, []string{"python"},
+			"python -m ensurepip", false},
+		{"bun installed by the runtime step", &engine.PackagePlan{Manager: "bun", Bootstrap: engine.BootstrapRuntime}, []string{"bun"}, "", false},
+		{"bun missing after the runtime step", &engine.PackagePlan{Manager: "bun", Bootstrap: engine.BootstrapRuntime}, nil, "", true},
+		{"pnpm installed at manager_version", &engine.PackagePlan{Manager: "pnpm", RequiredVersion: ">=9", Bootstrap: engine.BootstrapCorepack}, nil,
+			"corepack enable && corepack prepare pnpm@10.2.0 --activate", false},
+		{"pnpm at an exact manager_version", &engine.PackagePlan{Manager: "pnpm", RequiredVersion: "9.15.4", Bootstrap: engine.BootstrapCorepack}, nil,
+			"corepack enable && corepack prepare pnpm@9.15.4 --activate", false},
+		{"yarn range against yarn 2 and later", &engine.PackagePlan{Manager: "yarn", RequiredVersion: "^4", Bootstrap: engine.BootstrapCorepack}, nil,
+			"corepack enable && corepack prepare yarn@4.6.0 --activate", false},
+		{"range the latest pnpm doesn't satisfy", &engine.PackagePlan{Manager: "pnpm", RequiredVersion: "^9", Bootstrap: engine.BootstrapCorepack}, nil,
+			"", true},
+		{"outdated pnpm upgraded via corepack", &engine.PackagePlan{Manager: "pnpm", ManagerFound: true, ManagerVersion: "7.33.0",
+			RequiredVersion: ">=9", ManagerOutdated: true, Bootstrap: engine.BootstrapCorepack}, []string{"pnpm"},
+			"corepack enable && corepack prepare pnpm@10.2.0 --activate", false},
+		{"outdated npm upgraded via npm", &engine.PackagePlan{Manager: "npm", ManagerFound: true, ManagerVersion: "9.8.1",
+			RequiredVersion: ">=10, <12", ManagerOutdated: true, Bootstrap: engine.BootstrapNpm}, []string{"npm"},
+			`npm install -g "npm@>=10 <12"`, false},
+		{"outdated without an upgrade", &engine.PackagePlan{Manager: "pnpm", ManagerFound: true, ManagerVersion: "7.33.0",
+			RequiredVersion: ">=9", ManagerOutdated: true}, []string{"pnpm"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := fakeExec(t, tt.onPath, nil)
+			plan := &engine.SetupPlan{Manifest: &manifest.Manifest{}, Packages: tt.pp}
+
+			err := EnsureManager(context.Background(), plan, quietLogger(), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureManager() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := strings.Join(*ran, "; ")
+			if got != tt.want {
+				t.Errorf("ran %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureManager_Failure(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Packages: &engine.PackagePlan{Manager: "pnpm", Bootstrap: engine.BootstrapCorepack},
+	}
+
+	fakeExec(t, nil, errors.New("exit status 1"))
+	err := EnsureManager(context.Background(), plan, quietLogger(), nil)
+	if err == nil || !strings.Contains(err.Error(), "could not install pnpm via corepack") {
+		t.Errorf("error = %v, want it to name the manager and bootstrap", err)
+	}
+
+	// An upgrade says so
+	plan.Packages = &engine.PackagePlan{Manager: "pnpm", ManagerFound: true, ManagerVersion: "7.33.0",
+		RequiredVersion: ">=9", ManagerOutdated: true, Bootstrap: engine.BootstrapCorepack}
+	err = EnsureManager(context.Background(), plan, quietLogger(), nil)
+	if err == nil || !strings.Contains(err.Error(), "could not upgrade pnpm via corepack") {
+		t.Errorf("error = %v, want it to say the upgrade failed", err)
+	}
+
+	// A cancelled bootstrap stays recognisable to the callers
+	fakeExec(t, nil, install.ErrCancelled)
+	if err := EnsureManager(context.Background(), plan, quietLogger(), nil); !errors.Is(err, install.ErrCancelled) {
+		t.Errorf("error = %v, want install.ErrCancelled", err)
+	}
+}
@@ -1,76 +1,118 @@
 package packages
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
 	"github.com/templatr/templatr-setup/internal/logger"
 	"github.com/templatr/templatr-setup/internal/manifest"
 )
 
-// RunInstall executes the package manager install command from the manifest.
-func RunInstall(m *manifest.Manifest, log *logger.Logger) error {
-	if m.Packages.InstallCommand == "" {
-		log.Info("No install command specified, skipping package installation")
-		return nil
-	}
+// waitDelay bounds how long a killed command's output is waited for, in
+// case something it started escaped the kill and still holds the pipe.
+var waitDelay = 5 * time.Second
 
-	log.Info("Running: %s", m.Packages.InstallCommand)
+// TimeoutError reports a command that ran longer than [packages] timeout
+// and was killed.
+type TimeoutError struct {
+	Command string
+	Elapsed time.Duration
+}
 
-	parts := strings.Fields(m.Packages.InstallCommand)
-	if len(parts) == 0 {
-		return fmt.Errorf("empty install command")
+func (e *TimeoutError) Error() string {
+	elapsed := e.Elapsed.Round(time.Second)
+	if e.Elapsed < time.Second {
+		elapsed = e.Elapsed.Round(time.Millisecond)
 	}
+	return fmt.Sprintf("%q timed out after %s", e.Command, elapsed)
+}
 
-	cmd := exec.Command(parts[0], parts[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+// RunInstall executes the plan's package install commands: the manifest's
+// install_command, or the default for the manager inferred from the
+// project's lockfile, or each [[packages.install]] entry in its directory.
+// A failing entry doesn't stop the others; the error reports each one that
+// failed. Output goes to the log file and to out, if not nil. Cancelling
+// ctx kills the running command and everything it started, and skips the
+// rest.
+func RunInstall(ctx context.Context, plan *engine.SetupPlan, log *logger.Logger, out io.Writer) error {
+	if plan.Packages == nil || len(plan.Packages.Steps) == 0 {
+		log.Info("No install command specified, skipping package installation")
+		return nil
+	}
+	m := plan.Manifest
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("package install failed: %w", err)
+	r := newRunner(ctx, m, log, out)
+	var errs []error
+	for _, step := range plan.Packages.Steps {
+		if step.Dir != "" {
+			log.Info("Running in %s: %s", step.Dir, step.Command)
+		} else {
+			log.Info("Running: %s", step.Command)
+		}
+
+		if strings.TrimSpace(step.Command) == "" {
+			errs = append(errs, fmt.Errorf("empty install command"))
+			continue
+		}
+		dir := manifest.PackageInstall{Dir: step.Dir}.WorkDir(m.Dir)
+		err := r.shell(step.Command, dir, os.Stdin)
+		switch {
+		case err == nil:
+			continue
+		case step.Dir == "":
+			err = fmt.Errorf("package install failed: %w", err)
+		default:
+			err = fmt.Errorf("package install in %s failed: %w", step.Dir, err)
+		}
+		if errors.Is(err, install.ErrCancelled) {
+			return err
+		}
+		errs = append(errs, err)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-// RunGlobalInstalls installs global packages if specified in the manifest.
-func RunGlobalInstalls(m *manifest.Manifest, log *logger.Logger) error {
+// RunGlobalInstalls installs global packages if specified in the manifest,
+// with the plan's package manager. Their output goes to the log file and
+// to out, if not nil.
+func RunGlobalInstalls(ctx context.Context, plan *engine.SetupPlan, log *logger.Logger, out io.Writer) error {
+	m := plan.Manifest
 	if len(m.Packages.Global) == 0 {
 		return nil
 	}
 
-	manager := m.Packages.Manager
-	var installCmd string
-
-	switch manager {
-	case "npm":
-		installCmd = "npm install -g"
-	case "pnpm":
-		installCmd = "pnpm add -g"
-	case "yarn":
-		installCmd = "yarn global add"
-	case "bun":
-		installCmd = "bun add -g"
-	case "pip":
-		installCmd = "pip install"
-	default:
+	manager := planManager(plan)
+	installCmd := globalInstallCommand(manager)
+	if installCmd == "" {
 		log.Warn("Global package installation not supported for %s", manager)
 		return nil
 	}
 
+	r := newRunner(ctx, m, log, out)
 	for _, pkg := range m.Packages.Global {
 		fullCmd := installCmd + " " + pkg
 		log.Info("Running: %s", fullCmd)
 
 		parts := strings.Fields(fullCmd)
-		cmd := exec.Command(parts[0], parts[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
+		err := r.run(fullCmd, func(ctx context.Context) *exec.Cmd {
+			return newCommand(ctx, parts[0], parts[1:]...)
+		})
+		if errors.Is(err, install.ErrCancelled) {
+			return err
+		}
+		if err != nil {
 			log.Warn("Failed to install global package %s: %s", pkg, err)
 		}
 	}
@@ -78,28 +120,221 @@ func RunGlobalInstalls(m *manifest.Manifest, log *logger.Logger) error {
 	return nil
 }
 
-// RunPostSetup executes the post_setup commands from the manifest.
-func RunPostSetup(m *manifest.Manifest, log *logger.Logger) error {
-	if len(m.PostSetup.Commands) == 0 {
+// planManager returns the package manager the plan installs with.
+func planManager(plan *engine.SetupPlan) string {
+	if plan.Packages != nil {
+		return plan.Packages.Manager
+	}
+	return plan.Manifest.Packages.Manager
+}
+
+// globalInstallCommand returns the command that installs a global package
+// with manager, followed by the package, or "" if manager has none.
+func globalInstallCommand(manager string) string {
+	switch manager {
+	case "npm":
+		return "npm install -g"
+	case "pnpm":
+		return "pnpm add -g"
+	case "yarn":
+		return "yarn global add"
+	case "bun":
+		return "bun add -g"
+	case "pip":
+		return "pip install"
+	}
+	return ""
+}
+
+// ErrNotRun marks a post-setup command skipped because a required command
+// before it failed.
+var ErrNotRun = errors.New("was not run: a required command before it failed")
+
+// PostSetupResult is how one post-setup command went.
+type PostSetupResult struct {
+	Index   int // in the manifest's post_setup.commands
+	Command manifest.Command
+	Err     error // nil if it succeeded
+}
+
+// NotRun reports whether the command was skipped after a required command
+// failed.
+func (r PostSetupResult) NotRun() bool {
+	return errors.Is(r.Err, ErrNotRun)
+}
+
+// PostSetupResults are the results of RunPostSetup, in manifest order.
+type PostSetupResults []PostSetupResult
+
+// Failed returns the results of the commands that failed or were not run.
+func (rs PostSetupResults) Failed() PostSetupResults {
+	var failed PostSetupResults
+	for _, r := range rs {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Err summarizes the commands that failed or were not run, or returns nil
+// if every command succeeded.
+func (rs PostSetupResults) Err() error {
+	failed := rs.Failed()
+	switch len(failed) {
+	case 0:
 		return nil
+	case 1:
+		return failed[0].Err
 	}
+	errs := make([]error, len(failed))
+	for i, r := range failed {
+		errs[i] = r.Err
+	}
+	return fmt.Errorf("%d of %d post-setup commands did not complete:\n%w", len(failed), len(rs), errors.Join(errs...))
+}
 
-	for _, cmdStr := range m.PostSetup.Commands {
-		log.Info("Running post-setup: %s", cmdStr)
+// RunPostSetup executes the post_setup commands from the manifest that
+// apply to this OS, with env, the values configure wrote to the env files,
+// added to their environment. Secret values are masked in the log. Their
+// output goes to the log file and to out, if not nil. A failing command
+// doesn't stop the others unless it is required, in which case the rest
+// are reported with ErrNotRun. The error summarizes the results' failures.
+// Cancelling ctx kills the running command and skips the rest.
+func RunPostSetup(ctx context.Context, m *manifest.Manifest, env map[string]string, log *logger.Logger, out io.Writer) (PostSetupResults, error) {
+	for _, c := range m.PostSetup.Commands {
+		if !c.AppliesTo(runtime.GOOS) {
+			log.Info("Skipping post-setup on %s: %s", runtime.GOOS, c)
+		}
+	}
 
-		parts := strings.Fields(cmdStr)
-		if len(parts) == 0 {
+	r := newPostSetupRunner(ctx, m, env, log, out)
+	var results PostSetupResults
+	var abort bool
+	for i, c := range m.PostSetup.Commands {
+		if !c.AppliesTo(runtime.GOOS) || strings.TrimSpace(c.Run) == "" {
 			continue
 		}
+		if abort {
+			results = append(results, PostSetupResult{Index: i, Command: c, Err: fmt.Errorf("post-setup command %q %w", c.Run, ErrNotRun)})
+			continue
+		}
+
+		err := r.postSetup(m, c)
+		results = append(results, PostSetupResult{Index: i, Command: c, Err: err})
+		if errors.Is(err, install.ErrCancelled) {
+			break
+		}
+		if err != nil && c.Required {
+			log.Warn("Required post-setup command failed, skipping the rest: %s", c.Run)
+			abort = true
+		}
+	}
 
-		cmd := exec.Command(parts[0], parts[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	return results, results.Err()
+}
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("post-setup command %q failed: %w", cmdStr, err)
+// RunPostSetupCommand runs the post-setup command at index in the
+// manifest's post_setup.commands again, as RunPostSetup would, so a failed
+// one can be retried.
+func RunPostSetupCommand(ctx context.Context, m *manifest.Manifest, env map[string]string, index int, log *logger.Logger, out io.Writer) error {
+	if index < 0 || index >= len(m.PostSetup.Commands) {
+		return fmt.Errorf("no post-setup command %d", index)
+	}
+	return newPostSetupRunner(ctx, m, env, log, out).postSetup(m, m.PostSetup.Commands[index])
+}
+
+// newPostSetupRunner returns a runner that adds env to the commands'
+// environment, with its secret values masked in the log.
+func newPostSetupRunner(ctx context.Context, m *manifest.Manifest, env map[string]string, log *logger.Logger, out io.Writer) runner {
+	for _, def := range m.Env {
+		if v := env[def.Key]; def.Type == "secret" && v != "" {
+			log.AddSecret(v)
 		}
 	}
 
+	r := newRunner(ctx, m, log, out)
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		r.env = append(r.env, k+"="+env[k])
+	}
+	return r
+}
+
+// postSetup runs the post-setup command c in its directory.
+func (r runner) postSetup(m *manifest.Manifest, c manifest.Command) error {
+	if c.Dir != "" {
+		r.log.Info("Running post-setup in %s: %s", c.Dir, c.Run)
+	} else {
+		r.log.Info("Running post-setup: %s", c.Run)
+	}
+
+	err := r.shell(c.Run, c.WorkDir(m.Dir), nil)
+	var timeout *TimeoutError
+	if errors.As(err, &timeout) || errors.Is(err, install.ErrCancelled) {
+		return fmt.Errorf("post-setup failed: %w", err) // already names the command
+	}
+	if err != nil {
+		return fmt.Errorf("post-setup command %q failed: %w", c.Run, err)
+	}
 	return nil
 }
+
+// runner runs a manifest's commands with their output captured.
+type runner struct {
+	ctx     context.Context
+	timeout time.Duration // per command; zero means no limit
+	log     *logger.Logger
+	out     io.Writer
+	env     []string // KEY=value pairs added to the commands' environment
+}
+
+func newRunner(ctx context.Context, m *manifest.Manifest, log *logger.Logger, out io.Writer) runner {
+	return runner{ctx: ctx, timeout: m.Packages.CommandTimeout(), log: log, out: out}
+}
+
+// shell runs command through the platform shell in dir (the process's
+// working directory if empty).
+func (r runner) shell(command, dir string, stdin io.Reader) error {
+	return r.run(command, func(ctx context.Context) *exec.Cmd {
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = dir
+		cmd.Stdin = stdin
+		if len(r.env) > 0 {
+			cmd.Env = append(os.Environ(), r.env...)
+		}
+		return cmd
+	})
+}
+
+// run runs the command newCmd builds, with its stdout and stderr,
+// interleaved, going through log.Output, so they are masked and kept in
+// the log file. A command that outlives the timeout is killed with a
+// *TimeoutError; one stopped by the caller's ctx fails with
+// install.ErrCancelled.
+func (r runner) run(command string, newCmd func(context.Context) *exec.Cmd) error {
+	ctx := r.ctx
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	cmd := newCmd(ctx)
+	w := r.log.Output(r.out)
+	defer w.Close()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.WaitDelay = waitDelay
+
+	start := time.Now()
+	err := cmd.Run()
+	switch {
+	case err == nil:
+		return nil
+	case r.ctx.Err() != nil:
+		return fmt.Errorf("%q was stopped: %w", command, install.ErrCancelled)
+	case ctx.Err() != nil:
+		return &TimeoutError{Command: command, Elapsed: time.Since(start)}
+	}
+	return err
+}
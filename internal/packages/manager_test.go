@@ -0,0 +1,389 @@
+package packages
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func quietLogger() *logger.Logger {
+	log := logger.New()
+	log.SetLevel(logger.ERROR)
+	return log
+}
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
+}
+
+// installPlan is the plan BuildPlan makes for m's packages.
+func installPlan(m *manifest.Manifest) *engine.SetupPlan {
+	pp := &engine.PackagePlan{Manager: m.Packages.Manager, InstallCommand: m.Packages.InstallCommand}
+	if pp.InstallCommand != "" {
+		pp.Steps = []engine.InstallStep{{Command: pp.InstallCommand, Manager: pp.Manager}}
+	}
+	for _, entry := range m.Packages.Install {
+		pp.Steps = append(pp.Steps, engine.InstallStep{Dir: entry.Dir, Command: entry.Command, Manager: entry.Manager})
+	}
+	return &engine.SetupPlan{Manifest: m, Packages: pp}
+}
+
+func TestRunPostSetup_Quoting(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{{Run: `printf '%s|%s\n' "a b" 'c  d' > out.txt`}}
+
+	if _, err := RunPostSetup(context.Background(), m, nil, quietLogger(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "out.txt")); got != "a b|c  d" {
+		t.Errorf("out.txt = %q, want the quoted arguments kept whole", got)
+	}
+}
+
+func TestRunPostSetup_Chained(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{
+		{Run: `NODE_ENV=production sh -c 'echo $NODE_ENV' > env.txt && echo done | tr a-z A-Z > done.txt`},
+	}
+
+	if _, err := RunPostSetup(context.Background(), m, nil, quietLogger(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "env.txt")); got != "production" {
+		t.Errorf("env.txt = %q, want the assignment applied", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "done.txt")); got != "DONE" {
+		t.Errorf("done.txt = %q, want the piped second command run", got)
+	}
+
+	// A failing first command stops the chain and, being required, the
+	// rest of post-setup
+	m.PostSetup.Commands = []manifest.Command{{Run: "false && echo no > no.txt", Required: true}, {Run: "echo never > never.txt"}}
+	if _, err := RunPostSetup(context.Background(), m, nil, quietLogger(), nil); err == nil {
+		t.Error("expected an error from a failing chain")
+	}
+	for _, f := range []string{"no.txt", "never.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			t.Errorf("%s should not have been written", f)
+		}
+	}
+}
+
+func TestRunPostSetup_ContinuesPastFailures(t *testing.T) {
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{
+		{Run: "exit 3"},
+		{Run: "echo two> two.txt"},
+		{Run: "exit 4"},
+		{Run: "echo four> four.txt"},
+	}
+
+	results, err := RunPostSetup(context.Background(), m, nil, quietLogger(), nil)
+	if err == nil || !strings.Contains(err.Error(), "2 of 4 post-setup commands did not complete") {
+		t.Errorf("RunPostSetup() error = %v, want a summary of the two failures", err)
+	}
+	for _, f := range []string{"two.txt", "four.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("%s should have been written after the failures before it", f)
+		}
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want one per command", len(results))
+	}
+	var failed []int
+	for _, r := range results.Failed() {
+		failed = append(failed, r.Index)
+		if r.NotRun() {
+			t.Errorf("command %d was run, not skipped", r.Index)
+		}
+	}
+	if !slices.Equal(failed, []int{0, 2}) {
+		t.Errorf("failed = %v, want [0 2]", failed)
+	}
+}
+
+func TestRunPostSetup_RequiredAborts(t *testing.T) {
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{
+		{Run: "echo one> one.txt"},
+		{Run: "exit 3", Required: true},
+		{Run: "echo three> three.txt"},
+		{Run: "echo other> other.txt", OS: []string{"plan9"}},
+	}
+
+	results, err := RunPostSetup(context.Background(), m, nil, quietLogger(), nil)
+	if err == nil {
+		t.Fatal("expected an error from a failing required command")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "three.txt")); err == nil {
+		t.Error("commands after a failed required one should not run")
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want the commands for this OS", len(results))
+	}
+	if results[0].Err != nil || results[1].Err == nil || results[1].NotRun() || !results[2].NotRun() {
+		t.Errorf("results = %+v, want ok, failed, not run", results)
+	}
+	if !strings.Contains(err.Error(), `"echo three> three.txt" was not run`) {
+		t.Errorf("error = %q, want the skipped command named", err)
+	}
+}
+
+func TestRunPostSetupCommand_Retry(t *testing.T) {
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{{Run: "echo skip> skip.txt"}, {Run: "cd missing && echo ok> ok.txt"}}
+
+	results, _ := RunPostSetup(context.Background(), m, nil, quietLogger(), nil)
+	if failed := results.Failed(); len(failed) != 1 || failed[0].Index != 1 {
+		t.Fatalf("failed = %+v, want the second command", failed)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "skip.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "missing"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunPostSetupCommand(context.Background(), m, nil, 1, quietLogger(), nil); err != nil {
+		t.Fatalf("retry failed: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing", "ok.txt")); err != nil {
+		t.Error("the retried command should have run in the manifest's directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skip.txt")); err == nil {
+		t.Error("a retry should run only the one command")
+	}
+
+	if err := RunPostSetupCommand(context.Background(), m, nil, 2, quietLogger(), nil); err == nil {
+		t.Error("expected an error for a command index out of range")
+	}
+}
+
+func TestRunPostSetup_WorkDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "ios", "App"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{
+		{Run: "echo root> root.txt"},
+		{Run: "echo slash> slash.txt", Dir: "ios/App"},
+		{Run: "echo backslash> backslash.txt", Dir: `ios\App`},
+	}
+
+	if _, err := RunPostSetup(context.Background(), m, nil, quietLogger(), nil); err != nil {
+		t.Fatal(err)
+	}
+	for file, where := range map[string]string{
+		"root.txt":      dir,
+		"slash.txt":     filepath.Join(dir, "ios", "App"),
+		"backslash.txt": filepath.Join(dir, "ios", "App"),
+	} {
+		if _, err := os.Stat(filepath.Join(where, file)); err != nil {
+			t.Errorf("%s should have been written in %s: %s", file, where, err)
+		}
+	}
+}
+
+func TestRunPostSetup_SkipsOtherOS(t *testing.T) {
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	other := "windows"
+	if runtime.GOOS == "windows" {
+		other = "linux"
+	}
+	m.PostSetup.Commands = []manifest.Command{{Run: "echo x> other.txt", OS: []string{other}}}
+
+	if _, err := RunPostSetup(context.Background(), m, nil, quietLogger(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.txt")); err == nil {
+		t.Error("a command for another OS should be skipped")
+	}
+}
+
+func TestRunInstall_RunsInManifestDir(t *testing.T) {
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.Packages.InstallCommand = "echo one> install.txt && echo two>> install.txt"
+
+	if err := RunInstall(context.Background(), installPlan(m), quietLogger(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "install.txt")); got != "one\ntwo" {
+		t.Errorf("install.txt = %q, want both chained commands' output", got)
+	}
+
+	m.Packages.InstallCommand = "exit 3"
+	if err := RunInstall(context.Background(), installPlan(m), quietLogger(), nil); err == nil || !strings.Contains(err.Error(), "package install failed") {
+		t.Errorf("RunInstall() error = %v, want the failure reported", err)
+	}
+}
+
+func TestRunInstall_Entries(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"frontend", "backend", "docs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &manifest.Manifest{Dir: dir}
+	m.Packages.Install = []manifest.PackageInstall{
+		{Dir: "frontend", Command: "echo front> installed.txt"},
+		{Dir: "backend", Command: "exit 3"},
+		{Dir: "docs", Command: "echo docs> installed.txt"},
+	}
+
+	err := RunInstall(context.Background(), installPlan(m), quietLogger(), nil)
+	if err == nil || !strings.Contains(err.Error(), "package install in backend failed") {
+		t.Errorf("RunInstall() error = %v, want the backend entry's failure", err)
+	}
+	if err != nil && (strings.Contains(err.Error(), "frontend") || strings.Contains(err.Error(), "docs")) {
+		t.Errorf("RunInstall() error = %v, want only the failing entry named", err)
+	}
+	// The failure doesn't stop the entries after it
+	for file, want := range map[string]string{"frontend": "front", "docs": "docs"} {
+		if got := readFile(t, filepath.Join(dir, file, "installed.txt")); got != want {
+			t.Errorf("%s/installed.txt = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestRunPostSetup_InjectsEnv(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	t.Setenv("TEMPLATR_TEST_PARENT", "kept")
+	log := quietLogger()
+	m := &manifest.Manifest{Dir: dir}
+	m.Env = []manifest.EnvVar{{Key: "DATABASE_URL"}, {Key: "API_KEY", Type: "secret"}}
+	m.PostSetup.Commands = []manifest.Command{
+		{Run: `printf '%s|%s' "$DATABASE_URL" "$TEMPLATR_TEST_PARENT" > env.txt && echo "key $API_KEY"`},
+	}
+	env := map[string]string{"DATABASE_URL": "postgres://localhost/app", "API_KEY": "sk-live-123"}
+
+	var out strings.Builder
+	if _, err := RunPostSetup(context.Background(), m, env, log, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "env.txt")); got != "postgres://localhost/app|kept" {
+		t.Errorf("env.txt = %q, want the injected value alongside the inherited environment", got)
+	}
+	if got := out.String(); strings.Contains(got, "sk-live-123") || !strings.Contains(got, "key ****") {
+		t.Errorf("output = %q, want the secret masked", got)
+	}
+}
+
+func TestRunPostSetup_CapturesOutput(t *testing.T) {
+	dir := t.TempDir()
+	log := quietLogger()
+	log.AddSecret("hunter2")
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{{Run: "echo building && echo token hunter2 1>&2"}}
+
+	var out strings.Builder
+	if _, err := RunPostSetup(context.Background(), m, nil, log, &out); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.TrimSpace(strings.ReplaceAll(out.String(), "\r", ""))
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("output leaked a secret: %q", got)
+	}
+	if lines := strings.Split(got, "\n"); len(lines) != 2 || strings.TrimSpace(lines[0]) != "building" || strings.TrimSpace(lines[1]) != "token ****" {
+		t.Errorf("output = %q, want stdout and masked stderr lines", got)
+	}
+}
+
+func TestRunInstall_Timeout(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.Packages.Timeout = "300ms"
+	// The backgrounded child must die with the shell, not outlive it
+	m.Packages.InstallCommand = "(sleep 1 && echo late > late.txt) & sleep 30"
+
+	start := time.Now()
+	err := RunInstall(context.Background(), installPlan(m), quietLogger(), nil)
+	elapsed := time.Since(start)
+
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("RunInstall() error = %v, want a timeout", err)
+	}
+	if timeout.Command != m.Packages.InstallCommand || !strings.Contains(err.Error(), "timed out after") {
+		t.Errorf("error = %q, want the command and elapsed time", err)
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("RunInstall() took %s, want it killed soon after the 300ms timeout", elapsed)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(dir, "late.txt")); err == nil {
+		t.Error("the command's background child should have been killed")
+	}
+}
+
+func TestRunPostSetup_Cancelled(t *testing.T) {
+	skipOnWindows(t)
+	dir := t.TempDir()
+	m := &manifest.Manifest{Dir: dir}
+	m.PostSetup.Commands = []manifest.Command{{Run: "sleep 30"}, {Run: "echo next > next.txt"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := RunPostSetup(ctx, m, nil, quietLogger(), nil)
+
+	if !errors.Is(err, install.ErrCancelled) {
+		t.Fatalf("RunPostSetup() error = %v, want a cancellation", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("RunPostSetup() took %s after cancelling", elapsed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "next.txt")); err == nil {
+		t.Error("commands after the cancelled one should not run")
+	}
+}
+
+func TestTimeoutError_Message(t *testing.T) {
+	for _, tt := range []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{10*time.Minute + 400*time.Millisecond, `"npm install" timed out after 10m0s`},
+		{312 * time.Millisecond, `"npm install" timed out after 312ms`},
+	} {
+		if got := (&TimeoutError{Command: "npm install", Elapsed: tt.elapsed}).Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package packages
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+// SetupOptions are the steps that a setup leaves for the user to do
+// themselves, from --skip-packages, --skip-post-setup and --no-path or the
+// options on the confirm screen.
+type SetupOptions struct {
+	SkipPackages  bool `json:"skipPackages,omitempty"`  // don't run RunGlobalInstalls and RunInstall
+	SkipPostSetup bool `json:"skipPostSetup,omitempty"` // don't run RunPostSetup
+
+	install.ExecuteOptions // how the runtimes are installed, e.g. NoPath
+}
+
+// ManualStep is a setup step that was skipped, with the commands to run
+// in its place from the project directory.
+type ManualStep struct {
+	Name     string   `json:"name"`
+	Commands []string `json:"commands"`
+}
+
+// ManualSteps returns the steps of plan that o skips and that had
+// commands to run, in the order setup runs them.
+func (o SetupOptions) ManualSteps(plan *engine.SetupPlan) []ManualStep {
+	var steps []ManualStep
+	if cmds := PackageCommands(plan); o.SkipPackages && len(cmds) > 0 {
+		steps = append(steps, ManualStep{Name: "Packages", Commands: cmds})
+	}
+	if cmds := PostSetupCommands(plan.Manifest); o.SkipPostSetup && len(cmds) > 0 {
+		steps = append(steps, ManualStep{Name: "Post-setup commands", Commands: cmds})
+	}
+	return steps
+}
+
+// PackageCommands returns the commands RunGlobalInstalls and RunInstall
+// would run for plan, as run from the project directory.
+func PackageCommands(plan *engine.SetupPlan) []string {
+	var cmds []string
+	if installCmd := globalInstallCommand(planManager(plan)); installCmd != "" {
+		for _, pkg := range plan.Manifest.Packages.Global {
+			cmds = append(cmds, installCmd+" "+pkg)
+		}
+	}
+	if plan.Packages != nil {
+		for _, step := range plan.Packages.Steps {
+			if strings.TrimSpace(step.Command) != "" {
+				cmds = append(cmds, inDir(manifest.PackageInstall{Dir: step.Dir}.SlashDir(), step.Command))
+			}
+		}
+	}
+	return cmds
+}
+
+// PostSetupCommands returns the post-setup commands RunPostSetup would run
+// on this OS, as run from the project directory.
+func PostSetupCommands(m *manifest.Manifest) []string {
+	var cmds []string
+	for _, c := range m.PostSetup.Commands {
+		if c.AppliesTo(runtime.GOOS) && strings.TrimSpace(c.Run) != "" {
+			cmds = append(cmds, inDir(c.SlashDir(), c.Run))
+		}
+	}
+	return cmds
+}
+
+// inDir returns command as typed from the project directory when it runs
+// in dir.
+func inDir(dir, command string) string {
+	if dir == "" {
+		return command
+	}
+	return fmt.Sprintf("cd %s && %s", dir, command)
+}
@@ -0,0 +1,52 @@
+package packages
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestManualSteps(t *testing.T) {
+	m := &manifest.Manifest{}
+	m.Packages.Manager = "pnpm"
+	m.Packages.Global = []string{"turbo"}
+	m.Packages.Install = []manifest.PackageInstall{
+		{Command: "pnpm install"},
+		{Dir: `apps\web`, Command: "pnpm install"},
+		{Dir: "docs", Command: " "},
+	}
+	other := "windows"
+	if runtime.GOOS == "windows" {
+		other = "linux"
+	}
+	m.PostSetup.Commands = []manifest.Command{
+		{Run: "pnpm db:migrate", Dir: "apps/api"},
+		{Run: "make certs", OS: []string{other}},
+		{Run: "pnpm build"},
+	}
+	plan := installPlan(m)
+
+	steps := SetupOptions{SkipPackages: true, SkipPostSetup: true}.ManualSteps(plan)
+	want := []ManualStep{
+		{Name: "Packages", Commands: []string{"pnpm add -g turbo", "pnpm install", "cd apps/web && pnpm install"}},
+		{Name: "Post-setup commands", Commands: []string{"cd apps/api && pnpm db:migrate", "pnpm build"}},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("ManualSteps() = %v, want %v", steps, want)
+	}
+
+	if steps := (SetupOptions{SkipPostSetup: true}).ManualSteps(plan); len(steps) != 1 || steps[0].Name != "Post-setup commands" {
+		t.Errorf("ManualSteps() = %v, want only the post-setup commands", steps)
+	}
+	if steps := (SetupOptions{}).ManualSteps(plan); steps != nil {
+		t.Errorf("ManualSteps() = %v, want none when nothing is skipped", steps)
+	}
+
+	// A skipped step with nothing to run isn't listed
+	empty := installPlan(&manifest.Manifest{})
+	if steps := (SetupOptions{SkipPackages: true, SkipPostSetup: true}).ManualSteps(empty); steps != nil {
+		t.Errorf("ManualSteps() = %v, want none for a manifest without packages or post-setup", steps)
+	}
+}
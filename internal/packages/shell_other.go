@@ -0,0 +1,27 @@
+//go:build !windows
+
+package packages
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// newCommand returns a command that runs in its own process group, so
+// cancelling ctx kills everything it started too, such as the node
+// processes behind npm.
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return cmd
+}
+
+// shellCommand returns a command running command through sh, so quoting,
+// && chains, pipes and VAR=value prefixes work as they do in a terminal.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	return newCommand(ctx, "sh", "-c", command)
+}
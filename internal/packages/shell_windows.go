@@ -0,0 +1,34 @@
+package packages
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// newCommand returns a command whose whole process tree is killed when ctx
+// is cancelled; killing just cmd.exe or npm.cmd would leave node running.
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+	return cmd
+}
+
+// shellCommand returns a command running command through cmd.exe. The
+// command line is passed as written: exec's argument quoting would escape
+// the quotes inside it, which cmd doesn't understand.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	comspec := os.Getenv("ComSpec")
+	if comspec == "" {
+		comspec = filepath.Join(os.Getenv("SystemRoot"), "System32", "cmd.exe")
+	}
+	cmd := newCommand(ctx, comspec)
+	// /S strips just the outer quotes, leaving any in command alone
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd /S /C "` + command + `"`}
+	return cmd
+}
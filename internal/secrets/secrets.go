@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned by Get for a name with nothing stored.
+	ErrNotFound = errors.New("secret not found")
+	// ErrUnsupported is returned by Open where no credential store can be
+	// used, such as Linux without secret-tool.
+	ErrUnsupported = errors.New("no OS credential store available")
+)
+
+// Store keeps secret values by name in a credential store.
+type Store interface {
+	Set(name, value string) error
+	Get(name string) (string, error)
+	Delete(name string) error
+}
+
+// Name returns the name a template's env var is stored under:
+// templatr-setup/<template>/<key>.
+func Name(template, key string) string {
+	return "templatr-setup/" + template + "/" + key
+}
+
+// Open returns the OS credential store: the login Keychain on macOS,
+// Credential Manager on Windows, and the Secret Service through
+// secret-tool (libsecret) on Linux. The error wraps ErrUnsupported where
+// there is none.
+func Open() (Store, error) {
+	return system()
+}
+
+// Memory is a Store that keeps values in memory, for tests.
+type Memory struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{values: make(map[string]string)}
+}
+
+func (m *Memory) Set(name, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] = value
+	return nil
+}
+
+func (m *Memory) Get(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (m *Memory) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, name)
+	return nil
+}
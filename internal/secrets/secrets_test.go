@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	if got := Name("SaaS Starter", "STRIPE_SECRET_KEY"); got != "templatr-setup/SaaS Starter/STRIPE_SECRET_KEY" {
+		t.Errorf("Name = %q", got)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	s := NewMemory()
+	if _, err := s.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get on an empty store: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Set("a", "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("a", "two"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get("a"); err != nil || v != "two" {
+		t.Errorf("Get = %q, %v, want the value set last", v, err)
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Errorf("deleting a missing name should succeed, got %v", err)
+	}
+	if _, err := s.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
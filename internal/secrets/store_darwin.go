@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// account is the account secrets are filed under in the Keychain; the
+// name is the item's service.
+const account = "templatr-setup"
+
+// keychain stores secrets in the login Keychain with the security tool.
+type keychain struct{}
+
+func system() (Store, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, fmt.Errorf("%w: security not found", ErrUnsupported)
+	}
+	return keychain{}, nil
+}
+
+func (keychain) Set(name, value string) error {
+	// Commands read from stdin with -i keep the value off the command
+	// line, where other processes could see it
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		quote(name), quote(account), hex.EncodeToString([]byte(value))))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store %s in the Keychain: %s", name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (keychain) Get(name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", name, "-a", account, "-w").Output()
+	if err != nil {
+		if isNotFound(err) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to read %s from the Keychain: %w", name, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (keychain) Delete(name string) error {
+	out, err := exec.Command("security", "delete-generic-password", "-s", name, "-a", account).CombinedOutput()
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete %s from the Keychain: %s", name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// isNotFound reports whether security exited with errSecItemNotFound.
+func isNotFound(err error) bool {
+	var exit *exec.ExitError
+	return errors.As(err, &exit) && exit.ExitCode() == 44
+}
+
+// quote single-quotes s for security's interactive mode.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
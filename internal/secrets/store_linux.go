@@ -0,0 +1,54 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretTool stores secrets in the Secret Service (GNOME Keyring, KWallet)
+// with libsecret's secret-tool.
+type secretTool struct{}
+
+func system() (Store, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, fmt.Errorf("%w: secret-tool not found (install libsecret-tools)", ErrUnsupported)
+	}
+	return secretTool{}, nil
+}
+
+// attributes identify a secret's item.
+func attributes(name string) []string {
+	return []string{"application", "templatr-setup", "name", name}
+}
+
+func (secretTool) Set(name, value string) error {
+	// store reads the value from stdin, keeping it off the command line
+	cmd := exec.Command("secret-tool", append([]string{"store", "--label=" + name}, attributes(name)...)...)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store %s with secret-tool: %s", name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (secretTool) Get(name string) (string, error) {
+	out, err := exec.Command("secret-tool", append([]string{"lookup"}, attributes(name)...)...).Output()
+	if err != nil {
+		// lookup exits 1 without output when nothing matches
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && len(out) == 0 && len(exit.Stderr) == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to read %s with secret-tool: %w", name, err)
+	}
+	return string(out), nil
+}
+
+func (secretTool) Delete(name string) error {
+	if out, err := exec.Command("secret-tool", append([]string{"clear"}, attributes(name)...)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete %s with secret-tool: %s", name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !linux && !windows
+
+package secrets
+
+import "fmt"
+
+func system() (Store, error) {
+	return nil, fmt.Errorf("%w on this platform", ErrUnsupported)
+}
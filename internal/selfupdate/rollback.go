@@ -0,0 +1,200 @@
+package selfupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	binary "github.com/creativeprojects/go-selfupdate/update"
+	"github.com/templatr/templatr-setup/internal/fsutil"
+)
+
+const (
+	backupDirName  = ".templatr/backup"
+	backupsFile    = "backups.json"
+	maxBackups     = 2
+	rollbackSuffix = ".rollback-old"
+)
+
+// backup is a copy of the binary an update replaced.
+type backup struct {
+	Version   string    `json:"version"`
+	File      string    `json:"file"` // in the backup directory
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Rollback puts back the binary the last update replaced, checks that it
+// runs, and returns its version. The backup is used up, so a second
+// rollback goes back to the one before.
+func Rollback() (string, error) {
+	exe, err := executable()
+	if err != nil {
+		return "", err
+	}
+	dir, err := backupDir()
+	if err != nil {
+		return "", err
+	}
+	return rollback(dir, exe, verifyBinary)
+}
+
+// executable returns the path of the running binary, through any
+// symlink to it, so that the binary itself is replaced.
+func executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return exe, nil
+}
+
+// backupDir returns ~/.templatr/backup.
+func backupDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find home directory: %w", err)
+	}
+	return filepath.Join(home, backupDirName), nil
+}
+
+// backupBinary copies exe, which is version, into dir before an update
+// replaces it, keeping the maxBackups newest copies.
+func backupBinary(dir, exe, version string) error {
+	version = "v" + strings.TrimPrefix(version, "v")
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", exe, err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	name := "templatr-setup-" + version
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, name), data, 0o755); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", exe, err)
+	}
+
+	backups, err := readBackups(dir)
+	if err != nil {
+		return err
+	}
+	kept := backups[:0]
+	for _, b := range backups {
+		if b.File != name {
+			kept = append(kept, b)
+		}
+	}
+	backups = append(kept, backup{Version: version, File: name, CreatedAt: time.Now().UTC()})
+	for len(backups) > maxBackups {
+		os.Remove(filepath.Join(dir, backups[0].File))
+		backups = backups[1:]
+	}
+	return writeBackups(dir, backups)
+}
+
+// rollback replaces exe with the newest backup in dir. The binary it
+// replaces is kept beside it until verify says the backup runs, and put
+// back if it doesn't. Renaming rather than overwriting exe is what lets
+// this run on Windows, where the running binary can't be written to.
+func rollback(dir, exe string, verify func(path string) error) (string, error) {
+	old := exe + rollbackSuffix
+	os.Remove(old) // left by a rollback on Windows, where it was still running
+
+	backups, err := readBackups(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", fmt.Errorf("no backup to roll back to - 'templatr-setup update' makes one before it replaces the binary")
+	}
+	last := backups[len(backups)-1]
+	path := filepath.Join(dir, last.File)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the backup of %s: %w", last.Version, err)
+	}
+	err = binary.Apply(f, binary.Options{TargetPath: exe, OldSavePath: old})
+	f.Close()
+	if err != nil {
+		if rerr := binary.RollbackError(err); rerr != nil {
+			return "", fmt.Errorf("failed to restore %s, and to put the current binary back: %w", last.Version, rerr)
+		}
+		return "", fmt.Errorf("failed to restore %s: %w", last.Version, err)
+	}
+
+	if err := verify(exe); err != nil {
+		if rerr := restore(old, exe); rerr != nil {
+			return "", fmt.Errorf("the backup of %s doesn't run (%s), and the current binary couldn't be put back from %s: %w", last.Version, err, old, rerr)
+		}
+		return "", fmt.Errorf("the backup of %s doesn't run, kept the current binary: %w", last.Version, err)
+	}
+	os.Remove(old) // fails on Windows while it runs; removed by the next rollback
+
+	os.Remove(path)
+	if err := writeBackups(dir, backups[:len(backups)-1]); err != nil {
+		return "", err
+	}
+	return last.Version, nil
+}
+
+// restore moves the binary saved at old back to exe.
+func restore(old, exe string) error {
+	if err := os.Remove(exe); err != nil {
+		return err
+	}
+	return os.Rename(old, exe)
+}
+
+// verifyBinary runs 'path version', which every release has, to check
+// that path is a working templatr-setup.
+func verifyBinary(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("'%s version' failed: %w", filepath.Base(path), err)
+	}
+	if !strings.Contains(string(out), "templatr-setup") {
+		return fmt.Errorf("'%s version' printed %q, not a templatr-setup version", filepath.Base(path), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// readBackups returns the backups recorded in dir, oldest first.
+func readBackups(dir string) ([]backup, error) {
+	path := filepath.Join(dir, backupsFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var backups []backup
+	if err := json.Unmarshal(data, &backups); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return backups, nil
+}
+
+func writeBackups(dir string, backups []backup) error {
+	data, err := json.MarshalIndent(backups, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(filepath.Join(dir, backupsFile), append(data, '\n'), 0o644)
+}
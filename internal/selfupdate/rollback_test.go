@@ -0,0 +1,132 @@
+package selfupdate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeBinary(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readBinary(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func runs(string) error { return nil }
+
+func TestBackupBinary_KeepsTwo(t *testing.T) {
+	dir, exe := filepath.Join(t.TempDir(), "backup"), filepath.Join(t.TempDir(), "templatr-setup")
+	for _, version := range []string{"1.3.0", "v1.4.0", "1.4.2"} {
+		writeBinary(t, exe, "binary "+version)
+		if err := backupBinary(dir, exe, version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := readBackups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Version != "v1.4.0" || backups[1].Version != "v1.4.2" {
+		t.Fatalf("backups = %+v, want v1.4.0 and v1.4.2", backups)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 { // the two binaries and backups.json
+		t.Errorf("backup directory holds %d files, want the two newest binaries and %s", len(entries), backupsFile)
+	}
+	if got := readBinary(t, filepath.Join(dir, backups[1].File)); got != "binary 1.4.2" {
+		t.Errorf("newest backup = %q, want the binary as it was", got)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	dir, exe := filepath.Join(t.TempDir(), "backup"), filepath.Join(t.TempDir(), "templatr-setup")
+	writeBinary(t, exe, "binary 1.4.0")
+	if err := backupBinary(dir, exe, "1.4.0"); err != nil {
+		t.Fatal(err)
+	}
+	writeBinary(t, exe, "binary 1.4.2")
+	if err := backupBinary(dir, exe, "1.4.2"); err != nil {
+		t.Fatal(err)
+	}
+	writeBinary(t, exe, "binary 1.5.0") // the update that broke
+
+	restored, err := rollback(dir, exe, runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != "v1.4.2" || readBinary(t, exe) != "binary 1.4.2" {
+		t.Errorf("rollback() = %s with %q, want v1.4.2 back", restored, readBinary(t, exe))
+	}
+	if _, err := os.Stat(exe + rollbackSuffix); !os.IsNotExist(err) {
+		t.Error("the replaced binary should be removed once the backup runs")
+	}
+
+	// The backup is used up, so the next rollback goes further back
+	if restored, err = rollback(dir, exe, runs); err != nil || restored != "v1.4.0" {
+		t.Errorf("second rollback() = %s, %v, want v1.4.0", restored, err)
+	}
+	if _, err := rollback(dir, exe, runs); err == nil {
+		t.Error("rollback() with no backups left should fail")
+	}
+}
+
+func TestRollback_BackupDoesNotRun(t *testing.T) {
+	dir, exe := filepath.Join(t.TempDir(), "backup"), filepath.Join(t.TempDir(), "templatr-setup")
+	writeBinary(t, exe, "binary 1.4.2")
+	if err := backupBinary(dir, exe, "1.4.2"); err != nil {
+		t.Fatal(err)
+	}
+	writeBinary(t, exe, "binary 1.5.0")
+
+	var verified string
+	broken := func(path string) error {
+		verified = readBinary(t, path)
+		return errors.New("exit status 2")
+	}
+	if _, err := rollback(dir, exe, broken); err == nil {
+		t.Fatal("rollback() should fail when the backup doesn't run")
+	}
+	if verified != "binary 1.4.2" {
+		t.Errorf("verified %q, want the restored backup", verified)
+	}
+	if got := readBinary(t, exe); got != "binary 1.5.0" {
+		t.Errorf("binary = %q after a failed rollback, want the current one kept", got)
+	}
+	if backups, _ := readBackups(dir); len(backups) != 1 {
+		t.Errorf("backups = %+v, want the backup kept after a failed rollback", backups)
+	}
+}
+
+func TestVerifyBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh scripts as the binary")
+	}
+	dir := t.TempDir()
+	good, bad, other := filepath.Join(dir, "good"), filepath.Join(dir, "bad"), filepath.Join(dir, "other")
+	writeBinary(t, good, "#!/bin/sh\necho \"templatr-setup $1 v1.4.2\"\n")
+	writeBinary(t, bad, "#!/bin/sh\nexit 2\n")
+	writeBinary(t, other, "#!/bin/sh\necho hello\n")
+
+	if err := verifyBinary(good); err != nil {
+		t.Errorf("verifyBinary() = %v for a working binary", err)
+	}
+	if err := verifyBinary(bad); err == nil {
+		t.Error("verifyBinary() should fail for a binary that exits non-zero")
+	}
+	if err := verifyBinary(other); err == nil {
+		t.Error("verifyBinary() should fail for something that isn't templatr-setup")
+	}
+}
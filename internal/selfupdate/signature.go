@@ -0,0 +1,110 @@
+package selfupdate
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	update "github.com/creativeprojects/go-selfupdate"
+)
+
+// releaseKey is the public key each release's checksums.txt is signed
+// with by 'cosign sign-blob': cosign.pub base64 encoded, so the -X flag
+// that sets it at build time is a single line, or just the base64 between
+// its PEM lines. A build without it can't verify signatures.
+var releaseKey string
+
+// verifySignatures is set by --verify-signatures or verify_signatures.
+var verifySignatures bool
+
+// SetVerifySignatures makes DoUpdate check the signature of the release's
+// checksums.txt against the key built into the binary, for the rest of
+// the run.
+func SetVerifySignatures(on bool) {
+	verifySignatures = on
+}
+
+// errSignature is returned (wrapped) when a signature doesn't verify.
+var errSignature = errors.New("signature verification failed")
+
+// validator returns the validator for the release assets DoUpdate
+// downloads: the archive against checksums.txt and, with
+// SetVerifySignatures, checksums.txt against checksums.txt.sig.
+func validator() (update.Validator, error) {
+	checksums := &update.ChecksumValidator{UniqueFilename: "checksums.txt"}
+	if !verifySignatures {
+		return checksums, nil
+	}
+	if releaseKey == "" {
+		return nil, fmt.Errorf("this build has no release key to verify signatures with - install an official release, or update without --verify-signatures")
+	}
+	key, err := parsePublicKey(releaseKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release key in this build: %w", err)
+	}
+	return new(update.PatternValidator).
+		Add("checksums.txt", &cosignValidator{key: key}).
+		SkipValidation("*.sig").
+		Add("*", checksums), nil
+}
+
+// cosignValidator checks a file against the signature 'cosign sign-blob'
+// writes for it to <file>.sig.
+type cosignValidator struct {
+	key *ecdsa.PublicKey
+}
+
+func (v *cosignValidator) Validate(filename string, data, signature []byte) error {
+	return verifyCosign(v.key, data, signature, filename)
+}
+
+func (v *cosignValidator) GetValidationAssetName(filename string) string {
+	return filename + ".sig"
+}
+
+// verifyCosign checks that signature, an ASN.1 ECDSA signature of the
+// SHA-256 of data, base64 encoded as cosign writes it or raw, was made
+// with key. what names the file that was signed, for the error.
+func verifyCosign(key *ecdsa.PublicKey, data, signature []byte, what string) error {
+	sig := bytes.TrimSpace(signature)
+	if decoded, err := base64.StdEncoding.DecodeString(string(sig)); err == nil {
+		sig = decoded
+	}
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(key, digest[:], sig) {
+		return fmt.Errorf("%w for %s: it wasn't signed with the templatr-setup release key", errSignature, what)
+	}
+	return nil
+}
+
+// parsePublicKey parses an ECDSA public key in PKIX form, PEM encoded,
+// base64 encoded, or PEM encoded and then base64 encoded.
+func parsePublicKey(key string) (*ecdsa.PublicKey, error) {
+	data := []byte(key)
+	if block, _ := pem.Decode(data); block == nil {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("not a PEM or base64 public key")
+		}
+		data = decoded
+	}
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		der = block.Bytes
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an ECDSA public key")
+	}
+	return ecKey, nil
+}
@@ -0,0 +1,142 @@
+package selfupdate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// cosignSign signs data as 'cosign sign-blob' does: base64 of the ASN.1
+// signature of its SHA-256.
+func cosignSign(t *testing.T, key *ecdsa.PrivateKey, data string) []byte {
+	t.Helper()
+	digest := sha256.Sum256([]byte(data))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+func publicPEM(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerifyCosign(t *testing.T) {
+	release, other := newKey(t), newKey(t)
+	const checksums = "abc123  templatr-setup_1.5.0_linux_amd64.tar.gz\n"
+
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(string(cosignSign(t, release, checksums))))
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name      string
+		data      string
+		signature []byte
+		ok        bool
+	}{
+		{"cosign signature", checksums, cosignSign(t, release, checksums), true},
+		{"raw signature", checksums, raw, true},
+		{"tampered checksums", strings.Replace(checksums, "abc123", "def456", 1), cosignSign(t, release, checksums), false},
+		{"tampered signature", checksums, tampered, false},
+		{"another key", checksums, cosignSign(t, other, checksums), false},
+		{"not a signature", checksums, []byte("hello"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyCosign(&release.PublicKey, []byte(tt.data), tt.signature, "checksums.txt")
+			if tt.ok {
+				if err != nil {
+					t.Errorf("verifyCosign() = %v, want it to verify", err)
+				}
+				return
+			}
+			if !errors.Is(err, errSignature) || !strings.Contains(err.Error(), "checksums.txt") {
+				t.Errorf("verifyCosign() = %v, want a signature error naming checksums.txt", err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key := newKey(t)
+	pemKey := publicPEM(t, key)
+	body := strings.Join(strings.Split(pemKey, "\n")[1:3], "")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(pemKey))
+
+	for _, k := range []string{pemKey, body, encoded} {
+		parsed, err := parsePublicKey(k)
+		if err != nil {
+			t.Fatalf("parsePublicKey(%q) = %v", k, err)
+		}
+		if !parsed.Equal(&key.PublicKey) {
+			t.Errorf("parsePublicKey(%q) returned another key", k)
+		}
+	}
+	if _, err := parsePublicKey("not a key"); err == nil {
+		t.Error("parsePublicKey() should fail for something that isn't a key")
+	}
+}
+
+func TestValidator(t *testing.T) {
+	t.Cleanup(func() { releaseKey, verifySignatures = "", false })
+	key := newKey(t)
+	asset := "archive"
+	sum := sha256.Sum256([]byte(asset))
+	checksums := hex.EncodeToString(sum[:]) + "  templatr-setup_1.5.0_linux_amd64.tar.gz\n"
+
+	// Off: checksums only, so a pre-signing release still updates
+	v, err := validator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GetValidationAssetName("checksums.txt") == "checksums.txt.sig" {
+		t.Error("checksums.txt shouldn't need a signature without --verify-signatures")
+	}
+
+	SetVerifySignatures(true)
+	if _, err := validator(); err == nil {
+		t.Fatal("validator() should fail for a build without a release key")
+	}
+
+	releaseKey = publicPEM(t, key)
+	if v, err = validator(); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetValidationAssetName("checksums.txt"); got != "checksums.txt.sig" {
+		t.Errorf("checksums.txt is validated with %s, want checksums.txt.sig", got)
+	}
+	if err := v.Validate("templatr-setup_1.5.0_linux_amd64.tar.gz", []byte(asset), []byte(checksums)); err != nil {
+		t.Errorf("archive against checksums.txt: %v", err)
+	}
+	if err := v.Validate("checksums.txt", []byte(checksums), cosignSign(t, key, checksums)); err != nil {
+		t.Errorf("checksums.txt against its signature: %v", err)
+	}
+	if err := v.Validate("checksums.txt", []byte(checksums), cosignSign(t, newKey(t), checksums)); !errors.Is(err, errSignature) {
+		t.Errorf("checksums.txt signed with another key = %v, want a signature error", err)
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
 	update "github.com/creativeprojects/go-selfupdate"
+	"github.com/templatr/templatr-setup/internal/install"
 )
 
 const (
@@ -22,6 +24,32 @@ const (
 	latestCacheFile = ".templatr/latest_version"
 )
 
+// The update channels: stable only takes releases, beta pre-releases such
+// as v1.5.0-beta.1 as well.
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+// noCheck is set from update_check.enabled in the configuration.
+var noCheck bool
+
+// DisableCheck turns CheckForUpdate off for the rest of the run, as
+// TEMPLATR_NO_UPDATE_CHECK does. It is set from update_check.enabled.
+func DisableCheck(off bool) {
+	noCheck = off
+}
+
+// CheckDisabled reports whether CheckForUpdate is turned off, by
+// DisableCheck or TEMPLATR_NO_UPDATE_CHECK.
+func CheckDisabled() bool {
+	return checkDisabled(noCheck, os.Getenv)
+}
+
+func checkDisabled(off bool, getenv func(string) string) bool {
+	return off || getenv("TEMPLATR_NO_UPDATE_CHECK") != ""
+}
+
 // CheckResult contains the result of an update check.
 type CheckResult struct {
 	CurrentVersion string
@@ -29,115 +57,212 @@ type CheckResult struct {
 	UpdateAvail    bool
 }
 
-// CheckForUpdate checks GitHub for a newer release.
-// Returns nil if no update is available, the check was done recently, or on error.
-func CheckForUpdate(currentVersion string) *CheckResult {
-	if currentVersion == "dev" || currentVersion == "" {
+// CheckForUpdate checks GitHub for a newer release on channel.
+// Returns nil if no update is available, the check was done recently or
+// is turned off, or on error.
+func CheckForUpdate(currentVersion, channel string) *CheckResult {
+	if currentVersion == "dev" || currentVersion == "" || CheckDisabled() {
 		return nil
 	}
 
 	// Check cooldown
-	if !shouldCheck() {
-		return readCachedResult(currentVersion)
+	if !shouldCheck(channel) {
+		return readCachedResult(currentVersion, channel)
 	}
 
 	// Record check time
-	recordCheckTime()
+	recordCheckTime(channel)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	latest, err := fetchLatestVersion(ctx)
+	latest, err := fetchLatestVersion(ctx, channel)
 	if err != nil {
 		return nil
 	}
 
 	// Cache the result
-	cacheResult(latest)
+	cacheResult(latest, channel)
 
-	current, err := semver.NewVersion(strings.TrimPrefix(currentVersion, "v"))
+	result, err := compareVersions(currentVersion, latest)
 	if err != nil {
 		return nil
 	}
+	return result
+}
+
+// Check looks up the latest release now, for 'update --check': unlike
+// CheckForUpdate it ignores the cooldown and the opt-out, and reports why
+// it couldn't check. The result is cached for the background check.
+func Check(currentVersion, channel string) (*CheckResult, error) {
+	if currentVersion == "dev" || currentVersion == "" {
+		return nil, fmt.Errorf("cannot check a development build for updates - install a release version")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	latest, err := fetchLatestVersion(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	recordCheckTime(channel)
+	cacheResult(latest, channel)
 
+	return compareVersions(currentVersion, latest)
+}
+
+// compareVersions returns the CheckResult for a release tag latest.
+func compareVersions(currentVersion, latest string) (*CheckResult, error) {
+	current, err := semver.NewVersion(strings.TrimPrefix(currentVersion, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse current version %q: %w", currentVersion, err)
+	}
 	latestVer, err := semver.NewVersion(strings.TrimPrefix(latest, "v"))
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("cannot parse latest version %q: %w", latest, err)
 	}
 
 	return &CheckResult{
 		CurrentVersion: currentVersion,
 		LatestVersion:  latest,
 		UpdateAvail:    latestVer.GreaterThan(current),
-	}
+	}, nil
 }
 
-// DoUpdate performs the self-update to the latest version.
-func DoUpdate(currentVersion string) error {
+// DoUpdate replaces the running binary with release version, such as
+// v1.4.2, or the latest release on channel if version is "". An older
+// version is allowed, to go back to a release that worked. The binary it
+// replaces is backed up first, for Rollback. It returns the version
+// installed.
+func DoUpdate(currentVersion, version, channel string) (string, error) {
 	if currentVersion == "dev" {
-		return fmt.Errorf("cannot update a development build - install a release version")
+		return "", fmt.Errorf("cannot update a development build - install a release version")
 	}
 
 	// Check if installed via package manager
 	if hint := detectPackageManager(); hint != "" {
-		return fmt.Errorf("it looks like you installed via %s. Update using your package manager instead", hint)
+		return "", fmt.Errorf("it looks like you installed via %s. Update using your package manager instead", hint)
 	}
 
-	source, err := update.NewGitHubSource(update.GitHubConfig{})
+	source, err := update.NewGitHubSource(update.GitHubConfig{APIToken: install.GitHubToken()})
 	if err != nil {
-		return fmt.Errorf("failed to create update source: %w", err)
+		return "", fmt.Errorf("failed to create update source: %w", err)
 	}
 
-	updater, err := update.NewUpdater(update.Config{
-		Source:    source,
-		Validator: &update.ChecksumValidator{UniqueFilename: "checksums.txt"},
-	})
+	updater, err := newUpdater(source, channel)
 	if err != nil {
-		return fmt.Errorf("failed to create updater: %w", err)
+		return "", err
 	}
 
-	latest, found, err := updater.DetectLatest(context.Background(), update.ParseSlug(repoOwner+"/"+repoName))
+	release, err := resolveRelease(context.Background(), updater, currentVersion, version)
 	if err != nil {
-		return fmt.Errorf("failed to check for updates: %w", err)
-	}
-	if !found {
-		return fmt.Errorf("no releases found")
+		return "", err
 	}
 
-	current, err := semver.NewVersion(strings.TrimPrefix(currentVersion, "v"))
+	exe, err := executable()
 	if err != nil {
-		return fmt.Errorf("cannot parse current version %q: %w", currentVersion, err)
+		return "", err
 	}
 
-	latestVer, err := semver.NewVersion(strings.TrimPrefix(latest.Version(), "v"))
+	// Keep the binary being replaced for 'update --rollback'
+	dir, err := backupDir()
 	if err != nil {
-		return fmt.Errorf("cannot parse latest version: %w", err)
+		return "", err
+	}
+	if err := backupBinary(dir, exe, currentVersion); err != nil {
+		return "", err
 	}
 
-	if !latestVer.GreaterThan(current) {
-		return fmt.Errorf("already up to date (v%s)", currentVersion)
+	if err := updater.UpdateTo(context.Background(), release, exe); err != nil {
+		return "", fmt.Errorf("update failed: %w", err)
 	}
 
-	exe, err := os.Executable()
+	return "v" + release.Version(), nil
+}
+
+// newUpdater returns an updater for the releases in source on channel.
+// Whether a release is a pre-release is up to source, as GitHub's flag.
+func newUpdater(source update.Source, channel string) (*update.Updater, error) {
+	v, err := validator()
+	if err != nil {
+		return nil, err
+	}
+	updater, err := update.NewUpdater(update.Config{
+		Source:     source,
+		Validator:  v,
+		Prerelease: channel == ChannelBeta,
+	})
 	if err != nil {
-		return fmt.Errorf("cannot determine executable path: %w", err)
+		return nil, fmt.Errorf("failed to create updater: %w", err)
 	}
+	return updater, nil
+}
 
-	if err := updater.UpdateTo(context.Background(), latest, exe); err != nil {
-		return fmt.Errorf("update failed: %w", err)
+// resolveRelease finds the release DoUpdate installs: the one tagged
+// version, with or without its leading v, or else the latest, which must
+// be newer than currentVersion.
+func resolveRelease(ctx context.Context, updater *update.Updater, currentVersion, version string) (*update.Release, error) {
+	repo := update.ParseSlug(repoOwner + "/" + repoName)
+	current, err := semver.NewVersion(strings.TrimPrefix(currentVersion, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse current version %q: %w", currentVersion, err)
+	}
+
+	if version == "" {
+		latest, found, err := updater.DetectLatest(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for updates: %w", err)
+		}
+		if !found {
+			return nil, fmt.Errorf("no releases found")
+		}
+		latestVer, err := semver.NewVersion(latest.Version())
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse latest version: %w", err)
+		}
+		if !latestVer.GreaterThan(current) {
+			return nil, fmt.Errorf("already up to date (v%s)", strings.TrimPrefix(currentVersion, "v"))
+		}
+		return latest, nil
+	}
+
+	want, err := semver.NewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q - use a release tag such as v1.4.2", version)
+	}
+	if want.Equal(current) {
+		return nil, fmt.Errorf("v%s is already installed", want)
+	}
+	tag := "v" + strings.TrimPrefix(version, "v")
+	release, found, err := updater.DetectVersion(ctx, repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", tag, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("no release %s for %s/%s - see https://github.com/%s/%s/releases",
+			tag, runtime.GOOS, runtime.GOARCH, repoOwner, repoName)
 	}
+	return release, nil
+}
 
-	return nil
+// channelFile returns the file under home that file is kept in for
+// channel: file itself for stable, else with the channel appended.
+func channelFile(home, file, channel string) string {
+	if channel != "" && channel != ChannelStable {
+		file += "_" + channel
+	}
+	return filepath.Join(home, file)
 }
 
 // shouldCheck returns true if enough time has passed since last check.
-func shouldCheck() bool {
+func shouldCheck(channel string) bool {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return true
 	}
 
-	path := filepath.Join(home, checkFile)
+	path := channelFile(home, checkFile, channel)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return true
@@ -151,35 +276,35 @@ func shouldCheck() bool {
 	return time.Since(t) > checkCooldown
 }
 
-func recordCheckTime() {
+func recordCheckTime(channel string) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return
 	}
 
-	path := filepath.Join(home, checkFile)
+	path := channelFile(home, checkFile, channel)
 	os.MkdirAll(filepath.Dir(path), 0o755)
 	os.WriteFile(path, []byte(time.Now().UTC().Format(time.RFC3339)), 0o644)
 }
 
-func cacheResult(version string) {
+func cacheResult(version, channel string) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return
 	}
 
-	path := filepath.Join(home, latestCacheFile)
+	path := channelFile(home, latestCacheFile, channel)
 	os.MkdirAll(filepath.Dir(path), 0o755)
 	os.WriteFile(path, []byte(version), 0o644)
 }
 
-func readCachedResult(currentVersion string) *CheckResult {
+func readCachedResult(currentVersion, channel string) *CheckResult {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil
 	}
 
-	data, err := os.ReadFile(filepath.Join(home, latestCacheFile))
+	data, err := os.ReadFile(channelFile(home, latestCacheFile, channel))
 	if err != nil {
 		return nil
 	}
@@ -189,35 +314,22 @@ func readCachedResult(currentVersion string) *CheckResult {
 		return nil
 	}
 
-	current, err := semver.NewVersion(strings.TrimPrefix(currentVersion, "v"))
-	if err != nil {
-		return nil
-	}
-	latestVer, err := semver.NewVersion(strings.TrimPrefix(latest, "v"))
-	if err != nil {
-		return nil
-	}
-
-	result := &CheckResult{
-		CurrentVersion: currentVersion,
-		LatestVersion:  latest,
-		UpdateAvail:    latestVer.GreaterThan(current),
-	}
-
-	if !result.UpdateAvail {
+	result, err := compareVersions(currentVersion, latest)
+	if err != nil || !result.UpdateAvail {
 		return nil
 	}
 	return result
 }
 
-// fetchLatestVersion makes a lightweight API call to get the latest release tag.
-func fetchLatestVersion(ctx context.Context) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", repoOwner, repoName)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// fetchLatestVersion makes a lightweight API call to get the latest release
+// tag on channel. /releases/latest would only ever give the newest release
+// that isn't a pre-release, so the recent releases are listed instead.
+func fetchLatestVersion(ctx context.Context, channel string) (string, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases?per_page=30", repoOwner, repoName)
+	req, err := install.NewGitHubRequest(ctx, url)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -226,17 +338,52 @@ func fetchLatestVersion(ctx context.Context) (string, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("GitHub API returned %d", resp.StatusCode)
+		return "", install.GitHubResponseError(resp)
 	}
 
-	var release struct {
-		TagName string `json:"tag_name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
 		return "", err
 	}
 
-	return release.TagName, nil
+	latest := latestRelease(releases, channel)
+	if latest == "" {
+		return "", fmt.Errorf("no %s releases found", channel)
+	}
+	return latest, nil
+}
+
+// githubRelease is the part of a GitHub release fetchLatestVersion reads.
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// latestRelease returns the tag of the highest version in releases on
+// channel, by semver ordering so that v1.5.0 > v1.5.0-beta.10 >
+// v1.5.0-beta.2, or "" if there is none. A release is a pre-release if
+// GitHub says so or its tag has a pre-release part; drafts and tags that
+// aren't versions are skipped.
+func latestRelease(releases []githubRelease, channel string) string {
+	var latest string
+	var latestVer *semver.Version
+	for _, rel := range releases {
+		if rel.Draft {
+			continue
+		}
+		v, err := semver.NewVersion(strings.TrimPrefix(rel.TagName, "v"))
+		if err != nil {
+			continue
+		}
+		if (rel.Prerelease || v.Prerelease() != "") && channel != ChannelBeta {
+			continue
+		}
+		if latestVer == nil || v.GreaterThan(latestVer) {
+			latest, latestVer = rel.TagName, v
+		}
+	}
+	return latest
 }
 
 // detectPackageManager checks if the binary was installed via a package manager.
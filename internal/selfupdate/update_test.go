@@ -0,0 +1,201 @@
+package selfupdate
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	update "github.com/creativeprojects/go-selfupdate"
+)
+
+func TestCheckDisabled(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+	if checkDisabled(false, env(nil)) {
+		t.Error("checkDisabled() = true with neither the setting nor TEMPLATR_NO_UPDATE_CHECK")
+	}
+	if !checkDisabled(true, env(nil)) {
+		t.Error("checkDisabled() = false with update_check.enabled off")
+	}
+	if !checkDisabled(false, env(map[string]string{"TEMPLATR_NO_UPDATE_CHECK": "1"})) {
+		t.Error("checkDisabled() = false with TEMPLATR_NO_UPDATE_CHECK set")
+	}
+}
+
+func TestCheckForUpdate_Disabled(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("TEMPLATR_NO_UPDATE_CHECK", "")
+	t.Cleanup(func() { DisableCheck(false) })
+
+	// A recent check that found a newer release, so no request is made
+	dir := filepath.Join(home, ".templatr")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(home, checkFile), []byte(time.Now().UTC().Format(time.RFC3339)), 0o644)
+	os.WriteFile(filepath.Join(home, latestCacheFile), []byte("v1.5.0"), 0o644)
+
+	if result := CheckForUpdate("v1.4.2", ChannelStable); result == nil || result.LatestVersion != "v1.5.0" {
+		t.Fatalf("CheckForUpdate() = %+v, want v1.5.0 from the cache", result)
+	}
+
+	DisableCheck(true)
+	if result := CheckForUpdate("v1.4.2", ChannelStable); result != nil {
+		t.Errorf("CheckForUpdate() = %+v with the check turned off, want nil", result)
+	}
+	DisableCheck(false)
+
+	t.Setenv("TEMPLATR_NO_UPDATE_CHECK", "1")
+	if result := CheckForUpdate("v1.4.2", ChannelStable); result != nil {
+		t.Errorf("CheckForUpdate() = %+v with TEMPLATR_NO_UPDATE_CHECK set, want nil", result)
+	}
+}
+
+type fakeRelease struct {
+	tag        string
+	prerelease bool
+	assets     []string
+}
+
+func (r *fakeRelease) GetID() int64              { return 1 }
+func (r *fakeRelease) GetTagName() string        { return r.tag }
+func (r *fakeRelease) GetDraft() bool            { return false }
+func (r *fakeRelease) GetPrerelease() bool       { return r.prerelease }
+func (r *fakeRelease) GetPublishedAt() time.Time { return time.Time{} }
+func (r *fakeRelease) GetReleaseNotes() string   { return "" }
+func (r *fakeRelease) GetName() string           { return r.tag }
+func (r *fakeRelease) GetURL() string            { return "" }
+
+func (r *fakeRelease) GetAssets() []update.SourceAsset {
+	var assets []update.SourceAsset
+	for _, name := range r.assets {
+		assets = append(assets, fakeAsset(name))
+	}
+	return assets
+}
+
+type fakeAsset string
+
+func (a fakeAsset) GetID() int64                  { return 1 }
+func (a fakeAsset) GetName() string               { return string(a) }
+func (a fakeAsset) GetSize() int                  { return 0 }
+func (a fakeAsset) GetBrowserDownloadURL() string { return "https://example.com/" + string(a) }
+
+// fakeSource lists releases; nothing is downloaded from it.
+type fakeSource []*fakeRelease
+
+func (s fakeSource) ListReleases(ctx context.Context, repository update.Repository) ([]update.SourceRelease, error) {
+	var rels []update.SourceRelease
+	for _, rel := range s {
+		rels = append(rels, rel)
+	}
+	return rels, nil
+}
+
+func (s fakeSource) DownloadReleaseAsset(ctx context.Context, rel *update.Release, assetID int64) (io.ReadCloser, error) {
+	panic("resolveRelease shouldn't download anything")
+}
+
+func TestResolveRelease(t *testing.T) {
+	assets := func(version string) []string {
+		return []string{"templatr-setup_" + version + "_" + runtime.GOOS + "_" + runtime.GOARCH + ".tar.gz", "checksums.txt"}
+	}
+	source := fakeSource{
+		{tag: "v1.6.0-beta.2", prerelease: true, assets: assets("1.6.0-beta.2")},
+		{tag: "v1.6.0-beta.10", prerelease: true, assets: assets("1.6.0-beta.10")},
+		{tag: "v1.5.0", assets: assets("1.5.0")},
+		{tag: "v1.4.2", assets: assets("1.4.2")},
+		{tag: "v1.3.0", assets: assets("1.3.0")},
+		{tag: "v1.2.0", assets: []string{"templatr-setup_1.2.0_plan9_mips.tar.gz"}},
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		current string
+		version string
+		want    string // the release found, or the error's text
+		wantErr bool
+	}{
+		{"latest", ChannelStable, "v1.4.2", "", "1.5.0", false},
+		{"latest skips pre-releases", ChannelStable, "v1.3.0", "", "1.5.0", false},
+		{"already latest", ChannelStable, "v1.5.0", "", "already up to date", true},
+		{"beta takes pre-releases", ChannelBeta, "v1.5.0", "", "1.6.0-beta.10", false},
+		{"beta from an older beta", ChannelBeta, "v1.6.0-beta.2", "", "1.6.0-beta.10", false},
+		{"stable on a beta waits for the release", ChannelStable, "v1.6.0-beta.2", "", "already up to date", true},
+		{"explicit", ChannelStable, "v1.3.0", "v1.4.2", "1.4.2", false},
+		{"explicit without v", ChannelStable, "v1.3.0", "1.4.2", "1.4.2", false},
+		{"downgrade", ChannelStable, "v1.5.0", "v1.3.0", "1.3.0", false},
+		{"explicit pre-release", ChannelStable, "v1.5.0", "v1.6.0-beta.2", "1.6.0-beta.2", false},
+		{"same version", ChannelStable, "v1.4.2", "1.4.2", "already installed", true},
+		{"no build for this platform", ChannelStable, "v1.5.0", "v1.2.0", "no release v1.2.0", true},
+		{"no such release", ChannelStable, "v1.5.0", "v9.9.9", "no release v9.9.9", true},
+		{"not a version", ChannelStable, "v1.5.0", "latest", "invalid version", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater, err := newUpdater(source, tt.channel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			release, err := resolveRelease(context.Background(), updater, tt.current, tt.version)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("resolveRelease() error = %v, want one mentioning %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if release.Version() != tt.want {
+				t.Errorf("resolveRelease() = %s, want %s", release.Version(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	releases := []githubRelease{
+		{TagName: "v1.6.0", Draft: true},
+		{TagName: "v1.6.0-beta.2", Prerelease: true},
+		{TagName: "v1.6.0-beta.10", Prerelease: true},
+		{TagName: "v1.6.0-alpha.1"}, // tagged as a pre-release without the flag
+		{TagName: "v1.5.1"},
+		{TagName: "nightly"},
+		{TagName: "v1.5.0"},
+	}
+	if got := latestRelease(releases, ChannelStable); got != "v1.5.1" {
+		t.Errorf("latestRelease(stable) = %s, want v1.5.1", got)
+	}
+	if got := latestRelease(releases, ChannelBeta); got != "v1.6.0-beta.10" {
+		t.Errorf("latestRelease(beta) = %s, want v1.6.0-beta.10, the highest pre-release by semver", got)
+	}
+
+	// The release is newer than its pre-releases
+	releases = append(releases, githubRelease{TagName: "v1.6.0"})
+	if got := latestRelease(releases, ChannelBeta); got != "v1.6.0" {
+		t.Errorf("latestRelease(beta) = %s, want v1.6.0 over its betas", got)
+	}
+	if got := latestRelease(releases[1:4], ChannelStable); got != "" {
+		t.Errorf("latestRelease(stable) of pre-releases only = %s, want none", got)
+	}
+}
+
+func TestChannelFile(t *testing.T) {
+	home := t.TempDir()
+	if got := channelFile(home, latestCacheFile, ChannelStable); got != filepath.Join(home, latestCacheFile) {
+		t.Errorf("stable cache = %s, want %s as before channels", got, latestCacheFile)
+	}
+	if got := channelFile(home, latestCacheFile, ChannelBeta); got != filepath.Join(home, latestCacheFile+"_beta") {
+		t.Errorf("beta cache = %s, want its own file", got)
+	}
+}
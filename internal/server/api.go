@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+)
+
+// maxManifestSize is the largest manifest POST /api/manifest accepts.
+const maxManifestSize = 1 << 20
+
+// apiError is the body of a failed /api request.
+type apiError struct {
+	Error       string           `json:"error"`
+	FieldErrors []FieldErrorData `json:"fieldErrors,omitempty"`
+}
+
+// manifestResponse is the body of a POST /api/manifest response. Plan is
+// nil when the manifest has errors or its plan couldn't be built.
+type manifestResponse struct {
+	Validation *ValidationData  `json:"validation"`
+	Plan       *engine.PlanData `json:"plan,omitempty"`
+}
+
+// progressResponse is the body of a GET /api/progress response: the latest
+// status of each step and each runtime or download, as sent over the
+// WebSocket, and how the setup ended once it has.
+type progressResponse struct {
+	Phase     setupPhase             `json:"phase"`
+	Steps     []ServerMessage        `json:"steps"`
+	Runtimes  []ServerMessage        `json:"runtimes"`
+	PostSetup []PostSetupCommandData `json:"postSetup,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Complete  *ServerMessage         `json:"complete,omitempty"`
+}
+
+// phaseResponse is the body of a request that started or stopped work.
+type phaseResponse struct {
+	Phase setupPhase `json:"phase"`
+}
+
+// handleManifest loads the TOML in the request body, as "load_manifest"
+// does, and answers with its validation result and plan. A manifest with
+// only warnings gets its plan straight away, as "proceed" would give it.
+// ?name= is the file name its problems are reported against.
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxManifestSize))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read manifest: %s", err))
+		return
+	}
+	if current, ok := s.enterPhase(phasePlanning, phaseIdle, phaseDone); !ok {
+		writeAPIError(w, http.StatusConflict, refusal("load a manifest", current))
+		return
+	}
+	defer s.setPhase(phaseIdle)
+
+	plan, result := s.loadManifestFromContent(string(content), r.URL.Query().Get("name"))
+	if plan == nil && result.Valid {
+		plan, result = s.proceedPastWarnings()
+	}
+	status := http.StatusOK
+	if plan == nil {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, manifestResponse{Validation: result, Plan: plan})
+}
+
+// handlePlan answers with the plan of the loaded manifest.
+func (s *Server) handlePlan(w http.ResponseWriter, _ *http.Request) {
+	rec := s.hub.recorded()
+	if rec.plan == nil {
+		writeAPIError(w, http.StatusNotFound, "No manifest loaded. POST one to /api/manifest first.")
+		return
+	}
+	writeJSON(w, http.StatusOK, rec.plan.Plan)
+}
+
+// handleInstall starts installing the plan, as "confirm" does. An optional
+// JSON body sets the "overrides" and "options" of a "confirm" message. Progress is polled
+// from GET /api/progress.
+func (s *Server) handleInstall(w http.ResponseWriter, r *http.Request) {
+	var msg ClientMessage
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxManifestSize))
+	if err == nil && len(body) > 0 {
+		err = json.Unmarshal(body, &msg)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("Invalid install request: %s", err))
+		return
+	}
+	if current, ok := s.enterPhase(phaseInstalling, phaseIdle, phaseDone); !ok {
+		writeAPIError(w, http.StatusConflict, refusal("start the installation", current))
+		return
+	}
+	if s.loadedManifest == nil {
+		s.setPhase(phaseIdle)
+		writeAPIError(w, http.StatusConflict, "No manifest loaded. POST one to /api/manifest first.")
+		return
+	}
+	s.overrides, s.options = msg.Overrides, msg.Options
+	go s.runInstallation(false)
+	writeJSON(w, http.StatusAccepted, phaseResponse{Phase: phaseInstalling})
+}
+
+// handleProgress answers with how far the setup has got.
+func (s *Server) handleProgress(w http.ResponseWriter, _ *http.Request) {
+	rec := s.hub.recorded()
+	resp := progressResponse{
+		Phase:    s.currentPhase(),
+		Steps:    append([]ServerMessage{}, rec.steps...),
+		Runtimes: append([]ServerMessage{}, rec.runtimes...),
+		Complete: rec.complete,
+	}
+	if rec.postSetup != nil {
+		resp.PostSetup = rec.postSetup.PostSetup
+	}
+	if rec.err != nil {
+		resp.Error = rec.err.Message
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleConfigure writes the env and config values in the JSON body, with
+// the fields of a "configure" message, and finishes the setup. Invalid
+// values are refused with their field errors.
+func (s *Server) handleConfigure(w http.ResponseWriter, r *http.Request) {
+	var msg ClientMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxManifestSize)).Decode(&msg); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("Invalid configure request: %s", err))
+		return
+	}
+	if current, ok := s.enterPhase(phaseFinishing, phaseConfiguring); !ok {
+		writeAPIError(w, http.StatusConflict, refusal("save the configuration", current))
+		return
+	}
+	if errs := configureErrors(s.loadedManifest, msg); len(errs) > 0 {
+		s.setPhase(phaseConfiguring)
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{Error: "Some values are invalid.", FieldErrors: errs})
+		return
+	}
+	go s.runConfigure(msg)
+	writeJSON(w, http.StatusAccepted, phaseResponse{Phase: phaseFinishing})
+}
+
+// handleCancel stops the setup, as "cancel" does. A running installation
+// is still stopping when it answers 202.
+func (s *Server) handleCancel(w http.ResponseWriter, _ *http.Request) {
+	if s.cancelSetup() {
+		writeJSON(w, http.StatusAccepted, phaseResponse{Phase: s.currentPhase()})
+		return
+	}
+	writeJSON(w, http.StatusOK, phaseResponse{Phase: s.currentPhase()})
+}
+
+// writeJSON answers with v as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError answers with message as an apiError.
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Error: message})
+}
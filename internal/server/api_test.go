@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+const apiManifest = `
+[template]
+name = "API Test"
+version = "1.0.0"
+
+[runtimes]
+node = ">=20"
+`
+
+// newAPIServer returns a server with a fake plan and a runtime installer
+// that runs runInstall, serving its routes, and a function that makes an
+// authorized request to them and decodes the JSON answer into v.
+func newAPIServer(t *testing.T, runInstall func(context.Context) error) (*Server, func(method, path, body string, v any) int) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Chdir(t.TempDir())
+
+	s := New(embed.FS{}, logger.New(), "")
+	s.buildPlan = func(m *manifest.Manifest) (*engine.SetupPlan, error) {
+		return &engine.SetupPlan{Manifest: m, Runtimes: []engine.RuntimePlan{
+			{Name: "node", DisplayName: "Node.js", RequiredVersion: ">=20", Action: engine.ActionInstall},
+		}}, nil
+	}
+	s.installRuntime = func(ctx context.Context, rp engine.RuntimePlan, _ string, _ *logger.Logger, _ install.EventFunc, _ install.ExecuteOptions) (*install.InstallResult, error) {
+		if err := runInstall(ctx); err != nil {
+			return nil, err
+		}
+		return &install.InstallResult{Runtime: rp.Name, Version: "22.14.0"}, nil
+	}
+	go s.hub.Run()
+	srv := httptest.NewServer(s.routes())
+	t.Cleanup(srv.Close)
+
+	do := func(method, path, body string, v any) int {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("%s %s: decoding the answer: %v", method, path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+	return s, do
+}
+
+// waitForPhase polls GET /api/progress until the setup is in phase p.
+func waitForPhase(t *testing.T, do func(method, path, body string, v any) int, p setupPhase) progressResponse {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var progress progressResponse
+		do(http.MethodGet, "/api/progress", "", &progress)
+		if progress.Phase == p {
+			return progress
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("phase = %q, want %q: %+v", progress.Phase, p, progress)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAPI_Setup(t *testing.T) {
+	_, do := newAPIServer(t, func(context.Context) error { return nil })
+
+	var errResp apiError
+	if code := do(http.MethodGet, "/api/plan", "", &errResp); code != http.StatusNotFound {
+		t.Errorf("GET /api/plan before a manifest = %d, want %d", code, http.StatusNotFound)
+	}
+
+	var invalid manifestResponse
+	if code := do(http.MethodPost, "/api/manifest", "[template\n", &invalid); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST /api/manifest with bad TOML = %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+	if invalid.Plan != nil || invalid.Validation == nil || invalid.Validation.Stage != StageParse {
+		t.Errorf("bad TOML answer = %+v, want a parse error and no plan", invalid)
+	}
+
+	var loaded manifestResponse
+	if code := do(http.MethodPost, "/api/manifest?name=.templatr.toml", apiManifest, &loaded); code != http.StatusOK {
+		t.Fatalf("POST /api/manifest = %d, want %d: %+v", code, http.StatusOK, loaded)
+	}
+	if loaded.Plan == nil || loaded.Plan.Template.Name != "API Test" || !loaded.Validation.Valid {
+		t.Fatalf("POST /api/manifest answer = %+v, want the valid manifest's plan", loaded)
+	}
+
+	var plan engine.PlanData
+	if code := do(http.MethodGet, "/api/plan", "", &plan); code != http.StatusOK || plan.Template.Name != "API Test" {
+		t.Errorf("GET /api/plan = %d, %+v, want the loaded plan", code, plan)
+	}
+
+	var started phaseResponse
+	if code := do(http.MethodPost, "/api/install", "", &started); code != http.StatusAccepted || started.Phase != phaseInstalling {
+		t.Errorf("POST /api/install = %d, %+v, want %d installing", code, started, http.StatusAccepted)
+	}
+	progress := waitForPhase(t, do, phaseDone)
+	if progress.Complete == nil || !progress.Complete.Success {
+		t.Errorf("progress = %+v, want a complete setup", progress)
+	}
+	if len(progress.Runtimes) == 0 || progress.Runtimes[len(progress.Runtimes)-1].Status != "complete" {
+		t.Errorf("runtimes = %+v, want node complete", progress.Runtimes)
+	}
+}
+
+func TestAPI_Conflict(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	s, do := newAPIServer(t, func(ctx context.Context) error {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return install.ErrCancelled
+		}
+	})
+	defer close(release)
+
+	var errResp apiError
+	if code := do(http.MethodPost, "/api/install", "", &errResp); code != http.StatusConflict || !strings.Contains(errResp.Error, "No manifest loaded") {
+		t.Errorf("POST /api/install before a manifest = %d, %+v, want %d", code, errResp, http.StatusConflict)
+	}
+	if code := do(http.MethodPost, "/api/configure", `{"env":{}}`, &errResp); code != http.StatusConflict {
+		t.Errorf("POST /api/configure before the install = %d, want %d", code, http.StatusConflict)
+	}
+
+	do(http.MethodPost, "/api/manifest", apiManifest, nil)
+	if code := do(http.MethodPost, "/api/install", "", nil); code != http.StatusAccepted {
+		t.Fatalf("POST /api/install = %d, want %d", code, http.StatusAccepted)
+	}
+	<-started
+	if code := do(http.MethodPost, "/api/install", "", &errResp); code != http.StatusConflict || errResp.Error != "Can't start the installation: an installation is already running." {
+		t.Errorf("second POST /api/install = %d, %+v, want %d", code, errResp, http.StatusConflict)
+	}
+	if code := do(http.MethodPost, "/api/manifest", apiManifest, &errResp); code != http.StatusConflict {
+		t.Errorf("POST /api/manifest during the install = %d, want %d", code, http.StatusConflict)
+	}
+
+	// The WebSocket API is refused the same way
+	s.handleClientMessage(nil, ClientMessage{Type: "confirm"})
+
+	var cancelled phaseResponse
+	if code := do(http.MethodPost, "/api/cancel", "", &cancelled); code != http.StatusAccepted {
+		t.Errorf("POST /api/cancel during the install = %d, want %d", code, http.StatusAccepted)
+	}
+	progress := waitForPhase(t, do, phaseDone)
+	if progress.Complete == nil || progress.Complete.Success {
+		t.Errorf("progress = %+v, want the setup cancelled", progress)
+	}
+	if progress.Error != "Can't start the installation: an installation is already running." {
+		t.Errorf("error = %q, want the WebSocket confirm refused", progress.Error)
+	}
+}
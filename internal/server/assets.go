@@ -0,0 +1,35 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// CheckAssets returns the web/dist subtree of assets, or an error explaining
+// why the web UI cannot be served (e.g. the frontend was never built).
+func CheckAssets(assets fs.FS) (fs.FS, error) {
+	distFS, err := fs.Sub(assets, "web/dist")
+	if err != nil {
+		return nil, fmt.Errorf("web/dist: %w", err)
+	}
+	if _, err := fs.Stat(distFS, "index.html"); err != nil {
+		return nil, fmt.Errorf("web/dist: %w", err)
+	}
+	return distFS, nil
+}
+
+// PromptTerminalFallback asks whether to continue with the terminal UI
+// because the web UI is not built. An empty answer means yes.
+func PromptTerminalFallback(in io.Reader, out io.Writer) bool {
+	fmt.Fprint(out, "Web UI not built — continue with terminal UI? [Y/n] ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		// No input at all (closed stdin): don't start an interactive UI.
+		return false
+	}
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewSPAHandler_Dir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>index</html>"), 0644)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+	os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("console.log(1)"), 0644)
+
+	h := newSPAHandler(http.Dir(dir))
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/", "<html>index</html>"},
+		{"/assets/app.js", "console.log(1)"},
+		{"/some/client/route", "<html>index</html>"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d", tt.path, rec.Code)
+		}
+		if body := rec.Body.String(); body != tt.want {
+			t.Errorf("GET %s: body %q, want %q", tt.path, body, tt.want)
+		}
+	}
+
+	// Edits on disk are served without rebuilding anything
+	os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("console.log(2)"), 0644)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/assets/app.js", nil))
+	if body, _ := io.ReadAll(rec.Body); string(body) != "console.log(2)" {
+		t.Errorf("expected updated file, got %q", body)
+	}
+}
+
+func TestCheckAssets(t *testing.T) {
+	built := fstest.MapFS{"web/dist/index.html": {Data: []byte("ok")}}
+	if _, err := CheckAssets(built); err != nil {
+		t.Errorf("expected built assets to be usable, got %s", err)
+	}
+
+	unbuilt := fstest.MapFS{"web/dist/.gitkeep": {}}
+	if _, err := CheckAssets(unbuilt); err == nil {
+		t.Error("expected error when index.html is missing")
+	}
+}
+
+func TestFallbackHandler_IncludesError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	fallbackHandler(errors.New("web/dist: open <index.html>: file does not exist")).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "Web UI Not Built") {
+		t.Error("expected fallback page")
+	}
+	if !strings.Contains(body, "open &lt;index.html&gt;: file does not exist") {
+		t.Errorf("expected escaped error in page, got:\n%s", body)
+	}
+}
+
+func TestPromptTerminalFallback(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"\n", true},
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"no\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if got := PromptTerminalFallback(strings.NewReader(tt.input), &out); got != tt.want {
+			t.Errorf("input %q: got %v, want %v", tt.input, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "[Y/n]") {
+			t.Errorf("expected prompt, got %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import "fmt"
+
+// setupPhase is where the server is in the setup flow. Client messages
+// that start work move it to the next phase only from the phases they
+// make sense in, so a second "confirm" from a double click or another tab
+// can't start a duplicate installation.
+type setupPhase string
+
+const (
+	phaseIdle        setupPhase = "idle"        // waiting for a manifest, or for "confirm" on its plan
+	phasePlanning    setupPhase = "planning"    // loading a manifest and building its plan
+	phaseInstalling  setupPhase = "installing"  // runtimes, downloads and packages
+	phaseConfiguring setupPhase = "configuring" // waiting for the configure form
+	phaseFinishing   setupPhase = "finishing"   // writing configuration and running post-setup commands
+	phaseDone        setupPhase = "done"        // the setup finished, failed or was cancelled
+)
+
+// phaseReasons finish "Can't <action>: ..." for a message refused in a phase.
+var phaseReasons = map[setupPhase]string{
+	phaseIdle:        "no setup is under way",
+	phasePlanning:    "a manifest is still loading",
+	phaseInstalling:  "an installation is already running",
+	phaseConfiguring: "setup is waiting for the configuration values",
+	phaseFinishing:   "setup is writing the configuration and running post-setup commands",
+	phaseDone:        "the setup has finished",
+}
+
+// refusal returns the message a request to do action is refused with in
+// phase p.
+func refusal(action string, p setupPhase) string {
+	return fmt.Sprintf("Can't %s: %s.", action, phaseReasons[p])
+}
+
+// enterPhase moves to phase to if the server is in one of from. Otherwise
+// it stays put and returns the phase it is in.
+func (s *Server) enterPhase(to setupPhase, from ...setupPhase) (setupPhase, bool) {
+	s.phaseMu.Lock()
+	defer s.phaseMu.Unlock()
+	for _, p := range from {
+		if s.phase == p {
+			s.phase = to
+			return to, true
+		}
+	}
+	return s.phase, false
+}
+
+// setPhase moves to phase p unconditionally, at the end of a step.
+func (s *Server) setPhase(p setupPhase) {
+	s.phaseMu.Lock()
+	defer s.phaseMu.Unlock()
+	s.phase = p
+}
+
+// currentPhase returns the phase the server is in.
+func (s *Server) currentPhase() setupPhase {
+	s.phaseMu.Lock()
+	defer s.phaseMu.Unlock()
+	return s.phase
+}
+
+// broadcastComplete ends the setup with msg as its "complete" message. The
+// phase changes first, so a client can act on the message right away.
+func (s *Server) broadcastComplete(msg ServerMessage) {
+	msg.Type = MsgTypeComplete
+	s.setPhase(phaseDone)
+	s.hub.Broadcast(msg)
+}
@@ -0,0 +1,95 @@
+package server
+
+import "slices"
+
+// maxReplayLogs is how many of the latest log lines a client connecting
+// mid-setup is sent.
+const maxReplayLogs = 100
+
+// setupRecord is the state of the setup so far, kept from the broadcasts
+// so a client that connects mid-setup, such as a refreshed tab, can be
+// brought up to date before it gets live messages.
+type setupRecord struct {
+	plan      *ServerMessage
+	steps     []ServerMessage // the latest message per step, in the order the steps started
+	runtimes  []ServerMessage // the latest runtime, download or install message per runtime or download ID
+	logs      []ServerMessage // the latest maxReplayLogs log lines
+	postSetup *ServerMessage
+	err       *ServerMessage
+	complete  *ServerMessage
+}
+
+// add records a broadcast. A plan starts a new setup and the install step
+// starting a new run, such as a retry, so each drops what came before it.
+func (r *setupRecord) add(msg ServerMessage) {
+	switch msg.Type {
+	case MsgTypePlan:
+		*r = setupRecord{plan: &msg}
+	case MsgTypeStep:
+		if msg.Step == "install" && msg.Status == "running" {
+			r.steps, r.runtimes = nil, nil
+			r.postSetup, r.err, r.complete = nil, nil, nil
+		}
+		r.steps = upsert(r.steps, msg, func(m ServerMessage) string { return m.Step })
+	case MsgTypeRuntime, MsgTypeDownload, MsgTypeInstall:
+		r.runtimes = upsert(r.runtimes, msg, runtimeID)
+	case MsgTypeLog:
+		r.logs = append(r.logs, msg)
+		if len(r.logs) > maxReplayLogs {
+			r.logs = r.logs[len(r.logs)-maxReplayLogs:]
+		}
+	case MsgTypePostSetup:
+		r.postSetup = &msg
+	case MsgTypeError:
+		r.err = &msg
+	case MsgTypeComplete:
+		r.complete = &msg
+	}
+}
+
+// snapshot returns the messages that bring a new client to the recorded
+// state, in the order the web UI needs them: the plan, the steps and each
+// runtime's status, the log, then how the setup ended if it has.
+func (r *setupRecord) snapshot() []ServerMessage {
+	if r.plan == nil {
+		return nil
+	}
+	msgs := []ServerMessage{*r.plan}
+	msgs = append(msgs, r.steps...)
+	msgs = append(msgs, r.runtimes...)
+	msgs = append(msgs, r.logs...)
+	for _, msg := range []*ServerMessage{r.postSetup, r.err, r.complete} {
+		if msg != nil {
+			msgs = append(msgs, *msg)
+		}
+	}
+	return msgs
+}
+
+// clone returns a copy of the record that later broadcasts don't change.
+func (r *setupRecord) clone() setupRecord {
+	c := *r
+	c.steps = slices.Clone(r.steps)
+	c.runtimes = slices.Clone(r.runtimes)
+	c.logs = slices.Clone(r.logs)
+	return c
+}
+
+// runtimeID returns the runtime or download ID a progress message is for.
+func runtimeID(msg ServerMessage) string {
+	if msg.Type == MsgTypeRuntime {
+		return msg.Name
+	}
+	return msg.Runtime
+}
+
+// upsert replaces the message in msgs with msg's key, or appends msg.
+func upsert(msgs []ServerMessage, msg ServerMessage, key func(ServerMessage) string) []ServerMessage {
+	for i, m := range msgs {
+		if key(m) == key(msg) {
+			msgs[i] = msg
+			return msgs
+		}
+	}
+	return append(msgs, msg)
+}
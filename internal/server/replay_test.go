@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/engine"
+)
+
+func TestSetupRecord_Snapshot(t *testing.T) {
+	var r setupRecord
+	if msgs := r.snapshot(); len(msgs) != 0 {
+		t.Fatalf("snapshot before a plan = %+v, want nothing", msgs)
+	}
+
+	plan := ServerMessage{Type: MsgTypePlan, Plan: &engine.PlanData{Template: engine.TemplateData{Name: "Test"}}}
+	for _, msg := range []ServerMessage{
+		{Type: MsgTypeValidation},
+		plan,
+		{Type: MsgTypeStep, Step: "install", Status: "running"},
+		{Type: MsgTypeRuntime, Name: "node", Status: "installing"},
+		{Type: MsgTypeDownload, Runtime: "node", Progress: 40},
+		{Type: MsgTypeRuntime, Name: "python", Status: "installed", Action: "skip"},
+		{Type: MsgTypeDownload, Runtime: "node", Progress: 80},
+		{Type: MsgTypeInstall, Runtime: "node", Version: "22.14.0", Status: "complete"},
+		{Type: MsgTypeStep, Step: "packages", Status: "running"},
+		{Type: MsgTypeLog, Message: "added 312 packages"},
+		{Type: MsgTypeStep, Step: "packages", Status: "complete"},
+	} {
+		r.add(msg)
+	}
+
+	want := []ServerMessage{
+		plan,
+		{Type: MsgTypeStep, Step: "install", Status: "running"},
+		{Type: MsgTypeStep, Step: "packages", Status: "complete"},
+		{Type: MsgTypeInstall, Runtime: "node", Version: "22.14.0", Status: "complete"},
+		{Type: MsgTypeRuntime, Name: "python", Status: "installed", Action: "skip"},
+		{Type: MsgTypeLog, Message: "added 312 packages"},
+	}
+	if got := r.snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Logs are capped, and a new run drops the last one's outcome
+	for i := range maxReplayLogs + 5 {
+		r.add(ServerMessage{Type: MsgTypeLog, Message: fmt.Sprint(i)})
+	}
+	r.add(ServerMessage{Type: MsgTypeComplete, Message: "Installation failed"})
+	r.add(ServerMessage{Type: MsgTypeStep, Step: "install", Status: "running"})
+	got := r.snapshot()
+	if len(r.logs) != maxReplayLogs || r.logs[0].Message != "5" {
+		t.Errorf("kept %d logs starting at %q, want the last %d", len(r.logs), r.logs[0].Message, maxReplayLogs)
+	}
+	for _, msg := range got {
+		if msg.Type == MsgTypeComplete || msg.Type == MsgTypeInstall || (msg.Type == MsgTypeStep && msg.Step == "packages") {
+			t.Errorf("a new install run should drop %+v", msg)
+		}
+	}
+
+	// A new plan starts over
+	r.add(plan)
+	if got := r.snapshot(); len(got) != 1 {
+		t.Errorf("snapshot after a new plan = %+v, want only the plan", got)
+	}
+}
+
+// receive reads n messages sent to c.
+func receive(t *testing.T, c *Client, n int) []ServerMessage {
+	t.Helper()
+	var msgs []ServerMessage
+	for range n {
+		select {
+		case msg := <-c.send:
+			msgs = append(msgs, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d of %d messages: %+v", len(msgs), n, msgs)
+		}
+	}
+	return msgs
+}
+
+func TestHub_ReplaysToNewClient(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	first := &Client{send: make(chan ServerMessage, 256)}
+	h.register <- first
+
+	broadcasts := []ServerMessage{
+		{Type: MsgTypePlan, Plan: &engine.PlanData{Template: engine.TemplateData{Name: "Test"}}},
+		{Type: MsgTypeStep, Step: "install", Status: "running"},
+		{Type: MsgTypeRuntime, Name: "node", Status: "installing", Action: "install"},
+		{Type: MsgTypeInstall, Runtime: "node", Version: "22.14.0", Status: "complete"},
+		{Type: MsgTypeStep, Step: "packages", Status: "running"},
+		{Type: MsgTypeLog, Level: "output", Message: "npm install"},
+	}
+	for _, msg := range broadcasts {
+		h.Broadcast(msg)
+	}
+	receive(t, first, len(broadcasts))
+
+	second := &Client{send: make(chan ServerMessage, 256)}
+	h.register <- second
+	want := []ServerMessage{
+		broadcasts[0],
+		broadcasts[1],
+		broadcasts[4],
+		broadcasts[3],
+		broadcasts[5],
+	}
+	if got := receive(t, second, len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Live broadcasts follow the snapshot
+	live := ServerMessage{Type: MsgTypeStep, Step: "packages", Status: "complete"}
+	h.Broadcast(live)
+	if got := receive(t, second, 1)[0]; !reflect.DeepEqual(got, live) {
+		t.Errorf("after the snapshot got %+v, want %+v", got, live)
+	}
+	if !h.hasPlan() {
+		t.Error("hasPlan = false after a plan was broadcast")
+	}
+}
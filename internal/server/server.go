@@ -2,19 +2,27 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
 	"embed"
 	"fmt"
-	"io/fs"
+	"html"
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
-	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/templatr/templatr-setup/internal/browser"
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/history"
+	"github.com/templatr/templatr-setup/internal/install"
 	"github.com/templatr/templatr-setup/internal/logger"
 	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/packages"
 )
 
 const defaultPort = 19532
@@ -22,39 +30,128 @@ const defaultPort = 19532
 // Server is the local HTTP server that serves the embedded web UI
 // and provides WebSocket/REST APIs for the setup wizard.
 type Server struct {
-	assets         embed.FS
-	log            *logger.Logger
-	hub            *Hub
-	port           int
-	srv            *http.Server
-	manifestPath   string             // path to manifest file (from --file flag)
-	loadedManifest *manifest.Manifest // parsed manifest (from file or upload)
+	assets          embed.FS
+	devAssets       string // serve the SPA from this directory instead of assets (--dev-assets)
+	log             *logger.Logger
+	hub             *Hub
+	host            string // address to listen on, 127.0.0.1 unless set with SetListen (--listen)
+	port            int
+	fixedPort       bool // port was set with SetListen (--port) rather than found by findPort
+	openBrowser     bool // open the dashboard in a browser once started (--no-open disables it)
+	srv             *http.Server
+	manifestPath    string                  // path to manifest file (from --file flag)
+	loadedManifest  *manifest.Manifest      // parsed manifest (from file or upload)
+	pendingManifest *manifest.Manifest      // manifest with only warnings, awaiting "proceed"
+	pendingResult   *ValidationData         // its validation result, with the warnings
+	report          *history.SetupReport    // current run, appended to history on completion
+	installed       []install.InstallResult // runtimes installed this run, for the env changes summary
+	skipped         []packages.ManualStep   // steps of this run left to the user, for the completion message
+	checkIgnore     bool                    // flag secret env files that git would pick up
+	toolVersion     string                  // running tool version, checked against [meta] min_tool_version
+	unignored       []string                // env files written by configure that are not git-ignored
+	token           string                  // required on /ws and /api requests; passed to the browser in its URL
+
+	// Runtime actions chosen with "confirm", applied to each plan built for it
+	overrides map[string]engine.ActionType
+	// Steps after the runtimes left out with "confirm"
+	options packages.SetupOptions
+
+	cancelMu      sync.Mutex
+	cancelInstall context.CancelFunc // stops the running installation; nil when none is running
+
+	postSetupMu       sync.Mutex
+	postSetupManifest *manifest.Manifest     // manifest the post-setup commands were run from
+	postSetup         []PostSetupCommandData // last post-setup results, updated by retries
+
+	progressMu sync.Mutex
+	completed  map[string]completedInstall // runtimes and downloads installed this session, by name or download ID
+	failed     string                      // the runtime or download that failed to install, re-run by "retry"
+
+	phaseMu sync.Mutex
+	phase   setupPhase // where the setup is; guards messages that start work
+
+	// Replaced in tests
+	buildPlan      func(*manifest.Manifest) (*engine.SetupPlan, error)
+	installRuntime func(context.Context, engine.RuntimePlan, string, *logger.Logger, install.EventFunc, install.ExecuteOptions) (*install.InstallResult, error)
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
 }
 
 // New creates a new server with the embedded web assets.
 func New(assets embed.FS, log *logger.Logger, manifestFile string) *Server {
+	hub := NewHub()
+	hub.mask = log.Mask
 	return &Server{
 		assets:       assets,
 		log:          log,
-		hub:          NewHub(),
+		hub:          hub,
+		host:         "127.0.0.1",
 		port:         defaultPort,
+		openBrowser:  true,
 		manifestPath: manifestFile,
+		checkIgnore:  true,
+		token:        rand.Text(),
+		phase:        phaseIdle,
+
+		buildPlan:      engine.BuildPlan,
+		installRuntime: install.InstallSingleRuntime,
+		pingInterval:   pingInterval,
+		pongTimeout:    pongTimeout,
 	}
 }
 
-// Start starts the HTTP server and opens the browser.
-func (s *Server) Start() error {
-	mux := http.NewServeMux()
+// SetDevAssets serves the web UI from dir on disk instead of the embedded
+// assets, so frontend changes show up without rebuilding the binary.
+func (s *Server) SetDevAssets(dir string) {
+	s.devAssets = dir
+}
 
-	// API routes
-	mux.HandleFunc("GET /api/status", s.handleStatus)
+// SetGitignoreCheck controls whether configure warns about env files with
+// secrets that are not git-ignored (--no-gitignore disables it).
+func (s *Server) SetGitignoreCheck(enabled bool) {
+	s.checkIgnore = enabled
+}
 
-	// WebSocket endpoint
-	mux.HandleFunc("/ws", s.handleWebSocket)
+// SetListen serves the dashboard on host, such as 0.0.0.0 to reach it from
+// another machine on the LAN, and on port unless it is 0. A non-loopback
+// host needs the token for every request and never opens a browser, since
+// the machine serving it is usually headless.
+func (s *Server) SetListen(host string, port int) {
+	if host != "" {
+		s.host = host
+	}
+	if port != 0 {
+		s.port = port
+		s.fixedPort = true
+	}
+	if !s.loopback() {
+		s.openBrowser = false
+	}
+}
 
-	// Serve embedded SPA assets
-	mux.Handle("/", s.spaHandler())
+// SetOpenBrowser controls whether Start opens the dashboard in a browser
+// (--no-open disables it); the URL is printed instead.
+func (s *Server) SetOpenBrowser(enabled bool) {
+	s.openBrowser = enabled && s.loopback()
+}
 
+// loopback reports whether the server only listens on this machine.
+func (s *Server) loopback() bool {
+	if s.host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(s.host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// SetToolVersion sets the running tool's version, which manifests with a
+// newer [meta] min_tool_version are refused for.
+func (s *Server) SetToolVersion(version string) {
+	s.toolVersion = version
+}
+
+// Start starts the HTTP server and opens the browser.
+func (s *Server) Start() error {
 	// Find available port
 	port, err := s.findPort()
 	if err != nil {
@@ -62,14 +159,14 @@ func (s *Server) Start() error {
 	}
 	s.port = port
 
-	addr := fmt.Sprintf("127.0.0.1:%d", s.port)
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
 	s.srv = &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           s.routes(),
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	url := fmt.Sprintf("http://%s", addr)
+	url := s.dashboardURL()
 
 	s.log.Info("Starting web dashboard on %s", url)
 
@@ -86,11 +183,16 @@ func (s *Server) Start() error {
 		s.Shutdown()
 	}()
 
-	// Open browser after a short delay to let server start
-	go func() {
-		time.Sleep(300 * time.Millisecond)
-		openBrowser(url)
-	}()
+	if s.openBrowser {
+		// Open browser after a short delay to let server start
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			_ = browser.Open(url)
+		}()
+	} else {
+		// Printed even with --quiet: without it there is no way in
+		fmt.Printf("Open %s in a browser to continue the setup.\n", url)
+	}
 
 	// Start the hub for WebSocket connections
 	go s.hub.Run()
@@ -102,6 +204,90 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// routes returns the server's HTTP handler.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// API routes, mirroring the WebSocket messages for scripts
+	mux.HandleFunc("GET /api/status", s.requireToken(s.handleStatus))
+	mux.HandleFunc("POST /api/manifest", s.requireToken(s.handleManifest))
+	mux.HandleFunc("GET /api/plan", s.requireToken(s.handlePlan))
+	mux.HandleFunc("POST /api/install", s.requireToken(s.handleInstall))
+	mux.HandleFunc("GET /api/progress", s.requireToken(s.handleProgress))
+	mux.HandleFunc("POST /api/configure", s.requireToken(s.handleConfigure))
+	mux.HandleFunc("POST /api/cancel", s.requireToken(s.handleCancel))
+
+	// WebSocket endpoint
+	mux.HandleFunc("/ws", s.requireToken(s.handleWebSocket))
+
+	// Serve embedded SPA assets, which only need the token off this machine
+	if s.loopback() {
+		mux.Handle("/", s.spaHandler())
+	} else {
+		mux.Handle("/", s.requireToken(s.spaHandler().ServeHTTP))
+	}
+
+	return mux
+}
+
+// dashboardURL returns the URL the browser is opened at, or that is
+// printed to open. It carries the token the web UI passes on to /ws.
+func (s *Server) dashboardURL() string {
+	host := s.host
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = lanAddress()
+	}
+	return fmt.Sprintf("http://%s/?token=%s", net.JoinHostPort(host, strconv.Itoa(s.port)), s.token)
+}
+
+// lanAddress returns an IPv4 address other machines can likely reach this
+// one on, for the URL of a server listening on all interfaces.
+func lanAddress() string {
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+				return ipNet.IP.String()
+			}
+		}
+	}
+	if name, err := os.Hostname(); err == nil {
+		return name
+	}
+	return "localhost"
+}
+
+// tokenCookie holds the token for a browser that opened the dashboard URL,
+// so the page's own requests for assets carry it too.
+const tokenCookie = "templatr_token"
+
+// requireToken refuses requests without the server's token, in the
+// ?token= query parameter, an "Authorization: Bearer" header for scripts,
+// or the cookie set when the URL with it is opened, so other processes and
+// web pages can't drive the setup or read the values typed into it.
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && s.validToken(bearer) {
+			next(w, r)
+			return
+		}
+		token := r.URL.Query().Get("token")
+		if token != "" && s.validToken(token) {
+			http.SetCookie(w, &http.Cookie{Name: tokenCookie, Value: token, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		} else if c, err := r.Cookie(tokenCookie); err != nil || !s.validToken(c.Value) {
+			s.log.Warn("Refused %s %s from %s: missing or invalid token", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// validToken reports whether token is the server's token.
+func (s *Server) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -109,22 +295,30 @@ func (s *Server) Shutdown() error {
 	return s.srv.Shutdown(ctx)
 }
 
-// spaHandler returns an HTTP handler that serves the embedded SPA.
-// It serves files from web/dist/ and falls back to index.html for
-// client-side routing.
+// spaHandler returns an HTTP handler that serves the SPA, either from the
+// --dev-assets directory or from web/dist/ in the embedded FS. Unknown paths
+// fall back to index.html for client-side routing.
 func (s *Server) spaHandler() http.Handler {
+	if s.devAssets != "" {
+		s.log.Info("Serving web assets from %s", s.devAssets)
+		return newSPAHandler(http.Dir(s.devAssets))
+	}
+
 	// Try to get the web/dist subdirectory from the embedded FS
-	distFS, err := fs.Sub(s.assets, "web/dist")
+	distFS, err := CheckAssets(s.assets)
 	if err != nil {
-		// No web/dist directory - return a fallback page
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.WriteHeader(http.StatusOK)
-			fmt.Fprint(w, fallbackHTML)
-		})
+		// No usable web/dist directory - return a fallback page
+		s.log.Warn("Web assets unavailable: %s", err)
+		return fallbackHandler(err)
 	}
 
-	fileServer := http.FileServer(http.FS(distFS))
+	return newSPAHandler(http.FS(distFS))
+}
+
+// newSPAHandler serves files from root, answering unknown paths with
+// index.html.
+func newSPAHandler(root http.FileSystem) http.Handler {
+	fileServer := http.FileServer(root)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Try to serve the file directly
@@ -134,7 +328,7 @@ func (s *Server) spaHandler() http.Handler {
 		}
 
 		// Check if the file exists
-		f, err := distFS.Open(path[1:]) // strip leading /
+		f, err := root.Open(path)
 		if err != nil {
 			// File not found - serve index.html for SPA routing
 			r.URL.Path = "/"
@@ -147,16 +341,39 @@ func (s *Server) spaHandler() http.Handler {
 	})
 }
 
-// handleStatus returns a simple health check response.
+// fallbackHandler serves the "Web UI Not Built" page with the reason the
+// assets could not be loaded.
+func fallbackHandler(cause error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, fallbackHTML, html.EscapeString(cause.Error()))
+	})
+}
+
+// handleStatus returns a simple health check response, with the phase the
+// setup is in.
 func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{"status":"ok","manifest":"%s"}`, s.manifestPath)
+	writeJSON(w, http.StatusOK, struct {
+		Status   string     `json:"status"`
+		Manifest string     `json:"manifest"`
+		Phase    setupPhase `json:"phase"`
+	}{"ok", s.manifestPath, s.currentPhase()})
 }
 
-// findPort tries the default port, then scans upward for an available one.
+// findPort returns the port set with SetListen if it is available, or
+// else tries the default port, then scans upward for an available one.
 func (s *Server) findPort() (int, error) {
+	if s.fixedPort {
+		ln, err := net.Listen("tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+		if err != nil {
+			return 0, fmt.Errorf("port %d is not available: %w", s.port, err)
+		}
+		ln.Close()
+		return s.port, nil
+	}
 	for port := defaultPort; port < defaultPort+100; port++ {
-		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		ln, err := net.Listen("tcp", net.JoinHostPort(s.host, strconv.Itoa(port)))
 		if err == nil {
 			ln.Close()
 			return port, nil
@@ -165,20 +382,6 @@ func (s *Server) findPort() (int, error) {
 	return 0, fmt.Errorf("no available port found in range %d-%d", defaultPort, defaultPort+100)
 }
 
-// openBrowser opens the given URL in the user's default browser.
-func openBrowser(url string) {
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command("cmd", "/c", "start", url)
-	case "darwin":
-		cmd = exec.Command("open", url)
-	default: // linux and others
-		cmd = exec.Command("xdg-open", url)
-	}
-	_ = cmd.Start()
-}
-
 const fallbackHTML = `<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -190,6 +393,7 @@ const fallbackHTML = `<!DOCTYPE html>
     .container { text-align: center; max-width: 480px; padding: 2rem; }
     h1 { font-size: 1.5rem; margin-bottom: 0.5rem; }
     p { color: #a1a1aa; line-height: 1.6; }
+    .error { font-family: ui-monospace, monospace; font-size: 0.8em; color: #71717a; }
     code { background: #27272a; padding: 0.2em 0.5em; border-radius: 4px; font-size: 0.9em; }
   </style>
 </head>
@@ -199,6 +403,7 @@ const fallbackHTML = `<!DOCTYPE html>
     <p>The web assets have not been compiled yet. Run the following to build:</p>
     <p><code>cd web && npm install && npm run build</code></p>
     <p>Then restart the tool with <code>templatr-setup --ui</code></p>
+    <p class="error">%s</p>
   </div>
 </body>
 </html>`
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+func TestDashboardURL(t *testing.T) {
+	s := New(embed.FS{}, logger.New(), "")
+	if s.token == "" {
+		t.Fatal("expected a token")
+	}
+	if url := s.dashboardURL(); url != "http://127.0.0.1:19532/?token="+s.token {
+		t.Errorf("dashboardURL() = %q, want the token in its query", url)
+	}
+	if other := New(embed.FS{}, logger.New(), ""); other.token == s.token {
+		t.Error("each server should get its own token")
+	}
+}
+
+func TestRoutes_RequireToken(t *testing.T) {
+	s := New(embed.FS{}, logger.New(), "")
+	go s.hub.Run()
+	srv := httptest.NewServer(s.routes())
+	defer srv.Close()
+
+	for _, query := range []string{"", "?token=", "?token=wrong"} {
+		resp, err := http.Get(srv.URL + "/api/status" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET /api/status%s = %d, want %d", query, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+	resp, err := http.Get(srv.URL + "/api/status?token=" + s.token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /api/status with the token = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	ctx := context.Background()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	if _, resp, err := websocket.Dial(ctx, wsURL, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("dial without the token: err = %v, want %d", err, http.StatusUnauthorized)
+	}
+	foreign := &websocket.DialOptions{HTTPHeader: http.Header{"Origin": {"http://evil.example"}}}
+	if _, resp, err := websocket.Dial(ctx, wsURL+"?token="+s.token, foreign); err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("dial from another origin: err = %v, want %d", err, http.StatusForbidden)
+	}
+	conn, _, err := websocket.Dial(ctx, wsURL+"?token="+s.token, nil)
+	if err != nil {
+		t.Fatalf("dial with the token: %v", err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func TestFindPort_Fixed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	s := New(embed.FS{}, logger.New(), "")
+	s.SetListen("127.0.0.1", port)
+	if _, err := s.findPort(); err == nil || !strings.Contains(err.Error(), strconv.Itoa(port)) {
+		t.Errorf("findPort() with --port taken: err = %v, want port %d not available", err, port)
+	}
+	ln.Close()
+	if got, err := s.findPort(); err != nil || got != port {
+		t.Errorf("findPort() = %d, %v, want %d", got, err, port)
+	}
+}
+
+func TestStart_LAN(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0o644)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0o755)
+	os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("console.log(1)"), 0o644)
+
+	ln, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	s := New(embed.FS{}, logger.New(), "")
+	s.SetDevAssets(dir)
+	s.SetListen("0.0.0.0", port)
+	s.SetOpenBrowser(true)
+	if s.openBrowser {
+		t.Error("a LAN address should never open a browser")
+	}
+	if url := s.dashboardURL(); strings.Contains(url, "0.0.0.0") || !strings.Contains(url, "?token="+s.token) {
+		t.Errorf("dashboardURL() = %q, want a reachable address with the token", url)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+	base := "http://127.0.0.1:" + strconv.Itoa(port)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(base + "/api/status")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer func() {
+		s.Shutdown()
+		if err := <-done; err != nil {
+			t.Errorf("Start() = %v", err)
+		}
+	}()
+
+	// Off this machine, every request needs the token
+	for _, path := range []string{"/", "/assets/app.js", "/api/status"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without the token = %d, want %d", path, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+	if _, resp, err := websocket.Dial(context.Background(), "ws://127.0.0.1:"+strconv.Itoa(port)+"/ws", nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("dial without the token: err = %v, want %d", err, http.StatusUnauthorized)
+	}
+
+	// Opening the URL with the token lets the page load its assets
+	jar, _ := cookiejar.New(nil)
+	browser := &http.Client{Jar: jar}
+	for _, path := range []string{"/?token=" + s.token, "/assets/app.js", "/api/status"} {
+		resp, err := browser.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s after opening the URL = %d, want %d", path, resp.StatusCode, http.StatusOK)
+		}
+	}
+}
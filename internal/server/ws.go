@@ -1,29 +1,43 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/coder/websocket"
 	"github.com/templatr/templatr-setup/internal/config"
 	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/history"
 	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
 	"github.com/templatr/templatr-setup/internal/manifest"
 	"github.com/templatr/templatr-setup/internal/packages"
+	"github.com/templatr/templatr-setup/internal/secrets"
+	"github.com/templatr/templatr-setup/internal/state"
+	"github.com/templatr/templatr-setup/internal/term"
 )
 
 // Message types sent from server to client.
 const (
-	MsgTypeStep     = "step"
-	MsgTypeRuntime  = "runtime"
-	MsgTypeDownload = "download"
-	MsgTypeInstall  = "install"
-	MsgTypeLog      = "log"
-	MsgTypeComplete = "complete"
-	MsgTypePlan     = "plan"
-	MsgTypeError    = "error"
+	MsgTypeStep       = "step"
+	MsgTypeRuntime    = "runtime"
+	MsgTypeDownload   = "download"
+	MsgTypeInstall    = "install"
+	MsgTypeLog        = "log"
+	MsgTypeComplete   = "complete"
+	MsgTypePlan       = "plan"
+	MsgTypeError      = "error"
+	MsgTypeValidation = "validation"
+	MsgTypePostSetup  = "post_setup"
 )
 
 // ServerMessage is a message sent from the Go server to the web UI.
@@ -39,75 +53,72 @@ type ServerMessage struct {
 	Progress float64 `json:"progress,omitempty"`
 	Speed    string  `json:"speed,omitempty"`
 	Total    string  `json:"total,omitempty"`
-	// Log fields
-	Level   string `json:"level,omitempty"`
-	Message string `json:"message,omitempty"`
+	ETA      string  `json:"eta,omitempty"`   // time left at Speed, such as "3m05s"
+	Phase    string  `json:"phase,omitempty"` // install.Phase* the runtime or download is in
+	// Log fields; Record is the log entry they were taken from
+	Level   string         `json:"level,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Record  *logger.Record `json:"record,omitempty"`
 	// Complete fields
-	Success bool `json:"success,omitempty"`
+	Success    bool                  `json:"success,omitempty"`
+	Unignored  []string              `json:"unignored,omitempty"`  // env files with secrets that git would pick up
+	EnvChanges *install.EnvSummary   `json:"envChanges,omitempty"` // PATH/env var modifications made during install
+	Skipped    []packages.ManualStep `json:"skipped,omitempty"`    // steps left to the user, with the commands to run
 	// Plan data (sent once after manifest is loaded)
-	Plan *PlanData `json:"plan,omitempty"`
+	Plan *engine.PlanData `json:"plan,omitempty"`
+	// Validation results (sent for every load and revalidate)
+	Validation *ValidationData `json:"validation,omitempty"`
+	// Post-setup command statuses (sent after post-setup and each retry)
+	PostSetup []PostSetupCommandData `json:"postSetup,omitempty"`
+	// Values a "configure" message was rejected for
+	FieldErrors []FieldErrorData `json:"fieldErrors,omitempty"`
 }
 
-// PlanData is the setup plan serialized for the web UI.
-type PlanData struct {
-	Template TemplateData  `json:"template"`
-	Runtimes []RuntimeData `json:"runtimes"`
-	Packages *PackageData  `json:"packages,omitempty"`
-	EnvVars  []EnvVarData  `json:"envVars,omitempty"`
-	Configs  []ConfigData  `json:"configs,omitempty"`
+// FieldErrorData is a configure value that failed its field's rules. Env
+// or Config names the field; Environment is set for per-environment
+// values.
+type FieldErrorData struct {
+	Env         string `json:"env,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Config      string `json:"config,omitempty"` // config field path
+	Message     string `json:"message"`
 }
 
-// TemplateData is template info for the web UI.
-type TemplateData struct {
-	Name     string `json:"name"`
-	Version  string `json:"version"`
-	Tier     string `json:"tier"`
-	Category string `json:"category"`
-}
-
-// RuntimeData is runtime plan info for the web UI.
-type RuntimeData struct {
-	Name             string `json:"name"`
-	DisplayName      string `json:"displayName"`
-	RequiredVersion  string `json:"requiredVersion"`
-	InstalledVersion string `json:"installedVersion"`
-	Action           string `json:"action"`
-}
-
-// PackageData is package manager info for the web UI.
-type PackageData struct {
-	Manager        string `json:"manager"`
-	InstallCommand string `json:"installCommand"`
-	ManagerFound   bool   `json:"managerFound"`
-}
+// Post-setup command statuses.
+const (
+	PostSetupOK      = "ok"
+	PostSetupFailed  = "failed"
+	PostSetupNotRun  = "not_run" // a required command before it failed
+	PostSetupRunning = "running" // being retried
+)
 
-// EnvVarData is an env var definition for the web UI form.
-type EnvVarData struct {
-	Key         string `json:"key"`
-	Label       string `json:"label"`
-	Description string `json:"description"`
-	Default     string `json:"default"`
-	Required    bool   `json:"required"`
-	Type        string `json:"type"`
-	DocsURL     string `json:"docsUrl,omitempty"`
-	File        string `json:"file,omitempty"`
+// PostSetupCommandData is how one post-setup command went, for the web UI.
+// Index is its position in the manifest's post_setup.commands, as sent back
+// by "retry_command".
+type PostSetupCommandData struct {
+	Index    int    `json:"index"`
+	Command  string `json:"command"`
+	Dir      string `json:"dir,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
 }
 
-// ConfigData is a config file definition for the web UI form.
-type ConfigData struct {
-	File        string          `json:"file"`
-	Label       string          `json:"label"`
-	Description string          `json:"description"`
-	Fields      []ConfigFieldUI `json:"fields"`
-}
+// Stages a manifest can fail at, so the UI can tell a TOML syntax error
+// from validation problems and from a plan that couldn't be built.
+const (
+	StageParse    = "parse"
+	StageValidate = "validate"
+	StagePlan     = "plan"
+)
 
-// ConfigFieldUI is a single config field for the web UI form.
-type ConfigFieldUI struct {
-	Path        string `json:"path"`
-	Label       string `json:"label"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
-	Default     string `json:"default"`
+// ValidationData is the structured result of parsing and validating a manifest.
+type ValidationData struct {
+	Valid   bool                       `json:"valid"` // no error-severity results
+	Stage   string                     `json:"stage"` // StageParse, StageValidate or StagePlan
+	File    string                     `json:"file,omitempty"`
+	Errors  []manifest.ValidationError `json:"errors"`
+	Content string                     `json:"content,omitempty"` // the TOML that was validated, for the editor
 }
 
 // ClientMessage is a message sent from the web UI to the Go server.
@@ -116,9 +127,20 @@ type ClientMessage struct {
 	Action string            `json:"action,omitempty"`
 	Env    map[string]string `json:"env,omitempty"`
 	Config map[string]string `json:"config,omitempty"`
-	// Manifest content for upload
+	// Per-environment env values, keyed by environment then env key
+	EnvByEnvironment map[string]map[string]string `json:"envByEnvironment,omitempty"`
+	// Manifest content for upload or revalidate
 	ManifestContent string `json:"manifestContent,omitempty"`
+	ManifestName    string `json:"manifestName,omitempty"` // file the content was read from, for messages
 	ManifestPath    string `json:"manifestPath,omitempty"`
+	ManifestURL     string `json:"manifestURL,omitempty"` // https:// URL to fetch the manifest from
+	// Post-setup command to run again, for "retry_command"
+	Index int `json:"index,omitempty"`
+	// Runtime actions to use instead of the plan's, for "confirm", such as
+	// {"java": "skip"}
+	Overrides map[string]engine.ActionType `json:"overrides,omitempty"`
+	// Steps after the runtimes to leave to the user, for "confirm"
+	Options packages.SetupOptions `json:"options"`
 }
 
 // Hub manages WebSocket connections and broadcasts messages.
@@ -128,10 +150,29 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.Mutex
-	hadClients bool   // true once at least one client has connected
-	onEmpty    func() // called when all clients disconnect after at least one connected
+	hadClients bool                // true once at least one client has connected
+	onEmpty    func()              // called when all clients disconnect after at least one connected
+	emptyTimer *time.Timer         // pending onEmpty call, stopped if a client reconnects in time
+	grace      time.Duration       // how long onEmpty waits for a client to reconnect
+	mask       func(string) string // masks secrets in error messages; nil masks nothing
+	record     setupRecord         // replayed to each client on register
 }
 
+const (
+	// reconnectGrace is how long the hub waits after the last client
+	// leaves before calling onEmpty, so a refreshed tab can reconnect.
+	reconnectGrace = 10 * time.Second
+	// pingInterval is how often each client is pinged.
+	pingInterval = 15 * time.Second
+	// pongTimeout is how long a ping waits for its pong.
+	pongTimeout = 5 * time.Second
+	// maxMissedPongs is how many pings in a row may go unanswered before
+	// the client is dropped.
+	maxMissedPongs = 2
+	// writeTimeout is how long a message may take to write to a client.
+	writeTimeout = 10 * time.Second
+)
+
 // Client represents a single WebSocket connection.
 type Client struct {
 	conn *websocket.Conn
@@ -145,6 +186,7 @@ func NewHub() *Hub {
 		broadcast:  make(chan ServerMessage, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		grace:      reconnectGrace,
 	}
 }
 
@@ -154,32 +196,35 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			if h.emptyTimer != nil {
+				h.emptyTimer.Stop()
+				h.emptyTimer = nil
+			}
+			// Catch the client up before it gets live broadcasts
+			for _, msg := range h.record.snapshot() {
+				select {
+				case client.send <- msg:
+				default:
+				}
+			}
 			h.clients[client] = true
 			h.hadClients = true
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
-			empty := h.hadClients && len(h.clients) == 0
-			onEmpty := h.onEmpty
+			h.remove(client)
 			h.mu.Unlock()
 
-			if empty && onEmpty != nil {
-				onEmpty()
-			}
-
 		case msg := <-h.broadcast:
 			h.mu.Lock()
+			h.record.add(msg)
 			for client := range h.clients {
 				select {
 				case client.send <- msg:
 				default:
-					delete(h.clients, client)
-					close(client.send)
+					// Too far behind to catch up; closing send ends its connection
+					h.remove(client)
 				}
 			}
 			h.mu.Unlock()
@@ -187,15 +232,78 @@ func (h *Hub) Run() {
 	}
 }
 
+// remove drops client, if it is still registered, and starts the grace
+// period before onEmpty once no clients are left. h.mu must be held.
+func (h *Hub) remove(client *Client) {
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+	}
+	if h.hadClients && len(h.clients) == 0 && h.onEmpty != nil && h.emptyTimer == nil {
+		h.emptyTimer = time.AfterFunc(h.grace, h.stillEmpty)
+	}
+}
+
+// stillEmpty calls onEmpty unless a client connected during the grace
+// period.
+func (h *Hub) stillEmpty() {
+	h.mu.Lock()
+	empty := len(h.clients) == 0
+	h.emptyTimer = nil
+	onEmpty := h.onEmpty
+	h.mu.Unlock()
+
+	if empty && onEmpty != nil {
+		onEmpty()
+	}
+}
+
+// hasPlan reports whether a plan has been broadcast, so clients that
+// connect get the setup replayed instead of a fresh one.
+func (h *Hub) hasPlan() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.record.plan != nil
+}
+
+// recorded returns a copy of the setup so far, as replayed to new clients.
+func (h *Hub) recorded() setupRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.record.clone()
+}
+
+// outputStream forwards command output to the web UI as log messages. The
+// logger writes it one line at a time.
+type outputStream struct{ hub *Hub }
+
+func (o outputStream) Write(p []byte) (int, error) {
+	rec := logger.OutputRecord(strings.TrimSuffix(string(p), "\n"))
+	o.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "output", Message: rec.Msg, Record: &rec})
+	return len(p), nil
+}
+
+// broadcastLog sends a log message to the web UI, built from the same
+// masked record the logger would write.
+func (s *Server) broadcastLog(level logger.Level, format string, args ...any) {
+	rec := s.log.Record(level, format, args...)
+	s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: strings.ToLower(rec.Level), Message: rec.Msg, Record: &rec})
+}
+
 // Broadcast sends a message to all connected clients.
 func (h *Hub) Broadcast(msg ServerMessage) {
+	// Log messages come masked from the logger; errors carry raw err text
+	if msg.Type == MsgTypeError && h.mask != nil {
+		msg.Message = h.mask(msg.Message)
+	}
 	h.broadcast <- msg
 }
 
 // handleWebSocket upgrades the HTTP connection to a WebSocket and processes messages.
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Pages served by this server are same-origin and always accepted
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		OriginPatterns: []string{"*"}, // localhost only, so allow all origins
+		OriginPatterns: []string{fmt.Sprintf("127.0.0.1:%d", s.port), fmt.Sprintf("localhost:%d", s.port)},
 	})
 	if err != nil {
 		s.log.Error("WebSocket accept error: %s", err)
@@ -216,11 +324,15 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			if err != nil {
 				continue
 			}
-			if err := conn.Write(r.Context(), websocket.MessageText, data); err != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
+			err = conn.Write(ctx, websocket.MessageText, data)
+			cancel()
+			if err != nil {
 				return
 			}
 		}
 	}()
+	go s.keepAlive(r.Context(), conn)
 
 	// Reader loop - process incoming messages
 	defer func() {
@@ -228,9 +340,15 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		conn.CloseNow()
 	}()
 
-	// If a manifest was specified on the command line, auto-load it
-	if s.manifestPath != "" {
-		go s.loadManifestAndSendPlan(s.manifestPath)
+	// If a manifest was specified on the command line, auto-load it,
+	// unless a setup from it is under way and was replayed on register
+	if s.manifestPath != "" && !s.hub.hasPlan() {
+		if _, ok := s.enterPhase(phasePlanning, phaseIdle); ok {
+			go func() {
+				defer s.setPhase(phaseIdle)
+				s.loadManifestAndSendPlan(s.manifestPath)
+			}()
+		}
 	}
 
 	for {
@@ -249,112 +367,413 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// loadManifestAndSendPlan loads a manifest file and broadcasts the plan.
+// keepAlive pings conn every s.pingInterval until ctx is done, and closes it
+// once maxMissedPongs pings in a row go unanswered, such as from a tab on a
+// laptop that went to sleep, so the client's reader returns and the hub
+// drops it.
+func (s *Server) keepAlive(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+	missed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, s.pongTimeout)
+		err := conn.Ping(pingCtx)
+		cancel()
+		switch {
+		case err == nil:
+			missed = 0
+		case ctx.Err() != nil:
+			return
+		default:
+			missed++
+			if missed >= maxMissedPongs {
+				conn.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+// loadManifestAndSendPlan loads a manifest file, or fetches it from a URL,
+// and broadcasts the plan.
 func (s *Server) loadManifestAndSendPlan(path string) {
-	m, err := manifest.Load(path)
+	if path == "" {
+		path = manifest.DefaultManifestName
+	}
+
+	data, err := manifest.ReadSource(path)
 	if err != nil {
+		s.log.Error("Failed to load manifest: %s", err)
 		s.hub.Broadcast(ServerMessage{
 			Type:    MsgTypeError,
 			Message: fmt.Sprintf("Failed to load manifest: %s", err),
 		})
 		return
 	}
+	if manifest.IsURL(path) {
+		s.log.Info("Fetched manifest from %s", manifest.Redact(path))
+	}
 
-	if errs := manifest.Validate(m); len(errs) > 0 {
-		s.hub.Broadcast(ServerMessage{
-			Type:    MsgTypeError,
-			Message: fmt.Sprintf("Manifest validation failed: %s", errs[0]),
-		})
-		return
+	s.loadManifestFromContent(string(data), path)
+}
+
+// loadManifestFromContent parses and validates uploaded TOML content, broadcasts
+// the structured validation result, and broadcasts the plan if it is clean.
+// A manifest with only warnings is held until the client sends "proceed".
+// file is the name the content came from, shown with its problems. It
+// returns the plan, or nil, and the last validation result broadcast.
+func (s *Server) loadManifestFromContent(content, file string) (*engine.PlanData, *ValidationData) {
+	m, result := validateContent(content, file)
+	result.File = file
+	s.hub.Broadcast(ServerMessage{Type: MsgTypeValidation, Validation: result})
+
+	if !result.Valid {
+		return nil, result
+	}
+	if len(result.Errors) > 0 {
+		s.pendingManifest = m
+		s.pendingResult = result
+		return nil, result
 	}
 
-	s.validateAndBroadcastPlan(m)
+	return s.broadcastPlan(m, result)
 }
 
-// loadManifestFromContent parses uploaded TOML content and broadcasts the plan.
-func (s *Server) loadManifestFromContent(content string) {
-	m, err := manifest.Parse([]byte(content))
+// revalidate parses and validates edited content without building a plan.
+func (s *Server) revalidate(content, file string) {
+	_, result := validateContent(content, file)
+	result.File = file
+	s.hub.Broadcast(ServerMessage{Type: MsgTypeValidation, Validation: result})
+}
+
+// proceedPastWarnings builds the plan for a manifest that only had warnings,
+// returning what broadcastPlan does, or nils if none is waiting.
+func (s *Server) proceedPastWarnings() (*engine.PlanData, *ValidationData) {
+	m := s.pendingManifest
+	if m == nil {
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: "No manifest is waiting for confirmation."})
+		return nil, nil
+	}
+	result := s.pendingResult
+	s.pendingManifest, s.pendingResult = nil, nil
+	return s.broadcastPlan(m, result)
+}
+
+// validateContent parses and validates manifest TOML, reporting every
+// problem. A parse failure is reported as a single error with an empty path
+// and, when the decoder knows it, the line and column. Manifests it extends
+// are resolved against source, the file or URL the content came from.
+func validateContent(content, source string) (*manifest.Manifest, *ValidationData) {
+	result := &ValidationData{Stage: StageValidate, Content: content, Errors: []manifest.ValidationError{}}
+
+	m, err := manifest.ParseFrom([]byte(content), source)
 	if err != nil {
-		s.hub.Broadcast(ServerMessage{
-			Type:    MsgTypeError,
-			Message: fmt.Sprintf("Failed to parse manifest: %s", err),
-		})
-		return
+		result.Stage = StageParse
+		result.Errors = append(result.Errors, manifest.ParseError(err))
+		return nil, result
 	}
 
-	s.validateAndBroadcastPlan(m)
+	if errs := manifest.Validate(m); len(errs) > 0 {
+		result.Errors = errs
+	}
+	result.Valid = !manifest.HasErrors(result.Errors)
+	return m, result
 }
 
-// validateAndBroadcastPlan validates a manifest, stores it, builds a plan, and broadcasts it.
-func (s *Server) validateAndBroadcastPlan(m *manifest.Manifest) {
-	if errs := manifest.Validate(m); len(errs) > 0 {
-		s.hub.Broadcast(ServerMessage{
-			Type:    MsgTypeError,
-			Message: fmt.Sprintf("Manifest validation failed: %s", errs[0]),
-		})
-		return
+// broadcastPlan stores a validated manifest, builds a plan, and broadcasts it.
+// Manifests that need a newer tool than this one are refused. Either failure
+// is sent back as a plan-stage validation result for validated, so the
+// editor keeps the manifest it came from. It returns the plan and
+// validated, or nil and the failure.
+func (s *Server) broadcastPlan(m *manifest.Manifest, validated *ValidationData) (*engine.PlanData, *ValidationData) {
+	if err := m.Meta.CheckToolVersion(s.toolVersion); errors.Is(err, manifest.ErrDevBuild) {
+		s.log.Warn("%s", err)
+	} else if err != nil {
+		s.log.Error("%s", err)
+		return nil, s.broadcastPlanError(validated, "meta.min_tool_version", err.Error())
 	}
 
-	plan, err := engine.BuildPlan(m)
+	plan, err := s.buildPlan(m)
 	if err != nil {
-		s.hub.Broadcast(ServerMessage{
-			Type:    MsgTypeError,
-			Message: fmt.Sprintf("Failed to build plan: %s", err),
-		})
-		return
+		return nil, s.broadcastPlanError(validated, "", fmt.Sprintf("Failed to build plan: %s", err))
 	}
+	install.EstimateDownloads(plan)
 
+	// Reloading the same manifest, as a reconnecting tab does, keeps what
+	// this session installed
+	if s.loadedManifest == nil || !reflect.DeepEqual(s.loadedManifest, m) {
+		s.resetProgress()
+	}
 	s.loadedManifest = m
 
+	pd := engine.NewPlanData(plan)
+	s.addProgress(pd)
 	s.hub.Broadcast(ServerMessage{
 		Type: MsgTypePlan,
-		Plan: buildPlanData(plan),
+		Plan: pd,
+	})
+	return pd, validated
+}
+
+// broadcastPlanError reports that the plan for a validated manifest couldn't
+// be built, after any warnings it was validated with, and returns the
+// result it sent.
+func (s *Server) broadcastPlanError(validated *ValidationData, path, message string) *ValidationData {
+	result := &ValidationData{Stage: StagePlan, Errors: []manifest.ValidationError{}}
+	if validated != nil {
+		result.File, result.Content = validated.File, validated.Content
+		result.Errors = append(result.Errors, validated.Errors...)
+	}
+	result.Errors = append(result.Errors, manifest.ValidationError{
+		Path:     path,
+		Message:  s.log.Mask(message),
+		Severity: manifest.SeverityError,
 	})
+	s.hub.Broadcast(ServerMessage{Type: MsgTypeValidation, Validation: result})
+	return result
 }
 
-// handleClientMessage processes a message from a web UI client.
+// handleClientMessage processes a message from a web UI client. Messages
+// that start work are refused, with the reason, unless the setup is in a
+// phase they apply to.
 func (s *Server) handleClientMessage(_ *Client, msg ClientMessage) {
+	start := func(action string, to setupPhase, from ...setupPhase) bool {
+		current, ok := s.enterPhase(to, from...)
+		if !ok {
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: refusal(action, current)})
+		}
+		return ok
+	}
+
 	switch msg.Type {
 	case "load_manifest":
-		if msg.ManifestContent != "" {
-			go s.loadManifestFromContent(msg.ManifestContent)
-		} else {
-			go s.loadManifestAndSendPlan(msg.ManifestPath)
+		if !start("load a manifest", phasePlanning, phaseIdle, phaseDone) {
+			return
+		}
+		go func() {
+			defer s.setPhase(phaseIdle)
+			switch {
+			case msg.ManifestContent != "":
+				s.loadManifestFromContent(msg.ManifestContent, msg.ManifestName)
+			case msg.ManifestURL != "":
+				// Not a path: a URL field must not read files off the disk
+				if !manifest.IsURL(msg.ManifestURL) {
+					s.hub.Broadcast(ServerMessage{
+						Type:    MsgTypeError,
+						Message: fmt.Sprintf("Failed to load manifest: %s is not an http:// or https:// URL", manifest.Redact(msg.ManifestURL)),
+					})
+					return
+				}
+				s.loadManifestAndSendPlan(msg.ManifestURL)
+			default:
+				s.loadManifestAndSendPlan(msg.ManifestPath)
+			}
+		}()
+
+	case "revalidate":
+		go s.revalidate(msg.ManifestContent, msg.ManifestName)
+
+	case "proceed":
+		if !start("load the manifest", phasePlanning, phaseIdle) {
+			return
 		}
+		go func() {
+			defer s.setPhase(phaseIdle)
+			s.proceedPastWarnings()
+		}()
 
 	case "confirm":
-		go s.runInstallation()
+		if start("start the installation", phaseInstalling, phaseIdle, phaseDone) {
+			s.overrides, s.options = msg.Overrides, msg.Options
+			go s.runInstallation(false)
+		}
+
+	case "retry":
+		if start("retry the installation", phaseInstalling, phaseDone) {
+			go s.retryInstallation()
+		}
 
 	case "configure":
-		go s.runConfigure(msg)
+		if start("save the configuration", phaseFinishing, phaseConfiguring) {
+			go s.runConfigure(msg)
+		}
+
+	case "retry_command":
+		if start("retry the command", phaseFinishing, phaseDone) {
+			go func() {
+				defer s.setPhase(phaseDone)
+				s.retryPostSetupCommand(msg.Index)
+			}()
+		}
 
 	case "cancel":
-		s.hub.Broadcast(ServerMessage{
-			Type:    MsgTypeComplete,
-			Success: false,
-			Message: "Setup cancelled by user.",
-		})
+		s.cancelSetup()
+	}
+}
+
+// cancelSetup stops the running installation, which reports its own
+// completion once it stops, or else ends the setup as cancelled. It
+// reports whether an installation was running.
+func (s *Server) cancelSetup() bool {
+	if s.stopInstallation() {
+		return true
+	}
+	s.broadcastComplete(ServerMessage{
+		Success: false,
+		Message: "Setup cancelled by user.",
+	})
+	return false
+}
+
+// completedInstall is a runtime or download installed this session.
+type completedInstall struct {
+	action string // report action, e.g. "install" or install.ActionDownload
+	result install.InstallResult
+}
+
+// resetProgress forgets what this session installed.
+func (s *Server) resetProgress() {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	s.completed = nil
+	s.failed = ""
+}
+
+// installDone records that the runtime or download id was installed.
+func (s *Server) installDone(id, action string, result install.InstallResult) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	if s.completed == nil {
+		s.completed = make(map[string]completedInstall)
+	}
+	s.completed[id] = completedInstall{action: action, result: result}
+	if s.failed == id {
+		s.failed = ""
+	}
+}
+
+// installFailed records that the runtime or download id failed to install.
+func (s *Server) installFailed(id string) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	s.failed = id
+}
+
+// completedInstallOf returns what the runtime or download id was installed
+// as this session, if it was.
+func (s *Server) completedInstallOf(id string) (completedInstall, bool) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	c, ok := s.completed[id]
+	return c, ok
+}
+
+// addProgress marks the plan's runtimes and downloads installed or failed
+// this session, so a tab that reconnects shows how far setup got.
+func (s *Server) addProgress(pd *engine.PlanData) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	status := func(id string) string {
+		if _, ok := s.completed[id]; ok {
+			return "complete"
+		}
+		if id == s.failed {
+			return "failed"
+		}
+		return ""
+	}
+	for i := range pd.Runtimes {
+		pd.Runtimes[i].Status = status(pd.Runtimes[i].Name)
+		if c, ok := s.completed[pd.Runtimes[i].Name]; ok {
+			pd.Runtimes[i].InstalledVersion = c.result.Version
+		}
+	}
+	for i := range pd.Downloads {
+		pd.Downloads[i].Status = status(pd.Downloads[i].ID)
+	}
+}
+
+// retryInstallation re-runs the installation after a runtime or download
+// failed, installing again only what didn't complete this session.
+func (s *Server) retryInstallation() {
+	s.progressMu.Lock()
+	failed := s.failed
+	s.progressMu.Unlock()
+	if failed == "" {
+		s.setPhase(phaseDone)
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: "Nothing to retry - no install has failed."})
+		return
 	}
+	s.log.Info("Retrying installation from %s", strings.TrimPrefix(failed, "download:"))
+	s.runInstallation(true)
 }
 
 // runInstallation performs the full installation flow and broadcasts progress.
-func (s *Server) runInstallation() {
+// With resume, runtimes and downloads installed earlier this session are
+// reported as complete instead of installed again; otherwise they are
+// forgotten.
+func (s *Server) runInstallation(resume bool) {
 	m := s.loadedManifest
 	if m == nil {
+		s.setPhase(phaseIdle)
 		s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: "No manifest loaded. Please upload a .templatr.toml file first."})
 		return
 	}
 
-	plan, err := engine.BuildPlan(m)
+	plan, err := s.buildPlan(m)
 	if err != nil {
+		s.setPhase(phaseIdle)
 		s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: err.Error()})
 		return
 	}
+	if err := plan.ApplyOverrides(s.overrides); err != nil {
+		s.setPhase(phaseIdle)
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: fmt.Sprintf("Can't start the installation: %s.", err)})
+		return
+	}
+	install.EstimateDownloads(plan)
 
+	if issues := install.Preflight(plan); len(issues) > 0 {
+		for _, issue := range issues {
+			s.log.Error("Preflight: %s", issue)
+			s.hub.Broadcast(ServerMessage{
+				Type:    MsgTypeError,
+				Message: fmt.Sprintf("%s. %s", issue, issue.Fix),
+			})
+		}
+		s.broadcastComplete(ServerMessage{
+			Success: false,
+			Message: "Preflight check failed - nothing was installed.",
+		})
+		return
+	}
+
+	ctx, done := s.cancellable()
+	defer done()
+
+	if !resume {
+		s.resetProgress()
+	}
+	s.report = history.NewReport(plan, "web")
+	s.installed = nil
+	s.skipped = s.options.ManualSteps(plan)
 	s.hub.Broadcast(ServerMessage{Type: MsgTypeStep, Step: "install", Status: "running"})
 
+	install.RecordUses(plan, s.log)
+
 	// Install runtimes one at a time with progress
-	for _, rp := range plan.Runtimes {
+	for _, rp := range plan.InstallRuntimes() {
+		if c, ok := s.completedInstallOf(rp.Name); ok {
+			s.report.AddResult(c.action, c.result)
+			s.installed = append(s.installed, c.result)
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeInstall, Runtime: rp.Name, Version: c.result.Version, Status: "complete"})
+			continue
+		}
 		if rp.Action == engine.ActionSkip {
 			s.hub.Broadcast(ServerMessage{
 				Type:    MsgTypeRuntime,
@@ -373,32 +792,29 @@ func (s *Server) runInstallation() {
 			Action: string(rp.Action),
 		})
 
-		progress := func(downloaded, total int64) {
-			if total > 0 {
-				pct := float64(downloaded) / float64(total) * 100
-				s.hub.Broadcast(ServerMessage{
-					Type:     MsgTypeDownload,
-					Runtime:  rp.Name,
-					Progress: pct,
-					Total:    formatBytes(total),
-				})
-			}
-		}
-
-		result, err := install.InstallSingleRuntime(rp, m.Template.Slug, s.log, progress)
+		result, err := s.installRuntime(ctx, rp, m.Template.Slug, s.log, s.installEvents(rp.Name, string(rp.Action)), s.options.ExecuteOptions)
 		if err != nil {
+			s.finishReport(err)
+			if s.reportCancelled(err) {
+				return
+			}
+			s.installFailed(rp.Name)
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeRuntime, Name: rp.Name, Status: "failed", Action: string(rp.Action)})
 			s.hub.Broadcast(ServerMessage{
 				Type:    MsgTypeError,
 				Message: fmt.Sprintf("Failed to install %s: %s", rp.DisplayName, err),
 			})
-			s.hub.Broadcast(ServerMessage{
-				Type:    MsgTypeComplete,
+			s.broadcastComplete(ServerMessage{
 				Success: false,
 				Message: fmt.Sprintf("Installation failed: %s", err),
 			})
 			return
 		}
 
+		s.report.AddResult(string(rp.Action), *result)
+		s.installed = append(s.installed, *result)
+		s.installDone(rp.Name, string(rp.Action), *result)
+
 		s.hub.Broadcast(ServerMessage{
 			Type:    MsgTypeInstall,
 			Runtime: rp.Name,
@@ -407,175 +823,493 @@ func (s *Server) runInstallation() {
 		})
 	}
 
+	// Extra downloads, reported as additional progress rows
+	for _, dp := range plan.Downloads {
+		id := engine.DownloadID(dp.Name)
+		if c, ok := s.completedInstallOf(id); ok {
+			s.report.AddResult(c.action, c.result)
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeInstall, Runtime: id, Status: "complete"})
+			continue
+		}
+		if dp.Action == engine.ActionSkip {
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeRuntime, Name: id, Status: "installed", Action: "skip"})
+			continue
+		}
+
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeRuntime, Name: id, Status: "installing", Action: string(dp.Action)})
+
+		result, err := install.InstallDownload(ctx, dp, m.Template.Slug, s.log, s.installEvents(id, string(dp.Action)))
+		if err != nil {
+			s.finishReport(err)
+			if s.reportCancelled(err) {
+				return
+			}
+			s.installFailed(id)
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeRuntime, Name: id, Status: "failed", Action: string(dp.Action)})
+			s.hub.Broadcast(ServerMessage{
+				Type:    MsgTypeError,
+				Message: fmt.Sprintf("Failed to download %s: %s", dp.Name, err),
+			})
+			s.broadcastComplete(ServerMessage{
+				Success: false,
+				Message: fmt.Sprintf("Installation failed: %s", err),
+			})
+			return
+		}
+
+		s.report.AddResult(install.ActionDownload, *result)
+		s.installDone(id, install.ActionDownload, *result)
+
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeInstall, Runtime: id, Status: "complete"})
+	}
+
 	// Run packages
 	s.hub.Broadcast(ServerMessage{Type: MsgTypeStep, Step: "packages", Status: "running"})
-	s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "info", Message: "Installing packages..."})
+	s.broadcastLog(logger.INFO, "Installing packages...")
 
-	if err := packages.RunGlobalInstalls(m, s.log); err != nil {
-		s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "warn", Message: fmt.Sprintf("Global install warning: %s", err)})
+	if err := packages.EnsureManager(ctx, plan, s.log, outputStream{s.hub}); err != nil {
+		if s.reportPackagesCancelled(err) {
+			return
+		}
+		s.broadcastLog(logger.WARN, "Package manager warning: %s", err)
 	}
 
-	if err := packages.RunInstall(m, s.log); err != nil {
-		s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "warn", Message: fmt.Sprintf("Package install warning: %s", err)})
-	}
+	if s.options.SkipPackages {
+		s.broadcastLog(logger.INFO, "Skipping package installation")
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeStep, Step: "packages", Status: "skipped"})
+	} else {
+		if err := packages.RunGlobalInstalls(ctx, plan, s.log, outputStream{s.hub}); err != nil {
+			if s.reportPackagesCancelled(err) {
+				return
+			}
+			s.broadcastLog(logger.WARN, "Global install warning: %s", err)
+		}
+
+		if err := packages.RunInstall(ctx, plan, s.log, outputStream{s.hub}); err != nil {
+			if s.reportPackagesCancelled(err) {
+				return
+			}
+			s.broadcastLog(logger.WARN, "Package install warning: %s", err)
+		}
 
-	s.hub.Broadcast(ServerMessage{Type: MsgTypeStep, Step: "packages", Status: "complete"})
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeStep, Step: "packages", Status: "complete"})
+	}
 
 	// Check if configure step is needed
 	if len(m.Env) > 0 || len(m.Config) > 0 {
+		s.setPhase(phaseConfiguring)
 		s.hub.Broadcast(ServerMessage{Type: MsgTypeStep, Step: "configure", Status: "ready"})
 	} else {
 		// Run post-setup and complete
-		s.runPostSetupAndComplete(m)
+		s.runPostSetupAndComplete(ctx, m)
+	}
+}
+
+// cancellable returns a context that the "cancel" message stops, and the
+// function to call once the work it covers is done.
+func (s *Server) cancellable() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancelMu.Lock()
+	s.cancelInstall = cancel
+	s.cancelMu.Unlock()
+	return ctx, func() {
+		s.cancelMu.Lock()
+		s.cancelInstall = nil
+		s.cancelMu.Unlock()
+		cancel()
+	}
+}
+
+// stopInstallation cancels the running installation, if any, and reports
+// whether there was one.
+func (s *Server) stopInstallation() bool {
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	if s.cancelInstall == nil {
+		return false
 	}
+	s.log.Warn("Installation cancelled by user")
+	s.cancelInstall()
+	return true
+}
+
+// reportPackagesCancelled broadcasts the end of a setup stopped by "cancel"
+// while a package or post-setup command was running. It returns false if
+// err is not a cancellation.
+func (s *Server) reportPackagesCancelled(err error) bool {
+	if !errors.Is(err, install.ErrCancelled) {
+		return false
+	}
+	s.finishReport(err)
+	s.broadcastComplete(ServerMessage{
+		Success: false,
+		Message: "Setup cancelled. The runtimes are installed; run setup again to finish the package and post-setup steps.",
+	})
+	return true
+}
+
+// reportCancelled broadcasts the end of an installation stopped by "cancel".
+// It returns false if err is not a cancellation.
+func (s *Server) reportCancelled(err error) bool {
+	if !errors.Is(err, install.ErrCancelled) {
+		return false
+	}
+	s.broadcastComplete(ServerMessage{
+		Success: false,
+		Message: "Installation cancelled. Partial downloads were removed.",
+	})
+	return true
 }
 
 // runConfigure writes config values and completes the setup.
 func (s *Server) runConfigure(msg ClientMessage) {
 	m := s.loadedManifest
 	if m == nil {
+		s.setPhase(phaseIdle)
 		s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: "No manifest loaded."})
 		return
 	}
+	normalizeConfigure(m, msg)
+	if errs := configureErrors(m, msg); len(errs) > 0 {
+		s.log.Warn("Configure rejected: %d invalid values", len(errs))
+		s.setPhase(phaseConfiguring)
+		s.hub.Broadcast(ServerMessage{
+			Type:        MsgTypeError,
+			Message:     "Some values are invalid. Fix them and save again.",
+			FieldErrors: errs,
+		})
+		return
+	}
 
-	// Write env files (grouped by target file)
-	if len(msg.Env) > 0 && len(m.Env) > 0 {
+	// Write env files (grouped by target file, per environment)
+	s.unignored = nil
+	if (len(msg.Env) > 0 || len(msg.EnvByEnvironment) > 0) && len(m.Env) > 0 {
 		// Mask secrets
 		for _, envDef := range m.Env {
-			if envDef.Type == "secret" {
-				if v, ok := msg.Env[envDef.Key]; ok && v != "" {
+			if envDef.Type != "secret" {
+				continue
+			}
+			if v, ok := msg.Env[envDef.Key]; ok && v != "" {
+				s.log.AddSecret(v)
+			}
+			for _, values := range msg.EnvByEnvironment {
+				if v := values[envDef.Key]; v != "" {
 					s.log.AddSecret(v)
 				}
 			}
 		}
 
-		grouped, fileOrder := config.GroupEnvByFile(m.Env)
-		for _, file := range fileOrder {
-			s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "info", Message: fmt.Sprintf("Writing %s...", file)})
-			defs := grouped[file]
-			if err := config.WriteEnvFile(file, defs, msg.Env); err != nil {
-				s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "error", Message: fmt.Sprintf("Failed to write %s: %s", file, err)})
+		shared, err := config.StoreSecrets(m, msg.Env)
+		if errors.Is(err, secrets.ErrUnsupported) {
+			s.log.Warn("%s", err)
+			s.broadcastLog(logger.WARN, "%s", err)
+		} else if err != nil {
+			s.broadcastLog(logger.ERROR, "Failed to store secrets: %s", err)
+			s.setPhase(phaseConfiguring)
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: err.Error()})
+			return
+		}
+
+		for _, e := range config.EnvironmentDefs(m) {
+			grouped, fileOrder := config.GroupEnvByFile(e.Vars)
+			values := e.Values(shared, msg.EnvByEnvironment)
+			for _, file := range fileOrder {
+				s.broadcastLog(logger.INFO, "Writing %s...", file)
+				if err := config.WriteEnvFile(file, grouped[file], values); err != nil {
+					s.broadcastLog(logger.ERROR, "Failed to write %s: %s", file, err)
+				}
+			}
+		}
+		if m.EnvOptions.WriteExample {
+			written, err := config.WriteExampleFiles(m)
+			for _, file := range written {
+				s.broadcastLog(logger.INFO, "Wrote %s", file)
+			}
+			if err != nil {
+				s.broadcastLog(logger.ERROR, "Failed to write env example files: %s", err)
+			}
+		}
+
+		if s.checkIgnore {
+			for _, g := range config.CheckGitignore(config.SecretEnvFiles(config.AllEnvDefs(m))) {
+				s.unignored = append(s.unignored, g.File)
+				s.log.Warn("%s is not git-ignored", g.File)
+				s.broadcastLog(logger.WARN, "%s contains secrets but is not ignored by git - add it to .gitignore", g.File)
 			}
 		}
 	}
 
-	// Write config files
+	// Write config files, leaving out fields hidden by their condition
 	if len(msg.Config) > 0 {
+		shown := configValuesShown(msg)
 		for _, cfg := range m.Config {
-			s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "info", Message: fmt.Sprintf("Updating %s...", cfg.File)})
+			s.broadcastLog(logger.INFO, "Updating %s...", cfg.File)
 
 			fieldValues := make(map[string]string)
 			for _, field := range cfg.Fields {
-				if v, ok := msg.Config[field.Path]; ok {
+				if v, ok := msg.Config[field.Path]; ok && manifest.Shown(field.When, shown) {
 					fieldValues[field.Path] = v
 				}
 			}
 
 			if len(fieldValues) > 0 {
-				if err := config.UpdateConfigFile(cfg.File, fieldValues); err != nil {
-					s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "error", Message: fmt.Sprintf("Failed to update %s: %s", cfg.File, err)})
+				if err := config.UpdateConfigFile(cfg.File, cfg.Fields, fieldValues); err != nil {
+					s.broadcastLog(logger.ERROR, "Failed to update %s: %s", cfg.File, err)
 				}
 			}
 		}
 	}
 
-	s.runPostSetupAndComplete(m)
+	ctx, done := s.cancellable()
+	defer done()
+	s.runPostSetupAndComplete(ctx, m)
+}
+
+// normalizeConfigure tidies the values in a "configure" message, in place,
+// as the CLI and the terminal UI tidy what is typed into them.
+func normalizeConfigure(m *manifest.Manifest, msg ClientMessage) {
+	for _, env := range m.Env {
+		rules := config.EnvRules(env)
+		if v, ok := msg.Env[env.Key]; ok {
+			msg.Env[env.Key] = config.NormalizeFieldValue(v, rules)
+		}
+		for _, values := range msg.EnvByEnvironment {
+			if v, ok := values[env.Key]; ok {
+				values[env.Key] = config.NormalizeFieldValue(v, rules)
+			}
+		}
+	}
+	for _, cfg := range m.Config {
+		for _, field := range cfg.Fields {
+			if v, ok := msg.Config[field.Path]; ok {
+				msg.Config[field.Path] = config.NormalizeFieldValue(v, config.ConfigRules(field))
+			}
+		}
+	}
 }
 
-// runPostSetupAndComplete runs post-setup commands and sends the completion message.
-func (s *Server) runPostSetupAndComplete(m *manifest.Manifest) {
-	if len(m.PostSetup.Commands) > 0 {
-		s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "info", Message: "Running post-setup commands..."})
-		if err := packages.RunPostSetup(m, s.log); err != nil {
-			s.hub.Broadcast(ServerMessage{Type: MsgTypeLog, Level: "warn", Message: fmt.Sprintf("Post-setup warning: %s", err)})
+// configureErrors checks the values in a "configure" message against their
+// fields' rules, as the form should have. Fields without a value in the
+// message, or hidden by their when condition, are left alone.
+func configureErrors(m *manifest.Manifest, msg ClientMessage) []FieldErrorData {
+	var errs []FieldErrorData
+	for _, env := range m.Env {
+		rules := config.EnvRules(env)
+		if len(env.Environments) == 0 {
+			if v, ok := msg.Env[env.Key]; ok && manifest.Shown(env.When, msg.Env) {
+				if err := config.ValidateFieldValue(v, rules); err != nil {
+					errs = append(errs, FieldErrorData{Env: env.Key, Message: err.Error()})
+				}
+			}
+			continue
+		}
+		for _, name := range env.Environments {
+			values := config.EnvironmentVars{Name: name}.Values(msg.Env, msg.EnvByEnvironment)
+			if v, ok := values[env.Key]; ok && manifest.Shown(env.When, values) {
+				if err := config.ValidateFieldValue(v, rules); err != nil {
+					errs = append(errs, FieldErrorData{Env: env.Key, Environment: name, Message: err.Error()})
+				}
+			}
+		}
+	}
+	shown := configValuesShown(msg)
+	for _, cfg := range m.Config {
+		for _, field := range cfg.Fields {
+			if v, ok := msg.Config[field.Path]; ok && manifest.Shown(field.When, shown) {
+				if err := config.ValidateFieldValue(v, config.ConfigRules(field)); err != nil {
+					errs = append(errs, FieldErrorData{Config: field.Path, Message: err.Error()})
+				}
+			}
 		}
 	}
+	return errs
+}
+
+// configValuesShown returns the values config field conditions are checked
+// against: the shared env values and the config values.
+func configValuesShown(msg ClientMessage) map[string]string {
+	values := maps.Clone(msg.Env)
+	if values == nil {
+		values = make(map[string]string)
+	}
+	maps.Copy(values, msg.Config)
+	return values
+}
+
+// runPostSetupAndComplete runs post-setup commands, unless the "confirm"
+// options skip them, and sends the completion message. Cancelling ctx stops
+// the running command.
+func (s *Server) runPostSetupAndComplete(ctx context.Context, m *manifest.Manifest) {
+	if len(m.PostSetup.Commands) > 0 && s.options.SkipPostSetup {
+		s.broadcastLog(logger.INFO, "Skipping post-setup commands")
+	} else if len(m.PostSetup.Commands) > 0 {
+		s.broadcastLog(logger.INFO, "Running post-setup commands...")
+		results, err := packages.RunPostSetup(ctx, m, config.ConfiguredEnv(m), s.log, outputStream{s.hub})
+		if s.reportPackagesCancelled(err) {
+			return
+		}
+		if err != nil {
+			s.broadcastLog(logger.WARN, "Post-setup warning: %s", err)
+		}
+		s.setPostSetup(m, results)
+	}
+
+	if err := state.SaveSnapshot(m); err != nil {
+		s.log.Warn("Could not record setup snapshot: %s", err)
+	}
+	s.finishReport(nil)
 
 	completeMsg := "Setup complete!"
 	if m.PostSetup.Message != "" {
 		completeMsg = m.PostSetup.Message
 	}
 
-	s.hub.Broadcast(ServerMessage{
-		Type:    MsgTypeComplete,
-		Success: true,
-		Message: completeMsg,
+	var envChanges *install.EnvSummary
+	if summary := install.SummarizeEnvChanges(s.installed); len(summary.Changes) > 0 || len(summary.Manual) > 0 {
+		envChanges = &summary
+	}
+
+	s.broadcastComplete(ServerMessage{
+		Success:    true,
+		Message:    completeMsg,
+		Unignored:  s.unignored,
+		EnvChanges: envChanges,
+		Skipped:    s.skipped,
 	})
 }
 
-// buildPlanData converts an engine.SetupPlan to a PlanData for the web UI.
-func buildPlanData(plan *engine.SetupPlan) *PlanData {
-	pd := &PlanData{
-		Template: TemplateData{
-			Name:     plan.Manifest.Template.Name,
-			Version:  plan.Manifest.Template.Version,
-			Tier:     plan.Manifest.Template.Tier,
-			Category: plan.Manifest.Template.Category,
-		},
-	}
-
-	for _, rp := range plan.Runtimes {
-		pd.Runtimes = append(pd.Runtimes, RuntimeData{
-			Name:             rp.Name,
-			DisplayName:      rp.DisplayName,
-			RequiredVersion:  rp.RequiredVersion,
-			InstalledVersion: rp.InstalledVersion,
-			Action:           string(rp.Action),
-		})
+// setPostSetup records the post-setup results of m, so failed commands
+// can be retried, and broadcasts their statuses.
+func (s *Server) setPostSetup(m *manifest.Manifest, results packages.PostSetupResults) {
+	s.postSetupMu.Lock()
+	s.postSetupManifest = m
+	s.postSetup = make([]PostSetupCommandData, len(results))
+	for i, r := range results {
+		s.postSetup[i] = PostSetupCommandData{
+			Index:    r.Index,
+			Command:  r.Command.Run,
+			Dir:      r.Command.Dir,
+			Required: r.Command.Required,
+			Status:   postSetupStatus(r),
+		}
+		if r.Err != nil {
+			s.postSetup[i].Error = s.log.Mask(r.Err.Error())
+		}
 	}
+	s.postSetupMu.Unlock()
+	s.broadcastPostSetup()
+}
 
-	if plan.Packages != nil {
-		pd.Packages = &PackageData{
-			Manager:        plan.Packages.Manager,
-			InstallCommand: plan.Packages.InstallCommand,
-			ManagerFound:   plan.Packages.ManagerFound,
-		}
+// postSetupStatus is the web UI status of a post-setup result.
+func postSetupStatus(r packages.PostSetupResult) string {
+	switch {
+	case r.NotRun():
+		return PostSetupNotRun
+	case r.Err != nil:
+		return PostSetupFailed
 	}
+	return PostSetupOK
+}
 
-	for _, env := range plan.Manifest.Env {
-		pd.EnvVars = append(pd.EnvVars, EnvVarData{
-			Key:         env.Key,
-			Label:       env.Label,
-			Description: env.Description,
-			Default:     env.Default,
-			Required:    env.Required,
-			Type:        env.Type,
-			DocsURL:     env.DocsURL,
-			File:        env.File,
-		})
+// broadcastPostSetup sends the current post-setup command statuses.
+func (s *Server) broadcastPostSetup() {
+	s.postSetupMu.Lock()
+	statuses := slices.Clone(s.postSetup)
+	s.postSetupMu.Unlock()
+	s.hub.Broadcast(ServerMessage{Type: MsgTypePostSetup, PostSetup: statuses})
+}
+
+// retryPostSetupCommand runs the failed or skipped post-setup command at
+// index again and broadcasts the updated statuses.
+func (s *Server) retryPostSetupCommand(index int) {
+	s.postSetupMu.Lock()
+	m := s.postSetupManifest
+	i := slices.IndexFunc(s.postSetup, func(c PostSetupCommandData) bool { return c.Index == index })
+	if m == nil || i < 0 || s.postSetup[i].Status == PostSetupOK || s.postSetup[i].Status == PostSetupRunning {
+		s.postSetupMu.Unlock()
+		s.hub.Broadcast(ServerMessage{Type: MsgTypeError, Message: "No failed post-setup command to retry."})
+		return
+	}
+	s.postSetup[i].Status = PostSetupRunning
+	s.postSetup[i].Error = ""
+	command := s.postSetup[i].Command
+	s.postSetupMu.Unlock()
+	s.broadcastPostSetup()
+
+	s.broadcastLog(logger.INFO, "Retrying post-setup command: %s", command)
+	ctx, done := s.cancellable()
+	err := packages.RunPostSetupCommand(ctx, m, config.ConfiguredEnv(m), index, s.log, outputStream{s.hub})
+	done()
+
+	s.postSetupMu.Lock()
+	s.postSetup[i].Status = PostSetupOK
+	if err != nil {
+		s.postSetup[i].Status = PostSetupFailed
+		s.postSetup[i].Error = s.log.Mask(err.Error())
+	}
+	s.postSetupMu.Unlock()
+	if err != nil {
+		s.broadcastLog(logger.WARN, "Post-setup warning: %s", err)
 	}
+	s.broadcastPostSetup()
+}
 
-	for _, cfg := range plan.Manifest.Config {
-		cd := ConfigData{
-			File:        cfg.File,
-			Label:       cfg.Label,
-			Description: cfg.Description,
+// finishReport closes the current run's report and appends it to local history.
+func (s *Server) finishReport(err error) {
+	if s.report == nil {
+		return
+	}
+	s.report.Finish(err)
+	if appendErr := history.Append(s.report); appendErr != nil {
+		s.log.Warn("Could not record setup history: %s", appendErr)
+	}
+	s.report = nil
+}
+
+// installEvents returns the EventFunc that reports the install of id to
+// the web UI: a runtime message as each phase starts, then throttled
+// download and extract progress.
+func (s *Server) installEvents(id, action string) install.EventFunc {
+	var phase string
+	return install.ThrottledEvents(func(ev install.ProgressEvent, p install.TransferStats) {
+		if ev.Phase != phase {
+			phase = ev.Phase
+			s.hub.Broadcast(ServerMessage{Type: MsgTypeRuntime, Name: id, Status: "installing", Action: action, Phase: phase})
 		}
-		for _, field := range cfg.Fields {
-			cd.Fields = append(cd.Fields, ConfigFieldUI{
-				Path:        field.Path,
-				Label:       field.Label,
-				Description: field.Description,
-				Type:        field.Type,
-				Default:     field.Default,
-			})
+		switch {
+		case ev.Phase == install.PhaseDownloading && p.Total > 0:
+			s.hub.Broadcast(downloadMessage(id, p))
+		case ev.Phase == install.PhaseExtracting && p.Downloaded > 0:
+			s.hub.Broadcast(extractMessage(id, p))
 		}
-		pd.Configs = append(pd.Configs, cd)
-	}
+	})
+}
 
-	return pd
+// downloadMessage returns the progress message for the download of id.
+func downloadMessage(id string, p install.TransferStats) ServerMessage {
+	return ServerMessage{
+		Type:     MsgTypeDownload,
+		Runtime:  id,
+		Phase:    install.PhaseDownloading,
+		Progress: p.Percent,
+		Speed:    p.SpeedText(),
+		Total:    term.FormatBytes(p.Total),
+		ETA:      p.ETAText(),
+	}
 }
 
-// formatBytes formats bytes into a human-readable string.
-func formatBytes(b int64) string {
-	const unit = 1024
-	if b < unit {
-		return fmt.Sprintf("%d B", b)
+// extractMessage returns the progress message for the extraction of id's
+// archive, where p counts files rather than bytes.
+func extractMessage(id string, p install.TransferStats) ServerMessage {
+	msg := ServerMessage{
+		Type:     MsgTypeDownload,
+		Runtime:  id,
+		Phase:    install.PhaseExtracting,
+		Progress: p.Percent,
+		Total:    fmt.Sprintf("%d files", p.Downloaded),
 	}
-	div, exp := int64(unit), 0
-	for n := b / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	if p.Total > 0 {
+		msg.Total = fmt.Sprintf("%d of %d files", p.Downloaded, p.Total)
 	}
-	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+	return msg
 }
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockFile sits next to the state file. It is locked rather than
+// state.json itself, which Save replaces by renaming a new file over it.
+const lockFile = ".templatr/state.lock"
+
+// lockTimeout is how long WithLock waits for another process to finish.
+var lockTimeout = 5 * time.Second
+
+// lockRetry is how often WithLock tries for the lock while waiting.
+const lockRetry = 50 * time.Millisecond
+
+// ErrLocked is returned by WithLock when another process holds the state
+// lock for longer than lockTimeout.
+var ErrLocked = errors.New("another templatr-setup is running")
+
+// WithLock loads the state, passes it to fn and saves it, holding an
+// exclusive lock on the state file throughout so concurrent runs (say the
+// web UI and a terminal setup) can't overwrite each other's changes. The
+// state is saved even when fn fails, so work done before the failure is
+// recorded; fn's error is returned.
+func WithLock(fn func(*State) error) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	lockPath := filepath.Join(home, lockFile)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open state lock: %w", err)
+	}
+	defer f.Close()
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			return fmt.Errorf("failed to lock %s: %w", lockPath, err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w - waited %s for %s; try again once it finishes", ErrLocked, lockTimeout, lockPath)
+		}
+		time.Sleep(lockRetry)
+	}
+	defer unlock(f)
+
+	s, err := Load()
+	if err != nil {
+		return err
+	}
+	fnErr := fn(s)
+	if err := s.Save(); err != nil {
+		return errors.Join(fnErr, fmt.Errorf("failed to save state: %w", err))
+	}
+	return fnErr
+}
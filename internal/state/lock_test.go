@@ -0,0 +1,106 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithLock_NoLostUpdates(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- WithLock(func(s *State) error {
+				// Widen the read-modify-write window
+				time.Sleep(5 * time.Millisecond)
+				s.AddInstallation(Installation{Runtime: fmt.Sprintf("rt%d", i), Version: "1.0.0", Action: "install"})
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("WithLock failed: %s", err)
+		}
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Installations) != workers {
+		t.Errorf("got %d installations, want %d: updates were lost", len(s.Installations), workers)
+	}
+}
+
+func TestWithLock_TimesOut(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	orig := lockTimeout
+	lockTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { lockTimeout = orig })
+
+	held := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- WithLock(func(s *State) error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+
+	start := time.Now()
+	err := WithLock(func(s *State) error {
+		t.Error("fn ran without the lock")
+		return nil
+	})
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("err = %v, want ErrLocked", err)
+	}
+	if waited := time.Since(start); waited < lockTimeout {
+		t.Errorf("gave up after %s, want at least %s", waited, lockTimeout)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := WithLock(func(s *State) error { return nil }); err != nil {
+		t.Errorf("lock not released: %s", err)
+	}
+}
+
+func TestWithLock_SavesOnError(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	boom := errors.New("boom")
+	err := WithLock(func(s *State) error {
+		s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Action: "install"})
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("err = %v, want fn's error", err)
+	}
+	s, _ := Load()
+	if len(s.Installations) != 1 {
+		t.Error("changes made before the error should be saved")
+	}
+}
@@ -0,0 +1,181 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Drift is where state.json and the disk disagree, as found by Reconcile.
+type Drift struct {
+	Missing []Installation // recorded, but the path no longer exists
+
+	// Current links whose target is gone: Repoint holds links to aim at
+	// the newest remaining version instead, DanglingLinks those with no
+	// version left
+	Repoint       []RuntimeLink
+	DanglingLinks []RuntimeLink
+
+	// PATH entries and env vars pointing into what is gone
+	PathMods []PathModification
+	EnvMods  []EnvModification
+
+	Orphans []Orphan // version directories that state.json doesn't know about
+}
+
+// Orphan is a runtime version directory with no installation recorded.
+type Orphan struct {
+	Runtime string
+	Version string
+	Path    string
+}
+
+// Empty reports whether state and disk agree.
+func (d Drift) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Repoint) == 0 && len(d.DanglingLinks) == 0 &&
+		len(d.PathMods) == 0 && len(d.EnvMods) == 0 && len(d.Orphans) == 0
+}
+
+// Reconcile cross-checks s against the disk: recorded installations and
+// current links whose paths are gone, the PATH entries and env vars left
+// pointing into them, and version directories under runtimesDir (e.g.
+// ~/.templatr/runtimes) that no installation records. It changes nothing;
+// see Prune and Adopt.
+func Reconcile(s *State, runtimesDir string) (Drift, error) {
+	var d Drift
+	var gone []string
+	for _, inst := range s.Installations {
+		if inst.Path != "" && pathGone(inst.Path) {
+			d.Missing = append(d.Missing, inst)
+			gone = append(gone, inst.Path)
+		}
+	}
+
+	for _, link := range s.Links {
+		if !pathGone(link.Target) {
+			continue
+		}
+		if next := s.latestSibling(Installation{Runtime: link.Runtime}, link.Path); next != nil {
+			repoint := link
+			repoint.Target = next.Path
+			d.Repoint = append(d.Repoint, repoint)
+			continue
+		}
+		d.DanglingLinks = append(d.DanglingLinks, link)
+		gone = append(gone, link.Path)
+	}
+
+	for _, mod := range s.PathModifications {
+		if underAny(mod.Value, gone) && pathGone(mod.Value) {
+			d.PathMods = append(d.PathMods, mod)
+		}
+	}
+	for _, mod := range s.EnvModifications {
+		if underAny(mod.Value, gone) && pathGone(mod.Value) {
+			d.EnvMods = append(d.EnvMods, mod)
+		}
+	}
+
+	orphans, err := findOrphans(s, runtimesDir)
+	if err != nil {
+		return d, err
+	}
+	d.Orphans = orphans
+	return d, nil
+}
+
+// Prune forgets the missing installations and dangling links in d and
+// records repointed links. With mods, the PATH entries and env vars in d
+// are forgotten too; the caller removes them from the shell config or
+// registry.
+func (s *State) Prune(d Drift, mods bool) {
+	for _, inst := range d.Missing {
+		s.RemoveInstallation(inst.Runtime, inst.Version)
+	}
+	for _, link := range d.DanglingLinks {
+		s.RemoveLink(link.Runtime)
+	}
+	for _, link := range d.Repoint {
+		s.SetLink(link)
+	}
+	if !mods {
+		return
+	}
+	for _, mod := range d.PathMods {
+		s.RemovePathModification(mod.Value)
+	}
+	for _, mod := range d.EnvMods {
+		s.RemoveEnvModification(mod.Name)
+	}
+}
+
+// Adopt records an orphaned version directory as an installation, so
+// uninstall and clean manage it like any other.
+func (s *State) Adopt(o Orphan) {
+	for _, inst := range s.Installations {
+		if inst.Path == o.Path {
+			return
+		}
+	}
+	s.AddInstallation(Installation{Runtime: o.Runtime, Version: o.Version, Path: o.Path, Action: "install"})
+}
+
+// findOrphans returns the version directories under runtimesDir that no
+// installation records.
+func findOrphans(s *State, runtimesDir string) ([]Orphan, error) {
+	known := map[string]bool{}
+	for _, inst := range s.Installations {
+		known[filepath.Clean(inst.Path)] = true
+	}
+
+	runtimeDirs, err := os.ReadDir(runtimesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", runtimesDir, err)
+	}
+	var orphans []Orphan
+	for _, rd := range runtimeDirs {
+		if !rd.IsDir() {
+			continue
+		}
+		dir := filepath.Join(runtimesDir, rd.Name())
+		versionDirs, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		for _, vd := range versionDirs {
+			// Skip the current link, which is a directory on Windows, and
+			// unfinished extractions
+			if !vd.IsDir() || vd.Name() == "current" || strings.HasSuffix(vd.Name(), ".tmp") {
+				continue
+			}
+			path := filepath.Join(dir, vd.Name())
+			if !known[path] {
+				orphans = append(orphans, Orphan{Runtime: rd.Name(), Version: vd.Name(), Path: path})
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// pathGone reports whether nothing exists at path, following links, so a
+// link to a deleted directory is gone too.
+func pathGone(path string) bool {
+	_, err := os.Stat(path)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// underAny reports whether path is one of dirs or inside one.
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	base := t.TempDir()
+	mk := func(parts ...string) string {
+		dir := filepath.Join(append([]string{base}, parts...)...)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	python := mk("python", "3.12.8")
+	orphan := mk("go", "1.24.1")
+	mk("go", "1.25.0.tmp")  // unfinished extraction
+	mk("python", "current") // the link, a directory on Windows
+	node := filepath.Join(base, "node", "22.14.0")
+	javaLink := filepath.Join(base, "java", "current")
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: node, Action: "install"})
+	s.AddInstallation(Installation{Runtime: "python", Version: "3.12.8", Path: python, Action: "install"})
+	s.AddInstallation(Installation{Runtime: "java", Version: "21.0.2", Path: filepath.Join(base, "java", "21.0.2"), Action: "install"})
+	s.SetLink(RuntimeLink{Runtime: "java", Path: javaLink, Target: filepath.Join(base, "java", "21.0.2")})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: filepath.Join(node, "bin")})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: filepath.Join(javaLink, "bin")})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: filepath.Join(python, "bin")})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: "/usr/local/bin"})
+	s.AddEnvModification(EnvModification{Name: "JAVA_HOME", Value: javaLink, Method: "shell_rc"})
+
+	d, err := Reconcile(s, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Missing) != 2 || d.Missing[0].Runtime != "node" || d.Missing[1].Runtime != "java" {
+		t.Errorf("Missing = %+v, want node and java", d.Missing)
+	}
+	if len(d.DanglingLinks) != 1 || d.DanglingLinks[0].Path != javaLink || len(d.Repoint) != 0 {
+		t.Errorf("links = %+v / %+v, want the java link dangling", d.DanglingLinks, d.Repoint)
+	}
+	// python/bin doesn't exist, but python is still installed
+	if len(d.PathMods) != 2 || d.PathMods[0].Value != filepath.Join(node, "bin") || d.PathMods[1].Value != filepath.Join(javaLink, "bin") {
+		t.Errorf("PathMods = %+v, want node/bin and java/current/bin", d.PathMods)
+	}
+	if len(d.EnvMods) != 1 || d.EnvMods[0].Name != "JAVA_HOME" {
+		t.Errorf("EnvMods = %+v, want JAVA_HOME", d.EnvMods)
+	}
+	if len(d.Orphans) != 1 || d.Orphans[0] != (Orphan{Runtime: "go", Version: "1.24.1", Path: orphan}) {
+		t.Errorf("Orphans = %+v, want go 1.24.1", d.Orphans)
+	}
+
+	// Without mods, the PATH and env records stay with their rc lines
+	kept := *s
+	kept.Installations = append([]Installation(nil), s.Installations...)
+	kept.Links = append([]RuntimeLink(nil), s.Links...)
+	kept.Prune(d, false)
+	if len(kept.Installations) != 1 || kept.GetLink("java") != nil || len(kept.PathModifications) != 4 {
+		t.Errorf("Prune(mods=false) left %+v", kept)
+	}
+
+	s.Prune(d, true)
+	s.Adopt(d.Orphans[0])
+	s.Adopt(d.Orphans[0])
+	if len(s.Installations) != 2 || s.Installations[0].Runtime != "python" || s.Installations[1].Path != orphan {
+		t.Errorf("Installations = %+v, want python and the adopted go", s.Installations)
+	}
+	if len(s.PathModifications) != 2 || len(s.EnvModifications) != 0 {
+		t.Errorf("mods = %+v / %+v, want only python/bin and /usr/local/bin", s.PathModifications, s.EnvModifications)
+	}
+
+	d, err = Reconcile(s, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("Reconcile() after repair = %+v, want no drift", d)
+	}
+}
+
+func TestReconcile_RepointsToRemainingVersion(t *testing.T) {
+	base := t.TempDir()
+	older := filepath.Join(base, "node", "20.11.0")
+	newer := filepath.Join(base, "node", "22.14.0")
+	link := filepath.Join(base, "node", "current")
+	os.MkdirAll(older, 0o755)
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "20.11.0", Path: older, Action: "install"})
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: newer, Action: "upgrade"})
+	s.SetLink(RuntimeLink{Runtime: "node", Path: link, Target: newer})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: filepath.Join(link, "bin")})
+
+	d, err := Reconcile(s, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Repoint) != 1 || d.Repoint[0].Target != older || len(d.DanglingLinks) != 0 {
+		t.Fatalf("Repoint = %+v, want the link aimed at %s", d.Repoint, older)
+	}
+	if len(d.PathMods) != 0 {
+		t.Errorf("PathMods = %+v, current/bin should stay on PATH", d.PathMods)
+	}
+
+	s.Prune(d, true)
+	if got := s.GetLink("node"); got == nil || got.Target != older {
+		t.Errorf("link = %+v, want target %s", got, older)
+	}
+	if len(s.PathModifications) != 1 {
+		t.Error("the PATH entry should be kept")
+	}
+}
+
+func TestReconcile_NoRuntimesDir(t *testing.T) {
+	d, err := Reconcile(NewState(), filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("Reconcile() = %+v, want no drift", d)
+	}
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RuntimesBase returns the runtimes directory s's installations are in,
+// the parent of each <runtime>/<version> directory, or "" if there are
+// none. It fails if they are spread over more than one.
+func (s *State) RuntimesBase() (string, error) {
+	base := ""
+	for _, inst := range s.Installations {
+		if inst.Path == "" {
+			continue
+		}
+		dir := filepath.Dir(filepath.Dir(inst.Path))
+		switch {
+		case base == "":
+			base = dir
+		case dir != base:
+			return "", fmt.Errorf("installations are in both %s and %s", base, dir)
+		}
+	}
+	return base, nil
+}
+
+// Rehome rewrites every path in s inside the runtimes directory from to
+// the same place inside to, once the directory has been moved there. It
+// returns the number of installations moved and the PATH entries and env
+// vars that pointed into from, as they were, for their shell config lines
+// or user environment to be rewritten too.
+func (s *State) Rehome(from, to string) (moved int, pathMods []PathModification, envMods []EnvModification) {
+	for i := range s.Installations {
+		inst := &s.Installations[i]
+		if rebase(&inst.Path, from, to) {
+			moved++
+		}
+		rebase(&inst.PreviousPath, from, to)
+	}
+	for i := range s.PathModifications {
+		mod := &s.PathModifications[i]
+		old := *mod
+		if rebase(&mod.Value, from, to) {
+			mod.Line = strings.ReplaceAll(mod.Line, old.Value, mod.Value)
+			pathMods = append(pathMods, old)
+		}
+	}
+	for i := range s.EnvModifications {
+		mod := &s.EnvModifications[i]
+		old := *mod
+		if rebase(&mod.Value, from, to) {
+			envMods = append(envMods, old)
+		}
+	}
+	for i := range s.Links {
+		rebase(&s.Links[i].Path, from, to)
+		rebase(&s.Links[i].Target, from, to)
+	}
+	return moved, pathMods, envMods
+}
+
+// rebase points *path at the same place inside to if it is inside from,
+// and reports whether it did.
+func rebase(path *string, from, to string) bool {
+	if !underAny(*path, []string{from}) {
+		return false
+	}
+	rel, err := filepath.Rel(from, *path)
+	if err != nil {
+		return false
+	}
+	*path = filepath.Join(to, rel)
+	return true
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestState_Rehome(t *testing.T) {
+	from, to := filepath.Join(t.TempDir(), "runtimes"), filepath.Join(t.TempDir(), "data", "runtimes")
+	node := filepath.Join(from, "node", "22.14.0")
+	link := filepath.Join(from, "node", "current")
+	other := filepath.Join(t.TempDir(), ".nvm", "versions", "node", "v20.11.0")
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: node, PreviousPath: other, Action: "upgrade"})
+	s.AddInstallation(Installation{Runtime: "java", Version: "21.0.2", Path: filepath.Join(from, "java", "21.0.2"), Action: "install"})
+	s.SetLink(RuntimeLink{Runtime: "node", Path: link, Target: node})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: filepath.Join(link, "bin"), Line: "# templatr-setup: " + filepath.Join(link, "bin")})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: "/usr/local/bin"})
+	s.AddEnvModification(EnvModification{Name: "JAVA_HOME", Value: filepath.Join(from, "java", "current"), Method: "shell_rc"})
+
+	if base, err := s.RuntimesBase(); err != nil || base != from {
+		t.Errorf("RuntimesBase() = %s, %v, want %s", base, err, from)
+	}
+
+	moved, pathMods, envMods := s.Rehome(from, to)
+	if moved != 2 {
+		t.Errorf("Rehome() moved %d installations, want 2", moved)
+	}
+	if len(pathMods) != 1 || pathMods[0].Value != filepath.Join(link, "bin") {
+		t.Errorf("Rehome() PATH entries = %+v, want the old node entry", pathMods)
+	}
+	if len(envMods) != 1 || envMods[0].Value != filepath.Join(from, "java", "current") {
+		t.Errorf("Rehome() env vars = %+v, want the old JAVA_HOME", envMods)
+	}
+
+	newLink := filepath.Join(to, "node", "current")
+	if inst := s.Installations[0]; inst.Path != filepath.Join(to, "node", "22.14.0") || inst.PreviousPath != other {
+		t.Errorf("node = %+v, want its path moved and the previous one outside left alone", inst)
+	}
+	if l := s.GetLink("node"); l.Path != newLink || l.Target != filepath.Join(to, "node", "22.14.0") {
+		t.Errorf("link = %+v, want it inside %s", l, to)
+	}
+	if mod := s.PathModifications[0]; mod.Value != filepath.Join(newLink, "bin") || mod.Line != "# templatr-setup: "+filepath.Join(newLink, "bin") {
+		t.Errorf("PATH entry = %+v, want it inside %s", mod, to)
+	}
+	if s.PathModifications[1].Value != "/usr/local/bin" {
+		t.Errorf("a PATH entry outside the runtimes directory changed: %+v", s.PathModifications[1])
+	}
+	if base, _ := s.RuntimesBase(); base != to {
+		t.Errorf("RuntimesBase() after Rehome = %s, want %s", base, to)
+	}
+
+	s.AddInstallation(Installation{Runtime: "go", Version: "1.24.1", Path: filepath.Join(from, "go", "1.24.1"), Action: "install"})
+	if _, err := s.RuntimesBase(); err == nil {
+		t.Error("RuntimesBase() should fail for installations in two directories")
+	}
+}
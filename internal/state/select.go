@@ -0,0 +1,67 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Selection is the result of matching uninstall arguments against state.
+type Selection struct {
+	// Installations matched an argument unambiguously, in state order.
+	Installations []Installation
+
+	// Ambiguous holds, per runtime, the installations of a runtime given
+	// without a version (or with a partial one) that matched more than one.
+	Ambiguous map[string][]Installation
+}
+
+// Select matches runtime[@version] arguments, e.g. "python" or
+// "node@22.14.0", against the recorded installations. A version matches
+// exactly or as a prefix ending at a dot, so "node@22" matches 22.14.0.
+// When allVersions is set, every match goes into Installations instead of
+// Ambiguous. It is an error for an argument to match nothing.
+func (s *State) Select(args []string, allVersions bool) (*Selection, error) {
+	sel := &Selection{Ambiguous: map[string][]Installation{}}
+	picked := map[string]bool{}
+
+	for _, arg := range args {
+		runtime, version, _ := strings.Cut(arg, "@")
+		runtime = strings.ToLower(strings.TrimSpace(runtime))
+		version = strings.TrimSpace(version)
+
+		var matches []Installation
+		for _, inst := range s.Installations {
+			if inst.Runtime == runtime && versionMatches(inst.Version, version) {
+				matches = append(matches, inst)
+			}
+		}
+
+		switch {
+		case len(matches) == 0 && version != "":
+			return nil, fmt.Errorf("%s %s was not installed by templatr-setup", runtime, version)
+		case len(matches) == 0:
+			return nil, fmt.Errorf("no %s installation was recorded by templatr-setup", runtime)
+		case len(matches) > 1 && !allVersions:
+			sel.Ambiguous[runtime] = matches
+			continue
+		}
+
+		for _, inst := range matches {
+			key := inst.Runtime + "@" + inst.Version
+			if !picked[key] {
+				picked[key] = true
+				sel.Installations = append(sel.Installations, inst)
+			}
+		}
+	}
+	return sel, nil
+}
+
+// versionMatches reports whether installed is want, or starts with want
+// followed by a dot. An empty want matches any version.
+func versionMatches(installed, want string) bool {
+	if want == "" || installed == want {
+		return true
+	}
+	return strings.HasPrefix(installed, want+".")
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func selectState() *State {
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "20.11.0", Path: "/r/node/20.11.0"})
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: "/r/node/22.14.0"})
+	s.AddInstallation(Installation{Runtime: "python", Version: "3.12.8", Path: "/r/python/3.12.8"})
+	return s
+}
+
+func selected(sel *Selection) string {
+	var names []string
+	for _, inst := range sel.Installations {
+		names = append(names, inst.Runtime+"@"+inst.Version)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestState_Select(t *testing.T) {
+	s := selectState()
+
+	tests := []struct {
+		args        []string
+		allVersions bool
+		want        string
+		ambiguous   string
+	}{
+		{[]string{"python"}, false, "python@3.12.8", ""},
+		{[]string{"node@22.14.0"}, false, "node@22.14.0", ""},
+		{[]string{"node@22"}, false, "node@22.14.0", ""},
+		{[]string{"Python", "node@20.11.0"}, false, "python@3.12.8 node@20.11.0", ""},
+		{[]string{"node"}, false, "", "node"},
+		{[]string{"node", "python"}, false, "python@3.12.8", "node"},
+		{[]string{"node"}, true, "node@20.11.0 node@22.14.0", ""},
+		{[]string{"node@22", "node@22.14.0"}, false, "node@22.14.0", ""},
+	}
+	for _, tt := range tests {
+		sel, err := s.Select(tt.args, tt.allVersions)
+		if err != nil {
+			t.Errorf("Select(%v) error: %s", tt.args, err)
+			continue
+		}
+		if got := selected(sel); got != tt.want {
+			t.Errorf("Select(%v, %v) = %q, want %q", tt.args, tt.allVersions, got, tt.want)
+		}
+		if tt.ambiguous == "" && len(sel.Ambiguous) > 0 {
+			t.Errorf("Select(%v) should not be ambiguous, got %v", tt.args, sel.Ambiguous)
+		}
+		if tt.ambiguous != "" && len(sel.Ambiguous[tt.ambiguous]) != 2 {
+			t.Errorf("Select(%v) should ask which %s to remove, got %v", tt.args, tt.ambiguous, sel.Ambiguous)
+		}
+	}
+}
+
+func TestState_Select_NoMatch(t *testing.T) {
+	s := selectState()
+
+	for _, tt := range []struct {
+		arg  string
+		want string
+	}{
+		{"go", "no go installation was recorded"},
+		{"node@18", "node 18 was not installed by templatr-setup"},
+		{"node@2", "node 2 was not installed"}, // prefixes only end at a dot
+	} {
+		if _, err := s.Select([]string{tt.arg}, false); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Select(%s) error = %v, want %q", tt.arg, err, tt.want)
+		}
+	}
+}
+
+func TestState_Select_UndoLeavesOthers(t *testing.T) {
+	s := selectState()
+	s.AddPathModification(PathModification{Value: "/r/python/3.12.8/bin"})
+	s.AddPathModification(PathModification{Value: "/r/node/22.14.0/bin"})
+
+	sel, err := s.Select([]string{"python"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inst := range sel.Installations {
+		result, err := s.UndoInstallation(inst.Runtime, inst.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.PathMod == nil || result.PathMod.Value != "/r/python/3.12.8/bin" {
+			t.Errorf("expected the python PATH entry to be cleaned up, got %+v", result.PathMod)
+		}
+	}
+
+	if len(s.Installations) != 2 || len(s.GetInstallations("node")) != 2 {
+		t.Errorf("node installations should be kept, got %+v", s.Installations)
+	}
+	if len(s.PathModifications) != 1 || s.PathModifications[0].Value != "/r/node/22.14.0/bin" {
+		t.Errorf("node PATH entry should be kept, got %+v", s.PathModifications)
+	}
+}
@@ -0,0 +1,108 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+const snapshotDir = ".templatr/templates"
+
+// Snapshot records the manifest that was last applied successfully for a template.
+type Snapshot struct {
+	Slug      string             `json:"slug"`
+	Hash      string             `json:"hash"`
+	AppliedAt string             `json:"applied_at"`
+	Manifest  *manifest.Manifest `json:"manifest"`
+}
+
+var unsafeSlugChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// SnapshotKey returns the file-safe key used to store a template's snapshot.
+// It prefers the template slug and falls back to the template name.
+func SnapshotKey(m *manifest.Manifest) string {
+	key := m.Template.Slug
+	if key == "" {
+		key = m.Template.Name
+	}
+	key = unsafeSlugChars.ReplaceAllString(strings.ToLower(key), "-")
+	return strings.Trim(key, "-.")
+}
+
+// snapshotPath returns the full path to a template's snapshot file.
+func snapshotPath(key string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, snapshotDir, key+".json"), nil
+}
+
+// SaveSnapshot records m as the last applied manifest for its template.
+func SaveSnapshot(m *manifest.Manifest) error {
+	key := SnapshotKey(m)
+	if key == "" {
+		return fmt.Errorf("manifest has no template slug or name")
+	}
+
+	path, err := snapshotPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	snap := Snapshot{
+		Slug:      key,
+		Hash:      manifest.Hash(m),
+		AppliedAt: time.Now().UTC().Format(time.RFC3339),
+		Manifest:  m,
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadSnapshot reads the last applied manifest for the template described by m.
+// Returns nil without error if the template has never been set up.
+func LoadSnapshot(m *manifest.Manifest) (*Snapshot, error) {
+	key := SnapshotKey(m)
+	if key == "" {
+		return nil, nil
+	}
+
+	path, err := snapshotPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	if snap.Manifest == nil {
+		return nil, fmt.Errorf("snapshot %s has no manifest", path)
+	}
+
+	return &snap, nil
+}
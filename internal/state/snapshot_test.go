@@ -0,0 +1,61 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func TestSnapshotKey(t *testing.T) {
+	tests := []struct {
+		slug, name, want string
+	}{
+		{"saas-starter", "SaaS Starter", "saas-starter"},
+		{"", "SaaS Starter", "saas-starter"},
+		{"", "My/Template: v2", "my-template-v2"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		m := &manifest.Manifest{Template: manifest.TemplateInfo{Slug: tt.slug, Name: tt.name}}
+		if got := SnapshotKey(m); got != tt.want {
+			t.Errorf("SnapshotKey(slug=%q, name=%q) = %q, want %q", tt.slug, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshot_SaveAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+
+	m := &manifest.Manifest{
+		Template: manifest.TemplateInfo{Name: "SaaS Starter", Slug: "saas-starter", Version: "1.0.0"},
+		Runtimes: map[string]string{"node": ">=20.0.0"},
+	}
+
+	snap, err := LoadSnapshot(m)
+	if err != nil {
+		t.Fatalf("LoadSnapshot before save: %s", err)
+	}
+	if snap != nil {
+		t.Fatal("expected no snapshot before first save")
+	}
+
+	if err := SaveSnapshot(m); err != nil {
+		t.Fatalf("SaveSnapshot: %s", err)
+	}
+
+	snap, err = LoadSnapshot(m)
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %s", err)
+	}
+	if snap == nil {
+		t.Fatal("expected snapshot after save")
+	}
+	if snap.Hash != manifest.Hash(m) {
+		t.Error("snapshot hash does not match manifest")
+	}
+	if snap.Manifest.Runtimes["node"] != ">=20.0.0" {
+		t.Errorf("snapshot node = %q, want >=20.0.0", snap.Manifest.Runtimes["node"])
+	}
+}
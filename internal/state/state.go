@@ -6,49 +6,87 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/templatr/templatr-setup/internal/fsutil"
 )
 
 const stateFile = ".templatr/state.json"
 
+// backupSuffix names the copy of the last good state kept next to the
+// state file, e.g. state.json.bak.
+const backupSuffix = ".bak"
+
+// warnf reports a recovered state file; replaced in tests.
+var warnf = func(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
 // State tracks all installations performed by templatr-setup.
 type State struct {
 	Version           string             `json:"version"`
 	Installations     []Installation     `json:"installations"`
 	PathModifications []PathModification `json:"path_modifications"`
 	EnvModifications  []EnvModification  `json:"env_modifications,omitempty"`
+	Links             []RuntimeLink      `json:"links,omitempty"`
 }
 
 // Installation records a single runtime installation.
 type Installation struct {
-	Runtime         string `json:"runtime"`
-	Version         string `json:"version"`
-	Path            string `json:"path"`
-	InstalledAt     string `json:"installed_at"`
-	Template        string `json:"template,omitempty"`
-	Checksum        string `json:"checksum,omitempty"`
-	PreviousVersion string `json:"previous_version,omitempty"` // version before we installed (for revert messaging)
-	PreviousPath    string `json:"previous_path,omitempty"`    // path to the previous installation
-	Action          string `json:"action"`                     // "install" or "upgrade"
+	Runtime         string   `json:"runtime"`
+	Version         string   `json:"version"`
+	Path            string   `json:"path"`
+	InstalledAt     string   `json:"installed_at"`
+	Template        string   `json:"template,omitempty"`
+	UsedBy          []string `json:"used_by,omitempty"` // other templates that found it already installed
+	Checksum        string   `json:"checksum,omitempty"`
+	PreviousVersion string   `json:"previous_version,omitempty"` // version before we installed (for revert messaging)
+	PreviousPath    string   `json:"previous_path,omitempty"`    // path to the previous installation
+	Action          string   `json:"action"`                     // "install" or "upgrade"
+	NoPath          bool     `json:"no_path,omitempty"`          // installed with --no-path: no PATH entry or env var was written for it
+	Entries         []string `json:"entries,omitempty"`          // for an extracted download, what it placed in Path; uninstall removes these, not Path
 }
 
 // PathModification records a PATH change made by the tool.
 type PathModification struct {
-	Method  string `json:"method"`            // "shell_rc" or "windows_env"
-	File    string `json:"file,omitempty"`     // shell config file path (Unix)
-	Line    string `json:"line,omitempty"`     // line added to shell config
-	Value   string `json:"value"`             // the PATH directory value
-	AddedAt string `json:"added_at"`
+	Method  string   `json:"method"`          // "shell_rc", "fish", "windows_env" or "powershell_profile"
+	File    string   `json:"file,omitempty"`  // last shell config file or PowerShell profile written, kept for older versions
+	Files   []string `json:"files,omitempty"` // every shell config file or PowerShell profile written
+	Line    string   `json:"line,omitempty"`  // line added to shell config
+	Value   string   `json:"value"`           // the PATH directory value
+	AddedAt string   `json:"added_at"`
+}
+
+// ConfigFiles returns the shell config files the change was written to.
+// Entries from before Files was recorded only have File.
+func (m PathModification) ConfigFiles() []string {
+	if len(m.Files) > 0 {
+		return m.Files
+	}
+	if m.File != "" {
+		return []string{m.File}
+	}
+	return nil
 }
 
 // EnvModification records an environment variable set by the tool (e.g., JAVA_HOME).
 type EnvModification struct {
 	Name    string `json:"name"`              // e.g. "JAVA_HOME", "GOROOT"
 	Value   string `json:"value"`             // the value set
-	Method  string `json:"method"`            // "shell_rc" or "windows_env"
+	Method  string `json:"method"`            // "shell_rc", "fish" or "windows_env"
 	File    string `json:"file,omitempty"`     // shell config file path (Unix)
 	AddedAt string `json:"added_at"`
 }
 
+// RuntimeLink records the "current" symlink (a junction on Windows) that
+// points at the active version of a runtime. PATH gets the link's bin dir
+// once, so an upgrade only repoints the link.
+type RuntimeLink struct {
+	Runtime   string `json:"runtime"`
+	Path      string `json:"path"`   // e.g. ~/.templatr/runtimes/node/current
+	Target    string `json:"target"` // the version directory it points at
+	UpdatedAt string `json:"updated_at"`
+}
+
 // NewState creates an empty state.
 func NewState() *State {
 	return &State{
@@ -67,7 +105,8 @@ func stateFilePath() (string, error) {
 	return filepath.Join(home, stateFile), nil
 }
 
-// Load reads the state file from disk.
+// Load reads the state file from disk. If it is corrupt, the copy of the
+// last good state kept by Save is used instead, with a warning.
 func Load() (*State, error) {
 	path, err := stateFilePath()
 	if err != nil {
@@ -84,13 +123,45 @@ func Load() (*State, error) {
 
 	var s State
 	if err := json.Unmarshal(data, &s); err != nil {
-		return nil, fmt.Errorf("failed to parse state file: %w", err)
+		backup, backupErr := loadFile(path + backupSuffix)
+		if backupErr != nil {
+			return nil, fmt.Errorf("failed to parse state file: %w", err)
+		}
+		warnf("%s is corrupt (%s) - using the last good copy from %s", path, err, path+backupSuffix)
+		return backup, nil
 	}
 
+	s.migrate()
 	return &s, nil
 }
 
-// Save writes the state to disk.
+// migrate upgrades entries written by older versions: PATH changes
+// recorded a single File rather than Files.
+func (s *State) migrate() {
+	for i := range s.PathModifications {
+		mod := &s.PathModifications[i]
+		if len(mod.Files) == 0 && mod.File != "" {
+			mod.Files = []string{mod.File}
+		}
+	}
+}
+
+// loadFile reads and parses a state file.
+func loadFile(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	s.migrate()
+	return &s, nil
+}
+
+// Save writes the state to disk atomically, then refreshes the copy of the
+// last good state that Load falls back to.
 func (s *State) Save() error {
 	path, err := stateFilePath()
 	if err != nil {
@@ -106,7 +177,12 @@ func (s *State) Save() error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	return os.WriteFile(path, data, 0o644)
+	if err := fsutil.WriteFileAtomic(path, data, 0o644); err != nil {
+		return err
+	}
+	// Best effort: the state itself is already saved
+	fsutil.WriteFileAtomic(path+backupSuffix, data, 0o644)
+	return nil
 }
 
 // AddInstallation records a new installation.
@@ -163,6 +239,40 @@ func (s *State) RemoveEnvModification(name string) {
 	s.EnvModifications = filtered
 }
 
+// SetLink records the current link for a runtime, replacing any earlier one.
+func (s *State) SetLink(link RuntimeLink) {
+	link.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	for i := range s.Links {
+		if s.Links[i].Runtime == link.Runtime {
+			s.Links[i] = link
+			return
+		}
+	}
+	s.Links = append(s.Links, link)
+}
+
+// GetLink returns the current link recorded for a runtime, or nil.
+func (s *State) GetLink(runtime string) *RuntimeLink {
+	for i := range s.Links {
+		if s.Links[i].Runtime == runtime {
+			return &s.Links[i]
+		}
+	}
+	return nil
+}
+
+// RemoveLink forgets the current link for a runtime.
+func (s *State) RemoveLink(runtime string) {
+	var filtered []RuntimeLink
+	for _, link := range s.Links {
+		if link.Runtime == runtime {
+			continue
+		}
+		filtered = append(filtered, link)
+	}
+	s.Links = filtered
+}
+
 // GetInstallations returns all installations, optionally filtered by runtime.
 func (s *State) GetInstallations(runtime string) []Installation {
 	if runtime == "" {
@@ -224,3 +224,172 @@ func TestState_UndoInstallation_NotFound(t *testing.T) {
 		t.Error("expected error for non-existent installation")
 	}
 }
+
+func TestState_UndoInstallation_AlreadyDeleted(t *testing.T) {
+	installDir := filepath.Join(t.TempDir(), "node", "22.14.0")
+	binDir := filepath.Join(installDir, "bin")
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: installDir, Action: "install"})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: binDir})
+
+	// The directory was never created, as if deleted by hand
+	result, err := s.UndoInstallation("node", "22.14.0")
+	if err != nil {
+		t.Fatalf("undo of a deleted install should succeed: %s", err)
+	}
+	if result.AlreadyGone != installDir {
+		t.Errorf("AlreadyGone = %q, want %q", result.AlreadyGone, installDir)
+	}
+	if result.PathMod == nil || len(s.Installations) != 0 {
+		t.Errorf("state and PATH should still be cleaned up, got %+v", result)
+	}
+}
+
+func TestState_UndoInstallation_CurrentLink(t *testing.T) {
+	tmpDir := t.TempDir()
+	older := filepath.Join(tmpDir, "node", "20.11.0")
+	newer := filepath.Join(tmpDir, "node", "22.14.0")
+	link := filepath.Join(tmpDir, "node", "current")
+	os.MkdirAll(older, 0o755)
+	os.MkdirAll(newer, 0o755)
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "20.11.0", Path: older, Action: "install"})
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: newer, Action: "upgrade"})
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: filepath.Join(link, "bin")})
+	s.SetLink(RuntimeLink{Runtime: "node", Path: link, Target: newer})
+
+	// Removing the active version repoints the link and keeps PATH as is
+	result, err := s.UndoInstallation("node", "22.14.0")
+	if err != nil {
+		t.Fatalf("undo failed: %s", err)
+	}
+	if result.Repoint == nil || result.Repoint.Target != older {
+		t.Fatalf("expected the link to be repointed at %s, got %+v", older, result.Repoint)
+	}
+	if result.PathMod != nil || result.RemovedLink != nil {
+		t.Errorf("PATH should stay while a version remains, got %+v", result)
+	}
+	if got := s.GetLink("node"); got == nil || got.Target != older {
+		t.Errorf("state link = %+v, want target %s", got, older)
+	}
+
+	// Removing the last version removes the link and its PATH entry
+	result, err = s.UndoInstallation("node", "20.11.0")
+	if err != nil {
+		t.Fatalf("undo failed: %s", err)
+	}
+	if result.RemovedLink == nil || result.RemovedLink.Path != link {
+		t.Errorf("expected the link to be removed, got %+v", result.RemovedLink)
+	}
+	if result.PathMod == nil || result.PathMod.Value != filepath.Join(link, "bin") {
+		t.Errorf("expected the current/bin PATH entry to be removed, got %+v", result.PathMod)
+	}
+	if s.GetLink("node") != nil || len(s.PathModifications) != 0 {
+		t.Error("state should forget the link and PATH entry")
+	}
+}
+
+func TestState_UndoInstallation_NoPath(t *testing.T) {
+	installDir := filepath.Join(t.TempDir(), "java", "21.0.2")
+	os.MkdirAll(installDir, 0o755)
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "java", Version: "21.0.2", Path: installDir, Action: "install", NoPath: true})
+	// Left by another tool run for the same directory; not this install's
+	s.AddPathModification(PathModification{Method: "shell_rc", Value: filepath.Join(installDir, "bin")})
+	s.AddEnvModification(EnvModification{Name: "JAVA_HOME", Value: installDir})
+
+	result, err := s.UndoInstallation("java", "21.0.2")
+	if err != nil {
+		t.Fatalf("undo failed: %s", err)
+	}
+	if result.PathMod != nil || len(result.EnvMods) != 0 {
+		t.Errorf("a --no-path install has no PATH entry or env var to remove, got %+v", result)
+	}
+	if result.NoPath != installDir {
+		t.Errorf("NoPath = %q, want %q", result.NoPath, installDir)
+	}
+	if len(s.Installations) != 0 {
+		t.Errorf("expected 0 installations after undo, got %d", len(s.Installations))
+	}
+}
+
+func TestLoad_RecoversFromBackup(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	var warnings []string
+	orig := warnf
+	warnf = func(format string, args ...any) { warnings = append(warnings, format) }
+	t.Cleanup(func() { warnf = orig })
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Action: "install"})
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %s", err)
+	}
+
+	// A half-written primary, as left by a crash mid-write
+	path := filepath.Join(home, stateFile)
+	if err := os.WriteFile(path, []byte(`{"version": "1.0.0", "installations": [{"runt`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load should recover from the backup: %s", err)
+	}
+	if len(loaded.Installations) != 1 || loaded.Installations[0].Runtime != "node" {
+		t.Errorf("recovered state = %+v, want the saved node installation", loaded.Installations)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected one warning, got %v", warnings)
+	}
+
+	// Saving the recovered state repairs the primary
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save failed: %s", err)
+	}
+	warnings = nil
+	if _, err := Load(); err != nil || len(warnings) != 0 {
+		t.Errorf("Load after repair = %v, warnings %v", err, warnings)
+	}
+}
+
+func TestLoad_CorruptWithoutBackup(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	path := filepath.Join(home, stateFile)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, []byte("not json"), 0o644)
+	os.WriteFile(path+backupSuffix, []byte("also not json"), 0o644)
+
+	if _, err := Load(); err == nil {
+		t.Error("expected an error when both the state file and its backup are corrupt")
+	}
+}
+
+func TestLoad_MigratesPathModificationFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	// Written by a version that recorded only the last rc file
+	path := filepath.Join(home, stateFile)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, []byte(`{"version": "1.0.0", "installations": [], "path_modifications": [
+		{"method": "shell_rc", "file": "/home/u/.bashrc", "value": "/opt/bin", "added_at": ""}]}`), 0o644)
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := loaded.PathModifications[0]
+	if len(mod.Files) != 1 || mod.Files[0] != "/home/u/.bashrc" || mod.File != "/home/u/.bashrc" {
+		t.Errorf("migrated entry = %+v, want Files [/home/u/.bashrc] and File kept", mod)
+	}
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TemplateSelection is the result of matching uninstall --template
+// against state.
+type TemplateSelection struct {
+	// Installations are used by the template and nothing else.
+	Installations []Installation
+
+	// Kept are used by the template but also needed by other templates.
+	Kept []SharedInstallation
+}
+
+// SharedInstallation is an installation another template still needs.
+type SharedInstallation struct {
+	Installation
+	NeededBy []string // the other templates
+}
+
+// Templates returns every template relying on the installation: the one it
+// was installed for, then those that found it already installed.
+func (inst Installation) Templates() []string {
+	var templates []string
+	if inst.Template != "" {
+		templates = append(templates, inst.Template)
+	}
+	return append(templates, inst.UsedBy...)
+}
+
+// AddUse records that template relies on the installation of runtime found
+// at path: its version directory, a file inside it, or a file inside the
+// runtime's current link. It reports whether such an installation exists.
+func (s *State) AddUse(runtime, path, template string) bool {
+	link := s.GetLink(runtime)
+	for i := range s.Installations {
+		inst := &s.Installations[i]
+		if inst.Runtime != runtime || inst.Path == "" {
+			continue
+		}
+		if !underAny(path, []string{inst.Path}) && (link == nil || link.Target != inst.Path || !underAny(path, []string{link.Path})) {
+			continue
+		}
+		if !slices.Contains(inst.Templates(), template) {
+			inst.UsedBy = append(inst.UsedBy, template)
+		}
+		return true
+	}
+	return false
+}
+
+// SelectTemplate returns the installations that template relies on, split
+// into those only it uses and those other templates also need. A runtime
+// version counts as needed by every template recorded on any installation
+// of it. It is an error for nothing to be recorded for template.
+func (s *State) SelectTemplate(template string) (*TemplateSelection, error) {
+	sel := &TemplateSelection{}
+	for _, inst := range s.Installations {
+		if !slices.Contains(inst.Templates(), template) {
+			continue
+		}
+
+		var others []string
+		for _, other := range s.Installations {
+			if other.Runtime != inst.Runtime || other.Version != inst.Version {
+				continue
+			}
+			for _, t := range other.Templates() {
+				if t != template && !slices.Contains(others, t) {
+					others = append(others, t)
+				}
+			}
+		}
+
+		if len(others) > 0 {
+			sel.Kept = append(sel.Kept, SharedInstallation{Installation: inst, NeededBy: others})
+		} else {
+			sel.Installations = append(sel.Installations, inst)
+		}
+	}
+
+	if len(sel.Installations) == 0 && len(sel.Kept) == 0 {
+		return nil, fmt.Errorf("nothing was installed by templatr-setup for template %q", template)
+	}
+	return sel, nil
+}
+
+// DropTemplate forgets that template relies on the installation of runtime
+// and version, leaving it to the other templates that use it.
+func (s *State) DropTemplate(runtime, version, template string) {
+	for i := range s.Installations {
+		inst := &s.Installations[i]
+		if inst.Runtime != runtime || inst.Version != version {
+			continue
+		}
+		inst.UsedBy = slices.DeleteFunc(inst.UsedBy, func(t string) bool { return t == template })
+		if inst.Template == template {
+			inst.Template = ""
+			if len(inst.UsedBy) > 0 {
+				inst.Template, inst.UsedBy = inst.UsedBy[0], inst.UsedBy[1:]
+			}
+		}
+	}
+}
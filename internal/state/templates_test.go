@@ -0,0 +1,105 @@
+package state
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAddUse(t *testing.T) {
+	base := t.TempDir()
+	node := filepath.Join(base, "node", "22.14.0")
+	link := filepath.Join(base, "node", "current")
+
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: node, Template: "saas-landing", Action: "install"})
+	s.SetLink(RuntimeLink{Runtime: "node", Path: link, Target: node})
+
+	// Found through the current link on PATH, then directly; recorded once
+	if !s.AddUse("node", filepath.Join(link, "bin", "node"), "nextjs-blog") {
+		t.Fatal("AddUse() through the current link found nothing")
+	}
+	if !s.AddUse("node", node, "nextjs-blog") || !s.AddUse("node", node, "saas-landing") {
+		t.Fatal("AddUse() of the version directory found nothing")
+	}
+	if got := s.Installations[0].Templates(); !slices.Equal(got, []string{"saas-landing", "nextjs-blog"}) {
+		t.Errorf("Templates() = %v, want [saas-landing nextjs-blog]", got)
+	}
+
+	if s.AddUse("node", filepath.Join(base, "node", "20.11.0", "bin", "node"), "other") {
+		t.Error("AddUse() should not match another version")
+	}
+	if s.AddUse("python", node, "other") {
+		t.Error("AddUse() should not match another runtime")
+	}
+}
+
+func TestSelectTemplate_Lone(t *testing.T) {
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: "/r/node/22.14.0", Template: "saas-landing", Action: "install"})
+	s.AddInstallation(Installation{Runtime: "fonts", Path: "/p/fonts", Template: "saas-landing", Action: "download"})
+	s.AddInstallation(Installation{Runtime: "python", Version: "3.12.8", Path: "/r/python/3.12.8", Template: "ml-dashboard", Action: "install"})
+
+	sel, err := s.SelectTemplate("saas-landing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel.Installations) != 2 || sel.Installations[0].Runtime != "node" || sel.Installations[1].Runtime != "fonts" {
+		t.Errorf("Installations = %+v, want node and fonts", sel.Installations)
+	}
+	if len(sel.Kept) != 0 {
+		t.Errorf("Kept = %+v, want none", sel.Kept)
+	}
+
+	if _, err := s.SelectTemplate("unknown"); err == nil {
+		t.Error("SelectTemplate() of a template with nothing recorded should fail")
+	}
+}
+
+func TestSelectTemplate_SharedDependency(t *testing.T) {
+	s := NewState()
+	s.AddInstallation(Installation{Runtime: "node", Version: "22.14.0", Path: "/r/node/22.14.0", Template: "saas-landing", UsedBy: []string{"nextjs-blog"}, Action: "install"})
+	s.AddInstallation(Installation{Runtime: "python", Version: "3.12.8", Path: "/r/python/3.12.8", Template: "saas-landing", Action: "install"})
+	// Recorded twice, for two templates
+	s.AddInstallation(Installation{Runtime: "go", Version: "1.24.1", Path: "/r/go/1.24.1", Template: "saas-landing", Action: "install"})
+	s.AddInstallation(Installation{Runtime: "go", Version: "1.24.1", Path: "/r/go/1.24.1", Template: "api-starter", Action: "install"})
+
+	sel, err := s.SelectTemplate("saas-landing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel.Installations) != 1 || sel.Installations[0].Runtime != "python" {
+		t.Errorf("Installations = %+v, want only python", sel.Installations)
+	}
+	if len(sel.Kept) != 2 {
+		t.Fatalf("Kept = %+v, want node and go", sel.Kept)
+	}
+	if k := sel.Kept[0]; k.Runtime != "node" || !slices.Equal(k.NeededBy, []string{"nextjs-blog"}) {
+		t.Errorf("Kept[0] = %+v, want node needed by nextjs-blog", k)
+	}
+	if k := sel.Kept[1]; k.Runtime != "go" || !slices.Equal(k.NeededBy, []string{"api-starter"}) {
+		t.Errorf("Kept[1] = %+v, want go needed by api-starter", k)
+	}
+
+	// A template that only found node installed can be removed on its own
+	sel, err = s.SelectTemplate("nextjs-blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel.Installations) != 0 || len(sel.Kept) != 1 || !slices.Equal(sel.Kept[0].NeededBy, []string{"saas-landing"}) {
+		t.Errorf("SelectTemplate(nextjs-blog) = %+v, want node kept for saas-landing", sel)
+	}
+
+	// Dropping the template hands node over to the one still using it
+	s.DropTemplate("node", "22.14.0", "saas-landing")
+	if inst := s.Installations[0]; inst.Template != "nextjs-blog" || len(inst.UsedBy) != 0 {
+		t.Errorf("after DropTemplate, node = %+v, want it owned by nextjs-blog", inst)
+	}
+	sel, err = s.SelectTemplate("nextjs-blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel.Installations) != 1 || len(sel.Kept) != 0 {
+		t.Errorf("SelectTemplate(nextjs-blog) = %+v, want node removable", sel)
+	}
+}
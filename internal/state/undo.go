@@ -3,6 +3,7 @@ package state
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -11,6 +12,15 @@ type UndoResult struct {
 	PathMod  *PathModification
 	EnvMods  []EnvModification
 	Previous *Installation // what was there before (for messaging)
+
+	// The runtime's current link pointed at the removed version: Repoint is
+	// the link to aim at the newest remaining version, or RemovedLink the
+	// link to delete when no version is left.
+	Repoint     *RuntimeLink
+	RemovedLink *RuntimeLink
+
+	AlreadyGone string // the install path, if it was deleted before the undo
+	NoPath      string // the install path, if it was installed with --no-path and the user set up PATH for it
 }
 
 // UndoInstallation removes an installed runtime from disk and cleans up state.
@@ -39,16 +49,50 @@ func (s *State) UndoInstallation(runtime, version string) (*UndoResult, error) {
 		}
 	}
 
-	// Remove the runtime directory
-	if target.Path != "" {
-		if err := os.RemoveAll(target.Path); err != nil {
-			return nil, fmt.Errorf("failed to remove %s: %w", target.Path, err)
+	// Remove the runtime directory, or for an extracted download what it
+	// placed in its target directory, which isn't its own; one already
+	// deleted by hand is just forgotten
+	removes := []string{target.Path}
+	if len(target.Entries) > 0 {
+		removes = target.Entries
+	}
+	if target.Path != "" && allGone(removes) {
+		result.AlreadyGone = target.Path
+	} else if target.Path != "" {
+		for _, path := range removes {
+			if err := os.RemoveAll(path); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+	}
+
+	// Keep the current link on a remaining version; PATH and env vars point
+	// through the link, so they only go once the last version does. A
+	// --no-path install wrote none of its own; the lines the user added for
+	// it are theirs to remove
+	var owned []string
+	if target.NoPath {
+		result.NoPath = target.Path
+	} else {
+		owned = append(owned, removes...)
+	}
+	if link := s.GetLink(runtime); link != nil && target.Path != "" && link.Target == target.Path {
+		if next := s.latestSibling(*target, link.Path); next != nil {
+			repoint := *link
+			repoint.Target = next.Path
+			result.Repoint = &repoint
+			s.SetLink(repoint)
+		} else {
+			removed := *link
+			result.RemovedLink = &removed
+			owned = append(owned, link.Path)
+			s.RemoveLink(runtime)
 		}
 	}
 
 	// Find associated PATH modification
 	for _, mod := range s.PathModifications {
-		if target.Path != "" && strings.HasPrefix(mod.Value, target.Path) {
+		if target.Path != "" && hasAnyPrefix(mod.Value, owned) {
 			result.PathMod = &mod
 			break
 		}
@@ -56,7 +100,7 @@ func (s *State) UndoInstallation(runtime, version string) (*UndoResult, error) {
 
 	// Find associated env modifications (e.g., JAVA_HOME pointing into our install dir)
 	for _, mod := range s.EnvModifications {
-		if target.Path != "" && strings.HasPrefix(mod.Value, target.Path) {
+		if target.Path != "" && hasAnyPrefix(mod.Value, owned) {
 			result.EnvMods = append(result.EnvMods, mod)
 		}
 	}
@@ -73,6 +117,38 @@ func (s *State) UndoInstallation(runtime, version string) (*UndoResult, error) {
 	return result, nil
 }
 
+// latestSibling returns the most recently recorded other installation of
+// target's runtime that lives next to linkPath and still exists, or nil.
+func (s *State) latestSibling(target Installation, linkPath string) *Installation {
+	dir := filepath.Dir(linkPath)
+	for i := len(s.Installations) - 1; i >= 0; i-- {
+		inst := s.Installations[i]
+		if inst.Runtime == target.Runtime && inst.Version != target.Version && filepath.Dir(inst.Path) == dir && !pathGone(inst.Path) {
+			return &s.Installations[i]
+		}
+	}
+	return nil
+}
+
+// allGone reports whether every one of paths has been deleted.
+func allGone(paths []string) bool {
+	for _, path := range paths {
+		if !pathGone(path) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(value, p) {
+			return true
+		}
+	}
+	return false
+}
+
 // UndoAll removes all installations tracked in state.
 func (s *State) UndoAll() ([]UndoResult, []error) {
 	var results []UndoResult
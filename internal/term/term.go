@@ -0,0 +1,73 @@
+package term
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	xterm "golang.org/x/term"
+)
+
+// noColor is set by --no-color.
+var noColor bool
+
+// SetNoColor turns styling and animation off for the rest of the run, as
+// NO_COLOR does. It is set from --no-color.
+func SetNoColor(on bool) {
+	noColor = on
+}
+
+// IsTerminal reports whether f is connected to a terminal.
+func IsTerminal(f *os.File) bool {
+	return xterm.IsTerminal(int(f.Fd()))
+}
+
+// Plain reports whether output is to be left unstyled and still, for CI
+// logs and screen readers: --no-color was given, NO_COLOR is set to
+// anything (see https://no-color.org), or TERM is dumb.
+func Plain() bool {
+	return plain(noColor, os.Getenv)
+}
+
+// Rich reports whether output to f can use colour, animation such as
+// spinners and progress redrawn with \r, and box drawing: f is a terminal
+// and output isn't Plain.
+func Rich(f *os.File) bool {
+	return rich(noColor, os.Getenv, IsTerminal(f))
+}
+
+// Rule returns a horizontal line n wide for stdout: box drawing when
+// stdout is Rich, else ASCII dashes.
+func Rule(n int) string {
+	return rule(n, Rich(os.Stdout))
+}
+
+func plain(noColor bool, getenv func(string) string) bool {
+	return noColor || getenv("NO_COLOR") != "" || getenv("TERM") == "dumb"
+}
+
+func rich(noColor bool, getenv func(string) string, tty bool) bool {
+	return tty && !plain(noColor, getenv)
+}
+
+func rule(n int, rich bool) string {
+	if rich {
+		return strings.Repeat("─", n)
+	}
+	return strings.Repeat("-", n)
+}
+
+// FormatBytes formats b for people to read, in binary units: 512 B,
+// 1.5 KB, 20.0 MB.
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
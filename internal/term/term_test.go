@@ -0,0 +1,76 @@
+package term
+
+import "testing"
+
+func TestRich(t *testing.T) {
+	tests := []struct {
+		name      string
+		noColor   bool
+		env       map[string]string
+		tty       bool
+		wantPlain bool
+		wantRich  bool
+	}{
+		{"terminal", false, nil, true, false, true},
+		{"pipe", false, nil, false, false, false},
+		{"--no-color", true, nil, true, true, false},
+		{"NO_COLOR", false, map[string]string{"NO_COLOR": "1"}, true, true, false},
+		{"empty NO_COLOR", false, map[string]string{"NO_COLOR": ""}, true, false, true},
+		{"dumb terminal", false, map[string]string{"TERM": "dumb"}, true, true, false},
+		{"NO_COLOR in a pipe", false, map[string]string{"NO_COLOR": "1"}, false, true, false},
+		{"xterm", false, map[string]string{"TERM": "xterm-256color"}, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			if got := plain(tt.noColor, getenv); got != tt.wantPlain {
+				t.Errorf("plain = %v, want %v", got, tt.wantPlain)
+			}
+			if got := rich(tt.noColor, getenv, tt.tty); got != tt.wantRich {
+				t.Errorf("rich = %v, want %v", got, tt.wantRich)
+			}
+		})
+	}
+}
+
+func TestSetNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	t.Cleanup(func() { SetNoColor(false) })
+	if Plain() {
+		t.Fatal("Plain() = true without --no-color or NO_COLOR")
+	}
+	SetNoColor(true)
+	if !Plain() {
+		t.Error("Plain() = false after SetNoColor(true)")
+	}
+}
+
+func TestRule(t *testing.T) {
+	if got := rule(3, true); got != "───" {
+		t.Errorf("rule(3) = %q on a terminal, want box drawing", got)
+	}
+	if got := rule(3, false); got != "---" {
+		t.Errorf("rule(3) = %q off a terminal, want ASCII dashes", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{20 << 20, "20.0 MB"},
+		{3 << 30, "3.0 GB"},
+		{2 << 40, "2.0 TB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.in); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
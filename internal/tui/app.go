@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,34 +14,56 @@ import (
 	"github.com/templatr/templatr-setup/internal/install"
 	"github.com/templatr/templatr-setup/internal/logger"
 	"github.com/templatr/templatr-setup/internal/packages"
+	"github.com/templatr/templatr-setup/internal/secrets"
+	"github.com/templatr/templatr-setup/internal/term"
 )
 
 // phase tracks the current TUI state.
 type phase int
 
 const (
-	phaseSummary   phase = iota // Show plan summary
-	phaseConfirm                // Wait for user confirmation
-	phaseInstall                // Installing runtimes
-	phasePackages               // Installing packages
-	phaseConfigure              // Configure .env and config files
-	phaseComplete               // Done
+	phaseSummary         phase = iota // Show plan summary
+	phaseConfirm                      // Wait for user confirmation
+	phaseInstall                      // Installing runtimes
+	phasePackages                     // Installing packages
+	phaseConfigure                    // Configure .env and config files
+	phaseReview                       // Check the configuration before it is written
+	phasePostSetup                    // Running post-setup commands with the configured env
+	phasePostSetupFailed              // Retry or skip the post-setup commands that failed
+	phaseComplete                     // Done
 )
 
 // Custom messages for async operations.
 type (
 	runtimeResolvingMsg struct{ name string }
 	runtimeResolvedMsg  struct{ name, version string }
-	downloadProgressMsg struct{ downloaded, total int64 }
+	downloadProgressMsg struct {
+		phase string // the install.Phase* the stats are for
+		install.TransferStats
+	}
 	runtimeInstalledMsg struct {
 		name, version, installPath, binDir string
+		duration                           time.Duration
+		bytes                              int64
+		envChanges                         []install.EnvChange
 	}
 	runtimeFailedMsg struct{ err error }
 	installDoneMsg   struct {
 		results []install.InstallResult
 	}
-	packagesDoneMsg struct{ err error }
-	configDoneMsg   struct{ err error }
+	packagesDoneMsg  struct{ err error }
+	postSetupDoneMsg struct {
+		results packages.PostSetupResults
+		err     error
+	}
+	postSetupRetriedMsg struct {
+		index int // in the manifest's post_setup.commands
+		err   error
+	}
+	configDoneMsg struct {
+		err       error
+		unignored []config.IgnoreGap
+	}
 )
 
 // Model is the main Bubbletea model for the setup flow.
@@ -46,7 +71,9 @@ type Model struct {
 	phase       phase
 	plan        *engine.SetupPlan
 	log         *logger.Logger
+	opts        packages.SetupOptions // steps left to the user; toggled on the confirm screen
 	skipConfirm bool
+	checkIgnore bool // warn about secret env files git would pick up
 	width       int
 	height      int
 
@@ -55,39 +82,70 @@ type Model struct {
 	configureModel  configureModel
 	packagesSpinner spinner.Model
 	packagesRunning bool
+	packagesErr     error
+	postSetupDue    bool          // packages ran, so post-setup runs once configure is done
+	reviewCursor    int           // the configureModel field selected on the review
+	writingConfig   bool          // the reviewed configuration is being written
+	output          *outputTail   // package and post-setup command output
+	downloads       *liveProgress // progress of the runtime or download being installed
+	showOutput      bool          // the output pane is open; toggled with o
+	cursor          int           // the plan.Runtimes row selected on the summary; -1 if none can be toggled
+	logs            logPane       // the log while installing
+	logRecords      <-chan logger.Record
+	unsubscribe     func()
+
+	// Post-setup commands that failed or were not run, left to retry or skip
+	postSetupFailed   packages.PostSetupResults
+	postSetupSkipped  packages.PostSetupResults
+	postSetupCursor   int
+	postSetupRetrying bool
 
 	// Install state
 	installResults []install.InstallResult
+	ctx            context.Context    // cancelled by ctrl+c during phaseInstall
+	cancel         context.CancelFunc // stops the in-flight download or extract
+	confirmQuit    bool               // ctrl+c pressed, asking whether to stop the install
+	cancelling     bool               // stop confirmed, waiting for the install to stop
 
 	// Completion state
 	finalErr    error
 	logFilePath string
+	unignored   []config.IgnoreGap
 }
 
-// New creates a new TUI model.
-func New(plan *engine.SetupPlan, log *logger.Logger, skipConfirm bool) Model {
-	// Collect runtime names for progress model
-	var names, displayNames []string
-	for _, r := range plan.Runtimes {
-		if r.Action != engine.ActionSkip {
-			names = append(names, r.Name)
-			displayNames = append(displayNames, r.DisplayName)
-		}
+// New creates a new TUI model. The steps opts skips are listed on the
+// completion screen with the commands to run instead. When checkGitignore
+// is set, env files with secrets that git would not ignore are flagged on
+// the completion screen.
+func New(plan *engine.SetupPlan, log *logger.Logger, opts packages.SetupOptions, skipConfirm, checkGitignore bool) Model {
+	if term.Plain() {
+		usePlainStyles()
 	}
+	ps := newSpinner()
 
-	ps := spinner.New()
-	ps.Spinner = spinner.Dot
-	ps.Style = highlightStyle
+	ctx, cancel := context.WithCancel(context.Background())
+	records, unsubscribe := log.Subscribe()
 
 	m := Model{
+		ctx:             ctx,
+		cancel:          cancel,
 		plan:            plan,
 		log:             log,
+		opts:            opts,
 		skipConfirm:     skipConfirm,
-		progressModel:   newProgressModel(names, displayNames),
+		checkIgnore:     checkGitignore,
+		progressModel:   newProgressModel(progressRows(plan)),
 		configureModel:  newConfigureModel(plan.Manifest),
 		packagesSpinner: ps,
+		output:          newOutputTail(outputTailLines),
+		downloads:       newLiveProgress(),
+		showOutput:      true,
+		logs:            newLogPane(),
+		logRecords:      records,
+		unsubscribe:     unsubscribe,
 		logFilePath:     log.FilePath(),
 	}
+	m.cursor = m.nextToggle(-1, 1)
 
 	if !plan.NeedsAction() {
 		if len(m.configureModel.fields) > 0 {
@@ -108,10 +166,11 @@ func (m Model) Init() tea.Cmd {
 	cmds := []tea.Cmd{
 		m.progressModel.spinner.Tick,
 		m.packagesSpinner.Tick,
+		waitForLog(m.logRecords),
 	}
 
 	if m.phase == phaseInstall {
-		cmds = append(cmds, m.installRuntimeCmd(0))
+		cmds = append(cmds, m.recordUsesCmd(), m.installRuntimeCmd(0))
 	}
 
 	return tea.Batch(cmds...)
@@ -122,11 +181,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.logs.resize(msg.Width, msg.Height)
 		return m, nil
 
+	case logMsg:
+		m.logs.add(logger.Record(msg))
+		return m, waitForLog(m.logRecords)
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
+			// Ask before stopping the running install or package command,
+			// then wait for it to clean up; once it is stopping, another
+			// ctrl+c quits without waiting.
+			if m.working() && !m.cancelling {
+				if m.confirmQuit {
+					return m.stopWork(), nil
+				}
+				m.confirmQuit = true
+				return m, nil
+			}
+			m.cancel()
 			return m, tea.Quit
 		case "q":
 			if m.phase == phaseComplete || m.phase == phaseSummary || m.phase == phaseConfirm {
@@ -134,6 +209,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		if m.confirmQuit && m.working() {
+			switch msg.String() {
+			case "y", "Y":
+				return m.stopWork(), nil
+			case "n", "N", "esc":
+				m.confirmQuit = false
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "o":
+			if m.phase == phasePackages || m.phase == phasePostSetup || m.phase == phasePostSetupFailed ||
+				(m.phase == phaseComplete && (m.packagesErr != nil || len(m.postSetupSkipped) > 0)) {
+				m.showOutput = !m.showOutput
+				return m, nil
+			}
+		case "l":
+			if m.showsLog() {
+				m.logs.open = !m.logs.open
+				return m, nil
+			}
+		}
+
+		if m.showsLog() && m.logs.open && m.logs.scroll(msg.String()) {
+			return m, nil
+		}
+
+		if m.phase == phaseSummary || m.phase == phaseConfirm {
+			if next, ok := m.updateSelection(msg); ok {
+				return next, nil
+			}
+		}
+
 		switch m.phase {
 		case phaseSummary:
 			m.phase = phaseConfirm
@@ -142,27 +251,49 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case phaseConfirm:
 			switch msg.String() {
 			case "y", "Y":
-				m.phase = phaseInstall
-				return m, m.installRuntimeCmd(0)
+				return m.startInstall()
 			case "n", "N", "esc":
 				return m, tea.Quit
+			case "p":
+				if len(packages.PackageCommands(m.plan)) > 0 {
+					m.opts.SkipPackages = !m.opts.SkipPackages
+				}
+			case "c":
+				if len(packages.PostSetupCommands(m.plan.Manifest)) > 0 {
+					m.opts.SkipPostSetup = !m.opts.SkipPostSetup
+				}
+			case "e":
+				if len(m.actionRuntimes()) > 0 {
+					m.opts.NoPath = !m.opts.NoPath
+				}
 			}
 			return m, nil
 
 		case phaseConfigure:
 			if msg.String() == "esc" {
 				m.configureModel.skipped = true
-				m.phase = phaseComplete
-				return m, nil
+				return m.finishSetup()
 			}
 
 			var cmd tea.Cmd
 			m.configureModel, cmd = m.configureModel.Update(msg)
 			if m.configureModel.done {
-				return m, m.writeConfigCmd()
+				m.reviewCursor = m.configureModel.firstVisible()
+				if m.configureModel.revising {
+					m.configureModel.revising = false
+					m.reviewCursor = m.configureModel.focused
+				}
+				m.phase = phaseReview
+				return m, nil
 			}
 			return m, cmd
 
+		case phaseReview:
+			return m.updateReview(msg)
+
+		case phasePostSetupFailed:
+			return m.updatePostSetupFailed(msg)
+
 		case phaseComplete:
 			return m, tea.Quit
 		}
@@ -181,6 +312,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			Version:     msg.version,
 			InstallPath: msg.installPath,
 			BinDir:      msg.binDir,
+			Duration:    msg.duration,
+			Bytes:       msg.bytes,
+			EnvChanges:  msg.envChanges,
 		})
 		var cmd tea.Cmd
 		m.progressModel, cmd = m.progressModel.Update(msg)
@@ -201,28 +335,74 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case packagesDoneMsg:
 		m.packagesRunning = false
+		m.packagesErr = msg.err
+		if errors.Is(msg.err, install.ErrCancelled) {
+			m.finalErr = msg.err
+			m.phase = phaseComplete
+			return m, nil
+		}
 		if msg.err != nil {
 			m.log.Warn("Package install had issues: %s", msg.err)
 		}
+		m.postSetupDue = true
 		if len(m.configureModel.fields) > 0 {
 			m.phase = phaseConfigure
-		} else {
-			m.phase = phaseComplete
+			return m, nil
 		}
-		return m, nil
+		return m.finishSetup()
 
 	case configDoneMsg:
 		if msg.err != nil {
 			m.log.Warn("Config write failed: %s", msg.err)
 		}
+		m.unignored = msg.unignored
+		return m.finishSetup()
+
+	case postSetupDoneMsg:
+		m.packagesRunning = false
+		if errors.Is(msg.err, install.ErrCancelled) {
+			m.packagesErr = msg.err
+			m.finalErr = msg.err
+		} else if failed := msg.results.Failed(); len(failed) > 0 {
+			m.postSetupFailed = failed
+			m.postSetupCursor = 0
+			m.phase = phasePostSetupFailed
+			return m, nil
+		} else if msg.err != nil && m.packagesErr == nil {
+			m.packagesErr = msg.err
+		}
 		m.phase = phaseComplete
 		return m, nil
 
+	case postSetupRetriedMsg:
+		m.postSetupRetrying = false
+		if errors.Is(msg.err, install.ErrCancelled) {
+			m.packagesErr = msg.err
+			m.finalErr = msg.err
+			m.phase = phaseComplete
+			return m, nil
+		}
+		for i, r := range m.postSetupFailed {
+			if r.Index != msg.index {
+				continue
+			}
+			if msg.err == nil {
+				m.postSetupFailed = append(m.postSetupFailed[:i:i], m.postSetupFailed[i+1:]...)
+			} else {
+				m.postSetupFailed[i].Err = msg.err
+			}
+			break
+		}
+		return m.nextPostSetupFailure()
+
 	case spinner.TickMsg:
-		var cmd1, cmd2 tea.Cmd
+		var cmd1, cmd2, cmd3 tea.Cmd
 		m.progressModel.spinner, cmd1 = m.progressModel.spinner.Update(msg)
 		m.packagesSpinner, cmd2 = m.packagesSpinner.Update(msg)
-		return m, tea.Batch(cmd1, cmd2)
+		if progress, ok := m.downloads.take(); ok {
+			m.progressModel, cmd3 = m.progressModel.Update(progress)
+		}
+		return m, tea.Batch(cmd1, cmd2, cmd3)
 	}
 
 	// Forward progress model updates
@@ -241,42 +421,155 @@ func (m Model) View() string {
 
 	switch m.phase {
 	case phaseSummary:
-		b.WriteString(renderSummary(m.plan, width))
+		b.WriteString(renderSummary(m.plan, width, m.cursor))
 		b.WriteString("\n")
-		b.WriteString(mutedStyle.Render("Press any key to continue..."))
+		if m.cursor >= 0 {
+			b.WriteString(mutedStyle.Render("↑/↓ select a runtime, space to skip or install it, any other key to continue..."))
+		} else {
+			b.WriteString(mutedStyle.Render("Press any key to continue..."))
+		}
 
 	case phaseConfirm:
-		b.WriteString(renderSummary(m.plan, width))
+		b.WriteString(renderSummary(m.plan, width, m.cursor))
 		b.WriteString("\n")
+		b.WriteString(m.renderSetupOptions())
 		prompt := highlightStyle.Render("Proceed with installation? ") + boldStyle.Render("[y/n]")
 		b.WriteString(activeBoxStyle.Render(prompt))
+		if m.cursor >= 0 {
+			b.WriteString("\n")
+			b.WriteString(mutedStyle.Render("↑/↓ select a runtime, space to skip or install it"))
+		}
 
 	case phaseInstall:
 		b.WriteString(m.progressModel.View())
+		if m.cancelling {
+			b.WriteString("\n")
+			b.WriteString(warningStyle.Render("  Cancelling installation..."))
+			b.WriteString("\n")
+		}
+		b.WriteString(m.logs.View())
+		b.WriteString(m.renderConfirmQuit())
 
 	case phasePackages:
 		b.WriteString(m.progressModel.View())
 		b.WriteString("\n")
-		if m.packagesRunning {
+		if m.cancelling {
+			b.WriteString(warningStyle.Render("  Stopping package install..."))
+			b.WriteString("\n")
+		} else if m.packagesRunning {
 			b.WriteString(fmt.Sprintf("  %s Running package install...\n", m.packagesSpinner.View()))
+		} else if m.opts.SkipPackages {
+			b.WriteString(fmt.Sprintf("  %s Packages skipped\n", mutedStyle.Render(iconDot)))
 		} else {
 			b.WriteString(fmt.Sprintf("  %s Packages installed\n", successStyle.Render(iconCheck)))
 		}
+		b.WriteString(renderOutput(m.output, m.showOutput, width))
+		b.WriteString(m.logs.View())
+		b.WriteString(m.renderConfirmQuit())
 
 	case phaseConfigure:
 		b.WriteString(m.configureModel.View())
 
+	case phaseReview:
+		b.WriteString(m.configureModel.reviewView(m.reviewCursor))
+		if m.writingConfig {
+			b.WriteString(fmt.Sprintf("\n\n  %s Writing configuration...", m.packagesSpinner.View()))
+		}
+
+	case phasePostSetupFailed:
+		b.WriteString(m.renderPostSetupFailed())
+		b.WriteString(renderOutput(m.output, m.showOutput, width))
+		b.WriteString(m.renderConfirmQuit())
+
+	case phasePostSetup:
+		if m.cancelling {
+			b.WriteString(warningStyle.Render("  Stopping post-setup command..."))
+			b.WriteString("\n")
+		} else {
+			b.WriteString(fmt.Sprintf("  %s Running post-setup commands...\n", m.packagesSpinner.View()))
+		}
+		b.WriteString(renderOutput(m.output, m.showOutput, width))
+		b.WriteString(m.logs.View())
+		b.WriteString(m.renderConfirmQuit())
+
 	case phaseComplete:
-		b.WriteString(m.renderComplete())
+		b.WriteString(m.renderComplete(width))
 	}
 
 	return b.String()
 }
 
-func (m Model) renderComplete() string {
+// working reports whether an install, package or post-setup command is
+// running, so quitting would leave it half done.
+func (m Model) working() bool {
+	return m.phase == phaseInstall || m.phase == phasePackages || m.phase == phasePostSetup || m.postSetupRetrying
+}
+
+// stopWork cancels the running command. The installer removes what it had
+// half installed, and the runtimes already installed stay recorded; the
+// command's done message then brings up the cancelled completion screen.
+func (m Model) stopWork() Model {
+	m.confirmQuit = false
+	m.cancelling = true
+	m.cancel()
+	m.log.Warn("Installation cancelled by user")
+	return m
+}
+
+// renderConfirmQuit draws the prompt ctrl+c brings up while working.
+func (m Model) renderConfirmQuit() string {
+	if !m.confirmQuit || m.cancelling {
+		return ""
+	}
+	prompt := warningStyle.Render("Installation in progress — quit and clean up? ") + boldStyle.Render("[y/n]")
+	return "\n" + warningBoxStyle.Render(prompt) + "\n"
+}
+
+// showsLog reports whether the log pane is on screen: while runtimes,
+// packages and post-setup commands are running.
+func (m Model) showsLog() bool {
+	return m.phase == phaseInstall || m.phase == phasePackages || m.phase == phasePostSetup
+}
+
+// Close stops the log pane's subscription to the logger. Call it once the
+// program has exited.
+func (m Model) Close() {
+	m.unsubscribe()
+}
+
+// Succeeded returns true if the setup flow finished without an install error.
+func (m Model) Succeeded() bool {
+	return m.phase == phaseComplete && m.finalErr == nil
+}
+
+// Outcome returns the install results and error for history. started is
+// false if the user quit before the install phase began.
+func (m Model) Outcome() (results []install.InstallResult, started bool, err error) {
+	if m.phase == phaseSummary || m.phase == phaseConfirm {
+		return nil, false, nil
+	}
+	return m.installResults, true, m.finalErr
+}
+
+func (m Model) renderComplete(width int) string {
 	var b strings.Builder
 
-	if m.finalErr != nil {
+	if errors.Is(m.finalErr, install.ErrCancelled) {
+		b.WriteString(warningStyle.Render("Installation cancelled"))
+		b.WriteString("\n\n")
+		for _, r := range m.progressModel.runtimes {
+			if r.state == stateDone {
+				b.WriteString(fmt.Sprintf("  %s %s %s\n", successStyle.Render(iconCheck), boldStyle.Render(r.displayName), r.version))
+			} else {
+				b.WriteString(fmt.Sprintf("  %s %s\n", mutedStyle.Render(iconCross), mutedStyle.Render(r.displayName+" not installed")))
+			}
+		}
+		if len(m.progressModel.runtimes) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(mutedStyle.Render("  Partial downloads were removed. Run setup again to install the rest."))
+		b.WriteString("\n")
+	} else if m.finalErr != nil {
 		b.WriteString(errorStyle.Render("Installation failed"))
 		b.WriteString("\n\n")
 		b.WriteString(fmt.Sprintf("  %s %s\n", errorStyle.Render(iconCross), m.finalErr))
@@ -294,9 +587,24 @@ func (m Model) renderComplete() string {
 			))
 		}
 
+		if envSummary := install.SummarizeEnvChanges(m.installResults); len(envSummary.Changes) > 0 || len(envSummary.Manual) > 0 {
+			b.WriteString("\n")
+			b.WriteString(renderEnvChanges(envSummary))
+		}
+
 		if m.configureModel.done && !m.configureModel.skipped {
 			b.WriteString(fmt.Sprintf("\n  %s Configuration saved\n", successStyle.Render(iconCheck)))
 		}
+
+		if len(m.unignored) > 0 {
+			b.WriteString("\n")
+			b.WriteString(renderUnignored(m.unignored))
+		}
+
+		if steps := m.opts.ManualSteps(m.plan); len(steps) > 0 {
+			b.WriteString("\n")
+			b.WriteString(renderManualSteps(steps))
+		}
 	}
 
 	if m.plan.Manifest.PostSetup.Message != "" && m.finalErr == nil {
@@ -305,6 +613,20 @@ func (m Model) renderComplete() string {
 		b.WriteString("\n")
 	}
 
+	// Keep the output of a failed package install at hand
+	if m.packagesErr != nil {
+		b.WriteString(fmt.Sprintf("\n  %s %s\n", warningStyle.Render(iconWarning), m.packagesErr))
+	}
+	if len(m.postSetupSkipped) > 0 {
+		b.WriteString(fmt.Sprintf("\n  %s Skipped post-setup commands:\n", warningStyle.Render(iconWarning)))
+		for _, r := range m.postSetupSkipped {
+			b.WriteString(fmt.Sprintf("    %s %s\n", mutedStyle.Render(iconCross), r.Command.Run))
+		}
+	}
+	if m.packagesErr != nil || len(m.postSetupSkipped) > 0 {
+		b.WriteString(renderOutput(m.output, m.showOutput, width))
+	}
+
 	if m.logFilePath != "" {
 		b.WriteString(fmt.Sprintf("\n%s %s\n", mutedStyle.Render("Log file:"), mutedStyle.Render(m.logFilePath)))
 	}
@@ -315,22 +637,217 @@ func (m Model) renderComplete() string {
 	return b.String()
 }
 
+// renderEnvChanges lists the shell config and env var modifications made
+// during install and how to apply them to the current shell.
+func renderEnvChanges(s install.EnvSummary) string {
+	var b strings.Builder
+	b.WriteString(boldStyle.Render("Environment changes"))
+	b.WriteString("\n")
+	for _, line := range s.Lines() {
+		b.WriteString(fmt.Sprintf("  %s %s\n", mutedStyle.Render(iconDot), line))
+	}
+	if len(s.Changes) > 0 && !s.Modified() {
+		b.WriteString(fmt.Sprintf("  %s\n", successStyle.Render("No shell configuration changes were needed.")))
+	}
+	if len(s.Activation) > 0 {
+		b.WriteString(fmt.Sprintf("\n  %s\n", mutedStyle.Render(fmt.Sprintf("To use them in this terminal (%s), run:", s.Shell))))
+		for _, cmd := range s.Activation {
+			b.WriteString(fmt.Sprintf("    %s\n", highlightStyle.Render(cmd)))
+		}
+	}
+	if len(s.Manual) > 0 {
+		if len(s.Changes) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("  %s\n", mutedStyle.Render(fmt.Sprintf("PATH was left unchanged. Add these lines to %s:", s.ManualFile))))
+		for _, line := range s.Manual {
+			b.WriteString(fmt.Sprintf("    %s\n", highlightStyle.Render(line)))
+		}
+	}
+	return b.String()
+}
+
+// renderSetupOptions shows the steps that the confirm screen can leave
+// out, with the keys that toggle them.
+func (m Model) renderSetupOptions() string {
+	var b strings.Builder
+	option := func(key, label string, skip bool) {
+		box := successStyle.Render("[x]")
+		if skip {
+			box = mutedStyle.Render("[ ]")
+		}
+		b.WriteString(fmt.Sprintf("  %s %s %s\n", box, label, mutedStyle.Render("("+key+")")))
+	}
+	if len(m.actionRuntimes()) > 0 {
+		option("e", "Add runtimes to PATH", m.opts.NoPath)
+	}
+	if len(packages.PackageCommands(m.plan)) > 0 {
+		option("p", "Install packages", m.opts.SkipPackages)
+	}
+	if len(packages.PostSetupCommands(m.plan.Manifest)) > 0 {
+		option("c", "Run post-setup commands", m.opts.SkipPostSetup)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return b.String() + "\n"
+}
+
+// renderManualSteps lists the skipped setup steps with the commands to run
+// in their place.
+func renderManualSteps(steps []packages.ManualStep) string {
+	var b strings.Builder
+	b.WriteString(boldStyle.Render("Skipped steps"))
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("  %s\n", mutedStyle.Render("Run these yourself from the project directory:")))
+	for _, step := range steps {
+		b.WriteString(fmt.Sprintf("\n  %s\n", step.Name))
+		for _, cmd := range step.Commands {
+			b.WriteString(fmt.Sprintf("    %s\n", highlightStyle.Render(cmd)))
+		}
+	}
+	return b.String()
+}
+
+// renderUnignored warns about written env files with secrets that git would
+// pick up on the next `git add`.
+func renderUnignored(gaps []config.IgnoreGap) string {
+	var b strings.Builder
+	b.WriteString(warningStyle.Render(iconWarning + " These files contain secrets but are not ignored by git:"))
+	b.WriteString("\n")
+	for _, g := range gaps {
+		b.WriteString(fmt.Sprintf("\n  %s", boldStyle.Render(g.File)))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(mutedStyle.Render("Add them to .gitignore before committing."))
+	return warningBoxStyle.Render(b.String()) + "\n"
+}
+
 // --- Async commands ---
 
+// recordUsesCmd records the template as a user of runtimes it skips; see
+// install.RecordUses.
+func (m Model) recordUsesCmd() tea.Cmd {
+	plan, log := m.plan, m.log
+	return func() tea.Msg {
+		install.RecordUses(plan, log)
+		return nil
+	}
+}
+
+// progressRows returns the names and display names of the runtimes and
+// downloads the plan installs, one progress row each.
+func progressRows(plan *engine.SetupPlan) (names, displayNames []string) {
+	for _, r := range plan.InstallRuntimes() {
+		if r.Action != engine.ActionSkip {
+			names = append(names, r.Name)
+			displayNames = append(displayNames, r.DisplayName)
+		}
+	}
+	for _, d := range plan.Downloads {
+		if d.Action != engine.ActionSkip {
+			names = append(names, d.Name)
+			displayNames = append(displayNames, d.Name+" (download)")
+		}
+	}
+	return names, displayNames
+}
+
+// updateSelection handles the keys that move the cursor over the summary's
+// runtimes and toggle the selected one between its planned action and
+// skip. ok is false for any other key.
+func (m Model) updateSelection(msg tea.KeyMsg) (next Model, ok bool) {
+	if m.cursor < 0 {
+		return m, false
+	}
+	switch msg.String() {
+	case "up", "k":
+		if i := m.nextToggle(m.cursor, -1); i >= 0 {
+			m.cursor = i
+		}
+	case "down", "j":
+		if i := m.nextToggle(m.cursor, 1); i >= 0 {
+			m.cursor = i
+		}
+	case " ":
+		r := m.plan.Runtimes[m.cursor]
+		action := engine.ActionSkip
+		if r.Action == engine.ActionSkip {
+			action = r.PlannedAction
+		}
+		if err := m.plan.ApplyOverrides(map[string]engine.ActionType{r.Name: action}); err != nil {
+			m.log.Warn("Could not change %s: %s", r.DisplayName, err)
+		}
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+// nextToggle returns the index of the next runtime after from, stepping by
+// dir, that the user can toggle: one the plan installs or upgrades, or
+// that they chose to skip. It is -1 if there is none.
+func (m Model) nextToggle(from, dir int) int {
+	for i := from + dir; i >= 0 && i < len(m.plan.Runtimes); i += dir {
+		if r := m.plan.Runtimes[i]; r.Action != engine.ActionSkip || r.Overridden() {
+			return i
+		}
+	}
+	return -1
+}
+
+// startInstall starts installing the confirmed plan, with the progress rows
+// of what is left after any runtimes the user skipped. With nothing left
+// to install it moves straight on to the packages.
+func (m Model) startInstall() (tea.Model, tea.Cmd) {
+	spin := m.progressModel.spinner // already ticking
+	m.progressModel = newProgressModel(progressRows(m.plan))
+	m.progressModel.spinner = spin
+	if len(m.progressModel.runtimes) == 0 {
+		m.phase = phasePackages
+		m.packagesRunning = true
+		return m, tea.Batch(m.recordUsesCmd(), m.runPackagesCmd())
+	}
+	m.phase = phaseInstall
+	return m, tea.Batch(m.recordUsesCmd(), m.installRuntimeCmd(0))
+}
+
+// installRuntimeCmd installs the idx-th progress row: runtimes first, then downloads.
 func (m Model) installRuntimeCmd(idx int) tea.Cmd {
 	actionRuntimes := m.actionRuntimes()
+	log := m.log
+	slug := m.plan.Manifest.Template.Slug
+	ctx := m.ctx
+	progress := install.ThrottledEvents(m.downloads.set)
+	opts := m.opts.ExecuteOptions
+
 	if idx >= len(actionRuntimes) {
+		actionDownloads := m.actionDownloads()
+		if idx-len(actionRuntimes) >= len(actionDownloads) {
+			return func() tea.Msg {
+				return installDoneMsg{}
+			}
+		}
+
+		dp := actionDownloads[idx-len(actionRuntimes)]
 		return func() tea.Msg {
-			return installDoneMsg{}
+			result, err := install.InstallDownload(ctx, dp, slug, log, progress)
+			if err != nil {
+				return runtimeFailedMsg{err: err}
+			}
+			return runtimeInstalledMsg{
+				name:        result.Runtime,
+				installPath: result.InstallPath,
+				duration:    result.Duration,
+				bytes:       result.Bytes,
+			}
 		}
 	}
 
 	rp := actionRuntimes[idx]
-	log := m.log
-	slug := m.plan.Manifest.Template.Slug
 
 	return func() tea.Msg {
-		result, err := install.InstallSingleRuntime(rp, slug, log, nil)
+		result, err := install.InstallSingleRuntime(ctx, rp, slug, log, progress, opts)
 		if err != nil {
 			return runtimeFailedMsg{err: err}
 		}
@@ -340,54 +857,242 @@ func (m Model) installRuntimeCmd(idx int) tea.Cmd {
 			version:     result.Version,
 			installPath: result.InstallPath,
 			binDir:      result.BinDir,
+			duration:    result.Duration,
+			bytes:       result.Bytes,
+			envChanges:  result.EnvChanges,
 		}
 	}
 }
 
 func (m Model) runPackagesCmd() tea.Cmd {
-	mf := m.plan.Manifest
+	plan := m.plan
 	log := m.log
+	out := m.output
+	ctx := m.ctx
+	opts := m.opts
 
 	return func() tea.Msg {
-		if err := packages.RunGlobalInstalls(mf, log); err != nil {
-			log.Warn("Global install issues: %s", err)
+		if err := packages.EnsureManager(ctx, plan, log, out); err != nil {
+			if errors.Is(err, install.ErrCancelled) {
+				return packagesDoneMsg{err: err}
+			}
+			log.Warn("%s", err)
 		}
 
-		var err error
-		if mf.Packages.InstallCommand != "" {
-			log.Info("Running: %s", mf.Packages.InstallCommand)
-			err = packages.RunInstall(mf, log)
+		if opts.SkipPackages {
+			log.Info("Skipping package installation")
+			return packagesDoneMsg{}
 		}
 
-		if len(mf.PostSetup.Commands) > 0 {
-			log.Info("Running post-setup commands...")
-			if postErr := packages.RunPostSetup(mf, log); postErr != nil && err == nil {
-				err = postErr
+		if err := packages.RunGlobalInstalls(ctx, plan, log, out); err != nil {
+			if errors.Is(err, install.ErrCancelled) {
+				return packagesDoneMsg{err: err}
 			}
+			log.Warn("Global install issues: %s", err)
+		}
+
+		var err error
+		if plan.Packages != nil && len(plan.Packages.Steps) > 0 {
+			err = packages.RunInstall(ctx, plan, log, out)
 		}
 
 		return packagesDoneMsg{err: err}
 	}
 }
 
+// finishSetup runs the post-setup commands once packages and configure are
+// done, so they see the env files configure wrote, or ends the setup.
+func (m Model) finishSetup() (Model, tea.Cmd) {
+	if !m.postSetupDue || len(m.plan.Manifest.PostSetup.Commands) == 0 {
+		m.phase = phaseComplete
+		return m, nil
+	}
+	if m.opts.SkipPostSetup {
+		m.log.Info("Skipping post-setup commands")
+		m.phase = phaseComplete
+		return m, nil
+	}
+	m.postSetupDue = false
+	m.phase = phasePostSetup
+	m.packagesRunning = true
+	return m, m.runPostSetupCmd()
+}
+
+func (m Model) runPostSetupCmd() tea.Cmd {
+	mf := m.plan.Manifest
+	log := m.log
+	out := m.output
+	ctx := m.ctx
+
+	return func() tea.Msg {
+		log.Info("Running post-setup commands...")
+		results, err := packages.RunPostSetup(ctx, mf, config.ConfiguredEnv(mf), log, out)
+		return postSetupDoneMsg{results: results, err: err}
+	}
+}
+
+// updatePostSetupFailed handles the keys that retry or skip the selected
+// failed post-setup command, or skip all of them with enter.
+func (m Model) updatePostSetupFailed(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.postSetupRetrying {
+		return m, nil
+	}
+	switch msg.String() {
+	case "up", "k":
+		if m.postSetupCursor > 0 {
+			m.postSetupCursor--
+		}
+	case "down", "j":
+		if m.postSetupCursor < len(m.postSetupFailed)-1 {
+			m.postSetupCursor++
+		}
+	case "r":
+		m.postSetupRetrying = true
+		return m, m.retryPostSetupCmd(m.postSetupFailed[m.postSetupCursor].Index)
+	case "s":
+		i := m.postSetupCursor
+		m.postSetupSkipped = append(m.postSetupSkipped, m.postSetupFailed[i])
+		m.postSetupFailed = append(m.postSetupFailed[:i:i], m.postSetupFailed[i+1:]...)
+		return m.nextPostSetupFailure()
+	case "enter":
+		m.postSetupSkipped = append(m.postSetupSkipped, m.postSetupFailed...)
+		m.postSetupFailed = nil
+		return m.nextPostSetupFailure()
+	}
+	return m, nil
+}
+
+// nextPostSetupFailure keeps the cursor on a failed command, or ends the
+// setup once none is left.
+func (m Model) nextPostSetupFailure() (Model, tea.Cmd) {
+	if len(m.postSetupFailed) == 0 {
+		m.phase = phaseComplete
+		return m, nil
+	}
+	m.postSetupCursor = min(m.postSetupCursor, len(m.postSetupFailed)-1)
+	return m, nil
+}
+
+func (m Model) retryPostSetupCmd(index int) tea.Cmd {
+	mf := m.plan.Manifest
+	log := m.log
+	out := m.output
+	ctx := m.ctx
+
+	return func() tea.Msg {
+		log.Info("Retrying post-setup command: %s", mf.PostSetup.Commands[index].Run)
+		return postSetupRetriedMsg{index: index, err: packages.RunPostSetupCommand(ctx, mf, config.ConfiguredEnv(mf), index, log, out)}
+	}
+}
+
+func (m Model) renderPostSetupFailed() string {
+	var b strings.Builder
+	b.WriteString(warningStyle.Render("Some post-setup commands did not complete"))
+	b.WriteString("\n\n")
+
+	for i, r := range m.postSetupFailed {
+		cursor := "  "
+		name := r.Command.Run
+		if i == m.postSetupCursor {
+			cursor = highlightStyle.Render(iconArrow + " ")
+			name = boldStyle.Render(name)
+		}
+		status := errorStyle.Render(iconCross)
+		if r.NotRun() {
+			status = mutedStyle.Render(iconMissing)
+		}
+		b.WriteString(fmt.Sprintf("  %s%s %s\n", cursor, status, name))
+		if r.NotRun() {
+			b.WriteString(mutedStyle.Render("       not run: a required command before it failed"))
+		} else {
+			b.WriteString(mutedStyle.Render("       " + r.Err.Error()))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if m.cancelling {
+		b.WriteString(warningStyle.Render("  Stopping post-setup command..."))
+		b.WriteString("\n")
+	} else if m.postSetupRetrying {
+		b.WriteString(fmt.Sprintf("  %s Retrying %s...\n", m.packagesSpinner.View(), m.postSetupFailed[m.postSetupCursor].Command.Run))
+	} else {
+		b.WriteString(mutedStyle.Render("  r retry, s skip, ↑/↓ select, Enter to skip the rest and finish"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// updateReview handles a key on the configuration review: moving between
+// the fields, going back to change one, saving or skipping.
+func (m Model) updateReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.writingConfig {
+		return m, nil
+	}
+	switch msg.String() {
+	case "up", "k":
+		m.reviewCursor = m.configureModel.nextVisible(m.reviewCursor, -1)
+	case "down", "j":
+		m.reviewCursor = m.configureModel.nextVisible(m.reviewCursor, 1)
+	case "enter", "e":
+		m.phase = phaseConfigure
+		return m, m.configureModel.edit(m.reviewCursor)
+	case "y", "Y", "s":
+		m.writingConfig = true
+		return m, m.writeConfigCmd()
+	case "esc":
+		m.configureModel.skipped = true
+		return m.finishSetup()
+	}
+	return m, nil
+}
+
 func (m Model) writeConfigCmd() tea.Cmd {
 	mf := m.plan.Manifest
 	vals := m.configureModel.Values()
+	perEnv := m.configureModel.EnvironmentValues()
 	log := m.log
+	checkIgnore := m.checkIgnore
 
 	return func() tea.Msg {
-		// Write env files (grouped by target file)
+		// Write env files (grouped by target file, per environment)
 		envVals := make(map[string]string)
+		var unignored []config.IgnoreGap
 		for _, env := range mf.Env {
 			if v, ok := vals[env.Key]; ok {
 				envVals[env.Key] = v
 			}
 		}
-		if len(envVals) > 0 {
+		if len(envVals) > 0 || len(perEnv) > 0 {
+			fileVals, err := config.StoreSecrets(mf, envVals)
+			if errors.Is(err, secrets.ErrUnsupported) {
+				log.Warn("%s", err)
+			} else if err != nil {
+				return configDoneMsg{err: err}
+			}
 			log.Info("Writing env files...")
-			if err := config.WriteEnvFiles(mf.Env, envVals); err != nil {
+			written, err := config.WriteEnvironmentFiles(mf, fileVals, perEnv, "")
+			for _, file := range written {
+				log.Info("Wrote %s", file)
+			}
+			if err != nil {
 				return configDoneMsg{err: fmt.Errorf("failed to write env files: %w", err)}
 			}
+			if mf.EnvOptions.WriteExample {
+				written, err := config.WriteExampleFiles(mf)
+				for _, file := range written {
+					log.Info("Wrote %s", file)
+				}
+				if err != nil {
+					return configDoneMsg{err: fmt.Errorf("failed to write env example files: %w", err)}
+				}
+			}
+			if checkIgnore {
+				unignored = config.CheckGitignore(config.SecretEnvFiles(config.AllEnvDefs(mf)))
+				for _, g := range unignored {
+					log.Warn("%s is not git-ignored", g.File)
+				}
+			}
 		}
 
 		// Write config files
@@ -400,22 +1105,32 @@ func (m Model) writeConfigCmd() tea.Cmd {
 			}
 			if len(fieldVals) > 0 {
 				log.Info("Updating %s...", cfg.File)
-				if err := config.UpdateConfigFile(cfg.File, fieldVals); err != nil {
+				if err := config.UpdateConfigFile(cfg.File, cfg.Fields, fieldVals); err != nil {
 					log.Warn("Failed to update %s: %s", cfg.File, err)
 				}
 			}
 		}
 
-		return configDoneMsg{}
+		return configDoneMsg{unignored: unignored}
 	}
 }
 
 func (m Model) actionRuntimes() []engine.RuntimePlan {
 	var runtimes []engine.RuntimePlan
-	for _, r := range m.plan.Runtimes {
+	for _, r := range m.plan.InstallRuntimes() {
 		if r.Action != engine.ActionSkip {
 			runtimes = append(runtimes, r)
 		}
 	}
 	return runtimes
 }
+
+func (m Model) actionDownloads() []engine.DownloadPlan {
+	var downloads []engine.DownloadPlan
+	for _, d := range m.plan.Downloads {
+		if d.Action != engine.ActionSkip {
+			downloads = append(downloads, d)
+		}
+	}
+	return downloads
+}
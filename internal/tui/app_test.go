@@ -0,0 +1,763 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/logger"
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/packages"
+)
+
+func TestCtrlCDuringInstallCancels(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{
+			{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall},
+			{Name: "python", DisplayName: "Python", Action: engine.ActionInstall},
+		},
+	}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+	if m.phase != phaseInstall {
+		t.Fatalf("skipConfirm should start in the install phase, got %d", m.phase)
+	}
+	next, _ := m.Update(runtimeInstalledMsg{name: "node", version: "22.14.0"})
+	m = next.(Model)
+
+	key := func(k tea.KeyMsg) tea.Cmd {
+		t.Helper()
+		next, cmd := m.Update(k)
+		m = next.(Model)
+		return cmd
+	}
+
+	// ctrl+c asks first, and n carries on
+	if cmd := key(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd != nil || m.ctx.Err() != nil {
+		t.Fatal("ctrl+c during install should ask before stopping it")
+	}
+	if !strings.Contains(m.View(), "quit and clean up? [y/n]") {
+		t.Errorf("view should ask whether to stop the install:\n%s", m.View())
+	}
+	if key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")}); !m.logs.open {
+		t.Error("keys other than y and n should be ignored while asking")
+	}
+	key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if m.ctx.Err() != nil || strings.Contains(m.View(), "quit and clean up") {
+		t.Fatalf("n should dismiss the prompt and carry on:\n%s", m.View())
+	}
+
+	key(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd := key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}); cmd != nil {
+		t.Error("y should wait for the install to stop, not quit")
+	}
+	if m.ctx.Err() == nil {
+		t.Error("y should cancel the install context")
+	}
+	if !strings.Contains(m.View(), "Cancelling installation") {
+		t.Error("view should show that the install is being cancelled")
+	}
+
+	err := fmt.Errorf("failed to install Python 3.13.2: %w", install.ErrCancelled)
+	next, _ = m.Update(runtimeFailedMsg{err: err})
+	m = next.(Model)
+	if m.phase != phaseComplete {
+		t.Fatalf("phase = %d, want phaseComplete", m.phase)
+	}
+	view := m.View()
+	if !strings.Contains(view, "Installation cancelled") || strings.Contains(view, "Installation failed") {
+		t.Errorf("completion screen should report a cancellation, got:\n%s", view)
+	}
+	if !strings.Contains(view, "Node.js 22.14.0") || !strings.Contains(view, "Python not installed") {
+		t.Errorf("completion screen should say what was and wasn't installed, got:\n%s", view)
+	}
+	if results, started, _ := m.Outcome(); !started || len(results) != 1 || results[0].Runtime != "node" {
+		t.Errorf("Outcome() = %+v, %v, want node recorded", results, started)
+	}
+}
+
+func TestCtrlCTwiceStopsInstall(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall}},
+	}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	next, cmd := next.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	m = next.(Model)
+	if cmd != nil || m.ctx.Err() == nil || !m.cancelling {
+		t.Fatal("a second ctrl+c should confirm stopping the install")
+	}
+	// Once stopping, ctrl+c quits without waiting
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd == nil {
+		t.Error("ctrl+c while stopping should quit")
+	}
+}
+
+func TestPlainMode(t *testing.T) {
+	prev := lipgloss.ColorProfile()
+	t.Cleanup(func() { lipgloss.SetColorProfile(prev) })
+	t.Setenv("NO_COLOR", "1")
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall}},
+	}
+
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+	next, _ := m.Update(runtimeResolvingMsg{name: "node"})
+	m = next.(Model)
+	view := m.View()
+	if strings.Contains(view, "\x1b[") {
+		t.Errorf("NO_COLOR should leave the view unstyled:\n%q", view)
+	}
+	if !strings.Contains(view, "* Node.js") {
+		t.Errorf("NO_COLOR should replace the spinner with static text:\n%s", view)
+	}
+	next, _ = m.Update(m.progressModel.spinner.Tick())
+	if got := next.(Model).View(); got != view {
+		t.Errorf("a tick should not animate the plain spinner:\n%s", got)
+	}
+}
+
+func TestDownloadProgressShowsSpeed(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall}},
+	}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+	next, _ := m.Update(runtimeResolvedMsg{name: "node", version: "22.14.0"})
+	m = next.(Model)
+
+	// The install reports from its command; the next spinner tick shows it
+	m.downloads.set(install.ProgressEvent{Phase: install.PhaseDownloading}, install.TransferStats{Downloaded: 12 << 20, Total: 48 << 20, Percent: 25, Speed: 2 << 20, ETA: 18 * time.Second})
+	next, _ = m.Update(m.progressModel.spinner.Tick())
+	m = next.(Model)
+	if view := m.View(); !strings.Contains(view, "12.0 MB / 48.0 MB, 2.0 MB/s, 18s left") {
+		t.Errorf("view should show the download's speed and time left, got:\n%s", view)
+	}
+	if _, ok := m.downloads.take(); ok {
+		t.Error("the progress should be taken once")
+	}
+}
+
+func TestInstallPhasesShown(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall}},
+	}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+
+	tests := []struct {
+		ev    install.ProgressEvent
+		stats install.TransferStats
+		want  string
+	}{
+		{install.ProgressEvent{Phase: install.PhaseResolving}, install.TransferStats{}, "resolving version..."},
+		{install.ProgressEvent{Phase: install.PhaseVerifying}, install.TransferStats{}, "verifying"},
+		{install.ProgressEvent{Phase: install.PhaseExtracting}, install.TransferStats{Downloaded: 120, Total: 4310, Percent: 2.8}, "extracting ... 120 / 4310 files"},
+		{install.ProgressEvent{Phase: install.PhaseExtracting}, install.TransferStats{Downloaded: 75}, "extracting ... 75 files"},
+		{install.ProgressEvent{Phase: install.PhaseLinking}, install.TransferStats{}, "linking"},
+	}
+	for _, tt := range tests {
+		m.downloads.set(tt.ev, tt.stats)
+		next, _ := m.Update(m.progressModel.spinner.Tick())
+		m = next.(Model)
+		if view := m.View(); !strings.Contains(view, tt.want) {
+			t.Errorf("after a %s event the view should show %q, got:\n%s", tt.ev.Phase, tt.want, view)
+		}
+	}
+}
+
+func TestSummaryTogglesRuntimes(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{
+			{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall},
+			{Name: "java", DisplayName: "Java", InstalledVersion: "17.0.2", Action: engine.ActionUpgrade},
+			{Name: "go", DisplayName: "Go", InstalledVersion: "1.24.1", Action: engine.ActionSkip},
+		},
+	}
+	m := New(plan, logger.New(), packages.SetupOptions{}, false, false)
+	press := func(msg tea.KeyMsg) {
+		t.Helper()
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+
+	if m.cursor != 0 {
+		t.Fatalf("cursor = %d, want the first runtime to install", m.cursor)
+	}
+	// Go is already satisfied, so the cursor stops at Java
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	if m.cursor != 1 {
+		t.Fatalf("cursor = %d, want Java", m.cursor)
+	}
+	press(space)
+	if m.phase != phaseSummary || plan.Runtimes[1].Action != engine.ActionSkip {
+		t.Fatalf("space should skip Java and stay on the summary, got phase %d, %+v", m.phase, plan.Runtimes[1])
+	}
+	if view := m.View(); !strings.Contains(view, "Skip") || !strings.Contains(view, "1 to install, 1 skipped") {
+		t.Errorf("summary should show Java skipped, got:\n%s", view)
+	}
+
+	// Toggling works on the confirm prompt too, and undoes the skip
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	press(space)
+	if m.phase != phaseConfirm || plan.Runtimes[1].Action != engine.ActionUpgrade || plan.Runtimes[1].Overridden() {
+		t.Fatalf("space should put Java back to its upgrade, got phase %d, %+v", m.phase, plan.Runtimes[1])
+	}
+	press(space)
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = next.(Model)
+	if m.phase != phaseInstall || cmd == nil {
+		t.Fatalf("y should start the install, got phase %d", m.phase)
+	}
+	if rows := m.progressModel.runtimes; len(rows) != 1 || rows[0].name != "node" {
+		t.Errorf("progress rows = %+v, want only Node.js", rows)
+	}
+}
+
+func TestSummaryTogglesForcedRuntime(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{{Name: "go", DisplayName: "Go", InstalledVersion: "1.24.1", Action: engine.ActionSkip}},
+	}
+	plan.Force()
+	m := New(plan, logger.New(), packages.SetupOptions{}, false, false)
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+
+	next, _ := m.Update(space)
+	m = next.(Model)
+	if r := plan.Runtimes[0]; r.Action != engine.ActionSkip || r.Forced() {
+		t.Fatalf("space should skip the reinstall, got %+v", r)
+	}
+	if view := m.View(); !strings.Contains(view, "Skip") || strings.Contains(view, "Reinstall") {
+		t.Errorf("summary should show Go skipped, got:\n%s", view)
+	}
+	if m.nextToggle(-1, 1) != 0 {
+		t.Error("a skipped reinstall should still be selectable")
+	}
+
+	next, _ = m.Update(space)
+	m = next.(Model)
+	if r := plan.Runtimes[0]; !r.Forced() || r.Overridden() {
+		t.Fatalf("space should turn the reinstall back on, got %+v", r)
+	}
+	if view := m.View(); !strings.Contains(view, "Reinstall") {
+		t.Errorf("summary should show the reinstall again, got:\n%s", view)
+	}
+}
+
+func TestSummarySkipAll(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall}},
+	}
+	m := New(plan, logger.New(), packages.SetupOptions{}, false, false)
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if m = next.(Model); m.phase != phasePackages {
+		t.Errorf("with every runtime skipped, y should go straight to the packages, got phase %d", m.phase)
+	}
+}
+
+func TestPackagesOutputPane(t *testing.T) {
+	plan := &engine.SetupPlan{Manifest: &manifest.Manifest{}}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phasePackages
+	m.packagesRunning = true
+
+	for i := 1; i <= outputTailLines+3; i++ {
+		fmt.Fprintf(m.output, "line %d\n", i)
+	}
+	view := m.View()
+	if strings.Contains(view, "line 3") || !strings.Contains(view, fmt.Sprintf("line %d", outputTailLines+3)) {
+		t.Errorf("pane should show only the last %d lines:\n%s", outputTailLines, view)
+	}
+	if !strings.Contains(view, "3 earlier lines") {
+		t.Errorf("pane should say how many lines it dropped:\n%s", view)
+	}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	m = next.(Model)
+	if view := m.View(); strings.Contains(view, "line 15") || !strings.Contains(view, "Press o to show command output (15 lines)") {
+		t.Errorf("o should collapse the pane:\n%s", view)
+	}
+
+	// A failed package install keeps the output on the completion screen
+	next, _ = m.Update(packagesDoneMsg{err: fmt.Errorf("package install failed: exit status 1")})
+	m = next.(Model)
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	m = next.(Model)
+	if cmd != nil || !strings.Contains(m.View(), "line 15") {
+		t.Errorf("o on the completion screen should open the pane rather than quit:\n%s", m.View())
+	}
+}
+
+func TestLogPane(t *testing.T) {
+	plan := &engine.SetupPlan{
+		Manifest: &manifest.Manifest{},
+		Runtimes: []engine.RuntimePlan{{Name: "node", DisplayName: "Node.js", Action: engine.ActionInstall}},
+	}
+	log := logger.New()
+	m := New(plan, log, packages.SetupOptions{}, true, false)
+	defer m.Close()
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 18})
+	m = next.(Model)
+
+	// receive passes the next published entry to the model, as the
+	// program would
+	receive := func() {
+		t.Helper()
+		msg := waitForLog(m.logRecords)()
+		if msg == nil {
+			t.Fatal("subscription closed early")
+		}
+		next, cmd := m.Update(msg)
+		m = next.(Model)
+		if cmd == nil {
+			t.Fatal("the model should wait for the next entry")
+		}
+	}
+	for i := 1; i <= 10; i++ {
+		log.Info("log line %d", i)
+		receive()
+	}
+	log.Output(io.Discard).Write([]byte("npm output\n"))
+	receive()
+	log.Warn("disk nearly full")
+	receive()
+
+	// 18 rows leave room for 6 lines, following the newest
+	view := m.View()
+	if strings.Contains(view, "log line 5") || !strings.Contains(view, "log line 6") || !strings.Contains(view, "WARN: disk nearly full") {
+		t.Errorf("pane should show the last 6 lines:\n%s", view)
+	}
+	if strings.Contains(view, "npm output") {
+		t.Errorf("command output belongs in the output pane:\n%s", view)
+	}
+
+	key := func(k tea.KeyMsg) {
+		t.Helper()
+		next, _ := m.Update(k)
+		m = next.(Model)
+	}
+	key(tea.KeyMsg{Type: tea.KeyUp})
+	key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if view := m.View(); !strings.Contains(view, "log line 4") || strings.Contains(view, "log line 10") || !strings.Contains(view, "end to follow") {
+		t.Errorf("scrolling up 2 lines should show lines 4 to 9:\n%s", view)
+	}
+	// New lines don't move a pane scrolled back
+	log.Info("log line 12")
+	receive()
+	if view := m.View(); !strings.Contains(view, "log line 4") || strings.Contains(view, "log line 12") {
+		t.Errorf("pane should stay scrolled back:\n%s", view)
+	}
+	key(tea.KeyMsg{Type: tea.KeyEnd})
+	if view := m.View(); !strings.Contains(view, "log line 12") || strings.Contains(view, "log line 6") {
+		t.Errorf("end should return to the newest lines:\n%s", view)
+	}
+
+	// A taller terminal shows more lines
+	next, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = next.(Model)
+	if view := m.View(); !strings.Contains(view, "log line 5") || strings.Contains(view, "log line 4") {
+		t.Errorf("pane should grow to %d lines:\n%s", logPaneHeight, view)
+	}
+
+	key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	if view := m.View(); strings.Contains(view, "log line 12") || !strings.Contains(view, "Press l to show the log (12 lines)") {
+		t.Errorf("l should collapse the pane:\n%s", view)
+	}
+
+	m.Close()
+	if msg := waitForLog(m.logRecords)(); msg != nil {
+		t.Errorf("closed subscription sent %v", msg)
+	}
+}
+
+func TestCtrlCDuringPackagesCancels(t *testing.T) {
+	plan := &engine.SetupPlan{Manifest: &manifest.Manifest{}}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phasePackages
+	m.packagesRunning = true
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	next, cmd := next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = next.(Model)
+	if cmd != nil || m.ctx.Err() == nil {
+		t.Fatal("ctrl+c during packages should stop the command and wait for it")
+	}
+	if !strings.Contains(m.View(), "Stopping package install") {
+		t.Errorf("view should show the command being stopped:\n%s", m.View())
+	}
+
+	err := fmt.Errorf("package install failed: %q was stopped: %w", "npm install", install.ErrCancelled)
+	next, _ = m.Update(packagesDoneMsg{err: err})
+	m = next.(Model)
+	if m.phase != phaseComplete || !strings.Contains(m.View(), "cancelled") {
+		t.Errorf("a cancelled package install should end the setup, got phase %d:\n%s", m.phase, m.View())
+	}
+}
+
+func TestPostSetupRunsAfterConfigure(t *testing.T) {
+	mf := &manifest.Manifest{Env: []manifest.EnvVar{{Key: "DATABASE_URL", Label: "Database URL"}}}
+	mf.PostSetup.Commands = []manifest.Command{{Run: "npx prisma migrate dev"}}
+	m := New(&engine.SetupPlan{Manifest: mf}, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phasePackages
+	m.packagesRunning = true
+
+	next, cmd := m.Update(packagesDoneMsg{})
+	m = next.(Model)
+	if m.phase != phaseConfigure || cmd != nil {
+		t.Fatalf("phase = %d, want configure before post-setup runs", m.phase)
+	}
+
+	next, cmd = m.Update(configDoneMsg{})
+	m = next.(Model)
+	if m.phase != phasePostSetup || cmd == nil {
+		t.Fatalf("phase = %d, want post-setup started once configure is done", m.phase)
+	}
+	if !strings.Contains(m.View(), "Running post-setup commands") {
+		t.Errorf("view should show post-setup running:\n%s", m.View())
+	}
+
+	next, _ = m.Update(postSetupDoneMsg{err: fmt.Errorf("post-setup command %q failed: exit status 1", "npx prisma migrate dev")})
+	m = next.(Model)
+	if m.phase != phaseComplete || !strings.Contains(m.View(), "prisma") {
+		t.Errorf("a failed post-setup should be reported on the completion screen:\n%s", m.View())
+	}
+}
+
+func TestPostSetupWithoutConfigure(t *testing.T) {
+	mf := &manifest.Manifest{}
+	mf.PostSetup.Commands = []manifest.Command{{Run: "make build"}}
+	m := New(&engine.SetupPlan{Manifest: mf}, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phasePackages
+
+	next, cmd := m.Update(packagesDoneMsg{})
+	m = next.(Model)
+	if m.phase != phasePostSetup || cmd == nil {
+		t.Fatalf("phase = %d, want post-setup straight after packages", m.phase)
+	}
+
+	// ctrl+c and y stop the command rather than quitting
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	next, cmd = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = next.(Model)
+	if cmd != nil || m.ctx.Err() == nil || !strings.Contains(m.View(), "Stopping post-setup") {
+		t.Errorf("ctrl+c during post-setup should stop the command and wait:\n%s", m.View())
+	}
+	next, _ = m.Update(postSetupDoneMsg{err: fmt.Errorf("post-setup failed: %q was stopped: %w", "make build", install.ErrCancelled)})
+	m = next.(Model)
+	if m.phase != phaseComplete || !strings.Contains(m.View(), "cancelled") {
+		t.Errorf("a cancelled post-setup should end the setup:\n%s", m.View())
+	}
+}
+
+func TestPostSetupFailedRetryAndSkip(t *testing.T) {
+	mf := &manifest.Manifest{}
+	mf.PostSetup.Commands = []manifest.Command{{Run: "npm run db:seed"}, {Run: "npm run build"}, {Run: "make docs"}}
+	m := New(&engine.SetupPlan{Manifest: mf}, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phasePostSetup
+
+	results := packages.PostSetupResults{
+		{Index: 0, Command: mf.PostSetup.Commands[0], Err: fmt.Errorf("post-setup command %q failed: exit status 1", "npm run db:seed")},
+		{Index: 1, Command: mf.PostSetup.Commands[1]},
+		{Index: 2, Command: mf.PostSetup.Commands[2], Err: fmt.Errorf("post-setup command %q failed: exit status 2", "make docs")},
+	}
+	next, _ := m.Update(postSetupDoneMsg{results: results, err: results.Err()})
+	m = next.(Model)
+	if m.phase != phasePostSetupFailed {
+		t.Fatalf("phase = %d, want the failed commands listed", m.phase)
+	}
+	if view := m.View(); !strings.Contains(view, "npm run db:seed") || !strings.Contains(view, "make docs") || strings.Contains(view, "npm run build") {
+		t.Errorf("view should list only the failed commands:\n%s", view)
+	}
+
+	// r retries the selected command; a failure keeps it listed
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(Model)
+	if cmd == nil || !m.postSetupRetrying || !strings.Contains(m.View(), "Retrying npm run db:seed") {
+		t.Fatalf("r should retry the selected command:\n%s", m.View())
+	}
+	next, _ = m.Update(postSetupRetriedMsg{index: 0, err: fmt.Errorf("post-setup command %q failed: exit status 7", "npm run db:seed")})
+	m = next.(Model)
+	if m.phase != phasePostSetupFailed || len(m.postSetupFailed) != 2 || !strings.Contains(m.View(), "exit status 7") {
+		t.Fatalf("a failed retry should stay listed with its new error:\n%s", m.View())
+	}
+
+	// A successful retry drops it from the list
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(Model)
+	next, _ = m.Update(postSetupRetriedMsg{index: 0})
+	m = next.(Model)
+	if len(m.postSetupFailed) != 1 || m.postSetupFailed[0].Index != 2 {
+		t.Fatalf("failed = %+v, want only make docs left", m.postSetupFailed)
+	}
+
+	// s skips the last one and finishes
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	m = next.(Model)
+	if m.phase != phaseComplete {
+		t.Fatalf("phase = %d, want complete once nothing is left", m.phase)
+	}
+	if view := m.View(); !strings.Contains(view, "Skipped post-setup commands") || !strings.Contains(view, "make docs") || strings.Contains(view, "db:seed") {
+		t.Errorf("completion screen should list the skipped command only:\n%s", view)
+	}
+}
+
+func TestPostSetupFailedNotRun(t *testing.T) {
+	mf := &manifest.Manifest{}
+	mf.PostSetup.Commands = []manifest.Command{{Run: "npx prisma migrate dev", Required: true}, {Run: "npm run db:seed"}}
+	m := New(&engine.SetupPlan{Manifest: mf}, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phasePostSetup
+
+	results := packages.PostSetupResults{
+		{Index: 0, Command: mf.PostSetup.Commands[0], Err: fmt.Errorf("post-setup command %q failed: exit status 1", "npx prisma migrate dev")},
+		{Index: 1, Command: mf.PostSetup.Commands[1], Err: fmt.Errorf("post-setup command %q %w", "npm run db:seed", packages.ErrNotRun)},
+	}
+	next, _ := m.Update(postSetupDoneMsg{results: results, err: results.Err()})
+	m = next.(Model)
+	if view := m.View(); !strings.Contains(view, "not run: a required command before it failed") {
+		t.Errorf("view should mark the command that was not run:\n%s", view)
+	}
+
+	// enter skips everything left
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	if m.phase != phaseComplete || len(m.postSetupSkipped) != 2 {
+		t.Errorf("enter should skip the rest and finish, got phase %d, skipped %d", m.phase, len(m.postSetupSkipped))
+	}
+}
+
+func TestWriteConfigMultipleEnvFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mf := &manifest.Manifest{Env: []manifest.EnvVar{
+		{Key: "DATABASE_URL", Label: "Database URL"},
+		{Key: "NEXT_PUBLIC_API_URL", Label: "API URL", File: "apps/web/.env.local"},
+		{Key: "SESSION_SECRET", Label: "Session Secret"},
+	}}
+	m := New(&engine.SetupPlan{Manifest: mf}, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phaseConfigure
+
+	view := m.View()
+	for _, header := range []string{"Environment Variables (.env)", "Environment Variables (apps/web/.env.local)"} {
+		if !strings.Contains(view, header) {
+			t.Errorf("view should have a %q section:\n%s", header, view)
+		}
+	}
+
+	values := map[string]string{
+		"DATABASE_URL":        "postgres://localhost/app",
+		"NEXT_PUBLIC_API_URL": "http://localhost:4000",
+		"SESSION_SECRET":      "s3cret",
+	}
+	for i := range m.configureModel.fields {
+		f := &m.configureModel.fields[i]
+		f.input.SetValue(values[f.key])
+	}
+
+	msg := m.writeConfigCmd()()
+	if done := msg.(configDoneMsg); done.err != nil {
+		t.Fatalf("writeConfigCmd failed: %s", done.err)
+	}
+
+	root, err := os.ReadFile(".env")
+	if err != nil {
+		t.Fatalf("expected .env to be written: %s", err)
+	}
+	web, err := os.ReadFile(filepath.Join("apps", "web", ".env.local"))
+	if err != nil {
+		t.Fatalf("expected apps/web/.env.local to be written: %s", err)
+	}
+	for _, want := range []string{"DATABASE_URL=postgres://localhost/app", "SESSION_SECRET=s3cret"} {
+		if !strings.Contains(string(root), want) {
+			t.Errorf(".env missing %q:\n%s", want, root)
+		}
+	}
+	if strings.Contains(string(root), "NEXT_PUBLIC_API_URL") {
+		t.Errorf(".env should not have apps/web's key:\n%s", root)
+	}
+	if !strings.Contains(string(web), "NEXT_PUBLIC_API_URL=http://localhost:4000") || strings.Contains(string(web), "DATABASE_URL") {
+		t.Errorf("apps/web/.env.local should have only its own key:\n%s", web)
+	}
+}
+
+func TestConfigReview(t *testing.T) {
+	t.Chdir(t.TempDir())
+	mf := &manifest.Manifest{Env: []manifest.EnvVar{
+		{Key: "DATABASE_URL", Label: "Database URL"},
+		{Key: "SESSION_SECRET", Label: "Session Secret", Type: "secret"},
+		{Key: "NEXT_PUBLIC_API_URL", Label: "API URL", File: "apps/web/.env.local"},
+	}}
+	m := New(&engine.SetupPlan{Manifest: mf}, logger.New(), packages.SetupOptions{}, true, false)
+	m.phase = phaseConfigure
+
+	key := func(k tea.KeyMsg) tea.Cmd {
+		t.Helper()
+		next, cmd := m.Update(k)
+		m = next.(Model)
+		return cmd
+	}
+	typeText := func(s string) {
+		t.Helper()
+		key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+	}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	typeText("postgres://localhost/app")
+	key(enter)
+	typeText("s3cret")
+	key(enter)
+	typeText("http://localhost:4000")
+	if cmd := key(enter); cmd != nil || m.phase != phaseReview {
+		t.Fatalf("phase = %d, want the review before anything is written", m.phase)
+	}
+	view := m.View()
+	for _, want := range []string{"Environment Variables (.env)", "postgres://localhost/app", "••••", "Environment Variables (apps/web/.env.local)", "http://localhost:4000"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("review should show %q:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "s3cret") {
+		t.Errorf("review should mask secrets:\n%s", view)
+	}
+
+	// Change the API URL from the review
+	key(tea.KeyMsg{Type: tea.KeyDown})
+	key(tea.KeyMsg{Type: tea.KeyDown})
+	key(enter)
+	if m.phase != phaseConfigure || m.configureModel.fields[m.configureModel.focused].key != "NEXT_PUBLIC_API_URL" {
+		t.Fatalf("enter should edit the selected field, got phase %d on field %d", m.phase, m.configureModel.focused)
+	}
+	for range len("4000") {
+		key(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	typeText("5000")
+	key(enter)
+	if m.phase != phaseReview || !strings.Contains(m.View(), "http://localhost:5000") {
+		t.Fatalf("enter should return to the review with the new value:\n%s", m.View())
+	}
+	if m.configureModel.fields[m.reviewCursor].key != "NEXT_PUBLIC_API_URL" {
+		t.Errorf("review cursor = %d, want it on the field just changed", m.reviewCursor)
+	}
+
+	// Changing a field that isn't last also returns to the review
+	key(tea.KeyMsg{Type: tea.KeyUp})
+	key(tea.KeyMsg{Type: tea.KeyUp})
+	key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	if m.configureModel.fields[m.configureModel.focused].key != "DATABASE_URL" {
+		t.Fatalf("e should edit the selected field, got field %d", m.configureModel.focused)
+	}
+	if key(enter); m.phase != phaseReview {
+		t.Fatalf("phase = %d, want the review after changing the first field", m.phase)
+	}
+
+	cmd := key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if cmd == nil {
+		t.Fatal("y should write the configuration")
+	}
+	if done := cmd().(configDoneMsg); done.err != nil {
+		t.Fatalf("writing the configuration failed: %s", done.err)
+	}
+	root, _ := os.ReadFile(".env")
+	web, _ := os.ReadFile(filepath.Join("apps", "web", ".env.local"))
+	for _, want := range []string{"DATABASE_URL=postgres://localhost/app", "SESSION_SECRET=s3cret"} {
+		if !strings.Contains(string(root), want) {
+			t.Errorf(".env missing %q:\n%s", want, root)
+		}
+	}
+	if !strings.Contains(string(web), "NEXT_PUBLIC_API_URL=http://localhost:5000") {
+		t.Errorf("apps/web/.env.local should have the reviewed API URL:\n%s", web)
+	}
+}
+
+func TestSkipPackagesAndPostSetup(t *testing.T) {
+	dir := t.TempDir()
+	mf := &manifest.Manifest{Dir: dir}
+	mf.PostSetup.Commands = []manifest.Command{{Run: "echo ran> post.txt"}}
+	plan := &engine.SetupPlan{Manifest: mf, Packages: &engine.PackagePlan{
+		ManagerFound: true,
+		Steps:        []engine.InstallStep{{Command: "echo ran> install.txt"}},
+	}}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+
+	// p and c on the confirm screen toggle the two steps
+	m.phase = phaseConfirm
+	for _, key := range []string{"p", "c"} {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = next.(Model)
+	}
+	if !m.opts.SkipPackages || !m.opts.SkipPostSetup {
+		t.Fatalf("opts = %+v, want both steps skipped", m.opts)
+	}
+	if view := m.View(); !strings.Contains(view, "[ ] Install packages") || !strings.Contains(view, "[ ] Run post-setup commands") {
+		t.Errorf("confirm screen should show the steps unchecked:\n%s", view)
+	}
+
+	m.phase = phasePackages
+	m.packagesRunning = true
+	next, cmd := m.Update(m.runPackagesCmd()())
+	m = next.(Model)
+	if m.phase != phaseComplete || cmd != nil {
+		t.Fatalf("phase = %d, want the setup complete without running post-setup", m.phase)
+	}
+	for _, file := range []string{"install.txt", "post.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			t.Errorf("%s was written, want the skipped command not run", file)
+		}
+	}
+	view := m.View()
+	for _, want := range []string{"Skipped steps", "echo ran> install.txt", "echo ran> post.txt"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("completion screen should list %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestNoPath(t *testing.T) {
+	plan := &engine.SetupPlan{Manifest: &manifest.Manifest{}, Runtimes: []engine.RuntimePlan{
+		{Name: "java", DisplayName: "Java", RequiredVersion: "21", Action: engine.ActionInstall},
+	}}
+	m := New(plan, logger.New(), packages.SetupOptions{}, true, false)
+
+	m.phase = phaseConfirm
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m = next.(Model)
+	if !m.opts.NoPath {
+		t.Fatalf("opts = %+v, want NoPath", m.opts)
+	}
+	if view := m.View(); !strings.Contains(view, "[ ] Add runtimes to PATH") {
+		t.Errorf("confirm screen should show PATH unchecked:\n%s", view)
+	}
+
+	view := renderEnvChanges(install.EnvSummary{
+		Shell:      "zsh",
+		Manual:     []string{`export PATH="/home/dev/.templatr/runtimes/java/current/bin:$PATH"`, `export JAVA_HOME="/home/dev/.templatr/runtimes/java/current"`},
+		ManualFile: "~/.zshrc",
+	})
+	for _, want := range []string{"Add these lines to ~/.zshrc", `export JAVA_HOME="/home/dev/.templatr/runtimes/java/current"`} {
+		if !strings.Contains(view, want) {
+			t.Errorf("completion screen should show %q:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "No shell configuration changes were needed") {
+		t.Errorf("nothing was checked, so nothing should be reported as already set:\n%s", view)
+	}
+}
@@ -2,10 +2,13 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/templatr/templatr-setup/internal/browser"
 	"github.com/templatr/templatr-setup/internal/config"
 	"github.com/templatr/templatr-setup/internal/manifest"
 )
@@ -15,25 +18,62 @@ type configField struct {
 	key         string // env key or config path
 	label       string
 	description string
-	fieldType   string // text, url, email, secret, number, boolean
+	docsURL     string // where to get the value, from docs_url
+	fieldType   string // text, url, email, secret, number, boolean, select
 	required    bool
+	rules       config.FieldRules
+	when        string // condition from the manifest; the field is hidden while it doesn't hold
+	hidden      bool
+	environment string // env environment for per-environment vars, else empty
 	section     string // "env" or config file label
+	file        string // the config file a config field is written to; empty for env vars
 	input       textinput.Model
+	options     []string // choices for select fields
+	selected    int      // index into options; the input holds options[selected]
+	err         string   // validation error from the last submit, cleared on edit
+}
+
+// value returns the entered value, or the default when nothing was entered.
+func (f configField) value() string {
+	if v := f.input.Value(); v != "" {
+		return v
+	}
+	return f.input.Placeholder
+}
+
+// choose selects option i of a select field, wrapping around at either end.
+func (f *configField) choose(i int) {
+	f.selected = (i + len(f.options)) % len(f.options)
+	f.input.SetValue(f.options[f.selected])
+	f.err = ""
+}
+
+// isSelect reports whether the field is a select with options to cycle through.
+func (f configField) isSelect() bool {
+	return f.fieldType == "select" && len(f.options) > 0
 }
 
 // configureModel manages the configure form.
 type configureModel struct {
-	fields  []configField
-	focused int
-	done    bool
-	skipped bool
+	fields   []configField
+	focused  int
+	done     bool
+	skipped  bool
+	revising bool // a field is being changed from the review; enter goes back to it
+
+	// docsNotice is a docs URL that could not be opened in a browser
+	// (e.g. over SSH) and is shown prominently instead.
+	docsNotice string
 }
 
 func newConfigureModel(m *manifest.Manifest) configureModel {
 	var fields []configField
 
-	// .env fields (grouped by target file)
-	for _, env := range m.Env {
+	// .env fields (grouped by target file, or by environment when
+	// [env_environments] is declared), pre-filled from the files on disk
+	existing := config.ReadExistingEnv(m)
+	for _, p := range config.EnvPrompts(m, "") {
+		env := p.Var
 		ti := textinput.New()
 		ti.Placeholder = env.Default
 		ti.CharLimit = 256
@@ -43,20 +83,26 @@ func newConfigureModel(m *manifest.Manifest) configureModel {
 			ti.EchoMode = textinput.EchoPassword
 		}
 
-		// Pre-fill with default if set
-		if env.Default != "" {
+		// Pre-fill with the current value, or the default if set
+		if v := existing.Value(p); v != "" {
+			ti.SetValue(v)
+		} else if env.Default != "" {
 			ti.SetValue(env.Default)
 		}
 
-		target := config.EnvFileTarget(env)
 		fields = append(fields, configField{
 			key:         env.Key,
 			label:       env.Label,
 			description: env.Description,
+			docsURL:     env.DocsURL,
 			fieldType:   env.Type,
 			required:    env.Required,
-			section:     fmt.Sprintf("Environment Variables (%s)", target),
+			rules:       config.EnvRules(env),
+			when:        env.When,
+			environment: p.Environment,
+			section:     fmt.Sprintf("Environment Variables (%s)", p.Section),
 			input:       ti,
+			options:     env.Options,
 		})
 	}
 
@@ -77,58 +123,261 @@ func newConfigureModel(m *manifest.Manifest) configureModel {
 				label:       f.Label,
 				description: f.Description,
 				fieldType:   f.Type,
+				rules:       config.ConfigRules(f),
+				when:        f.When,
 				section:     cfg.Label,
+				file:        cfg.File,
 				input:       ti,
+				options:     f.Options,
 			})
 		}
 	}
 
-	// Focus the first field
-	if len(fields) > 0 {
-		fields[0].input.Focus()
+	// Select fields start on their current value, their default, or the
+	// first option
+	for i := range fields {
+		f := &fields[i]
+		if f.isSelect() {
+			idx := slices.Index(f.options, f.input.Value())
+			if idx < 0 {
+				idx = max(slices.Index(f.options, f.input.Placeholder), 0)
+			}
+			f.choose(idx)
+		}
 	}
 
-	return configureModel{
+	model := configureModel{
 		fields: fields,
 	}
+	model.refreshVisibility()
+
+	// Focus the first field that is shown
+	if len(fields) > 0 {
+		if fields[0].hidden {
+			model.focused = model.nextVisible(0, 1)
+		}
+		fields[model.focused].input.Focus()
+	}
+
+	return model
+}
+
+// refreshVisibility hides the fields whose when condition doesn't hold for
+// the values shown before them. A hidden field's value counts as empty, so
+// fields that depend on it are hidden too.
+func (m *configureModel) refreshVisibility() {
+	shared := make(map[string]string)
+	perEnv := make(map[string]map[string]string)
+	for i := range m.fields {
+		f := &m.fields[i]
+		values := shared
+		if f.environment != "" {
+			values = config.EnvironmentVars{Name: f.environment}.Values(shared, perEnv)
+		}
+		f.hidden = !manifest.Shown(f.when, values)
+		if f.hidden {
+			f.err = ""
+			continue
+		}
+		if f.environment == "" {
+			shared[f.key] = f.value()
+			continue
+		}
+		if perEnv[f.environment] == nil {
+			perEnv[f.environment] = make(map[string]string)
+		}
+		perEnv[f.environment][f.key] = f.value()
+	}
 }
 
+// nextVisible returns the index of the first shown field after from in
+// direction dir (1 or -1), wrapping around, or from if no other is shown.
+func (m configureModel) nextVisible(from, dir int) int {
+	n := len(m.fields)
+	for i := 1; i < n; i++ {
+		if j := ((from+dir*i)%n + n) % n; !m.fields[j].hidden {
+			return j
+		}
+	}
+	return from
+}
+
+// lastVisible returns the index of the last shown field, where enter submits.
+func (m configureModel) lastVisible() int {
+	for i := len(m.fields) - 1; i > 0; i-- {
+		if !m.fields[i].hidden {
+			return i
+		}
+	}
+	return 0
+}
+
+// Update handles a key, then shows or hides fields for the values it
+// changed.
 func (m configureModel) Update(msg tea.Msg) (configureModel, tea.Cmd) {
+	m, cmd := m.update(msg)
+	m.refreshVisibility()
+	return m, cmd
+}
+
+func (m configureModel) update(msg tea.Msg) (configureModel, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if msg.String() != "ctrl+o" {
+			m.docsNotice = ""
+		}
+		if f := &m.fields[m.focused]; f.isSelect() {
+			switch msg.String() {
+			case "left", "h":
+				f.choose(f.selected - 1)
+				return m, nil
+			case "right", "l", " ":
+				f.choose(f.selected + 1)
+				return m, nil
+			}
+		}
 		switch msg.String() {
+		case "ctrl+o":
+			m.openDocs()
+			return m, nil
+
+		case "ctrl+r":
+			m.fields[m.focused].toggleReveal()
+			return m, nil
+
 		case "tab", "down":
 			m.fields[m.focused].input.Blur()
-			m.focused = (m.focused + 1) % len(m.fields)
+			m.focused = m.nextVisible(m.focused, 1)
 			m.fields[m.focused].input.Focus()
 			return m, m.fields[m.focused].input.Focus()
 
 		case "shift+tab", "up":
 			m.fields[m.focused].input.Blur()
-			m.focused = (m.focused - 1 + len(m.fields)) % len(m.fields)
+			m.focused = m.nextVisible(m.focused, -1)
 			m.fields[m.focused].input.Focus()
 			return m, m.fields[m.focused].input.Focus()
 
 		case "enter":
-			// If on last field, submit
-			if m.focused == len(m.fields)-1 {
-				m.done = true
-				return m, nil
+			// If on the last shown field, or changing one from the review,
+			// submit once every field is valid
+			if m.focused == m.lastVisible() || m.revising {
+				if m.validate() {
+					m.done = true
+					return m, nil
+				}
+				return m, m.fields[m.focused].input.Focus()
 			}
 			// Otherwise move to next field
 			m.fields[m.focused].input.Blur()
-			m.focused++
+			m.focused = m.nextVisible(m.focused, 1)
 			m.fields[m.focused].input.Focus()
 			return m, m.fields[m.focused].input.Focus()
 		}
 	}
 
+	// Select fields only change by cycling
+	if m.fields[m.focused].isSelect() {
+		return m, nil
+	}
+
 	// Update the focused input
+	f := &m.fields[m.focused]
+	before := f.input.Value()
 	var cmd tea.Cmd
-	m.fields[m.focused].input, cmd = m.fields[m.focused].input.Update(msg)
+	f.input, cmd = f.input.Update(msg)
+	if f.input.Value() != before {
+		f.err = ""
+	}
 	return m, cmd
 }
 
+// toggleReveal shows or masks the value of a secret field.
+func (f *configField) toggleReveal() {
+	if f.fieldType != "secret" {
+		return
+	}
+	if f.input.EchoMode == textinput.EchoPassword {
+		f.input.EchoMode = textinput.EchoNormal
+	} else {
+		f.input.EchoMode = textinput.EchoPassword
+	}
+}
+
+// validate tidies every shown field's value and checks it against its
+// rules, recording inline errors, and moves focus to the first invalid
+// field. It reports whether the form can be submitted.
+func (m *configureModel) validate() bool {
+	first := -1
+	for i := range m.fields {
+		f := &m.fields[i]
+		f.err = ""
+		if f.hidden {
+			continue
+		}
+		if v := config.NormalizeFieldValue(f.input.Value(), f.rules); v != f.input.Value() {
+			f.input.SetValue(v)
+		}
+		if err := config.ValidateFieldValue(f.value(), f.rules); err != nil {
+			f.err = err.Error()
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return true
+	}
+	m.fields[m.focused].input.Blur()
+	m.focused = first
+	m.fields[m.focused].input.Focus()
+	return false
+}
+
+// edit reopens the form on field i to change it from the review.
+func (m *configureModel) edit(i int) tea.Cmd {
+	m.fields[m.focused].input.Blur()
+	m.focused = i
+	m.done, m.revising = false, true
+	return m.fields[i].input.Focus()
+}
+
+// firstVisible returns the index of the first shown field.
+func (m configureModel) firstVisible() int {
+	return m.nextVisible(m.lastVisible(), 1)
+}
+
+// hasSecrets reports whether any shown field is a secret.
+func (m configureModel) hasSecrets() bool {
+	for _, f := range m.fields {
+		if f.fieldType == "secret" && !f.hidden {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDocs reports whether any field links to documentation.
+func (m configureModel) hasDocs() bool {
+	for _, f := range m.fields {
+		if f.docsURL != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// openDocs opens the focused field's docs URL, or records it for display
+// when no local browser is available.
+func (m *configureModel) openDocs() {
+	url := m.fields[m.focused].docsURL
+	if url == "" {
+		return
+	}
+	if !browser.CanOpen() || browser.Open(url) != nil {
+		m.docsNotice = url
+	}
+}
+
 func (m configureModel) View() string {
 	if len(m.fields) == 0 {
 		return mutedStyle.Render("No configuration fields defined.")
@@ -138,11 +387,21 @@ func (m configureModel) View() string {
 
 	b.WriteString(boldStyle.Render("Configure Your Template"))
 	b.WriteString("\n")
-	b.WriteString(mutedStyle.Render("Tab/Shift+Tab to navigate, Enter to submit"))
+	help := "Tab/Shift+Tab to navigate, Enter to submit"
+	if m.hasDocs() {
+		help += ", Ctrl+O to open docs"
+	}
+	if m.hasSecrets() {
+		help += ", Ctrl+R to show a secret"
+	}
+	b.WriteString(mutedStyle.Render(help))
 	b.WriteString("\n\n")
 
 	currentSection := ""
 	for i, f := range m.fields {
+		if f.hidden {
+			continue
+		}
 		// Section header
 		if f.section != currentSection {
 			if currentSection != "" {
@@ -156,7 +415,11 @@ func (m configureModel) View() string {
 		// Field label
 		label := f.label
 		if f.required {
-			label += " " + errorStyle.Render("*")
+			if f.err != "" {
+				label += " " + errorStyle.Render("*")
+			} else {
+				label += " " + warningStyle.Render("*")
+			}
 		}
 
 		if i == m.focused {
@@ -171,14 +434,44 @@ func (m configureModel) View() string {
 			b.WriteString(fmt.Sprintf("    %s\n", mutedStyle.Render(f.description)))
 		}
 
+		// Where to get the value
+		if f.docsURL != "" {
+			switch {
+			case f.fieldType == "secret":
+				b.WriteString(fmt.Sprintf("    %s %s\n", warningStyle.Render("Get this from:"), f.docsURL))
+			case i == m.focused:
+				b.WriteString(fmt.Sprintf("    %s %s\n", mutedStyle.Render("Docs:"), f.docsURL))
+			}
+			if i == m.focused {
+				b.WriteString(fmt.Sprintf("    %s\n", mutedStyle.Render("ctrl+o to open in browser")))
+			}
+		}
+
 		// Input
-		b.WriteString(fmt.Sprintf("    %s\n", f.input.View()))
+		if f.isSelect() {
+			b.WriteString(fmt.Sprintf("    %s\n", f.selectView(i == m.focused)))
+		} else if n := len([]rune(f.input.Value())); n > 0 && f.input.EchoMode == textinput.EchoPassword {
+			// A masked value can't be read back, so say how long it is
+			b.WriteString(fmt.Sprintf("    %s %s\n", f.input.View(), mutedStyle.Render(fmt.Sprintf("(%d characters)", n))))
+		} else {
+			b.WriteString(fmt.Sprintf("    %s\n", f.input.View()))
+		}
+		if f.err != "" {
+			b.WriteString(fmt.Sprintf("    %s\n", errorStyle.Render(iconCross+" "+f.err)))
+		}
+		b.WriteString("\n")
+	}
+
+	if m.docsNotice != "" {
+		b.WriteString(warningBoxStyle.Render("Open this URL in your browser:\n\n" + boldStyle.Render(m.docsNotice)))
 		b.WriteString("\n")
 	}
 
 	b.WriteString("\n")
-	if m.focused == len(m.fields)-1 {
-		b.WriteString(highlightStyle.Render("  Press Enter to save configuration"))
+	if m.revising {
+		b.WriteString(highlightStyle.Render("  Press Enter to return to the review"))
+	} else if m.focused == m.lastVisible() {
+		b.WriteString(highlightStyle.Render("  Press Enter to review the configuration"))
 	} else {
 		b.WriteString(mutedStyle.Render("  Press Tab to move to next field"))
 	}
@@ -186,11 +479,91 @@ func (m configureModel) View() string {
 	return b.String()
 }
 
-// Values returns the filled-in values as a map.
-// Keys are env var keys or config field paths.
+// reviewView lists the shown fields with the values that will be written,
+// under the file they go to, with the field at cursor selected.
+func (m configureModel) reviewView(cursor int) string {
+	var b strings.Builder
+	b.WriteString(boldStyle.Render("Review Your Configuration"))
+	b.WriteString("\n")
+	b.WriteString(mutedStyle.Render("These values will be written. Check them before they are saved."))
+	b.WriteString("\n\n")
+
+	width := 0
+	for _, f := range m.fields {
+		if !f.hidden {
+			width = max(width, lipgloss.Width(f.label))
+		}
+	}
+	currentSection := ""
+	for i, f := range m.fields {
+		if f.hidden {
+			continue
+		}
+		if f.section != currentSection {
+			if currentSection != "" {
+				b.WriteString("\n")
+			}
+			currentSection = f.section
+			header := currentSection
+			if f.file != "" {
+				header += " → " + f.file
+			}
+			b.WriteString(infoStyle.Render(fmt.Sprintf("── %s ──", header)))
+			b.WriteString("\n")
+		}
+
+		cursorMark, label := "  ", f.label
+		if i == cursor {
+			cursorMark = highlightStyle.Render(iconArrow + " ")
+			label = boldStyle.Render(label)
+		}
+		label += strings.Repeat(" ", width-lipgloss.Width(f.label))
+		b.WriteString(fmt.Sprintf("  %s%s  %s\n", cursorMark, label, f.reviewValue()))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(mutedStyle.Render("  ↑/↓ select, Enter to edit, y to save, Esc to skip"))
+	return b.String()
+}
+
+// reviewValue returns the value the review shows: secrets are masked.
+func (f configField) reviewValue() string {
+	v := f.value()
+	switch {
+	case v == "":
+		return mutedStyle.Render("(empty)")
+	case f.fieldType == "secret":
+		return "••••"
+	}
+	return v
+}
+
+// selectView renders a select field's options with the chosen one marked.
+func (f configField) selectView(focused bool) string {
+	parts := make([]string, len(f.options))
+	for i, opt := range f.options {
+		if i == f.selected {
+			parts[i] = highlightStyle.Render(iconDot + " " + opt)
+		} else {
+			parts[i] = mutedStyle.Render("○ " + opt)
+		}
+	}
+	view := strings.Join(parts, "  ")
+	if focused {
+		view += "  " + mutedStyle.Render("←/→ to change")
+	}
+	return view
+}
+
+// Values returns the filled-in values of the shown fields as a map.
+// Keys are env var keys or config field paths. Per-environment env values
+// are returned by EnvironmentValues instead.
 func (m configureModel) Values() map[string]string {
 	vals := make(map[string]string, len(m.fields))
 	for _, f := range m.fields {
+		if f.environment != "" || f.hidden {
+			continue
+		}
 		v := f.input.Value()
 		if v == "" && f.input.Placeholder != "" {
 			v = f.input.Placeholder // use default
@@ -200,12 +573,32 @@ func (m configureModel) Values() map[string]string {
 	return vals
 }
 
+// EnvironmentValues returns the shown per-environment env values, keyed
+// by environment name, then env key.
+func (m configureModel) EnvironmentValues() map[string]map[string]string {
+	vals := make(map[string]map[string]string)
+	for _, f := range m.fields {
+		if f.environment == "" || f.hidden {
+			continue
+		}
+		if vals[f.environment] == nil {
+			vals[f.environment] = make(map[string]string)
+		}
+		v := f.input.Value()
+		if v == "" {
+			v = f.input.Placeholder
+		}
+		vals[f.environment][f.key] = v
+	}
+	return vals
+}
+
 // EnvValues returns only the .env field values.
 func (m configureModel) EnvValues(manifest *manifest.Manifest) map[string]string {
 	vals := make(map[string]string)
 	for _, env := range manifest.Env {
 		for _, f := range m.fields {
-			if f.key == env.Key {
+			if f.key == env.Key && !f.hidden {
 				v := f.input.Value()
 				if v == "" {
 					v = f.input.Placeholder
@@ -224,7 +617,7 @@ func (m configureModel) ConfigValues(manifest *manifest.Manifest) map[string]str
 	for _, cfg := range manifest.Config {
 		for _, field := range cfg.Fields {
 			for _, f := range m.fields {
-				if f.key == field.Path {
+				if f.key == field.Path && !f.hidden {
 					v := f.input.Value()
 					if v == "" {
 						v = f.input.Placeholder
@@ -0,0 +1,369 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/templatr/templatr-setup/internal/manifest"
+)
+
+func docsManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Env: []manifest.EnvVar{
+			{Key: "SITE_URL", Label: "Site URL", Type: "url", DocsURL: "https://example.com/site"},
+			{Key: "STRIPE_SECRET_KEY", Label: "Stripe Secret Key", Type: "secret", DocsURL: "https://dashboard.stripe.com/apikeys"},
+			{Key: "PLAIN", Label: "Plain", Type: "text"},
+		},
+	}
+}
+
+func TestConfigureView_DocsURL(t *testing.T) {
+	m := newConfigureModel(docsManifest())
+	view := m.View()
+
+	if !strings.Contains(view, "https://example.com/site") {
+		t.Error("expected docs URL for the focused field")
+	}
+	if !strings.Contains(view, "Get this from:") || !strings.Contains(view, "https://dashboard.stripe.com/apikeys") {
+		t.Error("expected get-this-from hint for the secret field")
+	}
+	if strings.Count(view, "ctrl+o to open in browser") != 1 {
+		t.Error("expected the open hint on the focused field only")
+	}
+
+	// Moving focus away hides the non-secret docs line but keeps the secret hint
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	view = m.View()
+	if strings.Contains(view, "https://example.com/site") {
+		t.Error("docs URL should only show for the focused field")
+	}
+	if !strings.Contains(view, "https://dashboard.stripe.com/apikeys") {
+		t.Error("secret hint should show regardless of focus")
+	}
+}
+
+func TestConfigureSecretReveal(t *testing.T) {
+	m := newConfigureModel(docsManifest())
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" https://example.com ")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("  sk_live_abc123 "), Paste: true})
+
+	view := m.View()
+	if strings.Contains(view, "sk_live_abc123") || !strings.Contains(view, "(17 characters)") {
+		t.Errorf("a masked secret should show its length, not its value:\n%s", view)
+	}
+	if !strings.Contains(view, "Ctrl+R to show a secret") {
+		t.Errorf("help should mention ctrl+r:\n%s", view)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if view := m.View(); !strings.Contains(view, "sk_live_abc123") || strings.Contains(view, "characters)") {
+		t.Errorf("ctrl+r should show the secret:\n%s", view)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if strings.Contains(m.View(), "sk_live_abc123") {
+		t.Error("a second ctrl+r should mask the secret again")
+	}
+
+	// ctrl+r leaves other fields alone
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if m.fields[2].input.EchoMode != textinput.EchoNormal {
+		t.Error("ctrl+r should not mask a text field")
+	}
+
+	// Submitting trims the pasted whitespace
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.done {
+		t.Fatal("form should submit")
+	}
+	vals := m.Values()
+	if vals["STRIPE_SECRET_KEY"] != "sk_live_abc123" || vals["SITE_URL"] != "https://example.com" {
+		t.Errorf("values = %q, want the secret and URL trimmed", vals)
+	}
+}
+
+func TestConfigureOpenDocs_SSHFallback(t *testing.T) {
+	t.Setenv("SSH_TTY", "/dev/pts/0")
+
+	m := newConfigureModel(docsManifest())
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+
+	if m.docsNotice != "https://example.com/site" {
+		t.Fatalf("expected URL to be shown instead of opened, got %q", m.docsNotice)
+	}
+	if !strings.Contains(m.View(), "Open this URL in your browser") {
+		t.Error("expected prominent URL notice in view")
+	}
+
+	// Any other key dismisses the notice
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.docsNotice != "" {
+		t.Error("expected notice to clear on next key")
+	}
+}
+
+func TestConfigureOpenDocs_NoURL(t *testing.T) {
+	t.Setenv("SSH_TTY", "/dev/pts/0")
+
+	m := newConfigureModel(docsManifest())
+	m.fields[m.focused].input.Blur()
+	m.focused = 2
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if m.docsNotice != "" {
+		t.Errorf("expected no notice for a field without docs, got %q", m.docsNotice)
+	}
+}
+
+func TestConfigureSelect_Cycling(t *testing.T) {
+	m := newConfigureModel(&manifest.Manifest{
+		Env: []manifest.EnvVar{
+			{Key: "PAYMENT_PROVIDER", Label: "Payment provider", Type: "select", Options: []string{"stripe", "lemonsqueezy", "paddle"}, Default: "lemonsqueezy"},
+			{Key: "DATABASE", Label: "Database", Type: "select", Options: []string{"postgres", "sqlite"}},
+		},
+	})
+
+	// Starts on the default, or the first option without one
+	if got := m.Values()["PAYMENT_PROVIDER"]; got != "lemonsqueezy" {
+		t.Errorf("initial value = %q, want the default", got)
+	}
+	if got := m.Values()["DATABASE"]; got != "postgres" {
+		t.Errorf("initial value = %q, want the first option", got)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if got := m.Values()["PAYMENT_PROVIDER"]; got != "paddle" {
+		t.Errorf("after right = %q, want paddle", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if got := m.Values()["PAYMENT_PROVIDER"]; got != "stripe" {
+		t.Errorf("right should wrap around to the first option, got %q", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if got := m.Values()["PAYMENT_PROVIDER"]; got != "paddle" {
+		t.Errorf("left should wrap around to the last option, got %q", got)
+	}
+
+	// Typing doesn't edit a select field
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if got := m.Values()["PAYMENT_PROVIDER"]; got != "paddle" {
+		t.Errorf("typing changed the value to %q", got)
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "● paddle") || !strings.Contains(view, "○ stripe") {
+		t.Errorf("view should mark the chosen option:\n%s", view)
+	}
+
+	// Other fields keep their own choice
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if got := m.Values()["DATABASE"]; got != "sqlite" {
+		t.Errorf("space should cycle the focused field, got %q", got)
+	}
+	if got := m.Values()["PAYMENT_PROVIDER"]; got != "paddle" {
+		t.Errorf("unfocused field changed to %q", got)
+	}
+}
+
+func TestConfigureSubmit_Validation(t *testing.T) {
+	m := newConfigureModel(&manifest.Manifest{
+		Env: []manifest.EnvVar{
+			{Key: "API_KEY", Label: "API key", Required: true},
+			{Key: "PORT", Label: "Port", Type: "number"},
+		},
+	})
+
+	// Enter on the last field with an empty required field doesn't submit
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("abc")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.done {
+		t.Fatal("form submitted with invalid fields")
+	}
+	if m.focused != 0 {
+		t.Errorf("focus = %d, want the first invalid field", m.focused)
+	}
+	if m.fields[0].err == "" || m.fields[1].err == "" {
+		t.Errorf("both fields should have errors: %q, %q", m.fields[0].err, m.fields[1].err)
+	}
+	if view := m.View(); !strings.Contains(view, "a value is required") || !strings.Contains(view, "must be a number") {
+		t.Errorf("view should show the errors:\n%s", view)
+	}
+
+	// Editing a field clears its error
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("sk_test")})
+	if m.fields[0].err != "" {
+		t.Errorf("error not cleared after editing: %q", m.fields[0].err)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	for range 3 {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("3000")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.done {
+		t.Errorf("form should submit once valid: %q, %q", m.fields[0].err, m.fields[1].err)
+	}
+}
+
+func TestConfigurePrefillsExistingValues(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(".env", []byte("API_KEY=sk_live\nPROVIDER=paddle\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newConfigureModel(&manifest.Manifest{
+		Env: []manifest.EnvVar{
+			{Key: "API_KEY", Label: "API key", Default: "changeme"},
+			{Key: "PROVIDER", Label: "Provider", Type: "select", Options: []string{"stripe", "paddle"}},
+			{Key: "SITE_URL", Label: "Site URL", Default: "http://localhost:3000"},
+		},
+	})
+
+	values := m.Values()
+	for key, want := range map[string]string{
+		"API_KEY":  "sk_live",
+		"PROVIDER": "paddle",
+		"SITE_URL": "http://localhost:3000",
+	} {
+		if values[key] != want {
+			t.Errorf("%s = %q, want %q", key, values[key], want)
+		}
+	}
+}
+
+func conditionalManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Env: []manifest.EnvVar{
+			{Key: "PAYMENT_PROVIDER", Label: "Provider", Type: "select", Options: []string{"stripe", "lemonsqueezy"}},
+			{Key: "STRIPE_SECRET_KEY", Label: "Stripe key", Required: true, When: "PAYMENT_PROVIDER == 'stripe'"},
+			{Key: "STRIPE_WEBHOOK", Label: "Stripe webhook", When: "STRIPE_SECRET_KEY != ''"},
+			{Key: "LEMON_API_KEY", Label: "Lemon key", Required: true, When: "PAYMENT_PROVIDER == 'lemonsqueezy'"},
+		},
+		Config: []manifest.ConfigFile{{
+			File:  "site.ts",
+			Label: "Site",
+			Fields: []manifest.ConfigField{
+				{Path: "site.checkout", Label: "Checkout URL", When: "PAYMENT_PROVIDER == 'lemonsqueezy'"},
+			},
+		}},
+	}
+}
+
+func TestConfigureWhen_Visibility(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m := newConfigureModel(conditionalManifest())
+
+	// Stripe is the first option, so its fields show and Lemon's don't;
+	// the webhook waits for a Stripe key
+	view := m.View()
+	if !strings.Contains(view, "Stripe key") || strings.Contains(view, "Lemon key") || strings.Contains(view, "Checkout URL") {
+		t.Errorf("view should only show the Stripe key:\n%s", view)
+	}
+	if strings.Contains(view, "Stripe webhook") {
+		t.Error("webhook should be hidden until a Stripe key is entered")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("sk_test")})
+	if !strings.Contains(m.View(), "Stripe webhook") {
+		t.Error("webhook should show once a Stripe key is entered")
+	}
+
+	// Switching provider hides the whole Stripe chain
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	view = m.View()
+	if strings.Contains(view, "Stripe key") || strings.Contains(view, "Stripe webhook") {
+		t.Errorf("Stripe fields should hide for lemonsqueezy:\n%s", view)
+	}
+	if !strings.Contains(view, "Lemon key") || !strings.Contains(view, "Checkout URL") {
+		t.Errorf("Lemon fields should show for lemonsqueezy:\n%s", view)
+	}
+
+	// Tab skips the hidden fields
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if got := m.fields[m.focused].key; got != "LEMON_API_KEY" {
+		t.Errorf("focused %s after tab, want LEMON_API_KEY", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if got := m.fields[m.focused].key; got != "PAYMENT_PROVIDER" {
+		t.Errorf("focused %s after wrapping around, want PAYMENT_PROVIDER", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if got := m.fields[m.focused].key; got != "site.checkout" {
+		t.Errorf("focused %s after shift+tab, want site.checkout", got)
+	}
+}
+
+func TestConfigureWhen_SubmitSkipsHidden(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m := newConfigureModel(conditionalManifest())
+
+	// Choose lemonsqueezy: the required Stripe key is hidden, so only the
+	// Lemon key has to be filled in
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.done {
+		t.Fatal("form submitted without the required Lemon key")
+	}
+	if got := m.fields[m.focused].key; got != "LEMON_API_KEY" {
+		t.Fatalf("focused %s, want the empty LEMON_API_KEY", got)
+	}
+	for _, f := range m.fields {
+		if f.hidden && f.err != "" {
+			t.Errorf("hidden %s has error %q", f.key, f.err)
+		}
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("lk_1")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.done {
+		t.Fatal("form should submit with the hidden required field empty")
+	}
+
+	values := m.Values()
+	if _, ok := values["STRIPE_SECRET_KEY"]; ok {
+		t.Errorf("values = %v, hidden STRIPE_SECRET_KEY should be left out", values)
+	}
+	if values["LEMON_API_KEY"] != "lk_1" || values["PAYMENT_PROVIDER"] != "lemonsqueezy" {
+		t.Errorf("values = %v", values)
+	}
+}
+
+func TestConfigureWhen_PerEnvironment(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m := newConfigureModel(&manifest.Manifest{
+		EnvEnvironments: manifest.EnvEnvironments{Names: []string{"development", "production"}},
+		Env: []manifest.EnvVar{
+			{Key: "DB_URL", Label: "Database URL", Default: "postgres://localhost", Environments: []string{"development", "production"}},
+			{Key: "DB_SSL", Label: "Database SSL", When: "DB_URL != 'postgres://localhost'", Environments: []string{"development", "production"}},
+		},
+	})
+
+	// Each environment's condition sees its own DB_URL
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	shown := map[string]bool{}
+	for _, f := range m.fields {
+		if f.key == "DB_SSL" {
+			shown[f.environment] = !f.hidden
+		}
+	}
+	if shown["development"] || !shown["production"] {
+		t.Errorf("DB_SSL shown = %v, want only production", shown)
+	}
+	if values := m.EnvironmentValues(); values["development"]["DB_SSL"] != "" || len(values["development"]) != 1 {
+		t.Errorf("development values = %v, want DB_SSL left out", values["development"])
+	}
+}
@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/templatr/templatr-setup/internal/logger"
+)
+
+const (
+	// logPaneLines is how many log lines the log pane keeps to scroll back
+	// through; the log file has the rest.
+	logPaneLines = 500
+	// logPaneHeight is the most lines the log pane shows at once.
+	logPaneHeight = 8
+)
+
+// logMsg is a log entry the logger published while the TUI runs.
+type logMsg logger.Record
+
+// waitForLog returns a command that waits for the next log entry. It is
+// issued again after each one, and stops once the subscription is closed.
+func waitForLog(records <-chan logger.Record) tea.Cmd {
+	if records == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		rec, ok := <-records
+		if !ok {
+			return nil
+		}
+		return logMsg(rec)
+	}
+}
+
+// logPane is a scrollable tail of the log below the install and package
+// progress. It follows new lines while scrolled to the bottom, and stays
+// put while the user scrolls back.
+type logPane struct {
+	view  viewport.Model
+	lines []string
+	open  bool // toggled with l
+}
+
+func newLogPane() logPane {
+	v := viewport.New(80, logPaneHeight+2)
+	v.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(colorMuted).
+		Padding(0, 1)
+	return logPane{view: v, open: true}
+}
+
+// add appends the log entry rec. Command output is left to the output
+// pane.
+func (p *logPane) add(rec logger.Record) {
+	if rec.Fields["stream"] == "output" {
+		return
+	}
+	line := mutedStyle.Render(rec.TS.Format("15:04:05")) + " "
+	switch rec.Level {
+	case logger.ERROR.String():
+		line += errorStyle.Render("ERROR: " + rec.Msg)
+	case logger.WARN.String():
+		line += warningStyle.Render("WARN: " + rec.Msg)
+	default:
+		line += rec.Msg
+	}
+	p.lines = append(p.lines, line)
+	if len(p.lines) > logPaneLines {
+		p.lines = p.lines[len(p.lines)-logPaneLines:]
+	}
+
+	follow := p.view.AtBottom()
+	p.view.SetContent(strings.Join(p.lines, "\n"))
+	if follow {
+		p.view.GotoBottom()
+	}
+}
+
+// resize fits the pane to a terminal of width by height: up to
+// logPaneHeight lines, and no more than a third of the height.
+func (p *logPane) resize(width, height int) {
+	follow := p.view.AtBottom()
+	p.view.Width = width
+	p.view.Height = min(max(height/3, 3), logPaneHeight) + p.view.Style.GetVerticalFrameSize()
+	if follow {
+		p.view.GotoBottom()
+	} else {
+		p.view.SetYOffset(p.view.YOffset)
+	}
+}
+
+// scroll moves the pane for a scrolling key, and reports whether key was
+// one.
+func (p *logPane) scroll(key string) bool {
+	switch key {
+	case "up", "k":
+		p.view.ScrollUp(1)
+	case "down", "j":
+		p.view.ScrollDown(1)
+	case "pgup":
+		p.view.HalfPageUp()
+	case "pgdown":
+		p.view.HalfPageDown()
+	case "home":
+		p.view.GotoTop()
+	case "end":
+		p.view.GotoBottom()
+	default:
+		return false
+	}
+	return true
+}
+
+// View draws the pane, or the hint to open it.
+func (p logPane) View() string {
+	if len(p.lines) == 0 {
+		return ""
+	}
+	if !p.open {
+		return mutedStyle.Render(fmt.Sprintf("  Press l to show the log (%d lines)", len(p.lines))) + "\n"
+	}
+	hint := "  ↑/↓ scroll the log, l to hide it"
+	if !p.view.AtBottom() {
+		hint = fmt.Sprintf("  ↑/↓ scroll the log (%d%%), end to follow it, l to hide it", int(p.view.ScrollPercent()*100))
+	}
+	return p.view.View() + "\n" + mutedStyle.Render(hint) + "\n"
+}
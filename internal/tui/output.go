@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// outputTailLines is how many lines of command output the packages pane
+// keeps.
+const outputTailLines = 12
+
+// outputTail keeps the last lines of package and post-setup command
+// output for the packages pane. The commands write to it from their own
+// goroutine while the view reads it, so it is shared by pointer between
+// copies of the Model.
+type outputTail struct {
+	mu    sync.Mutex
+	max   int
+	lines []string
+	total int
+}
+
+func newOutputTail(max int) *outputTail {
+	return &outputTail{max: max}
+}
+
+// Write records one line; the logger passes command output a line at a time.
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, strings.TrimSuffix(string(p), "\n"))
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+	t.total++
+	return len(p), nil
+}
+
+// Lines returns the kept lines and how many were written in all.
+func (t *outputTail) Lines() (lines []string, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...), t.total
+}
+
+// renderOutput draws the command output pane, or the hint to open it.
+func renderOutput(t *outputTail, show bool, width int) string {
+	lines, total := t.Lines()
+	if total == 0 {
+		return ""
+	}
+	if !show {
+		return mutedStyle.Render(fmt.Sprintf("  Press o to show command output (%d lines)", total)) + "\n"
+	}
+
+	var b strings.Builder
+	if hidden := total - len(lines); hidden > 0 {
+		b.WriteString(mutedStyle.Render(fmt.Sprintf("… %d earlier lines in the log file", hidden)))
+		b.WriteString("\n")
+	}
+	maxLen := max(width-8, 20)
+	for i, line := range lines {
+		if r := []rune(line); len(r) > maxLen {
+			line = string(r[:maxLen-1]) + "…"
+		}
+		b.WriteString(mutedStyle.Render(line))
+		if i < len(lines)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return boxStyle.Render(b.String()) + "\n" + mutedStyle.Render("  Press o to hide command output") + "\n"
+}
@@ -3,10 +3,13 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/templatr/templatr-setup/internal/install"
+	"github.com/templatr/templatr-setup/internal/term"
 )
 
 // runtimeStatus tracks the install state of a single runtime.
@@ -37,14 +40,17 @@ type progressModel struct {
 	progress progress.Model
 	dlBytes  int64
 	dlTotal  int64
+	dlSpeed  string // such as "2.3 MB/s"; "" until measured
+	dlETA    string // time left at dlSpeed; "" if unknown
+	step     string // the install.Phase* of a runtime in stateInstalling
+	files    int64  // files extracted so far
+	filesAll int64  // files in the archive; 0 if unknown
 	done     bool
 	err      error
 }
 
 func newProgressModel(runtimeNames []string, displayNames []string) progressModel {
-	s := spinner.New()
-	s.Spinner = spinner.Dot
-	s.Style = highlightStyle
+	s := newSpinner()
 
 	p := progress.New(
 		progress.WithDefaultGradient(),
@@ -93,11 +99,23 @@ func (m progressModel) Update(msg tea.Msg) (progressModel, tea.Cmd) {
 		return m, nil
 
 	case downloadProgressMsg:
-		m.dlBytes = msg.downloaded
-		m.dlTotal = msg.total
-		if msg.total > 0 {
-			pct := float64(msg.downloaded) / float64(msg.total)
-			return m, m.progress.SetPercent(pct)
+		if m.current < len(m.runtimes) {
+			m.runtimes[m.current].state = phaseState(msg.phase)
+		}
+		m.step = msg.phase
+		switch msg.phase {
+		case install.PhaseDownloading:
+			m.dlBytes = msg.Downloaded
+			m.dlTotal = msg.Total
+			m.dlSpeed = msg.SpeedText()
+			m.dlETA = msg.ETAText()
+		case install.PhaseExtracting:
+			m.files, m.filesAll = msg.Downloaded, msg.Total
+		default:
+			return m, nil
+		}
+		if msg.Total > 0 {
+			return m, m.progress.SetPercent(msg.Percent / 100)
 		}
 		return m, nil
 
@@ -107,8 +125,9 @@ func (m progressModel) Update(msg tea.Msg) (progressModel, tea.Cmd) {
 			m.runtimes[m.current].version = msg.version
 		}
 		m.current++
-		m.dlBytes = 0
-		m.dlTotal = 0
+		m.dlBytes, m.dlTotal = 0, 0
+		m.dlSpeed, m.dlETA = "", ""
+		m.step, m.files, m.filesAll = "", 0, 0
 		return m, m.progress.SetPercent(0)
 
 	case runtimeFailedMsg:
@@ -146,17 +165,21 @@ func (m progressModel) View() string {
 		case stateDownloading:
 			icon = m.spinner.View()
 			if m.dlTotal > 0 {
-				status = infoStyle.Render(fmt.Sprintf("downloading %s... %s / %s",
-					rt.version, formatBytes(m.dlBytes), formatBytes(m.dlTotal)))
+				status = infoStyle.Render(fmt.Sprintf("downloading %s... %s / %s%s",
+					rt.version, term.FormatBytes(m.dlBytes), term.FormatBytes(m.dlTotal), m.rate()))
 			} else {
 				status = infoStyle.Render(fmt.Sprintf("downloading %s...", rt.version))
 			}
 		case stateInstalling:
 			icon = m.spinner.View()
-			status = infoStyle.Render(fmt.Sprintf("installing %s...", rt.version))
+			status = infoStyle.Render(m.installingStatus(rt.version))
 		case stateDone:
 			icon = successStyle.Render(iconCheck)
-			status = successStyle.Render(fmt.Sprintf("%s installed", rt.version))
+			if rt.version == "" {
+				status = successStyle.Render("done")
+			} else {
+				status = successStyle.Render(fmt.Sprintf("%s installed", rt.version))
+			}
 		case stateFailed:
 			icon = errorStyle.Render(iconCross)
 			status = errorStyle.Render(fmt.Sprintf("failed: %s", rt.err))
@@ -164,8 +187,8 @@ func (m progressModel) View() string {
 
 		b.WriteString(fmt.Sprintf("  %s %s  %s\n", icon, boldStyle.Render(rt.displayName), status))
 
-		// Show progress bar for the current downloading runtime
-		if i == m.current && (rt.state == stateDownloading || rt.state == stateInstalling) && m.dlTotal > 0 {
+		// Show progress bar for the current download or zip extraction
+		if i == m.current && m.showBar(rt.state) {
 			b.WriteString(fmt.Sprintf("    %s\n", m.progress.View()))
 		}
 	}
@@ -173,15 +196,89 @@ func (m progressModel) View() string {
 	return b.String()
 }
 
-func formatBytes(b int64) string {
-	switch {
-	case b >= 1024*1024*1024:
-		return fmt.Sprintf("%.1f GB", float64(b)/(1024*1024*1024))
-	case b >= 1024*1024:
-		return fmt.Sprintf("%.1f MB", float64(b)/(1024*1024))
-	case b >= 1024:
-		return fmt.Sprintf("%.1f KB", float64(b)/1024)
+// phaseState returns the row state of a runtime in an install phase.
+func phaseState(phase string) installState {
+	switch phase {
+	case install.PhaseResolving:
+		return stateResolving
+	case install.PhaseDownloading:
+		return stateDownloading
+	default:
+		return stateInstalling
+	}
+}
+
+// installingStatus returns the status of the runtime in stateInstalling,
+// such as "extracting 22.14.0... 120 / 4310 files".
+func (m progressModel) installingStatus(version string) string {
+	switch m.step {
+	case install.PhaseVerifying:
+		return fmt.Sprintf("verifying %s...", version)
+	case install.PhaseExtracting:
+		if m.filesAll > 0 {
+			return fmt.Sprintf("extracting %s... %d / %d files", version, m.files, m.filesAll)
+		}
+		return fmt.Sprintf("extracting %s... %d files", version, m.files)
+	case install.PhaseLinking:
+		return fmt.Sprintf("linking %s...", version)
+	default:
+		return fmt.Sprintf("installing %s...", version)
+	}
+}
+
+// showBar reports whether the current runtime, in state, gets a progress
+// bar: while downloading a file of known size, and while extracting a zip.
+func (m progressModel) showBar(state installState) bool {
+	switch state {
+	case stateDownloading:
+		return m.dlTotal > 0
+	case stateInstalling:
+		return m.step == install.PhaseExtracting && m.filesAll > 0
 	default:
-		return fmt.Sprintf("%d B", b)
+		return false
+	}
+}
+
+// rate returns the download speed and time left to follow its size, such
+// as ", 2.3 MB/s, 15s left", or "" until the speed is measured.
+func (m progressModel) rate() string {
+	if m.dlSpeed == "" {
+		return ""
+	}
+	if m.dlETA == "" {
+		return ", " + m.dlSpeed
+	}
+	return fmt.Sprintf(", %s, %s left", m.dlSpeed, m.dlETA)
+}
+
+// liveProgress hands the latest progress from an install running in a
+// command to the Model, which picks it up on spinner ticks. It is shared by
+// pointer between copies of the Model.
+type liveProgress struct {
+	mu      sync.Mutex
+	phase   string
+	stats   install.TransferStats
+	updated bool
+}
+
+func newLiveProgress() *liveProgress {
+	return &liveProgress{}
+}
+
+// set records ev's phase and stats; it is an install's progress report.
+func (l *liveProgress) set(ev install.ProgressEvent, stats install.TransferStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.phase, l.stats, l.updated = ev.Phase, stats, true
+}
+
+// take returns the progress recorded since the last take, if any.
+func (l *liveProgress) take() (downloadProgressMsg, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.updated {
+		return downloadProgressMsg{}, false
 	}
+	l.updated = false
+	return downloadProgressMsg{l.phase, l.stats}, true
 }
@@ -1,6 +1,11 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/templatr/templatr-setup/internal/term"
+)
 
 // Color palette.
 var (
@@ -69,8 +74,34 @@ var (
 			BorderStyle(lipgloss.RoundedBorder()).
 			BorderForeground(colorPrimary).
 			Padding(1, 2)
+
+	warningBoxStyle = lipgloss.NewStyle().
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(colorWarning).
+			Padding(1, 2)
 )
 
+// plainSpinner stands in for the animated spinner in plain mode. It still
+// ticks, since the ticks poll the install progress, but never changes.
+var plainSpinner = spinner.Spinner{Frames: []string{"*"}, FPS: spinner.Dot.FPS}
+
+// newSpinner returns a spinner for work in progress.
+func newSpinner() spinner.Model {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	if term.Plain() {
+		s.Spinner = plainSpinner
+	}
+	s.Style = highlightStyle
+	return s
+}
+
+// usePlainStyles renders every style as unstyled text, for --no-color and
+// NO_COLOR.
+func usePlainStyles() {
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
 // Status icons.
 const (
 	iconOK      = "✓"
@@ -80,4 +111,5 @@ const (
 	iconDot     = "●"
 	iconCheck   = "✔"
 	iconCross   = "✘"
+	iconWarning = "⚠"
 )
@@ -6,10 +6,13 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/templatr/templatr-setup/internal/engine"
+	"github.com/templatr/templatr-setup/internal/manifest"
+	"github.com/templatr/templatr-setup/internal/term"
 )
 
-// renderSummary builds the summary table view for the plan.
-func renderSummary(plan *engine.SetupPlan, width int) string {
+// renderSummary builds the summary table view for the plan, with the
+// runtime at index cursor selected; -1 selects none.
+func renderSummary(plan *engine.SetupPlan, width, cursor int) string {
 	var b strings.Builder
 	m := plan.Manifest
 
@@ -25,10 +28,25 @@ func renderSummary(plan *engine.SetupPlan, width int) string {
 		b.WriteString(mutedStyle.Render(fmt.Sprintf("Docs: %s", m.Meta.Docs)))
 		b.WriteString("\n")
 	}
+	if plan.ArchivesDir != "" {
+		b.WriteString(infoStyle.Render(fmt.Sprintf("Offline: installing runtimes from archives in %s", plan.ArchivesDir)))
+		b.WriteString("\n")
+	}
+	if size := plan.DownloadSize(); size > 0 {
+		b.WriteString(mutedStyle.Render(fmt.Sprintf("Approx. download size: %s", term.FormatBytes(size))))
+		b.WriteString("\n")
+	}
 	b.WriteString("\n")
 
+	if plan.Changes != nil && !plan.Changes.Empty() {
+		b.WriteString(renderChanges(plan.Changes))
+		b.WriteString("\n")
+	}
+
 	if len(plan.Runtimes) == 0 {
 		b.WriteString(mutedStyle.Render("No runtimes required by this template."))
+		b.WriteString("\n")
+		b.WriteString(renderDownloads(plan.Downloads))
 		return b.String()
 	}
 
@@ -38,24 +56,21 @@ func renderSummary(plan *engine.SetupPlan, width int) string {
 		if len(r.DisplayName) > nameW {
 			nameW = len(r.DisplayName)
 		}
-		if len(r.RequiredVersion) > reqW {
-			reqW = len(r.RequiredVersion)
-		}
-		cur := r.InstalledVersion
-		if cur == "" {
-			cur = "-"
+		if len(r.RequiredLabel()) > reqW {
+			reqW = len(r.RequiredLabel())
 		}
+		cur := r.InstalledLabel()
 		if len(cur) > curW {
 			curW = len(cur)
 		}
-		act := r.Action.ActionIcon()
+		act := r.ActionLabel()
 		if len(act) > actW {
 			actW = len(act)
 		}
 	}
 
 	// Header row
-	header := fmt.Sprintf("  %-*s  %-*s  %-*s  %s",
+	header := fmt.Sprintf("    %-*s  %-*s  %-*s  %s",
 		nameW, "Runtime",
 		reqW, "Required",
 		curW, "Installed",
@@ -65,7 +80,7 @@ func renderSummary(plan *engine.SetupPlan, width int) string {
 	b.WriteString("\n")
 
 	// Separator
-	sep := fmt.Sprintf("  %s  %s  %s  %s",
+	sep := fmt.Sprintf("    %s  %s  %s  %s",
 		strings.Repeat("─", nameW),
 		strings.Repeat("─", reqW),
 		strings.Repeat("─", curW),
@@ -75,30 +90,38 @@ func renderSummary(plan *engine.SetupPlan, width int) string {
 	b.WriteString("\n")
 
 	// Rows
-	for _, r := range plan.Runtimes {
-		cur := r.InstalledVersion
-		if cur == "" {
-			cur = "-"
+	for i, r := range plan.Runtimes {
+		cur := r.InstalledLabel()
+
+		selected := "  "
+		if i == cursor {
+			selected = highlightStyle.Render(iconArrow + " ")
 		}
 
 		var icon string
 		var actionStyled string
-		switch r.Action {
-		case engine.ActionSkip:
+		switch {
+		case r.Action == engine.ActionSkip && r.Overridden():
+			icon = mutedStyle.Render(iconDot)
+			actionStyled = mutedStyle.Render("Skip")
+		case r.Action == engine.ActionSkip:
 			icon = successStyle.Render(iconOK)
 			actionStyled = successStyle.Render("OK")
-		case engine.ActionInstall:
+		case r.Forced():
+			icon = warningStyle.Render(iconUpgrade)
+			actionStyled = warningStyle.Render(r.ActionLabel())
+		case r.Action == engine.ActionInstall:
 			icon = errorStyle.Render(iconMissing)
 			actionStyled = warningStyle.Render("Install")
-		case engine.ActionUpgrade:
+		case r.Action == engine.ActionUpgrade:
 			icon = warningStyle.Render(iconUpgrade)
 			actionStyled = warningStyle.Render("Upgrade")
 		}
 
-		row := fmt.Sprintf("%s %-*s  %-*s  %-*s  %s",
-			icon,
+		row := fmt.Sprintf("%s%s %-*s  %-*s  %-*s  %s",
+			selected, icon,
 			nameW, r.DisplayName,
-			reqW, r.RequiredVersion,
+			reqW, r.RequiredLabel(),
 			curW, cur,
 			actionStyled,
 		)
@@ -107,19 +130,29 @@ func renderSummary(plan *engine.SetupPlan, width int) string {
 	}
 
 	b.WriteString("\n")
+	for _, r := range plan.Runtimes {
+		if r.Note != "" {
+			b.WriteString(mutedStyle.Render("Note: " + r.Note))
+			b.WriteString("\n")
+		}
+	}
 
 	// Actions summary
-	installs, upgrades := 0, 0
+	installs, upgrades, reinstalls, skipped := 0, 0, 0, 0
 	for _, r := range plan.Runtimes {
-		switch r.Action {
-		case engine.ActionInstall:
+		switch {
+		case r.Forced():
+			reinstalls++
+		case r.Action == engine.ActionInstall:
 			installs++
-		case engine.ActionUpgrade:
+		case r.Action == engine.ActionUpgrade:
 			upgrades++
+		case r.Overridden():
+			skipped++
 		}
 	}
 
-	if installs == 0 && upgrades == 0 {
+	if installs == 0 && upgrades == 0 && reinstalls == 0 && skipped == 0 {
 		b.WriteString(successStyle.Render("All runtimes are already installed and satisfy the requirements."))
 	} else {
 		var parts []string
@@ -129,20 +162,43 @@ func renderSummary(plan *engine.SetupPlan, width int) string {
 		if upgrades > 0 {
 			parts = append(parts, fmt.Sprintf("%d to upgrade", upgrades))
 		}
-		b.WriteString(boldStyle.Render("Actions needed: "))
+		if reinstalls > 0 {
+			parts = append(parts, fmt.Sprintf("%d to reinstall", reinstalls))
+		}
+		if skipped > 0 {
+			parts = append(parts, fmt.Sprintf("%d skipped", skipped))
+		}
+		label := "Actions needed: "
+		if installs == 0 && upgrades == 0 && reinstalls == 0 {
+			label = "Nothing to install: "
+		}
+		b.WriteString(boldStyle.Render(label))
 		b.WriteString(warningStyle.Render(strings.Join(parts, ", ")))
 	}
 	b.WriteString("\n")
 
+	b.WriteString(renderDownloads(plan.Downloads))
+
 	// Package manager
-	if plan.Packages != nil {
+	if plan.Packages != nil && plan.Packages.Manager != "" {
 		b.WriteString("\n")
-		status := errorStyle.Render("not found")
-		if plan.Packages.ManagerFound {
-			status = successStyle.Render("available")
+		style := errorStyle
+		if plan.Packages.ManagerFound && !plan.Packages.ManagerOutdated {
+			style = successStyle
+		} else if plan.Packages.Bootstrap != "" {
+			style = warningStyle
+		}
+		status := style.Render(plan.Packages.Status())
+		if plan.Packages.DetectedFrom != "" {
+			status = mutedStyle.Render("detected from "+plan.Packages.DetectedFrom+", ") + status
 		}
 		b.WriteString(fmt.Sprintf("  %s Package manager: %s (%s)\n",
-			mutedStyle.Render(iconDot), boldStyle.Render(plan.Packages.Manager), status))
+			mutedStyle.Render(iconDot), boldStyle.Render(plan.Packages.Label()), status))
+		if steps := plan.Packages.Steps; len(steps) > 1 || (len(steps) == 1 && steps[0].Dir != "") {
+			for _, step := range steps {
+				b.WriteString(fmt.Sprintf("      %s\n", mutedStyle.Render(step.String())))
+			}
+		}
 	}
 
 	// Env vars info
@@ -167,3 +223,73 @@ func renderSummary(plan *engine.SetupPlan, width int) string {
 
 	return lipgloss.NewStyle().MaxWidth(width).Render(b.String())
 }
+
+// renderDownloads builds one row per [[downloads]] entry.
+func renderDownloads(downloads []engine.DownloadPlan) string {
+	if len(downloads) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(boldStyle.Render("Downloads"))
+	b.WriteString("\n")
+	for _, d := range downloads {
+		icon := errorStyle.Render(iconMissing)
+		action := warningStyle.Render("Download")
+		if d.Action == engine.ActionSkip {
+			icon = successStyle.Render(iconOK)
+			action = successStyle.Render("OK")
+		}
+		auth := ""
+		if d.AuthEnv != "" {
+			auth = mutedStyle.Render(fmt.Sprintf(" (auth: $%s)", d.AuthEnv))
+		}
+		b.WriteString(fmt.Sprintf("%s %s %s %s%s  %s\n",
+			icon, boldStyle.Render(d.Name), mutedStyle.Render(iconArrow), mutedStyle.Render(d.TargetDir), auth, action))
+	}
+	return b.String()
+}
+
+// renderChanges builds the "changed since last setup" block for the summary.
+func renderChanges(d *manifest.Diff) string {
+	var b strings.Builder
+
+	b.WriteString(boldStyle.Render("Changed since your last setup"))
+	if d.OldVersion != "" && d.OldVersion != d.NewVersion {
+		b.WriteString(mutedStyle.Render(fmt.Sprintf(" (%s %s %s)", d.OldVersion, iconArrow, d.NewVersion)))
+	}
+	b.WriteString("\n")
+
+	for _, r := range d.Runtimes {
+		switch {
+		case r.Old == "":
+			b.WriteString(fmt.Sprintf("  %s runtime %s %s\n", successStyle.Render("+"), r.Name, r.New))
+		case r.New == "":
+			b.WriteString(fmt.Sprintf("  %s runtime %s %s\n", errorStyle.Render("-"), r.Name, r.Old))
+		default:
+			b.WriteString(fmt.Sprintf("  %s runtime %s %s %s %s\n", warningStyle.Render("~"), r.Name, r.Old, iconArrow, r.New))
+		}
+	}
+	for _, f := range d.AddedEnv {
+		req := ""
+		if f.Required {
+			req = " " + errorStyle.Render("*")
+		}
+		b.WriteString(fmt.Sprintf("  %s env %s %s%s\n", successStyle.Render("+"), f.Key, mutedStyle.Render("("+f.File+")"), req))
+	}
+	for _, f := range d.RemovedEnv {
+		b.WriteString(fmt.Sprintf("  %s env %s %s\n", errorStyle.Render("-"), f.Key, mutedStyle.Render("("+f.File+")")))
+	}
+	for _, f := range d.AddedConfig {
+		b.WriteString(fmt.Sprintf("  %s config %s %s\n", successStyle.Render("+"), f.Key, mutedStyle.Render("("+f.File+")")))
+	}
+	for _, f := range d.RemovedConfig {
+		b.WriteString(fmt.Sprintf("  %s config %s %s\n", errorStyle.Render("-"), f.Key, mutedStyle.Render("("+f.File+")")))
+	}
+	if d.PostSetupChanged {
+		b.WriteString(fmt.Sprintf("  %s post-setup commands changed\n", warningStyle.Render("~")))
+	}
+
+	return b.String()
+}
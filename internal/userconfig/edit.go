@@ -0,0 +1,166 @@
+package userconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/templatr/templatr-setup/internal/fsutil"
+)
+
+// SetFile sets key to value in the configuration file at path, creating
+// the file if need be. Only the key's line changes: it is rewritten in
+// place, keeping a comment at its end, or added to the end of its table,
+// so the rest of the file keeps its comments and layout. The file is only
+// written if it parses afterwards with key set to value, which can fix a
+// bad value on the key's line; otherwise, as when another line is broken
+// or the key is in an inline table, it is left alone with an error.
+func SetFile(path, key, value string) error {
+	s, err := lookup(key)
+	if err != nil {
+		return err
+	}
+	c := Default()
+	if err := s.set(c, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	want := s.get(c)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	table, name := splitKey(key)
+	edited := setLine(string(data), table, name, encodeValue(s, want))
+
+	// The edit is line by line, so check it says what was asked before
+	// writing it
+	check := Default()
+	if err := toml.Unmarshal([]byte(edited), check); err != nil {
+		return fmt.Errorf("can't set %s in %s, edit the file by hand: %w", key, path, err)
+	}
+	if got, _ := check.Get(key); got != want {
+		return fmt.Errorf("can't set %s in %s, edit the file by hand", key, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return fsutil.WriteFileAtomic(path, []byte(edited), 0o644)
+}
+
+// splitKey splits key into its table and the name within it: "" and
+// runtimes_dir, or update_check and enabled.
+func splitKey(key string) (table, name string) {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+// encodeValue returns value as TOML for setting s.
+func encodeValue(s setting, value string) string {
+	if s.isBool {
+		return value
+	}
+	return `"` + tomlEscaper.Replace(value) + `"`
+}
+
+var tomlEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// setLine returns content with name in table set to the TOML value:
+// replacing the value on the line that sets it, else adding a line after
+// the last key of the table, else adding the table at the end.
+func setLine(content, table, name, value string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	current, last, firstHeader := "", -1, -1
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(t, "["):
+			current = tableName(t)
+			if firstHeader < 0 {
+				firstHeader = i
+			}
+			if current == table {
+				last = i
+			}
+			continue
+		case t == "" || strings.HasPrefix(t, "#"):
+			continue
+		}
+
+		k, _, _ := strings.Cut(t, "=")
+		k = strings.Trim(strings.TrimSpace(k), `"'`)
+		if (current == table && k == name) || (current == "" && table != "" && k == table+"."+name) {
+			eq := strings.Index(line, "=")
+			newLine := line[:eq+1] + " " + value
+			if comment := trailingComment(line[eq+1:]); comment != "" {
+				newLine += " " + comment
+			}
+			lines[i] = newLine
+			return strings.Join(lines, "\n") + "\n"
+		}
+		if current == table {
+			last = i
+		}
+	}
+
+	entry := name + " = " + value
+	switch {
+	case last >= 0:
+		lines = insert(lines, last+1, entry)
+	case table == "" && firstHeader >= 0:
+		lines = insert(lines, firstHeader, entry, "")
+	default:
+		if table != "" {
+			if len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, "["+table+"]")
+		}
+		lines = append(lines, entry)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// tableName returns the table a [header] line starts. An [[array]] table
+// never matches a setting's table.
+func tableName(header string) string {
+	if strings.HasPrefix(header, "[[") {
+		return header
+	}
+	name, _, _ := strings.Cut(header[1:], "]")
+	return strings.TrimSpace(name)
+}
+
+// trailingComment returns the # comment at the end of a TOML value, if
+// any, skipping over a # inside a string.
+func trailingComment(value string) string {
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		switch ch := value[i]; {
+		case quote == '"' && ch == '\\':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#':
+			return value[i:]
+		}
+	}
+	return ""
+}
+
+func insert(lines []string, i int, add ...string) []string {
+	return append(lines[:i], append(add, lines[i:]...)...)
+}
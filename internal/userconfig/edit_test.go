@@ -0,0 +1,107 @@
+package userconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetFile_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, `# My templatr-setup settings
+
+[mirrors]
+node    = "https://npmmirror.com/mirrors/node" # nearest mirror
+
+[update_check]
+enabled = true # checks daily
+`)
+	runtimes := filepath.Join(dir, "runtimes")
+	edits := [][2]string{
+		{"update_check.enabled", "false"},
+		{"mirrors.node", "https://mirror.example.com/node#dist"},
+		{"mirrors.go", "https://golang.google.cn/dl"},
+		{"runtimes_dir", runtimes},
+		{"http.proxy", "http://proxy.corp:8080"},
+	}
+	for _, e := range edits {
+		if err := SetFile(path, e[0], e[1]); err != nil {
+			t.Fatalf("SetFile(%s) error: %s", e[0], err)
+		}
+	}
+
+	c, err := load(path, envOf(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range edits {
+		if got, _ := c.Get(e[0]); got != e[1] {
+			t.Errorf("%s = %q after SetFile, want %q", e[0], got, e[1])
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	want := `# My templatr-setup settings
+
+runtimes_dir = "` + strings.ReplaceAll(runtimes, `\`, `\\`) + `"
+
+[mirrors]
+node    = "https://mirror.example.com/node#dist" # nearest mirror
+go = "https://golang.google.cn/dl"
+
+[update_check]
+enabled = false # checks daily
+
+[http]
+proxy = "http://proxy.corp:8080"
+`
+	if string(data) != want {
+		t.Errorf("file after SetFile:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestSetFile_NewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".templatr", "config.toml")
+	if err := SetFile(path, "setup.yes", "true"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "[setup]\nyes = true\n" {
+		t.Errorf("new file = %q", data)
+	}
+}
+
+func TestSetFile_FixesBadValue(t *testing.T) {
+	path := writeConfig(t, "[update_check]\nenabled = 'no' # typo\n")
+	if err := SetFile(path, "update_check.enabled", "false"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "[update_check]\nenabled = false # typo\n" {
+		t.Errorf("file after SetFile = %q", data)
+	}
+}
+
+func TestSetFile_Rejected(t *testing.T) {
+	const inline = "update_check = { enabled = true }\n"
+	tests := []struct {
+		name, file, key, value string
+	}{
+		{"unknown key", "", "telemetry", "false"},
+		{"invalid value", "", "update_check.enabled", "maybe"},
+		{"broken file", "[mirrors\n", "mirrors.node", "https://npmmirror.com/mirrors/node"},
+		{"inline table", inline, "update_check.enabled", "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.file)
+			if err := SetFile(path, tt.key, tt.value); err == nil {
+				t.Errorf("SetFile(%s, %s) should fail", tt.key, tt.value)
+			}
+			if data, _ := os.ReadFile(path); string(data) != tt.file {
+				t.Errorf("a failed SetFile changed the file to %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package userconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// ApplyHTTP configures t, normally http.DefaultTransport, from the [http]
+// settings: requests go through http.proxy when it is set, instead of the
+// proxy from HTTPS_PROXY and friends, and servers may also present a
+// certificate signed by a CA in http.ca_bundle.
+func (c *Config) ApplyHTTP(t *http.Transport) error {
+	if c.HTTP.Proxy != "" {
+		u, err := url.Parse(c.HTTP.Proxy)
+		if err != nil {
+			return fmt.Errorf("http.proxy: %w", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if c.HTTP.CABundle != "" {
+		pem, err := os.ReadFile(c.HTTP.CABundle)
+		if err != nil {
+			return fmt.Errorf("http.ca_bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("http.ca_bundle: no PEM certificates found in %s", c.HTTP.CABundle)
+		}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package userconfig
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Config is the user configuration in ~/.templatr/config.toml. Every
+// setting but the mirrors can also be set for one run with a TEMPLATR_*
+// environment variable (see EnvVar), which wins over the file.
+type Config struct {
+	RuntimesDir      string            `toml:"runtimes_dir"`      // where runtimes are installed; empty for ~/.templatr/runtimes
+	VerifySignatures bool              `toml:"verify_signatures"` // check release signatures of updates and runtimes that publish them
+	Mirrors          map[string]string `toml:"mirrors"`           // download mirror base URL by runtime
+	UpdateCheck      UpdateCheck       `toml:"update_check"`
+	Update           Update            `toml:"update"`
+	HTTP             HTTP              `toml:"http"`
+	Setup            Setup             `toml:"setup"`
+}
+
+// UpdateCheck configures the check for a newer templatr-setup release that
+// runs alongside every command.
+type UpdateCheck struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// Update configures which releases the update check and update take.
+type Update struct {
+	Channel string `toml:"channel"` // stable, or beta for pre-releases too
+}
+
+// HTTP configures the connections made for downloads, remote manifests
+// and the update check.
+type HTTP struct {
+	Proxy    string `toml:"proxy"`     // proxy URL; empty to use HTTPS_PROXY and friends
+	CABundle string `toml:"ca_bundle"` // PEM file of extra CAs to trust, e.g. a corporate proxy's
+}
+
+// Setup holds defaults for the setup and install commands.
+type Setup struct {
+	Yes bool `toml:"yes"` // skip the confirmation prompts unless --yes=false is given
+}
+
+// setting is one key that config get and set know, with how to read and
+// parse its value.
+type setting struct {
+	key    string
+	isBool bool // the value is true or false rather than a string
+	get    func(c *Config) string
+	set    func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{
+		key: "runtimes_dir",
+		get: func(c *Config) string { return c.RuntimesDir },
+		set: func(c *Config, value string) (err error) {
+			c.RuntimesDir, err = absPath(value)
+			return err
+		},
+	},
+	{
+		key:    "verify_signatures",
+		isBool: true,
+		get:    func(c *Config) string { return strconv.FormatBool(c.VerifySignatures) },
+		set: func(c *Config, value string) (err error) {
+			c.VerifySignatures, err = parseBool(value)
+			return err
+		},
+	},
+	{
+		key:    "update_check.enabled",
+		isBool: true,
+		get:    func(c *Config) string { return strconv.FormatBool(c.UpdateCheck.Enabled) },
+		set: func(c *Config, value string) (err error) {
+			c.UpdateCheck.Enabled, err = parseBool(value)
+			return err
+		},
+	},
+	{
+		key: "update.channel",
+		get: func(c *Config) string { return c.Update.Channel },
+		set: func(c *Config, value string) error {
+			if value != "stable" && value != "beta" {
+				return fmt.Errorf("%q is not a channel - use stable or beta", value)
+			}
+			c.Update.Channel = value
+			return nil
+		},
+	},
+	{
+		key: "http.proxy",
+		get: func(c *Config) string { return c.HTTP.Proxy },
+		set: func(c *Config, value string) error {
+			if value != "" {
+				u, err := url.Parse(value)
+				if err != nil || u.Host == "" || !slices.Contains([]string{"http", "https", "socks5"}, u.Scheme) {
+					return fmt.Errorf("%q is not an http://, https:// or socks5:// proxy URL", value)
+				}
+			}
+			c.HTTP.Proxy = value
+			return nil
+		},
+	},
+	{
+		key: "http.ca_bundle",
+		get: func(c *Config) string { return c.HTTP.CABundle },
+		set: func(c *Config, value string) (err error) {
+			c.HTTP.CABundle, err = absPath(value)
+			return err
+		},
+	},
+	{
+		key:    "setup.yes",
+		isBool: true,
+		get:    func(c *Config) string { return strconv.FormatBool(c.Setup.Yes) },
+		set: func(c *Config, value string) (err error) {
+			c.Setup.Yes, err = parseBool(value)
+			return err
+		},
+	},
+}
+
+// mirrorPrefix starts the keys of the [mirrors] entries, e.g. mirrors.node.
+const mirrorPrefix = "mirrors."
+
+// Default returns the configuration used when the file doesn't set
+// anything.
+func Default() *Config {
+	return &Config{UpdateCheck: UpdateCheck{Enabled: true}, Update: Update{Channel: "stable"}}
+}
+
+// Path returns the path of the user configuration file
+// (~/.templatr/config.toml).
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".templatr", "config.toml"), nil
+}
+
+// Load reads the user configuration file over the defaults and applies the
+// TEMPLATR_* environment overrides. A missing file means the defaults.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return load(path, os.Getenv)
+}
+
+func load(path string, getenv func(string) string) (*Config, error) {
+	c, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range settings {
+		name := EnvVar(s.key)
+		if value := getenv(name); value != "" {
+			if err := s.set(c, value); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// loadFile reads the configuration file at path over the defaults, without
+// the environment overrides.
+func loadFile(path string) (*Config, error) {
+	c := Default()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := toml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	// Values from the file go through the same checks as config set
+	for _, s := range settings {
+		if err := s.set(c, s.get(c)); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, s.key, err)
+		}
+	}
+	return c, nil
+}
+
+// EnvVar returns the environment variable that overrides key, e.g.
+// TEMPLATR_UPDATE_CHECK_ENABLED for update_check.enabled.
+func EnvVar(key string) string {
+	return "TEMPLATR_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Keys returns the keys config get and set accept, besides the
+// mirrors.<runtime> entries.
+func Keys() []string {
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = s.key
+	}
+	return keys
+}
+
+// Get returns the value of key in c, as config get prints it.
+func (c *Config) Get(key string) (string, error) {
+	s, err := lookup(key)
+	if err != nil {
+		return "", err
+	}
+	return s.get(c), nil
+}
+
+// Set parses value and sets key to it in c. A flag for one of the settings
+// sets it this way, over the file and the environment.
+func (c *Config) Set(key, value string) error {
+	s, err := lookup(key)
+	if err != nil {
+		return err
+	}
+	if err := s.set(c, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// KeyValue is a setting's key and its value.
+type KeyValue struct {
+	Key, Value string
+}
+
+// Values returns every key of c with its value, the settings first and
+// then the mirrors by runtime name.
+func (c *Config) Values() []KeyValue {
+	var values []KeyValue
+	for _, s := range settings {
+		values = append(values, KeyValue{s.key, s.get(c)})
+	}
+	names := make([]string, 0, len(c.Mirrors))
+	for name := range c.Mirrors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		values = append(values, KeyValue{mirrorPrefix + name, c.Mirrors[name]})
+	}
+	return values
+}
+
+// lookup returns the setting for key, making one up for a mirror entry.
+func lookup(key string) (setting, error) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+	if name, ok := strings.CutPrefix(key, mirrorPrefix); ok && name != "" && !strings.Contains(name, ".") {
+		return setting{
+			key: key,
+			get: func(c *Config) string { return c.Mirrors[name] },
+			set: func(c *Config, value string) error {
+				if value != "" {
+					if u, err := url.Parse(value); err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+						return fmt.Errorf("%q is not an http:// or https:// URL", value)
+					}
+				}
+				if c.Mirrors == nil {
+					c.Mirrors = map[string]string{}
+				}
+				c.Mirrors[name] = value
+				return nil
+			},
+		}, nil
+	}
+	return setting{}, fmt.Errorf("unknown setting %q - known: %s, mirrors.<runtime>", key, strings.Join(Keys(), ", "))
+}
+
+func parseBool(value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%q is not true or false", value)
+	}
+	return b, nil
+}
+
+// absPath expands a leading ~ in path to the home directory and requires
+// the result to be absolute, since the file isn't read from any one
+// working directory. An empty path stays empty.
+func absPath(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%q must be an absolute path or start with ~/", path)
+	}
+	return filepath.Clean(path), nil
+}
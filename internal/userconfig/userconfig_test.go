@@ -0,0 +1,167 @@
+package userconfig
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func envOf(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	fileDir, envDir, flagDir := filepath.Join(dir, "file"), filepath.Join(dir, "env"), filepath.Join(dir, "flag")
+	path := writeConfig(t, "runtimes_dir = '"+fileDir+"'\n\n[update_check]\nenabled = false\n")
+	env := envOf(map[string]string{"TEMPLATR_RUNTIMES_DIR": envDir})
+
+	// default
+	c, err := load(filepath.Join(dir, "missing.toml"), envOf(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RuntimesDir != "" || !c.UpdateCheck.Enabled || c.Update.Channel != "stable" || c.Setup.Yes {
+		t.Errorf("defaults = %+v, want no runtimes_dir, the update check on, the stable channel and prompts", c)
+	}
+
+	// file over default
+	c, err = load(path, envOf(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RuntimesDir != fileDir || c.UpdateCheck.Enabled {
+		t.Errorf("from the file = %+v, want runtimes_dir %s and the update check off", c, fileDir)
+	}
+
+	// env over file
+	c, err = load(path, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RuntimesDir != envDir {
+		t.Errorf("runtimes_dir = %s, want %s from TEMPLATR_RUNTIMES_DIR", c.RuntimesDir, envDir)
+	}
+	if c.UpdateCheck.Enabled {
+		t.Error("a setting without its variable set should keep the file's value")
+	}
+
+	// flag over env
+	if err := c.Set("runtimes_dir", flagDir); err != nil {
+		t.Fatal(err)
+	}
+	if c.RuntimesDir != flagDir {
+		t.Errorf("runtimes_dir = %s, want %s from the flag", c.RuntimesDir, flagDir)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{"not toml", "runtimes_dir = ", nil, "failed to parse"},
+		{"relative runtimes_dir", "runtimes_dir = 'runtimes'", nil, "runtimes_dir"},
+		{"bad proxy", "[http]\nproxy = 'proxy.corp:8080'", nil, "http.proxy"},
+		{"unknown channel", "[update]\nchannel = 'nightly'", nil, "update.channel"},
+		{"bad env bool", "", map[string]string{"TEMPLATR_UPDATE_CHECK_ENABLED": "nope"}, "TEMPLATR_UPDATE_CHECK_ENABLED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(writeConfig(t, tt.file), envOf(tt.env))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("load() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_GetSet(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	c := Default()
+	if err := c.Set("runtimes_dir", "~/runtimes"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Get("runtimes_dir"); got != filepath.Join(home, "runtimes") {
+		t.Errorf("runtimes_dir = %s, want ~ expanded to %s", got, home)
+	}
+	if err := c.Set("mirrors.node", "https://npmmirror.com/mirrors/node"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Mirrors["node"] != "https://npmmirror.com/mirrors/node" {
+		t.Errorf("mirrors = %v, want the node mirror", c.Mirrors)
+	}
+	if err := c.Set("setup.yes", "yes"); err == nil {
+		t.Error("setup.yes should only take true or false")
+	}
+	if _, err := c.Get("update_check"); err == nil {
+		t.Error("Get() of an unknown key should fail")
+	}
+
+	values := c.Values()
+	if last := values[len(values)-1]; last.Key != "mirrors.node" {
+		t.Errorf("Values() should end with the mirrors, got %v", values)
+	}
+}
+
+func TestApplyHTTP(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // the untrusted request's handshake error
+	srv.StartTLS()
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &http.Transport{}
+	if _, err := (&http.Client{Transport: tr}).Get(srv.URL); err == nil {
+		t.Fatal("the test server's certificate shouldn't be trusted without the bundle")
+	}
+
+	c := Default()
+	c.HTTP.CABundle = bundle
+	c.HTTP.Proxy = "http://proxy.corp:8080"
+	tr = &http.Transport{}
+	if err := c.ApplyHTTP(tr); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "https://nodejs.org/dist/index.json", nil)
+	if u, err := tr.Proxy(req); err != nil || u.Host != "proxy.corp:8080" {
+		t.Errorf("proxy = %v, %v, want proxy.corp:8080", u, err)
+	}
+
+	tr.Proxy = nil
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with the CA bundle: %s", err)
+	}
+	resp.Body.Close()
+
+	c.HTTP.CABundle = writeConfig(t, "not a certificate")
+	if err := c.ApplyHTTP(&http.Transport{}); err == nil {
+		t.Error("ApplyHTTP() should fail for a bundle without certificates")
+	}
+}
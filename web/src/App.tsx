@@ -5,6 +5,7 @@ import { SummaryStep } from "@/components/steps/SummaryStep";
 import { InstallStep } from "@/components/steps/InstallStep";
 import { ConfigureStep } from "@/components/steps/ConfigureStep";
 import { CompleteStep } from "@/components/steps/CompleteStep";
+import { ManifestEditor } from "@/components/ManifestEditor";
 import { IconWifi, IconWifiOff } from "@tabler/icons-react";
 
 function App() {
@@ -12,6 +13,14 @@ function App() {
   const { connected, send } = useWebSocket(state.handleMessage);
 
   const hasManifest = state.plan !== null;
+  // A runtime or download failed: "retry" installs only what didn't complete
+  const canRetryInstall = state.runtimeStatuses.some(
+    (rs) => rs.status === "failed"
+  );
+  const retryInstall = () => {
+    state.setStep("install");
+    send({ type: "retry" });
+  };
 
   return (
     <div className="min-h-screen bg-background">
@@ -44,18 +53,54 @@ function App() {
               state.setStep("summary");
             }
           }}
-          onLoadManifest={(content) => {
-            send({ type: "load_manifest", manifestContent: content });
+          onLoadManifest={(content, fileName) => {
+            send({
+              type: "load_manifest",
+              manifestContent: content,
+              manifestName: fileName,
+            });
+          }}
+          onLoadManifestURL={(url) => {
+            send({ type: "load_manifest", manifestURL: url });
           }}
         />
       )}
 
+      {state.step === "welcome" &&
+        state.validation &&
+        state.validation.errors.length > 0 && (
+          <div className="flex justify-center px-4 pb-8">
+            <ManifestEditor
+              validation={state.validation}
+              onRevalidate={(content) => {
+                send({
+                  type: "revalidate",
+                  manifestContent: content,
+                  manifestName: state.validation?.file,
+                });
+              }}
+              onLoad={(content) => {
+                send({
+                  type: "load_manifest",
+                  manifestContent: content,
+                  manifestName: state.validation?.file,
+                });
+              }}
+              onProceed={() => send({ type: "proceed" })}
+            />
+          </div>
+        )}
+
       {state.step === "summary" && state.plan && (
         <SummaryStep
           plan={state.plan}
-          onInstall={() => {
+          onInstall={(overrides, options) => {
+            if (canRetryInstall) {
+              retryInstall();
+              return;
+            }
             state.setStep("install");
-            send({ type: "confirm", action: "install" });
+            send({ type: "confirm", action: "install", overrides, options });
           }}
           onBack={() => state.setStep("welcome")}
         />
@@ -73,8 +118,10 @@ function App() {
         <ConfigureStep
           envVars={state.plan.envVars ?? []}
           configs={state.plan.configs ?? []}
-          onSubmit={(env, config) => {
-            send({ type: "configure", env, config });
+          environments={state.plan.environments ?? []}
+          fieldErrors={state.fieldErrors}
+          onSubmit={(env, config, envByEnvironment) => {
+            send({ type: "configure", env, config, envByEnvironment });
           }}
           onSkip={() => {
             send({ type: "configure", env: {}, config: {} });
@@ -86,6 +133,12 @@ function App() {
         <CompleteStep
           success={state.success}
           message={state.completeMessage}
+          unignored={state.unignored}
+          envChanges={state.envChanges}
+          skipped={state.skipped}
+          postSetup={state.postSetup}
+          onRetry={(index) => send({ type: "retry_command", index })}
+          onRetryInstall={canRetryInstall ? retryInstall : undefined}
         />
       )}
     </div>
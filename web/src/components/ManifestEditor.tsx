@@ -0,0 +1,107 @@
+import { useState } from "react";
+import { Button } from "@/components/ui/button";
+import {
+  Card,
+  CardContent,
+  CardHeader,
+  CardTitle,
+  CardDescription,
+} from "@/components/ui/card";
+import type { ValidationData } from "@/types";
+import { IconAlertTriangle, IconCircleX } from "@tabler/icons-react";
+
+interface ManifestEditorProps {
+  validation: ValidationData;
+  onRevalidate: (content: string) => void;
+  onLoad: (content: string) => void;
+  onProceed: () => void;
+}
+
+const stageTitles: Record<ValidationData["stage"], string> = {
+  parse: "Manifest syntax error",
+  validate: "Manifest problems",
+  plan: "Setup plan failed",
+};
+
+// ManifestEditor lets template authors fix validation problems inline and
+// re-validate without re-uploading the file.
+export function ManifestEditor({
+  validation,
+  onRevalidate,
+  onLoad,
+  onProceed,
+}: ManifestEditorProps) {
+  const [content, setContent] = useState(validation.content ?? "");
+
+  const hasWarningsOnly = validation.valid && validation.errors.length > 0;
+  const file = validation.file ? ` in ${validation.file}` : "";
+
+  return (
+    <Card className="w-full max-w-2xl">
+      <CardHeader>
+        <CardTitle>{stageTitles[validation.stage]}</CardTitle>
+        <CardDescription>
+          {validation.valid
+            ? `The manifest${file} is valid. Review the warnings below or continue.`
+            : validation.stage === "plan"
+              ? `The manifest${file} is valid, but a setup plan couldn't be built from it.`
+              : `${validation.errors.length} problem(s) found${file}. Edit the manifest and re-validate.`}
+        </CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <ul className="space-y-2">
+          {validation.errors.map((e, i) => (
+            <li
+              key={`${e.path}-${i}`}
+              className="flex items-start gap-2 text-sm"
+            >
+              {e.severity === "warning" ? (
+                <IconAlertTriangle className="size-4 mt-0.5 text-amber-500" />
+              ) : (
+                <IconCircleX className="size-4 mt-0.5 text-destructive" />
+              )}
+              <span>
+                {e.path && (
+                  <code className="text-xs bg-secondary px-1 py-0.5 rounded mr-2">
+                    {e.path}
+                  </code>
+                )}
+                {!!e.line && (
+                  <code className="text-xs bg-secondary px-1 py-0.5 rounded mr-2">
+                    line {e.line}:{e.column}
+                  </code>
+                )}
+                {e.message}
+              </span>
+            </li>
+          ))}
+        </ul>
+
+        <textarea
+          value={content}
+          onChange={(e) => setContent(e.target.value)}
+          spellCheck={false}
+          className="w-full h-72 p-3 rounded-lg bg-secondary/50 border border-border font-mono text-xs"
+        />
+
+        <div className="flex gap-3">
+          <Button
+            variant="outline"
+            onClick={() => onRevalidate(content)}
+            className="flex-1"
+          >
+            Re-validate
+          </Button>
+          <Button onClick={() => onLoad(content)} className="flex-1">
+            Load
+          </Button>
+          {hasWarningsOnly && (
+            <Button variant="outline" onClick={onProceed} className="flex-1">
+              Continue anyway
+            </Button>
+          )}
+        </div>
+      </CardContent>
+    </Card>
+  );
+}
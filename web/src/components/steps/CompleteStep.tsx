@@ -6,17 +6,39 @@ import {
   IconCircleX,
   IconCopy,
   IconCheck,
+  IconAlertTriangle,
+  IconTerminal2,
+  IconLoader2,
+  IconRefresh,
 } from "@tabler/icons-react";
+import type {
+  EnvChange,
+  EnvSummary,
+  ManualStep,
+  PostSetupCommand,
+} from "@/types";
 
 interface CompleteStepProps {
   success: boolean;
   message: string | null;
+  unignored?: string[];
+  envChanges?: EnvSummary | null;
+  skipped?: ManualStep[]; // steps left to the user, with the commands to run
+  postSetup?: PostSetupCommand[];
+  onRetry?: (index: number) => void;
+  onRetryInstall?: () => void; // re-runs the install that failed
   logFilePath?: string;
 }
 
 export function CompleteStep({
   success,
   message,
+  unignored = [],
+  envChanges,
+  skipped = [],
+  postSetup = [],
+  onRetry,
+  onRetryInstall,
   logFilePath,
 }: CompleteStepProps) {
   const [copied, setCopied] = useState(false);
@@ -54,6 +76,154 @@ export function CompleteStep({
         </Card>
       )}
 
+      {postSetup.some((c) => c.status !== "ok") && (
+        <Card className="w-full max-w-md border-amber-500/50">
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2 text-amber-500">
+              <IconAlertTriangle className="size-5" />
+              Post-Setup Commands
+            </CardTitle>
+          </CardHeader>
+          <CardContent>
+            <ul className="space-y-3">
+              {postSetup.map((c) => (
+                <li key={c.index} className="flex items-start gap-3">
+                  <PostSetupIcon status={c.status} />
+                  <div className="flex-1 min-w-0 space-y-1">
+                    <code className="text-sm font-mono break-all">
+                      {c.command}
+                    </code>
+                    {c.status === "not_run" && (
+                      <p className="text-xs text-muted-foreground">
+                        Not run: a required command before it failed
+                      </p>
+                    )}
+                    {c.status === "failed" && c.error && (
+                      <p className="text-xs text-muted-foreground break-all">
+                        {c.error}
+                      </p>
+                    )}
+                  </div>
+                  {onRetry && (c.status === "failed" || c.status === "not_run") && (
+                    <Button
+                      variant="outline"
+                      size="xs"
+                      onClick={() => onRetry(c.index)}
+                    >
+                      <IconRefresh />
+                      {c.status === "not_run" ? "Run" : "Retry"}
+                    </Button>
+                  )}
+                </li>
+              ))}
+            </ul>
+          </CardContent>
+        </Card>
+      )}
+
+      {unignored.length > 0 && (
+        <Card className="w-full max-w-md border-amber-500/50">
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2 text-amber-500">
+              <IconAlertTriangle className="size-5" />
+              Secrets Not Ignored by Git
+            </CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-2">
+            <p className="text-sm text-muted-foreground">
+              These files contain secrets but are not listed in .gitignore.
+              Add them before committing:
+            </p>
+            <ul className="space-y-1">
+              {unignored.map((file) => (
+                <li key={file}>
+                  <code className="text-sm font-mono">{file}</code>
+                </li>
+              ))}
+            </ul>
+          </CardContent>
+        </Card>
+      )}
+
+      {envChanges && envChanges.changes.length > 0 && (
+        <Card className="w-full max-w-md">
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2">
+              <IconTerminal2 className="size-5" />
+              Environment Changes
+            </CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-3">
+            <ul className="space-y-1">
+              {envChanges.changes.map((change, i) => (
+                <li key={i} className="text-sm text-muted-foreground break-all">
+                  {describeChange(change)}
+                </li>
+              ))}
+            </ul>
+            {envChanges.changes.every((c) => c.unchanged) && (
+              <p className="text-sm text-muted-foreground">
+                No shell configuration changes were needed.
+              </p>
+            )}
+            {envChanges.activation && envChanges.activation.length > 0 && (
+              <div className="space-y-1">
+                <p className="text-sm text-muted-foreground">
+                  To use them in this terminal ({envChanges.shell}), run:
+                </p>
+                <pre className="text-sm font-mono bg-secondary/50 rounded-lg p-3 whitespace-pre-wrap break-all">
+                  {envChanges.activation.join("\n")}
+                </pre>
+              </div>
+            )}
+          </CardContent>
+        </Card>
+      )}
+
+      {envChanges?.manual && envChanges.manual.length > 0 && (
+        <Card className="w-full max-w-md">
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2">
+              <IconTerminal2 className="size-5" />
+              Add to PATH
+            </CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-3">
+            <p className="text-sm text-muted-foreground">
+              PATH was left unchanged. Add these lines to{" "}
+              <code>{envChanges.manualFile}</code>:
+            </p>
+            <pre className="text-sm font-mono bg-secondary/50 rounded-lg p-3 whitespace-pre-wrap break-all">
+              {envChanges.manual.join("\n")}
+            </pre>
+          </CardContent>
+        </Card>
+      )}
+
+      {success && skipped.length > 0 && (
+        <Card className="w-full max-w-md">
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2">
+              <IconTerminal2 className="size-5" />
+              Skipped Steps
+            </CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-3">
+            <p className="text-sm text-muted-foreground">
+              Run these yourself from the project directory:
+            </p>
+            {skipped.map((step) => (
+              <div key={step.name} className="space-y-1">
+                <p className="text-sm font-medium">{step.name}</p>
+                <pre className="text-sm font-mono bg-secondary/50 rounded-lg p-3 whitespace-pre-wrap break-all">
+                  {step.commands.join("\n")}
+                </pre>
+              </div>
+            ))}
+          </CardContent>
+        </Card>
+      )}
+
       {success && (
         <div className="w-full max-w-md">
           <button
@@ -70,6 +240,13 @@ export function CompleteStep({
         </div>
       )}
 
+      {!success && onRetryInstall && (
+        <Button onClick={onRetryInstall}>
+          <IconRefresh />
+          Retry Installation
+        </Button>
+      )}
+
       {logFilePath && (
         <p className="text-xs text-muted-foreground">
           Log file: {logFilePath}
@@ -86,3 +263,31 @@ export function CompleteStep({
     </div>
   );
 }
+
+function PostSetupIcon({ status }: { status: PostSetupCommand["status"] }) {
+  switch (status) {
+    case "ok":
+      return <IconCircleCheck className="size-4 mt-0.5 text-emerald-500" />;
+    case "running":
+      return <IconLoader2 className="size-4 mt-0.5 text-primary animate-spin" />;
+    case "not_run":
+      return <IconCircleX className="size-4 mt-0.5 text-muted-foreground" />;
+    default:
+      return <IconCircleX className="size-4 mt-0.5 text-destructive" />;
+  }
+}
+
+// describeChange mirrors Go's EnvSummary.Lines.
+function describeChange(c: EnvChange): string {
+  if (c.unchanged) {
+    return c.kind === "path"
+      ? `PATH already includes ${c.value} - no changes needed`
+      : `${c.name} is already set - no changes needed`;
+  }
+  if (c.registry) {
+    return c.kind === "path"
+      ? `Added ${c.value} to ${c.registry}`
+      : `Set ${c.registry} = ${c.value}`;
+  }
+  return `Added to ${c.file}: ${c.line}`;
+}
@@ -1,4 +1,4 @@
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { Button } from "@/components/ui/button";
 import {
   Card,
@@ -8,15 +8,21 @@ import {
   CardDescription,
 } from "@/components/ui/card";
 import { Input } from "@/components/ui/input";
-import type { EnvVarData, ConfigData } from "@/types";
+import { NativeSelect } from "@/components/ui/native-select";
+import type { EnvVarData, ConfigData, FieldError } from "@/types";
+import { validateField } from "@/lib/validate";
+import { shown } from "@/lib/condition";
 import { IconArrowRight, IconPlayerSkipForward } from "@tabler/icons-react";
 
 interface ConfigureStepProps {
   envVars: EnvVarData[];
   configs: ConfigData[];
+  environments: string[];
+  fieldErrors: FieldError[]; // values the server rejected
   onSubmit: (
     env: Record<string, string>,
-    config: Record<string, string>
+    config: Record<string, string>,
+    envByEnvironment: Record<string, Record<string, string>>
   ) => void;
   onSkip: () => void;
 }
@@ -24,17 +30,38 @@ interface ConfigureStepProps {
 export function ConfigureStep({
   envVars,
   configs,
+  environments,
+  fieldErrors,
   onSubmit,
   onSkip,
 }: ConfigureStepProps) {
+  // Vars without an environments list are asked once and copied to every
+  // environment's file.
+  const sharedVars = envVars.filter((ev) => !ev.environments?.length);
+  const varsFor = (name: string) =>
+    envVars.filter((ev) => ev.environments?.includes(name));
+
   const [envValues, setEnvValues] = useState<Record<string, string>>(() => {
     const defaults: Record<string, string> = {};
-    for (const ev of envVars) {
+    for (const ev of sharedVars) {
       defaults[ev.key] = ev.default;
     }
     return defaults;
   });
 
+  const [envByEnvironment, setEnvByEnvironment] = useState<
+    Record<string, Record<string, string>>
+  >(() => {
+    const defaults: Record<string, Record<string, string>> = {};
+    for (const name of environments) {
+      defaults[name] = {};
+      for (const ev of varsFor(name)) {
+        defaults[name][ev.key] = ev.default;
+      }
+    }
+    return defaults;
+  });
+
   const [configValues, setConfigValues] = useState<Record<string, string>>(
     () => {
       const defaults: Record<string, string> = {};
@@ -47,8 +74,85 @@ export function ConfigureStep({
     }
   );
 
+  // Errors by errorKey, from the last submit or the server's rejection
+  const [errors, setErrors] = useState<Record<string, string>>({});
+  useEffect(() => {
+    const next: Record<string, string> = {};
+    for (const fe of fieldErrors) {
+      const key = fe.config
+        ? configErrorKey(fe.config)
+        : envErrorKey(fe.env ?? "", fe.environment);
+      next[key] = fe.message;
+    }
+    setErrors(next);
+  }, [fieldErrors]);
+
+  const clearError = (key: string) =>
+    setErrors((prev) => {
+      if (!(key in prev)) return prev;
+      const next = { ...prev };
+      delete next[key];
+      return next;
+    });
+
+  // Fields are shown while their when condition holds for the values of
+  // the shown fields before them, so a hidden field counts as empty.
+  const sharedShown: Record<string, string> = {};
+  const visibleShared = sharedVars.filter((ev) => {
+    if (!shown(ev.when, sharedShown)) return false;
+    sharedShown[ev.key] = envValues[ev.key] ?? "";
+    return true;
+  });
+  const visibleFor = (name: string) => {
+    const values = { ...sharedShown };
+    return varsFor(name).filter((ev) => {
+      if (!shown(ev.when, values)) return false;
+      values[ev.key] = envByEnvironment[name]?.[ev.key] ?? "";
+      return true;
+    });
+  };
+  const configShown = { ...sharedShown };
+  const visibleConfigs = configs.map((cfg) => ({
+    ...cfg,
+    fields: cfg.fields.filter((field) => {
+      if (!shown(field.when, configShown)) return false;
+      configShown[field.path] = configValues[field.path] ?? "";
+      return true;
+    }),
+  }));
+
   const handleSubmit = () => {
-    onSubmit(envValues, configValues);
+    const next: Record<string, string> = {};
+    const check = (key: string, value: string, ev: EnvVarData) => {
+      const err = validateField(value, ev);
+      if (err) next[key] = err;
+    };
+
+    // Only shown fields are checked and sent
+    const env: Record<string, string> = {};
+    for (const ev of visibleShared) {
+      env[ev.key] = envValues[ev.key] ?? "";
+      check(envErrorKey(ev.key), env[ev.key], ev);
+    }
+    const perEnvironment: Record<string, Record<string, string>> = {};
+    for (const name of environments) {
+      perEnvironment[name] = {};
+      for (const ev of visibleFor(name)) {
+        perEnvironment[name][ev.key] = envByEnvironment[name]?.[ev.key] ?? "";
+        check(envErrorKey(ev.key, name), perEnvironment[name][ev.key], ev);
+      }
+    }
+    const config: Record<string, string> = {};
+    for (const cfg of visibleConfigs) {
+      for (const field of cfg.fields) {
+        config[field.path] = configValues[field.path] ?? "";
+        const err = validateField(config[field.path], field);
+        if (err) next[configErrorKey(field.path)] = err;
+      }
+    }
+    setErrors(next);
+    if (Object.keys(next).length > 0) return;
+    onSubmit(env, config, perEnvironment);
   };
 
   return (
@@ -60,103 +164,140 @@ export function ConfigureStep({
         </p>
       </div>
 
-      {envVars.length > 0 && (
+      {visibleShared.length > 0 && (
         <Card className="w-full">
           <CardHeader>
             <CardTitle>Environment Variables</CardTitle>
             <CardDescription>
-              These values will be written to your{" "}
-              <code className="text-xs bg-secondary px-1 py-0.5 rounded">
-                .env
-              </code>{" "}
-              file
+              {environments.length > 0 ? (
+                <>These values are written to every environment's file</>
+              ) : (
+                <>
+                  These values will be written to your{" "}
+                  <code className="text-xs bg-secondary px-1 py-0.5 rounded">
+                    .env
+                  </code>{" "}
+                  file
+                </>
+              )}
             </CardDescription>
           </CardHeader>
           <CardContent className="space-y-4">
-            {envVars.map((ev) => (
-              <div key={ev.key} className="space-y-1.5">
-                <label className="text-sm font-medium" htmlFor={ev.key}>
-                  {ev.label}
-                  {ev.required && (
-                    <span className="text-destructive ml-1">*</span>
-                  )}
-                </label>
-                {ev.description && (
-                  <p className="text-xs text-muted-foreground">
-                    {ev.description}
-                  </p>
-                )}
-                <Input
-                  id={ev.key}
-                  type={
-                    ev.type === "secret"
-                      ? "password"
-                      : ev.type === "number"
-                        ? "number"
-                        : "text"
-                  }
-                  placeholder={ev.default || ev.label}
-                  value={envValues[ev.key] ?? ""}
-                  onChange={(e) =>
-                    setEnvValues((prev) => ({
-                      ...prev,
-                      [ev.key]: e.target.value,
-                    }))
-                  }
-                />
-                {ev.docsUrl && (
-                  <a
-                    href={ev.docsUrl}
-                    target="_blank"
-                    rel="noopener noreferrer"
-                    className="text-xs text-primary hover:underline"
-                  >
-                    Documentation
-                  </a>
-                )}
-              </div>
+            {visibleShared.map((ev) => (
+              <EnvField
+                key={ev.key}
+                id={ev.key}
+                envVar={ev}
+                value={envValues[ev.key] ?? ""}
+                error={errors[envErrorKey(ev.key)]}
+                onChange={(value) => {
+                  setEnvValues((prev) => ({ ...prev, [ev.key]: value }));
+                  clearError(envErrorKey(ev.key));
+                }}
+              />
             ))}
           </CardContent>
         </Card>
       )}
 
-      {configs.map((cfg) => (
-        <Card key={cfg.file} className="w-full">
-          <CardHeader>
-            <CardTitle>{cfg.label}</CardTitle>
-            <CardDescription>
-              {cfg.description}
-              <span className="block text-xs mt-1 font-mono">{cfg.file}</span>
-            </CardDescription>
-          </CardHeader>
-          <CardContent className="space-y-4">
-            {cfg.fields.map((field) => (
-              <div key={field.path} className="space-y-1.5">
-                <label className="text-sm font-medium" htmlFor={field.path}>
-                  {field.label}
-                </label>
-                {field.description && (
-                  <p className="text-xs text-muted-foreground">
-                    {field.description}
-                  </p>
-                )}
-                <Input
-                  id={field.path}
-                  type={field.type === "number" ? "number" : "text"}
-                  placeholder={field.default || field.label}
-                  value={configValues[field.path] ?? ""}
-                  onChange={(e) =>
-                    setConfigValues((prev) => ({
-                      ...prev,
-                      [field.path]: e.target.value,
-                    }))
-                  }
-                />
-              </div>
-            ))}
-          </CardContent>
-        </Card>
-      ))}
+      {environments.map(
+        (name) =>
+          visibleFor(name).length > 0 && (
+            <Card key={name} className="w-full">
+              <CardHeader>
+                <CardTitle>Environment Variables ({name})</CardTitle>
+                <CardDescription>
+                  Values used only in the {name} environment
+                </CardDescription>
+              </CardHeader>
+              <CardContent className="space-y-4">
+                {visibleFor(name).map((ev) => (
+                  <EnvField
+                    key={ev.key}
+                    id={`${name}:${ev.key}`}
+                    envVar={ev}
+                    value={envByEnvironment[name]?.[ev.key] ?? ""}
+                    error={errors[envErrorKey(ev.key, name)]}
+                    onChange={(value) => {
+                      setEnvByEnvironment((prev) => ({
+                        ...prev,
+                        [name]: { ...prev[name], [ev.key]: value },
+                      }));
+                      clearError(envErrorKey(ev.key, name));
+                    }}
+                  />
+                ))}
+              </CardContent>
+            </Card>
+          )
+      )}
+
+      {visibleConfigs.map(
+        (cfg) =>
+          cfg.fields.length > 0 && (
+            <Card key={cfg.file} className="w-full">
+              <CardHeader>
+                <CardTitle>{cfg.label}</CardTitle>
+                <CardDescription>
+                  {cfg.description}
+                  <span className="block text-xs mt-1 font-mono">{cfg.file}</span>
+                </CardDescription>
+              </CardHeader>
+              <CardContent className="space-y-4">
+                {cfg.fields.map((field) => (
+                  <div key={field.path} className="space-y-1.5">
+                    <label className="text-sm font-medium" htmlFor={field.path}>
+                      {field.label}
+                    </label>
+                    {field.description && (
+                      <p className="text-xs text-muted-foreground">
+                        {field.description}
+                      </p>
+                    )}
+                    {field.type === "select" ? (
+                      <NativeSelect
+                        id={field.path}
+                        value={configValues[field.path] ?? ""}
+                        onChange={(e) => {
+                          setConfigValues((prev) => ({
+                            ...prev,
+                            [field.path]: e.target.value,
+                          }));
+                          clearError(configErrorKey(field.path));
+                        }}
+                      >
+                        {!field.default && <option value="">Choose...</option>}
+                        {field.options?.map((opt) => (
+                          <option key={opt} value={opt}>
+                            {opt}
+                          </option>
+                        ))}
+                      </NativeSelect>
+                    ) : (
+                      <Input
+                        id={field.path}
+                        type={field.type === "number" ? "number" : "text"}
+                        min={field.min}
+                        max={field.max}
+                        placeholder={field.default || field.label}
+                        value={configValues[field.path] ?? ""}
+                        aria-invalid={!!errors[configErrorKey(field.path)]}
+                        onChange={(e) => {
+                          setConfigValues((prev) => ({
+                            ...prev,
+                            [field.path]: e.target.value,
+                          }));
+                          clearError(configErrorKey(field.path));
+                        }}
+                      />
+                    )}
+                    <FieldErrorText error={errors[configErrorKey(field.path)]} />
+                  </div>
+                ))}
+              </CardContent>
+            </Card>
+          )
+      )}
 
       <div className="flex gap-3 w-full max-w-sm">
         <Button variant="outline" onClick={onSkip} className="flex-1">
@@ -171,3 +312,82 @@ export function ConfigureStep({
     </div>
   );
 }
+
+// Keys of the errors map: env vars by key and environment, config fields
+// by path, so neither can collide with the other.
+function envErrorKey(key: string, environment?: string) {
+  return environment ? `env:${environment}:${key}` : `env:${key}`;
+}
+
+function configErrorKey(path: string) {
+  return `config:${path}`;
+}
+
+function FieldErrorText({ error }: { error?: string }) {
+  if (!error) return null;
+  return <p className="text-xs text-destructive">{error}</p>;
+}
+
+interface EnvFieldProps {
+  id: string;
+  envVar: EnvVarData;
+  value: string;
+  error?: string;
+  onChange: (value: string) => void;
+}
+
+function EnvField({ id, envVar: ev, value, error, onChange }: EnvFieldProps) {
+  return (
+    <div className="space-y-1.5">
+      <label className="text-sm font-medium" htmlFor={id}>
+        {ev.label}
+        {ev.required && <span className="text-destructive ml-1">*</span>}
+      </label>
+      {ev.description && (
+        <p className="text-xs text-muted-foreground">{ev.description}</p>
+      )}
+      {ev.type === "select" ? (
+        <NativeSelect
+          id={id}
+          value={value}
+          onChange={(e) => onChange(e.target.value)}
+        >
+          {!ev.default && <option value="">Choose...</option>}
+          {ev.options?.map((opt) => (
+            <option key={opt} value={opt}>
+              {opt}
+            </option>
+          ))}
+        </NativeSelect>
+      ) : (
+        <Input
+          id={id}
+          type={
+            ev.type === "secret"
+              ? "password"
+              : ev.type === "number"
+                ? "number"
+                : "text"
+          }
+          min={ev.min}
+          max={ev.max}
+          placeholder={ev.default || ev.label}
+          value={value}
+          aria-invalid={!!error}
+          onChange={(e) => onChange(e.target.value)}
+        />
+      )}
+      <FieldErrorText error={error} />
+      {ev.docsUrl && (
+        <a
+          href={ev.docsUrl}
+          target="_blank"
+          rel="noopener noreferrer"
+          className="text-xs text-primary hover:underline"
+        >
+          Documentation
+        </a>
+      )}
+    </div>
+  );
+}
@@ -52,10 +52,11 @@ export function InstallStep({
                   <span className="text-sm font-medium">{rs.displayName}</span>
                 </div>
                 <span className="text-xs text-muted-foreground">
-                  {rs.status === "downloading" && rs.total && `${rs.total}`}
+                  {rs.status === "downloading" &&
+                    [rs.total, rs.speed, rs.eta && `${rs.eta} left`].filter(Boolean).join(" · ")}
                   {rs.status === "complete" && rs.version && `v${rs.version}`}
                   {rs.status === "pending" && "Waiting"}
-                  {rs.status === "installing" && "Installing..."}
+                  {rs.status === "installing" && installingText(rs)}
                   {rs.status === "failed" && "Failed"}
                 </span>
               </div>
@@ -102,12 +103,29 @@ function StatusIcon({ status }: { status: RuntimeStatus["status"] }) {
   }
 }
 
+function installingText(rs: RuntimeStatus): string {
+  switch (rs.phase) {
+    case "resolving":
+      return "Resolving version...";
+    case "verifying":
+      return "Verifying checksum...";
+    case "extracting":
+      return rs.total ? `Extracting · ${rs.total}` : "Extracting...";
+    case "linking":
+      return "Adding to PATH...";
+    default:
+      return "Installing...";
+  }
+}
+
 function logColor(level: string): string {
   switch (level) {
     case "error":
       return "text-destructive";
     case "warn":
       return "text-amber-500";
+    case "output":
+      return "text-foreground/80 whitespace-pre-wrap";
     default:
       return "text-muted-foreground";
   }
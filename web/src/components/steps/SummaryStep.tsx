@@ -1,3 +1,4 @@
+import { useState } from "react";
 import { Button } from "@/components/ui/button";
 import {
   Card,
@@ -7,7 +8,7 @@ import {
   CardDescription,
 } from "@/components/ui/card";
 import { Badge } from "@/components/ui/badge";
-import type { PlanData } from "@/types";
+import type { ClientMessage, PlanData, SetupOptions } from "@/types";
 import {
   IconCircleCheck,
   IconDownload,
@@ -15,14 +16,48 @@ import {
   IconArrowLeft,
 } from "@tabler/icons-react";
 
+// formatBytes mirrors the Go side's binary units (1024-based).
+function formatBytes(bytes: number): string {
+  const units = ["B", "KB", "MB", "GB", "TB"];
+  let i = 0;
+  let n = bytes;
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return i === 0 ? `${n} B` : `${n.toFixed(1)} ${units[i]}`;
+}
+
 interface SummaryStepProps {
   plan: PlanData;
-  onInstall: () => void;
+  onInstall: (
+    overrides: ClientMessage["overrides"],
+    options: SetupOptions
+  ) => void;
   onBack: () => void;
 }
 
 export function SummaryStep({ plan, onInstall, onBack }: SummaryStepProps) {
-  const needsAction = plan.runtimes.some((r) => r.action !== "skip");
+  // Runtimes the user manages themselves and chose not to install
+  const [skipped, setSkipped] = useState<Set<string>>(() => new Set());
+  const toggleSkip = (name: string) =>
+    setSkipped((prev) => {
+      const next = new Set(prev);
+      if (!next.delete(name)) next.add(name);
+      return next;
+    });
+  // Steps after the runtimes the user will run themselves
+  const [options, setOptions] = useState<SetupOptions>({});
+  const hasPackages = (plan.packages?.steps.length ?? 0) > 0;
+  const hasPostSetup = (plan.postSetup?.length ?? 0) > 0;
+
+  const installsRuntimes = plan.runtimes.some(
+    (r) => r.action !== "skip" && !skipped.has(r.name)
+  );
+
+  const needsAction =
+    installsRuntimes ||
+    (plan.downloads ?? []).some((d) => d.action !== "skip");
 
   return (
     <div className="flex flex-col items-center gap-6 px-4 py-8 max-w-2xl mx-auto">
@@ -34,6 +69,17 @@ export function SummaryStep({ plan, onInstall, onBack }: SummaryStepProps) {
             <span className="text-xs ml-2">({plan.template.tier})</span>
           )}
         </p>
+        {plan.archivesDir && (
+          <p className="text-xs text-muted-foreground">
+            Offline mode: installing runtimes from archives in{" "}
+            <code>{plan.archivesDir}</code>
+          </p>
+        )}
+        {plan.downloadSize ? (
+          <p className="text-xs text-muted-foreground">
+            Approx. download size: {formatBytes(plan.downloadSize)}
+          </p>
+        ) : null}
       </div>
 
       <Card className="w-full">
@@ -58,22 +104,141 @@ export function SummaryStep({ plan, onInstall, onBack }: SummaryStepProps) {
                     </p>
                     <p className="text-xs text-muted-foreground">
                       Required: {runtime.requiredVersion}
+                      {runtime.requiredSource && (
+                        <> ({runtime.requiredSource})</>
+                      )}
                       {runtime.installedVersion && (
                         <>
                           {" "}
                           &middot; Installed: {runtime.installedVersion}
+                          {runtime.source && runtime.source !== "path" && (
+                            <> ({runtime.source})</>
+                          )}
                         </>
                       )}
                     </p>
+                    {runtime.note && (
+                      <p className="text-xs text-muted-foreground">
+                        {runtime.note}
+                      </p>
+                    )}
                   </div>
                 </div>
-                <ActionBadge action={runtime.action} />
+                <div className="flex items-center gap-2">
+                  {runtime.action !== "skip" && (
+                    <Button
+                      variant="ghost"
+                      size="sm"
+                      onClick={() => toggleSkip(runtime.name)}
+                    >
+                      {!skipped.has(runtime.name)
+                        ? "Skip"
+                        : runtime.action === "upgrade"
+                          ? "Upgrade"
+                          : "Install"}
+                    </Button>
+                  )}
+                  {skipped.has(runtime.name) ? (
+                    <Badge variant="outline">Skipped</Badge>
+                  ) : (
+                    <ActionBadge action={runtime.action} />
+                  )}
+                </div>
               </div>
             ))}
           </div>
         </CardContent>
       </Card>
 
+      {plan.downloads && plan.downloads.length > 0 && (
+        <Card className="w-full">
+          <CardHeader>
+            <CardTitle>Downloads</CardTitle>
+            <CardDescription>
+              Extra files fetched after runtimes are installed
+            </CardDescription>
+          </CardHeader>
+          <CardContent>
+            <div className="space-y-3">
+              {plan.downloads.map((download) => (
+                <div
+                  key={download.id}
+                  className="flex items-center justify-between p-3 rounded-lg bg-secondary/50"
+                >
+                  <div className="flex items-center gap-3">
+                    <RuntimeIcon action={download.action} />
+                    <div>
+                      <p className="font-medium text-sm">
+                        {download.name}
+                        {download.authenticated && (
+                          <span className="text-xs ml-2 text-muted-foreground">
+                            (authenticated)
+                          </span>
+                        )}
+                      </p>
+                      <p className="text-xs text-muted-foreground">
+                        {download.targetDir}
+                      </p>
+                    </div>
+                  </div>
+                  <ActionBadge action={download.action} />
+                </div>
+              ))}
+            </div>
+          </CardContent>
+        </Card>
+      )}
+
+      {plan.changes && (
+        <Card className="w-full">
+          <CardHeader>
+            <CardTitle>Changed Since Last Setup</CardTitle>
+            {plan.changes.oldVersion &&
+              plan.changes.oldVersion !== plan.changes.newVersion && (
+                <CardDescription>
+                  {plan.changes.oldVersion} &rarr; {plan.changes.newVersion}
+                </CardDescription>
+              )}
+          </CardHeader>
+          <CardContent>
+            <ul className="space-y-1 text-sm font-mono">
+              {plan.changes.runtimes?.map((r) => (
+                <li key={`runtime-${r.name}`}>
+                  {!r.old
+                    ? `+ runtime ${r.name} ${r.new}`
+                    : !r.new
+                      ? `- runtime ${r.name} ${r.old}`
+                      : `~ runtime ${r.name} ${r.old} → ${r.new}`}
+                </li>
+              ))}
+              {plan.changes.addedEnv?.map((f) => (
+                <li key={`env+${f.file}-${f.key}`}>
+                  + env {f.key} ({f.file}){f.required && " *"}
+                </li>
+              ))}
+              {plan.changes.removedEnv?.map((f) => (
+                <li key={`env-${f.file}-${f.key}`}>
+                  - env {f.key} ({f.file})
+                </li>
+              ))}
+              {plan.changes.addedConfig?.map((f) => (
+                <li key={`config+${f.file}-${f.key}`}>
+                  + config {f.key} ({f.file})
+                </li>
+              ))}
+              {plan.changes.removedConfig?.map((f) => (
+                <li key={`config-${f.file}-${f.key}`}>
+                  - config {f.key} ({f.file})
+                </li>
+              ))}
+              {plan.changes.postSetupChanged && (
+                <li>~ post-setup commands changed</li>
+              )}
+            </ul>
+          </CardContent>
+        </Card>
+      )}
+
       {plan.packages && (
         <Card className="w-full">
           <CardHeader>
@@ -84,32 +249,144 @@ export function SummaryStep({ plan, onInstall, onBack }: SummaryStepProps) {
               <div>
                 <p className="font-medium text-sm">
                   Package Manager: {plan.packages.manager}
+                  {plan.packages.managerVersion &&
+                    ` ${plan.packages.managerVersion}`}
+                  {plan.packages.detectedFrom && (
+                    <span className="text-muted-foreground font-normal">
+                      {" "}
+                      (detected from {plan.packages.detectedFrom})
+                    </span>
+                  )}
                 </p>
-                <p className="text-xs text-muted-foreground">
-                  {plan.packages.installCommand}
-                </p>
+                {plan.packages.steps.map((step, i) => (
+                  <p key={i} className="text-xs text-muted-foreground">
+                    {step.dir && (
+                      <span className="font-mono">{step.dir}/: </span>
+                    )}
+                    {step.command}
+                  </p>
+                ))}
               </div>
               <Badge
-                variant={plan.packages.managerFound ? "secondary" : "outline"}
+                variant={
+                  plan.packages.managerFound && !plan.packages.managerOutdated
+                    ? "secondary"
+                    : "outline"
+                }
                 className={
-                  plan.packages.managerFound
+                  plan.packages.managerFound && !plan.packages.managerOutdated
                     ? "bg-emerald-500/20 text-emerald-400"
                     : "bg-amber-500/20 text-amber-400 border-amber-500/30"
                 }
               >
-                {plan.packages.managerFound ? "Found" : "Not found"}
+                {plan.packages.managerOutdated
+                  ? plan.packages.bootstrap
+                    ? `Needs ${plan.packages.requiredVersion}, upgrade via ${plan.packages.bootstrap}`
+                    : `Needs ${plan.packages.requiredVersion}`
+                  : plan.packages.managerFound
+                    ? "Found"
+                    : plan.packages.bootstrap === "runtime"
+                      ? "Installed with its runtime"
+                      : plan.packages.bootstrap
+                        ? `Via ${plan.packages.bootstrap}`
+                        : "Not found"}
               </Badge>
             </div>
           </CardContent>
         </Card>
       )}
 
+      {installsRuntimes && (
+        <Card className="w-full">
+          <CardHeader>
+            <CardTitle>Environment</CardTitle>
+            <CardDescription>
+              Uncheck to leave your shell config alone; the lines to add
+              yourself are listed when setup finishes
+            </CardDescription>
+          </CardHeader>
+          <CardContent>
+            <label className="flex items-center gap-3 text-sm">
+              <input
+                type="checkbox"
+                className="size-4 accent-primary"
+                checked={!options.noPath}
+                onChange={(e) =>
+                  setOptions((prev) => ({
+                    ...prev,
+                    noPath: !e.target.checked,
+                  }))
+                }
+              />
+              Add runtimes to PATH
+            </label>
+          </CardContent>
+        </Card>
+      )}
+
+      {(hasPackages || hasPostSetup) && (
+        <Card className="w-full">
+          <CardHeader>
+            <CardTitle>After the Runtimes</CardTitle>
+            <CardDescription>
+              Uncheck a step to run its commands yourself; they are listed
+              when setup finishes
+            </CardDescription>
+          </CardHeader>
+          <CardContent className="space-y-2">
+            {hasPackages && (
+              <label className="flex items-center gap-3 text-sm">
+                <input
+                  type="checkbox"
+                  className="size-4 accent-primary"
+                  checked={!options.skipPackages}
+                  onChange={(e) =>
+                    setOptions((prev) => ({
+                      ...prev,
+                      skipPackages: !e.target.checked,
+                    }))
+                  }
+                />
+                Install packages
+              </label>
+            )}
+            {hasPostSetup && (
+              <label className="flex items-center gap-3 text-sm">
+                <input
+                  type="checkbox"
+                  className="size-4 accent-primary"
+                  checked={!options.skipPostSetup}
+                  onChange={(e) =>
+                    setOptions((prev) => ({
+                      ...prev,
+                      skipPostSetup: !e.target.checked,
+                    }))
+                  }
+                />
+                Run post-setup commands
+              </label>
+            )}
+          </CardContent>
+        </Card>
+      )}
+
       <div className="flex gap-3 w-full max-w-sm">
         <Button variant="outline" onClick={onBack} className="flex-1">
           <IconArrowLeft className="size-4" />
           Back
         </Button>
-        <Button onClick={onInstall} className="flex-1" size="lg">
+        <Button
+          onClick={() =>
+            onInstall(
+              Object.fromEntries(
+                [...skipped].map((name) => [name, "skip" as const])
+              ),
+              options
+            )
+          }
+          className="flex-1"
+          size="lg"
+        >
           {needsAction ? "Install" : "Continue"}
         </Button>
       </div>
@@ -1,5 +1,6 @@
 import { useState, useRef, useCallback } from "react";
 import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
 import {
   Card,
   CardContent,
@@ -11,20 +12,24 @@ import {
   IconUpload,
   IconFileDescription,
   IconCheck,
+  IconLink,
 } from "@tabler/icons-react";
 
 interface WelcomeStepProps {
   hasManifest: boolean;
   onContinue: () => void;
-  onLoadManifest: (content: string) => void;
+  onLoadManifest: (content: string, fileName: string) => void;
+  onLoadManifestURL: (url: string) => void;
 }
 
 export function WelcomeStep({
   hasManifest,
   onContinue,
   onLoadManifest,
+  onLoadManifestURL,
 }: WelcomeStepProps) {
   const [dragging, setDragging] = useState(false);
+  const [url, setURL] = useState("");
   const [fileName, setFileName] = useState<string | null>(null);
   const fileInputRef = useRef<HTMLInputElement>(null);
 
@@ -43,7 +48,7 @@ export function WelcomeStep({
       const reader = new FileReader();
       reader.onload = () => {
         if (typeof reader.result === "string") {
-          onLoadManifest(reader.result);
+          onLoadManifest(reader.result, file.name);
         }
       };
       reader.readAsText(file);
@@ -136,6 +141,30 @@ export function WelcomeStep({
             </div>
           )}
 
+          {!hasManifest && (
+            <form
+              className="flex gap-2"
+              onSubmit={(e) => {
+                e.preventDefault();
+                if (url.trim()) {
+                  setFileName(url.trim());
+                  onLoadManifestURL(url.trim());
+                }
+              }}
+            >
+              <Input
+                type="url"
+                placeholder="or paste an https:// link to a .templatr.toml"
+                value={url}
+                onChange={(e) => setURL(e.target.value)}
+              />
+              <Button type="submit" variant="outline" disabled={!url.trim()}>
+                <IconLink className="size-4" />
+                Load
+              </Button>
+            </form>
+          )}
+
           <Button
             onClick={onContinue}
             disabled={!hasManifest}
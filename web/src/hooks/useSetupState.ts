@@ -1,19 +1,30 @@
 import { useCallback, useState } from "react";
 import type {
+  EnvSummary,
+  FieldError,
   LogEntry,
+  ManualStep,
   PlanData,
+  PostSetupCommand,
   RuntimeStatus,
   ServerMessage,
+  ValidationData,
   WizardStep,
 } from "../types";
 
 interface SetupState {
   step: WizardStep;
   plan: PlanData | null;
+  validation: ValidationData | null;
   runtimeStatuses: RuntimeStatus[];
   logs: LogEntry[];
   error: string | null;
+  fieldErrors: FieldError[]; // configure values the server rejected
   completeMessage: string | null;
+  unignored: string[];
+  envChanges: EnvSummary | null;
+  skipped: ManualStep[];
+  postSetup: PostSetupCommand[];
   success: boolean;
 }
 
@@ -26,10 +37,16 @@ export function useSetupState(): UseSetupStateReturn {
   const [state, setState] = useState<SetupState>({
     step: "welcome",
     plan: null,
+    validation: null,
     runtimeStatuses: [],
     logs: [],
     error: null,
+    fieldErrors: [],
     completeMessage: null,
+    unignored: [],
+    envChanges: null,
+    skipped: [],
+    postSetup: [],
     success: false,
   });
 
@@ -41,17 +58,34 @@ export function useSetupState(): UseSetupStateReturn {
     setState((prev) => {
       switch (msg.type) {
         case "plan": {
+          // A plan sent after a reconnect carries what this session
+          // already installed, or failed to
+          const statusOf = (action: string, status?: string) =>
+            action === "skip" || status === "complete"
+              ? ("complete" as const)
+              : status === "failed"
+                ? ("failed" as const)
+                : ("pending" as const);
           const statuses: RuntimeStatus[] =
-            msg.plan?.runtimes.map((r) => ({
-              name: r.name,
-              displayName: r.displayName,
-              status:
-                r.action === "skip"
-                  ? ("complete" as const)
-                  : ("pending" as const),
-              progress: r.action === "skip" ? 100 : 0,
-              version: r.installedVersion || undefined,
-            })) ?? [];
+            msg.plan?.runtimes.map((r) => {
+              const status = statusOf(r.action, r.status);
+              return {
+                name: r.name,
+                displayName: r.displayName,
+                status,
+                progress: status === "complete" ? 100 : 0,
+                version: r.installedVersion || undefined,
+              };
+            }) ?? [];
+          for (const d of msg.plan?.downloads ?? []) {
+            const status = statusOf(d.action, d.status);
+            statuses.push({
+              name: d.id,
+              displayName: `${d.name} (download)`,
+              status,
+              progress: status === "complete" ? 100 : 0,
+            });
+          }
 
           return {
             ...prev,
@@ -62,21 +96,33 @@ export function useSetupState(): UseSetupStateReturn {
           };
         }
 
+        case "validation": {
+          return {
+            ...prev,
+            validation: msg.validation ?? null,
+            error: null,
+          };
+        }
+
         case "step": {
           if (msg.step === "configure" && msg.status === "ready") {
             return { ...prev, step: "configure" };
           }
+          if (msg.step === "install" && msg.status === "running") {
+            // Also replayed to a refreshed tab; a retry starts over from
+            // the failure it follows
+            return { ...prev, step: "install", error: null };
+          }
           return prev;
         }
 
         case "runtime": {
-          if (msg.status === "installing") {
+          if (msg.status === "installing" || msg.status === "failed") {
+            const status = msg.status;
             return {
               ...prev,
               runtimeStatuses: prev.runtimeStatuses.map((rs) =>
-                rs.name === msg.name
-                  ? { ...rs, status: "installing" as const }
-                  : rs
+                rs.name === msg.name ? { ...rs, status, phase: msg.phase } : rs
               ),
             };
           }
@@ -105,9 +151,15 @@ export function useSetupState(): UseSetupStateReturn {
               rs.name === msg.runtime
                 ? {
                     ...rs,
-                    status: "downloading" as const,
+                    status:
+                      msg.phase === "extracting"
+                        ? ("installing" as const)
+                        : ("downloading" as const),
+                    phase: msg.phase,
                     progress: msg.progress ?? 0,
                     total: msg.total,
+                    speed: msg.speed,
+                    eta: msg.eta,
                   }
                 : rs
             ),
@@ -141,16 +193,21 @@ export function useSetupState(): UseSetupStateReturn {
               {
                 level: msg.level ?? "info",
                 message: msg.message ?? "",
-                timestamp: Date.now(),
+                timestamp: msg.record ? Date.parse(msg.record.ts) : Date.now(),
               },
             ],
           };
         }
 
+        case "post_setup": {
+          return { ...prev, postSetup: msg.postSetup ?? [] };
+        }
+
         case "error": {
           return {
             ...prev,
             error: msg.message ?? "An unknown error occurred",
+            fieldErrors: msg.fieldErrors ?? [],
           };
         }
 
@@ -158,8 +215,12 @@ export function useSetupState(): UseSetupStateReturn {
           return {
             ...prev,
             step: "complete",
+            fieldErrors: [],
             success: msg.success ?? false,
             completeMessage: msg.message ?? null,
+            unignored: msg.unignored ?? [],
+            envChanges: msg.envChanges ?? null,
+            skipped: msg.skipped ?? [],
           };
         }
 
@@ -20,7 +20,7 @@ export function useWebSocket(
 
   useEffect(() => {
     const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
-    const wsUrl = `${protocol}//${window.location.host}/ws`;
+    const wsUrl = `${protocol}//${window.location.host}/ws?token=${encodeURIComponent(sessionToken())}`;
 
     function connect() {
       const ws = new WebSocket(wsUrl);
@@ -66,3 +66,15 @@ export function useWebSocket(
 
   return { connected, send, lastMessage, messages };
 }
+
+// sessionToken returns the token the server opened the browser with
+// (?token=...), kept for the tab so it survives navigation that drops the
+// query string.
+function sessionToken(): string {
+  const token = new URLSearchParams(window.location.search).get("token");
+  if (token) {
+    sessionStorage.setItem("templatr-token", token);
+    return token;
+  }
+  return sessionStorage.getItem("templatr-token") ?? "";
+}
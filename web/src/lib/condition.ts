@@ -0,0 +1,35 @@
+// Mirrors manifest.ParseCondition and manifest.Shown: a `when` expression
+// such as PAYMENT_PROVIDER == 'stripe' decides whether a field is shown.
+
+interface Condition {
+  key: string;
+  op: "==" | "!=";
+  value: string;
+}
+
+const conditionPattern =
+  /^\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*(==|!=)\s*(?:'([^']*)'|"([^"]*)"|([^\s'"]+))\s*$/;
+
+function parseCondition(expr: string): Condition | null {
+  const m = conditionPattern.exec(expr);
+  if (!m) return null;
+  return {
+    key: m[1],
+    op: m[2] as Condition["op"],
+    value: m[3] ?? m[4] ?? m[5] ?? "",
+  };
+}
+
+// shown reports whether a field with the condition applies given values,
+// keyed by env key or config path. Fields without a condition always do,
+// as do ones whose condition doesn't parse, since validation reports those.
+export function shown(
+  when: string | undefined,
+  values: Record<string, string>
+): boolean {
+  if (!when) return true;
+  const c = parseCondition(when);
+  if (!c) return true;
+  const equal = (values[c.key] ?? "").trim() === c.value;
+  return c.op === "==" ? equal : !equal;
+}
@@ -0,0 +1,87 @@
+// Mirrors config.ValidateFieldValue so the form can flag a value before
+// the server rejects it.
+
+export interface FieldRules {
+  type: string;
+  required?: boolean;
+  options?: string[];
+  pattern?: string;
+  min?: number;
+  max?: number;
+}
+
+const numberLiteral = /^-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?$/;
+
+// validateField returns why value doesn't pass rules, or null if it does.
+// Empty optional values are always accepted.
+export function validateField(raw: string, rules: FieldRules): string | null {
+  const value = raw.trim();
+  if (value === "") {
+    return rules.required ? "a value is required" : null;
+  }
+
+  switch (rules.type) {
+    case "number": {
+      if (!numberLiteral.test(value)) {
+        return "must be a number";
+      }
+      const n = Number(value);
+      const { min, max } = rules;
+      if ((min !== undefined && n < min) || (max !== undefined && n > max)) {
+        if (min !== undefined && max !== undefined) {
+          return `must be between ${min} and ${max}`;
+        }
+        return min !== undefined
+          ? `must be at least ${min}`
+          : `must be at most ${max}`;
+      }
+      break;
+    }
+    case "url": {
+      try {
+        const u = new URL(value);
+        if (!u.protocol || !u.host) {
+          return "must be a URL like https://example.com";
+        }
+      } catch {
+        return "must be a URL like https://example.com";
+      }
+      break;
+    }
+    case "email": {
+      const domain = value.slice(value.lastIndexOf("@") + 1);
+      if (!/^[^\s@<>]+@[^\s@<>]+$/.test(value) || !domain.includes(".")) {
+        return "must be an email address like you@example.com";
+      }
+      break;
+    }
+    case "boolean": {
+      const v = value.toLowerCase();
+      if (v !== "true" && v !== "false") {
+        return "must be true or false";
+      }
+      break;
+    }
+    case "select": {
+      if (!rules.options?.includes(value)) {
+        return `must be one of: ${rules.options?.join(", ") ?? ""}`;
+      }
+      break;
+    }
+  }
+
+  if (rules.pattern) {
+    // Go's RE2 syntax; the common subset compiles the same in JavaScript,
+    // and the server checks anything that doesn't
+    let re: RegExp | null = null;
+    try {
+      re = new RegExp(rules.pattern);
+    } catch {
+      re = null;
+    }
+    if (re && !re.test(value)) {
+      return `must match ${rules.pattern}`;
+    }
+  }
+  return null;
+}
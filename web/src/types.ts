@@ -10,18 +10,147 @@ export interface ServerMessage {
   progress?: number;
   speed?: string;
   total?: string;
+  eta?: string; // time left at speed, such as "3m05s"
+  phase?: InstallPhase;
   level?: string;
   message?: string;
   success?: boolean;
+  unignored?: string[]; // env files with secrets that git would pick up
+  envChanges?: EnvSummary;
+  skipped?: ManualStep[]; // steps left to the user with "confirm" options
   plan?: PlanData;
+  validation?: ValidationData;
+  postSetup?: PostSetupCommand[];
+  record?: LogRecord; // the log entry a "log" message was taken from
+  fieldErrors?: FieldError[]; // values a "configure" message was rejected for
+}
+
+// A configure value that failed its field's rules (matches Go FieldErrorData)
+export interface FieldError {
+  env?: string;
+  environment?: string; // set for per-environment values
+  config?: string; // config field path
+  message: string;
+}
+
+// A structured log entry (matches Go logger.Record)
+export interface LogRecord {
+  ts: string;
+  level: "DEBUG" | "INFO" | "WARN" | "ERROR";
+  msg: string;
+  fields?: Record<string, string>; // e.g. stream: "output" for command output
+}
+
+// How one post-setup command went (matches Go PostSetupCommandData)
+export interface PostSetupCommand {
+  index: number; // in post_setup.commands, sent back by "retry_command"
+  command: string;
+  dir?: string;
+  required?: boolean;
+  status: "ok" | "failed" | "not_run" | "running";
+  error?: string;
+}
+
+// A skipped setup step and the commands to run instead (matches Go packages.ManualStep)
+export interface ManualStep {
+  name: string;
+  commands: string[];
+}
+
+// Steps to leave to the user (matches Go packages.SetupOptions)
+export interface SetupOptions {
+  skipPackages?: boolean;
+  skipPostSetup?: boolean;
+  noPath?: boolean; // don't change PATH or set JAVA_HOME etc.; list the lines instead
+}
+
+// PATH and variable changes made while installing (matches Go install.EnvSummary)
+export interface EnvSummary {
+  shell: string; // zsh, bash, fish, sh, or powershell
+  changes: EnvChange[];
+  activation?: string[]; // commands that apply the changes to the current shell
+  manual?: string[]; // lines for the user to add to manualFile, with noPath
+  manualFile?: string; // e.g. ~/.zshrc or $PROFILE
+}
+
+export interface EnvChange {
+  kind: "path" | "env";
+  name: string;
+  value: string;
+  file?: string; // shell rc file modified (Unix)
+  line?: string; // exact line appended to file
+  registry?: string; // registry value written (Windows)
+  unchanged?: boolean; // already configured, nothing written
+  manual?: boolean; // left for the user to add (noPath), nothing written
+}
+
+// Structured manifest validation result (matches Go ValidationData)
+export interface ValidationData {
+  valid: boolean;
+  stage: "parse" | "validate" | "plan"; // where the manifest failed
+  file?: string; // manifest the content came from
+  errors: ValidationIssue[];
+  content?: string;
+}
+
+export interface ValidationIssue {
+  path: string; // e.g. "env.2.type"; empty for TOML parse errors
+  message: string;
+  severity: "error" | "warning";
+  line?: number; // position of a TOML parse error
+  column?: number;
 }
 
 export interface PlanData {
   template: TemplateData;
+  needsAction: boolean; // a runtime or download will be installed
   runtimes: RuntimeData[];
   packages?: PackageData;
   envVars?: EnvVarData[];
   configs?: ConfigData[];
+  downloads?: DownloadData[];
+  postSetup?: string[]; // post-setup commands that run on this OS
+  changes?: ManifestDiff;
+  environments?: string[]; // declared in [env_environments]
+  archivesDir?: string; // offline mode: runtimes come from archives here
+  downloadSize?: number; // approx. bytes downloaded for runtimes
+}
+
+// Extra [[downloads]] entry; id keys its progress messages
+export interface DownloadData {
+  id: string;
+  name: string;
+  targetDir: string;
+  authenticated: boolean;
+  action: "skip" | "install";
+  status?: "complete" | "failed"; // as for RuntimeData
+}
+
+// Differences since the last successful setup (matches Go manifest.Diff)
+export interface ManifestDiff {
+  oldVersion?: string;
+  newVersion?: string;
+  runtimes?: RuntimeChange[];
+  addedEnv?: FieldChange[];
+  removedEnv?: FieldChange[];
+  addedConfig?: FieldChange[];
+  removedConfig?: FieldChange[];
+  postSetupChanged?: boolean;
+  oldCommands?: string[];
+  newCommands?: string[];
+}
+
+export interface RuntimeChange {
+  name: string;
+  old?: string;
+  new?: string;
+}
+
+export interface FieldChange {
+  key: string;
+  label?: string;
+  file?: string;
+  required?: boolean;
 }
 
 export interface TemplateData {
@@ -35,14 +164,30 @@ export interface RuntimeData {
   name: string;
   displayName: string;
   requiredVersion: string;
+  requiredSource?: string; // project file an "auto" requirement was read from
   installedVersion: string;
+  source?: string; // "path", or a version manager such as "nvm"
+  note?: string;
   action: "skip" | "install" | "upgrade";
+  status?: "complete" | "failed"; // how its install went earlier this session
 }
 
 export interface PackageData {
   manager: string;
   installCommand: string;
   managerFound: boolean;
+  managerVersion?: string; // e.g. "7.33.0"
+  requiredVersion?: string; // [packages] manager_version, e.g. ">=9"
+  managerOutdated?: boolean; // found, but older than requiredVersion
+  bootstrap?: "corepack" | "ensurepip" | "runtime" | "npm";
+  detectedFrom?: string;
+  steps: InstallStepData[];
+}
+
+export interface InstallStepData {
+  dir?: string;
+  command: string;
+  manager?: string;
 }
 
 export interface EnvVarData {
@@ -51,8 +196,15 @@ export interface EnvVarData {
   description: string;
   default: string;
   required: boolean;
-  type: "text" | "url" | "email" | "secret" | "number" | "boolean";
+  type: "text" | "url" | "email" | "secret" | "number" | "boolean" | "select";
   docsUrl?: string;
+  file?: string;
+  environments?: string[]; // per-environment value; empty means shared
+  options?: string[]; // choices for type "select"
+  pattern?: string; // regular expression the value must match
+  min?: number; // bounds for type "number"
+  max?: number;
+  when?: string; // shown only while this holds, e.g. "PAYMENT_PROVIDER == 'stripe'"
 }
 
 export interface ConfigData {
@@ -68,16 +220,35 @@ export interface ConfigFieldData {
   description: string;
   type: string;
   default: string;
+  options?: string[]; // choices for type "select"
+  pattern?: string;
+  min?: number;
+  max?: number;
+  when?: string;
 }
 
 // Client → Server message types (matches Go ClientMessage)
 export interface ClientMessage {
-  type: "load_manifest" | "confirm" | "configure" | "cancel";
+  type:
+    | "load_manifest"
+    | "revalidate"
+    | "proceed"
+    | "confirm"
+    | "configure"
+    | "retry_command"
+    | "retry"
+    | "cancel";
   action?: string;
   env?: Record<string, string>;
   config?: Record<string, string>;
+  envByEnvironment?: Record<string, Record<string, string>>;
   manifestContent?: string;
+  manifestName?: string; // file the content was read from
   manifestPath?: string;
+  manifestURL?: string; // https:// URL the server fetches the manifest from
+  index?: number; // post-setup command for "retry_command"
+  overrides?: Record<string, RuntimeData["action"]>; // runtime actions for "confirm", such as { java: "skip" }
+  options?: SetupOptions; // steps to leave out, for "confirm"
 }
 
 // Wizard step
@@ -103,4 +274,15 @@ export interface RuntimeStatus {
   progress: number;
   version?: string;
   total?: string;
+  speed?: string;
+  eta?: string;
+  phase?: InstallPhase;
 }
+
+// The step of an install a runtime or download is in (matches Go install.Phase*)
+export type InstallPhase =
+  | "resolving"
+  | "downloading"
+  | "verifying"
+  | "extracting"
+  | "linking";